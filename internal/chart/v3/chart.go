@@ -53,6 +53,7 @@ type Chart struct {
 
 	parent       *Chart
 	dependencies []*Chart
+	disabled     []*Dependency
 }
 
 type CRD struct {
@@ -97,6 +98,15 @@ func (ch *Chart) Root() *Chart {
 // Dependencies are the charts that this chart depends on.
 func (ch *Chart) Dependencies() []*Chart { return ch.dependencies }
 
+// DisabledDependencies are the dependencies of the chart disabled by their
+// condition or tags, which were removed when processing the dependencies.
+func (ch *Chart) DisabledDependencies() []*Dependency { return ch.disabled }
+
+// AddDisabledDependency records dependencies disabled by their condition or tags.
+func (ch *Chart) AddDisabledDependency(deps ...*Dependency) {
+	ch.disabled = append(ch.disabled, deps...)
+}
+
 // IsRoot determines if the chart is the root chart.
 func (ch *Chart) IsRoot() bool { return ch.parent == nil }
 
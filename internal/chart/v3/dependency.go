@@ -15,7 +15,10 @@ limitations under the License.
 
 package v3
 
-import "time"
+import (
+	"path/filepath"
+	"time"
+)
 
 // Dependency describes a chart upon which another chart depends.
 //
@@ -47,6 +50,10 @@ type Dependency struct {
 	ImportValues []interface{} `json:"import-values,omitempty" yaml:"import-values,omitempty"`
 	// Alias usable alias to be used for the chart
 	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	// ValuesFile is the path, relative to the root of the parent chart, of a
+	// values file holding the values of the parent chart for this dependency.
+	// The values of the parent chart for the dependency take precedence over it.
+	ValuesFile string `json:"valuesFile,omitempty" yaml:"valuesFile,omitempty"`
 }
 
 // Validate checks for common problems with the dependency datastructure in
@@ -66,6 +73,12 @@ func (d *Dependency) Validate() error {
 	if d.Alias != "" && !aliasNameFormat.MatchString(d.Alias) {
 		return ValidationErrorf("dependency %q has disallowed characters in the alias", d.Name)
 	}
+	if d.ValuesFile != "" {
+		d.ValuesFile = sanitizeString(d.ValuesFile)
+		if !filepath.IsLocal(filepath.FromSlash(d.ValuesFile)) {
+			return ValidationErrorf("dependency %q has a values file outside of the chart: %s", d.Name, d.ValuesFile)
+		}
+	}
 	return nil
 }
 
@@ -42,3 +42,22 @@ func TestValidateDependency(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDependencyValuesFile(t *testing.T) {
+	for value, shouldFail := range map[string]bool{
+		"deps/redis.yaml":      false,
+		"redis-values.yaml":    false,
+		"deps/../redis.yaml":   false,
+		"../redis.yaml":        true,
+		"deps/../../secrets":   true,
+		"/etc/helm/redis.yaml": true,
+	} {
+		dep := &Dependency{Name: "redis", ValuesFile: value}
+		res := dep.Validate()
+		if res != nil && !shouldFail {
+			t.Errorf("Failed on case %q: %s", value, res)
+		} else if res == nil && shouldFail {
+			t.Errorf("Expected failure for %q", value)
+		}
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package rules // import "helm.sh/helm/v4/internal/chart/v3/lint/rules"
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
+	"helm.sh/helm/v4/internal/chart/v3/lint/support"
 	"helm.sh/helm/v4/pkg/chart/common"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -40,6 +43,9 @@ var (
 type deprecatedAPIError struct {
 	Deprecated string
 	Message    string
+	// Removed is whether the API is no longer served by the Kubernetes
+	// version checked.
+	Removed bool
 }
 
 func (e deprecatedAPIError) Error() string {
@@ -89,9 +95,28 @@ func validateNoDeprecations(resource *k8sYamlStruct, kubeVersion *common.KubeVer
 	return deprecatedAPIError{
 		Deprecated: gvk,
 		Message:    deprecation.WarningMessage(runtimeObject),
+		Removed:    isRemoved(runtimeObject, major, minor),
 	}
 }
 
+// isRemoved reports whether an API is no longer served by the given
+// Kubernetes version.
+func isRemoved(obj runtime.Object, major, minor int) bool {
+	removedMajor, removedMinor, ok := strings.Cut(deprecation.RemovedRelease(obj), ".")
+	if !ok {
+		return false
+	}
+	rMajor, err := strconv.Atoi(removedMajor)
+	if err != nil {
+		return false
+	}
+	rMinor, err := strconv.Atoi(removedMinor)
+	if err != nil {
+		return false
+	}
+	return major > rMajor || major == rMajor && minor >= rMinor
+}
+
 func resourceToRuntimeObject(resource *k8sYamlStruct) (runtime.Object, error) {
 	scheme := runtime.NewScheme()
 	kscheme.AddToScheme(scheme)
@@ -104,3 +129,15 @@ func resourceToRuntimeObject(resource *k8sYamlStruct) (runtime.Object, error) {
 	out.GetObjectKind().SetGroupVersionKind(gvk)
 	return out, nil
 }
+
+// deprecationSeverity returns the severity of a deprecated API: a warning, to
+// allow charts to support out-of-date Kubernetes versions, unless removedAsErrors
+// is set and the API is no longer served by the Kubernetes version the chart
+// is linted against.
+func deprecationSeverity(err error, removedAsErrors bool) int {
+	var deprecated deprecatedAPIError
+	if removedAsErrors && errors.As(err, &deprecated) && deprecated.Removed {
+		return support.ErrorSev
+	}
+	return support.WarningSev
+}
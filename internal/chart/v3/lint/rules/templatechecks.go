@@ -0,0 +1,475 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"helm.sh/helm/v4/internal/chart/v3/lint/support"
+	"helm.sh/helm/v4/internal/chart/v3/loader"
+	chartutil "helm.sh/helm/v4/internal/chart/v3/util"
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
+	"helm.sh/helm/v4/pkg/engine"
+)
+
+// The checks of TemplateChecks, which can be disabled by name.
+const (
+	// IndentCheck reports indent applied to output that does not start a
+	// line, which misindents its first line.
+	IndentCheck = "indent"
+	// ToYAMLNilCheck reports toYaml applied to values that are null, which
+	// renders "null".
+	ToYAMLNilCheck = "toyaml-nil"
+	// QuotingCheck reports labels, annotations, environment variables and
+	// ConfigMap data rendered as booleans or numbers rather than strings, and
+	// ports rendered as strings rather than numbers.
+	QuotingCheck = "quoting"
+	// ChompCheck reports whitespace chomping that joins the output of an
+	// action with the line before or after it.
+	ChompCheck = "chomp"
+)
+
+// TemplateCheckNames are the names of the checks of TemplateChecks.
+var TemplateCheckNames = []string{IndentCheck, ToYAMLNilCheck, QuotingCheck, ChompCheck}
+
+// TemplateChecks lints the templates for common templating mistakes, skipping
+// the disabled checks. The templates are checked both as parsed and as
+// rendered with the values.
+func TemplateChecks(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *common.KubeVersion, disabled []string) {
+	enabled := func(check string) bool {
+		return !slices.Contains(disabled, check)
+	}
+
+	chart, err := loader.Load(linter.ChartDir)
+	if err != nil {
+		// Reported by the Templates rule.
+		return
+	}
+
+	// The templates are parsed without the functions of the engine, as only
+	// their structure matters here.
+	trees := make(map[string]*parse.Tree)
+	sources := make(map[string]string)
+	for _, template := range chart.Templates {
+		t := parse.New(template.Name)
+		t.Mode = parse.SkipFuncCheck
+		if _, err := t.Parse(string(template.Data), "", "", trees); err != nil {
+			// Reported by the Templates rule.
+			return
+		}
+		sources[template.Name] = string(template.Data)
+	}
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var renderValues common.Values
+	if err := chartutil.ProcessDependencies(chart, values); err == nil {
+		if cvals, err := util.CoalesceValues(chart, values); err == nil {
+			options := common.ReleaseOptions{Name: "test-release", Namespace: namespace}
+			caps := common.DefaultCapabilities.Copy()
+			if kubeVersion != nil {
+				caps.KubeVersion = *kubeVersion
+			}
+			renderValues, _ = util.ToRenderValuesWithSchemaValidation(chart, cvals, options, caps, true)
+		}
+	}
+
+	for _, name := range names {
+		tree := trees[name]
+		if tree.Root == nil {
+			continue
+		}
+		c := &templateChecker{
+			linter: linter,
+			tree:   tree,
+			source: sources[tree.ParseName],
+			values: renderValues,
+		}
+		c.walk(tree.Root, checkScope{dotIsRoot: true}, enabled)
+	}
+
+	if !enabled(QuotingCheck) || renderValues == nil {
+		return
+	}
+	var e engine.Engine
+	e.LintMode = true
+	rendered, err := e.Render(chart, renderValues)
+	if err != nil {
+		// Reported by the Templates rule.
+		return
+	}
+	for _, template := range chart.Templates {
+		if path.Ext(template.Name) != ".yaml" {
+			continue
+		}
+		content := rendered[path.Join(chart.Name(), template.Name)]
+		decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+		for {
+			var obj map[string]interface{}
+			if err := decoder.Decode(&obj); err != nil {
+				// Invalid YAML is reported by the Templates rule.
+				break
+			}
+			for _, err := range validateQuoting(obj) {
+				linter.RunLinterRule(support.WarningSev, template.Name, err)
+			}
+		}
+	}
+}
+
+// checkScope is the scope of the actions of a template.
+type checkScope struct {
+	// dotIsRoot is whether dot is the top-level data of the template, rather
+	// than set by a with or range action.
+	dotIsRoot bool
+	// guards are the values tested by the enclosing if and with actions.
+	guards []string
+}
+
+// templateChecker runs the checks on the parse tree of a template.
+type templateChecker struct {
+	linter *support.Linter
+	tree   *parse.Tree
+	source string
+	values common.Values
+}
+
+func (c *templateChecker) walk(list *parse.ListNode, scope checkScope, enabled func(string) bool) {
+	if list == nil {
+		return
+	}
+	for i, n := range list.Nodes {
+		switch n := n.(type) {
+		case *parse.ActionNode:
+			var prev, next *parse.TextNode
+			if i > 0 {
+				prev, _ = list.Nodes[i-1].(*parse.TextNode)
+			}
+			if i < len(list.Nodes)-1 {
+				next, _ = list.Nodes[i+1].(*parse.TextNode)
+			}
+			if enabled(IndentCheck) {
+				c.report(n, c.checkIndent(n, prev))
+			}
+			if enabled(ChompCheck) {
+				c.report(n, c.checkChomp(n, prev, next))
+			}
+			if enabled(ToYAMLNilCheck) {
+				c.report(n, c.checkToYAMLNil(n, scope))
+			}
+		case *parse.IfNode:
+			guarded := scope
+			guarded.guards = append(slices.Clone(scope.guards), valuePaths(n.Pipe, scope)...)
+			c.walk(n.List, guarded, enabled)
+			c.walk(n.ElseList, scope, enabled)
+		case *parse.WithNode:
+			c.walk(n.List, checkScope{guards: append(slices.Clone(scope.guards), valuePaths(n.Pipe, scope)...)}, enabled)
+			c.walk(n.ElseList, scope, enabled)
+		case *parse.RangeNode:
+			c.walk(n.List, checkScope{guards: scope.guards}, enabled)
+			c.walk(n.ElseList, scope, enabled)
+		}
+	}
+}
+
+func (c *templateChecker) report(n parse.Node, err error) {
+	if err == nil {
+		return
+	}
+	location, _ := c.tree.ErrorContext(n)
+	// The location is file:line:column.
+	parts := strings.Split(location, ":")
+	line := "?"
+	if len(parts) >= 3 {
+		line = parts[len(parts)-2]
+	}
+	c.linter.RunLinterRule(support.WarningSev, c.tree.ParseName, fmt.Errorf("line %s: %w", line, err))
+}
+
+// checkIndent reports indent applied to output that does not start a line.
+// indent indents each line of its input, the first one included, so that
+// the first line is misindented when the output follows other text, or the
+// indentation of the template, on its line.
+func (c *templateChecker) checkIndent(n *parse.ActionNode, prev *parse.TextNode) error {
+	if isAssignment(n.Pipe) || lastFunction(n.Pipe) != "indent" || prev == nil {
+		return nil
+	}
+	text := string(prev.Text)
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return nil
+	}
+	return fmt.Errorf("the output of %s does not start a line, so indent misindents its first line: use nindent instead, without the text before the action on its line", n)
+}
+
+// checkChomp reports an action whose trim markers remove the line break
+// between its output and the text before or after it.
+func (c *templateChecker) checkChomp(n *parse.ActionNode, prev, next *parse.TextNode) error {
+	if isAssignment(n.Pipe) {
+		return nil
+	}
+	if prev != nil && lastFunction(n.Pipe) != "nindent" {
+		// The source between the text and the action is the trimmed
+		// whitespace and the left delimiter with its trim marker.
+		between := c.source[int(prev.Pos)+len(prev.Text) : n.Pos]
+		if space, delim, ok := strings.Cut(between, "{{"); ok && strings.HasPrefix(strings.TrimSpace(delim), "-") && strings.Contains(space, "\n") {
+			return fmt.Errorf("{{- removes the line break before %s, joining its output to the line %q", n, lastLine(string(prev.Text)))
+		}
+	}
+	if next != nil {
+		// The source before the text ends with the right delimiter with its
+		// trim marker and the trimmed whitespace.
+		before := c.source[:next.Pos]
+		trimmed := strings.TrimRight(before, " \t\r\n")
+		if strings.HasSuffix(trimmed, "-}}") && strings.Contains(before[len(trimmed):], "\n") {
+			return fmt.Errorf("-}} removes the line break after %s, joining the line %q to its output", n, firstLine(string(next.Text)))
+		}
+	}
+	return nil
+}
+
+// checkToYAMLNil reports toYaml applied to a value that is null with the
+// values of the lint, unless an enclosing if or with action tests it.
+func (c *templateChecker) checkToYAMLNil(n *parse.ActionNode, scope checkScope) error {
+	if c.values == nil || isAssignment(n.Pipe) {
+		return nil
+	}
+	for i, cmd := range n.Pipe.Cmds {
+		if functionName(cmd) != "toYaml" {
+			continue
+		}
+		// The argument is either given to toYaml, or piped from the previous
+		// command.
+		var arg parse.Node
+		switch {
+		case len(cmd.Args) == 2:
+			arg = cmd.Args[1]
+		case len(cmd.Args) == 1 && i > 0 && len(n.Pipe.Cmds[i-1].Args) == 1:
+			arg = n.Pipe.Cmds[i-1].Args[0]
+		default:
+			continue
+		}
+		p := valuePath(arg, scope)
+		if p == "" || !strings.HasPrefix(p, "Values.") || guarded(p, scope.guards) {
+			continue
+		}
+		if lookupValue(c.values, p) == nil {
+			return fmt.Errorf("%s renders \"null\", as .%s is null: test it with if or with, or give it a default", n, p)
+		}
+	}
+	return nil
+}
+
+// guarded reports whether the value path p is tested by one of the guards,
+// which are then true only when p is not null.
+func guarded(p string, guards []string) bool {
+	for _, g := range guards {
+		if g == p || strings.HasPrefix(g, p+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePaths returns the paths of the values referenced by the pipeline.
+func valuePaths(pipe *parse.PipeNode, scope checkScope) []string {
+	var paths []string
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			if p := valuePath(arg, scope); p != "" {
+				paths = append(paths, p)
+			}
+		}
+	}
+	return paths
+}
+
+// valuePath returns the path of the top-level data referenced by the node,
+// such as Values.image.tag for .Values.image.tag or $.Values.image.tag, or ""
+// when it is not such a reference.
+func valuePath(n parse.Node, scope checkScope) string {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		if scope.dotIsRoot {
+			return strings.Join(n.Ident, ".")
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			return strings.Join(n.Ident[1:], ".")
+		}
+	case *parse.PipeNode:
+		if len(n.Cmds) == 1 && len(n.Cmds[0].Args) == 1 {
+			return valuePath(n.Cmds[0].Args[0], scope)
+		}
+	}
+	return ""
+}
+
+// lookupValue returns the value at the path p of the render values.
+func lookupValue(values common.Values, p string) interface{} {
+	var v interface{} = map[string]interface{}(values)
+	for _, k := range strings.Split(p, ".") {
+		switch m := v.(type) {
+		case common.Values:
+			v = m[k]
+		case map[string]interface{}:
+			v = m[k]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// isAssignment reports whether the pipeline declares or assigns variables,
+// which has no output.
+func isAssignment(pipe *parse.PipeNode) bool {
+	return pipe == nil || len(pipe.Decl) > 0
+}
+
+// lastFunction returns the name of the function of the last command of the
+// pipeline.
+func lastFunction(pipe *parse.PipeNode) string {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return ""
+	}
+	return functionName(pipe.Cmds[len(pipe.Cmds)-1])
+}
+
+// functionName returns the name of the function called by the command, or ""
+// when it does not call a function.
+func functionName(cmd *parse.CommandNode) string {
+	if len(cmd.Args) == 0 {
+		return ""
+	}
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		return id.Ident
+	}
+	return ""
+}
+
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// portFields are the fields of Kubernetes objects holding port numbers.
+var portFields = []string{"containerPort", "hostPort", "nodePort", "port"}
+
+// validateQuoting returns an error for each label, annotation, environment
+// variable and ConfigMap data item of the object that is not a string, and
+// for each port that is a quoted number.
+func validateQuoting(obj map[string]interface{}) []error {
+	kind, _ := obj["kind"].(string)
+	var name string
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+	}
+	var errs []error
+	report := func(field, problem string) {
+		errs = append(errs, fmt.Errorf("%s %q: %s %s", kind, name, field, problem))
+	}
+	notString := func(field string, v interface{}) {
+		switch v.(type) {
+		case string, nil:
+		default:
+			report(field, fmt.Sprintf("is %v, which is not a string: quote it", v))
+		}
+	}
+
+	var walk func(v interface{}, field string)
+	walk = func(v interface{}, field string) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for _, k := range sortedKeys(v) {
+				child := k
+				if field != "" {
+					child = field + "." + k
+				}
+				switch {
+				case (k == "labels" || k == "annotations") && strings.HasSuffix(field, "metadata"):
+					if m, ok := v[k].(map[string]interface{}); ok {
+						for _, mk := range sortedKeys(m) {
+							notString(child+"."+mk, m[mk])
+						}
+						continue
+					}
+				case k == "env":
+					if list, ok := v[k].([]interface{}); ok {
+						for i, item := range list {
+							if env, ok := item.(map[string]interface{}); ok {
+								if value, ok := env["value"]; ok {
+									notString(fmt.Sprintf("%s[%d].value", child, i), value)
+								}
+							}
+						}
+						continue
+					}
+				case slices.Contains(portFields, k):
+					if s, ok := v[k].(string); ok {
+						if _, err := strconv.Atoi(s); err == nil {
+							report(child, fmt.Sprintf("is the string %q, which is not a number: do not quote it", s))
+						}
+						continue
+					}
+				}
+				walk(v[k], child)
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(item, fmt.Sprintf("%s[%d]", field, i))
+			}
+		}
+	}
+
+	if kind == "ConfigMap" {
+		if data, ok := obj["data"].(map[string]interface{}); ok {
+			for _, k := range sortedKeys(data) {
+				notString("data."+k, data[k])
+			}
+		}
+	}
+	walk(obj, "")
+	return errs
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
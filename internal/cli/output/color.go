@@ -17,8 +17,16 @@ limitations under the License.
 package output
 
 import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
 	"github.com/fatih/color"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/term"
 
+	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
@@ -44,6 +52,24 @@ func ColorizeStatus(status release.Status, noColor bool) string {
 	}
 }
 
+// ColorizeResourceStatus returns a colorized version of the kstatus status of a resource
+func ColorizeResourceStatus(status string, noColor bool) string {
+	if noColor {
+		return status
+	}
+
+	switch status {
+	case "Current":
+		return color.GreenString(status)
+	case "Failed", "NotFound":
+		return color.RedString(status)
+	case "InProgress", "Terminating":
+		return color.YellowString(status)
+	default:
+		return status
+	}
+}
+
 // ColorizeHeader returns a colorized version of a header string
 func ColorizeHeader(header string, noColor bool) string {
 	// Disable color if requested
@@ -65,3 +91,178 @@ func ColorizeNamespace(namespace string, noColor bool) string {
 	// Use cyan for namespaces
 	return color.CyanString(namespace)
 }
+
+// ColorizeLintMessage returns a lint message with its severity colorized
+func ColorizeLintMessage(msg support.Message, noColor bool) string {
+	text := msg.Error()
+	if noColor {
+		return text
+	}
+
+	severity, rest, _ := strings.Cut(text, " ")
+	switch msg.Severity {
+	case support.ErrorSev:
+		severity = color.RedString(severity)
+	case support.WarningSev:
+		severity = color.YellowString(severity)
+	case support.InfoSev:
+		severity = color.CyanString(severity)
+	}
+	return severity + " " + rest
+}
+
+// ColorizeLintResult returns a colorized version of the outcome of linting a
+// chart: OK, WARNINGS or FAILED
+func ColorizeLintResult(result string, noColor bool) string {
+	if noColor {
+		return result
+	}
+
+	switch result {
+	case "OK":
+		return color.GreenString(result)
+	case "WARNINGS":
+		return color.YellowString(result)
+	case "FAILED":
+		return color.RedString(result)
+	default:
+		return result
+	}
+}
+
+// ColorizeDiff returns a colorized version of a unified diff. Added lines are
+// green, removed lines red and hunk headers cyan.
+func ColorizeDiff(diff string, noColor bool) string {
+	if noColor || diff == "" {
+		return diff
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+	var b strings.Builder
+	b.Grow(len(diff))
+	for _, line := range lines {
+		content := strings.TrimSuffix(line, "\n")
+		newline := line[len(content):]
+		switch {
+		case strings.HasPrefix(content, "+++"), strings.HasPrefix(content, "---"):
+			b.WriteString(color.New(color.Bold).Sprint(content))
+		case strings.HasPrefix(content, "+"):
+			b.WriteString(color.GreenString(content))
+		case strings.HasPrefix(content, "-"):
+			b.WriteString(color.RedString(content))
+		case strings.HasPrefix(content, "@@"):
+			b.WriteString(color.CyanString(content))
+		default:
+			b.WriteString(content)
+		}
+		b.WriteString(newline)
+	}
+	return b.String()
+}
+
+// ColorizeWordDiff returns a colorized version of a unified diff like
+// ColorizeDiff, also highlighting the words that changed in each line replaced
+// by another, when a block of removed lines is followed by as many added lines.
+func ColorizeWordDiff(diff string, noColor bool) string {
+	if noColor || diff == "" {
+		return diff
+	}
+
+	lines := strings.SplitAfter(diff, "\n")
+	var b strings.Builder
+	b.Grow(len(diff))
+	for i := 0; i < len(lines); {
+		removed := countDiffLines(lines[i:], "-", "---")
+		added := countDiffLines(lines[i+removed:], "+", "+++")
+		if removed == 0 || removed != added {
+			b.WriteString(ColorizeDiff(lines[i], false))
+			i++
+			continue
+		}
+		var olds, news strings.Builder
+		for j := range removed {
+			oldLine, newLine := highlightWords(lines[i+j], lines[i+removed+j])
+			olds.WriteString(oldLine)
+			news.WriteString(newLine)
+		}
+		b.WriteString(olds.String())
+		b.WriteString(news.String())
+		i += removed + added
+	}
+	return b.String()
+}
+
+// countDiffLines counts the lines at the start of lines with a prefix, other
+// than the file headers.
+func countDiffLines(lines []string, prefix, header string) int {
+	n := 0
+	for _, line := range lines {
+		if !strings.HasPrefix(line, prefix) || strings.HasPrefix(line, header) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// highlightWords colors a removed line and the line added in its place,
+// reversing the colors of the words that differ.
+func highlightWords(oldLine, newLine string) (string, string) {
+	oldContent := strings.TrimSuffix(oldLine, "\n")
+	newContent := strings.TrimSuffix(newLine, "\n")
+	oldWords, newWords := splitWords(oldContent[1:]), splitWords(newContent[1:])
+
+	red, green := color.New(color.FgRed), color.New(color.FgGreen)
+	redChanged, greenChanged := color.New(color.FgRed, color.ReverseVideo), color.New(color.FgGreen, color.ReverseVideo)
+	var o, n strings.Builder
+	o.WriteString(red.Sprint("-"))
+	n.WriteString(green.Sprint("+"))
+	for _, op := range difflib.NewMatcher(oldWords, newWords).GetOpCodes() {
+		oldPart, newPart := strings.Join(oldWords[op.I1:op.I2], ""), strings.Join(newWords[op.J1:op.J2], "")
+		if op.Tag == 'e' {
+			o.WriteString(red.Sprint(oldPart))
+			n.WriteString(green.Sprint(newPart))
+			continue
+		}
+		if oldPart != "" {
+			o.WriteString(redChanged.Sprint(oldPart))
+		}
+		if newPart != "" {
+			n.WriteString(greenChanged.Sprint(newPart))
+		}
+	}
+	return o.String() + oldLine[len(oldContent):], n.String() + newLine[len(newContent):]
+}
+
+// splitWords splits a line into words, runs of spaces, and single other
+// characters, such as punctuation.
+func splitWords(s string) []string {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	var words []string
+	start, last := 0, rune(0)
+	for i, r := range s {
+		if i > start && !(isWord(last) && isWord(r)) && !(unicode.IsSpace(last) && unicode.IsSpace(r)) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		last = r
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// IsTerminal reports whether w writes to a terminal. Colors are only used in
+// auto mode when the output is a terminal, so scripts reading the output of
+// Helm get plain text.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -17,9 +17,14 @@ limitations under the License.
 package output
 
 import (
+	"bytes"
+	"errors"
 	"strings"
 	"testing"
 
+	"github.com/fatih/color"
+
+	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
@@ -189,3 +194,96 @@ func TestColorizeNamespace(t *testing.T) {
 		})
 	}
 }
+
+// forceColor enables colors for the duration of the test, regardless of
+// whether the tests run in a terminal.
+func forceColor(t *testing.T) {
+	t.Helper()
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+}
+
+func TestColorizeLintMessage(t *testing.T) {
+	forceColor(t)
+
+	msg := support.NewMessage(support.ErrorSev, "templates/", errors.New("parse error"))
+	if got := ColorizeLintMessage(msg, true); got != "[ERROR] templates/: parse error" {
+		t.Errorf("ColorizeLintMessage() = %q, want plain message", got)
+	}
+
+	got := ColorizeLintMessage(msg, false)
+	if want := color.RedString("[ERROR]") + " templates/: parse error"; got != want {
+		t.Errorf("ColorizeLintMessage() = %q, want %q", got, want)
+	}
+
+	msg = support.NewMessage(support.UnknownSev, "Chart.yaml", errors.New("odd"))
+	if got := ColorizeLintMessage(msg, false); got != "[UNKNOWN] Chart.yaml: odd" {
+		t.Errorf("ColorizeLintMessage() = %q, want unknown severity uncolored", got)
+	}
+}
+
+func TestColorizeLintResult(t *testing.T) {
+	forceColor(t)
+
+	tests := map[string]string{
+		"OK":       color.GreenString("OK"),
+		"WARNINGS": color.YellowString("WARNINGS"),
+		"FAILED":   color.RedString("FAILED"),
+		"SKIPPED":  "SKIPPED",
+	}
+	for result, want := range tests {
+		if got := ColorizeLintResult(result, false); got != want {
+			t.Errorf("ColorizeLintResult(%q) = %q, want %q", result, got, want)
+		}
+		if got := ColorizeLintResult(result, true); got != result {
+			t.Errorf("ColorizeLintResult(%q) with no color = %q", result, got)
+		}
+	}
+}
+
+func TestColorizeDiff(t *testing.T) {
+	forceColor(t)
+
+	diff := "--- a\n+++ b\n@@ -1 +1 @@\n-old\n+new\n same\n"
+	if got := ColorizeDiff(diff, true); got != diff {
+		t.Errorf("ColorizeDiff() with no color = %q, want %q", got, diff)
+	}
+
+	want := color.New(color.Bold).Sprint("--- a") + "\n" +
+		color.New(color.Bold).Sprint("+++ b") + "\n" +
+		color.CyanString("@@ -1 +1 @@") + "\n" +
+		color.RedString("-old") + "\n" +
+		color.GreenString("+new") + "\n" +
+		" same\n"
+	if got := ColorizeDiff(diff, false); got != want {
+		t.Errorf("ColorizeDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestColorizeWordDiff(t *testing.T) {
+	forceColor(t)
+
+	diff := "--- a\n+++ b\n@@ -1,3 +1,2 @@\n-image: nginx:1.25\n+image: nginx:1.26\n-gone\n same\n"
+	if got := ColorizeWordDiff(diff, true); got != diff {
+		t.Errorf("ColorizeWordDiff() with no color = %q, want %q", got, diff)
+	}
+
+	red, green := color.New(color.FgRed), color.New(color.FgGreen)
+	want := color.New(color.Bold).Sprint("--- a") + "\n" +
+		color.New(color.Bold).Sprint("+++ b") + "\n" +
+		color.CyanString("@@ -1,3 +1,2 @@") + "\n" +
+		red.Sprint("-") + red.Sprint("image: nginx:1.") + color.New(color.FgRed, color.ReverseVideo).Sprint("25") + "\n" +
+		green.Sprint("+") + green.Sprint("image: nginx:1.") + color.New(color.FgGreen, color.ReverseVideo).Sprint("26") + "\n" +
+		color.RedString("-gone") + "\n" +
+		" same\n"
+	if got := ColorizeWordDiff(diff, false); got != want {
+		t.Errorf("ColorizeWordDiff() = %q, want %q", got, want)
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	if IsTerminal(&bytes.Buffer{}) {
+		t.Error("IsTerminal() = true for a buffer")
+	}
+}
@@ -35,6 +35,7 @@ func AtomicWriteFile(filename string, reader io.Reader, mode os.FileMode) error
 
 	if _, err := io.Copy(tempFile, reader); err != nil {
 		tempFile.Close() // return value is ignored as we are already on error path
+		os.Remove(tempName)
 		return err
 	}
 
@@ -0,0 +1,267 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package gitrepo reads the charts of dependencies kept in git repositories.
+
+The repository of such a dependency is the URL of the git repository prefixed
+with "git+", such as git+https://github.com/example/charts.git. The chart is
+looked up at the root of the repository, in charts/<name> and in <name>, or in
+the directory following a double slash in the URL:
+
+	git+https://github.com/example/charts.git//stable
+
+The tags of the repository are the versions of the chart. They are either
+semantic versions, such as v1.2.0, or the name of the chart followed by a
+semantic version, such as mychart-1.2.0, as in repositories holding several
+charts. A ref query parameter pins the dependency to a branch or a commit
+instead, and the version of the chart is the one of its Chart.yaml:
+
+	git+https://github.com/example/charts.git?ref=main
+*/
+package gitrepo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	stdfs "io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/Masterminds/vcs"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+)
+
+// Prefix is the prefix of the URLs of git repositories.
+const Prefix = "git+"
+
+// ErrNotCached is returned when opening a repository offline that was never
+// cloned.
+var ErrNotCached = errors.New("the git repository is not cached")
+
+// IsGit reports whether the repository of a dependency is a git repository.
+func IsGit(repository string) bool {
+	return strings.HasPrefix(repository, Prefix)
+}
+
+// Source is the location of a chart in a git repository.
+type Source struct {
+	// Remote is the URL of the git repository, without the git+ prefix.
+	Remote string
+	// Path is the directory of the repository to look for the chart in. The
+	// root of the repository when empty.
+	Path string
+	// Ref is the branch or commit the dependency is pinned to. The tags are
+	// the versions of the chart when empty.
+	Ref string
+}
+
+// Parse parses the repository of a dependency kept in a git repository.
+func Parse(repository string) (*Source, error) {
+	if !IsGit(repository) {
+		return nil, fmt.Errorf("%s is not a git repository", repository)
+	}
+	u, err := url.Parse(strings.TrimPrefix(repository, Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid git repository %s: %w", repository, err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("invalid git repository %s: missing the scheme, such as git+https://", repository)
+	}
+	s := &Source{Ref: u.Query().Get("ref")}
+	u.RawQuery = ""
+	u.Fragment = ""
+	if repoPath, chartPath, ok := strings.Cut(u.Path, "//"); ok {
+		u.Path = repoPath
+		s.Path = path.Clean(strings.Trim(chartPath, "/"))
+		if s.Path == "." {
+			s.Path = ""
+		}
+		if !filepath.IsLocal(filepath.FromSlash(s.Path)) && s.Path != "" {
+			return nil, fmt.Errorf("invalid git repository %s: the chart path %s is outside of the repository", repository, s.Path)
+		}
+	}
+	s.Remote = u.String()
+	return s, nil
+}
+
+// Version is a version of a chart in a git repository.
+type Version struct {
+	// Version is the version of the chart.
+	Version *semver.Version
+	// Revision is the tag, branch or commit holding the version.
+	Revision string
+}
+
+// Repo is a clone of a git repository in the cache.
+type Repo struct {
+	source *Source
+	repo   *vcs.GitRepo
+}
+
+// Open opens the clone of the repository of source in cacheDir, cloning it
+// when it is not cached yet and fetching it otherwise. Offline, the clone is
+// used as it is, and ErrNotCached is returned when there is none.
+func Open(source *Source, cacheDir string, offline bool) (*Repo, error) {
+	sum := sha256.Sum256([]byte(source.Remote))
+	local := filepath.Join(cacheDir, "git", hex.EncodeToString(sum[:]))
+	_, statErr := os.Stat(local)
+	if statErr != nil && !errors.Is(statErr, stdfs.ErrNotExist) {
+		return nil, statErr
+	}
+	cached := statErr == nil
+	if !cached && offline {
+		return nil, fmt.Errorf("%w: %s", ErrNotCached, source.Remote)
+	}
+
+	repo, err := vcs.NewGitRepo(source.Remote, local)
+	if err != nil {
+		return nil, fmt.Errorf("could not open git repository %s: %w", source.Remote, err)
+	}
+	switch {
+	case !cached:
+		if err := repo.Get(); err != nil {
+			return nil, fmt.Errorf("could not clone git repository %s: %w", source.Remote, err)
+		}
+	case !offline:
+		// Fetching is enough: the revisions are always checked out detached.
+		if out, err := repo.RunFromDir("git", "fetch", "--tags", "--force", "--prune", "--", source.Remote, "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			return nil, fmt.Errorf("could not fetch git repository %s: %w: %s", source.Remote, err, out)
+		}
+	}
+	return &Repo{source: source, repo: repo}, nil
+}
+
+// Versions returns the versions of the chart name, newest first. When the
+// source is pinned to a ref, this is the version the ref holds.
+func (r *Repo) Versions(name string) ([]Version, error) {
+	if r.source.Ref != "" {
+		if err := r.Checkout(r.source.Ref); err != nil {
+			return nil, err
+		}
+		ch, err := r.LoadChart(name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := semver.NewVersion(ch.Metadata.Version)
+		if err != nil {
+			return nil, fmt.Errorf("chart %s at %s of %s has an invalid version: %w", name, r.source.Ref, r.source.Remote, err)
+		}
+		return []Version{{Version: v, Revision: r.source.Ref}}, nil
+	}
+
+	tags, err := r.repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the tags of git repository %s: %w", r.source.Remote, err)
+	}
+	var versions []Version
+	for _, tag := range tags {
+		if v, ok := tagVersion(tag, name); ok {
+			versions = append(versions, Version{Version: v, Revision: tag})
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].Version.GreaterThan(versions[j].Version)
+	})
+	return versions, nil
+}
+
+// tagVersion returns the version of the chart name a tag stands for.
+func tagVersion(tag, name string) (*semver.Version, bool) {
+	if v, ok := strings.CutPrefix(tag, name+"-"); ok {
+		tag = v
+	}
+	v, err := semver.NewVersion(tag)
+	return v, err == nil
+}
+
+// Checkout checks out a tag, branch or commit of the repository.
+func (r *Repo) Checkout(revision string) error {
+	// Branches are checked out as fetched from the remote.
+	rev := revision
+	if _, err := r.repo.RunFromDir("git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+revision); err == nil {
+		rev = "refs/remotes/origin/" + revision
+	}
+	if out, err := r.repo.RunFromDir("git", "checkout", "--quiet", "--force", "--detach", rev, "--"); err != nil {
+		return fmt.Errorf("could not check out %s of git repository %s: %w: %s", revision, r.source.Remote, err, out)
+	}
+	return nil
+}
+
+// LoadChart loads the chart name from the checked out revision.
+func (r *Repo) LoadChart(name string) (*chart.Chart, error) {
+	var candidates []string
+	if r.source.Path != "" {
+		candidates = []string{r.source.Path, path.Join(r.source.Path, name)}
+	} else {
+		candidates = []string{".", path.Join("charts", name), name}
+	}
+	for _, c := range candidates {
+		dir := filepath.Join(r.repo.LocalPath(), filepath.FromSlash(c))
+		if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err != nil {
+			continue
+		}
+		ch, err := loader.LoadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("could not load chart %s from git repository %s: %w", name, r.source.Remote, err)
+		}
+		if ch.Name() == name {
+			return ch, nil
+		}
+	}
+	return nil, fmt.Errorf("chart %s not found in git repository %s (looked in %s)", name, r.source.Remote, strings.Join(candidates, ", "))
+}
+
+// LoadVersion checks out the given version of the chart name and loads it.
+func (r *Repo) LoadVersion(name, version string) (*chart.Chart, error) {
+	want, err := semver.NewVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("dependency %s has an invalid version %s: %w", name, version, err)
+	}
+	versions, err := r.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if !v.Version.Equal(want) {
+			continue
+		}
+		if err := r.Checkout(v.Revision); err != nil {
+			return nil, err
+		}
+		ch, err := r.LoadChart(name)
+		if err != nil {
+			return nil, err
+		}
+		if got, err := semver.NewVersion(ch.Metadata.Version); err != nil || !got.Equal(want) {
+			return nil, fmt.Errorf("%s of git repository %s holds version %s of chart %s, not %s", v.Revision, r.source.Remote, ch.Metadata.Version, name, version)
+		}
+		return ch, nil
+	}
+	if r.source.Ref != "" {
+		return nil, fmt.Errorf("%s of git repository %s no longer holds version %s of chart %s: run 'helm dependency update'", r.source.Ref, r.source.Remote, version, name)
+	}
+	return nil, fmt.Errorf("version %s of chart %s not found in the tags of git repository %s", version, name, r.source.Remote)
+}
@@ -0,0 +1,158 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		repository string
+		expect     *Source
+		err        bool
+	}{
+		{
+			repository: "git+https://github.com/example/charts.git",
+			expect:     &Source{Remote: "https://github.com/example/charts.git"},
+		},
+		{
+			repository: "git+https://github.com/example/charts.git//stable/?ref=main",
+			expect:     &Source{Remote: "https://github.com/example/charts.git", Path: "stable", Ref: "main"},
+		},
+		{
+			repository: "git+ssh://git@example.com/charts//",
+			expect:     &Source{Remote: "ssh://git@example.com/charts"},
+		},
+		{repository: "https://github.com/example/charts.git", err: true},
+		{repository: "git+github.com/example/charts.git", err: true},
+		{repository: "git+https://github.com/example/charts.git//../secrets", err: true},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.repository)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.repository)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.repository, err)
+			continue
+		}
+		if !reflect.DeepEqual(s, tt.expect) {
+			t.Errorf("%s: expected %+v, got %+v", tt.repository, tt.expect, s)
+		}
+	}
+}
+
+// gitRun runs git in dir with a fixed identity.
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Helm", "-c", "user.email=helm@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %s: %s", args, err, out)
+	}
+}
+
+// commitChart commits version of a chart named name at chartPath of the git
+// repository in dir, tagging it when tag is not empty.
+func commitChart(t *testing.T, dir, chartPath, name, version, tag string) {
+	t.Helper()
+	chartDir := filepath.Join(dir, chartPath)
+	if err := os.MkdirAll(chartDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chartYAML := "apiVersion: v2\nname: " + name + "\nversion: " + version + "\n"
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(chartYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", name+" "+version)
+	if tag != "" {
+		gitRun(t, dir, "tag", tag)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := t.TempDir()
+	gitRun(t, remote, "init", "-q")
+	commitChart(t, remote, "charts/web", "web", "1.0.0", "web-1.0.0")
+	commitChart(t, remote, "charts/web", "web", "1.1.0", "v1.1.0")
+	commitChart(t, remote, "charts/db", "db", "2.0.0", "db-2.0.0")
+	commitChart(t, remote, "charts/web", "web", "1.2.0-dev", "")
+
+	cache := t.TempDir()
+	source := &Source{Remote: "file://" + filepath.ToSlash(remote)}
+	if _, err := Open(source, cache, true); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("expected ErrNotCached offline, got %v", err)
+	}
+	r, err := Open(source, cache, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := r.Versions("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range versions {
+		got = append(got, v.Version.String()+"@"+v.Revision)
+	}
+	if expect := []string{"1.1.0@v1.1.0", "1.0.0@web-1.0.0"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected versions %v, got %v", expect, got)
+	}
+
+	ch, err := r.LoadVersion("web", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Metadata.Version != "1.0.0" {
+		t.Errorf("expected version 1.0.0, got %s", ch.Metadata.Version)
+	}
+	if _, err := r.LoadVersion("web", "1.5.0"); err == nil {
+		t.Error("expected an error loading a missing version")
+	}
+
+	// A branch pins the chart to the version of its Chart.yaml.
+	source.Ref = "main"
+	source.Path = "charts"
+	r, err = Open(source, cache, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err = r.Versions("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Version.String() != "1.2.0-dev" {
+		t.Errorf("expected version 1.2.0-dev on main, got %v", versions)
+	}
+	if _, err := r.LoadChart("api"); err == nil {
+		t.Error("expected an error loading a missing chart")
+	}
+}
@@ -44,6 +44,21 @@ func TestUnmarshaConfig(t *testing.T) {
 		}, *(config.(*schema.ConfigCLIV1)))
 	}
 
+	// Test unmarshalling an event plugin config
+	{
+		config, err := unmarshaConfig("event/v1", map[string]any{
+			"events": []any{"pre-install", "post-upgrade"},
+		})
+		require.NoError(t, err)
+
+		require.IsType(t, &schema.ConfigEventV1{}, config)
+		assert.Equal(t, []string{"pre-install", "post-upgrade"}, config.(*schema.ConfigEventV1).Events)
+		assert.NoError(t, config.Validate())
+
+		config.(*schema.ConfigEventV1).Events = []string{"pre-everything"}
+		assert.ErrorContains(t, config.Validate(), `unknown event "pre-everything"`)
+	}
+
 	// Test unmarshalling invalid config data
 	{
 		config, err := unmarshaConfig("cli/v1", map[string]any{
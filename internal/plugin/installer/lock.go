@@ -0,0 +1,156 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	stdfs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LockFileName is the name of the file recording the installed plugins in
+// the plugins directory.
+const LockFileName = "plugins.lock"
+
+// Lock records the versions and digests of the installed plugins, so a
+// plugin toolchain can be reproduced on another machine.
+type Lock struct {
+	// Generated is when the lock was last written.
+	Generated time.Time `json:"generated"`
+	// Plugins are the locked plugins, sorted by name.
+	Plugins []*LockedPlugin `json:"plugins"`
+}
+
+// LockedPlugin is an installed plugin recorded in a Lock.
+type LockedPlugin struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+	// Version is the installed version of the plugin.
+	Version string `json:"version,omitempty"`
+	// Source is where the plugin was installed from.
+	Source string `json:"source,omitempty"`
+	// Constraint is the version constraint the plugin was installed or
+	// updated with. Updates without a constraint honor it.
+	Constraint string `json:"constraint,omitempty"`
+	// Digest is the digest of the installed files of the plugin.
+	Digest string `json:"digest"`
+}
+
+// LoadLock reads a plugins lock. A missing lock loads as an empty one.
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, stdfs.ErrNotExist) {
+		return &Lock{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// Save writes the lock to path.
+func (l *Lock) Save(path string) error {
+	l.Generated = time.Now()
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Get returns the locked plugin with the given name, or nil.
+func (l *Lock) Get(name string) *LockedPlugin {
+	for _, p := range l.Plugins {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Set adds a plugin to the lock, replacing the entry of the same name.
+func (l *Lock) Set(p *LockedPlugin) {
+	l.Remove(p.Name)
+	l.Plugins = append(l.Plugins, p)
+	slices.SortFunc(l.Plugins, func(a, b *LockedPlugin) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// Remove removes the plugin with the given name from the lock.
+func (l *Lock) Remove(name string) {
+	l.Plugins = slices.DeleteFunc(l.Plugins, func(p *LockedPlugin) bool {
+		return p.Name == name
+	})
+}
+
+// DigestDir returns the digest of the files of an installed plugin. VCS
+// metadata is left out, so a plugin has the same digest whichever way it was
+// installed.
+func DigestDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d stdfs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		h.Write([]byte{0})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
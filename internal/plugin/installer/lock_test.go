@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+
+	lock, err := LoadLock(path)
+	require.NoError(t, err)
+	assert.Empty(t, lock.Plugins)
+
+	lock.Set(&LockedPlugin{Name: "zeta", Version: "1.0.0", Digest: "sha256:1"})
+	lock.Set(&LockedPlugin{Name: "alpha", Version: "0.1.0", Constraint: "~0.1.0", Digest: "sha256:2"})
+	lock.Set(&LockedPlugin{Name: "zeta", Version: "1.1.0", Digest: "sha256:3"})
+	require.NoError(t, lock.Save(path))
+
+	loaded, err := LoadLock(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Plugins, 2)
+	assert.Equal(t, "alpha", loaded.Plugins[0].Name)
+	assert.Equal(t, "~0.1.0", loaded.Get("alpha").Constraint)
+	assert.Equal(t, "1.1.0", loaded.Get("zeta").Version)
+	assert.False(t, loaded.Generated.IsZero())
+
+	loaded.Remove("alpha")
+	assert.Nil(t, loaded.Get("alpha"))
+	assert.Len(t, loaded.Plugins, 1)
+}
+
+func TestLoadLockInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockFileName)
+	require.NoError(t, os.WriteFile(path, []byte("plugins: {"), 0644))
+
+	_, err := LoadLock(path)
+	assert.ErrorContains(t, err, "failed to parse")
+}
+
+func TestDigestDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plugin.yaml"), []byte("name: echo\n"), 0644))
+
+	digest, err := DigestDir(dir)
+	require.NoError(t, err)
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, digest)
+
+	// VCS metadata does not change the digest.
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644))
+	withGit, err := DigestDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, digest, withGit)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "plugin.yaml"), []byte("name: echo2\n"), 0644))
+	changed, err := DigestDir(dir)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+}
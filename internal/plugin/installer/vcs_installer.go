@@ -95,7 +95,8 @@ func (i *VCSInstaller) Install() error {
 	return fs.CopyDir(i.Repo.LocalPath(), i.Path())
 }
 
-// Update updates a remote repository
+// Update updates a remote repository. When Version is set, the plugin is
+// moved to the latest tag matching it instead.
 func (i *VCSInstaller) Update() error {
 	slog.Debug("updating", "source", i.Repo.Remote())
 	if i.Repo.IsDirty() {
@@ -104,6 +105,15 @@ func (i *VCSInstaller) Update() error {
 	if err := i.Repo.Update(); err != nil {
 		return err
 	}
+	ref, err := i.solveVersion(i.Repo)
+	if err != nil {
+		return err
+	}
+	if ref != "" {
+		if err := i.setVersion(i.Repo, ref); err != nil {
+			return err
+		}
+	}
 	if !isPlugin(i.Repo.LocalPath()) {
 		return ErrMissingMetadata
 	}
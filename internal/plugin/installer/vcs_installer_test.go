@@ -40,6 +40,7 @@ type testRepo struct {
 func (r *testRepo) LocalPath() string           { return r.local }
 func (r *testRepo) Remote() string              { return r.remote }
 func (r *testRepo) Update() error               { return r.err }
+func (r *testRepo) IsDirty() bool               { return false }
 func (r *testRepo) Get() error                  { return r.err }
 func (r *testRepo) IsReference(string) bool     { return false }
 func (r *testRepo) Tags() ([]string, error)     { return r.tags, r.err }
@@ -187,3 +188,26 @@ func TestVCSInstallerUpdate(t *testing.T) {
 	}
 
 }
+
+func TestVCSInstallerUpdateToVersion(t *testing.T) {
+	ensure.HelmHome(t)
+
+	testRepoPath, _ := filepath.Abs("../testdata/plugdir/good/echo-v1")
+	repo := &testRepo{
+		local: testRepoPath,
+		tags:  []string{"0.1.0", "0.1.1", "0.2.0", "1.0.0"},
+	}
+	i := &VCSInstaller{Repo: repo, Version: "~0.1.0"}
+
+	if err := i.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if repo.current != "0.1.1" {
+		t.Fatalf("expected version '0.1.1', got %q", repo.current)
+	}
+
+	i.Version = ">2.0.0"
+	if err := i.Update(); err == nil {
+		t.Fatal("expected error for version does not exist, got none")
+	}
+}
@@ -95,6 +95,12 @@ var pluginTypes = []pluginTypeMeta{
 		outputType: reflect.TypeOf(schema.OutputMessagePostRendererV1{}),
 		configType: reflect.TypeOf(schema.ConfigPostRendererV1{}),
 	},
+	{
+		pluginType: "event/v1",
+		inputType:  reflect.TypeOf(schema.InputMessageEventV1{}),
+		outputType: reflect.TypeOf(schema.OutputMessageEventV1{}),
+		configType: reflect.TypeOf(schema.ConfigEventV1{}),
+	},
 }
 
 var pluginTypesIndex = func() map[string]*pluginTypeMeta {
@@ -18,6 +18,7 @@ package plugin
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
@@ -104,6 +105,8 @@ func (r *SubprocessPluginRuntime) Invoke(_ context.Context, input *Input) (*Outp
 		return r.runGetter(input)
 	case schema.InputMessagePostRendererV1:
 		return r.runPostrenderer(input)
+	case schema.InputMessageEventV1:
+		return r.runEvent(input)
 	default:
 		return nil, fmt.Errorf("unsupported subprocess plugin type %q", r.metadata.Type)
 	}
@@ -276,3 +279,44 @@ func (r *SubprocessPluginRuntime) runPostrenderer(input *Input) (*Output, error)
 		},
 	}, nil
 }
+
+func (r *SubprocessPluginRuntime) runEvent(input *Input) (*Output, error) {
+	msg, ok := input.Message.(schema.InputMessageEventV1)
+	if !ok {
+		return nil, fmt.Errorf("plugin %q input message does not implement InputMessageEventV1", r.metadata.Name)
+	}
+
+	env := parseEnv(os.Environ())
+	maps.Insert(env, maps.All(r.EnvVars))
+	maps.Insert(env, maps.All(parseEnv(input.Env)))
+	env["HELM_PLUGIN_NAME"] = r.metadata.Name
+	env["HELM_PLUGIN_DIR"] = r.pluginDir
+	env["HELM_EVENT"] = msg.Event
+
+	cmds := r.RuntimeConfig.PlatformCommand
+	command, args, err := PrepareCommands(cmds, true, []string{}, env)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare plugin command: %w", err)
+	}
+
+	// The event is passed to the plugin as JSON on stdin
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to json marshal plugin input message: %w", err)
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Env = formatEnv(env)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = input.Stdout
+	cmd.Stderr = input.Stderr
+
+	slog.Debug("executing plugin command", slog.String("pluginName", r.metadata.Name), slog.String("command", cmd.String()), slog.String("event", msg.Event))
+	if err := executeCmd(cmd, r.metadata.Name); err != nil {
+		return nil, err
+	}
+
+	return &Output{
+		Message: schema.OutputMessageEventV1{},
+	}, nil
+}
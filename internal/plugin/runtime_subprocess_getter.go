@@ -18,6 +18,7 @@ package plugin
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log/slog"
 	"maps"
 	"os"
@@ -78,13 +79,18 @@ func (r *SubprocessPluginRuntime) runGetter(input *Input) (*Output, error) {
 		msg.Href)
 
 	buf := bytes.Buffer{} // subprocess getters are expected to write content to stdout
+	stdout := io.Writer(&buf)
+	if input.Stdout != nil {
+		// stream the content to the caller instead
+		stdout = input.Stdout
+	}
 
 	pluginCommand := filepath.Join(r.pluginDir, command)
 	cmd := exec.Command(
 		pluginCommand,
 		args...)
 	cmd.Env = formatEnv(env)
-	cmd.Stdout = &buf
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 
 	slog.Debug("executing plugin command", slog.String("pluginName", r.metadata.Name), slog.String("command", cmd.String()))
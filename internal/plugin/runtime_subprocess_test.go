@@ -16,6 +16,7 @@ limitations under the License.
 package plugin
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -82,3 +83,35 @@ func TestSubprocessPluginRuntime(t *testing.T) {
 
 	assert.Nil(t, output)
 }
+
+func TestSubprocessPluginRuntimeEvent(t *testing.T) {
+	rc := RuntimeConfigSubprocess{
+		PlatformCommand: []PlatformCommand{
+			{Command: "sh", Args: []string{"-c", "printenv HELM_EVENT; cat"}},
+		},
+	}
+	p := &SubprocessPluginRuntime{
+		metadata: Metadata{
+			Name:       "notify",
+			Version:    "v0.1.0",
+			Type:       "event/v1",
+			APIVersion: "v1",
+			Runtime:    "subprocess",
+			Config:     &schema.ConfigEventV1{Events: []string{"post-install"}},
+		},
+		pluginDir:     t.TempDir(),
+		RuntimeConfig: rc,
+	}
+
+	stdout := &bytes.Buffer{}
+	output, err := p.Invoke(t.Context(), &Input{
+		Message: schema.InputMessageEventV1{
+			Event:   "post-install",
+			Release: schema.EventReleaseV1{Name: "foo", Namespace: "default", Revision: 1},
+		},
+		Stdout: stdout,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, schema.OutputMessageEventV1{}, output.Message)
+	assert.Equal(t, "post-install\n"+`{"event":"post-install","release":{"name":"foo","namespace":"default","revision":1}}`, stdout.String())
+}
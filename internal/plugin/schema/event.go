@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"slices"
+)
+
+// EventsV1 are the action lifecycle events event plugins can register for
+var EventsV1 = []string{
+	"pre-install",
+	"post-install",
+	"pre-upgrade",
+	"post-upgrade",
+	"pre-rollback",
+	"post-rollback",
+	"pre-uninstall",
+	"post-uninstall",
+	"post-render",
+}
+
+// EventReleaseV1 describes the release an event is about
+type EventReleaseV1 struct {
+	Name         string         `json:"name"`
+	Namespace    string         `json:"namespace"`
+	Revision     int            `json:"revision"`
+	Status       string         `json:"status,omitempty"`
+	Chart        string         `json:"chart,omitempty"`
+	ChartVersion string         `json:"chartVersion,omitempty"`
+	AppVersion   string         `json:"appVersion,omitempty"`
+	Values       map[string]any `json:"values,omitempty"`
+	Manifest     string         `json:"manifest,omitempty"`
+}
+
+// InputMessageEventV1 implements Input.Message
+type InputMessageEventV1 struct {
+	Event   string         `json:"event"`
+	Release EventReleaseV1 `json:"release"`
+}
+
+type OutputMessageEventV1 struct{}
+
+// ConfigEventV1 represents the configuration for event plugins
+type ConfigEventV1 struct {
+	// Events are the action lifecycle events the plugin is invoked on
+	Events []string `yaml:"events"`
+}
+
+func (c *ConfigEventV1) Validate() error {
+	if len(c.Events) == 0 {
+		return fmt.Errorf("event plugin has no events")
+	}
+	for _, event := range c.Events {
+		if !slices.Contains(EventsV1, event) {
+			return fmt.Errorf("event plugin has unknown event %q", event)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// Version aliases a dependency may use instead of a version range. A
+// repository index may redefine them as channels of a chart.
+const (
+	// VersionStable selects the newest release, leaving out pre-releases.
+	VersionStable = "stable"
+	// VersionLatest selects the newest version, pre-releases included.
+	VersionLatest = "latest"
+)
+
+// builtinChannels are the ranges of the version aliases.
+var builtinChannels = map[string]string{
+	VersionStable: ">=0.0.0",
+	VersionLatest: ">=0.0.0-0",
+}
+
+// Constraint is a constraint on the versions of a dependency.
+type Constraint interface {
+	// Check tells whether a version satisfies the constraint.
+	Check(v *semver.Version) bool
+}
+
+type constraintFunc func(v *semver.Version) bool
+
+func (f constraintFunc) Check(v *semver.Version) bool { return f(v) }
+
+// DependencyConstraint returns the constraint on the versions of a
+// dependency. Its version may be a semantic version range or a version alias,
+// and its channel, if any, further restricts the versions.
+//
+// channels maps the channels the repository index defines for the chart to
+// version ranges, and may be nil. A channel the index does not define, other
+// than the version aliases, is a pre-release channel: "beta" matches the
+// releases and the pre-releases whose first identifier is "beta", such as
+// 2.0.0-beta.1.
+func DependencyConstraint(d *chart.Dependency, channels map[string]string) (Constraint, error) {
+	var constraints []Constraint
+	if d.Version != "" || d.Channel == "" {
+		c, err := versionConstraint(d.Version, channels)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	if d.Channel != "" {
+		c, err := channelConstraint(d.Channel, channels)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	if len(constraints) == 1 {
+		return constraints[0], nil
+	}
+	return constraintFunc(func(v *semver.Version) bool {
+		for _, c := range constraints {
+			if !c.Check(v) {
+				return false
+			}
+		}
+		return true
+	}), nil
+}
+
+func versionConstraint(version string, channels map[string]string) (Constraint, error) {
+	if version == VersionStable || version == VersionLatest {
+		return channelConstraint(version, channels)
+	}
+	return semver.NewConstraint(version)
+}
+
+func channelConstraint(channel string, channels map[string]string) (Constraint, error) {
+	if r, ok := channels[channel]; ok {
+		c, err := semver.NewConstraint(r)
+		if err != nil {
+			return nil, fmt.Errorf("channel %q of the repository index has an invalid version range: %w", channel, err)
+		}
+		return c, nil
+	}
+	if r, ok := builtinChannels[channel]; ok {
+		return semver.NewConstraint(r)
+	}
+	return constraintFunc(func(v *semver.Version) bool {
+		if v.Prerelease() == "" {
+			return true
+		}
+		first, _, _ := strings.Cut(v.Prerelease(), ".")
+		return strings.EqualFold(first, channel)
+	}), nil
+}
+
+// describeConstraint describes the version constraint of a dependency.
+func describeConstraint(d *chart.Dependency) string {
+	switch {
+	case d.Channel == "":
+		return d.Version
+	case d.Version == "":
+		return "channel " + d.Channel
+	default:
+		return fmt.Sprintf("%s (channel %s)", d.Version, d.Channel)
+	}
+}
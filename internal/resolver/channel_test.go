@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/helmpath"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+func TestDependencyConstraint(t *testing.T) {
+	channels := map[string]string{
+		"stable": "~1.2.0",
+		"beta":   ">=2.0.0-beta.0 <3.0.0",
+	}
+	tests := []struct {
+		name     string
+		dep      *chart.Dependency
+		channels map[string]string
+		match    []string
+		nomatch  []string
+		err      bool
+	}{
+		{
+			name:    "version range",
+			dep:     &chart.Dependency{Version: "^1.0.0"},
+			match:   []string{"1.0.0", "1.9.0"},
+			nomatch: []string{"2.0.0", "1.1.0-beta.1"},
+		},
+		{
+			name:    "stable alias",
+			dep:     &chart.Dependency{Version: "stable"},
+			match:   []string{"0.1.0", "3.0.0"},
+			nomatch: []string{"3.1.0-rc.1"},
+		},
+		{
+			name:  "latest alias",
+			dep:   &chart.Dependency{Version: "latest"},
+			match: []string{"0.1.0", "3.1.0-rc.1"},
+		},
+		{
+			name:     "stable alias defined by the index",
+			dep:      &chart.Dependency{Version: "stable"},
+			channels: channels,
+			match:    []string{"1.2.0", "1.2.9"},
+			nomatch:  []string{"1.3.0", "2.0.0"},
+		},
+		{
+			name:     "channel defined by the index",
+			dep:      &chart.Dependency{Channel: "beta"},
+			channels: channels,
+			match:    []string{"2.0.0-beta.1", "2.1.0"},
+			nomatch:  []string{"1.2.0", "3.0.0"},
+		},
+		{
+			name:    "pre-release channel",
+			dep:     &chart.Dependency{Channel: "beta"},
+			match:   []string{"1.0.0", "2.0.0-beta.1", "2.0.0-BETA"},
+			nomatch: []string{"2.0.0-alpha.1", "2.0.0-rc.1"},
+		},
+		{
+			name:    "version range and channel",
+			dep:     &chart.Dependency{Version: ">=2.0.0-0", Channel: "rc"},
+			match:   []string{"2.0.0-rc.2", "2.0.0"},
+			nomatch: []string{"1.0.0", "2.0.0-beta.1"},
+		},
+		{
+			name: "invalid version range",
+			dep:  &chart.Dependency{Version: "not a range"},
+			err:  true,
+		},
+		{
+			name:     "invalid channel range in the index",
+			dep:      &chart.Dependency{Channel: "beta"},
+			channels: map[string]string{"beta": "not a range"},
+			err:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := DependencyConstraint(tt.dep, tt.channels)
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range tt.match {
+				if !c.Check(semver.MustParse(v)) {
+					t.Errorf("expected %s to match", v)
+				}
+			}
+			for _, v := range tt.nomatch {
+				if c.Check(semver.MustParse(v)) {
+					t.Errorf("expected %s not to match", v)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveChannels(t *testing.T) {
+	cachepath := t.TempDir()
+	index := repo.NewIndexFile()
+	for _, v := range []string{"1.2.0", "1.2.1", "1.3.0", "2.0.0-beta.1", "2.0.0-rc.1"} {
+		if err := index.MustAdd(&chart.Metadata{APIVersion: chart.APIVersionV2, Name: "alpine", Version: v}, "alpine-"+v+".tgz", "http://example.com", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index.SortEntries()
+	index.Channels = map[string]map[string]string{
+		"alpine": {"stable": "~1.2.0"},
+	}
+	if err := index.WriteFile(filepath.Join(cachepath, helmpath.CacheIndexFile("channels")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repoNames := map[string]string{"alpine": "channels"}
+
+	tests := []struct {
+		dep    *chart.Dependency
+		expect string
+	}{
+		{&chart.Dependency{Name: "alpine", Repository: "http://example.com", Version: "stable"}, "1.2.1"},
+		{&chart.Dependency{Name: "alpine", Repository: "http://example.com", Version: "latest"}, "2.0.0-rc.1"},
+		{&chart.Dependency{Name: "alpine", Repository: "http://example.com", Channel: "beta"}, "2.0.0-beta.1"},
+	}
+	r := New("testdata/chartpath", cachepath, nil)
+	for _, tt := range tests {
+		lock, err := r.Resolve([]*chart.Dependency{tt.dep}, repoNames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := lock.Dependencies[0].Version; v != tt.expect {
+			t.Errorf("expected %s to resolve to %s, got %s", describeConstraint(tt.dep), tt.expect, v)
+		}
+	}
+}
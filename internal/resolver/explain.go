@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// Explanation describes how the version of a dependency was chosen.
+type Explanation struct {
+	// Name is the name of the dependency.
+	Name string
+	// Repository is the repository of the dependency.
+	Repository string
+	// Constraint is the version or version range the dependency requires.
+	Constraint string
+	// Source describes where the candidate versions came from, such as the
+	// cached index of a repository or the tags of an OCI repository.
+	Source string
+	// Candidates are the versions considered, in the order they were
+	// considered. There are none when the version was not resolved from a
+	// list of versions.
+	Candidates []Candidate
+	// Version is the version locked, or empty if no candidate satisfied the
+	// constraint.
+	Version string
+}
+
+// Candidate is a version considered for a dependency.
+type Candidate struct {
+	// Version is the version of the candidate.
+	Version string
+	// Satisfies tells whether the version satisfies the constraint.
+	Satisfies bool
+	// Chosen tells whether the version was locked.
+	Chosen bool
+	// Skipped is why the candidate could not be used whatever the constraint,
+	// if it could not.
+	Skipped string
+}
+
+// ResolveExplain resolves dependencies like Resolve, and also explains how
+// the version of each dependency was chosen. The explanations are returned
+// along with any error, so that failures to resolve can be explained too.
+func (r *Resolver) ResolveExplain(reqs []*chart.Dependency, repoNames map[string]string) (*chart.Lock, []*Explanation, error) {
+	explanations := []*Explanation{}
+	lock, err := r.resolve(reqs, repoNames, &explanations)
+	return lock, explanations, err
+}
+
+// The methods below record the resolution of a dependency. They do nothing
+// on a nil Explanation, which is used when no explanation is asked for.
+
+func (e *Explanation) source(source string) {
+	if e != nil {
+		e.Source = source
+	}
+}
+
+func (e *Explanation) candidate(c Candidate) {
+	if e == nil {
+		return
+	}
+	e.Candidates = append(e.Candidates, c)
+	if c.Chosen {
+		e.Version = c.Version
+	}
+}
+
+func (e *Explanation) choose(version string) {
+	if e != nil {
+		e.Version = version
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestResolveExplain(t *testing.T) {
+	reqs := []*chart.Dependency{
+		{Name: "alpine", Repository: "http://example.com", Version: "<0.2.0"},
+		{Name: "base", Repository: "file://base", Version: "^0.1.0"},
+		{Name: "localdependency", Repository: "", Version: "0.1.0"},
+		{Name: "oedipus-rex", Repository: "http://example.com/uncached", Version: "1.0.0"},
+	}
+	repoNames := map[string]string{"alpine": "kubernetes-charts"}
+
+	r := New("testdata/chartpath", "testdata/repository", nil)
+	lock, explanations, err := r.ResolveExplain(reqs, repoNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Dependencies[0].Version != "0.1.0" {
+		t.Errorf("expected alpine 0.1.0 to be locked, got %s", lock.Dependencies[0].Version)
+	}
+
+	expect := []*Explanation{
+		{
+			Name:       "alpine",
+			Repository: "http://example.com",
+			Constraint: "<0.2.0",
+			Source:     "cached index of repository kubernetes-charts",
+			Candidates: []Candidate{
+				{Version: "0.2.0"},
+				{Version: "0.1.0", Satisfies: true, Chosen: true},
+			},
+			Version: "0.1.0",
+		},
+		{
+			Name:       "base",
+			Repository: "file://base",
+			Constraint: "^0.1.0",
+			Source:     "chart in the local directory " + filepath.Join("testdata/chartpath", "base"),
+			Candidates: []Candidate{{Version: "0.1.0", Satisfies: true, Chosen: true}},
+			Version:    "0.1.0",
+		},
+		{
+			Name:       "localdependency",
+			Constraint: "0.1.0",
+			Source:     "unpacked chart in the charts directory, checked against the constraint when building",
+			Version:    "0.1.0",
+		},
+		{
+			Name:       "oedipus-rex",
+			Repository: "http://example.com/uncached",
+			Constraint: "1.0.0",
+			Source:     "repository without a cached index, resolved when downloading",
+			Version:    "1.0.0",
+		},
+	}
+	if !reflect.DeepEqual(expect, explanations) {
+		for i := range explanations {
+			t.Logf("explanation %d: %+v", i, *explanations[i])
+		}
+		t.Error("unexpected explanations")
+	}
+
+	// Failures are explained too.
+	_, explanations, err = r.ResolveExplain([]*chart.Dependency{{Name: "alpine", Repository: "http://example.com", Version: ">=1.0.0"}}, repoNames)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(explanations) != 1 || explanations[0].Version != "" || len(explanations[0].Candidates) != 2 {
+		t.Errorf("unexpected explanations %+v", explanations)
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+
+	"helm.sh/helm/v4/internal/gitrepo"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// resolveGit returns the newest version of a dependency kept in a git
+// repository satisfying its constraint, or an empty string when there is none.
+func (r *Resolver) resolveGit(d *chart.Dependency, constraint Constraint, ex *Explanation) (string, error) {
+	source, err := gitrepo.Parse(d.Repository)
+	if err != nil {
+		return "", err
+	}
+	gr, err := gitrepo.Open(source, r.cachepath, r.offline)
+	if err != nil {
+		return "", err
+	}
+	versions, err := gr.Versions(d.Name)
+	if err != nil {
+		return "", err
+	}
+	if source.Ref != "" {
+		ex.source(fmt.Sprintf("chart at %s of git repository %s", source.Ref, source.Remote))
+	} else {
+		ex.source("tags of git repository " + source.Remote)
+	}
+
+	var chosen string
+	for _, v := range versions {
+		satisfies := constraint.Check(v.Version)
+		ex.candidate(Candidate{Version: v.Version.String(), Satisfies: satisfies, Chosen: satisfies && chosen == ""})
+		if satisfies && chosen == "" {
+			chosen = v.Version.String()
+			if ex == nil {
+				break
+			}
+		}
+	}
+	return chosen, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hasher is a hash algorithm for the digest of lock files.
+type Hasher interface {
+	// Name is the name of the algorithm, which prefixes the digests.
+	Name() string
+	// New returns a hash computing a digest.
+	New() hash.Hash
+}
+
+type hasher struct {
+	name string
+	new  func() hash.Hash
+}
+
+func (h hasher) Name() string   { return h.name }
+func (h hasher) New() hash.Hash { return h.new() }
+
+// NewHasher returns a Hasher for the named algorithm.
+func NewHasher(name string, newHash func() hash.Hash) Hasher {
+	return hasher{name: name, new: newHash}
+}
+
+// The hash algorithms available for lock files by default.
+var (
+	SHA256 = NewHasher("sha256", sha256.New)
+	SHA512 = NewHasher("sha512", sha512.New)
+)
+
+var (
+	hashersMu sync.RWMutex
+	hashers   = map[string]Hasher{
+		SHA256.Name(): SHA256,
+		SHA512.Name(): SHA512,
+	}
+)
+
+// RegisterHasher makes a hash algorithm available for lock files, such as
+// BLAKE3 from a third-party implementation. It replaces any algorithm
+// registered with the same name.
+func RegisterHasher(h Hasher) {
+	hashersMu.Lock()
+	defer hashersMu.Unlock()
+	hashers[h.Name()] = h
+}
+
+// LookupHasher returns the registered hash algorithm with the given name.
+func LookupHasher(name string) (Hasher, error) {
+	hashersMu.RLock()
+	defer hashersMu.RUnlock()
+	if h, ok := hashers[name]; ok {
+		return h, nil
+	}
+	names := make([]string, 0, len(hashers))
+	for n := range hashers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unsupported digest algorithm %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
+// DigestHasher returns the hash algorithm recorded in a digest such as
+// "sha256:...".
+func DigestHasher(digest string) (Hasher, error) {
+	name, _, ok := strings.Cut(digest, ":")
+	if !ok {
+		return nil, fmt.Errorf("digest %q does not record its algorithm", digest)
+	}
+	return LookupHasher(name)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestHashReqWith(t *testing.T) {
+	req := []*chart.Dependency{{Name: "alpine", Version: "^0.1.0", Repository: "http://example.com"}}
+	lock := []*chart.Dependency{{Name: "alpine", Version: "0.1.0", Repository: "http://example.com"}}
+
+	sha256Sum, err := HashReqWith(SHA256, req, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy, _ := HashReq(req, lock); legacy != sha256Sum {
+		t.Errorf("expected HashReq to use SHA256, got %s", legacy)
+	}
+
+	sha512Sum, err := HashReqWith(SHA512, req, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sha512Sum, "sha512:") || len(sha512Sum) != len("sha512:")+128 {
+		t.Errorf("unexpected SHA512 digest %s", sha512Sum)
+	}
+
+	for _, digest := range []string{sha256Sum, sha512Sum} {
+		if ok, err := VerifyReq(req, lock, digest); err != nil || !ok {
+			t.Errorf("expected %s to verify, got %t, %v", digest, ok, err)
+		}
+		if ok, err := VerifyReq(req, req, digest); err != nil || ok {
+			t.Errorf("expected %s not to verify other dependencies, got %t, %v", digest, ok, err)
+		}
+	}
+	if ok, err := VerifyReq(req, lock, strings.TrimPrefix(sha256Sum, "sha256:")); err != nil || ok {
+		t.Errorf("expected a digest without algorithm not to verify, got %t, %v", ok, err)
+	}
+
+	_, err = VerifyReq(req, lock, "blake3:af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262")
+	if err == nil || err.Error() != `unsupported digest algorithm "blake3" (supported: sha256, sha512)` {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// Third-party algorithms can be registered.
+	RegisterHasher(NewHasher("test", sha256.New))
+	t.Cleanup(func() {
+		hashersMu.Lock()
+		delete(hashers, "test")
+		hashersMu.Unlock()
+	})
+	testSum, err := HashReqWith(NewHasher("test", sha256.New), req, lock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if testSum != "test:"+strings.TrimPrefix(sha256Sum, "sha256:") {
+		t.Errorf("unexpected digest %s", testSum)
+	}
+	if ok, err := VerifyReq(req, lock, testSum); err != nil || !ok {
+		t.Errorf("expected %s to verify, got %t, %v", testSum, ok, err)
+	}
+}
@@ -17,17 +17,21 @@ package resolver
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 
+	"helm.sh/helm/v4/internal/gitrepo"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	"helm.sh/helm/v4/pkg/helmpath"
@@ -41,25 +45,66 @@ type Resolver struct {
 	chartpath      string
 	cachepath      string
 	registryClient *registry.Client
+	offline        bool
+	hasher         Hasher
+}
+
+// Option configures a Resolver.
+type Option func(*Resolver)
+
+// WithOffline forbids the resolver from accessing the network. Dependencies
+// are resolved from the cached repository indexes and the chart archives in
+// the charts/ directory only, and resolving fails with a list of the
+// dependencies that would need the network.
+func WithOffline() Option {
+	return func(r *Resolver) {
+		r.offline = true
+	}
+}
+
+// WithHasher sets the hash algorithm of the digest of the lock file. It
+// defaults to SHA256.
+func WithHasher(h Hasher) Option {
+	return func(r *Resolver) {
+		r.hasher = h
+	}
 }
 
 // New creates a new resolver for a given chart, helm home and registry client.
-func New(chartpath, cachepath string, registryClient *registry.Client) *Resolver {
-	return &Resolver{
+func New(chartpath, cachepath string, registryClient *registry.Client, opts ...Option) *Resolver {
+	r := &Resolver{
 		chartpath:      chartpath,
 		cachepath:      cachepath,
 		registryClient: registryClient,
+		hasher:         SHA256,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 // Resolve resolves dependencies and returns a lock file with the resolution.
 func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string) (*chart.Lock, error) {
+	return r.resolve(reqs, repoNames, nil)
+}
+
+// resolve resolves dependencies, appending how each version was chosen to
+// explanations unless it is nil.
+func (r *Resolver) resolve(reqs []*chart.Dependency, repoNames map[string]string, explanations *[]*Explanation) (*chart.Lock, error) {
 
 	// Now we clone the dependencies, locking as we go.
 	locked := make([]*chart.Dependency, len(reqs))
 	missing := []string{}
+	needNetwork := []string{}
 	for i, d := range reqs {
-		constraint, err := semver.NewConstraint(d.Version)
+		var ex *Explanation
+		if explanations != nil {
+			ex = &Explanation{Name: d.Name, Repository: d.Repository, Constraint: describeConstraint(d)}
+			*explanations = append(*explanations, ex)
+		}
+
+		constraint, err := DependencyConstraint(d, nil)
 		if err != nil {
 			return nil, fmt.Errorf("dependency %q has an invalid version/constraint format: %w", d.Name, err)
 		}
@@ -69,6 +114,8 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 			if _, err := GetLocalPath(filepath.Join("charts", d.Name), r.chartpath); err != nil {
 				return nil, err
 			}
+			ex.source("unpacked chart in the charts directory, checked against the constraint when building")
+			ex.choose(d.Version)
 
 			locked[i] = &chart.Dependency{
 				Name:       d.Name,
@@ -88,16 +135,20 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 				return nil, err
 			}
 
+			ex.source("chart in the local directory " + chartpath)
 			v, err := semver.NewVersion(ch.Metadata.Version)
 			if err != nil {
 				// Not a legit entry.
+				ex.candidate(Candidate{Version: ch.Metadata.Version, Skipped: "not a semantic version"})
 				continue
 			}
 
 			if !constraint.Check(v) {
+				ex.candidate(Candidate{Version: ch.Metadata.Version})
 				missing = append(missing, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
 				continue
 			}
+			ex.candidate(Candidate{Version: ch.Metadata.Version, Satisfies: true, Chosen: true})
 
 			locked[i] = &chart.Dependency{
 				Name:       d.Name,
@@ -107,13 +158,43 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 			continue
 		}
 
+		if gitrepo.IsGit(d.Repository) {
+			version, err := r.resolveGit(d, constraint, ex)
+			if errors.Is(err, gitrepo.ErrNotCached) {
+				needNetwork = append(needNetwork, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if version == "" {
+				missing = append(missing, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+				continue
+			}
+			locked[i] = &chart.Dependency{
+				Name:       d.Name,
+				Repository: d.Repository,
+				Version:    version,
+			}
+			continue
+		}
+
 		repoName := repoNames[d.Name]
 		// if the repository was not defined, but the dependency defines a repository url, bypass the cache
 		if repoName == "" && d.Repository != "" {
+			if d.Channel != "" {
+				return nil, fmt.Errorf("dependency %q tracks the channel %q, which needs the index of repository %s: add it with 'helm repo add'", d.Name, d.Channel, d.Repository)
+			}
+			version := d.Version
+			if r, ok := builtinChannels[version]; ok {
+				version = r
+			}
+			ex.source("repository without a cached index, resolved when downloading")
+			ex.choose(version)
 			locked[i] = &chart.Dependency{
 				Name:       d.Name,
 				Repository: d.Repository,
-				Version:    d.Version,
+				Version:    version,
 			}
 			continue
 		}
@@ -124,14 +205,33 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 		found := true
 		if !registry.IsOCI(d.Repository) {
 			repoIndex, err := repo.LoadIndexFile(filepath.Join(r.cachepath, helmpath.CacheIndexFile(repoName)))
-			if err != nil {
+			if err != nil && r.offline {
+				vs, err = r.archivedVersions(d.Name)
+				if err != nil {
+					return nil, err
+				}
+				if len(vs) == 0 {
+					needNetwork = append(needNetwork, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+					continue
+				}
+				repoIndex = &repo.IndexFile{Entries: map[string]repo.ChartVersions{d.Name: vs}}
+				ex.source("chart archives in the charts directory (offline, the index of repository " + repoName + " is not cached)")
+			} else if err != nil {
 				return nil, fmt.Errorf("no cached repository for %s found. (try 'helm repo update'): %w", repoName, err)
+			} else {
+				ex.source("cached index of repository " + repoName)
 			}
 
 			vs, ok = repoIndex.Entries[d.Name]
 			if !ok {
 				return nil, fmt.Errorf("%s chart not found in repo %s", d.Name, d.Repository)
 			}
+			if channels, ok := repoIndex.Channels[d.Name]; ok {
+				constraint, err = DependencyConstraint(d, channels)
+				if err != nil {
+					return nil, fmt.Errorf("dependency %q: %w", d.Name, err)
+				}
+			}
 			found = false
 		} else {
 			version = d.Version
@@ -141,12 +241,25 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 
 			// Use an explicit version, otherwise search for tags
 			if err == nil {
+				ex.source("explicit version, the tags of the registry are not listed")
 				vs = []*repo.ChartVersion{{
 					Metadata: &chart.Metadata{
 						Version: version,
 					},
 				}}
 
+			} else if r.offline {
+				// Listing the tags needs the registry, so only the archives
+				// already in the charts directory are candidates.
+				vs, err = r.archivedVersions(d.Name)
+				if err != nil {
+					return nil, err
+				}
+				if len(vs) == 0 {
+					needNetwork = append(needNetwork, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+					continue
+				}
+				ex.source("chart archives in the charts directory (offline, the registry is not queried)")
 			} else {
 				// Retrieve list of tags for repository
 				ref := fmt.Sprintf("%s/%s", strings.TrimPrefix(d.Repository, fmt.Sprintf("%s://", registry.OCIScheme)), d.Name)
@@ -154,6 +267,7 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 				if err != nil {
 					return nil, fmt.Errorf("could not retrieve list of tags for repository %s: %w", d.Repository, err)
 				}
+				ex.source("tags of " + ref)
 
 				vs = make(repo.ChartVersions, len(tags))
 				for ti, t := range tags {
@@ -174,29 +288,50 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 			Version:    version,
 		}
 		// The versions are already sorted and hence the first one to satisfy the constraint is used
+		chosen := false
 		for _, ver := range vs {
 			v, err := semver.NewVersion(ver.Version)
 			// OCI does not need URLs
 			if err != nil || (!registry.IsOCI(d.Repository) && len(ver.URLs) == 0) {
 				// Not a legit entry.
+				if err != nil {
+					ex.candidate(Candidate{Version: ver.Version, Skipped: "not a semantic version"})
+				} else {
+					ex.candidate(Candidate{Version: ver.Version, Skipped: "no download URL"})
+				}
 				continue
 			}
-			if constraint.Check(v) {
-				found = true
+			satisfies := constraint.Check(v)
+			ex.candidate(Candidate{Version: v.Original(), Satisfies: satisfies, Chosen: satisfies && !chosen})
+			if satisfies && !chosen {
+				found, chosen = true, true
 				locked[i].Version = v.Original()
-				break
+				if ex == nil {
+					break
+				}
 			}
 		}
 
 		if !found {
 			missing = append(missing, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+			continue
+		}
+		if registry.IsOCI(d.Repository) && r.registryClient != nil && !r.offline {
+			digest, err := ManifestDigest(r.registryClient, d.Repository, d.Name, locked[i].Version)
+			if err != nil {
+				return nil, err
+			}
+			locked[i].Digest = digest
 		}
 	}
+	if len(needNetwork) > 0 {
+		return nil, fmt.Errorf("offline mode: resolving %d subchart(s) would need network access: %s. Run 'helm repo update' or 'helm dependency update' while online to populate the repository cache and the charts directory", len(needNetwork), strings.Join(needNetwork, ", "))
+	}
 	if len(missing) > 0 {
 		return nil, fmt.Errorf("can't get a valid version for %d subchart(s): %s. Make sure a matching chart version exists in the repo, or change the version constraint in Chart.yaml", len(missing), strings.Join(missing, ", "))
 	}
 
-	digest, err := HashReq(reqs, locked)
+	digest, err := HashReqWith(r.hasher, reqs, locked)
 	if err != nil {
 		return nil, err
 	}
@@ -208,17 +343,84 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 	}, nil
 }
 
-// HashReq generates a hash of the dependencies.
+// ManifestDigest returns the digest of the manifest of the given version of
+// the chart name in an OCI repository, which pins the version to its content.
+func ManifestDigest(client *registry.Client, repository, name, version string) (string, error) {
+	// Tags use an underscore (_) in place of the plus (+) of build metadata.
+	ref := fmt.Sprintf("%s/%s:%s", strings.TrimPrefix(repository, fmt.Sprintf("%s://", registry.OCIScheme)), name, strings.ReplaceAll(version, "+", "_"))
+	desc, err := client.Resolve(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve the digest of %s: %w", ref, err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// archivedVersions returns the versions of the chart archives named name in
+// the charts directory, newest first.
+func (r *Resolver) archivedVersions(name string) (repo.ChartVersions, error) {
+	entries, err := os.ReadDir(filepath.Join(r.chartpath, "charts"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var vs repo.ChartVersions
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tgz") {
+			continue
+		}
+		archive := filepath.Join(r.chartpath, "charts", e.Name())
+		ch, err := loader.LoadFile(archive)
+		if err != nil || ch.Name() != name {
+			continue
+		}
+		vs = append(vs, &repo.ChartVersion{
+			Metadata: ch.Metadata,
+			URLs:     []string{archive},
+		})
+	}
+	sort.Sort(sort.Reverse(vs))
+	return vs, nil
+}
+
+// HashReq generates a SHA256 hash of the dependencies.
 //
 // This should be used only to compare against another hash generated by this
 // function.
 func HashReq(req, lock []*chart.Dependency) (string, error) {
+	return HashReqWith(SHA256, req, lock)
+}
+
+// HashReqWith generates a hash of the dependencies with the given algorithm.
+// The hash is prefixed with the name of the algorithm, as in "sha512:...".
+func HashReqWith(h Hasher, req, lock []*chart.Dependency) (string, error) {
 	data, err := json.Marshal([2][]*chart.Dependency{req, lock})
 	if err != nil {
 		return "", err
 	}
-	s, err := provenance.Digest(bytes.NewBuffer(data))
-	return "sha256:" + s, err
+	hash := h.New()
+	hash.Write(data)
+	return h.Name() + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyReq reports whether digest is the hash of the dependencies. The hash
+// is generated with the algorithm recorded in the digest, which fails if the
+// algorithm is not registered. Digests not recording an algorithm never match.
+func VerifyReq(req, lock []*chart.Dependency, digest string) (bool, error) {
+	name, _, ok := strings.Cut(digest, ":")
+	if !ok {
+		return false, nil
+	}
+	h, err := LookupHasher(name)
+	if err != nil {
+		return false, err
+	}
+	sum, err := HashReqWith(h, req, lock)
+	if err != nil {
+		return false, err
+	}
+	return sum == digest, nil
 }
 
 // HashV2Req generates a hash of requirements generated in Helm v2.
@@ -242,10 +444,9 @@ func HashV2Req(req []*chart.Dependency) (string, error) {
 func GetLocalPath(repo, chartpath string) (string, error) {
 	var depPath string
 	var err error
-	p := strings.TrimPrefix(repo, "file://")
+	p, abs := localPath(repo, runtime.GOOS == "windows")
 
-	// root path is absolute
-	if strings.HasPrefix(p, "/") {
+	if abs {
 		if depPath, err = filepath.Abs(p); err != nil {
 			return "", err
 		}
@@ -261,3 +462,46 @@ func GetLocalPath(repo, chartpath string) (string, error) {
 
 	return depPath, nil
 }
+
+// localPath returns the path of a "file://" repository and whether it is
+// absolute.
+//
+// On Windows the path may also be written with a drive letter
+// ("file:///C:/charts" or "file://C:\charts"), as a UNC path
+// ("file:////server/share/charts" or "file://\\server\share\charts"), or
+// as an extended-length path ("file://\\?\C:\charts"). It is given as a
+// parameter so the Windows rules can be tested on every platform.
+func localPath(repo string, windows bool) (string, bool) {
+	p := strings.TrimPrefix(repo, "file://")
+	if !windows {
+		return p, strings.HasPrefix(p, "/")
+	}
+
+	// A drive letter following the slash that separates it from the empty
+	// host of the URL, as in file:///C:/charts.
+	if len(p) >= 3 && isPathSeparator(p[0]) && hasDriveLetter(p[1:]) {
+		return p[1:], true
+	}
+	if hasDriveLetter(p) {
+		return p, true
+	}
+	// UNC and extended-length paths, and paths rooted on the current drive.
+	return p, p != "" && isPathSeparator(p[0])
+}
+
+// hasDriveLetter reports whether p starts with a drive letter followed by a
+// path separator or nothing, like "C:\" or "C:".
+func hasDriveLetter(p string) bool {
+	if len(p) < 2 || p[1] != ':' {
+		return false
+	}
+	c := p[0]
+	if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+		return false
+	}
+	return len(p) == 2 || isPathSeparator(p[2])
+}
+
+func isPathSeparator(c byte) bool {
+	return c == '/' || c == '\\'
+}
@@ -16,11 +16,25 @@ limitations under the License.
 package resolver
 
 import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
 	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/registry"
+	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
 func TestResolve(t *testing.T) {
@@ -181,6 +195,143 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+func TestResolveOffline(t *testing.T) {
+	chartpath := t.TempDir()
+	for _, version := range []string{"1.2.0", "1.3.0", "2.0.0"} {
+		ch := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "archived", Version: version}}
+		if _, err := chartutil.Save(ch, filepath.Join(chartpath, "charts")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		req     []*chart.Dependency
+		version string
+		wantErr string
+	}{
+		{
+			name:    "cached index",
+			req:     []*chart.Dependency{{Name: "alpine", Repository: "http://example.com", Version: ">=0.1.0"}},
+			version: "0.2.0",
+		},
+		{
+			name:    "OCI range from the charts directory",
+			req:     []*chart.Dependency{{Name: "archived", Repository: "oci://registry.example.com/charts", Version: "^1.0.0"}},
+			version: "1.3.0",
+		},
+		{
+			name:    "missing cached index from the charts directory",
+			req:     []*chart.Dependency{{Name: "archived", Repository: "http://uncached.example.com", Version: "~1.2.0"}},
+			version: "1.2.0",
+		},
+		{
+			name: "dependencies needing the network",
+			req: []*chart.Dependency{
+				{Name: "alpine", Repository: "oci://registry.example.com/charts", Version: "^0.1.0"},
+				{Name: "redis", Repository: "http://uncached.example.com", Version: "1.0.0"},
+			},
+			wantErr: `offline mode: resolving 2 subchart(s) would need network access: "alpine" (repository "oci://registry.example.com/charts", version "^0.1.0"), "redis" (repository "http://uncached.example.com", version "1.0.0")`,
+		},
+	}
+
+	repoNames := map[string]string{"alpine": "kubernetes-charts", "archived": "uncached", "redis": "uncached"}
+	r := New(chartpath, "testdata/repository", nil, WithOffline())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := r.Resolve(tt.req, repoNames)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := l.Dependencies[0].Version; got != tt.version {
+				t.Errorf("expected version %s, got %s", tt.version, got)
+			}
+		})
+	}
+}
+
+// ociTestServer serves the paginated tag list of charts/alpine, and the
+// manifests of its tags, whose digests it returns.
+func ociTestServer(t *testing.T, tags ...string) (string, map[string]string) {
+	t.Helper()
+	digests := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		digests[tag] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(tag)))
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/charts/alpine/tags/list":
+			page := tags
+			if last := r.URL.Query().Get("last"); last != "" {
+				page = tags[slices.Index(tags, last)+1:]
+			} else if len(tags) > 1 {
+				// Split the list in two pages.
+				page = tags[:1]
+				w.Header().Set("Link", fmt.Sprintf(`</v2/charts/alpine/tags/list?last=%s>; rel="next"`, tags[0]))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "charts/alpine", "tags": page})
+		case strings.HasPrefix(r.URL.Path, "/v2/charts/alpine/manifests/"):
+			digest, ok := digests[strings.TrimPrefix(r.URL.Path, "/v2/charts/alpine/manifests/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.Header().Set("Content-Length", "2")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://"), digests
+}
+
+func TestResolveOCIDigest(t *testing.T) {
+	host, digests := ociTestServer(t, "0.1.0", "0.1.1", "0.2.0", "0.1.2_build.1")
+	registryClient, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")),
+		registry.ClientOptPlainHTTP(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New("testdata/chartpath", "testdata/repository", registryClient)
+	repository := "oci://" + host + "/charts"
+	// The manager names OCI repositories after their URL.
+	repoNames := map[string]string{"alpine": repository}
+
+	tests := []struct {
+		version string
+		tag     string
+	}{
+		{version: "~0.1.0", tag: "0.1.2_build.1"},
+		{version: ">=0.2.0", tag: "0.2.0"},
+		{version: "0.1.1", tag: "0.1.1"},
+	}
+	for _, tt := range tests {
+		l, err := r.Resolve([]*chart.Dependency{{Name: "alpine", Repository: repository, Version: tt.version}}, repoNames)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Dependencies[0].Digest; got != digests[tt.tag] {
+			t.Errorf("%s: expected the digest of %s (%s), got %s", tt.version, tt.tag, digests[tt.tag], got)
+		}
+	}
+
+	_, err = r.Resolve([]*chart.Dependency{{Name: "alpine", Repository: repository, Version: "1.0.0"}}, repoNames)
+	if err == nil || !strings.Contains(err.Error(), "could not resolve the digest of "+host+"/charts/alpine:1.0.0") {
+		t.Errorf("expected an error resolving the digest of a missing tag, got %v", err)
+	}
+}
+
 func TestHashReq(t *testing.T) {
 	expect := "sha256:fb239e836325c5fa14b29d1540a13b7d3ba13151b67fe719f820e0ef6d66aaaf"
 
@@ -308,3 +459,114 @@ func TestGetLocalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		repo      string
+		expect    string
+		abs       bool
+		winExpect string
+		winAbs    bool
+	}{
+		{repo: "file://../base", expect: "../base", winExpect: "../base"},
+		{repo: "file://..\\base", expect: "..\\base", winExpect: "..\\base"},
+		{repo: "file://charts/base", expect: "charts/base", winExpect: "charts/base"},
+		{repo: "charts/base", expect: "charts/base", winExpect: "charts/base"},
+		{repo: "file:///srv/charts/base", expect: "/srv/charts/base", abs: true, winExpect: "/srv/charts/base", winAbs: true},
+		{repo: "file:///C:/charts/base", expect: "/C:/charts/base", abs: true, winExpect: "C:/charts/base", winAbs: true},
+		{repo: "file:///c:\\charts\\base", expect: "/c:\\charts\\base", abs: true, winExpect: "c:\\charts\\base", winAbs: true},
+		{repo: "file://C:/charts/base", expect: "C:/charts/base", winExpect: "C:/charts/base", winAbs: true},
+		{repo: "file://C:\\charts\\base", expect: "C:\\charts\\base", winExpect: "C:\\charts\\base", winAbs: true},
+		{repo: "file://C:", expect: "C:", winExpect: "C:", winAbs: true},
+		{repo: "file://C:base", expect: "C:base", winExpect: "C:base"},
+		{repo: "file:////server/share/charts", expect: "//server/share/charts", abs: true, winExpect: "//server/share/charts", winAbs: true},
+		{repo: "file://\\\\server\\share\\charts", expect: "\\\\server\\share\\charts", winExpect: "\\\\server\\share\\charts", winAbs: true},
+		{repo: "file://\\\\?\\C:\\charts\\base", expect: "\\\\?\\C:\\charts\\base", winExpect: "\\\\?\\C:\\charts\\base", winAbs: true},
+		{repo: "file://\\charts\\base", expect: "\\charts\\base", winExpect: "\\charts\\base", winAbs: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			p, abs := localPath(tt.repo, false)
+			if p != tt.expect || abs != tt.abs {
+				t.Errorf("expected (%q, %t), got (%q, %t)", tt.expect, tt.abs, p, abs)
+			}
+			p, abs = localPath(tt.repo, true)
+			if p != tt.winExpect || abs != tt.winAbs {
+				t.Errorf("windows: expected (%q, %t), got (%q, %t)", tt.winExpect, tt.winAbs, p, abs)
+			}
+		})
+	}
+}
+
+func TestGetLocalPathLayouts(t *testing.T) {
+	dir := t.TempDir()
+	// A path longer than the 260 characters Windows limits paths to by default.
+	long := filepath.Join(dir, strings.Repeat("a", 60), strings.Repeat("b", 60), strings.Repeat("c", 60), strings.Repeat("d", 60), "base")
+	if err := os.MkdirAll(long, 0755); err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(dir, long)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		repo      string
+		chartpath string
+	}{
+		{name: "absolute path", repo: "file://" + filepath.ToSlash(long)},
+		{name: "absolute path with empty host", repo: "file:///" + strings.TrimPrefix(filepath.ToSlash(long), "/")},
+		{name: "absolute path with native separators", repo: "file://" + long},
+		{name: "relative path", repo: "file://" + filepath.ToSlash(rel), chartpath: dir},
+		{name: "relative path with native separators", repo: "file://" + rel, chartpath: dir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := GetLocalPath(tt.repo, tt.chartpath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != long {
+				t.Errorf("expected %q, got %q", long, p)
+			}
+		})
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	// A repository cache holding an index with many charts and versions.
+	cachepath := b.TempDir()
+	index := repo.NewIndexFile()
+	for c := range 100 {
+		for v := range 20 {
+			md := &chart.Metadata{APIVersion: chart.APIVersionV2, Name: fmt.Sprintf("chart-%d", c), Version: fmt.Sprintf("1.%d.0", v)}
+			if err := index.MustAdd(md, fmt.Sprintf("%s-%s.tgz", md.Name, md.Version), "https://example.com/charts", "sha256:1234567890abcdef"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	index.SortEntries()
+	if err := index.WriteFile(filepath.Join(cachepath, helmpath.CacheIndexFile("bench")), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	var reqs []*chart.Dependency
+	repoNames := make(map[string]string)
+	for c := range 10 {
+		name := fmt.Sprintf("chart-%d", c*10)
+		reqs = append(reqs, &chart.Dependency{Name: name, Repository: "https://example.com/charts", Version: "^1.5.0"})
+		repoNames[name] = "bench"
+	}
+
+	registryClient, _ := registry.NewClient()
+	r := New("testdata/chartpath", cachepath, registryClient)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := r.Resolve(reqs, repoNames); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
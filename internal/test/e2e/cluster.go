@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package e2e starts the Kubernetes clusters the end-to-end tests of Helm run
+against.
+*/
+package e2e
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// ClusterEnv is the environment variable that selects the kind of cluster
+// the end-to-end tests run against. The tests are skipped when it is not set.
+const ClusterEnv = "HELM_E2E_CLUSTER"
+
+// The kinds of clusters the end-to-end tests run against.
+const (
+	// ClusterEnvtest is a control plane started with envtest, from the
+	// binaries in the directory of the KUBEBUILDER_ASSETS environment
+	// variable. It has no controllers nor nodes, so workloads never become
+	// ready.
+	ClusterEnvtest = "envtest"
+	// ClusterKind is a cluster created with kind, which must be installed.
+	ClusterKind = "kind"
+	// ClusterExisting is the cluster of the current context of the
+	// kubeconfig, selected with the KUBECONFIG environment variable.
+	ClusterExisting = "existing"
+)
+
+// kindClusterName is the name of the kind clusters created for the tests.
+const kindClusterName = "helm-e2e"
+
+// ErrNoCluster is returned by StartCluster when ClusterEnv is not set.
+var ErrNoCluster = errors.New(ClusterEnv + " is not set")
+
+// Cluster is a cluster the end-to-end tests run against.
+type Cluster struct {
+	// Kind is the kind of the cluster.
+	Kind string
+	// Config is the configuration of the clients of the cluster, with the
+	// permissions of a cluster administrator.
+	Config *rest.Config
+	stop   func() error
+}
+
+// StartCluster starts a cluster of the kind set in ClusterEnv.
+func StartCluster() (*Cluster, error) {
+	switch kind := os.Getenv(ClusterEnv); kind {
+	case "":
+		return nil, ErrNoCluster
+	case ClusterEnvtest:
+		return startEnvtest()
+	case ClusterKind:
+		return startKind()
+	case ClusterExisting:
+		config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the kubeconfig of the existing cluster: %w", err)
+		}
+		return &Cluster{Kind: kind, Config: config, stop: func() error { return nil }}, nil
+	default:
+		return nil, fmt.Errorf("unknown cluster %q in %s, expected %s, %s or %s", kind, ClusterEnv, ClusterEnvtest, ClusterKind, ClusterExisting)
+	}
+}
+
+// Stop stops the cluster. Existing clusters are left running.
+func (c *Cluster) Stop() error {
+	return c.stop()
+}
+
+func startEnvtest() (*Cluster, error) {
+	env := &envtest.Environment{}
+	config, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("unable to start envtest, check KUBEBUILDER_ASSETS: %w", err)
+	}
+	return &Cluster{Kind: ClusterEnvtest, Config: config, stop: env.Stop}, nil
+}
+
+func startKind() (*Cluster, error) {
+	dir, err := os.MkdirTemp("", kindClusterName)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig := filepath.Join(dir, "kubeconfig")
+	if err := runKind("create", "cluster", "--name", kindClusterName, "--kubeconfig", kubeconfig, "--wait", "2m"); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	stop := func() error {
+		defer os.RemoveAll(dir)
+		return runKind("delete", "cluster", "--name", kindClusterName, "--kubeconfig", kubeconfig)
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, errors.Join(err, stop())
+	}
+	return &Cluster{Kind: ClusterKind, Config: config, stop: stop}, nil
+}
+
+func runKind(args ...string) error {
+	var out bytes.Buffer
+	cmd := exec.Command("kind", args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("kind %s: %w\n%s", args[0], err, out.String())
+	}
+	return nil
+}
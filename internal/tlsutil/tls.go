@@ -29,6 +29,7 @@ type TLSConfigOptions struct {
 	insecureSkipTLSverify     bool
 	certPEMBlock, keyPEMBlock []byte
 	caPEMBlock                []byte
+	minVersion                uint16
 }
 
 type TLSConfigOption func(options *TLSConfigOptions) error
@@ -81,6 +82,41 @@ func WithCAFile(caFile string) TLSConfigOption {
 	}
 }
 
+// WithMinVersion sets the minimum TLS version, such as "1.2". An empty
+// version keeps the default of crypto/tls.
+func WithMinVersion(version string) TLSConfigOption {
+	return func(options *TLSConfigOptions) error {
+		if version == "" {
+			return nil
+		}
+
+		v, err := ParseVersion(version)
+		if err != nil {
+			return err
+		}
+
+		options.minVersion = v
+
+		return nil
+	}
+}
+
+// ParseVersion returns the crypto/tls constant of a TLS version written as
+// "1.0", "1.1", "1.2" or "1.3".
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q, must be one of 1.0, 1.1, 1.2 or 1.3", version)
+}
+
 func NewTLSConfig(options ...TLSConfigOption) (*tls.Config, error) {
 	to := TLSConfigOptions{}
 
@@ -98,6 +134,7 @@ func NewTLSConfig(options ...TLSConfigOption) (*tls.Config, error) {
 
 	config := tls.Config{
 		InsecureSkipVerify: to.insecureSkipTLSverify,
+		MinVersion:         to.minVersion,
 	}
 
 	if len(to.certPEMBlock) > 0 && len(to.keyPEMBlock) > 0 {
@@ -17,6 +17,7 @@ limitations under the License.
 package tlsutil
 
 import (
+	"crypto/tls"
 	"path/filepath"
 	"testing"
 )
@@ -104,3 +105,17 @@ func TestNewTLSConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestNewTLSConfigMinVersion(t *testing.T) {
+	cfg, err := NewTLSConfig(WithMinVersion("1.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MinVersion != tls.VersionTLS13 {
+		t.Errorf("expected minimum version %d, got %d", tls.VersionTLS13, cfg.MinVersion)
+	}
+
+	if _, err := NewTLSConfig(WithMinVersion("1.4")); err == nil {
+		t.Error("expected an error for an unknown TLS version")
+	}
+}
@@ -23,9 +23,11 @@ import (
 	"io"
 	"log/slog"
 	"maps"
+	"math/rand/v2"
 	"os"
 	"path"
 	"path/filepath"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
@@ -37,6 +39,7 @@ import (
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/utils/clock"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
 
@@ -56,21 +59,28 @@ import (
 // Timestamper is a function capable of producing a timestamp.Timestamper.
 //
 // By default, this is a time.Time function from the Helm time package. This can
-// be overridden for testing though, so that timestamps are predictable.
+// be overridden for testing though, so that timestamps are predictable. The
+// Clock of a Configuration takes precedence over it.
 var Timestamper = time.Now
 
 var (
 	// errMissingChart indicates that a chart was not provided.
-	errMissingChart = errors.New("no chart provided")
+	errMissingChart = classify(ErrInvalid, errors.New("no chart provided"))
 	// errMissingRelease indicates that a release (name) was not provided.
-	errMissingRelease = errors.New("no release provided")
+	errMissingRelease = classify(ErrInvalid, errors.New("no release provided"))
 	// errInvalidRevision indicates that an invalid release revision number was provided.
-	errInvalidRevision = errors.New("invalid release revision")
+	errInvalidRevision = classify(ErrInvalid, errors.New("invalid release revision"))
 	// errPending indicates that another instance of Helm is already applying an operation on a release.
-	errPending = errors.New("another operation (install/upgrade/rollback) is in progress")
+	errPending = classify(ErrConflict, errors.New("another operation (install/upgrade/rollback) is in progress"))
 )
 
 // Configuration injects the dependencies that all actions share.
+//
+// Once initialized, a Configuration may be shared by actions running
+// concurrently, for example by a server handling several installs in
+// parallel. Init and SetHookOutputFunc, as well as assignments to the fields,
+// must happen before the Configuration is shared. The Capabilities are
+// discovered at most once and guarded internally.
 type Configuration struct {
 	// RESTClientGetter is an interface that loads Kubernetes clients.
 	RESTClientGetter RESTClientGetter
@@ -93,7 +103,50 @@ type Configuration struct {
 	// HookOutputFunc called with container name and returns and expects writer that will receive the log output.
 	HookOutputFunc func(namespace, pod, container string) io.Writer
 
+	// Actor identifies who performs the actions, such as a user name. It is
+	// recorded in the revisions created by install, upgrade and rollback.
+	Actor string
+
+	// EventHandler is notified of the lifecycle events of the actions, when set.
+	EventHandler EventHandler
+
+	// Impersonation is the Kubernetes user and groups the requests of the
+	// actions are made as, so the cluster enforces their permissions. It is
+	// applied by Init and recorded in the revisions created by install,
+	// upgrade and rollback.
+	Impersonation *rest.ImpersonationConfig
+
+	// WarningFunc is called with the warnings of the actions as they run
+	// into them, such as the warnings of the API server for deprecated APIs.
+	// The warnings are also recorded in the results of the actions. They are
+	// logged when it is not set.
+	WarningFunc func(Warning)
+
+	// Clock is the clock the actions tell the time with, for the timestamps
+	// of the releases and hooks, and wait with, such as between health
+	// checks. The storage drivers created by Init record their timestamps
+	// with it too. A fake clock, such as the ones of k8s.io/utils/clock/testing,
+	// lets tests control time. The real clock, with Timestamper telling the
+	// time, is used when it is not set.
+	Clock clock.Clock
+
+	// Rand is the source of the randomness of the actions, such as the
+	// random functions of the name templates of install. A seeded source
+	// makes them predictable. A random source is used when it is not set.
+	Rand *rand.Rand
+
 	mutex sync.Mutex
+
+	// randMutex guards Rand, which is not safe for concurrent use.
+	randMutex sync.Mutex
+
+	// recorders are the result recorders of the running actions, which
+	// record the warnings of the API server.
+	recordersMutex sync.Mutex
+	recorders      []*resultRecorder
+
+	// capabilitiesMutex guards the lazy discovery of Capabilities.
+	capabilitiesMutex sync.Mutex
 }
 
 const (
@@ -171,13 +224,23 @@ func splitAndDeannotate(postrendered string) (map[string]string, error) {
 	return reconstructed, nil
 }
 
+// renderOptions are the options of renderResources that post-process the
+// rendered manifests.
+type renderOptions struct {
+	// ConfigChecksums annotates the pod templates of the workloads with the
+	// checksum of the configuration of the release they reference.
+	ConfigChecksums bool
+	// Namespace is the namespace of the manifests that set none.
+	Namespace string
+}
+
 // renderResources renders the templates in a chart
 //
 // TODO: This function is badly in need of a refactor.
 // TODO: As part of the refactor the duplicate code in cmd/helm/template.go should be removed
 //
 //	This code has to do with writing files to disk.
-func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrenderer.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
+func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrenderer.PostRenderer, interactWithRemote, enableDNS, hideSecret bool, opts renderOptions, warn func(WarningKind, string)) ([]*release.Hook, *bytes.Buffer, string, error) {
 	var hs []*release.Hook
 	b := bytes.NewBuffer(nil)
 
@@ -188,7 +251,7 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values,
 
 	if ch.Metadata.KubeVersion != "" {
 		if !chartutil.IsCompatibleRange(ch.Metadata.KubeVersion, caps.KubeVersion.String()) {
-			return hs, b, "", fmt.Errorf("chart requires kubeVersion: %s which is incompatible with Kubernetes %s", ch.Metadata.KubeVersion, caps.KubeVersion.String())
+			return hs, b, "", classify(ErrInvalid, fmt.Errorf("chart requires kubeVersion: %s which is incompatible with Kubernetes %s", ch.Metadata.KubeVersion, caps.KubeVersion.String()))
 		}
 	}
 
@@ -217,7 +280,7 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values,
 	}
 
 	if err2 != nil {
-		return hs, b, "", err2
+		return hs, b, "", classify(ErrInvalid, err2)
 	}
 
 	// NOTES.txt gets rendered like all the other files, but because it's not a hook nor a resource,
@@ -270,7 +333,10 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values,
 	// Sort hooks, manifests, and partials. Only hooks and manifests are returned,
 	// as partials are not used after renderer.Render. Empty manifests are also
 	// removed here.
-	hs, manifests, err := releaseutil.SortManifests(files, nil, releaseutil.InstallOrder)
+	hs, manifests, skipped, err := releaseutil.SortManifestsWithSkipped(files, nil, releaseutil.InstallOrder)
+	for _, m := range skipped {
+		warn(WarningSkippedResource, fmt.Sprintf("skipping %s %q in %s: unknown hook %q", m.Head.Kind, m.Head.Metadata.Name, m.Name, m.Head.Metadata.Annotations[release.HookAnnotation]))
+	}
 	if err != nil {
 		// By catching parse errors here, we can prevent bogus releases from going
 		// to Kubernetes.
@@ -286,13 +352,22 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values common.Values,
 		return hs, b, "", err
 	}
 
+	if opts.ConfigChecksums {
+		if err := injectConfigChecksums(manifests, opts.Namespace); err != nil {
+			return hs, b, "", fmt.Errorf("error computing the checksums of the configuration: %w", err)
+		}
+	}
+
 	// Aggregate all valid manifests into one big doc.
 	fileWritten := make(map[string]bool)
 
 	if includeCrds {
 		for _, crd := range ch.CRDObjects() {
 			if outputDir == "" {
-				fmt.Fprintf(b, "---\n# Source: %s\n%s\n", crd.Filename, string(crd.File.Data[:]))
+				// CRDs can be large, write them without converting them to strings first.
+				fmt.Fprintf(b, "---\n# Source: %s\n", crd.Filename)
+				b.Write(crd.File.Data)
+				b.WriteString("\n")
 			} else {
 				err = writeToFile(outputDir, crd.Filename, string(crd.File.Data[:]), fileWritten[crd.Filename])
 				if err != nil {
@@ -339,6 +414,8 @@ type RESTClientGetter interface {
 
 // capabilities builds a Capabilities from discovery information.
 func (cfg *Configuration) getCapabilities() (*common.Capabilities, error) {
+	cfg.capabilitiesMutex.Lock()
+	defer cfg.capabilitiesMutex.Unlock()
 	if cfg.Capabilities != nil {
 		return cfg.Capabilities, nil
 	}
@@ -379,6 +456,27 @@ func (cfg *Configuration) getCapabilities() (*common.Capabilities, error) {
 	return cfg.Capabilities, nil
 }
 
+// hasCapabilities reports whether the Capabilities are already known.
+func (cfg *Configuration) hasCapabilities() bool {
+	cfg.capabilitiesMutex.Lock()
+	defer cfg.capabilitiesMutex.Unlock()
+	return cfg.Capabilities != nil
+}
+
+// clone returns a copy of the configuration. All of its exported fields are
+// copied, while its locks and the recorders of the running actions are not,
+// as copying the struct itself would copy its locks.
+func (cfg *Configuration) clone() *Configuration {
+	c := &Configuration{}
+	src, dst := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(c).Elem()
+	for i := range src.NumField() {
+		if src.Type().Field(i).IsExported() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return c
+}
+
 // KubernetesClientSet creates a new kubernetes ClientSet based on the configuration
 func (cfg *Configuration) KubernetesClientSet() (kubernetes.Interface, error) {
 	conf, err := cfg.RESTClientGetter.ToRESTConfig()
@@ -391,12 +489,66 @@ func (cfg *Configuration) KubernetesClientSet() (kubernetes.Interface, error) {
 
 // Now generates a timestamp
 //
-// If the configuration has a Timestamper on it, that will be used.
-// Otherwise, this will use time.Now().
+// If the configuration has a Clock, that will be used. Otherwise, this will
+// use Timestamper.
 func (cfg *Configuration) Now() time.Time {
+	return cfg.clockOrDefault().Now()
+}
+
+// clockOrDefault returns the clock of the configuration, or the real clock
+// telling the time with Timestamper.
+func (cfg *Configuration) clockOrDefault() clock.Clock {
+	if cfg.Clock != nil {
+		return cfg.Clock
+	}
+	return timestamperClock{}
+}
+
+// configurationClock is the clock of a configuration for the storage drivers.
+// It reads the Clock of the configuration when telling the time, so that a
+// Clock set after Init is used by the drivers as well.
+type configurationClock struct {
+	cfg *Configuration
+}
+
+func (c configurationClock) Now() time.Time {
+	return c.cfg.Now()
+}
+
+func (c configurationClock) Since(t time.Time) time.Duration {
+	return c.cfg.clockOrDefault().Since(t)
+}
+
+// timestamperClock is the real clock, telling the time with Timestamper.
+type timestamperClock struct {
+	clock.RealClock
+}
+
+func (timestamperClock) Now() time.Time {
 	return Timestamper()
 }
 
+func (c timestamperClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// randText returns a string of n characters picked at random from a set with
+// the randomness of the configuration.
+func (cfg *Configuration) randText(n int, chars string) string {
+	cfg.randMutex.Lock()
+	defer cfg.randMutex.Unlock()
+	intN := rand.IntN
+	if cfg.Rand != nil {
+		intN = cfg.Rand.IntN
+	}
+	runes := []rune(chars)
+	text := make([]rune, n)
+	for i := range text {
+		text[i] = runes[intN(len(runes))]
+	}
+	return string(text)
+}
+
 func (cfg *Configuration) releaseContent(name string, version int) (*release.Release, error) {
 	if err := chartutil.ValidateReleaseName(name); err != nil {
 		return nil, fmt.Errorf("releaseContent: Release name is invalid: %s", name)
@@ -466,7 +618,12 @@ func (cfg *Configuration) recordRelease(r *release.Release) {
 
 // Init initializes the action configuration
 func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namespace, helmDriver string) error {
-	kc := kube.New(getter)
+	if cfg.deployedAs() != nil {
+		getter = &impersonatingGetter{RESTClientGetter: getter, impersonate: *cfg.Impersonation}
+	}
+	// The warnings of the API server for the requests of the Kubernetes
+	// client are warnings of the actions.
+	kc := kube.New(&warningGetter{RESTClientGetter: getter, handler: apiWarningHandler{cfg: cfg}})
 
 	lazyClient := &lazyClient{
 		namespace: namespace,
@@ -477,9 +634,11 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 	switch helmDriver {
 	case "secret", "secrets", "":
 		d := driver.NewSecrets(newSecretClient(lazyClient))
+		d.Clock = configurationClock{cfg}
 		store = storage.Init(d)
 	case "configmap", "configmaps":
 		d := driver.NewConfigMaps(newConfigMapClient(lazyClient))
+		d.Clock = configurationClock{cfg}
 		store = storage.Init(d)
 	case "memory":
 		var d *driver.Memory
@@ -504,6 +663,7 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 		if err != nil {
 			return fmt.Errorf("unable to instantiate SQL driver: %w", err)
 		}
+		d.Clock = configurationClock{cfg}
 		store = storage.Init(d)
 	default:
 		return fmt.Errorf("unknown driver %q", helmDriver)
@@ -517,6 +677,14 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 	return nil
 }
 
+// InitWithRESTConfig initializes the action configuration from an existing
+// REST config rather than from kubeconfig files. It lets programs embedding
+// Helm, such as controllers running in a cluster, reuse the config they
+// already have.
+func (cfg *Configuration) InitWithRESTConfig(config *rest.Config, namespace, helmDriver string) error {
+	return cfg.Init(kube.NewRESTClientGetterForConfig(config, namespace), namespace, helmDriver)
+}
+
 // SetHookOutputFunc sets the HookOutputFunc on the Configuration.
 func (cfg *Configuration) SetHookOutputFunc(hookOutputFunc func(_, _, _ string) io.Writer) {
 	cfg.HookOutputFunc = hookOutputFunc
@@ -22,13 +22,17 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"helm.sh/helm/v4/internal/logging"
 	"helm.sh/helm/v4/pkg/chart/common"
@@ -154,6 +158,12 @@ func withName(name string) chartOption {
 	}
 }
 
+func withAnnotations(annotations map[string]string) chartOption {
+	return func(opts *chartOptions) {
+		opts.Metadata.Annotations = annotations
+	}
+}
+
 func withSampleValues() chartOption {
 	values := map[string]interface{}{
 		"someKey": "someValue",
@@ -357,6 +367,19 @@ func TestConfiguration_Init(t *testing.T) {
 	}
 }
 
+func TestConfiguration_InitWithRESTConfig(t *testing.T) {
+	cfg := &Configuration{}
+	config := &rest.Config{Host: "https://example.com:6443"}
+
+	require.NoError(t, cfg.InitWithRESTConfig(config, "apps", "memory"))
+	assert.IsType(t, &driver.Memory{}, cfg.Releases.Driver)
+
+	restConfig, err := cfg.RESTClientGetter.ToRESTConfig()
+	require.NoError(t, err)
+	assert.Equal(t, config.Host, restConfig.Host)
+	assert.NotNil(t, cfg.KubeClient)
+}
+
 func TestGetVersionSet(t *testing.T) {
 	client := fakeclientset.NewClientset()
 
@@ -788,7 +811,7 @@ func TestRenderResources_PostRenderer_Success(t *testing.T) {
 
 	hooks, buf, notes, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		mockPR, false, false, false,
+		mockPR, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.NoError(t, err)
@@ -831,7 +854,7 @@ func TestRenderResources_PostRenderer_Error(t *testing.T) {
 
 	_, _, _, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		mockPR, false, false, false,
+		mockPR, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.Error(t, err)
@@ -859,7 +882,7 @@ func TestRenderResources_PostRenderer_MergeError(t *testing.T) {
 
 	_, _, _, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		mockPR, false, false, false,
+		mockPR, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.Error(t, err)
@@ -881,7 +904,7 @@ func TestRenderResources_PostRenderer_SplitError(t *testing.T) {
 
 	_, _, _, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		mockPR, false, false, false,
+		mockPR, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.Error(t, err)
@@ -902,7 +925,7 @@ func TestRenderResources_PostRenderer_Integration(t *testing.T) {
 
 	hooks, buf, notes, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		mockPR, false, false, false,
+		mockPR, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.NoError(t, err)
@@ -938,7 +961,7 @@ func TestRenderResources_NoPostRenderer(t *testing.T) {
 
 	hooks, buf, notes, err := cfg.renderResources(
 		ch, values, "test-release", "", false, false, false,
-		nil, false, false, false,
+		nil, false, false, false, renderOptions{}, nil,
 	)
 
 	assert.NoError(t, err)
@@ -951,3 +974,88 @@ func TestDetermineReleaseSSAApplyMethod(t *testing.T) {
 	assert.Equal(t, release.ApplyMethodClientSideApply, determineReleaseSSApplyMethod(false))
 	assert.Equal(t, release.ApplyMethodServerSideApply, determineReleaseSSApplyMethod(true))
 }
+
+func TestConfigurationClock(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := clocktesting.NewFakeClock(now)
+	cfg := actionConfigFixture(t)
+	cfg.Clock = clock
+
+	instAction := installActionWithConfig(cfg)
+	rel, err := instAction.Run(buildChart(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, now, rel.Info.FirstDeployed)
+	assert.Equal(t, now, rel.Info.LastDeployed)
+	require.NotEmpty(t, rel.Hooks)
+	assert.Equal(t, now, rel.Hooks[0].LastRun.StartedAt)
+	assert.Equal(t, now, rel.Hooks[0].LastRun.CompletedAt)
+
+	clock.Step(time.Hour)
+	upAction := NewUpgrade(cfg)
+	upAction.Namespace = instAction.Namespace
+	rel, err = upAction.Run(rel.Name, buildChart(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, now, rel.Info.FirstDeployed)
+	assert.Equal(t, now.Add(time.Hour), rel.Info.LastDeployed)
+
+	clock.Step(time.Hour)
+	res, err := NewUninstall(cfg).Run(rel.Name)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(2*time.Hour), res.Release.Info.Deleted)
+}
+
+func TestConfigurationClockClientOnly(t *testing.T) {
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	cfg := actionConfigFixture(t)
+	cfg.Clock = clocktesting.NewFakeClock(now)
+
+	instAction := installActionWithConfig(cfg)
+	instAction.ClientOnly = true
+	rel, err := instAction.Run(buildChart(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, now, rel.Info.FirstDeployed)
+	assert.Equal(t, now, rel.Info.LastDeployed)
+}
+
+func TestConfigurationClockAfterInit(t *testing.T) {
+	cfg := &Configuration{}
+	require.NoError(t, cfg.Init(genericclioptions.NewConfigFlags(true), "default", "secret"))
+
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	cfg.Clock = clocktesting.NewFakeClock(now)
+	d, ok := cfg.Releases.Driver.(*driver.Secrets)
+	require.True(t, ok)
+	assert.Equal(t, now, d.Clock.Now(), "the drivers should use a clock set after Init")
+}
+
+func TestConfigurationRand(t *testing.T) {
+	name := func(seed uint64) string {
+		cfg := actionConfigFixture(t)
+		cfg.Rand = rand.New(rand.NewPCG(seed, seed))
+		cfg.Clock = clocktesting.NewFakeClock(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC))
+		n, err := cfg.templateName(`{{ randAlpha 4 }}-{{ randNumeric 4 }}-{{ randAlphaNum 4 }}-{{ randAscii 4 }}-{{ now | date "2006" }}`)
+		require.NoError(t, err)
+		return n
+	}
+
+	n := name(1)
+	assert.Equal(t, n, name(1), "the names of a seeded source should be the same")
+	assert.NotEqual(t, n, name(2), "the names of different seeds should differ")
+	assert.Regexp(t, `^[a-zA-Z]{4}-[0-9]{4}-[a-zA-Z0-9]{4}-[ -~]{4}-2025$`, n)
+}
+
+func TestConfigurationClone(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	cfg.Actor = "jane"
+	cfg.Rand = rand.New(rand.NewPCG(1, 1))
+	r := &resultRecorder{cfg: cfg}
+	cfg.addRecorder(r)
+	defer cfg.removeRecorder(r)
+
+	c := cfg.clone()
+	assert.Equal(t, cfg.Releases, c.Releases)
+	assert.Equal(t, cfg.KubeClient, c.KubeClient)
+	assert.Equal(t, "jane", c.Actor)
+	assert.Same(t, cfg.Rand, c.Rand)
+	assert.Empty(t, c.recorders, "the recorders of the running actions should not be copied")
+}
@@ -0,0 +1,370 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package actiontest provides a harness to test the actions end to end, against
+a Kubernetes cluster, a chart repository and an OCI registry.
+
+The cluster is selected with the HELM_E2E_CLUSTER environment variable:
+
+  - "envtest" starts a control plane with envtest, from the binaries in the
+    directory of the KUBEBUILDER_ASSETS environment variable. It has no
+    controllers nor nodes, so workloads never become ready and the actions
+    should not wait for them.
+  - "kind" creates a cluster with kind, which must be installed.
+  - "existing" uses the cluster of the current context of the kubeconfig.
+
+The tests using the harness are skipped when it is not set. The chart
+repository and the OCI registry are served by a repotest server started for
+each test.
+*/
+package actiontest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"helm.sh/helm/v4/internal/test/e2e"
+	"helm.sh/helm/v4/pkg/action"
+	ci "helm.sh/helm/v4/pkg/chart"
+	"helm.sh/helm/v4/pkg/chart/loader"
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/repo/v1"
+	"helm.sh/helm/v4/pkg/repo/v1/repotest"
+)
+
+// RepoName is the name of the chart repository of the harness in its
+// settings.
+const RepoName = "test"
+
+// cluster is the cluster shared by the tests of a package run with Main.
+var cluster struct {
+	sync.Mutex
+	shared  bool
+	cluster *e2e.Cluster
+}
+
+// Main runs the tests of a package against a single cluster, which is stopped
+// once they are done. It is meant to be called from TestMain. Without it, a
+// cluster is started for each test.
+func Main(m *testing.M) {
+	cluster.shared = true
+	code := m.Run()
+	if cluster.cluster != nil {
+		if err := cluster.cluster.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to stop the %s cluster: %v\n", cluster.cluster.Kind, err)
+		}
+	}
+	os.Exit(code)
+}
+
+// startCluster returns the cluster of a test, and skips the test when no
+// cluster is selected.
+func startCluster(t *testing.T) *e2e.Cluster {
+	t.Helper()
+	cluster.Lock()
+	defer cluster.Unlock()
+	if cluster.cluster != nil {
+		return cluster.cluster
+	}
+
+	c, err := e2e.StartCluster()
+	if err == e2e.ErrNoCluster {
+		t.Skipf("set %s to %q, %q or %q to run the end-to-end tests", e2e.ClusterEnv, e2e.ClusterEnvtest, e2e.ClusterKind, e2e.ClusterExisting)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cluster.shared {
+		cluster.cluster = c
+	} else {
+		t.Cleanup(func() {
+			if err := c.Stop(); err != nil {
+				t.Errorf("unable to stop the %s cluster: %v", c.Kind, err)
+			}
+		})
+	}
+	return c
+}
+
+// Harness runs the actions of a test against a cluster, a chart repository
+// and an OCI registry.
+type Harness struct {
+	// Namespace is the namespace of the releases of the test. It is created
+	// for the test, and deleted once it is done.
+	Namespace string
+	// Config is the configuration of the actions of the test.
+	Config *action.Configuration
+	// Client is a client of the cluster, to check the resources of the
+	// releases.
+	Client kubernetes.Interface
+	// Settings are the settings of the Helm environment of the test, whose
+	// repository configuration has the chart repository of the harness as
+	// RepoName.
+	Settings *cli.EnvSettings
+	// Repo is the chart repository and OCI registry of the harness.
+	Repo *repotest.Server
+
+	t *testing.T
+}
+
+// Option configures a harness.
+type Option func(*options)
+
+type options struct {
+	charts        string
+	serverOptions []repotest.ServerOption
+}
+
+// WithCharts serves the chart archives matching a glob from the chart
+// repository and the OCI registry of the harness.
+func WithCharts(glob string) Option {
+	return func(o *options) {
+		o.charts = glob
+	}
+}
+
+// WithServerOptions configures the repotest server of the harness.
+func WithServerOptions(opts ...repotest.ServerOption) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
+
+// New sets up a harness for a test: the cluster selected with
+// HELM_E2E_CLUSTER, a namespace for the releases of the test, and a repotest
+// server with uploads and an OCI registry. It skips the test when no cluster
+// is selected.
+func New(t *testing.T, opts ...Option) *Harness {
+	t.Helper()
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := startCluster(t)
+
+	client, err := kubernetes.NewForConfig(c.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := newNamespace(t, client)
+
+	serverOptions := []repotest.ServerOption{repotest.WithUploads(), repotest.WithOCIRegistry()}
+	if o.charts != "" {
+		serverOptions = append(serverOptions, repotest.WithChartSourceGlob(o.charts))
+	}
+	srv := repotest.NewTempServer(t, append(serverOptions, o.serverOptions...)...)
+	t.Cleanup(srv.Stop)
+	registryClient, err := srv.RegistryClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := cli.New()
+	settings.SetNamespace(ns)
+	settings.RepositoryConfig = filepath.Join(srv.Root(), "repositories.yaml")
+	settings.RegistryConfig = filepath.Join(srv.Root(), "config.json")
+	settings.RepositoryCache = t.TempDir()
+	settings.ContentCache = t.TempDir()
+
+	cfg := &action.Configuration{RegistryClient: registryClient}
+	if err := cfg.Init(kube.NewRESTClientGetterForConfig(c.Config, ns), ns, "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Harness{
+		Namespace: ns,
+		Config:    cfg,
+		Client:    client,
+		Settings:  settings,
+		Repo:      srv,
+		t:         t,
+	}
+	h.UpdateRepo()
+	return h
+}
+
+// newNamespace creates a namespace for a test, deleted once it is done.
+func newNamespace(t *testing.T, client kubernetes.Interface) string {
+	t.Helper()
+	// The names of namespaces are lowercase.
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "helm-e2e-" + strings.ToLower(rand.Text()[:10])}}
+	if _, err := client.CoreV1().Namespaces().Create(context.Background(), ns, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := client.CoreV1().Namespaces().Delete(context.Background(), ns.Name, metav1.DeleteOptions{}); err != nil {
+			t.Errorf("unable to delete namespace %s: %v", ns.Name, err)
+		}
+	})
+	return ns.Name
+}
+
+// UpdateRepo downloads the index of the chart repository of the harness to
+// the repository cache of its settings.
+func (h *Harness) UpdateRepo() {
+	h.t.Helper()
+	r, err := repo.NewChartRepository(&repo.Entry{Name: RepoName, URL: h.Repo.URL()}, getter.All(h.Settings))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	r.CachePath = h.Settings.RepositoryCache
+	if _, err := r.DownloadIndexFile(); err != nil {
+		h.t.Fatal(err)
+	}
+}
+
+// UploadChart uploads a chart archive, along with its provenance file when
+// there is one, to the chart repository of the harness and pushes it to its
+// OCI registry. It returns the oci:// reference of the chart; it is referenced
+// as RepoName/<name> in the chart repository.
+func (h *Harness) UploadChart(archive string) string {
+	h.t.Helper()
+	for _, f := range []struct{ name, path string }{{archive, repotest.UploadPath}, {archive + ".prov", repotest.ProvenanceUploadPath}} {
+		data, err := os.ReadFile(f.name)
+		if os.IsNotExist(err) && f.path == repotest.ProvenanceUploadPath {
+			continue
+		}
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		res, err := h.Repo.Client().Post(h.Repo.URL()+f.path+"?force", "application/octet-stream", bytes.NewReader(data))
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusCreated {
+			h.t.Fatalf("unable to upload %s: %s", f.name, res.Status)
+		}
+	}
+	h.UpdateRepo()
+
+	refs, err := h.Repo.PushCharts(archive)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return "oci://" + refs[0]
+}
+
+// LoadChart locates and loads a chart: a local chart, a URL, a reference to a
+// chart of a repository such as RepoName/<name>, or an oci:// reference.
+func (h *Harness) LoadChart(opts *action.ChartPathOptions, ref string) ci.Charter {
+	h.t.Helper()
+	path, err := opts.LocateChart(ref, h.Settings)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	chrt, err := loader.Load(path)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	return chrt
+}
+
+// Install installs a chart as a release in the namespace of the test, failing
+// the test when it fails. The action only waits for the hooks of the chart
+// unless configured otherwise.
+func (h *Harness) Install(name, ref string, vals map[string]interface{}, configure ...func(*action.Install)) *release.Release {
+	h.t.Helper()
+	install := action.NewInstall(h.Config)
+	install.ReleaseName = name
+	install.Namespace = h.Namespace
+	install.WaitStrategy = kube.HookOnlyStrategy
+	install.PlainHTTP = true
+	for _, c := range configure {
+		c(install)
+	}
+	rel, err := install.Run(h.LoadChart(&install.ChartPathOptions, ref), vals)
+	if err != nil {
+		h.t.Fatalf("unable to install %s: %v", name, err)
+	}
+	return rel
+}
+
+// Upgrade upgrades a release to a chart, failing the test when it fails. The
+// action only waits for the hooks of the chart unless configured otherwise.
+func (h *Harness) Upgrade(name, ref string, vals map[string]interface{}, configure ...func(*action.Upgrade)) *release.Release {
+	h.t.Helper()
+	upgrade := action.NewUpgrade(h.Config)
+	upgrade.Namespace = h.Namespace
+	upgrade.WaitStrategy = kube.HookOnlyStrategy
+	upgrade.PlainHTTP = true
+	for _, c := range configure {
+		c(upgrade)
+	}
+	rel, err := upgrade.Run(name, h.LoadChart(&upgrade.ChartPathOptions, ref), vals)
+	if err != nil {
+		h.t.Fatalf("unable to upgrade %s: %v", name, err)
+	}
+	return rel
+}
+
+// Rollback rolls a release back to a revision, failing the test when it
+// fails. The action only waits for the hooks of the chart unless configured
+// otherwise.
+func (h *Harness) Rollback(name string, revision int, configure ...func(*action.Rollback)) {
+	h.t.Helper()
+	rollback := action.NewRollback(h.Config)
+	rollback.Version = revision
+	rollback.WaitStrategy = kube.HookOnlyStrategy
+	for _, c := range configure {
+		c(rollback)
+	}
+	if err := rollback.Run(name); err != nil {
+		h.t.Fatalf("unable to roll %s back to revision %d: %v", name, revision, err)
+	}
+}
+
+// Uninstall uninstalls a release, failing the test when it fails. The action
+// only waits for the hooks of the chart unless configured otherwise.
+func (h *Harness) Uninstall(name string, configure ...func(*action.Uninstall)) *release.UninstallReleaseResponse {
+	h.t.Helper()
+	uninstall := action.NewUninstall(h.Config)
+	uninstall.WaitStrategy = kube.HookOnlyStrategy
+	for _, c := range configure {
+		c(uninstall)
+	}
+	res, err := uninstall.Run(name)
+	if err != nil {
+		h.t.Fatalf("unable to uninstall %s: %v", name, err)
+	}
+	return res
+}
+
+// History returns the revisions of a release, oldest first.
+func (h *Harness) History(name string) []*release.Release {
+	h.t.Helper()
+	revisions, err := action.NewHistory(h.Config).Run(name)
+	if err != nil {
+		h.t.Fatalf("unable to get the history of %s: %v", name, err)
+	}
+	return revisions
+}
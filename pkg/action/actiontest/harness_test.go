@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actiontest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"helm.sh/helm/v4/pkg/action/actiontest"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestMain(m *testing.M) {
+	actiontest.Main(m)
+}
+
+// greeting returns the greeting of the ConfigMap of a release of the
+// configmap chart.
+func greeting(t *testing.T, h *actiontest.Harness, name string) string {
+	t.Helper()
+	cm, err := h.Client.CoreV1().ConfigMaps(h.Namespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return cm.Data["greeting"]
+}
+
+func TestReleaseLifecycle(t *testing.T) {
+	h := actiontest.New(t)
+
+	rel := h.Install("lifecycle", "testdata/configmap", nil)
+	assert.Equal(t, release.StatusDeployed, rel.Info.Status)
+	assert.Equal(t, "hello", greeting(t, h, "lifecycle"))
+
+	rel = h.Upgrade("lifecycle", "testdata/configmap", map[string]interface{}{"data": map[string]interface{}{"greeting": "hi"}})
+	assert.Equal(t, 2, rel.Version)
+	assert.Equal(t, "hi", greeting(t, h, "lifecycle"))
+
+	h.Rollback("lifecycle", 1)
+	assert.Equal(t, "hello", greeting(t, h, "lifecycle"))
+	history := h.History("lifecycle")
+	require.Len(t, history, 3)
+	assert.Equal(t, release.StatusSuperseded, history[1].Info.Status)
+	assert.Equal(t, release.StatusDeployed, history[2].Info.Status)
+
+	h.Uninstall("lifecycle")
+	_, err := h.Client.CoreV1().ConfigMaps(h.Namespace).Get(context.Background(), "lifecycle", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "expected the ConfigMap to be deleted, got %v", err)
+}
+
+func TestInstallFromRepositories(t *testing.T) {
+	h := actiontest.New(t)
+
+	c, err := loader.Load("testdata/configmap")
+	require.NoError(t, err)
+	archive, err := chartutil.Save(c, t.TempDir())
+	require.NoError(t, err)
+	ref := h.UploadChart(archive)
+
+	rel := h.Install("from-repo", actiontest.RepoName+"/configmap", nil)
+	assert.Equal(t, "0.1.0", rel.Chart.Metadata.Version)
+	assert.Equal(t, "hello", greeting(t, h, "from-repo"))
+
+	rel = h.Install("from-oci", ref, nil)
+	assert.Equal(t, "0.1.0", rel.Chart.Metadata.Version)
+	assert.Equal(t, "hello", greeting(t, h, "from-oci"))
+}
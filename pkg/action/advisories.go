@@ -0,0 +1,95 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+// AdvisoryPolicy blocks charts that are, or that have dependencies that are,
+// affected by known security advisories.
+type AdvisoryPolicy struct {
+	// Feed is the feed of the advisories.
+	Feed *chartutil.AdvisoryFeed
+	// Threshold is the lowest severity of the advisories that block charts.
+	// Advisories without a known severity block charts whatever the
+	// threshold.
+	Threshold chartutil.Severity
+}
+
+// LoadAdvisoryFeed loads a feed of OSV advisories from a URL, with the getter
+// of its scheme, or else from a file.
+func LoadAdvisoryFeed(source string, getters getter.Providers) (*chartutil.AdvisoryFeed, error) {
+	var data []byte
+	// Single letter schemes are Windows drive letters.
+	if u, err := url.Parse(source); err == nil && len(u.Scheme) > 1 {
+		g, err := getters.ByScheme(u.Scheme)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := g.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch advisories from %s: %w", source, err)
+		}
+		data = buf.Bytes()
+	} else {
+		data, err = os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+	}
+	feed, err := chartutil.ParseAdvisoryFeed(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	return feed, nil
+}
+
+// Matches returns the advisories affecting the chart tree with a severity at
+// or above the threshold of the policy. repository is the repository the
+// chart comes from, or "" when it is not known.
+func (p *AdvisoryPolicy) Matches(c *chart.Chart, repository string) []chartutil.AdvisoryMatch {
+	var matches []chartutil.AdvisoryMatch
+	for _, m := range chartutil.Advisories(c, p.Feed, repository) {
+		if m.Severity >= p.Threshold {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Check returns an error listing the advisories affecting the chart tree with
+// a severity at or above the threshold of the policy. repository is the
+// repository the chart comes from, or "" when it is not known.
+func (p *AdvisoryPolicy) Check(c *chart.Chart, repository string) error {
+	matches := p.Matches(c, repository)
+	if len(matches) == 0 {
+		return nil
+	}
+	lines := make([]string, len(matches))
+	for i, m := range matches {
+		lines[i] = m.String()
+	}
+	return fmt.Errorf("charts affected by security advisories:\n  %s", strings.Join(lines, "\n  "))
+}
@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+const testAdvisoryFeed = `[
+  {"id": "HELM-1", "affected": [{"package": {"name": "hello"}, "versions": ["0.1.0"]}], "database_specific": {"severity": "LOW"}},
+  {"id": "HELM-2", "affected": [{"package": {"name": "dep"}, "versions": ["0.1.0"]}], "database_specific": {"severity": "CRITICAL"}}
+]`
+
+func TestLoadAdvisoryFeed(t *testing.T) {
+	feedFile := filepath.Join(t.TempDir(), "advisories.json")
+	require.NoError(t, os.WriteFile(feedFile, []byte(testAdvisoryFeed), 0644))
+
+	feed, err := LoadAdvisoryFeed(feedFile, nil)
+	require.NoError(t, err)
+	assert.Len(t, feed.Advisories, 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(testAdvisoryFeed))
+	}))
+	defer srv.Close()
+
+	feed, err = LoadAdvisoryFeed(srv.URL+"/advisories.json", getter.All(cli.New()))
+	require.NoError(t, err)
+	assert.Len(t, feed.Advisories, 2)
+
+	_, err = LoadAdvisoryFeed("ftp://example.com/advisories.json", getter.All(cli.New()))
+	assert.Error(t, err)
+}
+
+func TestAdvisoryPolicyCheck(t *testing.T) {
+	feed, err := chartutil.ParseAdvisoryFeed([]byte(testAdvisoryFeed))
+	require.NoError(t, err)
+
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "hello", Version: "0.1.0"}}
+	c.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "dep", Version: "0.1.0"}})
+
+	p := &AdvisoryPolicy{Feed: feed}
+	assert.EqualError(t, p.Check(c, ""), "charts affected by security advisories:\n  hello 0.1.0: HELM-1 (low)\n  hello/charts/dep 0.1.0: HELM-2 (critical)")
+
+	p.Threshold = chartutil.SeverityHigh
+	assert.EqualError(t, p.Check(c, ""), "charts affected by security advisories:\n  hello/charts/dep 0.1.0: HELM-2 (critical)")
+
+	c.SetDependencies()
+	assert.NoError(t, p.Check(c, ""))
+}
+
+func TestInstallRelease_AdvisoryPolicy(t *testing.T) {
+	feed, err := chartutil.ParseAdvisoryFeed([]byte(testAdvisoryFeed))
+	require.NoError(t, err)
+
+	instAction := installAction(t)
+	instAction.AdvisoryPolicy = &AdvisoryPolicy{Feed: feed}
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	assert.ErrorContains(t, err, "hello 0.1.0: HELM-1 (low)")
+}
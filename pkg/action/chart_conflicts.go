@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// checkChartConflicts refuses to install a chart when it, or one of its
+// enabled dependencies, conflicts with a chart deployed in the cluster, or
+// when a deployed chart declares a conflict with it. When the releases of the
+// cluster cannot be listed, only the charts declaring conflicts are refused,
+// as only their conflicts are known to be checked.
+func (i *Install) checkChartConflicts(chrt *chart.Chart) error {
+	others, err := i.cfg.deployedReleasesOfCluster()
+	if err != nil {
+		if !hasConflicts(chrt) {
+			slog.Debug("unable to list the deployed releases to check the conflicts they declare with the chart", slog.Any("error", err))
+			return nil
+		}
+		return fmt.Errorf("unable to list the deployed releases to check the conflicts of the chart: %w", err)
+	}
+	var found []string
+	for _, rls := range others {
+		if rls.Chart == nil || (rls.Name == i.ReleaseName && rls.Namespace == i.Namespace) {
+			continue
+		}
+		where := fmt.Sprintf("release %s in namespace %s", rls.Name, rls.Namespace)
+		found = append(found, chartConflicts(chrt, rls.Chart, where)...)
+		found = append(found, chartConflicts(rls.Chart, chrt, where)...)
+	}
+	if len(found) > 0 {
+		return classify(ErrConflict, fmt.Errorf("chart %s conflicts with deployed charts, use --ignore-chart-conflicts to install it anyway:\n  %s", chrt.Name(), strings.Join(found, "\n  ")))
+	}
+	return nil
+}
+
+// chartConflicts describes the conflicts the charts of a chart tree declare
+// with the charts of another tree, deployed as the given release.
+func chartConflicts(declaring, other *chart.Chart, release string) []string {
+	var found []string
+	walkCharts(declaring, func(d *chart.Chart) {
+		for _, c := range d.Metadata.Conflicts {
+			walkCharts(other, func(o *chart.Chart) {
+				if !c.Matches(o.Name(), o.Metadata.Version) {
+					return
+				}
+				msg := fmt.Sprintf("chart %s-%s conflicts with chart %s-%s of %s", d.Name(), d.Metadata.Version, o.Name(), o.Metadata.Version, release)
+				if c.Reason != "" {
+					msg += ": " + c.Reason
+				}
+				found = append(found, msg)
+			})
+		}
+	})
+	return found
+}
+
+// hasConflicts reports whether a chart, or one of its dependencies, declares
+// conflicts.
+func hasConflicts(chrt *chart.Chart) bool {
+	found := false
+	walkCharts(chrt, func(c *chart.Chart) {
+		found = found || len(c.Metadata.Conflicts) > 0
+	})
+	return found
+}
+
+// walkCharts calls fn for a chart and all its dependencies with metadata.
+func walkCharts(chrt *chart.Chart, fn func(*chart.Chart)) {
+	if chrt.Metadata == nil {
+		return
+	}
+	fn(chrt)
+	for _, dep := range chrt.Dependencies() {
+		walkCharts(dep, fn)
+	}
+}
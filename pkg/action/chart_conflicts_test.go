@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func withConflicts(conflicts ...*chart.Conflict) chartOption {
+	return func(opts *chartOptions) {
+		opts.Metadata.Conflicts = conflicts
+	}
+}
+
+func withVersion(version string) chartOption {
+	return func(opts *chartOptions) {
+		opts.Metadata.Version = version
+	}
+}
+
+func TestInstallChartConflicts(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		deployed *chart.Chart
+		chart    *chart.Chart
+		ignore   bool
+		wantErr  string
+	}{
+		{
+			name:     "conflicting chart",
+			deployed: buildChart(withName("ingress-nginx"), withVersion("4.11.0")),
+			chart:    buildChart(withName("traefik"), withConflicts(&chart.Conflict{Name: "ingress-nginx", Version: ">=4.0.0", Reason: "both claim the nginx ingress class"})),
+			wantErr:  "chart traefik-0.1.0 conflicts with chart ingress-nginx-4.11.0 of release deployed in namespace other: both claim the nginx ingress class",
+		},
+		{
+			name:     "conflicting chart ignored",
+			deployed: buildChart(withName("ingress-nginx"), withVersion("4.11.0")),
+			chart:    buildChart(withName("traefik"), withConflicts(&chart.Conflict{Name: "ingress-nginx"})),
+			ignore:   true,
+		},
+		{
+			name:     "version out of the range",
+			deployed: buildChart(withName("ingress-nginx"), withVersion("3.40.0")),
+			chart:    buildChart(withName("traefik"), withConflicts(&chart.Conflict{Name: "ingress-nginx", Version: ">=4.0.0"})),
+		},
+		{
+			name:     "conflicting dependency",
+			deployed: buildChart(withName("platform"), withDependency(withName("ingress-nginx"), withVersion("4.11.0"))),
+			chart:    buildChart(withName("app"), withDependency(withName("traefik"), withConflicts(&chart.Conflict{Name: "ingress-nginx"}))),
+			wantErr:  "chart traefik-0.1.0 conflicts with chart ingress-nginx-4.11.0 of release deployed in namespace other",
+		},
+		{
+			name:     "conflict declared by the deployed chart",
+			deployed: buildChart(withName("ingress-nginx"), withConflicts(&chart.Conflict{Name: "traefik"})),
+			chart:    buildChart(withName("traefik"), withConflicts(&chart.Conflict{Name: "haproxy"})),
+			wantErr:  "chart ingress-nginx-0.1.0 conflicts with chart traefik-0.1.0 of release deployed in namespace other",
+		},
+		{
+			name:     "conflict declared by the deployed chart with a chart without conflicts",
+			deployed: buildChart(withName("ingress-nginx"), withConflicts(&chart.Conflict{Name: "traefik"})),
+			chart:    buildChart(withName("traefik")),
+			wantErr:  "chart ingress-nginx-0.1.0 conflicts with chart traefik-0.1.0 of release deployed in namespace other",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			instAction := installAction(t)
+			instAction.IgnoreChartConflicts = tt.ignore
+			deployed := namedReleaseStub("deployed", release.StatusDeployed)
+			deployed.Namespace = "other"
+			deployed.Chart = tt.deployed
+			require.NoError(t, instAction.cfg.Releases.Create(deployed))
+
+			_, err := instAction.RunWithContext(context.Background(), tt.chart, map[string]interface{}{})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
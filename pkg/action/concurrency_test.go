@@ -0,0 +1,173 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// These tests share a single Configuration between actions running in
+// parallel. They are meant to be run with the race detector enabled.
+
+const concurrency = 10
+
+func TestConfigurationConcurrentInstalls(t *testing.T) {
+	cfg := actionConfigFixture(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency)
+	for n := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instAction := installActionWithConfig(cfg)
+			instAction.ReleaseName = fmt.Sprintf("concurrent-%d", n)
+			_, err := instAction.Run(buildChart(), nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	rels, err := cfg.Releases.ListDeployed()
+	require.NoError(t, err)
+	assert.Len(t, rels, concurrency)
+}
+
+func TestConfigurationConcurrentUpgrades(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	for n := range concurrency {
+		rel := releaseStub()
+		rel.Name = fmt.Sprintf("concurrent-%d", n)
+		rel.Info.Status = release.StatusDeployed
+		require.NoError(t, cfg.Releases.Create(rel))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrency)
+	for n := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			upAction := NewUpgrade(cfg)
+			upAction.Namespace = "spaced"
+			// Every upgrade uses its own history limit.
+			upAction.MaxHistory = n + 1
+			_, err := upAction.Run(fmt.Sprintf("concurrent-%d", n), buildChart(), nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Zero(t, cfg.Releases.MaxHistory, "upgrades must not modify the shared storage")
+}
+
+func TestConfigurationConcurrentClientOnlyInstalls(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	caps := cfg.Capabilities
+	kubeClient := cfg.KubeClient
+	releases := cfg.Releases
+
+	var wg sync.WaitGroup
+	for n := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instAction := installActionWithConfig(cfg)
+			instAction.ReleaseName = fmt.Sprintf("template-%d", n)
+			instAction.ClientOnly = true
+			instAction.DryRun = true
+			instAction.KubeVersion = &common.KubeVersion{Version: fmt.Sprintf("v1.%d.0", 20+n), Major: "1", Minor: fmt.Sprint(20 + n)}
+			rel, err := instAction.Run(buildChart(), nil)
+			if assert.NoError(t, err) {
+				assert.Equal(t, instAction.ReleaseName, rel.Name)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Client only installs mock the cluster in a configuration of their own.
+	assert.Same(t, caps, cfg.Capabilities)
+	assert.Same(t, releases, cfg.Releases)
+	assert.Equal(t, kubeClient, cfg.KubeClient)
+}
+
+func TestConfigurationConcurrentCapabilities(t *testing.T) {
+	fakeDiscovery := &discoveryfake.FakeDiscovery{
+		Fake:               &k8stesting.Fake{},
+		FakedServerVersion: &version.Info{GitVersion: "v1.33.0", Major: "1", Minor: "33"},
+	}
+	cfg := actionConfigFixture(t)
+	cfg.Capabilities = nil
+	cfg.RESTClientGetter = &discoveryGetter{discovery: &cachedDiscovery{FakeDiscovery: fakeDiscovery}}
+
+	var wg sync.WaitGroup
+	results := make(chan *common.Capabilities, concurrency)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			caps, err := cfg.getCapabilities()
+			assert.NoError(t, err)
+			results <- caps
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	first := <-results
+	assert.Equal(t, "v1.33.0", first.KubeVersion.Version)
+	for caps := range results {
+		assert.Same(t, first, caps, "capabilities must be discovered once")
+	}
+}
+
+// discoveryGetter is a RESTClientGetter only able to return a discovery client.
+type discoveryGetter struct {
+	RESTClientGetter
+	discovery discovery.CachedDiscoveryInterface
+}
+
+func (g *discoveryGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	return g.discovery, nil
+}
+
+type cachedDiscovery struct {
+	*discoveryfake.FakeDiscovery
+}
+
+func (d *cachedDiscovery) Fresh() bool { return true }
+
+func (d *cachedDiscovery) Invalidate() {}
@@ -0,0 +1,202 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+// ConfigChecksumAnnotation is the pod template annotation holding the checksum
+// of the ConfigMaps and Secrets of the release a workload references. As the
+// pod template changes along with them, the workload rolls out when its
+// configuration changes.
+const ConfigChecksumAnnotation = "helm.sh/config-checksum"
+
+// podTemplatePaths are the paths to the pod templates of the workload kinds.
+var podTemplatePaths = map[string][]string{
+	"Deployment":            {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// injectConfigChecksums annotates the pod templates of the workloads among
+// the manifests with the checksum of the ConfigMaps and Secrets among the
+// manifests they reference. Manifests that set no namespace are in the given
+// one. Workloads referencing none of them are left as they are.
+//
+// The annotation is set on the YAML nodes of the workloads, so their fields
+// keep their order and comments.
+func injectConfigChecksums(manifests []releaseutil.Manifest, namespace string) error {
+	objectNamespace := func(node *kyaml.RNode) string {
+		if ns := node.GetNamespace(); ns != "" {
+			return ns
+		}
+		return namespace
+	}
+
+	configs := make(map[string]string)
+	for _, m := range manifests {
+		if m.Head == nil || m.Head.Metadata == nil || m.Head.Version != "v1" {
+			continue
+		}
+		if m.Head.Kind == "ConfigMap" || m.Head.Kind == "Secret" {
+			node, err := kyaml.Parse(m.Content)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.Name, err)
+			}
+			configs[objectNamespace(node)+"/"+m.Head.Kind+"/"+m.Head.Metadata.Name] = m.Content
+		}
+	}
+	if len(configs) == 0 {
+		return nil
+	}
+
+	for i, m := range manifests {
+		if m.Head == nil {
+			continue
+		}
+		templatePath, ok := podTemplatePaths[m.Head.Kind]
+		if !ok {
+			continue
+		}
+		node, err := kyaml.Parse(m.Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		obj, err := node.Map()
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		template, found, err := unstructured.NestedMap(obj, templatePath...)
+		if err != nil || !found {
+			continue
+		}
+
+		// A pod references the configuration of its own namespace.
+		ns := objectNamespace(node)
+		var refs []string
+		for _, ref := range configReferences(template) {
+			ref = ns + "/" + ref
+			if _, ok := configs[ref]; ok && !slices.Contains(refs, ref) {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			continue
+		}
+		slices.Sort(refs)
+		h := sha256.New()
+		for _, ref := range refs {
+			fmt.Fprintf(h, "%s\n%s\n", ref, configs[ref])
+		}
+		sum := hex.EncodeToString(h.Sum(nil))
+
+		annotationsPath := append(slices.Clone(templatePath), "metadata", "annotations")
+		if err := node.PipeE(
+			kyaml.LookupCreate(kyaml.MappingNode, annotationsPath...),
+			kyaml.SetField(ConfigChecksumAnnotation, kyaml.NewStringRNode(sum)),
+		); err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		content, err := node.String()
+		if err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		manifests[i].Content = strings.TrimSuffix(content, "\n")
+	}
+	return nil
+}
+
+// configReferences lists the ConfigMaps and Secrets a pod template references
+// through its volumes and the environment of its containers, as Kind/name.
+func configReferences(template map[string]interface{}) []string {
+	var refs []string
+	ref := func(kind string, obj map[string]interface{}, field string) {
+		if name, ok := obj[field].(string); ok && name != "" {
+			refs = append(refs, kind+"/"+name)
+		}
+	}
+
+	for _, v := range nestedMaps(template, "spec", "volumes") {
+		if cm, ok := v["configMap"].(map[string]interface{}); ok {
+			ref("ConfigMap", cm, "name")
+		}
+		if s, ok := v["secret"].(map[string]interface{}); ok {
+			ref("Secret", s, "secretName")
+		}
+		for _, src := range nestedMaps(v, "projected", "sources") {
+			if cm, ok := src["configMap"].(map[string]interface{}); ok {
+				ref("ConfigMap", cm, "name")
+			}
+			if s, ok := src["secret"].(map[string]interface{}); ok {
+				ref("Secret", s, "name")
+			}
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		for _, c := range nestedMaps(template, "spec", field) {
+			for _, from := range nestedMaps(c, "envFrom") {
+				if cm, ok := from["configMapRef"].(map[string]interface{}); ok {
+					ref("ConfigMap", cm, "name")
+				}
+				if s, ok := from["secretRef"].(map[string]interface{}); ok {
+					ref("Secret", s, "name")
+				}
+			}
+			for _, env := range nestedMaps(c, "env") {
+				valueFrom, ok := env["valueFrom"].(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if cm, ok := valueFrom["configMapKeyRef"].(map[string]interface{}); ok {
+					ref("ConfigMap", cm, "name")
+				}
+				if s, ok := valueFrom["secretKeyRef"].(map[string]interface{}); ok {
+					ref("Secret", s, "name")
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// nestedMaps returns the objects of the list at the given path, skipping
+// anything else.
+func nestedMaps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	list, _, _ := unstructured.NestedSlice(obj, fields...)
+	var items []map[string]interface{}
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			items = append(items, m)
+		}
+	}
+	return items
+}
@@ -0,0 +1,226 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+const checksumConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  level: %s
+`
+
+const checksumSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+stringData:
+  password: hunter2
+`
+
+const checksumDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        envFrom:
+        - configMapRef:
+            name: settings
+        env:
+        - name: PASSWORD
+          valueFrom:
+            secretKeyRef:
+              name: credentials
+              key: password
+`
+
+const checksumCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          volumes:
+          - name: settings
+            configMap:
+              name: settings
+          containers:
+          - name: report
+            image: busybox
+`
+
+const checksumUnrelated = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: proxy
+spec:
+  template:
+    spec:
+      volumes:
+      - name: external
+        secret:
+          secretName: external
+      containers:
+      - name: proxy
+        image: envoy
+`
+
+func sortedChecksumManifests(t *testing.T, level string) []releaseutil.Manifest {
+	t.Helper()
+	files := map[string]string{
+		"chart/templates/configmap.yaml": strings.Replace(checksumConfigMap, "%s", level, 1),
+		"chart/templates/secret.yaml":    checksumSecret,
+		"chart/templates/workloads.yaml": checksumDeployment + "---\n" + checksumCronJob + "---\n" + checksumUnrelated,
+	}
+	_, manifests, err := releaseutil.SortManifests(files, nil, releaseutil.InstallOrder)
+	require.NoError(t, err)
+	return manifests
+}
+
+func podTemplateChecksums(t *testing.T, manifests []releaseutil.Manifest) map[string]string {
+	t.Helper()
+	checksums := make(map[string]string)
+	for _, m := range manifests {
+		templatePath, ok := podTemplatePaths[m.Head.Kind]
+		if !ok {
+			continue
+		}
+		var obj map[string]interface{}
+		require.NoError(t, yaml.Unmarshal([]byte(m.Content), &obj))
+		for _, field := range append(templatePath, "metadata", "annotations") {
+			obj, _ = obj[field].(map[string]interface{})
+		}
+		if sum, ok := obj[ConfigChecksumAnnotation].(string); ok {
+			checksums[m.Head.Metadata.Name] = sum
+		}
+	}
+	return checksums
+}
+
+func TestInjectConfigChecksums(t *testing.T) {
+	manifests := sortedChecksumManifests(t, "info")
+	require.NoError(t, injectConfigChecksums(manifests, "default"))
+	checksums := podTemplateChecksums(t, manifests)
+
+	// Workloads referencing none of the configuration of the release are left alone.
+	assert.Len(t, checksums, 2)
+	assert.Contains(t, checksums, "web")
+	assert.Contains(t, checksums, "report")
+	assert.NotEqual(t, checksums["web"], checksums["report"], "the deployment also references the secret")
+	for _, m := range manifests {
+		if m.Head.Metadata.Name == "web" {
+			// The fields of the workload keep their order.
+			want := checksumDeployment + "    metadata:\n      annotations:\n        " + ConfigChecksumAnnotation + ": " + checksums["web"]
+			assert.Equal(t, want, m.Content)
+		}
+		if m.Head.Metadata.Name == "proxy" {
+			assert.Equal(t, strings.TrimSpace(checksumUnrelated), m.Content)
+		}
+	}
+
+	// The checksums are stable, and follow the configuration.
+	again := sortedChecksumManifests(t, "info")
+	require.NoError(t, injectConfigChecksums(again, "default"))
+	assert.Equal(t, checksums, podTemplateChecksums(t, again))
+
+	changed := sortedChecksumManifests(t, "debug")
+	require.NoError(t, injectConfigChecksums(changed, "default"))
+	for name, sum := range podTemplateChecksums(t, changed) {
+		assert.NotEqual(t, checksums[name], sum, "checksum of %s", name)
+	}
+}
+
+func TestInjectConfigChecksumsNamespaces(t *testing.T) {
+	settings := strings.Replace(checksumConfigMap, "%s", "info", 1)
+	configMap := func(namespace string) string {
+		return strings.Replace(settings, "  name: settings\n", "  name: settings\n  namespace: "+namespace+"\n", 1)
+	}
+	deployment := func(namespace string) string {
+		return strings.Replace(checksumDeployment, "  name: web\n", "  name: web\n  namespace: "+namespace+"\n", 1)
+	}
+	checksum := func(files map[string]string) string {
+		t.Helper()
+		_, manifests, err := releaseutil.SortManifests(files, nil, releaseutil.InstallOrder)
+		require.NoError(t, err)
+		require.NoError(t, injectConfigChecksums(manifests, "default"))
+		return podTemplateChecksums(t, manifests)["web"]
+	}
+
+	// A workload does not reference the configuration of other namespaces.
+	assert.Empty(t, checksum(map[string]string{
+		"chart/templates/configmap.yaml":  configMap("other"),
+		"chart/templates/deployment.yaml": checksumDeployment,
+	}))
+	assert.Empty(t, checksum(map[string]string{
+		"chart/templates/configmap.yaml":  settings,
+		"chart/templates/deployment.yaml": deployment("other"),
+	}))
+	assert.NotEmpty(t, checksum(map[string]string{
+		"chart/templates/configmap.yaml":  configMap("other"),
+		"chart/templates/deployment.yaml": deployment("other"),
+	}))
+
+	// Manifests setting no namespace are in the namespace of the release.
+	assert.NotEmpty(t, checksum(map[string]string{
+		"chart/templates/configmap.yaml":  configMap("default"),
+		"chart/templates/deployment.yaml": checksumDeployment,
+	}))
+	assert.NotEmpty(t, checksum(map[string]string{
+		"chart/templates/configmap.yaml":  settings,
+		"chart/templates/deployment.yaml": deployment("default"),
+	}))
+}
+
+func TestInstallConfigChecksums(t *testing.T) {
+	instAction := installAction(t)
+	instAction.ConfigChecksums = true
+	ch := buildChart()
+	ch.Templates = append(ch.Templates,
+		&common.File{Name: "templates/configmap.yaml", Data: []byte(strings.Replace(checksumConfigMap, "%s", "info", 1))},
+		&common.File{Name: "templates/deployment.yaml", Data: []byte(checksumDeployment)},
+	)
+	rel, err := instAction.Run(ch, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Contains(t, rel.Manifest, ConfigChecksumAnnotation+": ")
+
+	instAction = installAction(t)
+	rel, err = instAction.Run(ch, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.NotContains(t, rel.Manifest, ConfigChecksumAnnotation)
+}
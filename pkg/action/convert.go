@@ -0,0 +1,669 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	kyaml "sigs.k8s.io/kustomize/kyaml/yaml"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// convertNamePrefixKey is the key of the values of a converted chart holding
+// the prefix of the names of its resources.
+const convertNamePrefixKey = "namePrefix"
+
+// Convert is the action for converting a directory of Kubernetes manifests,
+// or a kustomization, into a chart.
+//
+// It provides the implementation of 'helm convert'.
+type Convert struct {
+	// Name is the name of the chart. It defaults to the name of the converted
+	// directory.
+	Name string
+	// Version is the version of the chart.
+	Version string
+	// AppVersion is the version of the application of the chart.
+	AppVersion string
+}
+
+// ConvertParameter is a field of a converted resource that was extracted to
+// the values of the chart.
+type ConvertParameter struct {
+	// Resource is the kind and the name of the resource, e.g.
+	// "Deployment/web".
+	Resource string
+	// Field is the path of the field in the resource.
+	Field string
+	// Key is the path of the value the field was extracted to.
+	Key string
+	// Value is the value of the field in the manifests.
+	Value string
+}
+
+// String returns the parameter as "Deployment/web spec.replicas: web.replicas".
+func (p ConvertParameter) String() string {
+	return fmt.Sprintf("%s %s: %s", p.Resource, p.Field, p.Key)
+}
+
+// ConvertResult is the result of converting manifests into a chart.
+type ConvertResult struct {
+	// Chart is the converted chart.
+	Chart *chart.Chart
+	// Kustomization is true when the manifests were built from a
+	// kustomization.
+	Kustomization bool
+	// Resources is the number of converted resources.
+	Resources int
+	// Parameters are the fields extracted to the values of the chart.
+	Parameters []ConvertParameter
+}
+
+// NewConvert creates a new Convert object.
+func NewConvert() *Convert {
+	return &Convert{Version: "0.1.0"}
+}
+
+// Run converts the manifests of the directory src into a chart.
+//
+// When src has a kustomization file, the kustomization is built and its
+// resources are converted. Otherwise, the YAML and JSON files of src and of
+// its subdirectories are read.
+//
+// Each resource is written to a template of the chart, without its namespace
+// for the chart to be installed in the namespace of the release. The names of
+// the resources, and the references to them in pod specs, ingresses and role
+// bindings, are prefixed with the namePrefix value. The images of the
+// containers and the replicas of the workloads are extracted to values.
+func (c *Convert) Run(src string) (*ConvertResult, error) {
+	if fi, err := os.Stat(src); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", src)
+	}
+	name := c.Name
+	if name == "" {
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return nil, err
+		}
+		name = filepath.Base(abs)
+	}
+
+	result := &ConvertResult{Kustomization: isKustomization(src)}
+	var nodes []*kyaml.RNode
+	var err error
+	if result.Kustomization {
+		nodes, err = buildKustomization(src)
+	} else {
+		nodes, err = readManifests(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no Kubernetes resources found in %s", src)
+	}
+	result.Resources = len(nodes)
+
+	conv := &converter{
+		names:  make(map[string]bool),
+		keys:   map[string]bool{convertNamePrefixKey: true, common.GlobalKey: true},
+		files:  make(map[string]bool),
+		tokens: make(map[string]string),
+	}
+	for _, node := range nodes {
+		conv.names[node.GetKind()+"/"+node.GetName()] = true
+	}
+	var templates []*common.File
+	for _, node := range nodes {
+		tpl, err := conv.convert(node)
+		if err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", node.GetKind(), node.GetName(), err)
+		}
+		templates = append(templates, tpl)
+	}
+	result.Parameters = conv.parameters
+
+	values, err := conv.valuesFile()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := common.ReadValues(values)
+	if err != nil {
+		return nil, err
+	}
+	result.Chart = &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion:  chart.APIVersionV2,
+			Name:        name,
+			Description: fmt.Sprintf("A Helm chart converted from the manifests of %s", filepath.Base(filepath.Clean(src))),
+			Type:        "application",
+			Version:     c.Version,
+			AppVersion:  c.AppVersion,
+		},
+		Templates: templates,
+		Values:    vals,
+		Raw:       []*common.File{{Name: chartutil.ValuesfileName, Data: values}},
+	}
+	if err := result.Chart.Validate(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isKustomization returns whether the directory has a kustomization file.
+func isKustomization(dir string) bool {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// buildKustomization builds the kustomization in dir.
+func buildKustomization(dir string) ([]*kyaml.RNode, error) {
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build the kustomization: %w", err)
+	}
+	return resources.ToRNodeSlice(), nil
+}
+
+// readManifests reads the resources of the YAML and JSON files of dir and of
+// its subdirectories, skipping the hidden ones.
+func readManifests(dir string) ([]*kyaml.RNode, error) {
+	var nodes []*kyaml.RNode
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		read, err := (&kio.ByteReader{Reader: bytes.NewReader(data), OmitReaderAnnotations: true}).Read()
+		if err != nil {
+			return fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		for _, node := range read {
+			if node.GetKind() == "" || node.GetApiVersion() == "" || node.GetName() == "" {
+				return fmt.Errorf("%s: not a Kubernetes resource: apiVersion, kind and metadata.name are required", path)
+			}
+			if strings.EqualFold(filepath.Ext(path), ".json") {
+				// The resources of JSON files are written in the block
+				// style of the other templates.
+				clearStyle(node.YNode())
+			}
+		}
+		nodes = append(nodes, read...)
+		return nil
+	})
+	return nodes, err
+}
+
+// clearStyle clears the style of a node and of its children.
+func clearStyle(node *kyaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// converter converts resources into templates, collecting the values they
+// are parameterized with.
+type converter struct {
+	// names are the kinds and names of the converted resources.
+	names map[string]bool
+	// keys are the top-level keys of the values.
+	keys map[string]bool
+	// files are the names of the templates.
+	files map[string]bool
+	// tokens map the placeholders set in the resources to the template
+	// actions replacing them once the resources are serialized.
+	tokens map[string]string
+
+	values     []convertValues
+	parameters []ConvertParameter
+}
+
+// convertValues are the values extracted from a resource.
+type convertValues struct {
+	resource string
+	key      string
+	values   map[string]interface{}
+}
+
+// podSpecPath returns the path of the pod spec of a workload, or nil if the
+// kind is not a workload.
+func podSpecPath(kind string) []string {
+	switch kind {
+	case "Pod":
+		return []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		return []string{"spec", "template", "spec"}
+	case "CronJob":
+		return []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	return nil
+}
+
+// hasReplicas returns whether a workload kind has replicas.
+func hasReplicas(kind string) bool {
+	switch kind {
+	case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController":
+		return true
+	}
+	return false
+}
+
+func (cv *converter) convert(node *kyaml.RNode) (*common.File, error) {
+	kind, name := node.GetKind(), node.GetName()
+	resource := kind + "/" + name
+
+	if err := node.PipeE(kyaml.Lookup("metadata"), kyaml.Clear("namespace")); err != nil {
+		return nil, err
+	}
+	if err := cv.prefixName(node, "metadata", "name"); err != nil {
+		return nil, err
+	}
+
+	vals := convertValues{resource: resource, key: cv.uniqueKey(cv.keys, name), values: map[string]interface{}{}}
+	if hasReplicas(kind) {
+		replicas, err := node.Pipe(kyaml.Lookup("spec", "replicas"))
+		if err != nil {
+			return nil, err
+		}
+		if replicas != nil {
+			var n int
+			if err := replicas.YNode().Decode(&n); err != nil {
+				return nil, fmt.Errorf("spec.replicas: %w", err)
+			}
+			vals.values["replicas"] = n
+			cv.parameters = append(cv.parameters, ConvertParameter{
+				Resource: resource,
+				Field:    "spec.replicas",
+				Key:      vals.key + ".replicas",
+				Value:    replicas.YNode().Value,
+			})
+			cv.parameterize(replicas.YNode(), fmt.Sprintf("{{ .Values.%s.replicas }}", vals.key))
+		}
+	}
+	if path := podSpecPath(kind); path != nil {
+		if err := cv.convertPodSpec(node, path, &vals); err != nil {
+			return nil, err
+		}
+	}
+	switch kind {
+	case "Ingress":
+		if err := cv.convertIngress(node); err != nil {
+			return nil, err
+		}
+	case "RoleBinding", "ClusterRoleBinding":
+		if err := cv.convertRoleBinding(node); err != nil {
+			return nil, err
+		}
+	}
+	if len(vals.values) > 0 {
+		cv.keys[vals.key] = true
+		cv.values = append(cv.values, vals)
+	}
+
+	out, err := node.String()
+	if err != nil {
+		return nil, err
+	}
+	// The manifests are not templates: their own delimiters are escaped
+	// before the placeholders are replaced with the template actions.
+	out = strings.ReplaceAll(out, "{{", `{{ "{{" }}`)
+	for token, action := range cv.tokens {
+		out = strings.ReplaceAll(out, token, action)
+	}
+
+	base := strings.ToLower(kind + "-" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name))
+	file := base
+	for i := 2; cv.files[file]; i++ {
+		file = fmt.Sprintf("%s-%d", base, i)
+	}
+	cv.files[file] = true
+	return &common.File{
+		Name: filepath.ToSlash(filepath.Join(chartutil.TemplatesDir, file+".yaml")),
+		Data: []byte(out),
+	}, nil
+}
+
+// convertPodSpec extracts the images of the containers of a pod spec, and
+// prefixes its references to the converted resources.
+func (cv *converter) convertPodSpec(node *kyaml.RNode, path []string, vals *convertValues) error {
+	spec, err := node.Pipe(kyaml.Lookup(path...))
+	if err != nil || spec == nil {
+		return err
+	}
+	field := strings.Join(path, ".")
+	images := map[string]interface{}{}
+	containerKeys := make(map[string]bool)
+	for _, list := range []string{"initContainers", "containers"} {
+		containers, err := spec.Pipe(kyaml.Lookup(list))
+		if err != nil {
+			return err
+		}
+		if containers == nil {
+			continue
+		}
+		elements, err := containers.Elements()
+		if err != nil {
+			return err
+		}
+		for _, container := range elements {
+			cname, _ := container.GetString("name")
+			if image := container.Field("image"); image != nil && image.Value.YNode().Value != "" {
+				ckey := cv.uniqueKey(containerKeys, cname)
+				containerKeys[ckey] = true
+				key := fmt.Sprintf("%s.images.%s", vals.key, ckey)
+				ref := parseImage(image.Value.YNode().Value)
+				images[ckey] = ref.values()
+				cv.parameters = append(cv.parameters, ConvertParameter{
+					Resource: vals.resource,
+					Field:    fmt.Sprintf("%s.%s[%s].image", field, list, cname),
+					Key:      key,
+					Value:    image.Value.YNode().Value,
+				})
+				cv.parameterize(image.Value.YNode(), ref.template(".Values."+key))
+			}
+			if err := cv.convertContainerRefs(container); err != nil {
+				return err
+			}
+		}
+	}
+	if len(images) > 0 {
+		vals.values["images"] = images
+	}
+
+	if err := cv.prefixRef(spec, "ServiceAccount", "serviceAccountName"); err != nil {
+		return err
+	}
+	if volumes, err := spec.Pipe(kyaml.Lookup("volumes")); err != nil {
+		return err
+	} else if volumes != nil {
+		elements, err := volumes.Elements()
+		if err != nil {
+			return err
+		}
+		for _, volume := range elements {
+			if err := cv.prefixRef(volume, "ConfigMap", "configMap", "name"); err != nil {
+				return err
+			}
+			if err := cv.prefixRef(volume, "Secret", "secret", "secretName"); err != nil {
+				return err
+			}
+			if err := cv.prefixRef(volume, "PersistentVolumeClaim", "persistentVolumeClaim", "claimName"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// convertContainerRefs prefixes the references of a container to the
+// converted config maps and secrets.
+func (cv *converter) convertContainerRefs(container *kyaml.RNode) error {
+	for _, list := range []string{"envFrom", "env"} {
+		items, err := container.Pipe(kyaml.Lookup(list))
+		if err != nil {
+			return err
+		}
+		if items == nil {
+			continue
+		}
+		elements, err := items.Elements()
+		if err != nil {
+			return err
+		}
+		for _, item := range elements {
+			for _, ref := range [][]string{
+				{"ConfigMap", "configMapRef", "name"},
+				{"Secret", "secretRef", "name"},
+				{"ConfigMap", "valueFrom", "configMapKeyRef", "name"},
+				{"Secret", "valueFrom", "secretKeyRef", "name"},
+			} {
+				if err := cv.prefixRef(item, ref[0], ref[1:]...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// convertIngress prefixes the services an ingress routes to.
+func (cv *converter) convertIngress(node *kyaml.RNode) error {
+	if err := cv.prefixRef(node, "Service", "spec", "defaultBackend", "service", "name"); err != nil {
+		return err
+	}
+	rules, err := node.Pipe(kyaml.Lookup("spec", "rules"))
+	if err != nil || rules == nil {
+		return err
+	}
+	elements, err := rules.Elements()
+	if err != nil {
+		return err
+	}
+	for _, rule := range elements {
+		paths, err := rule.Pipe(kyaml.Lookup("http", "paths"))
+		if err != nil {
+			return err
+		}
+		if paths == nil {
+			continue
+		}
+		pathElements, err := paths.Elements()
+		if err != nil {
+			return err
+		}
+		for _, p := range pathElements {
+			if err := cv.prefixRef(p, "Service", "backend", "service", "name"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// convertRoleBinding prefixes the role and the service accounts a role
+// binding binds.
+func (cv *converter) convertRoleBinding(node *kyaml.RNode) error {
+	roleRef, err := node.Pipe(kyaml.Lookup("roleRef"))
+	if err != nil {
+		return err
+	}
+	if roleRef != nil {
+		kind, _ := roleRef.GetString("kind")
+		if err := cv.prefixRef(roleRef, kind, "name"); err != nil {
+			return err
+		}
+	}
+	subjects, err := node.Pipe(kyaml.Lookup("subjects"))
+	if err != nil || subjects == nil {
+		return err
+	}
+	elements, err := subjects.Elements()
+	if err != nil {
+		return err
+	}
+	for _, subject := range elements {
+		kind, _ := subject.GetString("kind")
+		if err := cv.prefixRef(subject, kind, "name"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixRef prefixes the field of node at path when it references a
+// converted resource of the kind.
+func (cv *converter) prefixRef(node *kyaml.RNode, kind string, path ...string) error {
+	ref, err := node.Pipe(kyaml.Lookup(path...))
+	if err != nil || ref == nil {
+		return err
+	}
+	if !cv.names[kind+"/"+ref.YNode().Value] {
+		return nil
+	}
+	return cv.prefixName(node, path...)
+}
+
+// prefixName prefixes the name at path with the namePrefix value.
+func (cv *converter) prefixName(node *kyaml.RNode, path ...string) error {
+	name, err := node.Pipe(kyaml.Lookup(path...))
+	if err != nil || name == nil {
+		return err
+	}
+	cv.parameterize(name.YNode(), fmt.Sprintf("{{ .Values.%s }}%s", convertNamePrefixKey, name.YNode().Value))
+	return nil
+}
+
+// parameterize replaces the value of a scalar node with a placeholder for a
+// template action.
+func (cv *converter) parameterize(node *kyaml.Node, action string) {
+	token := fmt.Sprintf("helm-convert-%d-parameter", len(cv.tokens))
+	cv.tokens[token] = action
+	node.Value = token
+	node.Tag = kyaml.NodeTagString
+	node.Style = 0
+}
+
+// uniqueKey returns a key for name that can be used in a template, e.g.
+// .Values.webFrontend for "web-frontend", and that is not in keys.
+func (cv *converter) uniqueKey(keys map[string]bool, name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			upper = b.Len() > 0
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		case b.Len() == 0:
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	key := b.String()
+	if key == "" || unicode.IsDigit([]rune(key)[0]) {
+		key = "_" + key
+	}
+	unique := key
+	for i := 2; keys[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", key, i)
+	}
+	return unique
+}
+
+// valuesFile returns the values.yaml file of the chart, with a comment on the
+// resource the values of each key were extracted from.
+func (cv *converter) valuesFile() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s is prepended to the names of the resources of the chart.\n", convertNamePrefixKey)
+	fmt.Fprintf(&b, "%s: \"\"\n", convertNamePrefixKey)
+	for _, vals := range cv.values {
+		data, err := yaml.Marshal(map[string]interface{}{vals.key: vals.values})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "\n# Values of %s.\n%s", vals.resource, data)
+	}
+	return b.Bytes(), nil
+}
+
+// imageRef is a container image reference split into its parts.
+type imageRef struct {
+	repository, tag, digest string
+}
+
+// parseImage splits an image reference into its repository, tag and digest.
+func parseImage(image string) imageRef {
+	var ref imageRef
+	ref.repository, ref.digest, _ = strings.Cut(image, "@")
+	// A colon after the last slash separates the tag, while one before it
+	// separates the port of the registry.
+	if i := strings.LastIndex(ref.repository, ":"); i > strings.LastIndex(ref.repository, "/") {
+		ref.repository, ref.tag = ref.repository[:i], ref.repository[i+1:]
+	}
+	return ref
+}
+
+// values returns the values of the parts of the image reference.
+func (ref imageRef) values() map[string]interface{} {
+	vals := map[string]interface{}{"repository": ref.repository}
+	if ref.tag != "" {
+		vals["tag"] = ref.tag
+	}
+	if ref.digest != "" {
+		vals["digest"] = ref.digest
+	}
+	return vals
+}
+
+// template returns the template action rebuilding the image reference from
+// the values at key.
+func (ref imageRef) template(key string) string {
+	parts := []string{fmt.Sprintf("{{ %s.repository }}", key)}
+	if ref.tag != "" {
+		parts = append(parts, fmt.Sprintf(":{{ %s.tag }}", key))
+	}
+	if ref.digest != "" {
+		parts = append(parts, fmt.Sprintf("@{{ %s.digest }}", key))
+	}
+	return `"` + strings.Join(parts, "") + `"`
+}
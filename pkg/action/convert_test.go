@@ -0,0 +1,242 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/engine"
+)
+
+// writeManifests writes the files to a temporary directory and returns it.
+func writeManifests(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(data), 0644))
+	}
+	return dir
+}
+
+// renderConverted renders a converted chart with the values.
+func renderConverted(t *testing.T, c *chart.Chart, vals map[string]interface{}) map[string]string {
+	t.Helper()
+	cvals, err := util.CoalesceValues(c, vals)
+	require.NoError(t, err)
+	options := common.ReleaseOptions{Name: "release-name", Namespace: "default", IsInstall: true}
+	valuesToRender, err := util.ToRenderValues(c, cvals, options, common.DefaultCapabilities.Copy())
+	require.NoError(t, err)
+	out, err := engine.Render(c, valuesToRender)
+	require.NoError(t, err)
+	return out
+}
+
+const convertDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: shop
+spec:
+  # Survives a node drain.
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: registry.example.com:5000/web:1.25
+        envFrom:
+        - configMapRef:
+            name: web-config
+        - secretRef:
+            name: external
+      - name: log-shipper
+        image: fluent/fluent-bit@sha256:0123
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+data:
+  greeting: "Hello {{ name }}"
+`
+
+func TestConvert(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"shop/deployment.yaml":             convertDeployment,
+		"shop/rbac/serviceaccount.json":    `{"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "web"}}`,
+		"shop/README.md":                   "Not a manifest.",
+		"shop/.hidden/ignored.yaml":        "not: a resource",
+		"shop/rbac/.ignored-manifest.yaml": "not: a resource",
+	})
+
+	result, err := NewConvert().Run(filepath.Join(dir, "shop"))
+	require.NoError(t, err)
+	assert.False(t, result.Kustomization)
+	assert.Equal(t, 3, result.Resources)
+	assert.Equal(t, "shop", result.Chart.Name())
+	assert.Equal(t, "0.1.0", result.Chart.Metadata.Version)
+
+	var names []string
+	for _, f := range result.Chart.Templates {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{
+		"templates/deployment-web.yaml",
+		"templates/configmap-web-config.yaml",
+		"templates/serviceaccount-web.yaml",
+	}, names)
+	assert.Equal(t, []ConvertParameter{
+		{Resource: "Deployment/web", Field: "spec.replicas", Key: "web.replicas", Value: "2"},
+		{Resource: "Deployment/web", Field: "spec.template.spec.containers[web].image", Key: "web.images.web", Value: "registry.example.com:5000/web:1.25"},
+		{Resource: "Deployment/web", Field: "spec.template.spec.containers[log-shipper].image", Key: "web.images.logShipper", Value: "fluent/fluent-bit@sha256:0123"},
+	}, result.Parameters)
+	assert.Equal(t, "Deployment/web spec.replicas: web.replicas", result.Parameters[0].String())
+	assert.Equal(t, map[string]interface{}{
+		"namePrefix": "",
+		"web": map[string]interface{}{
+			"replicas": float64(2),
+			"images": map[string]interface{}{
+				"web":        map[string]interface{}{"repository": "registry.example.com:5000/web", "tag": "1.25"},
+				"logShipper": map[string]interface{}{"repository": "fluent/fluent-bit", "digest": "sha256:0123"},
+			},
+		},
+	}, result.Chart.Values)
+	assert.Contains(t, string(result.Chart.Raw[0].Data), "# Values of Deployment/web.\nweb:\n")
+
+	out := renderConverted(t, result.Chart, nil)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  # Survives a node drain.
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: "registry.example.com:5000/web:1.25"
+        envFrom:
+        - configMapRef:
+            name: web-config
+        - secretRef:
+            name: external
+      - name: log-shipper
+        image: "fluent/fluent-bit@sha256:0123"
+`, out["shop/templates/deployment-web.yaml"])
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web-config\ndata:\n  greeting: \"Hello {{ name }}\"\n", out["shop/templates/configmap-web-config.yaml"])
+	assert.Equal(t, "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: web\n", out["shop/templates/serviceaccount-web.yaml"])
+
+	// The names of the converted resources are prefixed, but not the
+	// references to other resources.
+	out = renderConverted(t, result.Chart, map[string]interface{}{
+		"namePrefix": "dev-",
+		"web":        map[string]interface{}{"replicas": 5},
+	})
+	deployment := out["shop/templates/deployment-web.yaml"]
+	assert.Contains(t, deployment, "  name: dev-web\n")
+	assert.Contains(t, deployment, "  replicas: 5\n")
+	assert.Contains(t, deployment, "            name: dev-web-config\n")
+	assert.Contains(t, deployment, "            name: external\n")
+}
+
+func TestConvertKustomization(t *testing.T) {
+	dir := writeManifests(t, map[string]string{
+		"base/kustomization.yaml": "resources:\n- deployment.yaml\n",
+		"base/deployment.yaml":    convertDeployment,
+		"overlay/kustomization.yaml": `namespace: prod
+resources:
+- ../base
+images:
+- name: registry.example.com:5000/web
+  newTag: "1.26"
+replicas:
+- name: web
+  count: 3
+`,
+	})
+
+	client := NewConvert()
+	client.Name = "web"
+	client.AppVersion = "1.26"
+	result, err := client.Run(filepath.Join(dir, "overlay"))
+	require.NoError(t, err)
+	assert.True(t, result.Kustomization)
+	assert.Equal(t, 2, result.Resources)
+	assert.Equal(t, "web", result.Chart.Name())
+	assert.Equal(t, "1.26", result.Chart.AppVersion())
+	assert.Equal(t, "3", result.Parameters[0].Value)
+	assert.Equal(t, "registry.example.com:5000/web:1.26", result.Parameters[1].Value)
+
+	out := renderConverted(t, result.Chart, nil)
+	assert.Contains(t, out["web/templates/deployment-web.yaml"], "  replicas: 3\n")
+	assert.NotContains(t, out["web/templates/deployment-web.yaml"], "namespace")
+
+	_, err = NewConvert().Run(filepath.Join(dir, "base", "deployment.yaml"))
+	assert.ErrorContains(t, err, "is not a directory")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "kustomization.yaml"), []byte("resources:\n- missing.yaml\n"), 0644))
+	_, err = NewConvert().Run(filepath.Join(dir, "overlay"))
+	assert.ErrorContains(t, err, "unable to build the kustomization")
+}
+
+func TestConvertErrors(t *testing.T) {
+	_, err := NewConvert().Run(writeManifests(t, map[string]string{"README.md": "No manifests."}))
+	assert.ErrorContains(t, err, "no Kubernetes resources found")
+
+	_, err = NewConvert().Run(writeManifests(t, map[string]string{"values.yaml": "replicas: 2\n"}))
+	assert.ErrorContains(t, err, "values.yaml: not a Kubernetes resource")
+
+	client := NewConvert()
+	client.Version = "latest"
+	_, err = client.Run(writeManifests(t, map[string]string{"deployment.yaml": convertDeployment}))
+	assert.ErrorContains(t, err, `chart.metadata.version "latest" is invalid`)
+}
+
+func TestConvertKeys(t *testing.T) {
+	conv := &converter{}
+	keys := map[string]bool{"web": true}
+	for name, key := range map[string]string{
+		"web":          "web2",
+		"web-frontend": "webFrontend",
+		"Web.Frontend": "webFrontend",
+		"2048-game":    "_2048Game",
+		"---":          "_",
+	} {
+		assert.Equal(t, key, conv.uniqueKey(keys, name), name)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	for image, ref := range map[string]imageRef{
+		"nginx":                              {repository: "nginx"},
+		"nginx:1.25":                         {repository: "nginx", tag: "1.25"},
+		"localhost:5000/nginx":               {repository: "localhost:5000/nginx"},
+		"localhost:5000/nginx:1.25@sha256:0": {repository: "localhost:5000/nginx", tag: "1.25", digest: "sha256:0"},
+	} {
+		assert.Equal(t, ref, parseImage(image), image)
+	}
+}
@@ -22,12 +22,18 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/gosuri/uitable"
 
+	"helm.sh/helm/v4/internal/resolver"
+	ci "helm.sh/helm/v4/pkg/chart"
+	chartloader "helm.sh/helm/v4/pkg/chart/loader"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/downloader"
 )
 
 // Dependency is the action for building a given chart's dependency tree.
@@ -37,6 +43,10 @@ type Dependency struct {
 	Verify                bool
 	Keyring               string
 	SkipRefresh           bool
+	Offline               bool
+	DigestAlgorithm       string
+	ArchiveDigests        bool
+	Explain               bool
 	ColumnWidth           uint
 	Username              string
 	Password              string
@@ -45,12 +55,20 @@ type Dependency struct {
 	CaFile                string
 	InsecureSkipTLSverify bool
 	PlainHTTP             bool
+	// Retries is the number of times a download failing with a network or
+	// server error is retried, and RetryBackoff the time waited before the
+	// first retry, doubled before each of the next ones.
+	Retries      int
+	RetryBackoff time.Duration
+	// Concurrency is the maximum number of charts downloaded at once.
+	Concurrency int
 }
 
 // NewDependency creates a new Dependency object with the given configuration.
 func NewDependency() *Dependency {
 	return &Dependency{
 		ColumnWidth: 80,
+		Concurrency: 1,
 	}
 }
 
@@ -136,7 +154,7 @@ func (d *Dependency) dependencyStatus(chartpath string, dep *chart.Dependency, p
 	}
 
 	if depChart.Metadata.Version != dep.Version {
-		constraint, err := semver.NewConstraint(dep.Version)
+		constraint, err := resolver.DependencyConstraint(dep, nil)
 		if err != nil {
 			return "invalid version"
 		}
@@ -169,7 +187,7 @@ func statArchiveForStatus(archive string, dep *chart.Dependency) string {
 		}
 
 		if c.Metadata.Version != dep.Version {
-			constraint, err := semver.NewConstraint(dep.Version)
+			constraint, err := resolver.DependencyConstraint(dep, nil)
 			if err != nil {
 				return "invalid version"
 			}
@@ -235,3 +253,73 @@ func (d *Dependency) printMissing(chartpath string, out io.Writer, reqs []*chart
 		}
 	}
 }
+
+// DependenciesOutdated reports whether the charts/ directory of the given chart
+// is missing dependencies declared in Chart.yaml, or holds dependencies whose
+// versions differ from the ones pinned in Chart.lock.
+func DependenciesOutdated(ch ci.Charter) (bool, error) {
+	ac, err := ci.NewAccessor(ch)
+	if err != nil {
+		return false, err
+	}
+	if req := ac.MetaDependencies(); req != nil {
+		if err := CheckDependencies(ch, req); err != nil {
+			return true, nil
+		}
+	}
+
+	c, ok := ch.(*chart.Chart)
+	if !ok || c.Lock == nil {
+		return false, nil
+	}
+	vendored := make(map[string]string, len(c.Dependencies()))
+	for _, d := range c.Dependencies() {
+		vendored[d.Name()] = d.Metadata.Version
+	}
+	for _, l := range c.Lock.Dependencies {
+		if v, ok := vendored[l.Name]; ok && v != l.Version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UpdateDependencies makes sure the dependencies of the chart at man.ChartPath
+// are present and match its Chart.lock, updating them with the given manager
+// when they are not. It returns the chart loaded from disk afterwards.
+//
+// Charts whose dependencies are already up to date are returned as-is without
+// contacting any repository, so repeated calls reuse the vendored charts and
+// any cache configured on the manager.
+func UpdateDependencies(man *downloader.Manager) (ci.Charter, error) {
+	ch, err := chartloader.Load(man.ChartPath)
+	if err != nil {
+		return nil, err
+	}
+	outdated, err := DependenciesOutdated(ch)
+	if err != nil || !outdated {
+		return ch, err
+	}
+	if err := man.Update(); err != nil {
+		return nil, err
+	}
+	// Reload the chart with the updated Chart.lock file.
+	if ch, err = chartloader.Load(man.ChartPath); err != nil {
+		return nil, fmt.Errorf("failed reloading chart after repo update: %w", err)
+	}
+	return ch, nil
+}
+
+// Add executes 'helm dependency add', adding a dependency to the Chart.yaml of
+// the chart directory at chartpath. The comments and formatting of the file
+// are kept.
+func (d *Dependency) Add(chartpath string, dep *chart.Dependency) error {
+	return chartutil.AddChartfileDependency(chartpath, dep)
+}
+
+// Remove executes 'helm dependency remove', removing the dependency loaded
+// under name, its alias or else its name, from the Chart.yaml of the chart
+// directory at chartpath. The comments and formatting of the file are kept.
+func (d *Dependency) Remove(chartpath, name string) error {
+	return chartutil.RemoveChartfileDependency(chartpath, name)
+}
@@ -150,3 +150,36 @@ func TestStatArchiveForStatus(t *testing.T) {
 	}
 	is.Equal("ok", statArchiveForStatus(where, dep))
 }
+
+func TestDependenciesOutdated(t *testing.T) {
+	sub := &chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "sub", Version: "1.0.0"}}
+	newParent := func(lockVersion string, deps ...*chart.Chart) *chart.Chart {
+		c := &chart.Chart{
+			Metadata: &chart.Metadata{
+				APIVersion:   chart.APIVersionV2,
+				Name:         "parent",
+				Version:      "0.1.0",
+				Dependencies: []*chart.Dependency{{Name: "sub", Version: "^1.0.0"}},
+			},
+			Lock: &chart.Lock{Dependencies: []*chart.Dependency{{Name: "sub", Version: lockVersion}}},
+		}
+		c.SetDependencies(deps...)
+		return c
+	}
+
+	for _, tcase := range []struct {
+		name     string
+		chart    *chart.Chart
+		expected bool
+	}{
+		{name: "up to date", chart: newParent("1.0.0", sub), expected: false},
+		{name: "missing", chart: newParent("1.0.0"), expected: true},
+		{name: "stale", chart: newParent("1.1.0", sub), expected: true},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			outdated, err := DependenciesOutdated(tcase.chart)
+			assert.NoError(t, err)
+			assert.Equal(t, tcase.expected, outdated)
+		})
+	}
+}
@@ -0,0 +1,388 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+// DefaultDiffContext is the number of unchanged lines shown around changes by
+// default.
+const DefaultDiffContext = 3
+
+// DiffFormat is the format of the diffs of manifests.
+type DiffFormat string
+
+const (
+	// DiffFormatUnified renders a unified diff per changed object.
+	DiffFormatUnified DiffFormat = "unified"
+	// DiffFormatJSONPatch renders a JSON object mapping each changed object
+	// to the JSON patch (RFC 6902) turning it into its new state. Lists whose
+	// length changes are replaced as a whole, and objects are added or removed
+	// at the root path.
+	DiffFormatJSONPatch DiffFormat = "json-patch"
+)
+
+// DiffOptions control how the diffs of manifests are rendered.
+type DiffOptions struct {
+	// Context is the number of unchanged lines shown around the changes of
+	// unified diffs.
+	Context int
+	// IgnorePaths are the fields left out of the diffs of all the objects, in
+	// addition to the fields maintained by the API server such as the status,
+	// the generation or the managed fields, and to the IgnoreDifferences of
+	// the releases. They are JSON pointers (RFC 6901), like the paths of JSON
+	// patches, such as "/metadata/labels/helm.sh~1chart", in which "*"
+	// matches any field or list item.
+	IgnorePaths []string
+	// Format is the format of the diffs, unified by default.
+	Format DiffFormat
+}
+
+// ReleaseDiff describes the changes moving a release to another manifest
+// makes, such as a rollback or an upgrade.
+type ReleaseDiff struct {
+	// CurrentRevision is the revision the release is moved from.
+	CurrentRevision int
+	// TargetRevision is the revision whose manifest is applied.
+	TargetRevision int
+	// Current is a diff from the manifest of the current revision to the
+	// manifest of the target revision.
+	Current string
+	// Live is a diff from the live state of the resources of the target
+	// revision to their manifest. Fields maintained by the API server, such
+	// as the status, are left out.
+	Live string
+}
+
+// diffRelease diffs the manifest of a target release against the manifest of
+// the current release, and against the live state of its resources.
+func (cfg *Configuration) diffRelease(current, target *release.Release, targetRevision int, opts DiffOptions) (*ReleaseDiff, error) {
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceResources)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
+	}
+	ignore, err := compileIgnoreRules(slices.Concat(current.IgnoreDifferences, target.IgnoreDifferences), opts.IgnorePaths...)
+	if err != nil {
+		return nil, err
+	}
+	diff := &ReleaseDiff{CurrentRevision: current.Version, TargetRevision: targetRevision}
+
+	currentObjects, err := manifestObjects(current.Manifest, current.Namespace, ignore...)
+	if err != nil {
+		return nil, err
+	}
+	targetObjects, err := manifestObjects(target.Manifest, target.Namespace, ignore...)
+	if err != nil {
+		return nil, err
+	}
+	targetLabel := fmt.Sprintf("revision %d", targetRevision)
+	diff.Current, err = diffObjects(fmt.Sprintf("revision %d", current.Version), targetLabel, currentObjects, targetObjects, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := cfg.KubeClient.Build(strings.NewReader(target.Manifest), false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build kubernetes objects from new release manifest: %w", err)
+	}
+	fetched, err := kubeClient.Get(resources, false)
+	if err != nil {
+		return nil, err
+	}
+	liveObjects, err := liveObjects(fetched, target.Namespace, ignore...)
+	if err != nil {
+		return nil, err
+	}
+	diff.Live, err = diffObjects("live", targetLabel, liveObjects, targetObjects, opts)
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// manifestObjects parses a manifest into its objects, rendered as normalized
+// YAML and keyed by kind, namespace and name. Objects without a namespace are
+// placed in the default namespace, so that they match their live counterparts.
+// The fields the ignore rules select are removed.
+func manifestObjects(manifest, namespace string, ignore ...ignoreRule) (map[string]string, error) {
+	objects := make(map[string]string)
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, fmt.Errorf("unable to parse manifest: %w", err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		key, text, err := normalizeObject(obj, namespace, ignore)
+		if err != nil {
+			return nil, err
+		}
+		objects[key] = text
+	}
+	return objects, nil
+}
+
+// liveObjects renders the objects fetched from the cluster as normalized YAML,
+// keyed like the objects returned by manifestObjects.
+func liveObjects(fetched map[string][]runtime.Object, namespace string, ignore ...ignoreRule) (map[string]string, error) {
+	objects := make(map[string]string)
+	for _, list := range fetched {
+		for _, o := range list {
+			obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(o)
+			if err != nil {
+				return nil, err
+			}
+			key, text, err := normalizeObject(obj, namespace, ignore)
+			if err != nil {
+				return nil, err
+			}
+			objects[key] = text
+		}
+	}
+	return objects, nil
+}
+
+// normalizeObject drops the fields maintained by the API server and the fields
+// the ignore rules select, and returns the key and YAML representation of the
+// object.
+func normalizeObject(obj map[string]interface{}, namespace string, ignore []ignoreRule) (string, string, error) {
+	delete(obj, "status")
+	kind, _ := obj["kind"].(string)
+	var name string
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		for _, field := range []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink"} {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+		name, _ = metadata["name"].(string)
+		// Objects in the default namespace may or may not name it, so it is
+		// only shown for the objects in other namespaces.
+		if ns, _ := metadata["namespace"].(string); ns == namespace {
+			delete(metadata, "namespace")
+		} else if ns != "" {
+			namespace = ns
+		}
+	}
+	apiVersion, _ := obj["apiVersion"].(string)
+	var group string
+	if g, _, ok := strings.Cut(apiVersion, "/"); ok {
+		group = g
+	}
+	for _, rule := range ignore {
+		if !rule.matches(group, kind, namespace, name) {
+			continue
+		}
+		for _, path := range rule.paths {
+			removeField(obj, path)
+		}
+	}
+	text, err := yaml.Marshal(obj)
+	if err != nil {
+		return "", "", err
+	}
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name), string(text), nil
+}
+
+// diffObjects returns a diff between two sets of objects in the format of the
+// options, or an empty string when they do not differ.
+func diffObjects(fromLabel, toLabel string, from, to map[string]string, opts DiffOptions) (string, error) {
+	switch opts.Format {
+	case "", DiffFormatUnified:
+		return unifiedDiff(fromLabel, toLabel, from, to, opts.Context)
+	case DiffFormatJSONPatch:
+		return jsonPatchDiff(from, to)
+	}
+	return "", fmt.Errorf("unknown diff format %q, expected %q or %q", opts.Format, DiffFormatUnified, DiffFormatJSONPatch)
+}
+
+// changedObjects returns the sorted keys of the objects that differ between
+// two sets, including the objects missing on one side.
+func changedObjects(from, to map[string]string) []string {
+	keys := make(map[string]bool, len(from)+len(to))
+	for k := range from {
+		keys[k] = true
+	}
+	for k := range to {
+		keys[k] = true
+	}
+	var changed []string
+	for _, key := range slices.Sorted(maps.Keys(keys)) {
+		if from[key] != to[key] {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// unifiedDiff returns a unified diff between two sets of objects, one file
+// per changed object. Objects missing on one side are diffed against an empty
+// file. The labels name both sides in the file headers.
+func unifiedDiff(fromLabel, toLabel string, from, to map[string]string, context int) (string, error) {
+	var b strings.Builder
+	for _, key := range changedObjects(from, to) {
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(from[key]),
+			B:        splitLines(to[key]),
+			FromFile: fmt.Sprintf("%s (%s)", key, fromLabel),
+			ToFile:   fmt.Sprintf("%s (%s)", key, toLabel),
+			Context:  max(context, 0),
+		})
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(diff)
+	}
+	return b.String(), nil
+}
+
+// jsonPatchOperation is an operation of a JSON patch.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// jsonPatchDiff returns an indented JSON object mapping the keys of the
+// changed objects to the JSON patches turning them into their new state.
+func jsonPatchDiff(from, to map[string]string) (string, error) {
+	patches := make(map[string][]jsonPatchOperation)
+	for _, key := range changedObjects(from, to) {
+		var fromObj, toObj interface{}
+		if err := yaml.Unmarshal([]byte(from[key]), &fromObj); err != nil {
+			return "", err
+		}
+		if err := yaml.Unmarshal([]byte(to[key]), &toObj); err != nil {
+			return "", err
+		}
+		switch {
+		case fromObj == nil:
+			patches[key] = []jsonPatchOperation{{Op: "add", Path: "", Value: toObj}}
+		case toObj == nil:
+			patches[key] = []jsonPatchOperation{{Op: "remove", Path: ""}}
+		default:
+			patches[key] = jsonPatch(nil, "", fromObj, toObj)
+		}
+	}
+	if len(patches) == 0 {
+		return "", nil
+	}
+	data, err := json.MarshalIndent(patches, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// jsonPatch appends the operations turning from into to, at a path, to ops.
+// The fields of objects, and the items of lists of the same length, are
+// compared one by one. Other values are replaced as a whole.
+func jsonPatch(ops []jsonPatchOperation, path string, from, to interface{}) []jsonPatchOperation {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		t, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range slices.Sorted(maps.Keys(f)) {
+			fieldPath := path + "/" + escapeJSONPointer(key)
+			if value, ok := t[key]; ok {
+				ops = jsonPatch(ops, fieldPath, f[key], value)
+			} else {
+				ops = append(ops, jsonPatchOperation{Op: "remove", Path: fieldPath})
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(t)) {
+			if _, ok := f[key]; !ok {
+				ops = append(ops, jsonPatchOperation{Op: "add", Path: path + "/" + escapeJSONPointer(key), Value: t[key]})
+			}
+		}
+		return ops
+	case []interface{}:
+		t, ok := to.([]interface{})
+		if !ok || len(t) != len(f) {
+			break
+		}
+		for i := range f {
+			ops = jsonPatch(ops, path+"/"+strconv.Itoa(i), f[i], t[i])
+		}
+		return ops
+	}
+	if !reflect.DeepEqual(from, to) {
+		ops = append(ops, jsonPatchOperation{Op: "replace", Path: path, Value: to})
+	}
+	return ops
+}
+
+// escapeJSONPointer escapes a field for a JSON pointer (RFC 6901).
+func escapeJSONPointer(field string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(field)
+}
+
+// deltaObjects returns the keys of the objects only in to, only in from, and
+// in both with a different representation, each sorted.
+func deltaObjects(from, to map[string]string) (added, removed, changed []string) {
+	for _, key := range slices.Sorted(maps.Keys(to)) {
+		text, ok := from[key]
+		switch {
+		case !ok:
+			added = append(added, key)
+		case text != to[key]:
+			changed = append(changed, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(from)) {
+		if _, ok := to[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	return added, removed, changed
+}
+
+// splitLines splits s into lines, each ending with a newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,346 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+const (
+	diffManifestV1 = `---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  color: blue
+  size: small
+---
+# Source: app/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: app
+`
+	diffManifestV2 = `---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  color: red
+  size: small
+---
+# Source: app/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: other
+`
+)
+
+// liveKubeClient builds fixed resources and returns fixed live objects.
+type liveKubeClient struct {
+	kubefake.PrintingKubeClient
+	resources kube.ResourceList
+	live      map[string][]runtime.Object
+}
+
+func (c *liveKubeClient) Build(_ io.Reader, _ bool) (kube.ResourceList, error) {
+	return c.resources, nil
+}
+
+func (c *liveKubeClient) Get(_ kube.ResourceList, _ bool) (map[string][]runtime.Object, error) {
+	return c.live, nil
+}
+
+func TestManifestObjects(t *testing.T) {
+	objects, err := manifestObjects(diffManifestV2, "default")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"ConfigMap default/app": "apiVersion: v1\ndata:\n  color: red\n  size: small\nkind: ConfigMap\nmetadata:\n  name: app\n",
+		"Service other/app":     "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n  namespace: other\n",
+	}, objects)
+}
+
+func TestRollbackDiff(t *testing.T) {
+	config := actionConfigFixture(t)
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":            "app",
+			"namespace":       "default",
+			"uid":             "1234",
+			"resourceVersion": "42",
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+		},
+		"data": map[string]interface{}{
+			"color": "green",
+			"size":  "small",
+		},
+	}}
+	config.KubeClient = &liveKubeClient{
+		PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+		live:               map[string][]runtime.Object{"v1/ConfigMap": {live}},
+	}
+
+	for i, manifest := range []string{diffManifestV1, diffManifestV2} {
+		rel := releaseStub()
+		rel.Name = "app"
+		rel.Namespace = "default"
+		rel.Version = i + 1
+		rel.Manifest = manifest
+		rel.Info.Status = release.StatusSuperseded
+		if i == 1 {
+			rel.Info.Status = release.StatusDeployed
+		}
+		require.NoError(t, config.Releases.Create(rel))
+	}
+
+	client := NewRollback(config)
+	client.DryRun = true
+	client.ServerSideApply = "auto"
+	diff, err := client.Diff("app")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, diff.CurrentRevision)
+	assert.Equal(t, 1, diff.TargetRevision)
+	assert.Equal(t, `--- ConfigMap default/app (revision 2)
++++ ConfigMap default/app (revision 1)
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  color: red
++  color: blue
+   size: small
+ kind: ConfigMap
+ metadata:
+--- Secret default/app (revision 2)
++++ Secret default/app (revision 1)
+@@ -0,0 +1,4 @@
++apiVersion: v1
++kind: Secret
++metadata:
++  name: app
+--- Service other/app (revision 2)
++++ Service other/app (revision 1)
+@@ -1,5 +0,0 @@
+-apiVersion: v1
+-kind: Service
+-metadata:
+-  name: app
+-  namespace: other
+`, diff.Current)
+	assert.Equal(t, `--- ConfigMap default/app (live)
++++ ConfigMap default/app (revision 1)
+@@ -1,6 +1,6 @@
+ apiVersion: v1
+ data:
+-  color: green
++  color: blue
+   size: small
+ kind: ConfigMap
+ metadata:
+--- Secret default/app (live)
++++ Secret default/app (revision 1)
+@@ -0,0 +1,4 @@
++apiVersion: v1
++kind: Secret
++metadata:
++  name: app
+`, diff.Live)
+
+	// The dry run leaves the history untouched.
+	last, err := config.Releases.Last("app")
+	require.NoError(t, err)
+	assert.Equal(t, 2, last.Version)
+
+	client.Version = 5
+	_, err = client.Diff("app")
+	assert.ErrorContains(t, err, "release has no 5 version")
+}
+
+func TestDiffObjectsOptions(t *testing.T) {
+	from, err := manifestObjects("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  labels:\n    helm.sh/chart: app-1.0.0\ndata:\n  color: blue\n  size: small\n", "default")
+	require.NoError(t, err)
+	to, err := manifestObjects("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  labels:\n    helm.sh/chart: app-1.1.0\ndata:\n  color: red\n  size: small\n", "default")
+	require.NoError(t, err)
+
+	diff, err := diffObjects("a", "b", from, to, DiffOptions{Context: 0})
+	require.NoError(t, err)
+	assert.Equal(t, `--- ConfigMap default/app (a)
++++ ConfigMap default/app (b)
+@@ -3 +3 @@
+-  color: blue
++  color: red
+@@ -8 +8 @@
+-    helm.sh/chart: app-1.0.0
++    helm.sh/chart: app-1.1.0
+`, diff)
+
+	diff, err = diffObjects("a", "b", from, to, DiffOptions{Format: DiffFormatJSONPatch})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ConfigMap default/app": [
+		{"op": "replace", "path": "/data/color", "value": "red"},
+		{"op": "replace", "path": "/metadata/labels/helm.sh~1chart", "value": "app-1.1.0"}
+	]}`, diff)
+
+	_, err = diffObjects("a", "b", from, to, DiffOptions{Format: "side-by-side"})
+	assert.ErrorContains(t, err, `unknown diff format "side-by-side"`)
+}
+
+func TestDiffObjectsJSONPatch(t *testing.T) {
+	from, err := manifestObjects(diffManifestV1, "default")
+	require.NoError(t, err)
+	to, err := manifestObjects(diffManifestV2, "default")
+	require.NoError(t, err)
+
+	diff, err := diffObjects("a", "b", from, to, DiffOptions{Format: DiffFormatJSONPatch})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"ConfigMap default/app": [{"op": "replace", "path": "/data/color", "value": "red"}],
+		"Secret default/app": [{"op": "remove", "path": ""}],
+		"Service other/app": [{"op": "add", "path": "", "value": {
+			"apiVersion": "v1", "kind": "Service", "metadata": {"name": "app", "namespace": "other"}
+		}}]
+	}`, diff)
+
+	diff, err = diffObjects("a", "b", from, from, DiffOptions{Format: DiffFormatJSONPatch})
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
+
+func TestJSONPatch(t *testing.T) {
+	from := map[string]interface{}{
+		"items": []interface{}{"a", "b"},
+		"sizes": []interface{}{1.0},
+		"kept":  true,
+		"gone":  "x",
+	}
+	to := map[string]interface{}{
+		"items": []interface{}{"a", "c"},
+		"sizes": []interface{}{1.0, 2.0},
+		"kept":  true,
+		"new":   map[string]interface{}{"k": "v"},
+	}
+	assert.Equal(t, []jsonPatchOperation{
+		{Op: "remove", Path: "/gone"},
+		{Op: "replace", Path: "/items/1", Value: "c"},
+		{Op: "replace", Path: "/sizes", Value: []interface{}{1.0, 2.0}},
+		{Op: "add", Path: "/new", Value: map[string]interface{}{"k": "v"}},
+	}, jsonPatch(nil, "", from, to))
+}
+
+func TestUpgradeDiff(t *testing.T) {
+	upAction := upgradeAction(t)
+	upAction.cfg.KubeClient = &liveKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}
+	rel := releaseStub()
+	rel.Name = "app"
+	rel.Namespace = "spaced"
+	rel.Manifest = diffManifestV1
+	rel.Info.Status = release.StatusDeployed
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	upAction.DryRun = true
+	upAction.DiffOptions.Context = 1
+	upAction.DiffOptions.IgnorePaths = []string{"/data/size"}
+	ch := buildChartWithTemplates([]*common.File{
+		{Name: "templates/configmap.yaml", Data: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  color: red\n  size: large\n")},
+	})
+	upgraded, err := upAction.Run("app", ch, map[string]interface{}{})
+	require.NoError(t, err)
+	diff, err := upAction.Diff(upgraded)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, diff.CurrentRevision)
+	assert.Equal(t, 2, diff.TargetRevision)
+	assert.Equal(t, `--- ConfigMap spaced/app (revision 1)
++++ ConfigMap spaced/app (revision 2)
+@@ -2,3 +2,3 @@
+ data:
+-  color: blue
++  color: red
+ kind: ConfigMap
+--- Secret spaced/app (revision 1)
++++ Secret spaced/app (revision 2)
+@@ -1,4 +0,0 @@
+-apiVersion: v1
+-kind: Secret
+-metadata:
+-  name: app
+`, diff.Current)
+	assert.Equal(t, `--- ConfigMap spaced/app (live)
++++ ConfigMap spaced/app (revision 2)
+@@ -0,0 +1,6 @@
++apiVersion: v1
++data:
++  color: red
++kind: ConfigMap
++metadata:
++  name: app
+`, diff.Live)
+}
+
+func TestStatusDelta(t *testing.T) {
+	config := actionConfigFixture(t)
+	for i, manifest := range []string{diffManifestV1, diffManifestV2} {
+		rel := releaseStub()
+		rel.Name = "app"
+		rel.Namespace = "default"
+		rel.Version = i + 1
+		rel.Manifest = manifest
+		require.NoError(t, config.Releases.Create(rel))
+	}
+
+	client := NewStatus(config)
+	client.Version = 1
+	rel, err := client.Run("app")
+	require.NoError(t, err)
+	delta, err := client.Delta(rel)
+	require.NoError(t, err)
+	assert.Equal(t, &ResourceDelta{
+		FromRevision: 1,
+		ToRevision:   2,
+		Added:        []string{"Service other/app"},
+		Removed:      []string{"Secret default/app"},
+		Changed:      []string{"ConfigMap default/app"},
+	}, delta)
+
+	client.Version = 2
+	rel, err = client.Run("app")
+	require.NoError(t, err)
+	delta, err = client.Delta(rel)
+	require.NoError(t, err)
+	assert.Equal(t, &ResourceDelta{FromRevision: 2, ToRevision: 2}, delta)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+)
+
+// The errors of the actions match these errors with errors.Is by class of
+// failure, so that callers can tell them apart without parsing their
+// messages. Releases or revisions not found match driver.ErrReleaseNotFound,
+// and missing chart paths fs.ErrNotExist.
+var (
+	// ErrInvalid is matched by the errors of the actions refusing their
+	// input, such as an invalid release name, values not matching the schema
+	// of the chart, or templates failing to render.
+	ErrInvalid = errors.New("invalid input")
+	// ErrConflict is matched by the errors of the actions conflicting with
+	// other releases or with the resources of the cluster, such as another
+	// operation in progress on the release, or a release name in use.
+	ErrConflict = errors.New("conflict")
+)
+
+// RolledBackError is the error of an install or an upgrade that failed, and
+// whose changes were reverted because of rollback on failure: the release
+// was uninstalled, or rolled back to its last successful revision. It is
+// not returned when reverting the changes failed as well.
+type RolledBackError struct {
+	error
+}
+
+func (e *RolledBackError) Unwrap() error {
+	return e.error
+}
+
+// classifiedError is an error keeping its message, and matching the error
+// of its class with errors.Is as well.
+type classifiedError struct {
+	error
+	class error
+}
+
+// classify returns err, matching the error of a class as well, such as
+// ErrInvalid.
+func classify(class, err error) error {
+	return &classifiedError{error: err, class: class}
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.error
+}
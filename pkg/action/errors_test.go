@@ -0,0 +1,46 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func TestClassify(t *testing.T) {
+	err := classify(ErrInvalid, driver.ErrReleaseNotFound)
+	assert.EqualError(t, err, driver.ErrReleaseNotFound.Error())
+	assert.ErrorIs(t, err, ErrInvalid)
+	assert.ErrorIs(t, err, driver.ErrReleaseNotFound, "the classified error itself should match")
+	assert.NotErrorIs(t, err, ErrConflict)
+
+	pathErr := &fs.PathError{Op: "open", Path: "chart", Err: fs.ErrNotExist}
+	err = classify(ErrInvalid, pathErr)
+	var target *fs.PathError
+	assert.True(t, errors.As(err, &target), "the classified error itself should be found by errors.As")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	err = classify(ErrConflict, fmt.Errorf("release: %w", driver.ErrReleaseExists))
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.ErrorIs(t, err, driver.ErrReleaseExists)
+}
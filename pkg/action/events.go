@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// Lifecycle events of the actions.
+const (
+	// EventPreInstall is emitted before the resources of a release are installed.
+	EventPreInstall = "pre-install"
+	// EventPostInstall is emitted after a release is installed.
+	EventPostInstall = "post-install"
+	// EventPreUpgrade is emitted before the resources of a release are upgraded.
+	EventPreUpgrade = "pre-upgrade"
+	// EventPostUpgrade is emitted after a release is upgraded.
+	EventPostUpgrade = "post-upgrade"
+	// EventPreRollback is emitted before a release is rolled back.
+	EventPreRollback = "pre-rollback"
+	// EventPostRollback is emitted after a release is rolled back.
+	EventPostRollback = "post-rollback"
+	// EventPreUninstall is emitted before the resources of a release are deleted.
+	EventPreUninstall = "pre-uninstall"
+	// EventPostUninstall is emitted after a release is uninstalled.
+	EventPostUninstall = "post-uninstall"
+	// EventPostRender is emitted once the manifest of a release is rendered,
+	// including for dry runs.
+	EventPostRender = "post-render"
+)
+
+// EventHandler is notified of the lifecycle events of the actions.
+//
+// An error returned for a pre event or for EventPostRender aborts the action,
+// which lets handlers enforce policies. Errors returned for the other post
+// events are logged, as the action has already completed.
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event string, rel *release.Release) error
+}
+
+// emitEvent notifies the event handler of an event the action can be aborted
+// on.
+func (cfg *Configuration) emitEvent(ctx context.Context, event string, rel *release.Release) error {
+	if cfg.EventHandler == nil {
+		return nil
+	}
+	if err := cfg.EventHandler.HandleEvent(ctx, event, rel); err != nil {
+		return fmt.Errorf("%s event handler failed: %w", event, err)
+	}
+	return nil
+}
+
+// notifyEvent notifies the event handler of an event emitted once the action
+// completed. Errors are only logged.
+func (cfg *Configuration) notifyEvent(ctx context.Context, event string, rel *release.Release) {
+	if cfg.EventHandler == nil {
+		return
+	}
+	if err := cfg.EventHandler.HandleEvent(ctx, event, rel); err != nil {
+		slog.Warn("event handler failed", slog.String("event", event), slog.String("release", rel.Name), slog.Any("error", err))
+	}
+}
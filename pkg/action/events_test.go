@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// recordingEventHandler records the events it is notified of and fails the
+// events listed in fail.
+type recordingEventHandler struct {
+	events []string
+	fail   map[string]bool
+}
+
+func (h *recordingEventHandler) HandleEvent(_ context.Context, event string, _ *release.Release) error {
+	h.events = append(h.events, event)
+	if h.fail[event] {
+		return errors.New("denied by policy")
+	}
+	return nil
+}
+
+func TestInstallRelease_Events(t *testing.T) {
+	instAction := installAction(t)
+	handler := &recordingEventHandler{fail: map[string]bool{EventPostInstall: true}}
+	instAction.cfg.EventHandler = handler
+
+	// A failing post event does not fail the install.
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventPostRender, EventPreInstall, EventPostInstall}, handler.events)
+
+	uninstall := NewUninstall(instAction.cfg)
+	_, err = uninstall.Run(instAction.ReleaseName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventPostRender, EventPreInstall, EventPostInstall, EventPreUninstall, EventPostUninstall}, handler.events)
+}
+
+func TestInstallRelease_EventsDryRun(t *testing.T) {
+	instAction := installAction(t)
+	instAction.DryRunOption = "client"
+	handler := &recordingEventHandler{}
+	instAction.cfg.EventHandler = handler
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventPostRender}, handler.events)
+}
+
+func TestInstallRelease_EventsClientOnly(t *testing.T) {
+	instAction := installAction(t)
+	instAction.ClientOnly = true
+	instAction.DryRunOption = "client"
+	handler := &recordingEventHandler{}
+	instAction.cfg.EventHandler = handler
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventPostRender}, handler.events, "helm template should notify the post-render event")
+}
+
+func TestInstallRelease_PreInstallEventFailure(t *testing.T) {
+	instAction := installAction(t)
+	instAction.cfg.EventHandler = &recordingEventHandler{fail: map[string]bool{EventPreInstall: true}}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.EqualError(t, err, "pre-install event handler failed: denied by policy")
+
+	_, err = instAction.cfg.Releases.Get(instAction.ReleaseName, 1)
+	assert.Error(t, err, "the release should not be recorded")
+}
+
+func TestUpgradeRelease_Events(t *testing.T) {
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "events"
+	rel.Info.Status = release.StatusDeployed
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	handler := &recordingEventHandler{fail: map[string]bool{EventPreUpgrade: true}}
+	upAction.cfg.EventHandler = handler
+
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	require.EqualError(t, err, "pre-upgrade event handler failed: denied by policy")
+	assert.Equal(t, []string{EventPostRender, EventPreUpgrade}, handler.events)
+
+	handler.fail = nil
+	handler.events = nil
+	_, err = upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventPostRender, EventPreUpgrade, EventPostUpgrade}, handler.events)
+}
@@ -0,0 +1,320 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
+	"helm.sh/helm/v4/pkg/engine"
+	"helm.sh/helm/v4/pkg/getter"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// Chart annotations declaring the health checks of the releases of a chart.
+// The options of the install and upgrade actions take precedence over them.
+const (
+	// HealthChecksAnnotation lists the health checks of the chart, as a YAML
+	// list of HealthCheck. It is rendered as a template, with the values and
+	// the named templates of the chart, before being parsed.
+	HealthChecksAnnotation = "helm.sh/health-checks"
+	// HealthCheckWindowAnnotation sets the time, as a duration, within which
+	// the health checks must pass.
+	HealthCheckWindowAnnotation = "helm.sh/health-check-window"
+)
+
+const (
+	// defaultHealthCheckWindow is the time within which the health checks
+	// must pass by default.
+	defaultHealthCheckWindow = time.Minute
+	// defaultHealthCheckInterval is the time between the attempts of the
+	// health checks that did not pass by default.
+	defaultHealthCheckInterval = 5 * time.Second
+)
+
+// HealthCheck is a check of the health of a release, run once the release is
+// deployed. It is either an HTTP check or an exec check.
+type HealthCheck struct {
+	// Name identifies the check in the errors.
+	Name string `json:"name"`
+	// HTTP checks the response to an HTTP request.
+	HTTP *HTTPHealthCheck `json:"http,omitempty"`
+	// Exec checks the exit code of a command run on the machine running
+	// Helm.
+	Exec *ExecHealthCheck `json:"exec,omitempty"`
+}
+
+// HTTPHealthCheck is a health check passing when an HTTP request gets the
+// expected status code.
+type HTTPHealthCheck struct {
+	// URL is the URL of the request.
+	URL string `json:"url"`
+	// Method is the method of the request. It defaults to GET.
+	Method string `json:"method,omitempty"`
+	// Headers are the headers of the request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// ExpectedStatus is the status code of the response for the check to
+	// pass. Any 2xx status code passes when it is not set.
+	ExpectedStatus int `json:"expectedStatus,omitempty"`
+}
+
+// ExecHealthCheck is a health check passing when a command exits with a zero
+// code.
+type ExecHealthCheck struct {
+	// Command is the command and its arguments.
+	Command []string `json:"command"`
+}
+
+// HealthChecks configures the checks of the health of a release, run once
+// the release is deployed. When they do not all pass within the window, the
+// release fails, and is rolled back when rollback on failure is set.
+type HealthChecks struct {
+	// Checks are run for all the charts.
+	Checks []HealthCheck
+	// FromChart also runs the checks declared in the annotations of the
+	// chart.
+	FromChart bool
+	// AllowExec allows the checks declared by the chart to run commands on
+	// the machine running Helm. The exec checks of Checks are always allowed.
+	AllowExec bool
+	// Window is the time within which the checks must pass. It defaults to
+	// the window declared by the chart, or else to a minute.
+	Window time.Duration
+	// Interval is the time between the attempts of the checks that did not
+	// pass. It defaults to 5 seconds.
+	Interval time.Duration
+	// GetterOptions configure the TLS and proxy settings of the requests of
+	// the HTTP checks, like the ones of the HTTP getter.
+	GetterOptions []getter.Option
+}
+
+// validate checks that a health check has exactly one of its kinds.
+func (hc HealthCheck) validate() error {
+	switch {
+	case hc.Name == "":
+		return errors.New("health check without a name")
+	case (hc.HTTP == nil) == (hc.Exec == nil):
+		return fmt.Errorf("health check %s: exactly one of http and exec must be set", hc.Name)
+	case hc.HTTP != nil && hc.HTTP.URL == "":
+		return fmt.Errorf("health check %s: the URL is required", hc.Name)
+	case hc.Exec != nil && len(hc.Exec.Command) == 0:
+		return fmt.Errorf("health check %s: the command is required", hc.Name)
+	}
+	return nil
+}
+
+// run runs a health check once, sending the requests of HTTP checks with
+// client.
+func (hc HealthCheck) run(ctx context.Context, client *http.Client) error {
+	if hc.Exec != nil {
+		out, err := exec.CommandContext(ctx, hc.Exec.Command[0], hc.Exec.Command[1:]...).CombinedOutput()
+		if err != nil {
+			if out = bytes.TrimSpace(out); len(out) > 0 {
+				return fmt.Errorf("%w: %s", err, out)
+			}
+			return err
+		}
+		return nil
+	}
+
+	method := hc.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, hc.HTTP.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range hc.HTTP.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if hc.HTTP.ExpectedStatus != 0 && resp.StatusCode != hc.HTTP.ExpectedStatus {
+		return fmt.Errorf("%s %s: %s, expected %d", method, hc.HTTP.URL, resp.Status, hc.HTTP.ExpectedStatus)
+	}
+	if hc.HTTP.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("%s %s: %s", method, hc.HTTP.URL, resp.Status)
+	}
+	return nil
+}
+
+// healthChecksFor returns the health checks of a release and the window
+// within which they must pass. It renders and parses the checks declared by
+// the chart when they are enabled.
+func (cfg *Configuration) healthChecksFor(rel *release.Release, opts HealthChecks, isUpgrade bool) ([]HealthCheck, time.Duration, error) {
+	checks := slices.Clone(opts.Checks)
+	window := opts.Window
+	if opts.FromChart && rel.Chart != nil && rel.Chart.Metadata != nil {
+		annos := rel.Chart.Metadata.Annotations
+		if v := annos[HealthChecksAnnotation]; v != "" {
+			chartChecks, err := cfg.renderHealthChecks(rel, v, isUpgrade)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid chart annotation %s: %w", HealthChecksAnnotation, err)
+			}
+			for _, hc := range chartChecks {
+				if hc.Exec != nil && !opts.AllowExec {
+					return nil, 0, fmt.Errorf("health check %s of the chart runs a command, which requires allowing exec health checks", hc.Name)
+				}
+			}
+			checks = append(checks, chartChecks...)
+		}
+		if v := annos[HealthCheckWindowAnnotation]; v != "" && window == 0 {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid chart annotation %s: %w", HealthCheckWindowAnnotation, err)
+			}
+			window = d
+		}
+	}
+	for _, hc := range checks {
+		if err := hc.validate(); err != nil {
+			return nil, 0, err
+		}
+	}
+	if window <= 0 {
+		window = defaultHealthCheckWindow
+	}
+	return checks, window, nil
+}
+
+// renderHealthChecks renders the health checks declared by the chart of a
+// release with the values of the release.
+func (cfg *Configuration) renderHealthChecks(rel *release.Release, checks string, isUpgrade bool) ([]HealthCheck, error) {
+	caps, err := cfg.getCapabilities()
+	if err != nil {
+		return nil, err
+	}
+	vals, err := util.CoalesceValues(rel.Chart, rel.Config)
+	if err != nil {
+		return nil, err
+	}
+	options := common.ReleaseOptions{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		IsInstall: !isUpgrade,
+		IsUpgrade: isUpgrade,
+	}
+	valuesToRender, err := util.ToRenderValues(rel.Chart, vals, options, caps)
+	if err != nil {
+		return nil, err
+	}
+
+	// The checks are rendered along with the named templates of the chart
+	// only.
+	const name = "templates/health-checks"
+	c := *rel.Chart
+	c.Templates = []*common.File{{Name: name, Data: []byte(checks)}}
+	for _, f := range rel.Chart.Templates {
+		if strings.HasPrefix(f.Name[strings.LastIndex(f.Name, "/")+1:], "_") {
+			c.Templates = append(c.Templates, f)
+		}
+	}
+	e := engine.Engine{CustomTemplateFuncs: cfg.CustomTemplateFuncs}
+	out, err := e.Render(&c, valuesToRender)
+	if err != nil {
+		return nil, err
+	}
+	var parsed []HealthCheck
+	if err := yaml.UnmarshalStrict([]byte(out[c.Name()+"/"+name]), &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// checkHealth runs the health checks of a release until they all passed or
+// the window elapsed. The checks are run concurrently, so that each of them
+// has the whole window to pass.
+func (cfg *Configuration) checkHealth(rel *release.Release, opts HealthChecks, isUpgrade bool) error {
+	checks, window, err := cfg.healthChecksFor(rel, opts, isUpgrade)
+	if err != nil || len(checks) == 0 {
+		return err
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	client, err := getter.HTTPClient(opts.GetterOptions...)
+	if err != nil {
+		return err
+	}
+
+	failures := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, hc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failures[i] = cfg.runHealthCheck(rel, hc, client, window, interval)
+		}()
+	}
+	wg.Wait()
+
+	var msgs []string
+	for i, hc := range checks {
+		if failures[i] != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", hc.Name, failures[i]))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("health checks did not pass within %s:\n  %s", window, strings.Join(msgs, "\n  "))
+}
+
+// runHealthCheck runs a health check until it passes or the window elapses,
+// and returns the failure of its last attempt otherwise.
+func (cfg *Configuration) runHealthCheck(rel *release.Release, hc HealthCheck, client *http.Client, window, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), window)
+	defer cancel()
+	var failure error
+	for {
+		err := hc.run(ctx, client)
+		if err == nil {
+			return nil
+		}
+		slog.Debug("health check did not pass", "release", rel.Name, "check", hc.Name, slog.Any("error", err))
+		// An attempt cut short by the end of the window does not replace the
+		// failure of the previous one.
+		if ctx.Err() == nil || failure == nil {
+			failure = err
+		}
+
+		select {
+		case <-ctx.Done():
+			return failure
+		case <-cfg.clockOrDefault().After(interval):
+		}
+	}
+}
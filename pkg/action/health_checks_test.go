@@ -0,0 +1,255 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/getter"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+const chartHealthChecks = `- name: api
+  http:
+    url: {{ include "hello.url" . }}/healthz
+    headers:
+      X-Token: {{ .Values.token | quote }}
+`
+
+// healthServer serves /healthz with the given status codes in turn, and then
+// with 200. It requires the X-Token header to be "secret".
+func healthServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		switch {
+		case r.URL.Path != "/healthz" || r.Header.Get("X-Token") != "secret":
+			w.WriteHeader(http.StatusForbidden)
+		case n <= len(statuses):
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func healthCheckedChart(url string) *chartOptions {
+	c := &chartOptions{Chart: buildChart(
+		withAnnotations(map[string]string{HealthChecksAnnotation: chartHealthChecks}),
+		withValues(map[string]interface{}{"url": url, "token": "secret"}),
+	)}
+	c.Templates = append(c.Templates, &common.File{Name: "templates/_helpers.tpl", Data: []byte(`{{ define "hello.url" }}{{ .Values.url }}{{ end }}`)})
+	return c
+}
+
+func TestInstallRelease_HealthChecks(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	srv, requests := healthServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	instAction := installAction(t)
+	instAction.HealthChecks = HealthChecks{FromChart: true, Interval: 10 * time.Millisecond}
+	res, err := instAction.Run(healthCheckedChart(srv.URL).Chart, map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Equal(int32(3), requests.Load(), "expected the check to be retried until it passed")
+
+	// The checks of the chart are only run when enabled.
+	srv, requests = healthServer(t)
+	instAction = installAction(t)
+	_, err = instAction.Run(healthCheckedChart(srv.URL).Chart, map[string]interface{}{})
+	req.NoError(err)
+	is.Zero(requests.Load())
+}
+
+func TestInstallRelease_HealthChecksFail(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	srv, _ := healthServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	instAction := installAction(t)
+	instAction.HealthChecks = HealthChecks{FromChart: true, Window: 100 * time.Millisecond, Interval: 50 * time.Millisecond}
+	res, err := instAction.Run(healthCheckedChart(srv.URL).Chart, map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "health checks did not pass within 100ms:\n  api: GET "+srv.URL+"/healthz: 503 Service Unavailable")
+	is.Equal(release.StatusFailed, res.Info.Status)
+
+	srv, _ = healthServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	instAction = installAction(t)
+	instAction.ReleaseName = "come-fail-away"
+	instAction.RollbackOnFailure = true
+	instAction.DisableHooks = true
+	instAction.HealthChecks = HealthChecks{FromChart: true, Window: 100 * time.Millisecond, Interval: 50 * time.Millisecond}
+	res, err = instAction.Run(healthCheckedChart(srv.URL).Chart, map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "rollback-on-failure")
+	_, err = instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.Equal(driver.ErrReleaseNotFound, err)
+}
+
+func TestInstallRelease_ExecHealthChecks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the exec checks run sh")
+	}
+	is := assert.New(t)
+	req := require.New(t)
+
+	passing := HealthCheck{Name: "passing", Exec: &ExecHealthCheck{Command: []string{"true"}}}
+	failing := HealthCheck{Name: "failing", Exec: &ExecHealthCheck{Command: []string{"sh", "-c", "echo unhealthy >&2; exit 3"}}}
+
+	instAction := installAction(t)
+	instAction.HealthChecks = HealthChecks{Checks: []HealthCheck{passing}}
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	req.NoError(err)
+
+	instAction = installAction(t)
+	instAction.HealthChecks = HealthChecks{Checks: []HealthCheck{passing, failing}, Window: 2 * time.Second, Interval: 100 * time.Millisecond}
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "health checks did not pass within 2s:\n  failing: exit status 3: unhealthy")
+	is.NotContains(err.Error(), "passing")
+
+	// The exec checks of the chart must be allowed, and are rejected before
+	// the release is deployed otherwise.
+	chrt := buildChart(withAnnotations(map[string]string{
+		HealthChecksAnnotation: "- name: smoke\n  exec:\n    command: [{{ .Values.binary | quote }}]\n",
+	}), withValues(map[string]interface{}{"binary": "true"}))
+	instAction = installAction(t)
+	instAction.HealthChecks = HealthChecks{FromChart: true}
+	_, err = instAction.Run(chrt, map[string]interface{}{})
+	is.EqualError(err, "health check smoke of the chart runs a command, which requires allowing exec health checks")
+	_, err = instAction.cfg.Releases.Last(instAction.ReleaseName)
+	is.Equal(driver.ErrReleaseNotFound, err)
+
+	instAction.HealthChecks.AllowExec = true
+	_, err = instAction.Run(chrt, map[string]interface{}{})
+	is.NoError(err)
+}
+
+func TestCheckHealthConcurrently(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the exec checks run sh")
+	}
+	// A slow check does not use up the window of the others: the second
+	// check only passes on its second attempt, after the slow check passed,
+	// which would be too late if the checks were run one after another.
+	marker := filepath.Join(t.TempDir(), "marker")
+	checks := []HealthCheck{
+		{Name: "slow", Exec: &ExecHealthCheck{Command: []string{"sleep", "1"}}},
+		{Name: "flaky", Exec: &ExecHealthCheck{Command: []string{"sh", "-c", `test -f "$0" || { touch "$0"; exit 1; }`, marker}}},
+	}
+	cfg := actionConfigFixture(t)
+	err := cfg.checkHealth(releaseStub(), HealthChecks{Checks: checks, Window: 1500 * time.Millisecond, Interval: time.Second}, false)
+	require.NoError(t, err)
+}
+
+func TestCheckHealthTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	opts := HealthChecks{
+		Checks:   []HealthCheck{{Name: "tls", HTTP: &HTTPHealthCheck{URL: srv.URL}}},
+		Window:   time.Second,
+		Interval: time.Minute,
+	}
+	cfg := actionConfigFixture(t)
+
+	err := cfg.checkHealth(releaseStub(), opts, false)
+	require.ErrorContains(t, err, "certificate")
+
+	// The requests are sent with the TLS settings of the getter options.
+	opts.GetterOptions = []getter.Option{getter.WithInsecureSkipVerifyTLS(true)}
+	require.NoError(t, cfg.checkHealth(releaseStub(), opts, false))
+}
+
+func TestUpgradeRelease_HealthChecks(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	srv, _ := healthServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "nuketown"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	upAction.RollbackOnFailure = true
+	upAction.HealthChecks = HealthChecks{FromChart: true, Window: 100 * time.Millisecond, Interval: 50 * time.Millisecond}
+	_, err := upAction.Run(rel.Name, healthCheckedChart(srv.URL).Chart, map[string]interface{}{})
+	req.Error(err)
+	is.Contains(err.Error(), "health checks did not pass within 100ms")
+	is.Contains(err.Error(), "rolled back")
+
+	failed, err := upAction.cfg.Releases.Get(rel.Name, 2)
+	req.NoError(err)
+	is.Equal(release.StatusFailed, failed.Info.Status)
+	rolledBack, err := upAction.cfg.Releases.Get(rel.Name, 3)
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, rolledBack.Info.Status)
+}
+
+func TestHealthChecksFor(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Chart = healthCheckedChart("http://api.example.com").Chart
+	rel.Config = map[string]interface{}{"token": "override"}
+	rel.Chart.Metadata.Annotations[HealthCheckWindowAnnotation] = "2m"
+
+	checks, window, err := cfg.healthChecksFor(rel, HealthChecks{FromChart: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []HealthCheck{{
+		Name: "api",
+		HTTP: &HTTPHealthCheck{URL: "http://api.example.com/healthz", Headers: map[string]string{"X-Token": "override"}},
+	}}, checks)
+	assert.Equal(t, 2*time.Minute, window)
+
+	_, window, err = cfg.healthChecksFor(rel, HealthChecks{FromChart: true, Window: time.Second}, false)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, window, "the window of the options takes precedence over the chart")
+
+	checks, window, err = cfg.healthChecksFor(rel, HealthChecks{}, false)
+	require.NoError(t, err)
+	assert.Empty(t, checks)
+	assert.Equal(t, defaultHealthCheckWindow, window)
+
+	for annotation, msg := range map[string]string{
+		"- name: api\n":                                          "health check api: exactly one of http and exec must be set",
+		"- http:\n    url: http://api\n":                         "health check without a name",
+		"- name: api\n  http:\n    url: {{ .Values.missing.x }}": "invalid chart annotation helm.sh/health-checks",
+		"- name: api\n  http:\n    uri: http://api\n":            `unknown field "uri"`,
+	} {
+		rel.Chart.Metadata.Annotations[HealthChecksAnnotation] = annotation
+		_, _, err := cfg.healthChecksFor(rel, HealthChecks{FromChart: true}, false)
+		assert.ErrorContains(t, err, msg, annotation)
+	}
+
+	rel.Chart.Metadata.Annotations[HealthChecksAnnotation] = chartHealthChecks
+	rel.Chart.Metadata.Annotations[HealthCheckWindowAnnotation] = "soon"
+	_, _, err = cfg.healthChecksFor(rel, HealthChecks{FromChart: true}, false)
+	assert.ErrorContains(t, err, "invalid chart annotation helm.sh/health-check-window")
+}
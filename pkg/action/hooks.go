@@ -16,23 +16,33 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"log"
 	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"helm.sh/helm/v4/pkg/kube"
 
 	"go.yaml.in/yaml/v3"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
 // execHook executes all of the hooks for the given hook event.
 func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent, waitStrategy kube.WaitStrategy, timeout time.Duration, serverSideApply bool) error {
+	return cfg.execHookWithContext(context.Background(), rl, hook, waitStrategy, timeout, serverSideApply)
+}
+
+// execHookWithContext executes the hooks for the given hook event until ctx
+// is cancelled. The hook running when ctx is cancelled runs to completion, or
+// until its timeout, but the next hooks do not start.
+func (cfg *Configuration) execHookWithContext(ctx context.Context, rl *release.Release, hook release.HookEvent, waitStrategy kube.WaitStrategy, timeout time.Duration, serverSideApply bool) error {
 	executingHooks := []*release.Hook{}
 
 	for _, h := range rl.Hooks {
@@ -47,6 +57,10 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 	sort.Stable(hookByWeight(executingHooks))
 
 	for i, h := range executingHooks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Set default delete policy to before-hook-creation
 		cfg.hookSetDeletePolicy(h)
 
@@ -54,15 +68,21 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 			return err
 		}
 
-		resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), true)
+		resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), true)
 		if err != nil {
 			return fmt.Errorf("unable to build kubernetes object for %s hook %s: %w", hook, h.Path, err)
 		}
+		if rl.ObjectLabels != nil {
+			if err := resources.Visit(hookMetadataVisitor(rl)); err != nil {
+				return fmt.Errorf("unable to set the labels of %s hook %s: %w", hook, h.Path, err)
+			}
+		}
 
 		// Record the time at which the hook was applied to the cluster
 		h.LastRun = release.HookExecution{
-			StartedAt: time.Now(),
+			StartedAt: cfg.Now(),
 			Phase:     release.HookPhaseRunning,
+			Event:     hook,
 		}
 		cfg.recordRelease(rl)
 
@@ -75,8 +95,9 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 		if _, err := cfg.KubeClient.Create(
 			resources,
 			kube.ClientCreateOptionServerSideApply(serverSideApply, false)); err != nil {
-			h.LastRun.CompletedAt = time.Now()
+			h.LastRun.CompletedAt = cfg.Now()
 			h.LastRun.Phase = release.HookPhaseFailed
+			h.LastRun.Message = err.Error()
 			return fmt.Errorf("warning: Hook %s %s failed: %w", hook, h.Path, err)
 		}
 
@@ -87,10 +108,13 @@ func (cfg *Configuration) execHook(rl *release.Release, hook release.HookEvent,
 		// Watch hook resources until they have completed
 		err = waiter.WatchUntilReady(resources, timeout)
 		// Note the time of success/failure
-		h.LastRun.CompletedAt = time.Now()
+		h.LastRun.CompletedAt = cfg.Now()
+		// Record the outcome of the pods run by the hook before they may be deleted
+		cfg.recordHookPodOutcome(h, rl.Namespace)
 		// Mark hook as succeeded or failed
 		if err != nil {
 			h.LastRun.Phase = release.HookPhaseFailed
+			h.LastRun.Message = err.Error()
 			// If a hook is failed, check the annotation of the hook to determine if we should copy the logs client side
 			if errOutputting := cfg.outputLogsByPolicy(h, rl.Namespace, release.HookOutputOnFailed); errOutputting != nil {
 				// We log the error here as we want to propagate the hook failure upwards to the release object.
@@ -150,7 +174,7 @@ func (cfg *Configuration) deleteHookByPolicy(h *release.Hook, policy release.Hoo
 		return nil
 	}
 	if cfg.hookHasDeletePolicy(h, policy) {
-		resources, err := cfg.KubeClient.Build(bytes.NewBufferString(h.Manifest), false)
+		resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), false)
 		if err != nil {
 			return fmt.Errorf("unable to build kubernetes object for deleting hook %s: %w", h.Path, err)
 		}
@@ -212,14 +236,85 @@ func (cfg *Configuration) outputLogsByPolicy(h *release.Hook, releaseNamespace s
 	if err != nil {
 		return err
 	}
+	listOptions, ok := hookPodListOptions(h)
+	if !ok {
+		return nil
+	}
+	return cfg.outputContainerLogsForListOptions(namespace, listOptions)
+}
+
+// hookPodListOptions returns the options selecting the pods run by a hook.
+// Only Job and Pod hooks run pods.
+func hookPodListOptions(h *release.Hook) (metav1.ListOptions, bool) {
 	switch h.Kind {
 	case "Job":
-		return cfg.outputContainerLogsForListOptions(namespace, metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", h.Name)})
+		return metav1.ListOptions{LabelSelector: fmt.Sprintf("job-name=%s", h.Name)}, true
 	case "Pod":
-		return cfg.outputContainerLogsForListOptions(namespace, metav1.ListOptions{FieldSelector: fmt.Sprintf("metadata.name=%s", h.Name)})
+		return metav1.ListOptions{FieldSelector: fmt.Sprintf("metadata.name=%s", h.Name)}, true
 	default:
-		return nil
+		return metav1.ListOptions{}, false
+	}
+}
+
+// recordHookPodOutcome records the exit code and the number of retries of the
+// pods run by a hook in its last run. Errors are logged, as they must not turn
+// the outcome of the hook itself into a failure.
+func (cfg *Configuration) recordHookPodOutcome(h *release.Hook, releaseNamespace string) {
+	kubeClient, ok := cfg.KubeClient.(kube.InterfaceLogs)
+	if !ok {
+		return
+	}
+	listOptions, ok := hookPodListOptions(h)
+	if !ok {
+		return
+	}
+	namespace, err := cfg.deriveNamespace(h, releaseNamespace)
+	if err != nil {
+		log.Printf("error recording the outcome of hook %s: %v", h.Path, err)
+		return
+	}
+	podList, err := kubeClient.GetPodList(namespace, listOptions)
+	if err != nil {
+		log.Printf("error recording the outcome of hook %s: %v", h.Path, err)
+		return
+	}
+	h.LastRun.ExitCode, h.LastRun.Retries = hookPodOutcome(podList.Items)
+}
+
+// hookPodOutcome returns the exit code of the most recent pod that terminated,
+// preferring a failing container, and how many times the pods were retried.
+func hookPodOutcome(pods []v1.Pod) (*int32, int) {
+	if len(pods) == 0 {
+		return nil, 0
+	}
+	pods = slices.Clone(pods)
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pods[i].CreationTimestamp.Before(&pods[j].CreationTimestamp)
+	})
+
+	// Every pod after the first one is a retry, as is every container restart.
+	retries := len(pods) - 1
+	var exitCode *int32
+	for _, pod := range pods {
+		var podExitCode *int32
+		for _, cs := range pod.Status.ContainerStatuses {
+			retries += int(cs.RestartCount)
+			terminated := cs.State.Terminated
+			if terminated == nil {
+				terminated = cs.LastTerminationState.Terminated
+			}
+			if terminated == nil {
+				continue
+			}
+			if podExitCode == nil || (*podExitCode == 0 && terminated.ExitCode != 0) {
+				podExitCode = &terminated.ExitCode
+			}
+		}
+		if podExitCode != nil {
+			exitCode = podExitCode
+		}
 	}
+	return exitCode, retries
 }
 
 func (cfg *Configuration) outputContainerLogsForListOptions(namespace string, listOptions metav1.ListOptions) error {
@@ -256,3 +351,49 @@ func (cfg *Configuration) deriveNamespace(h *release.Hook, namespace string) (st
 func hookHasOutputLogPolicy(h *release.Hook, policy release.HookOutputLogPolicy) bool {
 	return slices.Contains(h.OutputLogPolicies, policy)
 }
+
+// hookMetadataVisitor sets the labels and annotations of the objects of the
+// release on the objects of its hooks, and fails when they are missing labels
+// the release requires.
+func hookMetadataVisitor(rl *release.Release) resource.VisitorFunc {
+	labels := hookObjectLabels(rl.ObjectLabels, rl.Name, rl.Namespace)
+	annotations := objectAnnotations(rl.ObjectLabels)
+	return func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		if len(labels) > 0 {
+			if err := mergeLabels(info.Object, labels); err != nil {
+				return err
+			}
+		}
+		if missing, err := missingRequiredLabels(rl.ObjectLabels, info.Object); err != nil {
+			return err
+		} else if len(missing) > 0 {
+			return fmt.Errorf("%s is missing the required labels %s", resourceString(info), strings.Join(missing, ", "))
+		}
+		if len(annotations) == 0 {
+			return nil
+		}
+		return mergeAnnotations(info.Object, annotations)
+	}
+}
+
+// checkHookLabels fails when the objects of the hooks of a release are missing
+// labels the release requires, so that the release fails before any of its
+// hooks or resources is created rather than halfway through.
+func (cfg *Configuration) checkHookLabels(rl *release.Release) error {
+	if rl.ObjectLabels == nil || len(rl.ObjectLabels.Required) == 0 {
+		return nil
+	}
+	for _, h := range rl.Hooks {
+		resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), false)
+		if err != nil {
+			return fmt.Errorf("unable to build kubernetes object for hook %s: %w", h.Path, err)
+		}
+		if err := resources.Visit(hookMetadataVisitor(rl)); err != nil {
+			return fmt.Errorf("unable to set the labels of hook %s: %w", h.Path, err)
+		}
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/cli-runtime/pkg/resource"
 
@@ -402,3 +403,128 @@ data:
 		})
 	}
 }
+
+func TestHookPodOutcome(t *testing.T) {
+	terminated := func(code int32) v1.ContainerState {
+		return v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: code}}
+	}
+	pod := func(minute int, restarts int32, states ...v1.ContainerState) v1.Pod {
+		p := v1.Pod{}
+		p.CreationTimestamp = metav1.NewTime(time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC))
+		for _, s := range states {
+			p.Status.ContainerStatuses = append(p.Status.ContainerStatuses, v1.ContainerStatus{State: s, RestartCount: restarts})
+		}
+		return p
+	}
+	exitCode := func(code int32) *int32 { return &code }
+
+	tests := []struct {
+		name     string
+		pods     []v1.Pod
+		exitCode *int32
+		retries  int
+	}{
+		{
+			name: "no pods",
+		},
+		{
+			name:     "single succeeded pod",
+			pods:     []v1.Pod{pod(0, 0, terminated(0))},
+			exitCode: exitCode(0),
+		},
+		{
+			name:     "failing container takes precedence",
+			pods:     []v1.Pod{pod(0, 0, terminated(0), terminated(2))},
+			exitCode: exitCode(2),
+		},
+		{
+			name:     "most recent pod wins",
+			pods:     []v1.Pod{pod(2, 0, terminated(0)), pod(0, 0, terminated(1)), pod(1, 0, terminated(1))},
+			exitCode: exitCode(0),
+			retries:  2,
+		},
+		{
+			name:     "container restarts are retries",
+			pods:     []v1.Pod{pod(0, 3, terminated(137))},
+			exitCode: exitCode(137),
+			retries:  3,
+		},
+		{
+			name:    "running pod has no exit code",
+			pods:    []v1.Pod{pod(0, 0, v1.ContainerState{Running: &v1.ContainerStateRunning{}})},
+			retries: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exitCode, retries := hookPodOutcome(tt.pods)
+			assert.Equal(t, tt.exitCode, exitCode)
+			assert.Equal(t, tt.retries, retries)
+		})
+	}
+}
+
+// podListKubeClient returns the given pods for every pod list request.
+type podListKubeClient struct {
+	*kubefake.FailingKubeClient
+	pods []v1.Pod
+}
+
+func (c *podListKubeClient) GetPodList(_ string, _ metav1.ListOptions) (*v1.PodList, error) {
+	return &v1.PodList{Items: c.pods}, nil
+}
+
+func TestInstallRelease_HookLastRun(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ReleaseName = "failed-hooks"
+	failingClient := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failingClient.WatchUntilReadyError = fmt.Errorf("job failed: BackoffLimitExceeded")
+	instAction.cfg.KubeClient = &podListKubeClient{
+		FailingKubeClient: failingClient,
+		pods: []v1.Pod{
+			{Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				RestartCount: 1,
+				State:        v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 3}},
+			}}}},
+		},
+	}
+
+	templates := []*common.File{
+		{Name: "templates/hello", Data: []byte("hello: world")},
+		{Name: "templates/hooks", Data: []byte(jobManifestWithOutputLog(nil))},
+	}
+	res, err := instAction.Run(buildChartWithTemplates(templates), map[string]interface{}{})
+	is.Error(err)
+
+	is.Len(res.Hooks, 1)
+	lastRun := res.Hooks[0].LastRun
+	is.Equal(release.HookPhaseFailed, lastRun.Phase)
+	is.Equal(release.HookPreInstall, lastRun.Event)
+	is.Equal("job failed: BackoffLimitExceeded", lastRun.Message)
+	is.NotNil(lastRun.ExitCode)
+	is.Equal(int32(3), *lastRun.ExitCode)
+	is.Equal(1, lastRun.Retries)
+	is.False(lastRun.CompletedAt.Before(lastRun.StartedAt))
+}
+
+func TestCheckHookLabels(t *testing.T) {
+	config := actionConfigFixture(t)
+	config.KubeClient.(*kubefake.FailingKubeClient).DummyResources = kube.ResourceList{newDeploymentResource("migrate", "ns-a")}
+	rel := &release.Release{
+		Name:      "rel-a",
+		Namespace: "ns-a",
+		Hooks: []*release.Hook{{
+			Path:     "templates/migrate.yaml",
+			Manifest: "kind: Deployment",
+			Events:   []release.HookEvent{release.HookPostInstall},
+		}},
+		ObjectLabels: &release.ObjectLabels{Required: []string{"team"}},
+	}
+
+	err := config.checkHookLabels(rel)
+	assert.EqualError(t, err, `unable to set the labels of hook templates/migrate.yaml: Deployment "migrate" in namespace "" is missing the required labels team`)
+
+	rel.ObjectLabels.Extra = map[string]string{"team": "payments"}
+	assert.NoError(t, config.checkHookLabels(rel))
+}
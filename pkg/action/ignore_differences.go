@@ -0,0 +1,257 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// IgnoreDifferencesAnnotation is the chart annotation listing the fields of
+// the objects of its releases left out of their diffs, as a YAML list of
+// release.IgnoreDifference, such as:
+//
+//	annotations:
+//	  helm.sh/ignore-differences: |
+//	    - kind: Deployment
+//	      name: web
+//	      json_pointers: [/spec/replicas]
+//	    - kind: Deployment
+//	      json_paths: [".spec.template.spec.containers[?(@.name=='istio-proxy')]"]
+//
+// The rules of the install and upgrade actions are added to them.
+const IgnoreDifferencesAnnotation = "helm.sh/ignore-differences"
+
+// ignoreDifferencesFor returns the rules of a release of the chart: the rules
+// of the chart annotation followed by the given rules. It returns nil when
+// there are none.
+func ignoreDifferencesFor(chrt *chart.Chart, opts []release.IgnoreDifference) ([]release.IgnoreDifference, error) {
+	var rules []release.IgnoreDifference
+	if chrt != nil && chrt.Metadata != nil {
+		if v := chrt.Metadata.Annotations[IgnoreDifferencesAnnotation]; v != "" {
+			if err := yaml.UnmarshalStrict([]byte(v), &rules); err != nil {
+				return nil, fmt.Errorf("invalid chart annotation %s: %w", IgnoreDifferencesAnnotation, err)
+			}
+			if _, err := compileIgnoreRules(rules); err != nil {
+				return nil, fmt.Errorf("invalid chart annotation %s: %w", IgnoreDifferencesAnnotation, err)
+			}
+		}
+	}
+	if _, err := compileIgnoreRules(opts); err != nil {
+		return nil, err
+	}
+	rules = append(rules, opts...)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// ignoreRule is a compiled release.IgnoreDifference.
+type ignoreRule struct {
+	group, kind, name, namespace string
+	paths                        [][]pathSegment
+}
+
+// matches reports whether the rule applies to an object.
+func (r ignoreRule) matches(group, kind, namespace, name string) bool {
+	return (r.group == "" || r.group == group) &&
+		(r.kind == "" || r.kind == kind) &&
+		(r.namespace == "" || r.namespace == namespace) &&
+		(r.name == "" || r.name == name)
+}
+
+// compileIgnoreRules parses the paths of rules. The JSON pointers, if any,
+// make up an additional rule applying to all the objects.
+func compileIgnoreRules(rules []release.IgnoreDifference, pointers ...string) ([]ignoreRule, error) {
+	compiled := make([]ignoreRule, 0, len(rules)+1)
+	for _, r := range slices.Concat(rules, []release.IgnoreDifference{{JSONPointers: pointers}}) {
+		rule := ignoreRule{group: r.Group, kind: r.Kind, name: r.Name, namespace: r.Namespace}
+		for _, p := range r.JSONPointers {
+			path, err := parseJSONPointer(p)
+			if err != nil {
+				return nil, err
+			}
+			rule.paths = append(rule.paths, path)
+		}
+		for _, p := range r.JSONPaths {
+			path, err := parseJSONPath(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSONPath %q: %w", p, err)
+			}
+			rule.paths = append(rule.paths, path)
+		}
+		if len(rule.paths) > 0 {
+			compiled = append(compiled, rule)
+		}
+	}
+	return compiled, nil
+}
+
+// pathSegment selects fields of objects, or items of lists, by their name or
+// index, any of them with "*", or the items of lists whose field has a value.
+type pathSegment struct {
+	name        string
+	filterField string
+	filterValue string
+}
+
+// matches reports whether the segment selects a field, or a list item when
+// key is its index.
+func (s pathSegment) matches(key string, value interface{}, isItem bool) bool {
+	if s.filterField == "" {
+		return s.name == "*" || s.name == key
+	}
+	fields, ok := value.(map[string]interface{})
+	if !isItem || !ok {
+		return false
+	}
+	field, ok := fields[s.filterField]
+	return ok && fmt.Sprint(field) == s.filterValue
+}
+
+// parseJSONPointer splits a JSON pointer into its unescaped fields.
+func parseJSONPointer(p string) ([]pathSegment, error) {
+	if !strings.HasPrefix(p, "/") || p == "/" {
+		return nil, fmt.Errorf("invalid ignore path %q: expected a JSON pointer such as /metadata/labels", p)
+	}
+	var path []pathSegment
+	for _, field := range strings.Split(p[1:], "/") {
+		path = append(path, pathSegment{name: strings.NewReplacer("~1", "/", "~0", "~").Replace(field)})
+	}
+	return path, nil
+}
+
+// parseJSONPath parses the JSONPath expressions selecting fields: child
+// fields, such as ".spec.replicas" or "['helm.sh/chart']", list indexes and
+// wildcards, such as "[0]" or "[*]", and filters on the fields of list items,
+// such as "[?(@.name=='istio-proxy')]".
+func parseJSONPath(expr string) ([]pathSegment, error) {
+	p := strings.TrimSpace(expr)
+	if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+		p = p[1 : len(p)-1]
+	}
+	p = strings.TrimPrefix(p, "$")
+
+	var path []pathSegment
+	for p != "" {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			n := strings.IndexAny(p, ".[")
+			if n < 0 {
+				n = len(p)
+			}
+			if n == 0 {
+				return nil, errors.New("missing field name")
+			}
+			path = append(path, pathSegment{name: p[:n]})
+			p = p[n:]
+		case '[':
+			end := "]"
+			if strings.HasPrefix(p, "[?(") {
+				end = ")]"
+			}
+			n := strings.Index(p, end)
+			if n < 0 {
+				return nil, fmt.Errorf("missing %q", end)
+			}
+			segment, err := parseJSONPathBracket(p[1 : n+len(end)-1])
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, segment)
+			p = p[n+len(end):]
+		default:
+			return nil, fmt.Errorf("unexpected %q, expected a field such as .spec", p)
+		}
+	}
+	if len(path) == 0 {
+		return nil, errors.New("no field selected")
+	}
+	return path, nil
+}
+
+// parseJSONPathBracket parses the content of brackets of a JSONPath.
+func parseJSONPathBracket(s string) (pathSegment, error) {
+	s = strings.TrimSpace(s)
+	if filter, ok := strings.CutPrefix(s, "?("); ok {
+		filter = strings.TrimSuffix(filter, ")")
+		field, value, ok := strings.Cut(filter, "==")
+		field = strings.TrimSpace(field)
+		if !ok || !strings.HasPrefix(field, "@.") || len(field) == 2 {
+			return pathSegment{}, fmt.Errorf("unsupported filter %q, expected one such as ?(@.name=='sidecar')", s)
+		}
+		return pathSegment{filterField: field[2:], filterValue: unquote(strings.TrimSpace(value))}, nil
+	}
+	if s == "*" {
+		return pathSegment{name: s}, nil
+	}
+	if _, err := strconv.Atoi(s); err == nil {
+		return pathSegment{name: s}, nil
+	}
+	if name := unquote(s); name != s {
+		return pathSegment{name: name}, nil
+	}
+	return pathSegment{}, fmt.Errorf("unsupported selector [%s]", s)
+}
+
+// unquote removes the single or double quotes around s, if any.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// removeField removes the fields at a path from a value, and returns the
+// value.
+func removeField(value interface{}, path []pathSegment) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if !path[0].matches(key, child, false) {
+				continue
+			}
+			if len(path) == 1 {
+				delete(v, key)
+			} else {
+				v[key] = removeField(child, path[1:])
+			}
+		}
+	case []interface{}:
+		kept := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			switch {
+			case !path[0].matches(strconv.Itoa(i), item, true):
+				kept = append(kept, item)
+			case len(path) > 1:
+				kept = append(kept, removeField(item, path[1:]))
+			}
+		}
+		return kept
+	}
+	return value
+}
@@ -0,0 +1,240 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+const ignoreDifferencesManifest = `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.25
+---
+# Source: app/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+spec:
+  replicas: 1
+`
+
+func TestIgnorePaths(t *testing.T) {
+	ignore, err := compileIgnoreRules(nil,
+		"/metadata/labels/helm.sh~1chart",
+		"/spec/containers/*/image",
+		"/spec/volumes/0",
+	)
+	require.NoError(t, err)
+	objects, err := manifestObjects(`apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  labels:
+    app: web
+    helm.sh/chart: app-1.0.0
+spec:
+  containers:
+  - name: web
+    image: nginx:1.25
+  - name: sidecar
+    image: envoy:1.30
+  volumes:
+  - name: cache
+  - name: data
+`, "default", ignore...)
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Pod
+metadata:
+  labels:
+    app: web
+  name: app
+spec:
+  containers:
+  - name: web
+  - name: sidecar
+  volumes:
+  - name: data
+`, objects["Pod default/app"])
+
+	for _, path := range []string{"metadata/labels", "/", ""} {
+		_, err := compileIgnoreRules(nil, path)
+		assert.ErrorContains(t, err, "invalid ignore path", path)
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []pathSegment
+		err  string
+	}{
+		{expr: ".spec.replicas", want: []pathSegment{{name: "spec"}, {name: "replicas"}}},
+		{expr: "{$.spec.replicas}", want: []pathSegment{{name: "spec"}, {name: "replicas"}}},
+		{expr: ".metadata.annotations['helm.sh/chart']", want: []pathSegment{{name: "metadata"}, {name: "annotations"}, {name: "helm.sh/chart"}}},
+		{expr: ".spec.containers[*].image", want: []pathSegment{{name: "spec"}, {name: "containers"}, {name: "*"}, {name: "image"}}},
+		{expr: ".spec.containers[0]", want: []pathSegment{{name: "spec"}, {name: "containers"}, {name: "0"}}},
+		{expr: `.spec.containers[?(@.name == "istio-proxy")]`, want: []pathSegment{{name: "spec"}, {name: "containers"}, {filterField: "name", filterValue: "istio-proxy"}}},
+		{expr: "spec.replicas", err: `unexpected "spec.replicas"`},
+		{expr: ".spec..replicas", err: "missing field name"},
+		{expr: ".spec.containers[0", err: `missing "]"`},
+		{expr: ".spec.containers[?(@.name!='web')]", err: "unsupported filter"},
+		{expr: ".spec.containers[-1:]", err: "unsupported selector"},
+		{expr: "$", err: "no field selected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseJSONPath(tt.expr)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestIgnoreDifferencesFor(t *testing.T) {
+	chrt := buildChart(withAnnotations(map[string]string{
+		IgnoreDifferencesAnnotation: "- kind: Deployment\n  json_pointers: [/spec/replicas]\n",
+	}))
+	opts := []release.IgnoreDifference{{Kind: "Pod", JSONPaths: []string{".spec.nodeName"}}}
+	rules, err := ignoreDifferencesFor(chrt, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []release.IgnoreDifference{
+		{Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}},
+		{Kind: "Pod", JSONPaths: []string{".spec.nodeName"}},
+	}, rules)
+
+	rules, err = ignoreDifferencesFor(buildChart(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+
+	for _, annotation := range []string{"- kind: Deployment\n  jsonPointers: [/spec/replicas]\n", "- json_paths: [spec]\n"} {
+		chrt := buildChart(withAnnotations(map[string]string{IgnoreDifferencesAnnotation: annotation}))
+		_, err := ignoreDifferencesFor(chrt, nil)
+		assert.ErrorContains(t, err, "invalid chart annotation helm.sh/ignore-differences")
+	}
+
+	_, err = ignoreDifferencesFor(&chart.Chart{}, []release.IgnoreDifference{{JSONPointers: []string{"spec"}}})
+	assert.ErrorContains(t, err, `invalid ignore path "spec"`)
+}
+
+func TestRollbackDiffIgnoreDifferences(t *testing.T) {
+	config := actionConfigFixture(t)
+	// The autoscaler scaled the deployment, and a sidecar was injected.
+	live := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"replicas": int64(5),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "web", "image": "nginx:1.25"},
+						map[string]interface{}{"name": "istio-proxy", "image": "istio/proxyv2"},
+					},
+				},
+			},
+		},
+	}}
+	worker := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "worker", "namespace": "default"},
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+	}}
+	config.KubeClient = &liveKubeClient{
+		PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+		live:               map[string][]runtime.Object{"apps/v1/Deployment": {live, worker}},
+	}
+
+	for i := range 2 {
+		rel := releaseStub()
+		rel.Name = "app"
+		rel.Namespace = "default"
+		rel.Version = i + 1
+		rel.Manifest = ignoreDifferencesManifest
+		rel.Info.Status = release.StatusSuperseded
+		if i == 1 {
+			rel.Info.Status = release.StatusDeployed
+		}
+		rel.IgnoreDifferences = []release.IgnoreDifference{
+			{Group: "apps", Kind: "Deployment", Name: "web", JSONPointers: []string{"/spec/replicas"}},
+			{Kind: "Deployment", JSONPaths: []string{".spec.template.spec.containers[?(@.name=='istio-proxy')]"}},
+		}
+		require.NoError(t, config.Releases.Create(rel))
+	}
+
+	client := NewRollback(config)
+	client.DryRun = true
+	client.ServerSideApply = "auto"
+	diff, err := client.Diff("app")
+	require.NoError(t, err)
+	assert.Empty(t, diff.Current)
+	// The replicas of the worker are not ignored.
+	assert.Equal(t, `--- Deployment default/worker (live)
++++ Deployment default/worker (revision 1)
+@@ -3,4 +3,4 @@
+ metadata:
+   name: worker
+ spec:
+-  replicas: 3
++  replicas: 1
+`, diff.Live)
+}
+
+func TestInstallIgnoreDifferences(t *testing.T) {
+	instAction := installAction(t)
+	instAction.IgnoreDifferences = []release.IgnoreDifference{{Kind: "Pod", JSONPointers: []string{"/spec/nodeName"}}}
+	chrt := buildChart(withAnnotations(map[string]string{
+		IgnoreDifferencesAnnotation: "- kind: Deployment\n  json_pointers: [/spec/replicas]\n",
+	}))
+	rel, err := instAction.Run(chrt, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []release.IgnoreDifference{
+		{Kind: "Deployment", JSONPointers: []string{"/spec/replicas"}},
+		{Kind: "Pod", JSONPointers: []string{"/spec/nodeName"}},
+	}, rel.IgnoreDifferences)
+
+	stored, err := instAction.cfg.Releases.Get(rel.Name, rel.Version)
+	require.NoError(t, err)
+	assert.Equal(t, rel.IgnoreDifferences, stored.IgnoreDifferences)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"reflect"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// impersonatingGetter is a RESTClientGetter making all its requests as
+// another Kubernetes user.
+type impersonatingGetter struct {
+	genericclioptions.RESTClientGetter
+	impersonate rest.ImpersonationConfig
+}
+
+// ToRESTConfig returns the REST config of the wrapped getter, impersonating
+// the configured user.
+func (g *impersonatingGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	config.Impersonate = g.impersonate
+	return config, nil
+}
+
+// ToDiscoveryClient returns a discovery client impersonating the configured
+// user. The discovery client of the wrapped getter, and its cache, is used
+// when it impersonates the user already.
+func (g *impersonatingGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if g.impersonatesAlready() {
+		return g.RESTClientGetter.ToDiscoveryClient()
+	}
+	config, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(dc), nil
+}
+
+// ToRESTMapper returns a REST mapper discovering resources as the configured
+// user.
+func (g *impersonatingGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	if g.impersonatesAlready() {
+		return g.RESTClientGetter.ToRESTMapper()
+	}
+	dc, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return restmapper.NewShortcutExpander(mapper, dc, nil), nil
+}
+
+func (g *impersonatingGetter) impersonatesAlready() bool {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	return err == nil && reflect.DeepEqual(config.Impersonate, g.impersonate)
+}
+
+// deployedAs returns the identity recorded in the revisions created by the
+// actions, or nil when they do not impersonate anyone.
+func (cfg *Configuration) deployedAs() *release.Impersonation {
+	if cfg.Impersonation == nil || (cfg.Impersonation.UserName == "" && len(cfg.Impersonation.Groups) == 0) {
+		return nil
+	}
+	return &release.Impersonation{
+		User:   cfg.Impersonation.UserName,
+		Groups: slices.Clone(cfg.Impersonation.Groups),
+	}
+}
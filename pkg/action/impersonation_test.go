@@ -0,0 +1,55 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+func TestConfigurationInitImpersonation(t *testing.T) {
+	flags := genericclioptions.NewConfigFlags(false)
+	server := "https://localhost:6443"
+	flags.APIServer = &server
+
+	cfg := &Configuration{
+		Impersonation: &rest.ImpersonationConfig{UserName: "alice", Groups: []string{"dev"}},
+	}
+	require.NoError(t, cfg.Init(flags, "default", "memory"))
+
+	config, err := cfg.RESTClientGetter.ToRESTConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "alice", config.Impersonate.UserName)
+	assert.Equal(t, []string{"dev"}, config.Impersonate.Groups)
+
+	// The getter passed to Init is left alone.
+	config, err = flags.ToRESTConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.Impersonate.UserName)
+}
+
+func TestConfigurationInitWithoutImpersonation(t *testing.T) {
+	flags := genericclioptions.NewConfigFlags(false)
+	cfg := &Configuration{Impersonation: &rest.ImpersonationConfig{}}
+	require.NoError(t, cfg.Init(flags, "default", "memory"))
+	assert.Same(t, flags, cfg.RESTClientGetter)
+	assert.Nil(t, cfg.deployedAs())
+}
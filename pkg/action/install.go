@@ -114,6 +114,12 @@ type Install struct {
 	DisableOpenAPIValidation bool
 	IncludeCRDs              bool
 	Labels                   map[string]string
+	// ObjectLabels customizes the labels set on the objects of the release.
+	// Its fields take precedence over the annotations of the chart.
+	ObjectLabels release.ObjectLabels
+	// IgnoreDifferences are the fields of the objects of the release left out
+	// of its diffs, in addition to the ones the chart annotations list.
+	IgnoreDifferences []release.IgnoreDifference
 	// KubeVersion allows specifying a custom kubernetes version to use and
 	// APIVersions allows a manual set of supported API Versions to be passed
 	// (for things like templating). These are ignored if ClientOnly is false
@@ -123,15 +129,55 @@ type Install struct {
 	IsUpgrade bool
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// ConfigChecksums annotates the pod templates of the workloads with the
+	// checksum of the ConfigMaps and Secrets of the release they reference,
+	// so that they roll out when their configuration changes.
+	ConfigChecksums bool
 	// Used by helm template to add the release as part of OutputDir path
 	// OutputDir/<ReleaseName>
 	UseReleaseName bool
 	// TakeOwnership will ignore the check for helm annotations and take ownership of the resources.
 	TakeOwnership bool
-	PostRenderer  postrenderer.PostRenderer
+	// CheckPermissions reviews whether the user is allowed to create all the
+	// resources and hooks of the release before installing it, and fails
+	// listing all the missing permissions.
+	CheckPermissions bool
+	// CheckQuota estimates the resources the release uses, such as the CPU
+	// and memory its workloads request, and fails before installing it when
+	// they do not fit in the ResourceQuotas of its namespace.
+	CheckQuota bool
+	// SkipSetValidation disables checking that the values set by the --set
+	// family of flags among the ValuesSources are at paths of the default
+	// values or the values schema of the chart.
+	SkipSetValidation bool
+	// StrictSetValidation fails when values are set by the --set family of
+	// flags at paths unknown to the chart, which are only warned about
+	// otherwise, as charts may read values they do not declare.
+	StrictSetValidation bool
+	// SkipInstallConstraints disables checking the constraints the chart
+	// declares with its annotations, such as SingletonAnnotation.
+	SkipInstallConstraints bool
+	// IgnoreChartConflicts installs the chart even though it conflicts with
+	// charts deployed in the cluster, as the conflicts of the charts declare.
+	IgnoreChartConflicts bool
+	// ValuesSources are the sources the values passed to Run were merged
+	// from, lowest precedence first. Dry runs report, in the ValuesProvenance
+	// of the release, which of them, if any, each value comes from.
+	ValuesSources []common.ValuesSource
+	// LicensePolicy, when set, blocks installing charts with dependencies
+	// under licenses it does not allow.
+	LicensePolicy *LicensePolicy
+	// AdvisoryPolicy, when set, blocks installing charts affected by security
+	// advisories, or with dependencies that are.
+	AdvisoryPolicy *AdvisoryPolicy
+	// HealthChecks checks the health of the release once it is deployed,
+	// failing the release when the checks do not pass.
+	HealthChecks HealthChecks
+	PostRenderer postrenderer.PostRenderer
 	// Lock to control raceconditions when the process receives a SIGTERM
 	Lock           sync.Mutex
 	goroutineCount atomic.Int32
+	results        resultRecorder
 }
 
 // ChartPathOptions captures common options used for controlling chart paths
@@ -155,6 +201,10 @@ type ChartPathOptions struct {
 }
 
 // NewInstall creates a new Install object with the given configuration.
+//
+// The fields of the returned action are set by the caller and are not
+// validated until the action runs. New code should prefer
+// NewInstallWithOptions, which validates the options up front.
 func NewInstall(cfg *Configuration) *Install {
 	in := &Install{
 		cfg:             cfg,
@@ -186,9 +236,10 @@ func (i *Install) installCRDs(crds []chart.CRD) error {
 		}
 
 		// Send them to Kube
-		if _, err := i.cfg.KubeClient.Create(
+		created, err := i.cfg.KubeClient.Create(
 			res,
-			kube.ClientCreateOptionServerSideApply(i.ServerSideApply, i.ForceConflicts)); err != nil {
+			kube.ClientCreateOptionServerSideApply(i.ServerSideApply, i.ForceConflicts))
+		if err != nil {
 			// If the error is CRD already exists, continue.
 			if apierrors.IsAlreadyExists(err) {
 				crdName := res[0].Name
@@ -197,6 +248,7 @@ func (i *Install) installCRDs(crds []chart.CRD) error {
 			}
 			return fmt.Errorf("failed to install CRD %s: %w", obj.Name, err)
 		}
+		i.results.resources(created)
 		totalItems = append(totalItems, res...)
 	}
 	if len(totalItems) > 0 {
@@ -214,7 +266,7 @@ func (i *Install) installCRDs(crds []chart.CRD) error {
 		// the case when an action configuration is reused for multiple actions,
 		// as otherwise it is later loaded by ourselves when getCapabilities
 		// is called later on in the installation process.
-		if i.cfg.Capabilities != nil {
+		if i.cfg.hasCapabilities() {
 			discoveryClient, err := i.cfg.RESTClientGetter.ToDiscoveryClient()
 			if err != nil {
 				return err
@@ -254,6 +306,18 @@ func (i *Install) Run(chrt ci.Charter, vals map[string]interface{}) (*release.Re
 // When the task is cancelled through ctx, the function returns and the install
 // proceeds in the background.
 func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[string]interface{}) (*release.Release, error) {
+	i.results.start(i.cfg)
+	rel, err := i.run(ctx, ch, vals)
+	i.results.finish(rel, release.HookPreInstall, release.HookPostInstall)
+	return rel, err
+}
+
+// Result returns what the last run of the install did.
+func (i *Install) Result() *Result {
+	return i.results.get()
+}
+
+func (i *Install) run(ctx context.Context, ch ci.Charter, vals map[string]interface{}) (*release.Release, error) {
 	var chrt *chart.Chart
 	switch c := ch.(type) {
 	case *chart.Chart:
@@ -283,11 +347,41 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 		return nil, fmt.Errorf("release name check failed: %w", err)
 	}
 
+	if !i.SkipInstallConstraints {
+		if err := i.checkInstallConstraints(chrt); err != nil {
+			return nil, err
+		}
+	}
+
+	if !i.SkipSetValidation {
+		if err := checkSetPaths(chrt, i.ValuesSources, i.StrictSetValidation, i.results.warn); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := chartutil.ProcessDependencies(chrt, vals); err != nil {
 		slog.Error("chart dependencies processing failed", slog.Any("error", err))
 		return nil, fmt.Errorf("chart dependencies processing failed: %w", err)
 	}
 
+	// Disabled dependencies are not installed, so their licenses and
+	// conflicts are not checked.
+	if !i.ClientOnly && !i.IgnoreChartConflicts {
+		if err := i.checkChartConflicts(chrt); err != nil {
+			return nil, err
+		}
+	}
+	if i.LicensePolicy != nil {
+		if err := i.LicensePolicy.Check(chrt); err != nil {
+			return nil, err
+		}
+	}
+	if i.AdvisoryPolicy != nil {
+		if err := i.AdvisoryPolicy.Check(chrt, i.RepoURL); err != nil {
+			return nil, err
+		}
+	}
+
 	var interactWithRemote bool
 	if !i.isDryRun() || i.DryRunOption == "server" || i.DryRunOption == "none" || i.DryRunOption == "false" {
 		interactWithRemote = true
@@ -298,7 +392,7 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 	if crds := chrt.CRDObjects(); !i.ClientOnly && !i.SkipCRDs && len(crds) > 0 {
 		// On dry run, bail here
 		if i.isDryRun() {
-			slog.Warn("This chart or one of its subcharts contains CRDs. Rendering may fail or contain inaccuracies.")
+			i.results.warn(WarningRendering, "This chart or one of its subcharts contains CRDs. Rendering may fail or contain inaccuracies.")
 		} else if err := i.installCRDs(crds); err != nil {
 			return nil, err
 		}
@@ -307,16 +401,22 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 	if i.ClientOnly {
 		// Add mock objects in here so it doesn't use Kube API server
 		// NOTE(bacongobbler): used for `helm template`
-		i.cfg.Capabilities = common.DefaultCapabilities.Copy()
+		caps := common.DefaultCapabilities.Copy()
 		if i.KubeVersion != nil {
-			i.cfg.Capabilities.KubeVersion = *i.KubeVersion
+			caps.KubeVersion = *i.KubeVersion
 		}
-		i.cfg.Capabilities.APIVersions = append(i.cfg.Capabilities.APIVersions, i.APIVersions...)
-		i.cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+		caps.APIVersions = append(caps.APIVersions, i.APIVersions...)
 
 		mem := driver.NewMemory()
 		mem.SetNamespace(i.Namespace)
-		i.cfg.Releases = storage.Init(mem)
+
+		// The mocks go into a configuration of their own, as the one given
+		// to the action may be shared with other actions.
+		cfg := i.cfg.clone()
+		cfg.Releases = storage.Init(mem)
+		cfg.KubeClient = &kubefake.PrintingKubeClient{Out: io.Discard}
+		cfg.Capabilities = caps
+		i.cfg = cfg
 	} else if !i.ClientOnly && len(i.APIVersions) > 0 {
 		slog.Debug("API Version list given outside of client only mode, this list will be ignored")
 	}
@@ -341,19 +441,37 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 		IsInstall: !isUpgrade,
 		IsUpgrade: isUpgrade,
 	}
-	valuesToRender, err := util.ToRenderValuesWithSchemaValidation(chrt, vals, options, caps, i.SkipSchemaValidation)
+	valuesToRender, err := util.ToRenderValuesWithWarnings(chrt, vals, options, caps, i.SkipSchemaValidation, i.results.warnf(WarningValues))
 	if err != nil {
-		return nil, err
+		return nil, classify(ErrInvalid, err)
 	}
 
 	if driver.ContainsSystemLabels(i.Labels) {
 		return nil, fmt.Errorf("user supplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels())
 	}
 
+	objectLabels, err := objectLabelsFor(chrt, chartValues(valuesToRender), i.ObjectLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreDifferences, err := ignoreDifferencesFor(chrt, i.IgnoreDifferences)
+	if err != nil {
+		return nil, err
+	}
+
 	rel := i.createRelease(chrt, vals, i.Labels)
+	rel.ObjectLabels = objectLabels
+	rel.IgnoreDifferences = ignoreDifferences
+
+	// The health checks are resolved before the release is deployed, for
+	// invalid checks not to fail it once deployed.
+	if _, _, err := i.cfg.healthChecksFor(rel, i.HealthChecks, false); err != nil {
+		return nil, err
+	}
 
 	var manifestDoc *bytes.Buffer
-	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
+	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret, renderOptions{ConfigChecksums: i.ConfigChecksums, Namespace: i.Namespace}, i.results.warn)
 	// Even for errors, attach this if available
 	if manifestDoc != nil {
 		rel.Manifest = manifestDoc.String()
@@ -364,21 +482,30 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 		// Return a release with partial data so that the client can show debugging information.
 		return rel, err
 	}
+	if err := i.cfg.emitEvent(ctx, EventPostRender, rel); err != nil {
+		rel.SetStatus(release.StatusFailed, err.Error())
+		return rel, err
+	}
 
 	// Mark this release as in-progress
 	rel.SetStatus(release.StatusPendingInstall, "Initial install underway")
 
 	var toBeAdopted kube.ResourceList
-	resources, err := i.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), !i.DisableOpenAPIValidation)
+	resources, err := i.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), !i.DisableOpenAPIValidation)
 	if err != nil {
 		return nil, fmt.Errorf("unable to build kubernetes objects from release manifest: %w", err)
 	}
 
 	// It is safe to use "forceOwnership" here because these are resources currently rendered by the chart.
-	err = resources.Visit(setMetadataVisitor(rel.Name, rel.Namespace, true))
+	err = resources.Visit(setMetadataVisitor(rel.Name, rel.Namespace, true, rel.ObjectLabels))
 	if err != nil {
 		return nil, err
 	}
+	if !i.DisableHooks {
+		if err := i.cfg.checkHookLabels(rel); err != nil {
+			return nil, err
+		}
+	}
 
 	// Install requires an extra validation step of checking that resources
 	// don't already exist before we actually create resources. If we continue
@@ -390,19 +517,44 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 		if i.TakeOwnership {
 			toBeAdopted, err = requireAdoption(resources)
 		} else {
-			toBeAdopted, err = existingResourceConflict(resources, rel.Name, rel.Namespace)
+			toBeAdopted, err = existingResourceConflict(resources, rel.Name, rel.Namespace, rel.ObjectLabels)
 		}
 		if err != nil {
 			return nil, fmt.Errorf("unable to continue with install: %w", err)
 		}
 	}
 
+	if i.CheckPermissions && !i.ClientOnly {
+		accesses := accessesFor("create", resources.Difference(toBeAdopted))
+		accesses = append(accesses, accessesFor("patch", toBeAdopted)...)
+		hookAccesses, err := i.cfg.hookAccesses(rel.Hooks)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.cfg.checkPermissions(ctx, append(accesses, hookAccesses...)); err != nil {
+			return nil, fmt.Errorf("unable to continue with install: %w", err)
+		}
+	}
+
+	if i.CheckQuota && !i.ClientOnly {
+		if err := i.cfg.checkQuota(ctx, rel.Manifest, rel.Namespace, i.results.warn); err != nil {
+			return nil, fmt.Errorf("unable to continue with install: %w", err)
+		}
+	}
+
 	// Bail out here if it is a dry run
 	if i.isDryRun() {
 		rel.Info.Description = "Dry run complete"
+		if rel.ValuesProvenance, err = util.ValuesProvenance(chrt, vals, i.ValuesSources); err != nil {
+			return nil, fmt.Errorf("unable to trace the values: %w", err)
+		}
 		return rel, nil
 	}
 
+	if err := i.cfg.emitEvent(ctx, EventPreInstall, rel); err != nil {
+		return nil, err
+	}
+
 	if i.CreateNamespace {
 		ns := &v1.Namespace{
 			TypeMeta: metav1.TypeMeta{
@@ -424,11 +576,15 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 		if err != nil {
 			return nil, err
 		}
-		if _, err := i.cfg.KubeClient.Create(
+		created, err := i.cfg.KubeClient.Create(
 			resourceList,
-			kube.ClientCreateOptionServerSideApply(i.ServerSideApply, false)); err != nil && !apierrors.IsAlreadyExists(err) {
+			kube.ClientCreateOptionServerSideApply(i.ServerSideApply, false))
+		if err != nil && !apierrors.IsAlreadyExists(err) {
 			return nil, err
 		}
+		if err == nil {
+			i.results.resources(created)
+		}
 	}
 
 	// If Replace is true, we need to supersede the last release.
@@ -448,9 +604,10 @@ func (i *Install) RunWithContext(ctx context.Context, ch ci.Charter, vals map[st
 
 	rel, err = i.performInstallCtx(ctx, rel, toBeAdopted, resources)
 	if err != nil {
-		rel, err = i.failRelease(rel, err)
+		return i.failRelease(rel, err)
 	}
-	return rel, err
+	i.cfg.notifyEvent(ctx, EventPostInstall, rel)
+	return rel, nil
 }
 
 func (i *Install) performInstallCtx(ctx context.Context, rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
@@ -489,6 +646,7 @@ func (i *Install) isDryRun() bool {
 }
 
 func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.ResourceList, resources kube.ResourceList) (*release.Release, error) {
+	var applied *kube.Result
 	var err error
 	// pre-install hooks
 	if !i.DisableHooks {
@@ -501,12 +659,12 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	// do an update, but it's not clear whether we WANT to do an update if the reuse is set
 	// to true, since that is basically an upgrade operation.
 	if len(toBeAdopted) == 0 && len(resources) > 0 {
-		_, err = i.cfg.KubeClient.Create(
+		applied, err = i.cfg.KubeClient.Create(
 			resources,
 			kube.ClientCreateOptionServerSideApply(i.ServerSideApply, false))
 	} else if len(resources) > 0 {
 		updateThreeWayMergeForUnstructured := i.TakeOwnership && !i.ServerSideApply // Use three-way merge when taking ownership (and not using server-side apply)
-		_, err = i.cfg.KubeClient.Update(
+		applied, err = i.cfg.KubeClient.Update(
 			toBeAdopted,
 			resources,
 			kube.ClientUpdateOptionForceReplace(i.ForceReplace),
@@ -514,6 +672,7 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 			kube.ClientUpdateOptionThreeWayMergeForUnstructured(updateThreeWayMergeForUnstructured),
 			kube.ClientUpdateOptionUpgradeClientSideFieldManager(true))
 	}
+	i.results.resources(applied)
 	if err != nil {
 		return rel, err
 	}
@@ -523,11 +682,13 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 		return rel, fmt.Errorf("failed to get waiter: %w", err)
 	}
 
+	waitStart := i.cfg.Now()
 	if i.WaitForJobs {
 		err = waiter.WaitWithJobs(resources, i.Timeout)
 	} else {
 		err = waiter.Wait(resources, i.Timeout)
 	}
+	i.results.waited(waitStart)
 	if err != nil {
 		return rel, err
 	}
@@ -538,9 +699,16 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 		}
 	}
 
-	if len(i.Description) > 0 {
+	if err := i.cfg.checkHealth(rel, i.HealthChecks, false); err != nil {
+		return rel, err
+	}
+
+	switch {
+	case len(i.Description) > 0:
 		rel.SetStatus(release.StatusDeployed, i.Description)
-	} else {
+	case i.Replace && rel.Version > 1:
+		rel.SetStatus(release.StatusDeployed, fmt.Sprintf("Install complete, replacing the history up to revision %d", rel.Version-1))
+	default:
 		rel.SetStatus(release.StatusDeployed, "Install complete")
 	}
 
@@ -566,10 +734,12 @@ func (i *Install) failRelease(rel *release.Release, err error) (*release.Release
 		uninstall.DisableHooks = i.DisableHooks
 		uninstall.KeepHistory = false
 		uninstall.Timeout = i.Timeout
-		if _, uninstallErr := uninstall.Run(i.ReleaseName); uninstallErr != nil {
+		_, uninstallErr := uninstall.Run(i.ReleaseName)
+		i.results.merge(uninstall.Result())
+		if uninstallErr != nil {
 			return rel, fmt.Errorf("an error occurred while uninstalling the release. original install error: %w: %w", err, uninstallErr)
 		}
-		return rel, fmt.Errorf("release %s failed, and has been uninstalled due to rollback-on-failure being set: %w", i.ReleaseName, err)
+		return rel, &RolledBackError{fmt.Errorf("release %s failed, and has been uninstalled due to rollback-on-failure being set: %w", i.ReleaseName, err)}
 	}
 	i.recordRelease(rel) // Ignore the error, since we have another error to deal with.
 	return rel, err
@@ -587,7 +757,7 @@ func (i *Install) availableName() error {
 	start := i.ReleaseName
 
 	if err := chartutil.ValidateReleaseName(start); err != nil {
-		return fmt.Errorf("release name %q: %w", start, err)
+		return classify(ErrInvalid, fmt.Errorf("release name %q: %w", start, err))
 	}
 	// On dry run, bail here
 	if i.isDryRun() {
@@ -604,7 +774,10 @@ func (i *Install) availableName() error {
 	if st := rel.Info.Status; i.Replace && (st == release.StatusUninstalled || st == release.StatusFailed) {
 		return nil
 	}
-	return errors.New("cannot reuse a name that is still in use")
+	if rel.Info.Status == release.StatusUninstalled {
+		return classify(ErrConflict, fmt.Errorf("cannot reuse the name of uninstalled release %q whose history was kept: use --replace to install it again, continuing its history from revision %d", start, rel.Version+1))
+	}
+	return classify(ErrConflict, errors.New("cannot reuse a name that is still in use"))
 }
 
 // createRelease creates a new release object
@@ -620,6 +793,8 @@ func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{
 			FirstDeployed: ts,
 			LastDeployed:  ts,
 			Status:        release.StatusUnknown,
+			DeployedBy:    i.cfg.Actor,
+			DeployedAs:    i.cfg.deployedAs(),
 		},
 		Version:     1,
 		Labels:      labels,
@@ -729,7 +904,7 @@ func (i *Install) NameAndChart(args []string) (string, string, error) {
 	}
 
 	if i.NameTemplate != "" {
-		name, err := TemplateName(i.NameTemplate)
+		name, err := i.cfg.templateName(i.NameTemplate)
 		return name, args[0], err
 	}
 
@@ -750,16 +925,39 @@ func (i *Install) NameAndChart(args []string) (string, string, error) {
 		base = base[0:idx]
 	}
 
-	return fmt.Sprintf("%s-%d", base, time.Now().Unix()), args[0], nil
+	return fmt.Sprintf("%s-%d", base, i.cfg.Now().Unix()), args[0], nil
 }
 
 // TemplateName renders a name template, returning the name or an error.
 func TemplateName(nameTemplate string) (string, error) {
+	return (&Configuration{}).templateName(nameTemplate)
+}
+
+// The characters of the random functions of the name templates.
+const (
+	randLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	randDigits  = "0123456789"
+	randASCII   = " !\"#$%&'()*+,-./" + randDigits + ":;<=>?@" + "ABCDEFGHIJKLMNOPQRSTUVWXYZ" + "[\\]^_`" + "abcdefghijklmnopqrstuvwxyz" + "{|}~"
+)
+
+// templateName renders a name template, whose now and random functions use
+// the clock and the randomness of the configuration.
+func (cfg *Configuration) templateName(nameTemplate string) (string, error) {
 	if nameTemplate == "" {
 		return "", nil
 	}
 
-	t, err := template.New("name-template").Funcs(sprig.TxtFuncMap()).Parse(nameTemplate)
+	funcs := sprig.TxtFuncMap()
+	funcs["now"] = cfg.Now
+	for name, chars := range map[string]string{
+		"randAlpha":    randLetters,
+		"randAlphaNum": randLetters + randDigits,
+		"randNumeric":  randDigits,
+		"randAscii":    randASCII,
+	} {
+		funcs[name] = func(count int) string { return cfg.randText(count, chars) }
+	}
+	t, err := template.New("name-template").Funcs(funcs).Parse(nameTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -823,41 +1021,13 @@ func urlEqual(u1, u2 *url.URL) bool {
 	return u1.Scheme == u2.Scheme && u1.Hostname() == u2.Hostname() && portOrDefault(u1) == portOrDefault(u2)
 }
 
-// LocateChart looks for a chart directory in known places, and returns either the full path or an error.
-//
-// This does not ensure that the chart is well-formed; only that the requested filename exists.
-//
-// Order of resolution:
-// - relative to current working directory
-// - if path is absolute or begins with '.', error out here
-// - URL
-//
-// If 'verify' was set on ChartPathOptions, this will attempt to also verify the chart.
-func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (string, error) {
-	if registry.IsOCI(name) && c.registryClient == nil {
-		return "", fmt.Errorf("unable to lookup chart %q, missing registry client", name)
-	}
-
-	name = strings.TrimSpace(name)
+// newChartDownloader returns a downloader for the chart reference name, set
+// up with the options of c. When RepoURL is set, the reference is resolved to
+// the URL of the chart in that repository and returned instead of name.
+func (c *ChartPathOptions) newChartDownloader(name string, settings *cli.EnvSettings) (*downloader.ChartDownloader, string, error) {
 	version := strings.TrimSpace(c.Version)
 
-	if _, err := os.Stat(name); err == nil {
-		abs, err := filepath.Abs(name)
-		if err != nil {
-			return abs, err
-		}
-		if c.Verify {
-			if _, err := downloader.VerifyChart(abs, abs+".prov", c.Keyring); err != nil {
-				return "", err
-			}
-		}
-		return abs, nil
-	}
-	if filepath.IsAbs(name) || strings.HasPrefix(name, ".") {
-		return name, fmt.Errorf("path %q not found", name)
-	}
-
-	dl := downloader.ChartDownloader{
+	dl := &downloader.ChartDownloader{
 		Out:     os.Stdout,
 		Keyring: c.Keyring,
 		Getters: getter.All(settings),
@@ -893,7 +1063,7 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 			repo.WithPassCredentialsAll(c.PassCredentialsAll),
 		)
 		if err != nil {
-			return "", err
+			return nil, "", err
 		}
 		name = chartURL
 
@@ -901,11 +1071,11 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 		// location of the chart repo and the chart are the same domain.
 		u1, err := url.Parse(c.RepoURL)
 		if err != nil {
-			return "", err
+			return nil, "", err
 		}
 		u2, err := url.Parse(chartURL)
 		if err != nil {
-			return "", err
+			return nil, "", err
 		}
 
 		// Host on URL (returned from url.Parse) contains the port if present.
@@ -919,6 +1089,47 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 	} else {
 		dl.Options = append(dl.Options, getter.WithBasicAuth(c.Username, c.Password))
 	}
+	return dl, name, nil
+}
+
+// LocateChart looks for a chart directory in known places, and returns either the full path or an error.
+//
+// This does not ensure that the chart is well-formed; only that the requested filename exists.
+//
+// Order of resolution:
+// - relative to current working directory
+// - if path is absolute or begins with '.', error out here
+// - URL
+//
+// If 'verify' was set on ChartPathOptions, this will attempt to also verify the chart.
+func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (string, error) {
+	if registry.IsOCI(name) && c.registryClient == nil {
+		return "", fmt.Errorf("unable to lookup chart %q, missing registry client", name)
+	}
+
+	name = strings.TrimSpace(name)
+	version := strings.TrimSpace(c.Version)
+
+	if _, err := os.Stat(name); err == nil {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			return abs, err
+		}
+		if c.Verify {
+			if _, err := downloader.VerifyChart(abs, abs+".prov", c.Keyring); err != nil {
+				return "", err
+			}
+		}
+		return abs, nil
+	}
+	if filepath.IsAbs(name) || strings.HasPrefix(name, ".") {
+		return name, classify(fs.ErrNotExist, fmt.Errorf("path %q not found", name))
+	}
+
+	dl, name, err := c.newChartDownloader(name, settings)
+	if err != nil {
+		return "", err
+	}
 
 	if err := os.MkdirAll(settings.RepositoryCache, 0755); err != nil {
 		return "", err
@@ -935,3 +1146,18 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 	}
 	return lname, nil
 }
+
+// checkSetPaths checks that the values set by the --set family of flags are
+// at paths known to the chart. Unknown paths fail when strict, and are warned
+// about otherwise.
+func checkSetPaths(chrt ci.Charter, sources []common.ValuesSource, strict bool, warn func(WarningKind, string)) error {
+	err := util.ValidateSetPaths(chrt, sources)
+	var unknownErr *util.UnknownValuesError
+	if strict || !errors.As(err, &unknownErr) {
+		return err
+	}
+	for _, v := range unknownErr.Values {
+		warn(WarningValues, v.String())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// Chart annotations constraining how the chart is installed. They guard
+// against common mistakes, such as installing an operator twice in a cluster,
+// and are checked by the install action unless SkipInstallConstraints is set.
+const (
+	// DefaultNamespaceAnnotation is the namespace the chart is meant to be
+	// installed in. Installing it in another namespace fails.
+	DefaultNamespaceAnnotation = "helm.sh/default-namespace"
+	// ReleaseNamePatternAnnotation is a regular expression the whole name of
+	// the releases of the chart must match.
+	ReleaseNamePatternAnnotation = "helm.sh/release-name-pattern"
+	// SingletonAnnotation restricts the chart to a single deployed release
+	// per cluster, with the value "cluster", or per namespace, with the
+	// value "namespace".
+	SingletonAnnotation = "helm.sh/singleton"
+)
+
+// Scopes of SingletonAnnotation.
+const (
+	SingletonCluster   = "cluster"
+	SingletonNamespace = "namespace"
+)
+
+// checkInstallConstraints checks that the release satisfies the constraints
+// the annotations of the chart declare. The releases of the cluster are only
+// looked up for singleton charts when installing in a cluster.
+func (i *Install) checkInstallConstraints(chrt *chart.Chart) error {
+	if chrt.Metadata == nil {
+		return nil
+	}
+	annos := chrt.Metadata.Annotations
+	const skip = "use --skip-install-constraints to install it anyway"
+
+	if ns := annos[DefaultNamespaceAnnotation]; ns != "" && ns != i.Namespace {
+		return classify(ErrInvalid, fmt.Errorf("chart %s is meant to be installed in namespace %q, not %q: use --namespace %s, or %s", chrt.Name(), ns, i.Namespace, ns, skip))
+	}
+
+	if pattern := annos[ReleaseNamePatternAnnotation]; pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return classify(ErrInvalid, fmt.Errorf("invalid chart annotation %s: %w", ReleaseNamePatternAnnotation, err))
+		}
+		if !re.MatchString(i.ReleaseName) {
+			return classify(ErrInvalid, fmt.Errorf("release name %q does not match %q, required by chart %s: %s", i.ReleaseName, pattern, chrt.Name(), skip))
+		}
+	}
+
+	scope := annos[SingletonAnnotation]
+	switch scope {
+	case "":
+		return nil
+	case SingletonCluster, SingletonNamespace:
+	default:
+		return classify(ErrInvalid, fmt.Errorf("invalid chart annotation %s: %q is neither %q nor %q", SingletonAnnotation, scope, SingletonCluster, SingletonNamespace))
+	}
+	if i.ClientOnly {
+		return nil
+	}
+	var others []*release.Release
+	var err error
+	if scope == SingletonCluster {
+		others, err = i.cfg.deployedReleasesOfCluster()
+	} else {
+		others, err = i.cfg.Releases.ListDeployed()
+	}
+	if err != nil {
+		return fmt.Errorf("unable to list the deployed releases: %w", err)
+	}
+	var found []string
+	for _, rls := range others {
+		if rls.Chart == nil || rls.Chart.Metadata == nil || rls.Chart.Metadata.Name != chrt.Name() {
+			continue
+		}
+		if rls.Name == i.ReleaseName && rls.Namespace == i.Namespace {
+			continue
+		}
+		found = append(found, fmt.Sprintf("%s in namespace %s", rls.Name, rls.Namespace))
+	}
+	if len(found) > 0 {
+		return classify(ErrConflict, fmt.Errorf("chart %s can only be installed once per %s, and is already installed as release %s: %s", chrt.Name(), scope, strings.Join(found, ", "), skip))
+	}
+	return nil
+}
+
+// deployedReleasesOfCluster returns the deployed releases of all the
+// namespaces, read with the storage driver of the configuration.
+func (cfg *Configuration) deployedReleasesOfCluster() ([]*release.Release, error) {
+	deployed := func(rls *release.Release) bool {
+		return rls.Info != nil && rls.Info.Status == release.StatusDeployed
+	}
+	lc := &lazyClient{clientFn: func() (*kubernetes.Clientset, error) {
+		conf, err := cfg.RESTClientGetter.ToRESTConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate config for kubernetes client: %w", err)
+		}
+		return kubernetes.NewForConfig(conf)
+	}}
+	switch d := cfg.Releases.Driver.(type) {
+	case *driver.Memory:
+		return d.ListAllNamespaces(deployed)
+	case *driver.Secrets:
+		return driver.NewSecrets(newSecretClient(lc)).List(deployed)
+	case *driver.ConfigMaps:
+		return driver.NewConfigMaps(newConfigMapClient(lc)).List(deployed)
+	default:
+		return nil, fmt.Errorf("the %s storage driver cannot list the releases of all the namespaces", d.Name())
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func TestInstallConstraintsNamespace(t *testing.T) {
+	instAction := installAction(t)
+	chrt := buildChart(withAnnotations(map[string]string{DefaultNamespaceAnnotation: "operators"}))
+
+	_, err := instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.ErrorContains(t, err, `chart hello is meant to be installed in namespace "operators", not "spaced"`)
+
+	instAction.Namespace = "operators"
+	_, err = instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.NoError(t, err)
+}
+
+func TestInstallConstraintsReleaseName(t *testing.T) {
+	instAction := installAction(t)
+	chrt := buildChart(withAnnotations(map[string]string{ReleaseNamePatternAnnotation: "hello|hello-[a-z]+"}))
+
+	_, err := instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.ErrorContains(t, err, `release name "test-install-release" does not match "hello|hello-[a-z]+"`)
+
+	instAction.SkipInstallConstraints = true
+	_, err = instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.NoError(t, err)
+
+	instAction = installAction(t)
+	instAction.ReleaseName = "hello-world"
+	_, err = instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.NoError(t, err)
+
+	instAction = installAction(t)
+	chrt = buildChart(withAnnotations(map[string]string{ReleaseNamePatternAnnotation: "("}))
+	_, err = instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+	assert.ErrorContains(t, err, "invalid chart annotation "+ReleaseNamePatternAnnotation)
+}
+
+func TestInstallConstraintsSingleton(t *testing.T) {
+	for _, tt := range []struct {
+		scope     string
+		namespace string
+		wantErr   string
+	}{
+		{scope: SingletonNamespace, namespace: "spaced", wantErr: "chart hello can only be installed once per namespace, and is already installed as release other in namespace spaced"},
+		{scope: SingletonNamespace, namespace: "elsewhere"},
+		{scope: SingletonCluster, namespace: "elsewhere", wantErr: "chart hello can only be installed once per cluster, and is already installed as release other in namespace elsewhere"},
+		{scope: "galaxy", wantErr: `invalid chart annotation helm.sh/singleton: "galaxy" is neither "cluster" nor "namespace"`},
+	} {
+		t.Run(tt.scope+"/"+tt.namespace, func(t *testing.T) {
+			instAction := installAction(t)
+			mem := instAction.cfg.Releases.Driver.(*driver.Memory)
+			mem.SetNamespace("spaced")
+
+			other := namedReleaseStub("other", release.StatusDeployed)
+			other.Namespace = tt.namespace
+			require.NoError(t, instAction.cfg.Releases.Create(other))
+			// Releases of other charts and superseded ones do not count.
+			unrelated := namedReleaseStub("unrelated", release.StatusDeployed)
+			unrelated.Namespace = "spaced"
+			unrelated.Chart = buildChart(withName("unrelated"))
+			require.NoError(t, instAction.cfg.Releases.Create(unrelated))
+			superseded := namedReleaseStub("superseded", release.StatusSuperseded)
+			superseded.Namespace = "spaced"
+			require.NoError(t, instAction.cfg.Releases.Create(superseded))
+
+			chrt := buildChart(withAnnotations(map[string]string{SingletonAnnotation: tt.scope}))
+			_, err := instAction.RunWithContext(context.Background(), chrt, map[string]interface{}{})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -41,10 +41,13 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/rest/fake"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	"helm.sh/helm/v4/internal/test"
 	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
 	"helm.sh/helm/v4/pkg/kube"
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -259,6 +262,25 @@ func TestInstallReleaseClientOnly(t *testing.T) {
 	is.Equal(instAction.cfg.KubeClient, &kubefake.PrintingKubeClient{Out: io.Discard})
 }
 
+func TestInstallRelease_Actor(t *testing.T) {
+	instAction := installAction(t)
+	instAction.cfg.Actor = "alice"
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", res.Info.DeployedBy)
+	assert.Nil(t, res.Info.DeployedAs)
+}
+
+func TestInstallRelease_Impersonation(t *testing.T) {
+	instAction := installAction(t)
+	instAction.cfg.Actor = "platform"
+	instAction.cfg.Impersonation = &rest.ImpersonationConfig{UserName: "alice", Groups: []string{"dev"}}
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "platform", res.Info.DeployedBy)
+	assert.Equal(t, &release.Impersonation{User: "alice", Groups: []string{"dev"}}, res.Info.DeployedAs)
+}
+
 func TestInstallRelease_NoName(t *testing.T) {
 	instAction := installAction(t)
 	instAction.ReleaseName = ""
@@ -378,6 +400,57 @@ func TestInstallRelease_DryRun(t *testing.T) {
 	is.Equal(res.Info.Description, "Dry run complete")
 }
 
+func TestInstallRelease_UnknownSetPaths(t *testing.T) {
+	sources := []common.ValuesSource{
+		{Name: "--set somekey=fromSet", Values: map[string]interface{}{"somekey": "fromSet"}},
+	}
+	vals := map[string]interface{}{"somekey": "fromSet"}
+
+	instAction := installAction(t)
+	instAction.ValuesSources = sources
+	_, err := instAction.Run(buildChart(withSampleValues()), vals)
+	require.NoError(t, err, "values at unknown paths should only be warned about")
+	assert.Contains(t, instAction.Result().Warnings, Warning{
+		Kind:    WarningValues,
+		Message: "--set somekey=fromSet sets somekey, which is neither in the default values nor in the values schema of the chart (did you mean someKey?)",
+	})
+
+	instAction = installAction(t)
+	instAction.ValuesSources = sources
+	instAction.StrictSetValidation = true
+	_, err = instAction.Run(buildChart(withSampleValues()), vals)
+	var unknownErr *util.UnknownValuesError
+	assert.ErrorAs(t, err, &unknownErr)
+}
+
+func TestInstallRelease_DryRunValuesProvenance(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.DryRun = true
+	instAction.ValuesSources = []common.ValuesSource{
+		{Name: "--set someKey=fromSet", Values: map[string]interface{}{"someKey": "fromSet"}},
+	}
+	vals := map[string]interface{}{"someKey": "fromSet"}
+	res, err := instAction.Run(buildChart(withSampleValues()), vals)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+
+	is.Equal([]common.ValueOrigin{
+		{Path: "nestedKey.anotherNestedKey.yetAnotherNestedKey.youReadyForAnotherNestedKey", Value: "No", Source: "chart default (hello)"},
+		{Path: "nestedKey.simpleKey", Value: "simpleValue", Source: "chart default (hello)"},
+		{Path: "someKey", Value: "fromSet", Source: "--set someKey=fromSet"},
+	}, res.ValuesProvenance)
+
+	// The provenance of values is only reported by dry runs.
+	instAction = installAction(t)
+	res, err = instAction.Run(buildChart(withSampleValues()), vals)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.Nil(res.ValuesProvenance)
+}
+
 func TestInstallRelease_DryRunHiddenSecret(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -846,6 +919,8 @@ func TestNameAndChartGenerateName(t *testing.T) {
 
 	instAction.ReleaseName = ""
 	instAction.GenerateName = true
+	now := time.Unix(1700000000, 0)
+	instAction.cfg.Clock = clocktesting.NewFakeClock(now)
 
 	tests := []struct {
 		Name         string
@@ -855,32 +930,32 @@ func TestNameAndChartGenerateName(t *testing.T) {
 		{
 			"local filepath",
 			"./chart",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 		{
 			"dot filepath",
 			".",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 		{
 			"empty filepath",
 			"",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 		{
 			"packaged chart",
 			"chart.tgz",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 		{
 			"packaged chart with .tar.gz extension",
 			"chart.tar.gz",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 		{
 			"packaged chart with local extension",
 			"./chart.tgz",
-			fmt.Sprintf("chart-%d", time.Now().Unix()),
+			"chart-1700000000",
 		},
 	}
 
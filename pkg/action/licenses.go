@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+// LicensePolicy restricts the licenses of the charts, and of all their
+// dependencies, that can be installed. Licenses are SPDX license identifiers,
+// compared case-insensitively.
+type LicensePolicy struct {
+	// Allowed are the only licenses allowed. When it is empty, all the
+	// licenses that are not denied are allowed.
+	Allowed []string `json:"allowed,omitempty"`
+	// Denied are licenses that are not allowed.
+	Denied []string `json:"denied,omitempty"`
+	// RequireLicense blocks charts without license information.
+	RequireLicense bool `json:"requireLicense,omitempty"`
+}
+
+// LoadLicensePolicy loads a license policy from a YAML file.
+func LoadLicensePolicy(filename string) (*LicensePolicy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &LicensePolicy{}
+	if err := yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("unable to parse license policy %s: %w", filename, err)
+	}
+	return p, nil
+}
+
+func (p *LicensePolicy) allows(id string) bool {
+	equal := func(l string) bool { return strings.EqualFold(l, id) }
+	if slices.ContainsFunc(p.Denied, equal) {
+		return false
+	}
+	return len(p.Allowed) == 0 || slices.ContainsFunc(p.Allowed, equal)
+}
+
+// Check returns an error listing the charts in the chart tree whose licenses
+// are not allowed by the policy. A chart is allowed when its license
+// expression can be satisfied with allowed licenses only.
+func (p *LicensePolicy) Check(c *chart.Chart) error {
+	var denied []string
+	for _, l := range chartutil.Licenses(c) {
+		if l.Expression == "" {
+			if p.RequireLicense {
+				denied = append(denied, fmt.Sprintf("%s %s: no license information", l.Chart, l.Version))
+			}
+			continue
+		}
+		ok, err := chartutil.EvalLicenseExpression(l.Expression, p.allows)
+		if err != nil {
+			return fmt.Errorf("chart %s: %w", l.Chart, err)
+		}
+		if !ok {
+			denied = append(denied, fmt.Sprintf("%s %s: %s", l.Chart, l.Version, l.Expression))
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("charts with licenses not allowed by the license policy:\n  %s", strings.Join(denied, "\n  "))
+	}
+	return nil
+}
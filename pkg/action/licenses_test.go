@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestLoadLicensePolicy(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.yaml")
+	require.NoError(t, os.WriteFile(policyFile, []byte("denied: [GPL-3.0]\nrequireLicense: true\n"), 0644))
+
+	p, err := LoadLicensePolicy(policyFile)
+	require.NoError(t, err)
+	assert.Equal(t, &LicensePolicy{Denied: []string{"GPL-3.0"}, RequireLicense: true}, p)
+
+	require.NoError(t, os.WriteFile(policyFile, []byte("deny: [GPL-3.0]\n"), 0644))
+	_, err = LoadLicensePolicy(policyFile)
+	assert.ErrorContains(t, err, "unable to parse license policy")
+}
+
+func TestLicensePolicyCheck(t *testing.T) {
+	withLicense := func(name, expr string) *chart.Chart {
+		c := &chart.Chart{Metadata: &chart.Metadata{Name: name, Version: "0.1.0"}}
+		if expr != "" {
+			c.Metadata.Annotations = map[string]string{chartutil.LicenseAnnotation: expr}
+		}
+		return c
+	}
+	parent := withLicense("parent", "Apache-2.0")
+	parent.AddDependency(withLicense("dual", "MIT OR GPL-3.0"), withLicense("copyleft", "AGPL-3.0"), withLicense("unknown", ""))
+
+	tests := []struct {
+		name    string
+		policy  LicensePolicy
+		wantErr string
+	}{
+		{
+			name:   "everything allowed",
+			policy: LicensePolicy{},
+		},
+		{
+			name:    "denied licenses",
+			policy:  LicensePolicy{Denied: []string{"gpl-3.0", "AGPL-3.0"}},
+			wantErr: "charts with licenses not allowed by the license policy:\n  parent/charts/copyleft 0.1.0: AGPL-3.0",
+		},
+		{
+			name:    "allowed licenses",
+			policy:  LicensePolicy{Allowed: []string{"Apache-2.0", "MIT"}, RequireLicense: true},
+			wantErr: "charts with licenses not allowed by the license policy:\n  parent/charts/copyleft 0.1.0: AGPL-3.0\n  parent/charts/unknown 0.1.0: no license information",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(parent)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestInstallRelease_LicensePolicy(t *testing.T) {
+	instAction := installAction(t)
+	instAction.LicensePolicy = &LicensePolicy{RequireLicense: true}
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	assert.ErrorContains(t, err, "hello 0.1.0: no license information")
+}
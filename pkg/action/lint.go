@@ -23,9 +23,12 @@ import (
 	"strings"
 
 	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
 	"helm.sh/helm/v4/pkg/chart/v2/lint"
 	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
 )
 
 // Lint is the action for checking that the semantics of a chart are well-formed.
@@ -38,6 +41,20 @@ type Lint struct {
 	Quiet                bool
 	SkipSchemaValidation bool
 	KubeVersion          *common.KubeVersion
+	// DisabledChecks are the names of the template checks not to run, among
+	// rules.TemplateCheckNames.
+	DisabledChecks []string
+	// FailOnRemovedAPIs reports as errors the APIs that KubeVersion no longer
+	// serves. Deprecated APIs are otherwise warnings, to allow charts to
+	// support out-of-date Kubernetes versions.
+	FailOnRemovedAPIs bool
+	// Coverage renders the templates of each chart with the values of the
+	// lint and with each values set of the chart, and reports which template
+	// files, named templates and branches they executed.
+	Coverage bool
+	// AdvisoryPolicy, when set, reports as errors the security advisories
+	// affecting the charts or their dependencies.
+	AdvisoryPolicy *AdvisoryPolicy
 }
 
 // LintResult is the result of Lint
@@ -45,6 +62,32 @@ type LintResult struct {
 	TotalChartsLinted int
 	Messages          []support.Message
 	Errors            []error
+	// Coverage is the template coverage of each chart, when requested.
+	Coverage []*LintCoverage
+}
+
+// coverageValuesSets is the pattern of the values sets of a chart, as used by
+// chart testing tools.
+const coverageValuesSets = "ci/*-values.yaml"
+
+// LintCoverage is the template coverage of a linted chart.
+type LintCoverage struct {
+	// Path is the path of the chart, as given to Lint.
+	Path string
+	// ValuesSets are the values the templates were rendered with: those of
+	// the lint, followed by the values sets of the chart.
+	ValuesSets []LintValuesSet
+	// Blocks are the template blocks of the chart and its subcharts.
+	Blocks []engine.CoverageBlock
+}
+
+// LintValuesSet is a set of values templates were rendered with for coverage.
+type LintValuesSet struct {
+	// Name is the file of the values set, relative to the chart, or "values"
+	// for the values of the lint.
+	Name string
+	// Err is the error rendering the templates with the values set, if any.
+	Err error
 }
 
 // NewLint creates a new Lint object with the given configuration.
@@ -60,11 +103,22 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	}
 	result := &LintResult{}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.SkipSchemaValidation)
+		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.SkipSchemaValidation, l.DisabledChecks, l.FailOnRemovedAPIs)
 		if err != nil {
 			result.Errors = append(result.Errors, err)
 			continue
 		}
+		if l.Coverage {
+			coverage, err := lintCoverage(path, vals, l.Namespace, l.KubeVersion)
+			if err != nil {
+				result.Errors = append(result.Errors, err)
+				continue
+			}
+			result.Coverage = append(result.Coverage, coverage)
+		}
+		if l.AdvisoryPolicy != nil {
+			linter.Messages = append(linter.Messages, lintAdvisories(path, l.AdvisoryPolicy)...)
+		}
 
 		result.Messages = append(result.Messages, linter.Messages...)
 		result.TotalChartsLinted++
@@ -87,33 +141,67 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *common.KubeVersion, skipSchemaValidation bool) (support.Linter, error) {
-	var chartPath string
+// lintAdvisories reports the advisories of the policy affecting the chart at
+// path. Charts that cannot be loaded are reported by the other lint rules.
+func lintAdvisories(path string, policy *AdvisoryPolicy) []support.Message {
+	ch, err := loader.Load(path)
+	if err != nil {
+		return nil
+	}
+	var messages []support.Message
+	for _, m := range policy.Matches(ch, "") {
+		messages = append(messages, support.NewMessage(support.ErrorSev, "Chart.yaml", fmt.Errorf("security advisory: %s", m)))
+	}
+	return messages
+}
+
+func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *common.KubeVersion, skipSchemaValidation bool, disabledChecks []string, failOnRemovedAPIs bool) (support.Linter, error) {
 	linter := support.Linter{}
 
+	chartPath, cleanup, err := lintChartDir(path)
+	if err != nil {
+		return linter, err
+	}
+	defer cleanup()
+
+	return lint.RunAll(
+		chartPath,
+		vals,
+		namespace,
+		lint.WithKubeVersion(kubeVersion),
+		lint.WithSkipSchemaValidation(skipSchemaValidation),
+		lint.WithDisabledChecks(disabledChecks),
+		lint.WithFailOnRemovedAPIs(failOnRemovedAPIs),
+	), nil
+}
+
+// lintChartDir returns the directory of the chart at path, expanding it to a
+// temporary directory removed by cleanup when it is an archive.
+func lintChartDir(path string) (chartPath string, cleanup func(), err error) {
+	cleanup = func() {}
 	if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
 		tempDir, err := os.MkdirTemp("", "helm-lint")
 		if err != nil {
-			return linter, fmt.Errorf("unable to create temp dir to extract tarball: %w", err)
+			return "", cleanup, fmt.Errorf("unable to create temp dir to extract tarball: %w", err)
 		}
-		defer os.RemoveAll(tempDir)
+		cleanup = func() { os.RemoveAll(tempDir) }
 
 		file, err := os.Open(path)
 		if err != nil {
-			return linter, fmt.Errorf("unable to open tarball: %w", err)
+			return "", cleanup, fmt.Errorf("unable to open tarball: %w", err)
 		}
 		defer file.Close()
 
 		if err = chartutil.Expand(tempDir, file); err != nil {
-			return linter, fmt.Errorf("unable to extract tarball: %w", err)
+			return "", cleanup, fmt.Errorf("unable to extract tarball: %w", err)
 		}
 
 		files, err := os.ReadDir(tempDir)
 		if err != nil {
-			return linter, fmt.Errorf("unable to read temporary output directory %s: %w", tempDir, err)
+			return "", cleanup, fmt.Errorf("unable to read temporary output directory %s: %w", tempDir, err)
 		}
 		if !files[0].IsDir() {
-			return linter, fmt.Errorf("unexpected file %s in temporary output directory %s", files[0].Name(), tempDir)
+			return "", cleanup, fmt.Errorf("unexpected file %s in temporary output directory %s", files[0].Name(), tempDir)
 		}
 
 		chartPath = filepath.Join(tempDir, files[0].Name())
@@ -123,14 +211,75 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 
 	// Guard: Error out if this is not a chart.
 	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
-		return linter, fmt.Errorf("unable to check Chart.yaml file in chart: %w", err)
+		return "", cleanup, fmt.Errorf("unable to check Chart.yaml file in chart: %w", err)
 	}
+	return chartPath, cleanup, nil
+}
 
-	return lint.RunAll(
-		chartPath,
-		vals,
-		namespace,
-		lint.WithKubeVersion(kubeVersion),
-		lint.WithSkipSchemaValidation(skipSchemaValidation),
-	), nil
+// lintCoverage renders the templates of the chart at path with vals and with
+// each values set of the chart, and returns the coverage of the renders.
+func lintCoverage(path string, vals map[string]interface{}, namespace string, kubeVersion *common.KubeVersion) (*LintCoverage, error) {
+	chartPath, cleanup, err := lintChartDir(path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	files, err := filepath.Glob(filepath.Join(chartPath, filepath.FromSlash(coverageValuesSets)))
+	if err != nil {
+		return nil, err
+	}
+	sets := []LintValuesSet{{Name: "values"}}
+	valueSets := []map[string]interface{}{vals}
+	for _, f := range files {
+		name, err := filepath.Rel(chartPath, f)
+		if err != nil {
+			return nil, err
+		}
+		set := LintValuesSet{Name: filepath.ToSlash(name)}
+		setVals, err := common.ReadValuesFile(f)
+		if err != nil {
+			set.Err = err
+		}
+		sets = append(sets, set)
+		valueSets = append(valueSets, setVals)
+	}
+
+	options := common.ReleaseOptions{
+		Name:      "test-release",
+		Namespace: namespace,
+	}
+	caps := common.DefaultCapabilities.Copy()
+	if kubeVersion != nil {
+		caps.KubeVersion = *kubeVersion
+	}
+	coverage := engine.NewCoverage()
+	e := engine.Engine{LintMode: true, Coverage: coverage}
+	for i := range sets {
+		if sets[i].Err != nil {
+			continue
+		}
+		sets[i].Err = func() error {
+			// The dependencies of the chart are processed with the values, so it
+			// is loaded anew for each values set.
+			chrt, err := loader.Load(chartPath)
+			if err != nil {
+				return err
+			}
+			if err := chartutil.ProcessDependencies(chrt, valueSets[i]); err != nil {
+				return err
+			}
+			cvals, err := util.CoalesceValues(chrt, valueSets[i])
+			if err != nil {
+				return err
+			}
+			valuesToRender, err := util.ToRenderValuesWithSchemaValidation(chrt, cvals, options, caps, true)
+			if err != nil {
+				return err
+			}
+			_, err = e.Render(chrt, valuesToRender)
+			return err
+		}()
+	}
+	return &LintCoverage{Path: path, ValuesSets: sets, Blocks: coverage.Blocks()}, nil
 }
@@ -17,6 +17,9 @@ limitations under the License.
 package action
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -83,7 +86,7 @@ func TestLintChart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace, nil, tt.skipSchemaValidation)
+			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace, nil, tt.skipSchemaValidation, nil, false)
 			switch {
 			case err != nil && !tt.err:
 				t.Errorf("%s", err)
@@ -163,3 +166,53 @@ func TestLint_ChartWithWarnings(t *testing.T) {
 		}
 	})
 }
+
+func TestLint_Coverage(t *testing.T) {
+	chartDir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":             "apiVersion: v2\nname: coverage\nversion: 0.1.0\n",
+		"templates/cm.yaml":      "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n{{- if .Values.data }}\ndata: {}\n{{- end }}\n",
+		"ci/data-values.yaml":    "data: true\n",
+		"ci/invalid-values.yaml": "data: [\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(chartDir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(chartDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testLint := NewLint()
+	testLint.Coverage = true
+	result := testLint.Run([]string{chartDir}, values)
+	if len(result.Errors) > 0 {
+		t.Fatal(result.Errors)
+	}
+	if len(result.Coverage) != 1 {
+		t.Fatalf("expected the coverage of one chart, got %d", len(result.Coverage))
+	}
+	coverage := result.Coverage[0]
+
+	var sets []string
+	for _, set := range coverage.ValuesSets {
+		sets = append(sets, set.Name)
+		if (set.Err != nil) != (set.Name == "ci/invalid-values.yaml") {
+			t.Errorf("unexpected error for values set %s: %v", set.Name, set.Err)
+		}
+	}
+	if expect := []string{"values", "ci/data-values.yaml", "ci/invalid-values.yaml"}; !slices.Equal(sets, expect) {
+		t.Errorf("expected values sets %v, got %v", expect, sets)
+	}
+
+	// The lint values take the else branch, the data values set the if body.
+	for _, b := range coverage.Blocks {
+		if b.Hits == 0 {
+			t.Errorf("expected %s to be rendered", b)
+		}
+	}
+	if len(coverage.Blocks) != 3 {
+		t.Errorf("expected 3 blocks, got %v", coverage.Blocks)
+	}
+}
@@ -17,8 +17,10 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"path"
 	"regexp"
+	"time"
 
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -83,6 +85,16 @@ func (s ListStates) FromName(str string) ListStates {
 // ListAll is a convenience for enabling all list filters
 const ListAll = ListDeployed | ListUninstalled | ListUninstalling | ListPendingInstall | ListPendingRollback | ListPendingUpgrade | ListSuperseded | ListFailed
 
+// ListPending is a convenience for enabling the filters on the states of
+// releases with an operation in progress.
+const ListPending = ListPendingInstall | ListPendingUpgrade | ListPendingRollback | ListUninstalling
+
+// DefaultStuckThreshold is how long a release may stay in a pending state
+// before it is considered stuck, when no threshold is given. It is well above
+// the default timeout of the operations, so that operations that are still
+// running are not reported.
+const DefaultStuckThreshold = 15 * time.Minute
+
 // Sorter is a top-level sort
 type Sorter uint
 
@@ -134,6 +146,20 @@ type List struct {
 	Failed       bool
 	Pending      bool
 	Selector     string
+	// PendingOlderThan, when set, restricts the results to releases that have
+	// been in a pending state for at least this long.
+	PendingOlderThan time.Duration
+}
+
+// StuckRelease is a release that looks trapped in a pending state, typically
+// because the operation that put it there was interrupted.
+type StuckRelease struct {
+	Release *release.Release
+	// PendingFor is how long the release has been in its pending state.
+	PendingFor time.Duration
+	// Suggestion is the command that most likely gets the release out of its
+	// pending state.
+	Suggestion string
 }
 
 // NewList constructs a new *List
@@ -194,6 +220,10 @@ func (l *List) Run() ([]*release.Release, error) {
 	}
 	results = l.filterSelector(results, selectorObj)
 
+	if l.PendingOlderThan > 0 {
+		results = filterPendingOlderThan(results, l.PendingOlderThan, l.cfg.Now())
+	}
+
 	// Unfortunately, we have to sort before truncating, which can incur substantial overhead
 	l.sort(results)
 
@@ -276,6 +306,64 @@ func (l *List) filterStateMask(releases []*release.Release) []*release.Release {
 	return desiredStateReleases
 }
 
+// filterPendingOlderThan returns the releases that have been in a pending
+// state for at least the given duration.
+func filterPendingOlderThan(releases []*release.Release, d time.Duration, now time.Time) []*release.Release {
+	pending := make([]*release.Release, 0)
+	for _, rls := range releases {
+		if ListPending.FromName(rls.Info.Status.String())&ListPending == 0 {
+			continue
+		}
+		if now.Sub(rls.Info.LastDeployed) >= d {
+			pending = append(pending, rls)
+		}
+	}
+	return pending
+}
+
+// Stuck returns the releases that have been in a pending state for longer than
+// threshold, or DefaultStuckThreshold when threshold is zero, together with
+// how to recover them. The other filters of the list, such as the namespaces
+// and the selector, apply too.
+func (l *List) Stuck(threshold time.Duration) ([]StuckRelease, error) {
+	if threshold <= 0 {
+		threshold = DefaultStuckThreshold
+	}
+	lc := *l
+	lc.StateMask = ListPending
+	lc.PendingOlderThan = threshold
+	lc.Limit = 0
+	lc.Offset = 0
+	results, err := lc.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	now := l.cfg.Now()
+	stuck := make([]StuckRelease, 0, len(results))
+	for _, rls := range results {
+		stuck = append(stuck, StuckRelease{
+			Release:    rls,
+			PendingFor: now.Sub(rls.Info.LastDeployed),
+			Suggestion: stuckSuggestion(rls),
+		})
+	}
+	return stuck, nil
+}
+
+// stuckSuggestion returns the command that most likely recovers a release
+// stuck in its pending state.
+func stuckSuggestion(rls *release.Release) string {
+	switch rls.Info.Status {
+	case release.StatusPendingInstall:
+		return fmt.Sprintf("helm uninstall %s --namespace %s", rls.Name, rls.Namespace)
+	case release.StatusUninstalling:
+		return fmt.Sprintf("helm uninstall %s --namespace %s --no-hooks", rls.Name, rls.Namespace)
+	default:
+		return fmt.Sprintf("helm rollback %s --namespace %s", rls.Name, rls.Namespace)
+	}
+}
+
 func (l *List) filterSelector(releases []*release.Release, selector labels.Selector) []*release.Release {
 	desiredStateReleases := make([]*release.Release, 0)
 
@@ -308,6 +396,9 @@ func (l *List) SetStateMask() {
 	if l.Pending {
 		state |= ListPendingInstall | ListPendingRollback | ListPendingUpgrade
 	}
+	if l.PendingOlderThan > 0 {
+		state |= ListPending
+	}
 	if l.Failed {
 		state |= ListFailed
 	}
@@ -18,14 +18,18 @@ package action
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
 	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage"
+	"helm.sh/helm/v4/pkg/storage/driver"
 )
 
 func TestListStates(t *testing.T) {
@@ -209,6 +213,79 @@ func TestList_StateMaskWithStaleRevisions(t *testing.T) {
 	is.Equal("failed", res[0].Name)
 }
 
+func makeMeSomePendingReleases(t *testing.T, store *storage.Storage, now time.Time) {
+	t.Helper()
+	for _, r := range []struct {
+		name   string
+		status release.Status
+		age    time.Duration
+	}{
+		{"installing", release.StatusPendingInstall, 2 * time.Hour},
+		{"upgrading", release.StatusPendingUpgrade, 20 * time.Minute},
+		{"rolling-back", release.StatusPendingRollback, 5 * time.Minute},
+		{"uninstalling", release.StatusUninstalling, time.Hour},
+		{"deployed", release.StatusDeployed, 3 * time.Hour},
+	} {
+		rel := namedReleaseStub(r.name, r.status)
+		rel.Namespace = "default"
+		rel.Version = 2
+		rel.Info.LastDeployed = now.Add(-r.age)
+		if err := store.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestList_PendingOlderThan(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	defer func(ts func() time.Time) { Timestamper = ts }(Timestamper)
+	Timestamper = func() time.Time { return now }
+
+	lister := newListFixture(t)
+	makeMeSomePendingReleases(t, lister.cfg.Releases, now)
+
+	lister.PendingOlderThan = 30 * time.Minute
+	lister.SetStateMask()
+	res, err := lister.Run()
+	require.NoError(t, err)
+	var names []string
+	for _, rel := range res {
+		names = append(names, rel.Name)
+	}
+	assert.Equal(t, []string{"installing", "uninstalling"}, names)
+}
+
+func TestList_Stuck(t *testing.T) {
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	defer func(ts func() time.Time) { Timestamper = ts }(Timestamper)
+	Timestamper = func() time.Time { return now }
+
+	lister := newListFixture(t)
+	makeMeSomePendingReleases(t, lister.cfg.Releases, now)
+
+	stuck, err := lister.Stuck(0)
+	require.NoError(t, err)
+	require.Len(t, stuck, 3)
+	assert.Equal(t, "installing", stuck[0].Release.Name)
+	assert.Equal(t, 2*time.Hour, stuck[0].PendingFor)
+	assert.Equal(t, "helm uninstall installing --namespace default", stuck[0].Suggestion)
+	assert.Equal(t, "uninstalling", stuck[1].Release.Name)
+	assert.Equal(t, "helm uninstall uninstalling --namespace default --no-hooks", stuck[1].Suggestion)
+	assert.Equal(t, "upgrading", stuck[2].Release.Name)
+	assert.Equal(t, "helm rollback upgrading --namespace default", stuck[2].Suggestion)
+
+	stuck, err = lister.Stuck(time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, stuck, 4)
+
+	// The filters of the list apply to the report.
+	lister.Filter = "^up"
+	stuck, err = lister.Stuck(time.Minute)
+	require.NoError(t, err)
+	require.Len(t, stuck, 1)
+	assert.Equal(t, "upgrading", stuck[0].Release.Name)
+}
+
 func makeMeSomeReleasesWithStaleFailure(t *testing.T, store *storage.Storage) {
 	t.Helper()
 	one := namedReleaseStub("clean", release.StatusDeployed)
@@ -383,3 +460,37 @@ func TestListRun_UnreachableKubeClient(t *testing.T) {
 	assert.Nil(t, result)
 	assert.ErrorContains(t, err, "connection refused")
 }
+
+func BenchmarkListReleases(b *testing.B) {
+	cfg := &Configuration{
+		Releases:   storage.Init(driver.NewMemory()),
+		KubeClient: &kubefake.PrintingKubeClient{Out: io.Discard},
+	}
+	// Many releases with a history of superseded revisions each.
+	for n := range 200 {
+		for v := 1; v <= 5; v++ {
+			status := release.StatusSuperseded
+			if v == 5 {
+				status = release.StatusDeployed
+			}
+			rel := namedReleaseStub(fmt.Sprintf("release-%d", n), status)
+			rel.Version = v
+			if err := cfg.Releases.Create(rel); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	lister := NewList(cfg)
+	lister.SetStateMask()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		rels, err := lister.Run()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(rels) != 200 {
+			b.Fatalf("expected 200 releases, got %d", len(rels))
+		}
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+// ManifestFilter selects rendered manifests by the templates they are
+// rendered from, and by the kinds and names of their objects, as
+// 'helm template --show-only' does.
+type ManifestFilter struct {
+	// Files are the template paths or glob patterns the manifests are
+	// rendered from, relative to the chart. Patterns starting with "*/" or
+	// "**/" also match the templates of subcharts, relative to the subcharts,
+	// and "**" matches any number of directories.
+	Files []string
+	// Kinds are the kinds of the objects, optionally prefixed with their API
+	// version, such as "Deployment" or "apps/v1/Deployment".
+	Kinds []string
+	// Names are glob patterns of the names of the objects.
+	Names []string
+}
+
+var manifestSourceRegex = regexp.MustCompile("# Source: [^/]+/(.+)")
+
+// Empty reports whether the filter selects all manifests.
+func (f *ManifestFilter) Empty() bool {
+	return f == nil || len(f.Files) == 0 && len(f.Kinds) == 0 && len(f.Names) == 0
+}
+
+// Apply returns the manifests selected by the filter, in install order. Each
+// template of Files must match a manifest.
+func (f *ManifestFilter) Apply(manifests string) ([]string, error) {
+	// This is necessary to ensure consistent manifest ordering when using --show-only
+	// with globs or directory names.
+	splitManifests := releaseutil.SplitManifests(manifests)
+	manifestsKeys := make([]string, 0, len(splitManifests))
+	for k := range splitManifests {
+		manifestsKeys = append(manifestsKeys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(manifestsKeys))
+
+	selected := manifestsKeys
+	if len(f.Files) > 0 {
+		selected = nil
+		seen := make(map[string]bool)
+		for _, file := range f.Files {
+			// Use linux-style filepath separators to unify user's input path.
+			// The manifest paths use them on Windows as well as macOS/linux.
+			file = filepath.ToSlash(file)
+			missing := true
+			for _, key := range manifestsKeys {
+				submatch := manifestSourceRegex.FindStringSubmatch(splitManifests[key])
+				if len(submatch) == 0 || !matchTemplatePath(file, submatch[1]) {
+					continue
+				}
+				missing = false
+				if !seen[key] {
+					seen[key] = true
+					selected = append(selected, key)
+				}
+			}
+			if missing {
+				return nil, fmt.Errorf("could not find template %s in chart", file)
+			}
+		}
+	}
+
+	var result []string
+	for _, key := range selected {
+		manifest := splitManifests[key]
+		if len(f.Kinds) > 0 || len(f.Names) > 0 {
+			var obj struct {
+				APIVersion string `json:"apiVersion"`
+				Kind       string `json:"kind"`
+				Metadata   struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+			}
+			if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+				continue
+			}
+			if !f.matchKind(obj.APIVersion, obj.Kind) || !f.matchName(obj.Metadata.Name) {
+				continue
+			}
+		}
+		result = append(result, manifest)
+	}
+	if len(result) == 0 && (len(f.Kinds) > 0 || len(f.Names) > 0) {
+		return nil, errors.New("could not find objects matching --show-kind and --show-name in chart")
+	}
+	return result, nil
+}
+
+func (f *ManifestFilter) matchKind(apiVersion, kind string) bool {
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	for _, k := range f.Kinds {
+		if i := strings.LastIndex(k, "/"); i >= 0 {
+			if k[:i] == apiVersion && strings.EqualFold(k[i+1:], kind) {
+				return true
+			}
+		} else if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ManifestFilter) matchName(name string) bool {
+	if len(f.Names) == 0 {
+		return true
+	}
+	for _, pattern := range f.Names {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTemplatePath reports whether a template path, relative to the chart
+// being rendered, matches a pattern of ManifestFilter.Files. Patterns starting
+// with "*/" or "**/" are also matched against the path relative to each
+// subchart, so that "*/deployment.yaml" matches the deployments of the chart
+// and its subcharts, while "templates/*.yaml" only matches the templates of
+// the chart. "**" matches any number of directories.
+func matchTemplatePath(pattern, name string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	if matchPathSegments(patternSegments, strings.Split(name, "/")) {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*/") && !strings.HasPrefix(pattern, "**/") {
+		return false
+	}
+	rest := name
+	for {
+		subchart, ok := strings.CutPrefix(rest, "charts/")
+		if !ok {
+			return false
+		}
+		if _, rest, ok = strings.Cut(subchart, "/"); !ok {
+			return false
+		}
+		if matchPathSegments(patternSegments, strings.Split(rest, "/")) {
+			return true
+		}
+	}
+}
+
+func matchPathSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchPathSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTemplatePath(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"templates/service.yaml", "templates/service.yaml", true},
+		{"templates/service.yaml", "charts/sub/templates/service.yaml", false},
+		{"templates/*.yaml", "templates/service.yaml", true},
+		{"templates/*.yaml", "charts/sub/templates/service.yaml", false},
+		{"*/service.yaml", "templates/service.yaml", true},
+		{"*/service.yaml", "charts/sub/templates/service.yaml", true},
+		{"*/service.yaml", "charts/sub/charts/nested/templates/service.yaml", true},
+		{"**/service.yaml", "charts/sub/templates/service.yaml", true},
+		{"templates/**/role*.yaml", "templates/subdir/role.yaml", true},
+		{"templates/**/role*.yaml", "templates/role.yaml", true},
+		{"templates/**/role*.yaml", "charts/sub/templates/role.yaml", false},
+	} {
+		assert.Equal(t, tt.want, matchTemplatePath(tt.pattern, tt.name), "%s matching %s", tt.pattern, tt.name)
+	}
+}
+
+func TestManifestFilter(t *testing.T) {
+	manifests := `---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: chart/charts/sub/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: sub-web
+---
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`
+	f := &ManifestFilter{Files: []string{"templates/*.yaml"}, Kinds: []string{"Service"}}
+	selected, err := f.Apply(manifests)
+	assert.NoError(t, err)
+	assert.Len(t, selected, 1)
+	assert.Contains(t, selected[0], "name: web")
+
+	f = &ManifestFilter{Files: []string{"templates/missing.yaml"}}
+	_, err = f.Apply(manifests)
+	assert.EqualError(t, err, "could not find template templates/missing.yaml in chart")
+
+	assert.True(t, (&ManifestFilter{}).Empty())
+}
@@ -0,0 +1,296 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// Chart annotations customizing the labels of the objects of a release. The
+// options of the install and upgrade actions take precedence over them.
+const (
+	// ManagedByAnnotation sets the value of the app.kubernetes.io/managed-by
+	// label.
+	ManagedByAnnotation = "helm.sh/managed-by"
+	// ReleaseNameLabelAnnotation sets the key of a label set to the name of
+	// the release.
+	ReleaseNameLabelAnnotation = "helm.sh/release-name-label"
+	// ReleaseNamespaceLabelAnnotation sets the key of a label set to the
+	// namespace of the release.
+	ReleaseNamespaceLabelAnnotation = "helm.sh/release-namespace-label"
+	// ObjectLabelsAnnotation lists labels set on all the objects of the
+	// release, as comma separated key=value pairs.
+	ObjectLabelsAnnotation = "helm.sh/object-labels"
+	// ObjectAnnotationsAnnotation lists annotations set on all the objects
+	// of the release, as comma separated key=value pairs.
+	ObjectAnnotationsAnnotation = "helm.sh/object-annotations"
+	// ObjectMetadataValuesAnnotation is the path of a table of the values,
+	// such as "global.tags", whose "labels" and "annotations" tables are set
+	// on all the objects of the release. It lets users tag the objects with
+	// their values files, such as with a team or cost center.
+	ObjectMetadataValuesAnnotation = "helm.sh/object-metadata-values"
+	// RequiredObjectLabelsAnnotation lists the keys of the labels all the
+	// objects of the release must have, comma separated.
+	RequiredObjectLabelsAnnotation = "helm.sh/required-object-labels"
+)
+
+// objectLabelsFor returns the labels of the objects of a release of the chart,
+// customized by the chart annotations, then by the values the chart points
+// them to, and then by the given options. It returns nil when the objects
+// carry the standard labels only.
+func objectLabelsFor(chrt *chart.Chart, vals common.Values, opts release.ObjectLabels) (*release.ObjectLabels, error) {
+	var l release.ObjectLabels
+	if chrt != nil && chrt.Metadata != nil {
+		annos := chrt.Metadata.Annotations
+		l.ManagedBy = annos[ManagedByAnnotation]
+		l.ReleaseNameKey = annos[ReleaseNameLabelAnnotation]
+		l.ReleaseNamespaceKey = annos[ReleaseNamespaceLabelAnnotation]
+		if v := annos[ObjectLabelsAnnotation]; v != "" {
+			extra, err := parseObjectLabels(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chart annotation %s: %w", ObjectLabelsAnnotation, err)
+			}
+			l.Extra = extra
+		}
+		if v := annos[ObjectAnnotationsAnnotation]; v != "" {
+			extra, err := parseObjectLabels(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid chart annotation %s: %w", ObjectAnnotationsAnnotation, err)
+			}
+			l.Annotations = extra
+		}
+		if path := annos[ObjectMetadataValuesAnnotation]; path != "" {
+			labels, err := valuesTable(vals, path+".labels")
+			if err != nil {
+				return nil, err
+			}
+			annotations, err := valuesTable(vals, path+".annotations")
+			if err != nil {
+				return nil, err
+			}
+			l.Extra = mergeNonEmpty(l.Extra, labels)
+			l.Annotations = mergeNonEmpty(l.Annotations, annotations)
+		}
+		for _, key := range strings.Split(annos[RequiredObjectLabelsAnnotation], ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				l.Required = append(l.Required, key)
+			}
+		}
+	}
+	if opts.ManagedBy != "" {
+		l.ManagedBy = opts.ManagedBy
+	}
+	if opts.ReleaseNameKey != "" {
+		l.ReleaseNameKey = opts.ReleaseNameKey
+	}
+	if opts.ReleaseNamespaceKey != "" {
+		l.ReleaseNamespaceKey = opts.ReleaseNamespaceKey
+	}
+	l.Extra = mergeNonEmpty(l.Extra, opts.Extra)
+	l.Annotations = mergeNonEmpty(l.Annotations, opts.Annotations)
+	for _, key := range opts.Required {
+		if !slices.Contains(l.Required, key) {
+			l.Required = append(l.Required, key)
+		}
+	}
+
+	if l.ManagedBy == "" && l.ReleaseNameKey == "" && l.ReleaseNamespaceKey == "" && len(l.Extra) == 0 && len(l.Annotations) == 0 && len(l.Required) == 0 {
+		return nil, nil
+	}
+	if err := validateObjectLabels(&l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// chartValues returns the values of the chart among the values to render it
+// with.
+func chartValues(valuesToRender common.Values) common.Values {
+	vals, err := valuesToRender.Table("Values")
+	if err != nil {
+		return nil
+	}
+	return vals
+}
+
+// mergeNonEmpty merges two maps like mergeStrStrMaps, but returns nil rather
+// than an empty map.
+func mergeNonEmpty(current, desired map[string]string) map[string]string {
+	if len(current) == 0 && len(desired) == 0 {
+		return nil
+	}
+	return mergeStrStrMaps(current, desired)
+}
+
+// valuesTable returns the table of the values at path as strings. A missing
+// table is empty.
+func valuesTable(vals common.Values, path string) (map[string]string, error) {
+	table, err := vals.Table(path)
+	if err != nil {
+		var noTable common.ErrNoTable
+		if errors.As(err, &noTable) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("invalid values %s for chart annotation %s: %w", path, ObjectMetadataValuesAnnotation, err)
+	}
+	m := make(map[string]string, len(table))
+	for k, v := range table {
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("invalid values %s for chart annotation %s: %s is not a string", path, ObjectMetadataValuesAnnotation, k)
+		}
+		if v != nil {
+			m[k] = fmt.Sprint(v)
+		}
+	}
+	return m, nil
+}
+
+func parseObjectLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not a key=value pair", pair)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
+
+func validateObjectLabels(l *release.ObjectLabels) error {
+	var errs []error
+	if l.ManagedBy != "" {
+		for _, msg := range validation.IsValidLabelValue(l.ManagedBy) {
+			errs = append(errs, fmt.Errorf("invalid value %q of the %s label: %s", l.ManagedBy, appManagedByLabel, msg))
+		}
+	}
+	for _, key := range []string{l.ReleaseNameKey, l.ReleaseNamespaceKey} {
+		if key == "" {
+			continue
+		}
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Errorf("invalid label key %q: %s", key, msg))
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(l.Extra)) {
+		v := l.Extra[k]
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = append(errs, fmt.Errorf("invalid label key %q: %s", k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			errs = append(errs, fmt.Errorf("invalid value %q of label %s: %s", v, k, msg))
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(l.Annotations)) {
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = append(errs, fmt.Errorf("invalid annotation key %q: %s", k, msg))
+		}
+	}
+	for _, k := range l.Required {
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = append(errs, fmt.Errorf("invalid required label key %q: %s", k, msg))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid object labels: %w", joinErrors(errs, "; "))
+	}
+	return nil
+}
+
+// missingRequiredLabels returns the keys of the labels required by l that the
+// object does not have.
+func missingRequiredLabels(l *release.ObjectLabels, obj runtime.Object) ([]string, error) {
+	if l == nil || len(l.Required) == 0 {
+		return nil, nil
+	}
+	labels, err := accessor.Labels(obj)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, key := range l.Required {
+		if _, ok := labels[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	return missing, nil
+}
+
+// managedBy returns the value of the managed-by label of the objects of a
+// release.
+func managedBy(l *release.ObjectLabels) string {
+	if l == nil || l.ManagedBy == "" {
+		return appManagedByHelm
+	}
+	return l.ManagedBy
+}
+
+// releaseObjectLabels returns the labels set on the objects of a release,
+// including the managed-by label. The standard labels take precedence over
+// the extra ones.
+func releaseObjectLabels(l *release.ObjectLabels, releaseName, releaseNamespace string) map[string]string {
+	labels := hookObjectLabels(l, releaseName, releaseNamespace)
+	if labels == nil {
+		labels = make(map[string]string, 1)
+	}
+	labels[appManagedByLabel] = managedBy(l)
+	return labels
+}
+
+// objectAnnotations returns the annotations set on the objects and hooks of a
+// release, besides the ones tracking the release.
+func objectAnnotations(l *release.ObjectLabels) map[string]string {
+	if l == nil {
+		return nil
+	}
+	return l.Annotations
+}
+
+// hookObjectLabels returns the labels set on the hooks of a release. Hooks are
+// not managed with the release, so they do not get the managed-by label.
+func hookObjectLabels(l *release.ObjectLabels, releaseName, releaseNamespace string) map[string]string {
+	if l == nil {
+		return nil
+	}
+	labels := maps.Clone(l.Extra)
+	if labels == nil {
+		labels = make(map[string]string, 2)
+	}
+	if l.ReleaseNameKey != "" {
+		labels[l.ReleaseNameKey] = releaseName
+	}
+	if l.ReleaseNamespaceKey != "" {
+		labels[l.ReleaseNamespaceKey] = releaseNamespace
+	}
+	return labels
+}
@@ -0,0 +1,157 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestObjectLabelsFor(t *testing.T) {
+	annotated := buildChart(withAnnotations(map[string]string{
+		ManagedByAnnotation:        "platform",
+		ReleaseNameLabelAnnotation: "example.com/release",
+		ObjectLabelsAnnotation:     "team=payments, tier=backend",
+	}))
+
+	tests := []struct {
+		name    string
+		chart   *chart.Chart
+		vals    common.Values
+		opts    release.ObjectLabels
+		expect  *release.ObjectLabels
+		wantErr string
+	}{
+		{
+			name:  "standard labels",
+			chart: buildChart(),
+		},
+		{
+			name:  "chart annotations",
+			chart: annotated,
+			expect: &release.ObjectLabels{
+				ManagedBy:      "platform",
+				ReleaseNameKey: "example.com/release",
+				Extra:          map[string]string{"team": "payments", "tier": "backend"},
+			},
+		},
+		{
+			name:  "options take precedence",
+			chart: annotated,
+			opts: release.ObjectLabels{
+				ManagedBy:           "argo",
+				ReleaseNamespaceKey: "example.com/namespace",
+				Extra:               map[string]string{"tier": "frontend"},
+			},
+			expect: &release.ObjectLabels{
+				ManagedBy:           "argo",
+				ReleaseNameKey:      "example.com/release",
+				ReleaseNamespaceKey: "example.com/namespace",
+				Extra:               map[string]string{"team": "payments", "tier": "frontend"},
+			},
+		},
+		{
+			name: "values",
+			chart: buildChart(withAnnotations(map[string]string{
+				ObjectLabelsAnnotation:         "team=payments",
+				ObjectAnnotationsAnnotation:    "example.com/owner=payments",
+				ObjectMetadataValuesAnnotation: "global.tags",
+				RequiredObjectLabelsAnnotation: "team, cost-center",
+			})),
+			vals: common.Values{"global": map[string]interface{}{"tags": map[string]interface{}{
+				"labels":      map[string]interface{}{"cost-center": 1234, "team": "checkout"},
+				"annotations": map[string]interface{}{"example.com/budget": "q3"},
+			}}},
+			opts: release.ObjectLabels{Required: []string{"team", "environment"}},
+			expect: &release.ObjectLabels{
+				Extra:       map[string]string{"team": "checkout", "cost-center": "1234"},
+				Annotations: map[string]string{"example.com/owner": "payments", "example.com/budget": "q3"},
+				Required:    []string{"team", "cost-center", "environment"},
+			},
+		},
+		{
+			name:   "missing values",
+			chart:  buildChart(withAnnotations(map[string]string{ObjectMetadataValuesAnnotation: "global.tags"})),
+			vals:   common.Values{},
+			expect: nil,
+		},
+		{
+			name:    "invalid values",
+			chart:   buildChart(withAnnotations(map[string]string{ObjectMetadataValuesAnnotation: "tags"})),
+			vals:    common.Values{"tags": map[string]interface{}{"labels": map[string]interface{}{"team": []interface{}{"a"}}}},
+			wantErr: `invalid values tags.labels for chart annotation helm.sh/object-metadata-values: team is not a string`,
+		},
+		{
+			name:    "invalid annotation",
+			chart:   buildChart(withAnnotations(map[string]string{ObjectLabelsAnnotation: "team"})),
+			wantErr: `invalid chart annotation helm.sh/object-labels: "team" is not a key=value pair`,
+		},
+		{
+			name:    "invalid labels",
+			chart:   buildChart(),
+			opts:    release.ObjectLabels{ManagedBy: "not valid", Extra: map[string]string{"-team": "payments"}},
+			wantErr: `invalid object labels: invalid value "not valid" of the app.kubernetes.io/managed-by label`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := objectLabelsFor(tt.chart, tt.vals, tt.opts)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expect, l)
+		})
+	}
+}
+
+func TestObjectLabelsRelease(t *testing.T) {
+	instAction := installAction(t)
+	instAction.ObjectLabels = release.ObjectLabels{Extra: map[string]string{"team": "payments"}}
+	ch := buildChart(withAnnotations(map[string]string{ManagedByAnnotation: "platform"}))
+	rel, err := instAction.Run(ch, map[string]interface{}{})
+	require.NoError(t, err)
+	expect := &release.ObjectLabels{ManagedBy: "platform", Extra: map[string]string{"team": "payments"}}
+	assert.Equal(t, expect, rel.ObjectLabels)
+
+	// The options are carried over to upgrades, and merged with the new ones.
+	upAction := NewUpgrade(instAction.cfg)
+	upAction.Namespace = rel.Namespace
+	upAction.ObjectLabels = release.ObjectLabels{Extra: map[string]string{"environment": "prod"}}
+	upgraded, err := upAction.Run(rel.Name, ch, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, &release.ObjectLabels{
+		ManagedBy: "platform",
+		Extra:     map[string]string{"team": "payments", "environment": "prod"},
+	}, upgraded.ObjectLabels)
+
+	// Rolling back restores the labels of the revision.
+	rbAction := NewRollback(instAction.cfg)
+	rbAction.Version = rel.Version
+	rbAction.ServerSideApply = "auto"
+	require.NoError(t, rbAction.Run(rel.Name))
+	last, err := instAction.cfg.Releases.Last(rel.Name)
+	require.NoError(t, err)
+	assert.Equal(t, expect, last.ObjectLabels)
+}
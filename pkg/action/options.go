@@ -0,0 +1,417 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/pkg/kube"
+	"helm.sh/helm/v4/pkg/postrenderer"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// IncompatibleOptionsError is returned when an action is constructed with
+// options that cannot be used together.
+//
+// Only Install and Upgrade have constructors validating their options. The
+// other actions, such as Rollback and Uninstall, are configured by setting
+// their fields.
+type IncompatibleOptionsError struct {
+	// Options are the names of the conflicting options.
+	Options []string
+	// Reason explains why the options cannot be combined.
+	Reason string
+}
+
+func (e *IncompatibleOptionsError) Error() string {
+	return fmt.Sprintf("incompatible options %s: %s", strings.Join(e.Options, ", "), e.Reason)
+}
+
+func incompatible(reason string, options ...string) error {
+	return &IncompatibleOptionsError{Options: options, Reason: reason}
+}
+
+// InstallOption configures an Install action created by NewInstallWithOptions.
+type InstallOption func(*Install) error
+
+// NewInstallWithOptions creates a new Install action with the given
+// configuration and options.
+//
+// Unlike NewInstall, which leaves the caller to set the fields of the returned
+// action, the options are validated as a whole and an *IncompatibleOptionsError
+// is returned for combinations that cannot work.
+func NewInstallWithOptions(cfg *Configuration, opts ...InstallOption) (*Install, error) {
+	i := NewInstall(cfg)
+	for _, opt := range opts {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+	if i.WaitStrategy == "" {
+		i.WaitStrategy = kube.HookOnlyStrategy
+		// Waiting is implied by rolling back on failure.
+		if i.RollbackOnFailure {
+			i.WaitStrategy = kube.StatusWatcherStrategy
+		}
+	}
+	if err := validateActionOptions(i.RollbackOnFailure, i.WaitStrategy, i.WaitForJobs, i.DryRun, i.HideSecret, i.ServerSideApply, i.ForceConflicts); err != nil {
+		return nil, err
+	}
+	if i.GenerateName && (i.ReleaseName != "" || i.NameTemplate != "") {
+		return nil, incompatible("a generated name cannot be combined with a release name or name template", "GenerateName", "ReleaseName", "NameTemplate")
+	}
+	return i, nil
+}
+
+// InstallWithReleaseName sets the name of the release to install.
+func InstallWithReleaseName(name string) InstallOption {
+	return func(i *Install) error {
+		i.ReleaseName = name
+		return nil
+	}
+}
+
+// InstallWithGenerateName generates a name for the release.
+func InstallWithGenerateName() InstallOption {
+	return func(i *Install) error {
+		i.GenerateName = true
+		return nil
+	}
+}
+
+// InstallWithNamespace sets the namespace to install the release into.
+// When createNamespace is true the namespace is created if it does not exist.
+func InstallWithNamespace(namespace string, createNamespace bool) InstallOption {
+	return func(i *Install) error {
+		i.Namespace = namespace
+		i.CreateNamespace = createNamespace
+		return nil
+	}
+}
+
+// InstallWithChartPathOptions sets the options used to locate the chart.
+func InstallWithChartPathOptions(c ChartPathOptions) InstallOption {
+	return func(i *Install) error {
+		// The registry client is not part of the options given by the caller.
+		registryClient := i.registryClient
+		i.ChartPathOptions = c
+		i.registryClient = registryClient
+		return nil
+	}
+}
+
+// InstallWithWait waits for the resources of the release using the given
+// strategy, for at most timeout. When waitForJobs is true Jobs must have
+// completed too.
+func InstallWithWait(strategy kube.WaitStrategy, timeout time.Duration, waitForJobs bool) InstallOption {
+	return func(i *Install) error {
+		i.WaitStrategy = strategy
+		i.Timeout = timeout
+		i.WaitForJobs = waitForJobs
+		return nil
+	}
+}
+
+// InstallWithRollbackOnFailure uninstalls the release when the installation fails.
+func InstallWithRollbackOnFailure() InstallOption {
+	return func(i *Install) error {
+		i.RollbackOnFailure = true
+		return nil
+	}
+}
+
+// InstallWithDryRun renders the release without installing it. The strategy
+// is either "client" or "server"; when hideSecret is true Secrets are left out
+// of the rendered output.
+func InstallWithDryRun(strategy string, hideSecret bool) InstallOption {
+	return func(i *Install) error {
+		if err := validateDryRunStrategy(strategy); err != nil {
+			return err
+		}
+		i.DryRun = true
+		i.DryRunOption = strategy
+		i.HideSecret = hideSecret
+		return nil
+	}
+}
+
+// InstallWithServerSideApply configures whether resources are applied server-side.
+func InstallWithServerSideApply(enabled, forceConflicts bool) InstallOption {
+	return func(i *Install) error {
+		i.ServerSideApply = enabled
+		i.ForceConflicts = forceConflicts
+		return nil
+	}
+}
+
+// InstallWithDescription sets the description of the release.
+func InstallWithDescription(description string) InstallOption {
+	return func(i *Install) error {
+		i.Description = description
+		return nil
+	}
+}
+
+// InstallWithLabels sets the labels added to the release metadata.
+func InstallWithLabels(labels map[string]string) InstallOption {
+	return func(i *Install) error {
+		i.Labels = maps.Clone(labels)
+		return nil
+	}
+}
+
+// InstallWithObjectLabels customizes the labels set on the objects of the
+// release.
+func InstallWithObjectLabels(labels release.ObjectLabels) InstallOption {
+	return func(i *Install) error {
+		labels.Extra = maps.Clone(labels.Extra)
+		i.ObjectLabels = labels
+		return nil
+	}
+}
+
+// InstallWithHealthChecks checks the health of the release once it is
+// deployed.
+func InstallWithHealthChecks(checks HealthChecks) InstallOption {
+	return func(i *Install) error {
+		checks.Checks = slices.Clone(checks.Checks)
+		i.HealthChecks = checks
+		return nil
+	}
+}
+
+// InstallWithPostRenderer sets the post-renderer applied to the rendered manifests.
+func InstallWithPostRenderer(pr postrenderer.PostRenderer) InstallOption {
+	return func(i *Install) error {
+		i.PostRenderer = pr
+		return nil
+	}
+}
+
+// UpgradeOption configures an Upgrade action created by NewUpgradeWithOptions.
+type UpgradeOption func(*Upgrade) error
+
+// NewUpgradeWithOptions creates a new Upgrade action with the given
+// configuration and options.
+//
+// Unlike NewUpgrade, which leaves the caller to set the fields of the returned
+// action, the options are validated as a whole and an *IncompatibleOptionsError
+// is returned for combinations that cannot work.
+func NewUpgradeWithOptions(cfg *Configuration, opts ...UpgradeOption) (*Upgrade, error) {
+	u := NewUpgrade(cfg)
+	for _, opt := range opts {
+		if err := opt(u); err != nil {
+			return nil, err
+		}
+	}
+	if u.WaitStrategy == "" {
+		u.WaitStrategy = kube.HookOnlyStrategy
+		// Waiting is implied by rolling back on failure.
+		if u.RollbackOnFailure {
+			u.WaitStrategy = kube.StatusWatcherStrategy
+		}
+	}
+	if err := validateActionOptions(u.RollbackOnFailure, u.WaitStrategy, u.WaitForJobs, u.DryRun, u.HideSecret, u.ServerSideApply != "false", u.ForceConflicts); err != nil {
+		return nil, err
+	}
+	if countTrue(u.ResetValues, u.ReuseValues, u.ResetThenReuseValues) > 1 {
+		return nil, incompatible("only one way of handling the values of the previous release can be chosen", "ResetValues", "ReuseValues", "ResetThenReuseValues")
+	}
+	return u, nil
+}
+
+// UpgradeWithNamespace sets the namespace of the release to upgrade.
+func UpgradeWithNamespace(namespace string) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.Namespace = namespace
+		return nil
+	}
+}
+
+// UpgradeWithChartPathOptions sets the options used to locate the chart.
+func UpgradeWithChartPathOptions(c ChartPathOptions) UpgradeOption {
+	return func(u *Upgrade) error {
+		// The registry client is not part of the options given by the caller.
+		registryClient := u.registryClient
+		u.ChartPathOptions = c
+		u.registryClient = registryClient
+		return nil
+	}
+}
+
+// UpgradeWithWait waits for the resources of the release using the given
+// strategy, for at most timeout. When waitForJobs is true Jobs must have
+// completed too.
+func UpgradeWithWait(strategy kube.WaitStrategy, timeout time.Duration, waitForJobs bool) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.WaitStrategy = strategy
+		u.Timeout = timeout
+		u.WaitForJobs = waitForJobs
+		return nil
+	}
+}
+
+// UpgradeWithRollbackOnFailure rolls the release back when the upgrade fails.
+// When cleanupOnFail is true resources created by the failed upgrade are deleted.
+func UpgradeWithRollbackOnFailure(cleanupOnFail bool) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.RollbackOnFailure = true
+		u.CleanupOnFail = cleanupOnFail
+		return nil
+	}
+}
+
+// UpgradeWithDryRun renders the upgraded release without applying it. The
+// strategy is either "client" or "server"; when hideSecret is true Secrets are
+// left out of the rendered output.
+func UpgradeWithDryRun(strategy string, hideSecret bool) UpgradeOption {
+	return func(u *Upgrade) error {
+		if err := validateDryRunStrategy(strategy); err != nil {
+			return err
+		}
+		u.DryRun = true
+		u.DryRunOption = strategy
+		u.HideSecret = hideSecret
+		return nil
+	}
+}
+
+// UpgradeWithServerSideApply configures whether resources are applied
+// server-side. The mode is one of "true", "false" or "auto".
+func UpgradeWithServerSideApply(mode string, forceConflicts bool) UpgradeOption {
+	return func(u *Upgrade) error {
+		switch mode {
+		case "true", "false", "auto":
+		default:
+			return fmt.Errorf("invalid server-side apply mode %q, valid modes are true, false and auto", mode)
+		}
+		u.ServerSideApply = mode
+		u.ForceConflicts = forceConflicts
+		return nil
+	}
+}
+
+// UpgradeWithResetValues resets the values to the ones of the chart.
+func UpgradeWithResetValues() UpgradeOption {
+	return func(u *Upgrade) error {
+		u.ResetValues = true
+		return nil
+	}
+}
+
+// UpgradeWithReuseValues reuses the values of the previous release.
+func UpgradeWithReuseValues() UpgradeOption {
+	return func(u *Upgrade) error {
+		u.ReuseValues = true
+		return nil
+	}
+}
+
+// UpgradeWithMaxHistory limits the number of revisions kept for the release.
+func UpgradeWithMaxHistory(maxHistory int) UpgradeOption {
+	return func(u *Upgrade) error {
+		if maxHistory < 0 {
+			return fmt.Errorf("max history must not be negative, got %d", maxHistory)
+		}
+		u.MaxHistory = maxHistory
+		return nil
+	}
+}
+
+// UpgradeWithDescription sets the description of the release.
+func UpgradeWithDescription(description string) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.Description = description
+		return nil
+	}
+}
+
+// UpgradeWithLabels sets the labels added to the release metadata.
+func UpgradeWithLabels(labels map[string]string) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.Labels = maps.Clone(labels)
+		return nil
+	}
+}
+
+// UpgradeWithObjectLabels customizes the labels set on the objects of the
+// release.
+func UpgradeWithObjectLabels(labels release.ObjectLabels) UpgradeOption {
+	return func(u *Upgrade) error {
+		labels.Extra = maps.Clone(labels.Extra)
+		u.ObjectLabels = labels
+		return nil
+	}
+}
+
+// UpgradeWithHealthChecks checks the health of the release once it is
+// upgraded.
+func UpgradeWithHealthChecks(checks HealthChecks) UpgradeOption {
+	return func(u *Upgrade) error {
+		checks.Checks = slices.Clone(checks.Checks)
+		u.HealthChecks = checks
+		return nil
+	}
+}
+
+// UpgradeWithPostRenderer sets the post-renderer applied to the rendered manifests.
+func UpgradeWithPostRenderer(pr postrenderer.PostRenderer) UpgradeOption {
+	return func(u *Upgrade) error {
+		u.PostRenderer = pr
+		return nil
+	}
+}
+
+// validateActionOptions checks the option combinations shared by the install
+// and upgrade actions.
+func validateActionOptions(rollbackOnFailure bool, ws kube.WaitStrategy, waitForJobs, dryRun, hideSecret, serverSideApply, forceConflicts bool) error {
+	if rollbackOnFailure && ws == kube.HookOnlyStrategy {
+		return incompatible("rolling back on failure requires waiting for the resources", "RollbackOnFailure", "WaitStrategy")
+	}
+	if waitForJobs && ws == kube.HookOnlyStrategy {
+		return incompatible("waiting for jobs requires waiting for the resources", "WaitForJobs", "WaitStrategy")
+	}
+	if hideSecret && !dryRun {
+		return incompatible("hiding Kubernetes secrets requires a dry-run mode", "HideSecret", "DryRun")
+	}
+	if forceConflicts && !serverSideApply {
+		return incompatible("forcing conflicts requires server-side apply", "ForceConflicts", "ServerSideApply")
+	}
+	return nil
+}
+
+func validateDryRunStrategy(strategy string) error {
+	switch strategy {
+	case "client", "server":
+		return nil
+	}
+	return fmt.Errorf("invalid dry-run strategy %q, valid strategies are client and server", strategy)
+}
+
+func countTrue(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
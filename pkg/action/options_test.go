@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+func TestNewInstallWithOptions(t *testing.T) {
+	cfg := actionConfigFixture(t)
+
+	instAction, err := NewInstallWithOptions(cfg,
+		InstallWithReleaseName("test"),
+		InstallWithNamespace("spaced", true),
+		InstallWithChartPathOptions(ChartPathOptions{Version: "1.0.0"}),
+		InstallWithRollbackOnFailure(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "test", instAction.ReleaseName)
+	assert.Equal(t, "spaced", instAction.Namespace)
+	assert.True(t, instAction.CreateNamespace)
+	assert.Equal(t, "1.0.0", instAction.Version)
+	assert.Equal(t, kube.StatusWatcherStrategy, instAction.WaitStrategy, "rolling back on failure implies waiting")
+
+	instAction, err = NewInstallWithOptions(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, kube.HookOnlyStrategy, instAction.WaitStrategy)
+	assert.True(t, instAction.ServerSideApply)
+
+	for name, opts := range map[string][]InstallOption{
+		"rollback without wait":      {InstallWithRollbackOnFailure(), InstallWithWait(kube.HookOnlyStrategy, time.Minute, false)},
+		"jobs without wait":          {InstallWithWait(kube.HookOnlyStrategy, time.Minute, true)},
+		"hide secret without dryrun": {func(i *Install) error { i.HideSecret = true; return nil }},
+		"force without ssa":          {InstallWithServerSideApply(false, true)},
+		"generate and name":          {InstallWithGenerateName(), InstallWithReleaseName("test")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewInstallWithOptions(cfg, opts...)
+			var incompatibleErr *IncompatibleOptionsError
+			assert.True(t, errors.As(err, &incompatibleErr), "expected IncompatibleOptionsError, got %v", err)
+		})
+	}
+
+	_, err = NewInstallWithOptions(cfg, InstallWithDryRun("sometimes", false))
+	assert.Error(t, err)
+}
+
+func TestNewUpgradeWithOptions(t *testing.T) {
+	cfg := actionConfigFixture(t)
+
+	upAction, err := NewUpgradeWithOptions(cfg,
+		UpgradeWithNamespace("spaced"),
+		UpgradeWithDryRun("server", true),
+		UpgradeWithMaxHistory(5),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "spaced", upAction.Namespace)
+	assert.True(t, upAction.DryRun)
+	assert.True(t, upAction.HideSecret)
+	assert.Equal(t, 5, upAction.MaxHistory)
+	assert.Equal(t, "auto", upAction.ServerSideApply)
+
+	for name, opts := range map[string][]UpgradeOption{
+		"rollback without wait": {UpgradeWithRollbackOnFailure(false), UpgradeWithWait(kube.HookOnlyStrategy, time.Minute, false)},
+		"reset and reuse":       {UpgradeWithResetValues(), UpgradeWithReuseValues()},
+		"force without ssa":     {UpgradeWithServerSideApply("false", true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewUpgradeWithOptions(cfg, opts...)
+			var incompatibleErr *IncompatibleOptionsError
+			assert.True(t, errors.As(err, &incompatibleErr), "expected IncompatibleOptionsError, got %v", err)
+		})
+	}
+
+	_, err = NewUpgradeWithOptions(cfg, UpgradeWithServerSideApply("maybe", false))
+	assert.Error(t, err)
+	_, err = NewUpgradeWithOptions(cfg, UpgradeWithMaxHistory(-1))
+	assert.Error(t, err)
+}
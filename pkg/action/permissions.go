@@ -0,0 +1,139 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// resourceAccess is an access to a resource an action needs.
+type resourceAccess struct {
+	verb      string
+	group     string
+	resource  string
+	namespace string
+	name      string
+}
+
+func (a resourceAccess) String() string {
+	res := a.resource
+	if a.group != "" {
+		res += "." + a.group
+	}
+	if a.name != "" {
+		res += fmt.Sprintf(" %q", a.name)
+	}
+	if a.namespace != "" {
+		return fmt.Sprintf("%s %s in namespace %q", a.verb, res, a.namespace)
+	}
+	return fmt.Sprintf("%s %s", a.verb, res)
+}
+
+// accessesFor returns the accesses needed to perform verb on the resources.
+// Names are left out of create accesses, as Kubernetes does not authorize
+// creations by name.
+func accessesFor(verb string, resources kube.ResourceList) []resourceAccess {
+	var accesses []resourceAccess
+	for _, info := range resources {
+		if info.Mapping == nil {
+			continue
+		}
+		a := resourceAccess{
+			verb:      verb,
+			group:     info.Mapping.Resource.Group,
+			resource:  info.Mapping.Resource.Resource,
+			namespace: info.Namespace,
+		}
+		if verb != "create" {
+			a.name = info.Name
+		}
+		accesses = append(accesses, a)
+	}
+	return accesses
+}
+
+// hookAccesses returns the accesses needed to create and delete the hooks of
+// a release.
+func (cfg *Configuration) hookAccesses(hooks []*release.Hook) ([]resourceAccess, error) {
+	var accesses []resourceAccess
+	for _, h := range hooks {
+		resources, err := cfg.KubeClient.Build(strings.NewReader(h.Manifest), false)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build kubernetes object for hook %s: %w", h.Path, err)
+		}
+		accesses = append(accesses, accessesFor("create", resources)...)
+		accesses = append(accesses, accessesFor("delete", resources)...)
+	}
+	return accesses, nil
+}
+
+// checkPermissions reviews whether the Kubernetes user of the configuration
+// is allowed all the accesses, and returns one error listing all the denied
+// ones. It lets an action fail before changing anything in the cluster,
+// instead of in the middle of applying a release.
+func (cfg *Configuration) checkPermissions(ctx context.Context, accesses []resourceAccess) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return fmt.Errorf("unable to check permissions: %w", err)
+	}
+	return checkAccesses(ctx, client, accesses)
+}
+
+func checkAccesses(ctx context.Context, client kubernetes.Interface, accesses []resourceAccess) error {
+	seen := map[resourceAccess]bool{}
+	var denied []string
+	for _, a := range accesses {
+		if seen[a] {
+			continue
+		}
+		seen[a] = true
+
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Verb:      a.verb,
+					Group:     a.group,
+					Resource:  a.resource,
+					Namespace: a.namespace,
+					Name:      a.name,
+				},
+			},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to check permission to %s: %w", a, err)
+		}
+		if !review.Status.Allowed {
+			denied = append(denied, a.String())
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("missing permissions to:\n  %s", strings.Join(denied, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+func TestAccessesFor(t *testing.T) {
+	resources := kube.ResourceList{newMissingDeployment("web", "prod")}
+
+	assert.Equal(t, []resourceAccess{
+		{verb: "create", group: "apps", resource: "deployment", namespace: "prod"},
+	}, accessesFor("create", resources))
+	assert.Equal(t, []resourceAccess{
+		{verb: "delete", group: "apps", resource: "deployment", namespace: "prod", name: "web"},
+	}, accessesFor("delete", resources))
+}
+
+func TestCheckAccesses(t *testing.T) {
+	client := fakeclientset.NewClientset()
+	var reviews int
+	client.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Verb == "create"
+		return true, review, nil
+	})
+
+	accesses := []resourceAccess{
+		{verb: "create", resource: "configmaps", namespace: "prod"},
+		{verb: "create", resource: "configmaps", namespace: "prod"},
+		{verb: "patch", group: "apps", resource: "deployments", namespace: "prod", name: "web"},
+		{verb: "delete", group: "rbac.authorization.k8s.io", resource: "clusterroles", name: "web"},
+	}
+	err := checkAccesses(context.Background(), client, accesses)
+	require.Error(t, err)
+	assert.Equal(t, `missing permissions to:
+  patch deployments.apps "web" in namespace "prod"
+  delete clusterroles.rbac.authorization.k8s.io "web"`, err.Error())
+	assert.Equal(t, 3, reviews, "duplicate accesses are reviewed once")
+
+	require.NoError(t, checkAccesses(context.Background(), client, accesses[:2]))
+}
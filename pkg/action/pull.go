@@ -21,7 +21,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/cli"
 	"helm.sh/helm/v4/pkg/downloader"
@@ -43,7 +45,15 @@ type Pull struct {
 	VerifyLater bool
 	UntarDir    string
 	DestDir     string
-	cfg         *Configuration
+	// Retries is the number of times a download failing with a network or
+	// server error is retried, and RetryBackoff the time waited before the
+	// first retry, doubled before each of the next ones.
+	Retries      int
+	RetryBackoff time.Duration
+	// AdvisoryPolicy, when set, rejects pulled charts affected by security
+	// advisories, or with dependencies that are.
+	AdvisoryPolicy *AdvisoryPolicy
+	cfg            *Configuration
 }
 
 type PullOpt func(*Pull)
@@ -64,15 +74,21 @@ func NewPull(opts ...PullOpt) *Pull {
 	return p
 }
 
-// SetRegistryClient sets the registry client on the pull configuration object.
+// SetRegistryClient sets the registry client used by the pull, overriding the
+// one of the configuration.
 func (p *Pull) SetRegistryClient(client *registry.Client) {
-	p.cfg.RegistryClient = client
+	p.registryClient = client
 }
 
 // Run executes 'helm pull' against the given release.
 func (p *Pull) Run(chartRef string) (string, error) {
 	var out strings.Builder
 
+	registryClient := p.registryClient
+	if registryClient == nil {
+		registryClient = p.cfg.RegistryClient
+	}
+
 	c := downloader.ChartDownloader{
 		Out:     &out,
 		Keyring: p.Keyring,
@@ -85,16 +101,18 @@ func (p *Pull) Run(chartRef string) (string, error) {
 			getter.WithInsecureSkipVerifyTLS(p.InsecureSkipTLSverify),
 			getter.WithPlainHTTP(p.PlainHTTP),
 		},
-		RegistryClient:   p.cfg.RegistryClient,
+		RegistryClient:   registryClient,
 		RepositoryConfig: p.Settings.RepositoryConfig,
 		RepositoryCache:  p.Settings.RepositoryCache,
 		ContentCache:     p.Settings.ContentCache,
+		Retries:          p.Retries,
+		RetryBackoff:     p.RetryBackoff,
 	}
 
 	if registry.IsOCI(chartRef) {
 		c.Options = append(c.Options,
-			getter.WithRegistryClient(p.cfg.RegistryClient))
-		c.RegistryClient = p.cfg.RegistryClient
+			getter.WithRegistryClient(registryClient))
+		c.RegistryClient = registryClient
 	}
 
 	if p.Verify {
@@ -146,6 +164,22 @@ func (p *Pull) Run(chartRef string) (string, error) {
 		fmt.Fprintf(&out, "Chart Hash Verified: %s\n", v.FileHash)
 	}
 
+	if p.AdvisoryPolicy != nil {
+		ch, err := loader.Load(saved)
+		if err != nil {
+			return out.String(), err
+		}
+		if err := p.AdvisoryPolicy.Check(ch, p.chartRepository(chartRef)); err != nil {
+			// Affected charts are not kept. When untarring, the chart is in
+			// a temporary directory removed on return.
+			if !p.Untar {
+				os.Remove(saved)
+				os.Remove(saved + ".prov")
+			}
+			return out.String(), err
+		}
+	}
+
 	// After verification, untar the chart into the requested directory.
 	if p.Untar {
 		ud := p.UntarDir
@@ -173,3 +207,19 @@ func (p *Pull) Run(chartRef string) (string, error) {
 	}
 	return out.String(), nil
 }
+
+// chartRepository returns the repository of the chart pulled by chartRef:
+// the repository URL given with --repo, or the registry path of an OCI
+// reference. It is "" for the other references, whose repository is not
+// known here.
+func (p *Pull) chartRepository(chartRef string) string {
+	if p.RepoURL != "" {
+		return p.RepoURL
+	}
+	if registry.IsOCI(chartRef) {
+		if i := strings.LastIndex(chartRef, "/"); i > len(registry.OCIScheme+"://") {
+			return chartRef[:i]
+		}
+	}
+	return ""
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"errors"
 	"io"
 	"strings"
 
@@ -37,6 +38,10 @@ type Push struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	separateLayers        bool
+	provReferrer          bool
+	attachments           []registry.Attachment
+	chunkSize             int64
 	out                   io.Writer
 }
 
@@ -73,6 +78,39 @@ func WithPlainHTTP(plainHTTP bool) PushOpt {
 	}
 }
 
+// WithSeparateLayers pushes the values, schema and README of the chart as
+// separate layers as well, so they can be read without pulling the chart.
+func WithSeparateLayers(separateLayers bool) PushOpt {
+	return func(p *Push) {
+		p.separateLayers = separateLayers
+	}
+}
+
+// WithProvReferrer attaches the provenance file of the chart to it as an OCI
+// referrer, instead of pushing it as a layer of the chart.
+func WithProvReferrer(provReferrer bool) PushOpt {
+	return func(p *Push) {
+		p.provReferrer = provReferrer
+	}
+}
+
+// WithAttachments attaches artifacts, such as SBOMs or signatures, to the
+// chart as OCI referrers.
+func WithAttachments(attachments ...registry.Attachment) PushOpt {
+	return func(p *Push) {
+		p.attachments = append(p.attachments, attachments...)
+	}
+}
+
+// WithChunkSize uploads the layers of the chart larger than chunkSize bytes
+// in chunks, resumed from the last byte received by the registry when
+// failing.
+func WithChunkSize(chunkSize int64) PushOpt {
+	return func(p *Push) {
+		p.chunkSize = chunkSize
+	}
+}
+
 // WithPushOptWriter sets the registryOut field on the push configuration object.
 func WithPushOptWriter(out io.Writer) PushOpt {
 	return func(p *Push) {
@@ -100,9 +138,17 @@ func (p *Push) Run(chartRef string, remote string) (string, error) {
 			pusher.WithTLSClientConfig(p.certFile, p.keyFile, p.caFile),
 			pusher.WithInsecureSkipTLSVerify(p.insecureSkipTLSverify),
 			pusher.WithPlainHTTP(p.plainHTTP),
+			pusher.WithSeparateLayers(p.separateLayers),
+			pusher.WithProvReferrer(p.provReferrer),
+			pusher.WithAttachments(p.attachments...),
+			pusher.WithChunkSize(p.chunkSize),
 		},
 	}
 
+	if !registry.IsOCI(remote) && (p.provReferrer || len(p.attachments) > 0) {
+		return "", errors.New("artifacts can only be attached to charts pushed to OCI registries")
+	}
+
 	if registry.IsOCI(remote) {
 		// Don't use the default registry client if tls options are set.
 		c.Options = append(c.Options, pusher.WithRegistryClient(p.cfg.RegistryClient))
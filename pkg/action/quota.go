@@ -0,0 +1,299 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+// quotaCountedKinds are the kinds whose objects are counted by ResourceQuotas,
+// with the name of their count.
+var quotaCountedKinds = map[string]v1.ResourceName{
+	"ConfigMap":             v1.ResourceConfigMaps,
+	"Secret":                v1.ResourceSecrets,
+	"Service":               v1.ResourceServices,
+	"PersistentVolumeClaim": v1.ResourcePersistentVolumeClaims,
+	"ReplicationController": v1.ResourceReplicationControllers,
+}
+
+// quotaEstimate is the least amount of each resource counted by
+// ResourceQuotas the objects of a release use in a namespace.
+type quotaEstimate struct {
+	used v1.ResourceList
+	// unset lists, for each compute resource, the workloads with containers
+	// not requesting or limiting it, which ResourceQuotas constraining it
+	// reject unless a LimitRange sets a default.
+	unset map[v1.ResourceName][]string
+}
+
+func (e *quotaEstimate) add(name v1.ResourceName, q resource.Quantity) {
+	sum := e.used[name]
+	sum.Add(q)
+	e.used[name] = sum
+}
+
+// estimateQuota sums the resources counted by ResourceQuotas that the objects
+// of a manifest use, per namespace. Objects without a namespace are placed in
+// the given one.
+//
+// The estimate is a lower bound: DaemonSets are counted as running a single
+// pod, and Jobs as running their parallelism at once. A release exceeding the
+// quota with it cannot possibly be installed.
+func estimateQuota(manifest, namespace string) (map[string]*quotaEstimate, error) {
+	estimates := make(map[string]*quotaEstimate)
+	for _, doc := range releaseutil.SplitManifests(manifest) {
+		var obj unstructured.Unstructured
+		if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+			return nil, fmt.Errorf("unable to parse manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		ns := obj.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		e, ok := estimates[ns]
+		if !ok {
+			e = &quotaEstimate{used: v1.ResourceList{}, unset: map[v1.ResourceName][]string{}}
+			estimates[ns] = e
+		}
+		if err := e.addObject(&obj); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+	return estimates, nil
+}
+
+// addObject adds the resources an object uses to the estimate.
+func (e *quotaEstimate) addObject(obj *unstructured.Unstructured) error {
+	kind := obj.GetKind()
+	if name, ok := quotaCountedKinds[kind]; ok {
+		e.add(name, *resource.NewQuantity(1, resource.DecimalSI))
+	}
+	if kind == "PersistentVolumeClaim" {
+		return e.addClaim(obj.Object, 1)
+	}
+
+	var podSpec []string
+	if kind == "Pod" {
+		podSpec = []string{"spec"}
+	} else if path, ok := podTemplatePaths[kind]; ok {
+		podSpec = append(slices.Clone(path), "spec")
+	} else {
+		return nil
+	}
+	m, found, err := unstructured.NestedMap(obj.Object, podSpec...)
+	if err != nil || !found {
+		return err
+	}
+	var spec v1.PodSpec
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err != nil {
+		return err
+	}
+
+	pods := podCount(obj)
+	e.add(v1.ResourcePods, *resource.NewQuantity(pods, resource.DecimalSI))
+	requests, limits := podResources(&spec)
+	for name, q := range requests {
+		e.add(name, multiply(q, pods))
+		e.add(v1.ResourceName("requests."+name), multiply(q, pods))
+	}
+	for name, q := range limits {
+		e.add(v1.ResourceName("limits."+name), multiply(q, pods))
+	}
+	workload := fmt.Sprintf("%s %s", kind, obj.GetName())
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		if missingResource(&spec, name, false) {
+			for _, quotaName := range []v1.ResourceName{name, "requests." + name} {
+				e.unset[quotaName] = append(e.unset[quotaName], workload)
+			}
+		}
+		if missingResource(&spec, name, true) {
+			e.unset["limits."+name] = append(e.unset["limits."+name], workload)
+		}
+	}
+
+	if kind == "StatefulSet" {
+		claims, _, err := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+		if err != nil {
+			return err
+		}
+		for _, c := range claims {
+			claim, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			e.add(v1.ResourcePersistentVolumeClaims, *resource.NewQuantity(pods, resource.DecimalSI))
+			if err := e.addClaim(claim, pods); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addClaim adds the storage requested by count copies of a claim.
+func (e *quotaEstimate) addClaim(claim map[string]interface{}, count int64) error {
+	storage, found, err := unstructured.NestedString(claim, "spec", "resources", "requests", "storage")
+	if err != nil || !found {
+		return err
+	}
+	q, err := resource.ParseQuantity(storage)
+	if err != nil {
+		return fmt.Errorf("invalid storage request %q: %w", storage, err)
+	}
+	e.add(v1.ResourceRequestsStorage, multiply(q, count))
+	return nil
+}
+
+// podCount returns the least number of pods a workload runs at once.
+func podCount(obj *unstructured.Unstructured) int64 {
+	var path []string
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet", "ReplicaSet", "ReplicationController":
+		path = []string{"spec", "replicas"}
+	case "Job":
+		path = []string{"spec", "parallelism"}
+	case "CronJob":
+		path = []string{"spec", "jobTemplate", "spec", "parallelism"}
+	default:
+		return 1
+	}
+	// Numbers parsed from YAML are float64s.
+	n, _, _ := unstructured.NestedFieldNoCopy(obj.Object, path...)
+	switch n := n.(type) {
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	}
+	return 1
+}
+
+// podResources returns the resources requested and limited by a pod: the
+// largest of the sum over its containers and of each of its init containers.
+func podResources(spec *v1.PodSpec) (requests, limits v1.ResourceList) {
+	requests, limits = v1.ResourceList{}, v1.ResourceList{}
+	for _, c := range spec.Containers {
+		for name, q := range c.Resources.Requests {
+			sum := requests[name]
+			sum.Add(q)
+			requests[name] = sum
+		}
+		for name, q := range c.Resources.Limits {
+			sum := limits[name]
+			sum.Add(q)
+			limits[name] = sum
+		}
+	}
+	for _, c := range spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if q.Cmp(requests[name]) > 0 {
+				requests[name] = q
+			}
+		}
+		for name, q := range c.Resources.Limits {
+			if q.Cmp(limits[name]) > 0 {
+				limits[name] = q
+			}
+		}
+	}
+	return requests, limits
+}
+
+// missingResource reports whether a container of a pod does not request, or
+// limit, a resource.
+func missingResource(spec *v1.PodSpec, name v1.ResourceName, limit bool) bool {
+	for _, c := range slices.Concat(spec.InitContainers, spec.Containers) {
+		list := c.Resources.Requests
+		if limit {
+			list = c.Resources.Limits
+		}
+		if _, ok := list[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func multiply(q resource.Quantity, n int64) resource.Quantity {
+	q.Mul(n)
+	return q
+}
+
+// checkQuota compares the resources the objects of a manifest use with the
+// ResourceQuotas of their namespaces, and returns one error listing all the
+// quotas the release cannot fit in. It lets an install fail before changing
+// anything in the cluster, instead of when creating the pods.
+func (cfg *Configuration) checkQuota(ctx context.Context, manifest, namespace string, warn func(WarningKind, string)) error {
+	estimates, err := estimateQuota(manifest, namespace)
+	if err != nil {
+		return fmt.Errorf("unable to estimate the resources of the release: %w", err)
+	}
+	client, err := cfg.KubernetesClientSet()
+	if err != nil {
+		return fmt.Errorf("unable to check resource quotas: %w", err)
+	}
+	return checkQuotas(ctx, client, estimates, warn)
+}
+
+func checkQuotas(ctx context.Context, client kubernetes.Interface, estimates map[string]*quotaEstimate, warn func(WarningKind, string)) error {
+	var exceeded []string
+	for _, ns := range slices.Sorted(maps.Keys(estimates)) {
+		e := estimates[ns]
+		quotas, err := client.CoreV1().ResourceQuotas(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("unable to list the resource quotas of namespace %q: %w", ns, err)
+		}
+		for _, quota := range quotas.Items {
+			for _, name := range slices.Sorted(maps.Keys(quota.Spec.Hard)) {
+				if workloads := e.unset[name]; len(workloads) > 0 {
+					warn(WarningQuota, fmt.Sprintf("ResourceQuota %q in namespace %q constrains %s, which is not set for all the containers of %s: their pods are rejected unless a LimitRange sets a default", quota.Name, ns, name, strings.Join(workloads, ", ")))
+				}
+				want, ok := e.used[name]
+				if !ok {
+					continue
+				}
+				hard := quota.Spec.Hard[name]
+				left := hard.DeepCopy()
+				left.Sub(quota.Status.Used[name])
+				if want.Cmp(left) > 0 {
+					exceeded = append(exceeded, fmt.Sprintf("%s: the release needs at least %s, ResourceQuota %q in namespace %q has %s left of %s", name, want.String(), quota.Name, ns, left.String(), hard.String()))
+				}
+			}
+		}
+	}
+	if len(exceeded) > 0 {
+		return fmt.Errorf("release does not fit in the resource quotas:\n  %s", strings.Join(exceeded, "\n  "))
+	}
+	return nil
+}
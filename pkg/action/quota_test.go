@@ -0,0 +1,160 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+)
+
+const quotaManifest = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        resources:
+          requests:
+            cpu: 500m
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: 100m
+            memory: 128Mi
+          limits:
+            memory: 256Mi
+      - name: sidecar
+        resources:
+          requests:
+            cpu: 50m
+            memory: 64Mi
+          limits:
+            memory: 64Mi
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: data
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: db
+        resources:
+          requests:
+            cpu: "1"
+            memory: 1Gi
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+    spec:
+      resources:
+        requests:
+          storage: 10Gi
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`
+
+func TestEstimateQuota(t *testing.T) {
+	estimates, err := estimateQuota(quotaManifest, "prod")
+	require.NoError(t, err)
+
+	quantities := func(e *quotaEstimate) map[v1.ResourceName]string {
+		m := map[v1.ResourceName]string{}
+		for name, q := range e.used {
+			m[name] = q.String()
+		}
+		return m
+	}
+	assert.Equal(t, map[v1.ResourceName]string{
+		"pods":            "3",
+		"configmaps":      "1",
+		"cpu":             "1500m",
+		"requests.cpu":    "1500m",
+		"memory":          "576Mi",
+		"requests.memory": "576Mi",
+		"limits.memory":   "960Mi",
+	}, quantities(estimates["prod"]))
+	assert.Equal(t, []string{"Deployment web"}, estimates["prod"].unset["limits.cpu"])
+	assert.Equal(t, []string{"Deployment web"}, estimates["prod"].unset["requests.memory"], "the init container requests no memory")
+
+	assert.Equal(t, map[v1.ResourceName]string{
+		"pods":                   "2",
+		"persistentvolumeclaims": "2",
+		"requests.storage":       "20Gi",
+		"cpu":                    "2",
+		"requests.cpu":           "2",
+		"memory":                 "2Gi",
+		"requests.memory":        "2Gi",
+	}, quantities(estimates["data"]))
+}
+
+func TestCheckQuotas(t *testing.T) {
+	estimates, err := estimateQuota(quotaManifest, "prod")
+	require.NoError(t, err)
+
+	newQuota := func(ns string, hard, used v1.ResourceList) *v1.ResourceQuota {
+		return &v1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: ns},
+			Spec:       v1.ResourceQuotaSpec{Hard: hard},
+			Status:     v1.ResourceQuotaStatus{Hard: hard, Used: used},
+		}
+	}
+	client := fakeclientset.NewClientset(
+		newQuota("prod",
+			v1.ResourceList{"requests.cpu": resource.MustParse("2"), "pods": resource.MustParse("10")},
+			v1.ResourceList{"requests.cpu": resource.MustParse("1"), "pods": resource.MustParse("2")}),
+		newQuota("data",
+			v1.ResourceList{"requests.storage": resource.MustParse("100Gi"), "limits.cpu": resource.MustParse("4")},
+			nil),
+	)
+
+	var warnings []string
+	warn := func(kind WarningKind, msg string) {
+		assert.Equal(t, WarningQuota, kind)
+		warnings = append(warnings, msg)
+	}
+	err = checkQuotas(context.Background(), client, estimates, warn)
+	require.Error(t, err)
+	assert.Equal(t, `release does not fit in the resource quotas:
+  requests.cpu: the release needs at least 1500m, ResourceQuota "compute" in namespace "prod" has 1 left of 2`, err.Error())
+	assert.Equal(t, []string{
+		`ResourceQuota "compute" in namespace "data" constrains limits.cpu, which is not set for all the containers of StatefulSet db: their pods are rejected unless a LimitRange sets a default`,
+	}, warnings)
+
+	delete(estimates, "prod")
+	warnings = nil
+	require.NoError(t, checkQuotas(context.Background(), client, estimates, warn))
+}
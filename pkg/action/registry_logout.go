@@ -23,6 +23,9 @@ import (
 // RegistryLogout performs a registry login operation.
 type RegistryLogout struct {
 	cfg *Configuration
+
+	// All logs out of all the registries with stored credentials.
+	All bool
 }
 
 // NewRegistryLogout creates a new RegistryLogout object with the given configuration.
@@ -32,7 +35,11 @@ func NewRegistryLogout(cfg *Configuration) *RegistryLogout {
 	}
 }
 
-// Run executes the registry logout operation
+// Run executes the registry logout operation. The hostname is ignored when
+// logging out of all registries.
 func (a *RegistryLogout) Run(_ io.Writer, hostname string) error {
+	if a.All {
+		return a.cfg.RegistryClient.LogoutAll()
+	}
 	return a.cfg.RegistryClient.Logout(hostname)
 }
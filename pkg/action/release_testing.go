@@ -18,10 +18,12 @@ package action
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -58,12 +60,23 @@ func NewReleaseTesting(cfg *Configuration) *ReleaseTesting {
 
 // Run executes 'helm test' against the given release.
 func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
+	return r.RunWithContext(context.Background(), name)
+}
+
+// RunWithContext executes 'helm test' against the given release.
+//
+// The test fixtures of the release are created before its tests, and are
+// always deleted after them, even when the fixtures or the tests fail. When
+// the tests are cancelled through ctx, the next fixtures and tests do not
+// start, and the fixtures are deleted once the one running completes or
+// times out.
+func (r *ReleaseTesting) RunWithContext(ctx context.Context, name string) (*release.Release, error) {
 	if err := r.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
 
 	if err := chartutil.ValidateReleaseName(name); err != nil {
-		return nil, fmt.Errorf("releaseTest: Release name is invalid: %s", name)
+		return nil, classify(ErrInvalid, fmt.Errorf("releaseTest: Release name is invalid: %s", name))
 	}
 
 	// finds the non-deleted release with the given name
@@ -76,7 +89,7 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 	executingHooks := []*release.Hook{}
 	if len(r.Filters[ExcludeNameFilter]) != 0 {
 		for _, h := range rel.Hooks {
-			if slices.Contains(r.Filters[ExcludeNameFilter], h.Name) {
+			if slices.Contains(r.Filters[ExcludeNameFilter], h.Name) && !isFixture(h) {
 				skippedHooks = append(skippedHooks, h)
 			} else {
 				executingHooks = append(executingHooks, h)
@@ -87,7 +100,7 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 	if len(r.Filters[IncludeNameFilter]) != 0 {
 		executingHooks = nil
 		for _, h := range rel.Hooks {
-			if slices.Contains(r.Filters[IncludeNameFilter], h.Name) {
+			if slices.Contains(r.Filters[IncludeNameFilter], h.Name) || isFixture(h) {
 				executingHooks = append(executingHooks, h)
 			} else {
 				skippedHooks = append(skippedHooks, h)
@@ -96,8 +109,17 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 		rel.Hooks = executingHooks
 	}
 
+	fixtures := hooksOf(rel, release.HookTestFixture)
 	serverSideApply := rel.ApplyMethod == string(release.ApplyMethodServerSideApply)
-	if err := r.cfg.execHook(rel, release.HookTest, kube.StatusWatcherStrategy, r.Timeout, serverSideApply); err != nil {
+	// The fixtures are deleted once no hook is running, so that none is
+	// created after they are deleted.
+	err = r.runTests(ctx, rel, serverSideApply)
+	teardownErr := r.teardownFixtures(fixtures)
+	if ctx.Err() != nil {
+		// The release of cancelled tests is left as is.
+		return nil, errors.Join(ctx.Err(), teardownErr)
+	}
+	if err = errors.Join(err, teardownErr); err != nil {
 		rel.Hooks = append(skippedHooks, rel.Hooks...)
 		r.cfg.Releases.Update(rel)
 		return rel, err
@@ -107,6 +129,70 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 	return rel, r.cfg.Releases.Update(rel)
 }
 
+// runTests creates the test fixtures of a release, and then runs its tests.
+func (r *ReleaseTesting) runTests(ctx context.Context, rel *release.Release, serverSideApply bool) error {
+	if err := r.cfg.execHookWithContext(ctx, rel, release.HookTestFixture, kube.StatusWatcherStrategy, r.Timeout, serverSideApply); err != nil {
+		return fmt.Errorf("unable to create the test fixtures: %w", err)
+	}
+	return r.cfg.execHookWithContext(ctx, rel, release.HookTest, kube.StatusWatcherStrategy, r.Timeout, serverSideApply)
+}
+
+// teardownFixtures deletes the test fixtures, the heaviest first, whatever
+// their delete policies. The fixtures not created are ignored.
+func (r *ReleaseTesting) teardownFixtures(fixtures []*release.Hook) error {
+	if len(fixtures) == 0 {
+		return nil
+	}
+	var errs []error
+	var deleted kube.ResourceList
+	for i := len(fixtures) - 1; i >= 0; i-- {
+		h := fixtures[i]
+		// Never delete CustomResourceDefinitions, like the other hooks.
+		if h.Kind == "CustomResourceDefinition" {
+			continue
+		}
+		resources, err := r.cfg.KubeClient.Build(strings.NewReader(h.Manifest), false)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to build kubernetes object for deleting test fixture %s: %w", h.Path, err))
+			continue
+		}
+		if _, deleteErrs := r.cfg.KubeClient.Delete(resources); len(deleteErrs) > 0 {
+			errs = append(errs, fmt.Errorf("unable to delete test fixture %s: %w", h.Path, joinErrors(deleteErrs, "; ")))
+			continue
+		}
+		deleted = append(deleted, resources...)
+	}
+	if len(deleted) > 0 {
+		waiter, err := r.cfg.KubeClient.GetWaiter(kube.StatusWatcherStrategy)
+		if err == nil {
+			err = waiter.WaitForDelete(deleted, r.Timeout)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("test fixtures not deleted: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hooksOf returns the hooks of a release running on an event, in the order
+// they run.
+func hooksOf(rel *release.Release, event release.HookEvent) []*release.Hook {
+	var hooks []*release.Hook
+	for _, h := range rel.Hooks {
+		if slices.Contains(h.Events, event) {
+			hooks = append(hooks, h)
+		}
+	}
+	sort.Stable(hookByWeight(hooks))
+	return hooks
+}
+
+// isFixture reports whether a hook is a test fixture, which the filters of
+// the tests do not apply to.
+func isFixture(h *release.Hook) bool {
+	return slices.Contains(h.Events, release.HookTestFixture)
+}
+
 // GetPodLogs will write the logs for all test pods in the given release into
 // the given writer. These can be immediately output to the user or captured for
 // other uses
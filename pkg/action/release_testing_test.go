@@ -0,0 +1,165 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func testingHook(name string, event release.HookEvent, weight int) *release.Hook {
+	return &release.Hook{
+		Name: name,
+		Kind: "ConfigMap",
+		Path: "templates/" + name + ".yaml",
+		Manifest: fmt.Sprintf(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: %s
+  namespace: test
+`, name),
+		Weight: weight,
+		Events: []release.HookEvent{event},
+	}
+}
+
+func releaseTestingFixture(t *testing.T, kubeClient kube.Interface) *ReleaseTesting {
+	t.Helper()
+	rel := releaseStub()
+	rel.Hooks = []*release.Hook{
+		testingHook("test-1", release.HookTest, 0),
+		testingHook("fixture-2", release.HookTestFixture, 1),
+		testingHook("fixture-1", release.HookTestFixture, 0),
+	}
+	cfg := &Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   kubeClient,
+		Capabilities: common.DefaultCapabilities,
+	}
+	require.NoError(t, cfg.Releases.Create(rel))
+	client := NewReleaseTesting(cfg)
+	client.Timeout = time.Second
+	return client
+}
+
+func TestReleaseTestingFixtures(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		failOn  string
+		filters map[string][]string
+		wantErr string
+	}{
+		{name: "tests succeed"},
+		{name: "test fails", failOn: "test-1", wantErr: "Hook failed!"},
+		{name: "fixture fails", failOn: "fixture-2", wantErr: "unable to create the test fixtures"},
+		{name: "fixtures are not filtered", filters: map[string][]string{IncludeNameFilter: {"test-1"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := &HookFailingKubeClient{
+				kubefake.PrintingKubeClient{Out: io.Discard}, resource.Info{Name: tt.failOn, Namespace: "test"}, []resource.Info{},
+			}
+			client := releaseTestingFixture(t, kubeClient)
+			for k, v := range tt.filters {
+				client.Filters[k] = v
+			}
+
+			rel, err := client.Run("angry-panda")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NotNil(t, rel)
+
+			// The fixtures are deleted after the tests, the heaviest first.
+			require.GreaterOrEqual(t, len(kubeClient.deleteRecord), 2)
+			teardown := kubeClient.deleteRecord[len(kubeClient.deleteRecord)-2:]
+			assert.Equal(t, []resource.Info{
+				{Name: "fixture-2", Namespace: "test"},
+				{Name: "fixture-1", Namespace: "test"},
+			}, teardown)
+			// The fixtures are created before the tests, by weight.
+			assert.Equal(t, resource.Info{Name: "fixture-1", Namespace: "test"}, kubeClient.deleteRecord[0], "before-hook-creation of the first fixture")
+		})
+	}
+}
+
+// blockingKubeClient blocks the first fixture until it is released.
+type blockingKubeClient struct {
+	HookFailingKubeClient
+	started  chan struct{}
+	released chan struct{}
+}
+
+type blockingKubeWaiter struct {
+	kube.Waiter
+	client *blockingKubeClient
+}
+
+func (c *blockingKubeClient) GetWaiter(strategy kube.WaitStrategy) (kube.Waiter, error) {
+	waiter, err := c.HookFailingKubeClient.GetWaiter(strategy)
+	return &blockingKubeWaiter{Waiter: waiter, client: c}, err
+}
+
+func (w *blockingKubeWaiter) WatchUntilReady(resources kube.ResourceList, timeout time.Duration) error {
+	if resources[0].Name == "fixture-1" {
+		close(w.client.started)
+		<-w.client.released
+	}
+	return w.Waiter.WatchUntilReady(resources, timeout)
+}
+
+func TestReleaseTestingFixturesCancelled(t *testing.T) {
+	kubeClient := &blockingKubeClient{
+		HookFailingKubeClient: HookFailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}},
+		started:               make(chan struct{}),
+		released:              make(chan struct{}),
+	}
+	client := releaseTestingFixture(t, kubeClient)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-kubeClient.started
+		cancel()
+		close(kubeClient.released)
+	}()
+	rel, err := client.RunWithContext(ctx, "angry-panda")
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, rel)
+
+	// The fixtures are deleted once the running fixture completes, and no
+	// other fixture or test starts.
+	assert.Equal(t, []resource.Info{
+		{Name: "fixture-1", Namespace: "test"},
+		{Name: "fixture-2", Namespace: "test"},
+		{Name: "fixture-1", Namespace: "test"},
+	}, kubeClient.deleteRecord)
+}
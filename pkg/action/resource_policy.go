@@ -17,8 +17,7 @@ limitations under the License.
 package action
 
 import (
-	"strings"
-
+	"helm.sh/helm/v4/pkg/annotations"
 	"helm.sh/helm/v4/pkg/kube"
 	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
 )
@@ -36,8 +35,7 @@ func filterManifestsToKeep(manifests []releaseutil.Manifest) (keep, remaining []
 			continue
 		}
 
-		resourcePolicyType = strings.ToLower(strings.TrimSpace(resourcePolicyType))
-		if resourcePolicyType == kube.KeepPolicy {
+		if annotations.Normalize(resourcePolicyType) == kube.KeepPolicy {
 			keep = append(keep, m)
 		}
 
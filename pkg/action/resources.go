@@ -0,0 +1,108 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// Resources is the action for listing the live resources of a release.
+//
+// It provides the implementation of 'helm resources'.
+type Resources struct {
+	cfg *Configuration
+
+	// Version is the revision of the release whose resources are listed.
+	// The latest revision is used when it is 0.
+	Version int
+}
+
+// ReleaseResource is a resource of a release, as it currently exists in the
+// cluster.
+type ReleaseResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Namespace is empty for cluster scoped resources.
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Status is one of the kstatus statuses (InProgress, Failed, Current,
+	// Terminating, NotFound or Unknown).
+	Status string `json:"status"`
+	// Message is a human readable explanation of the status.
+	Message string `json:"message,omitempty"`
+	// Created is when the resource was created. It is zero for resources that
+	// do not exist.
+	Created time.Time `json:"created,omitzero"`
+}
+
+// NewResources creates a new Resources object with the given configuration.
+func NewResources(cfg *Configuration) *Resources {
+	return &Resources{
+		cfg: cfg,
+	}
+}
+
+// Run returns the resources recorded in the manifest of the release, in the
+// order of the manifest, with their live status. Resources missing from the
+// cluster are reported with the NotFound status, and the ones that cannot be
+// read with the Unknown status and the error as message.
+func (r *Resources) Run(name string) ([]ReleaseResource, error) {
+	if err := r.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	statusClient, ok := r.cfg.KubeClient.(kube.InterfaceStatus)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceStatus")
+	}
+
+	rel, err := r.cfg.releaseContent(name, r.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := r.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	if err != nil {
+		return nil, err
+	}
+	// The resources are read one by one, so that each is matched with its own
+	// live object, whatever its API group.
+	statuses, err := statusClient.Status(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ReleaseResource, 0, len(statuses))
+	for _, rs := range statuses {
+		rr := ReleaseResource{
+			APIVersion: rs.APIVersion,
+			Kind:       rs.Kind,
+			Namespace:  rs.Namespace,
+			Name:       rs.Name,
+			Status:     rs.Status,
+			Message:    rs.Message,
+		}
+		if !rs.Created.IsZero() {
+			rr.Created = rs.Created.UTC()
+		}
+		result = append(result, rr)
+	}
+	return result, nil
+}
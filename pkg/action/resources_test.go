@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v4/pkg/kube"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+)
+
+// statusKubeClient builds fixed resources and returns fixed statuses.
+type statusKubeClient struct {
+	kubefake.PrintingKubeClient
+	resources kube.ResourceList
+	statuses  []kube.ResourceStatus
+	err       error
+}
+
+func (c *statusKubeClient) Build(_ io.Reader, _ bool) (kube.ResourceList, error) {
+	return c.resources, nil
+}
+
+func (c *statusKubeClient) Status(_ kube.ResourceList) ([]kube.ResourceStatus, error) {
+	return c.statuses, c.err
+}
+
+func TestResources(t *testing.T) {
+	created := time.Date(2024, time.June, 1, 8, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	info := func(group, version, kind, namespace, name string) *resource.Info {
+		return &resource.Info{
+			Namespace: namespace,
+			Name:      name,
+			Mapping: &meta.RESTMapping{
+				GroupVersionKind: schema.GroupVersionKind{Group: group, Version: version, Kind: kind},
+			},
+		}
+	}
+
+	client := &statusKubeClient{
+		PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard},
+		resources: kube.ResourceList{
+			info("", "v1", "ConfigMap", "default", "app"),
+			info("apps", "v1", "Deployment", "default", "app"),
+			info("", "v1", "Secret", "default", "gone"),
+			info("example.com", "v1", "Widget", "default", "app"),
+		},
+		statuses: []kube.ResourceStatus{
+			{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "app", Status: "Current", Message: "Resource is always ready", Created: created},
+			{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app", Status: "InProgress", Message: "Ready: 1/2", Created: created},
+			{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "gone", Status: "NotFound", Message: "Resource not found"},
+			{APIVersion: "example.com/v1", Kind: "Widget", Namespace: "default", Name: "app", Status: "Unknown", Message: `widgets.example.com "app" is forbidden`},
+		},
+	}
+	config := actionConfigFixture(t)
+	config.KubeClient = client
+	rel := releaseStub()
+	rel.Name = "app"
+	require.NoError(t, config.Releases.Create(rel))
+
+	resources, err := NewResources(config).Run("app")
+	require.NoError(t, err)
+	assert.Equal(t, []ReleaseResource{
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "app", Status: "Current", Message: "Resource is always ready", Created: created.UTC()},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "default", Name: "app", Status: "InProgress", Message: "Ready: 1/2", Created: created.UTC()},
+		{APIVersion: "v1", Kind: "Secret", Namespace: "default", Name: "gone", Status: "NotFound", Message: "Resource not found"},
+		{APIVersion: "example.com/v1", Kind: "Widget", Namespace: "default", Name: "app", Status: "Unknown", Message: `widgets.example.com "app" is forbidden`},
+	}, resources)
+
+	_, err = NewResources(config).Run("missing")
+	assert.Error(t, err)
+
+	client.err = errors.New("cluster unreachable")
+	_, err = NewResources(config).Run("app")
+	assert.EqualError(t, err, "cluster unreachable")
+}
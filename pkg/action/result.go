@@ -0,0 +1,247 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+)
+
+// ResourceOperation is what an action did to a resource of a release.
+type ResourceOperation string
+
+const (
+	// ResourceCreated is a resource created by the action.
+	ResourceCreated ResourceOperation = "created"
+	// ResourceUpdated is a resource updated by the action.
+	ResourceUpdated ResourceOperation = "updated"
+	// ResourceDeleted is a resource deleted by the action.
+	ResourceDeleted ResourceOperation = "deleted"
+	// ResourceKept is a resource an uninstall did not delete because of its
+	// resource policy.
+	ResourceKept ResourceOperation = "kept"
+)
+
+// ResourceChange is a resource of a release an action applied to the cluster.
+type ResourceChange struct {
+	GroupVersionKind schema.GroupVersionKind `json:"gvk"`
+	Namespace        string                  `json:"namespace,omitempty"`
+	Name             string                  `json:"name"`
+	Operation        ResourceOperation       `json:"operation"`
+}
+
+func (c ResourceChange) String() string {
+	if c.Namespace == "" {
+		return fmt.Sprintf("%s %s/%s", c.Operation, c.GroupVersionKind.Kind, c.Name)
+	}
+	return fmt.Sprintf("%s %s/%s in %s", c.Operation, c.GroupVersionKind.Kind, c.Name, c.Namespace)
+}
+
+// HookRun is a hook an action ran.
+type HookRun struct {
+	Name  string            `json:"name"`
+	Kind  string            `json:"kind"`
+	Path  string            `json:"path"`
+	Event release.HookEvent `json:"event"`
+	Phase release.HookPhase `json:"phase"`
+	// Duration is the time from the creation of the resource of the hook to
+	// its completion.
+	Duration time.Duration `json:"duration"`
+	// Message is the reason of the failure of the hook.
+	Message string `json:"message,omitempty"`
+}
+
+// Result describes what an install, an upgrade, a rollback or an uninstall
+// did, so that embedders do not have to parse the logs to know it.
+type Result struct {
+	// Release is the release the action left, as returned by the action.
+	Release *release.Release `json:"release,omitempty"`
+	// Resources are the resources the action applied, in the order it
+	// applied them.
+	Resources []ResourceChange `json:"resources,omitempty"`
+	// Hooks are the hooks the action ran, in the order it ran them.
+	Hooks []HookRun `json:"hooks,omitempty"`
+	// Wait is the time the action waited for the resources to be ready, or
+	// to be deleted for an uninstall. Hooks are waited for separately.
+	Wait time.Duration `json:"wait"`
+	// Warnings are the issues the action ran into without failing, in the
+	// order it ran into them. They are also passed to the WarningFunc of the
+	// configuration as they occur.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// resultRecorder records the result of an action while it runs. Actions may
+// carry on in the background once their context is cancelled, so it is safe
+// for concurrent use.
+type resultRecorder struct {
+	mu      sync.Mutex
+	cfg     *Configuration
+	started time.Time
+	result  Result
+}
+
+// start resets the recorder for a new run of the action with the given
+// configuration, and records the warnings of the API server until finish.
+func (r *resultRecorder) start(cfg *Configuration) {
+	r.mu.Lock()
+	r.cfg = cfg
+	r.started = cfg.Now()
+	r.result = Result{}
+	r.mu.Unlock()
+	cfg.addRecorder(r)
+}
+
+// finish records the release the action left, and the hooks for the events
+// that the action ran.
+func (r *resultRecorder) finish(rel *release.Release, events ...release.HookEvent) {
+	r.cfg.removeRecorder(r)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rel == nil {
+		return
+	}
+	r.result.Release = rel
+	var hooks []*release.Hook
+	for _, h := range rel.Hooks {
+		if slices.Contains(events, h.LastRun.Event) && !h.LastRun.StartedAt.Before(r.started) {
+			hooks = append(hooks, h)
+		}
+	}
+	slices.SortStableFunc(hooks, func(a, b *release.Hook) int {
+		return a.LastRun.StartedAt.Compare(b.LastRun.StartedAt)
+	})
+	for _, h := range hooks {
+		run := HookRun{
+			Name:    h.Name,
+			Kind:    h.Kind,
+			Path:    h.Path,
+			Event:   h.LastRun.Event,
+			Phase:   h.LastRun.Phase,
+			Message: h.LastRun.Message,
+		}
+		if !h.LastRun.CompletedAt.IsZero() {
+			run.Duration = h.LastRun.CompletedAt.Sub(h.LastRun.StartedAt)
+		}
+		r.result.Hooks = append(r.result.Hooks, run)
+	}
+}
+
+// resources records the resources of a kube.Result.
+func (r *resultRecorder) resources(res *kube.Result) {
+	if res == nil {
+		return
+	}
+	r.resourceList(ResourceCreated, res.Created)
+	r.resourceList(ResourceUpdated, res.Updated)
+	r.resourceList(ResourceDeleted, res.Deleted)
+}
+
+// resourceList records an operation on resources.
+func (r *resultRecorder) resourceList(op ResourceOperation, resources kube.ResourceList) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, info := range resources {
+		change := ResourceChange{Namespace: info.Namespace, Name: info.Name, Operation: op}
+		switch {
+		case info.Mapping != nil:
+			change.GroupVersionKind = info.Mapping.GroupVersionKind
+		case info.Object != nil:
+			change.GroupVersionKind = info.Object.GetObjectKind().GroupVersionKind()
+		}
+		r.result.Resources = append(r.result.Resources, change)
+	}
+}
+
+// kept records the manifests an uninstall kept.
+func (r *resultRecorder) kept(manifests []releaseutil.Manifest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range manifests {
+		change := ResourceChange{GroupVersionKind: schema.FromAPIVersionAndKind(m.Head.Version, m.Head.Kind), Operation: ResourceKept}
+		if m.Head.Metadata != nil {
+			change.Name = m.Head.Metadata.Name
+		}
+		r.result.Resources = append(r.result.Resources, change)
+	}
+}
+
+// waited records the time spent waiting for the resources since start.
+func (r *resultRecorder) waited(start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result.Wait += r.cfg.clockOrDefault().Since(start)
+}
+
+// warn records a warning of the action and streams it. Warnings that were
+// already recorded are ignored.
+func (r *resultRecorder) warn(kind WarningKind, msg string) {
+	w := Warning{Kind: kind, Message: msg}
+	if r.record(w) && r.cfg != nil {
+		r.cfg.streamWarning(w)
+	}
+}
+
+// warnf is warn formatting the message, for the functions reporting their
+// issues with a printf-like function.
+func (r *resultRecorder) warnf(kind WarningKind) func(format string, v ...interface{}) {
+	return func(format string, v ...interface{}) {
+		r.warn(kind, fmt.Sprintf(format, v...))
+	}
+}
+
+// record records a warning, and reports whether it was not recorded yet.
+func (r *resultRecorder) record(w Warning) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slices.Contains(r.result.Warnings, w) {
+		return false
+	}
+	r.result.Warnings = append(r.result.Warnings, w)
+	return true
+}
+
+// merge records what another action run on behalf of the action did, such
+// as the rollback of a failed upgrade. Its warnings were already streamed.
+func (r *resultRecorder) merge(other *Result) {
+	r.mu.Lock()
+	r.result.Resources = append(r.result.Resources, other.Resources...)
+	r.result.Hooks = append(r.result.Hooks, other.Hooks...)
+	r.result.Wait += other.Wait
+	r.mu.Unlock()
+	for _, w := range other.Warnings {
+		r.record(w)
+	}
+}
+
+// get returns a copy of the recorded result.
+func (r *resultRecorder) get() *Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := r.result
+	res.Resources = slices.Clone(res.Resources)
+	res.Hooks = slices.Clone(res.Hooks)
+	res.Warnings = slices.Clone(res.Warnings)
+	return &res
+}
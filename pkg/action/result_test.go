@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+var dummyDeployment = ResourceChange{
+	GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+	Namespace:        "spaced",
+	Name:             "dummyName",
+}
+
+func withOperation(c ResourceChange, op ResourceOperation) ResourceChange {
+	c.Operation = op
+	return c
+}
+
+func TestInstallRelease_Result(t *testing.T) {
+	config := actionConfigFixtureWithDummyResources(t, createDummyResourceList(true))
+	instAction := installActionWithConfig(config)
+	instAction.WaitStrategy = "watcher"
+	rel, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	res := instAction.Result()
+	assert.Same(t, rel, res.Release)
+	assert.Equal(t, []ResourceChange{withOperation(dummyDeployment, ResourceUpdated)}, res.Resources)
+	require.Len(t, res.Hooks, 1)
+	assert.Equal(t, "test-cm", res.Hooks[0].Name)
+	assert.Equal(t, release.HookPostInstall, res.Hooks[0].Event)
+	assert.Equal(t, release.HookPhaseSucceeded, res.Hooks[0].Phase)
+	assert.Empty(t, res.Warnings)
+
+	// The result is a copy, and it is reset by the next run.
+	res.Resources = nil
+	assert.Len(t, instAction.Result().Resources, 1)
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	require.Error(t, err)
+	assert.Empty(t, instAction.Result().Resources)
+	assert.Empty(t, instAction.Result().Hooks)
+}
+
+func TestUpgradeRelease_Result(t *testing.T) {
+	config := actionConfigFixtureWithDummyResources(t, createDummyResourceList(true))
+	upAction := NewUpgrade(config)
+	upAction.Namespace = "spaced"
+
+	rel := releaseStub()
+	rel.Name = "result"
+	rel.Info.Status = release.StatusDeployed
+	require.NoError(t, config.Releases.Create(rel))
+
+	upgraded, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	res := upAction.Result()
+	assert.Same(t, upgraded, res.Release)
+	assert.Equal(t, []ResourceChange{withOperation(dummyDeployment, ResourceUpdated)}, res.Resources)
+	require.Len(t, res.Hooks, 1)
+	assert.Equal(t, release.HookPostUpgrade, res.Hooks[0].Event)
+}
+
+func TestRollbackRelease_Result(t *testing.T) {
+	config := actionConfigFixtureWithDummyResources(t, createDummyResourceList(true))
+	rel := releaseStub()
+	rel.Name = "result"
+	rel.Info.Status = release.StatusSuperseded
+	require.NoError(t, config.Releases.Create(rel))
+	current := releaseStub()
+	current.Name = "result"
+	current.Version = 2
+	require.NoError(t, config.Releases.Create(current))
+
+	rbAction := NewRollback(config)
+	rbAction.ServerSideApply = "auto"
+	require.NoError(t, rbAction.Run(rel.Name))
+
+	res := rbAction.Result()
+	require.NotNil(t, res.Release)
+	assert.Equal(t, 3, res.Release.Version)
+	assert.Equal(t, []ResourceChange{withOperation(dummyDeployment, ResourceUpdated)}, res.Resources)
+	assert.Empty(t, res.Hooks)
+}
+
+func TestUninstallRelease_Result(t *testing.T) {
+	unAction := uninstallAction(t)
+	unAction.KeepHistory = true
+
+	rel := releaseStub()
+	rel.Name = "keep-secret"
+	rel.Manifest = `{
+		"apiVersion": "v1",
+		"kind": "Secret",
+		"metadata": {
+		  "name": "secret",
+		  "annotations": {
+			"helm.sh/resource-policy": "keep"
+		  }
+		},
+		"type": "Opaque"
+	}`
+	require.NoError(t, unAction.cfg.Releases.Create(rel))
+	_, err := unAction.Run(rel.Name)
+	require.NoError(t, err)
+
+	res := unAction.Result()
+	require.NotNil(t, res.Release)
+	assert.Equal(t, release.StatusUninstalled, res.Release.Info.Status)
+	expected := ResourceChange{
+		GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		Name:             "secret",
+		Operation:        ResourceKept,
+	}
+	assert.Equal(t, []ResourceChange{expected}, res.Resources)
+	assert.Equal(t, "kept Secret/secret", expected.String())
+	require.Len(t, res.Hooks, 1)
+	assert.Equal(t, release.HookPreDelete, res.Hooks[0].Event)
+}
+
+func TestInstallRelease_ResultWarnings(t *testing.T) {
+	config := actionConfigFixture(t)
+	var streamed []Warning
+	config.WarningFunc = func(w Warning) { streamed = append(streamed, w) }
+	instAction := installActionWithConfig(config)
+
+	unknownHook := `kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: unknown-hook
+  annotations:
+    "helm.sh/hook": pre-everything
+`
+	ch := buildChartWithTemplates([]*common.File{
+		{Name: "templates/hello", Data: []byte("hello: world")},
+		{Name: "templates/unknown-hook", Data: []byte(unknownHook)},
+	}, withValues(map[string]interface{}{"image": "nginx"}))
+	_, err := instAction.Run(ch, map[string]interface{}{"image": map[string]interface{}{"tag": "1.0"}})
+	require.NoError(t, err)
+
+	warnings := instAction.Result().Warnings
+	require.Len(t, warnings, 2)
+	assert.Equal(t, WarningValues, warnings[0].Kind)
+	assert.Equal(t, "warning: skipped value for hello.image: Not a table.", warnings[0].Message)
+	assert.Equal(t, Warning{
+		Kind:    WarningSkippedResource,
+		Message: `skipping ConfigMap "unknown-hook" in hello/templates/unknown-hook: unknown hook "pre-everything"`,
+	}, warnings[1])
+	assert.Equal(t, warnings, streamed)
+}
+
+func TestAPIServerWarnings(t *testing.T) {
+	config := actionConfigFixture(t)
+	var streamed []Warning
+	config.WarningFunc = func(w Warning) { streamed = append(streamed, w) }
+	handler := apiWarningHandler{cfg: config}
+	deprecated := Warning{Kind: WarningAPIServer, Message: "batch/v1beta1 CronJob is deprecated"}
+
+	// The warnings are recorded in the results of the running actions, once.
+	var first, second resultRecorder
+	first.start(config)
+	second.start(config)
+	handler.HandleWarningHeader(299, "-", deprecated.Message)
+	handler.HandleWarningHeader(299, "-", deprecated.Message)
+	handler.HandleWarningHeader(199, "-", "not a warning")
+	first.finish(nil)
+	handler.HandleWarningHeader(299, "-", "after the first action")
+	second.finish(nil)
+
+	assert.Equal(t, []Warning{deprecated}, first.get().Warnings)
+	assert.Equal(t, []Warning{deprecated, {Kind: WarningAPIServer, Message: "after the first action"}}, second.get().Warnings)
+	assert.Equal(t, second.get().Warnings, streamed)
+
+	// They are still streamed when no action runs.
+	handler.HandleWarningHeader(299, "-", deprecated.Message)
+	assert.Len(t, streamed, 3)
+}
@@ -17,7 +17,7 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -26,6 +26,7 @@ import (
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/kube"
 	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
 )
 
 // Rollback is the action for rolling back to a given release.
@@ -54,55 +55,93 @@ type Rollback struct {
 	ServerSideApply string
 	CleanupOnFail   bool
 	MaxHistory      int // MaxHistory limits the maximum number of revisions saved per release
+	// DiffOptions control how Diff renders the changes.
+	DiffOptions DiffOptions
+
+	results resultRecorder
 }
 
 // NewRollback creates a new Rollback object with the given configuration.
 func NewRollback(cfg *Configuration) *Rollback {
 	return &Rollback{
-		cfg: cfg,
+		cfg:         cfg,
+		DiffOptions: DiffOptions{Context: DefaultDiffContext},
 	}
 }
 
 // Run executes 'helm rollback' against the given release.
 func (r *Rollback) Run(name string) error {
+	r.results.start(r.cfg)
+	rel, err := r.run(name)
+	r.results.finish(rel, release.HookPreRollback, release.HookPostRollback)
+	return err
+}
+
+// Result returns what the last run of the rollback did.
+func (r *Rollback) Result() *Result {
+	return r.results.get()
+}
+
+func (r *Rollback) run(name string) (*release.Release, error) {
 	if err := r.cfg.KubeClient.IsReachable(); err != nil {
-		return err
+		return nil, err
 	}
 
-	r.cfg.Releases.MaxHistory = r.MaxHistory
-
 	slog.Debug("preparing rollback", "name", name)
 	currentRelease, targetRelease, serverSideApply, err := r.prepareRollback(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !r.DryRun {
+		if err := r.cfg.emitEvent(context.Background(), EventPreRollback, targetRelease); err != nil {
+			return nil, err
+		}
+
 		slog.Debug("creating rolled back release", "name", name)
-		if err := r.cfg.Releases.Create(targetRelease); err != nil {
-			return err
+		if err := r.cfg.Releases.CreateWithMaxHistory(targetRelease, r.MaxHistory); err != nil {
+			return nil, err
 		}
 	}
 
 	slog.Debug("performing rollback", "name", name)
 	if _, err := r.performRollback(currentRelease, targetRelease, serverSideApply); err != nil {
-		return err
+		return targetRelease, err
 	}
 
 	if !r.DryRun {
 		slog.Debug("updating status for rolled back release", "name", name)
 		if err := r.cfg.Releases.Update(targetRelease); err != nil {
-			return err
+			return targetRelease, err
 		}
+		r.cfg.notifyEvent(context.Background(), EventPostRollback, targetRelease)
 	}
-	return nil
+	return targetRelease, nil
+}
+
+// Diff returns the changes rolling back the given release would make, without
+// making them.
+func (r *Rollback) Diff(name string) (*ReleaseDiff, error) {
+	if err := r.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	currentRelease, targetRelease, _, err := r.prepareRollback(name)
+	if err != nil {
+		return nil, err
+	}
+	targetRevision := r.Version
+	if targetRevision == 0 {
+		targetRevision = currentRelease.Version - 1
+	}
+	return r.cfg.diffRelease(currentRelease, targetRelease, targetRevision, r.DiffOptions)
 }
 
 // prepareRollback finds the previous release and prepares a new release object with
 // the previous release's configuration
 func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Release, bool, error) {
 	if err := chartutil.ValidateReleaseName(name); err != nil {
-		return nil, nil, false, fmt.Errorf("prepareRollback: Release name is invalid: %s", name)
+		return nil, nil, false, classify(ErrInvalid, fmt.Errorf("prepareRollback: Release name is invalid: %s", name))
 	}
 
 	if r.Version < 0 {
@@ -134,7 +173,7 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 		}
 	}
 	if !previousVersionExist {
-		return nil, nil, false, fmt.Errorf("release has no %d version", previousVersion)
+		return nil, nil, false, classify(driver.ErrReleaseNotFound, fmt.Errorf("release has no %d version", previousVersion))
 	}
 
 	slog.Debug("rolling back", "name", name, "currentVersion", currentRelease.Version, "targetVersion", previousVersion)
@@ -157,18 +196,22 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 		Config:    previousRelease.Config,
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
-			LastDeployed:  time.Now(),
+			LastDeployed:  r.cfg.Now(),
 			Status:        release.StatusPendingRollback,
 			Notes:         previousRelease.Info.Notes,
+			DeployedBy:    r.cfg.Actor,
+			DeployedAs:    r.cfg.deployedAs(),
 			// Because we lose the reference to previous version elsewhere, we set the
 			// message here, and only override it later if we experience failure.
 			Description: fmt.Sprintf("Rollback to %d", previousVersion),
 		},
-		Version:     currentRelease.Version + 1,
-		Labels:      previousRelease.Labels,
-		Manifest:    previousRelease.Manifest,
-		Hooks:       previousRelease.Hooks,
-		ApplyMethod: string(determineReleaseSSApplyMethod(serverSideApply)),
+		Version:           currentRelease.Version + 1,
+		Labels:            previousRelease.Labels,
+		Manifest:          previousRelease.Manifest,
+		Hooks:             previousRelease.Hooks,
+		ApplyMethod:       string(determineReleaseSSApplyMethod(serverSideApply)),
+		ObjectLabels:      previousRelease.ObjectLabels,
+		IgnoreDifferences: previousRelease.IgnoreDifferences,
 	}
 
 	return currentRelease, targetRelease, serverSideApply, nil
@@ -180,15 +223,26 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		return targetRelease, nil
 	}
 
-	current, err := r.cfg.KubeClient.Build(bytes.NewBufferString(currentRelease.Manifest), false)
+	current, err := r.cfg.KubeClient.Build(strings.NewReader(currentRelease.Manifest), false)
 	if err != nil {
 		return targetRelease, fmt.Errorf("unable to build kubernetes objects from current release manifest: %w", err)
 	}
-	target, err := r.cfg.KubeClient.Build(bytes.NewBufferString(targetRelease.Manifest), false)
+	target, err := r.cfg.KubeClient.Build(strings.NewReader(targetRelease.Manifest), false)
 	if err != nil {
 		return targetRelease, fmt.Errorf("unable to build kubernetes objects from new release manifest: %w", err)
 	}
 
+	// It is safe to use "forceOwnership" here because these are resources currently rendered by the chart.
+	err = target.Visit(setMetadataVisitor(targetRelease.Name, targetRelease.Namespace, true, targetRelease.ObjectLabels))
+	if err != nil {
+		return targetRelease, fmt.Errorf("unable to set metadata visitor from target release: %w", err)
+	}
+	if !r.DisableHooks {
+		if err := r.cfg.checkHookLabels(targetRelease); err != nil {
+			return targetRelease, err
+		}
+	}
+
 	// pre-rollback hooks
 
 	if !r.DisableHooks {
@@ -199,11 +253,6 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		slog.Debug("rollback hooks disabled", "name", targetRelease.Name)
 	}
 
-	// It is safe to use "forceOwnership" here because these are resources currently rendered by the chart.
-	err = target.Visit(setMetadataVisitor(targetRelease.Name, targetRelease.Namespace, true))
-	if err != nil {
-		return targetRelease, fmt.Errorf("unable to set metadata visitor from target release: %w", err)
-	}
 	results, err := r.cfg.KubeClient.Update(
 		current,
 		target,
@@ -211,6 +260,7 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		kube.ClientUpdateOptionServerSideApply(serverSideApply, r.ForceConflicts),
 		kube.ClientUpdateOptionThreeWayMergeForUnstructured(false),
 		kube.ClientUpdateOptionUpgradeClientSideFieldManager(true))
+	r.results.resources(results)
 
 	if err != nil {
 		msg := fmt.Sprintf("Rollback %q failed: %s", targetRelease.Name, err)
@@ -222,7 +272,8 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		r.cfg.recordRelease(targetRelease)
 		if r.CleanupOnFail {
 			slog.Debug("cleanup on fail set, cleaning up resources", "count", len(results.Created))
-			_, errs := r.cfg.KubeClient.Delete(results.Created)
+			deleted, errs := r.cfg.KubeClient.Delete(results.Created)
+			r.results.resources(deleted)
 			if errs != nil {
 				return targetRelease, fmt.Errorf(
 					"an error occurred while cleaning up resources. original rollback error: %w",
@@ -237,8 +288,10 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	if err != nil {
 		return nil, fmt.Errorf("unable to set metadata visitor from target release: %w", err)
 	}
+	waitStart := r.cfg.Now()
 	if r.WaitForJobs {
 		if err := waiter.WaitWithJobs(target, r.Timeout); err != nil {
+			r.results.waited(waitStart)
 			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
 			r.cfg.recordRelease(currentRelease)
 			r.cfg.recordRelease(targetRelease)
@@ -246,6 +299,7 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		}
 	} else {
 		if err := waiter.Wait(target, r.Timeout); err != nil {
+			r.results.waited(waitStart)
 			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
 			r.cfg.recordRelease(currentRelease)
 			r.cfg.recordRelease(targetRelease)
@@ -253,6 +307,8 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		}
 	}
 
+	r.results.waited(waitStart)
+
 	// post-rollback hooks
 	if !r.DisableHooks {
 		if err := r.cfg.execHook(targetRelease, release.HookPostRollback, r.WaitStrategy, r.Timeout, serverSideApply); err != nil {
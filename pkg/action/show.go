@@ -19,8 +19,14 @@ package action
 import (
 	"bytes"
 	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/gosuri/uitable"
 	"k8s.io/cli-runtime/pkg/printers"
 	"sigs.k8s.io/yaml"
 
@@ -28,7 +34,9 @@ import (
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/cli"
 	"helm.sh/helm/v4/pkg/registry"
+	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
 // ShowOutputFormat is the format of the output of `helm show`
@@ -45,6 +53,9 @@ const (
 	ShowReadme ShowOutputFormat = "readme"
 	// ShowCRDs is the format which only shows the chart's CRDs
 	ShowCRDs ShowOutputFormat = "crds"
+	// ShowLicenses is the format which only shows the licenses of the chart
+	// and its dependencies
+	ShowLicenses ShowOutputFormat = "licenses"
 )
 
 var readmeFileNames = []string{"readme.md", "readme.txt", "readme"}
@@ -94,6 +105,11 @@ func (s *Show) Run(chartpath string) (string, error) {
 	}
 
 	var out strings.Builder
+	if s.OutputFormat == ShowLicenses {
+		fmt.Fprintln(&out, formatLicenses(chartutil.Licenses(s.chart)))
+		return out.String(), nil
+	}
+
 	if s.OutputFormat == ShowChart || s.OutputFormat == ShowAll {
 		fmt.Fprintf(&out, "%s\n", cf)
 	}
@@ -141,6 +157,184 @@ func (s *Show) Run(chartpath string) (string, error) {
 	return out.String(), nil
 }
 
+// RunWithoutChart shows the metadata, the values or the README of a chart
+// without downloading the whole chart. The metadata of charts in OCI
+// registries is read from their config, and their values and README from the
+// separate layers they were pushed with. For other charts they are read from
+// the beginning of the chart archive.
+//
+// It returns false when the output format needs the whole chart, the chart is
+// local or verified, or the requested content cannot be read on its own.
+func (s *Show) RunWithoutChart(name string, settings *cli.EnvSettings) (string, bool, error) {
+	name = strings.TrimSpace(name)
+	if s.Verify || s.OutputFormat == ShowAll || s.OutputFormat == ShowCRDs || s.OutputFormat == ShowLicenses {
+		return "", false, nil
+	}
+	if _, err := os.Stat(name); err == nil || filepath.IsAbs(name) || strings.HasPrefix(name, ".") {
+		return "", false, nil
+	}
+	if registry.IsOCI(name) {
+		if s.registryClient == nil {
+			return "", false, nil
+		}
+		return s.runFromLayers(name, settings)
+	}
+
+	dl, name, err := s.newChartDownloader(name, settings)
+	if err != nil {
+		return "", false, err
+	}
+	files, err := dl.FetchHead(name, strings.TrimSpace(s.Version))
+	if err != nil {
+		return "", false, err
+	}
+	for _, f := range files {
+		switch {
+		case s.OutputFormat == ShowChart && f.Name == chartutil.ChartfileName:
+			md := new(chart.Metadata)
+			if err := yaml.Unmarshal(f.Data, md); err != nil {
+				return "", false, err
+			}
+			out, err := s.showMetadata(md)
+			if err != nil {
+				return "", false, err
+			}
+			cv, err := dl.IndexEntry(name, strings.TrimSpace(s.Version))
+			if err != nil {
+				return "", false, err
+			}
+			return strings.TrimSuffix(out, "\n") + formatIndexEntry(cv) + "\n", true, nil
+		case s.OutputFormat == ShowValues && f.Name == chartutil.ValuesfileName:
+			out, err := s.showValues(f.Data)
+			return out, true, err
+		case s.OutputFormat == ShowReadme && isReadme(f.Name):
+			return fmt.Sprintf("%s\n", f.Data), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// runFromLayers shows the metadata, the values or the README of a chart in an
+// OCI registry without pulling the chart layer.
+func (s *Show) runFromLayers(ref string, settings *cli.EnvSettings) (string, bool, error) {
+	if s.OutputFormat == ShowChart {
+		dl, ref, err := s.newChartDownloader(ref, settings)
+		if err != nil {
+			return "", false, err
+		}
+		md, err := dl.FetchMetadata(ref, strings.TrimSpace(s.Version))
+		if err != nil {
+			return "", false, err
+		}
+		out, err := s.showMetadata(md)
+		return out, true, err
+	}
+
+	var opt registry.PullOption
+	switch s.OutputFormat {
+	case ShowValues:
+		opt = registry.PullOptWithValues(true)
+	case ShowReadme:
+		opt = registry.PullOptWithReadme(true)
+	default:
+		return "", false, nil
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false, err
+	}
+	_, u, err = s.registryClient.ValidateReference(ref, s.Version, u)
+	if err != nil {
+		return "", false, err
+	}
+	result, err := s.registryClient.Pull(u.Host+u.Path, registry.PullOptWithChart(false), opt)
+	if err != nil {
+		return "", false, err
+	}
+
+	switch {
+	case s.OutputFormat == ShowValues && result.Values != nil:
+		out, err := s.showValues(result.Values.Data)
+		return out, true, err
+	case s.OutputFormat == ShowReadme && result.Readme != nil:
+		return fmt.Sprintf("%s\n", result.Readme.Data), true, nil
+	}
+	return "", false, nil
+}
+
+func (s *Show) showMetadata(md *chart.Metadata) (string, error) {
+	cf, err := yaml.Marshal(md)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n", cf), nil
+}
+
+// formatIndexEntry formats the digests and the provenance file of a chart in
+// the index of its repository as YAML comments, for the metadata of the chart
+// shown before them to be kept as is.
+func formatIndexEntry(cv *repo.ChartVersion) string {
+	if cv == nil {
+		return ""
+	}
+	var out strings.Builder
+	switch {
+	case len(cv.Digests) > 0:
+		fmt.Fprintln(&out, "# digests:")
+		for _, artifact := range slices.Sorted(maps.Keys(cv.Digests)) {
+			fmt.Fprintf(&out, "#   %s: %s\n", artifact, cv.Digests[artifact])
+		}
+	case cv.Digest != "":
+		fmt.Fprintf(&out, "# digest: %s\n", cv.Digest)
+	}
+	if cv.Signed() {
+		fmt.Fprintf(&out, "# provenance: %s\n", cv.Provenance)
+	}
+	return out.String()
+}
+
+func (s *Show) showValues(data []byte) (string, error) {
+	if s.JSONPathTemplate == "" {
+		return string(data) + "\n", nil
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return "", err
+	}
+	printer, err := printers.NewJSONPathPrinter(s.JSONPathTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing jsonpath %s: %w", s.JSONPathTemplate, err)
+	}
+	var out strings.Builder
+	printer.Execute(&out, values)
+	return out.String(), nil
+}
+
+// formatLicenses returns a table of the licenses of a chart and its
+// dependencies.
+func formatLicenses(licenses []chartutil.License) string {
+	table := uitable.New()
+	table.AddRow("CHART", "VERSION", "LICENSE", "FILES")
+	for _, l := range licenses {
+		expr := l.Expression
+		if expr == "" {
+			expr = "unknown"
+		}
+		table.AddRow(l.Chart, l.Version, expr, strings.Join(l.Files, ", "))
+	}
+	return table.String()
+}
+
+func isReadme(name string) bool {
+	for _, n := range readmeFileNames {
+		if strings.EqualFold(name, n) {
+			return true
+		}
+	}
+	return false
+}
+
 func findReadme(files []*common.File) (file *common.File) {
 	for _, file := range files {
 		for _, n := range readmeFileNames {
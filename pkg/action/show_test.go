@@ -17,10 +17,14 @@ limitations under the License.
 package action
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v4/pkg/chart/common"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/repo/v1/repotest"
 )
 
 func TestShow(t *testing.T) {
@@ -164,3 +168,89 @@ bar
 		t.Errorf("Expected\n%q\nGot\n%q\n", expect, output)
 	}
 }
+
+func TestShowRunWithoutChartNeedsChart(t *testing.T) {
+	config := actionConfigFixture(t)
+
+	for _, tt := range []struct {
+		format ShowOutputFormat
+		ref    string
+	}{
+		{ShowValues, "testdata/charts/chart-with-schema"},
+		{ShowChart, "./missing"},
+		{ShowAll, "oci://localhost:5000/charts/alpine"},
+		{ShowCRDs, "repo/alpine"},
+	} {
+		client := NewShow(tt.format, config)
+		_, ok, err := client.RunWithoutChart(tt.ref, cli.New())
+		if err != nil || ok {
+			t.Errorf("%s %s: expected the chart to be needed, got %t, %v", tt.format, tt.ref, ok, err)
+		}
+	}
+}
+
+func TestShowRunWithoutChart(t *testing.T) {
+	srv := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("../downloader/testdata/signtest-0.1.0.tgz"),
+	)
+	defer srv.Stop()
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := cli.New()
+	settings.RepositoryConfig = filepath.Join(t.TempDir(), "repositories.yaml")
+	settings.RepositoryCache = t.TempDir()
+
+	for _, tt := range []struct {
+		format ShowOutputFormat
+		expect string
+	}{
+		{ShowChart, "apiVersion: v1\ndescription: A Helm chart for Kubernetes\nname: signtest\nversion: 0.1.0\n\n"},
+		{ShowValues, "{}\n\n"},
+	} {
+		client := NewShow(tt.format, actionConfigFixture(t))
+		client.RepoURL = srv.URL()
+		output, ok, err := client.RunWithoutChart("signtest", settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("%s: expected the chart not to be needed", tt.format)
+		}
+		if output != tt.expect {
+			t.Errorf("%s: expected\n%q\nGot\n%q", tt.format, tt.expect, output)
+		}
+	}
+}
+
+func TestShowRunWithoutChartIndexEntry(t *testing.T) {
+	srv := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("../downloader/testdata/signtest-0.1.0.tgz*"),
+	)
+	defer srv.Stop()
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := cli.New()
+	settings.RepositoryConfig = filepath.Join(srv.Root(), "repositories.yaml")
+	settings.RepositoryCache = srv.Root()
+
+	client := NewShow(ShowChart, actionConfigFixture(t))
+	output, ok, err := client.RunWithoutChart("test/signtest", settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected the chart not to be needed")
+	}
+	prefix := "apiVersion: v1\ndescription: A Helm chart for Kubernetes\nname: signtest\nversion: 0.1.0\n# digests:\n#   chart: sha256:"
+	suffix := "\n# provenance: " + srv.URL() + "/signtest-0.1.0.tgz.prov\n\n"
+	if !strings.HasPrefix(output, prefix) || !strings.HasSuffix(output, suffix) || !strings.Contains(output, "\n#   provenance: sha256:") {
+		t.Errorf("expected the digests and the provenance file of the index entry, got\n%s", output)
+	}
+}
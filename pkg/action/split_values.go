@@ -0,0 +1,265 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/common/util"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/engine"
+)
+
+// defaultSplitValuesDir is the directory of a chart the values files of its
+// dependencies are written to by default.
+const defaultSplitValuesDir = "values"
+
+// SplitValues is the action for splitting the values.yaml file of an umbrella
+// chart into a values file for each of its dependencies.
+//
+// It provides the implementation of 'helm dependency split-values'.
+type SplitValues struct {
+	// Dir is the directory, relative to the chart, the values files of the
+	// dependencies are written to. It defaults to "values".
+	Dir string
+	// DryRun computes and validates the split without writing it.
+	DryRun bool
+}
+
+// SplitValuesResult is the result of splitting the values of a chart.
+type SplitValuesResult struct {
+	// Files are the contents of the files of the split, keyed by their path
+	// relative to the chart, with forward slashes: the values.yaml file, and
+	// the values files of the dependencies.
+	Files map[string][]byte
+	// ValuesFiles are the values files of the dependencies, keyed by the name
+	// the dependencies are loaded under.
+	ValuesFiles map[string]string
+}
+
+// NewSplitValues creates a new SplitValues object.
+func NewSplitValues() *SplitValues {
+	return &SplitValues{Dir: defaultSplitValuesDir}
+}
+
+// Run splits the values.yaml file of the chart at chartpath, which must be a
+// chart directory. The values the chart has for each of its dependencies are
+// moved to a values file of the dependency, set as its valuesFile in
+// Chart.yaml, while the values of the chart itself and the globals stay in
+// values.yaml. The values of a dependency that already has a values file are
+// merged into it.
+//
+// The chart is rendered before and after the split, and the split is only
+// written when both renders are identical. values.yaml and Chart.yaml are
+// rewritten, so their comments are not kept.
+func (s *SplitValues) Run(chartpath string) (*SplitValuesResult, error) {
+	if fi, err := os.Stat(chartpath); err != nil {
+		return nil, err
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a chart directory: the values of packaged charts cannot be split", chartpath)
+	}
+	dir := s.Dir
+	if dir == "" {
+		dir = defaultSplitValuesDir
+	}
+	dir = path.Clean(filepath.ToSlash(dir))
+	if !filepath.IsLocal(filepath.FromSlash(dir)) {
+		return nil, fmt.Errorf("the values directory %s is outside of the chart", s.Dir)
+	}
+
+	c, err := loader.Load(chartpath)
+	if err != nil {
+		return nil, err
+	}
+	split := chartutil.SplitValues(c, c.Values)
+	if len(split.Dependencies) == 0 {
+		return nil, errors.New("the chart has no values for its dependencies")
+	}
+
+	result := &SplitValuesResult{
+		Files:       make(map[string][]byte),
+		ValuesFiles: make(map[string]string),
+	}
+	for _, dep := range c.Metadata.Dependencies {
+		name := dep.Name
+		if dep.Alias != "" {
+			name = dep.Alias
+		}
+		vals, ok := split.Dependencies[name]
+		if !ok {
+			continue
+		}
+		file := path.Join(dir, name+".yaml")
+		if dep.ValuesFile != "" {
+			// The values of the parent chart for the dependency take
+			// precedence over its values file.
+			file = path.Clean(filepath.ToSlash(dep.ValuesFile))
+			existing, err := chartFileValues(c, file)
+			if err != nil {
+				return nil, fmt.Errorf("dependency %s: %w", name, err)
+			}
+			vals = util.MergeTables(vals, existing)
+		} else if _, err := os.Stat(filepath.Join(chartpath, filepath.FromSlash(file))); err == nil {
+			return nil, fmt.Errorf("dependency %s: %s already exists", name, file)
+		}
+		data, err := yaml.Marshal(vals)
+		if err != nil {
+			return nil, err
+		}
+		result.Files[file] = data
+		result.ValuesFiles[name] = file
+	}
+	data, err := yaml.Marshal(split.Values)
+	if err != nil {
+		return nil, err
+	}
+	result.Files[chartutil.ValuesfileName] = data
+
+	if err := validateSplitValues(chartpath, result); err != nil {
+		return nil, err
+	}
+	if s.DryRun {
+		return result, nil
+	}
+
+	for name, data := range result.Files {
+		file := filepath.Join(chartpath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return nil, err
+		}
+	}
+	applyValuesFiles(c.Metadata, result.ValuesFiles)
+	if err := chartutil.SaveChartfile(filepath.Join(chartpath, chartutil.ChartfileName), c.Metadata); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// chartFileValues reads the values of a values file of a chart.
+func chartFileValues(c *chart.Chart, name string) (map[string]interface{}, error) {
+	for _, f := range c.Files {
+		if f.Name == name {
+			return common.ReadValues(f.Data)
+		}
+	}
+	return nil, fmt.Errorf("values file %s not found", name)
+}
+
+// applyValuesFiles sets the values files of the dependencies of a chart.
+func applyValuesFiles(md *chart.Metadata, files map[string]string) {
+	for _, dep := range md.Dependencies {
+		name := dep.Name
+		if dep.Alias != "" {
+			name = dep.Alias
+		}
+		if file, ok := files[name]; ok {
+			dep.ValuesFile = file
+		}
+	}
+}
+
+// validateSplitValues checks that the chart at chartpath renders the same
+// with the split of its values as it does without.
+func validateSplitValues(chartpath string, result *SplitValuesResult) error {
+	before, err := renderForSplit(chartpath, nil)
+	if err != nil {
+		return fmt.Errorf("unable to render the chart: %w", err)
+	}
+	after, err := renderForSplit(chartpath, func(c *chart.Chart) error {
+		for name, data := range result.Files {
+			if name == chartutil.ValuesfileName {
+				vals, err := common.ReadValues(data)
+				if err != nil {
+					return err
+				}
+				c.Values = vals
+				continue
+			}
+			c.Files = slices.DeleteFunc(c.Files, func(f *common.File) bool { return f.Name == name })
+			c.Files = append(c.Files, &common.File{Name: name, Data: data})
+		}
+		applyValuesFiles(c.Metadata, result.ValuesFiles)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to render the chart with the split values: %w", err)
+	}
+
+	var differ []string
+	for name, out := range before {
+		if other, ok := after[name]; !ok || other != out {
+			differ = append(differ, name)
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			differ = append(differ, name)
+		}
+	}
+	if len(differ) > 0 {
+		slices.Sort(differ)
+		return fmt.Errorf("the chart renders differently with the split values, the values were not split:\n  %s", strings.Join(differ, "\n  "))
+	}
+	return nil
+}
+
+// renderForSplit loads and renders the chart at chartpath with its default
+// values, after applying modify to it.
+func renderForSplit(chartpath string, modify func(*chart.Chart) error) (map[string]string, error) {
+	c, err := loader.Load(chartpath)
+	if err != nil {
+		return nil, err
+	}
+	if modify != nil {
+		if err := modify(c); err != nil {
+			return nil, err
+		}
+	}
+	vals := map[string]interface{}{}
+	if err := chartutil.ProcessDependencies(c, vals); err != nil {
+		return nil, err
+	}
+	cvals, err := util.CoalesceValues(c, vals)
+	if err != nil {
+		return nil, err
+	}
+	options := common.ReleaseOptions{
+		Name:      "release-name",
+		Namespace: "default",
+		Revision:  1,
+		IsInstall: true,
+	}
+	valuesToRender, err := util.ToRenderValuesWithSchemaValidation(c, cvals, options, common.DefaultCapabilities.Copy(), true)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Render(c, valuesToRender)
+}
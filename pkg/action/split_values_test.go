@@ -0,0 +1,156 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+const splitSubchartTemplate = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .Release.Name }}-{{ .Chart.Name }}
+data:
+  size: {{ .Values.size | quote }}
+  user: {{ .Values.user | quote }}
+  env: {{ .Values.global.env | quote }}
+`
+
+// umbrellaChart saves an umbrella chart with a database aliased as "db" and
+// a cache, and returns its directory.
+func umbrellaChart(t *testing.T, values string, templates ...*common.File) string {
+	t.Helper()
+	sub := func(name string) *chart.Chart {
+		return &chart.Chart{
+			Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: name, Version: "1.0.0"},
+			Raw:      []*common.File{{Name: "values.yaml", Data: []byte("size: 64\nuser: admin\nglobal:\n  env: dev\n")}},
+			Values:   map[string]interface{}{"size": 64, "user": "admin", "global": map[string]interface{}{"env": "dev"}},
+			Templates: []*common.File{
+				{Name: "templates/configmap.yaml", Data: []byte(splitSubchartTemplate)},
+			},
+		}
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: chart.APIVersionV2,
+			Name:       "umbrella",
+			Version:    "0.1.0",
+			Dependencies: []*chart.Dependency{
+				{Name: "database", Version: "1.0.0", Alias: "db"},
+				{Name: "cache", Version: "1.0.0"},
+			},
+		},
+		Raw:       []*common.File{{Name: "values.yaml", Data: []byte(values)}},
+		Templates: templates,
+	}
+	c.AddDependency(sub("database"), sub("cache"))
+	dir := t.TempDir()
+	require.NoError(t, chartutil.SaveDir(c, dir))
+	return filepath.Join(dir, "umbrella")
+}
+
+const umbrellaValues = `replicas: 2
+global:
+  env: prod
+db:
+  user: app
+database:
+  user: ignored
+cache:
+  size: 128
+`
+
+func TestSplitValues(t *testing.T) {
+	chartPath := umbrellaChart(t, umbrellaValues)
+	before, err := renderForSplit(chartPath, nil)
+	require.NoError(t, err)
+	assert.Contains(t, before["umbrella/charts/db/templates/configmap.yaml"], `user: "app"`)
+	assert.Contains(t, before["umbrella/charts/cache/templates/configmap.yaml"], `env: "prod"`)
+
+	client := NewSplitValues()
+	client.DryRun = true
+	result, err := client.Run(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"db": "values/db.yaml", "cache": "values/cache.yaml"}, result.ValuesFiles)
+	assert.Equal(t, "user: app\n", string(result.Files["values/db.yaml"]))
+	assert.Equal(t, "size: 128\n", string(result.Files["values/cache.yaml"]))
+	assert.Equal(t, "database:\n  user: ignored\nglobal:\n  env: prod\nreplicas: 2\n", string(result.Files["values.yaml"]))
+	assert.NoDirExists(t, filepath.Join(chartPath, "values"), "a dry run must not write the split")
+
+	client.DryRun = false
+	_, err = client.Run(chartPath)
+	require.NoError(t, err)
+
+	c, err := loader.Load(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, "values/db.yaml", c.Metadata.Dependencies[0].ValuesFile)
+	assert.Equal(t, "values/cache.yaml", c.Metadata.Dependencies[1].ValuesFile)
+	assert.NotContains(t, c.Values, "db")
+	after, err := renderForSplit(chartPath, nil)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// The split chart has no values for its dependencies left.
+	_, err = client.Run(chartPath)
+	assert.EqualError(t, err, "the chart has no values for its dependencies")
+}
+
+func TestSplitValuesMergesValuesFiles(t *testing.T) {
+	chartPath := umbrellaChart(t, umbrellaValues)
+	chartfile := filepath.Join(chartPath, chartutil.ChartfileName)
+	md, err := chartutil.LoadChartfile(chartfile)
+	require.NoError(t, err)
+	md.Dependencies[1].ValuesFile = "cache-values.yaml"
+	require.NoError(t, chartutil.SaveChartfile(chartfile, md))
+	require.NoError(t, os.WriteFile(filepath.Join(chartPath, "cache-values.yaml"), []byte("size: 256\nuser: cache\n"), 0644))
+
+	result, err := NewSplitValues().Run(chartPath)
+	require.NoError(t, err)
+	assert.Equal(t, "cache-values.yaml", result.ValuesFiles["cache"])
+	assert.Equal(t, "size: 128\nuser: cache\n", string(result.Files["cache-values.yaml"]))
+}
+
+func TestSplitValuesValidation(t *testing.T) {
+	// A template listing the files of the chart renders differently once the
+	// values files of the dependencies are added.
+	chartPath := umbrellaChart(t, umbrellaValues, &common.File{
+		Name: "templates/files.yaml",
+		Data: []byte("files: {{ range $path, $_ := .Files.Glob \"values/*\" }}{{ $path }} {{ end }}\n"),
+	})
+	_, err := NewSplitValues().Run(chartPath)
+	assert.ErrorContains(t, err, "the chart renders differently with the split values, the values were not split:\n  umbrella/templates/files.yaml")
+	assert.NoDirExists(t, filepath.Join(chartPath, "values"))
+
+	chartPath = umbrellaChart(t, "replicas: 2\n")
+	_, err = NewSplitValues().Run(chartPath)
+	assert.EqualError(t, err, "the chart has no values for its dependencies")
+
+	client := NewSplitValues()
+	client.Dir = "../values"
+	_, err = client.Run(chartPath)
+	assert.ErrorContains(t, err, "outside of the chart")
+}
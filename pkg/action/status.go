@@ -17,8 +17,10 @@ limitations under the License.
 package action
 
 import (
-	"bytes"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
 	"helm.sh/helm/v4/pkg/kube"
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -35,6 +37,10 @@ type Status struct {
 	// ShowResourcesTable is used with ShowResources. When true this will cause
 	// the resulting objects to be retrieved as a kind=table.
 	ShowResourcesTable bool
+
+	// ShowResourceStatus causes the kstatus status of each resource in the
+	// release to be computed and recorded in the release info.
+	ShowResourceStatus bool
 }
 
 // NewStatus creates a new Status object with the given configuration.
@@ -58,12 +64,12 @@ func (s *Status) Run(name string) (*release.Release, error) {
 	if kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceResources); ok {
 		var resources kube.ResourceList
 		if s.ShowResourcesTable {
-			resources, err = kubeClient.BuildTable(bytes.NewBufferString(rel.Manifest), false)
+			resources, err = kubeClient.BuildTable(strings.NewReader(rel.Manifest), false)
 			if err != nil {
 				return nil, err
 			}
 		} else {
-			resources, err = s.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+			resources, err = s.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
 			if err != nil {
 				return nil, err
 			}
@@ -76,7 +82,83 @@ func (s *Status) Run(name string) (*release.Release, error) {
 
 		rel.Info.Resources = resp
 
+		if s.ShowResourceStatus {
+			if err := s.computeResourceStatuses(rel); err != nil {
+				return nil, err
+			}
+		}
+
 		return rel, nil
 	}
 	return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
 }
+
+// ResourceDelta lists the resources that differ between two revisions of a
+// release, as "Kind namespace/name".
+type ResourceDelta struct {
+	// FromRevision is the revision the resources are compared from.
+	FromRevision int `json:"fromRevision"`
+	// ToRevision is the revision the resources are compared to.
+	ToRevision int `json:"toRevision"`
+	// Added are the resources of ToRevision that FromRevision does not have.
+	Added []string `json:"added,omitempty"`
+	// Removed are the resources of FromRevision that ToRevision does not have.
+	Removed []string `json:"removed,omitempty"`
+	// Changed are the resources of both revisions whose manifest differs.
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Delta returns the resources added, removed and changed from the given
+// revision of a release to its current revision, as recorded in their stored
+// manifests, leaving out the fields the releases ignore the differences of.
+// The cluster is not consulted.
+func (s *Status) Delta(rel *release.Release) (*ResourceDelta, error) {
+	current, err := s.cfg.releaseContent(rel.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := compileIgnoreRules(slices.Concat(rel.IgnoreDifferences, current.IgnoreDifferences))
+	if err != nil {
+		return nil, err
+	}
+	from, err := manifestObjects(rel.Manifest, rel.Namespace, ignore...)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", rel.Version, err)
+	}
+	to, err := manifestObjects(current.Manifest, current.Namespace, ignore...)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d: %w", current.Version, err)
+	}
+	delta := &ResourceDelta{FromRevision: rel.Version, ToRevision: current.Version}
+	delta.Added, delta.Removed, delta.Changed = deltaObjects(from, to)
+	return delta, nil
+}
+
+// computeResourceStatuses records the kstatus status of each resource of the
+// release in its info.
+func (s *Status) computeResourceStatuses(rel *release.Release) error {
+	statusClient, ok := s.cfg.KubeClient.(kube.InterfaceStatus)
+	if !ok {
+		return errors.New("unable to get kubeClient with interface InterfaceStatus")
+	}
+	resources, err := s.cfg.KubeClient.Build(strings.NewReader(rel.Manifest), false)
+	if err != nil {
+		return err
+	}
+	statuses, err := statusClient.Status(resources)
+	if err != nil {
+		return err
+	}
+	rel.Info.ResourceStatuses = make([]release.ResourceStatus, 0, len(statuses))
+	for _, rs := range statuses {
+		rel.Info.ResourceStatuses = append(rel.Info.ResourceStatuses, release.ResourceStatus{
+			APIVersion: rs.APIVersion,
+			Kind:       rs.Kind,
+			Namespace:  rs.Namespace,
+			Name:       rs.Name,
+			Status:     rs.Status,
+			Message:    rs.Message,
+		})
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -38,16 +39,26 @@ import (
 type Uninstall struct {
 	cfg *Configuration
 
-	DisableHooks        bool
-	DryRun              bool
-	IgnoreNotFound      bool
-	KeepHistory         bool
+	DisableHooks   bool
+	DryRun         bool
+	IgnoreNotFound bool
+	KeepHistory    bool
+	// CompactHistory, with KeepHistory, deletes the revisions of the release
+	// other than the first, the last and the failed ones, and marks the kept
+	// revisions with the ArchivedLabel.
+	CompactHistory      bool
 	WaitStrategy        kube.WaitStrategy
 	DeletionPropagation string
 	Timeout             time.Duration
 	Description         string
+
+	results resultRecorder
 }
 
+// ArchivedLabel is the release label marking the revisions kept by an
+// uninstall compacting the history of a release.
+const ArchivedLabel = "helm.sh/archived"
+
 // NewUninstall creates a new Uninstall object with the given configuration.
 func NewUninstall(cfg *Configuration) *Uninstall {
 	return &Uninstall{
@@ -57,6 +68,22 @@ func NewUninstall(cfg *Configuration) *Uninstall {
 
 // Run uninstalls the given release.
 func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error) {
+	u.results.start(u.cfg)
+	res, err := u.run(name)
+	var rel *release.Release
+	if res != nil {
+		rel = res.Release
+	}
+	u.results.finish(rel, release.HookPreDelete, release.HookPostDelete)
+	return res, err
+}
+
+// Result returns what the last run of the uninstall did.
+func (u *Uninstall) Result() *Result {
+	return u.results.get()
+}
+
+func (u *Uninstall) run(name string) (*release.UninstallReleaseResponse, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
@@ -66,6 +93,10 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		return nil, err
 	}
 
+	if u.CompactHistory && !u.KeepHistory {
+		return nil, errors.New("compacting the history requires keeping the history")
+	}
+
 	if u.DryRun {
 		r, err := u.cfg.releaseContent(name, 0)
 		if err != nil {
@@ -107,9 +138,13 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		return nil, fmt.Errorf("the release named %q is already deleted", name)
 	}
 
+	if err := u.cfg.emitEvent(context.Background(), EventPreUninstall, rel); err != nil {
+		return nil, err
+	}
+
 	slog.Debug("uninstall: deleting release", "name", name)
 	rel.Info.Status = release.StatusUninstalling
-	rel.Info.Deleted = time.Now()
+	rel.Info.Deleted = u.cfg.Now()
 	rel.Info.Description = "Deletion in progress (or silently failed)"
 	res := &release.UninstallReleaseResponse{Release: rel}
 
@@ -139,9 +174,11 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	}
 	res.Info = kept
 
+	waitStart := u.cfg.Now()
 	if err := waiter.WaitForDelete(deletedResources, u.Timeout); err != nil {
 		errs = append(errs, err)
 	}
+	u.results.waited(waitStart)
 
 	if !u.DisableHooks {
 		serverSideApply := true
@@ -156,6 +193,9 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	} else {
 		rel.Info.Description = "Uninstallation complete"
 	}
+	if len(errs) == 0 {
+		u.cfg.notifyEvent(context.Background(), EventPostUninstall, rel)
+	}
 
 	if !u.KeepHistory {
 		slog.Debug("purge requested", "release", name)
@@ -176,6 +216,12 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		slog.Debug("uninstall: Failed to store updated release", slog.Any("error", err))
 	}
 
+	if u.CompactHistory {
+		if err := u.compactHistory(rels); err != nil {
+			errs = append(errs, fmt.Errorf("uninstall: Failed to compact the history: %w", err))
+		}
+	}
+
 	if len(errs) > 0 {
 		return res, fmt.Errorf("uninstallation completed with %d error(s): %w", len(errs), joinErrors(errs, "; "))
 	}
@@ -191,6 +237,28 @@ func (u *Uninstall) purgeReleases(rels ...*release.Release) error {
 	return nil
 }
 
+// compactHistory deletes the revisions of a release sorted by revision, except
+// the first, the last and the failed ones, which are marked as archived.
+func (u *Uninstall) compactHistory(rels []*release.Release) error {
+	for i, rel := range rels {
+		if i > 0 && i < len(rels)-1 && rel.Info.Status != release.StatusFailed {
+			slog.Debug("uninstall: compacting revision", "name", rel.Name, "revision", rel.Version)
+			if _, err := u.cfg.Releases.Delete(rel.Name, rel.Version); err != nil {
+				return err
+			}
+			continue
+		}
+		if rel.Labels == nil {
+			rel.Labels = map[string]string{}
+		}
+		rel.Labels[ArchivedLabel] = "true"
+		if err := u.cfg.Releases.Update(rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type joinedErrors struct {
 	errs []error
 	sep  string
@@ -230,6 +298,7 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 	}
 
 	filesToKeep, filesToDelete := filterManifestsToKeep(files)
+	u.results.kept(filesToKeep)
 	var kept string
 	for _, f := range filesToKeep {
 		kept += "[" + f.Head.Kind + "] " + f.Head.Metadata.Name + "\n"
@@ -245,11 +314,13 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 		return nil, "", []error{fmt.Errorf("unable to build kubernetes objects for delete: %w", err)}
 	}
 	if len(resources) > 0 {
+		var deleted *kube.Result
 		if kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDeletionPropagation); ok {
-			_, errs = kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.DeletionPropagation))
-			return resources, kept, errs
+			deleted, errs = kubeClient.DeleteWithPropagationPolicy(resources, parseCascadingFlag(u.DeletionPropagation))
+		} else {
+			deleted, errs = u.cfg.KubeClient.Delete(resources)
 		}
-		_, errs = u.cfg.KubeClient.Delete(resources)
+		u.results.resources(deleted)
 	}
 	return resources, kept, errs
 }
@@ -91,6 +91,58 @@ func TestUninstallRelease_deleteRelease(t *testing.T) {
 	is.Contains(res.Info, expected)
 }
 
+func TestUninstallRelease_CompactHistory(t *testing.T) {
+	unAction := uninstallAction(t)
+	unAction.DisableHooks = true
+	unAction.KeepHistory = true
+	unAction.CompactHistory = true
+
+	statuses := []release.Status{
+		release.StatusSuperseded,
+		release.StatusSuperseded,
+		release.StatusFailed,
+		release.StatusSuperseded,
+		release.StatusDeployed,
+	}
+	for i, status := range statuses {
+		rel := namedReleaseStub("compact", status)
+		rel.Version = i + 1
+		require.NoError(t, unAction.cfg.Releases.Create(rel))
+	}
+
+	_, err := unAction.Run("compact")
+	require.NoError(t, err)
+
+	hist, err := unAction.cfg.Releases.History("compact")
+	require.NoError(t, err)
+	var versions []int
+	for _, rel := range hist {
+		versions = append(versions, rel.Version)
+		assert.Equal(t, "true", rel.Labels[ArchivedLabel], "revision %d", rel.Version)
+	}
+	assert.ElementsMatch(t, []int{1, 3, 5}, versions)
+
+	// The name can only be reused by replacing the archived history.
+	instAction := installAction(t)
+	instAction.cfg = unAction.cfg
+	instAction.ReleaseName = "compact"
+	_, err = instAction.Run(buildChart(), nil)
+	assert.ErrorContains(t, err, "use --replace to install it again, continuing its history from revision 6")
+
+	instAction.Replace = true
+	rel, err := instAction.Run(buildChart(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 6, rel.Version)
+	assert.Equal(t, "Install complete, replacing the history up to revision 5", rel.Info.Description)
+}
+
+func TestUninstallRelease_CompactHistoryNeedsKeepHistory(t *testing.T) {
+	unAction := uninstallAction(t)
+	unAction.CompactHistory = true
+	_, err := unAction.Run("compact")
+	assert.Error(t, err)
+}
+
 func TestUninstallRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 
@@ -18,10 +18,12 @@ package action
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -113,6 +115,14 @@ type Upgrade struct {
 	// Description is the description of this operation
 	Description string
 	Labels      map[string]string
+	// ObjectLabels customizes the labels set on the objects of the release.
+	// Its fields take precedence over the annotations of the chart, and are
+	// merged with the ones of the last release. The extra labels and
+	// annotations set to "null" are removed.
+	ObjectLabels release.ObjectLabels
+	// IgnoreDifferences are the fields of the objects of the release left out
+	// of its diffs, in addition to the ones the chart annotations list.
+	IgnoreDifferences []release.IgnoreDifference
 	// PostRenderer is an optional post-renderer
 	//
 	// If this is non-nil, then after templates are rendered, they will be sent to the
@@ -126,8 +136,42 @@ type Upgrade struct {
 	Lock sync.Mutex
 	// Enable DNS lookups when rendering templates
 	EnableDNS bool
+	// ConfigChecksums annotates the pod templates of the workloads with the
+	// checksum of the ConfigMaps and Secrets of the release they reference,
+	// so that they roll out when their configuration changes.
+	ConfigChecksums bool
 	// TakeOwnership will skip the check for helm annotations and adopt all existing resources.
 	TakeOwnership bool
+	// CheckPermissions reviews whether the user is allowed to create, update
+	// and delete all the resources and hooks changed by the upgrade before
+	// upgrading, and fails listing all the missing permissions.
+	CheckPermissions bool
+	// SkipSetValidation disables checking that the values set by the --set
+	// family of flags among the ValuesSources are at paths of the default
+	// values or the values schema of the chart.
+	SkipSetValidation bool
+	// StrictSetValidation fails when values are set by the --set family of
+	// flags at paths unknown to the chart, which are only warned about
+	// otherwise, as charts may read values they do not declare.
+	StrictSetValidation bool
+	// ValuesSources are the sources the values passed to Run were merged
+	// from, lowest precedence first. Dry runs report, in the ValuesProvenance
+	// of the release, which of them, if any, each value comes from. Values
+	// reused from the current release are reported as such.
+	ValuesSources []common.ValuesSource
+	// LicensePolicy, when set, blocks upgrading to charts with dependencies
+	// under licenses it does not allow.
+	LicensePolicy *LicensePolicy
+	// AdvisoryPolicy, when set, blocks upgrading to charts affected by
+	// security advisories, or with dependencies that are.
+	AdvisoryPolicy *AdvisoryPolicy
+	// HealthChecks checks the health of the release once it is upgraded,
+	// failing the upgrade when the checks do not pass.
+	HealthChecks HealthChecks
+	// DiffOptions control how Diff renders the changes.
+	DiffOptions DiffOptions
+
+	results resultRecorder
 }
 
 type resultMessage struct {
@@ -136,10 +180,15 @@ type resultMessage struct {
 }
 
 // NewUpgrade creates a new Upgrade object with the given configuration.
+//
+// The fields of the returned action are set by the caller and are not
+// validated until the action runs. New code should prefer
+// NewUpgradeWithOptions, which validates the options up front.
 func NewUpgrade(cfg *Configuration) *Upgrade {
 	up := &Upgrade{
 		cfg:             cfg,
 		ServerSideApply: "auto",
+		DiffOptions:     DiffOptions{Context: DefaultDiffContext},
 	}
 	up.registryClient = cfg.RegistryClient
 
@@ -159,6 +208,18 @@ func (u *Upgrade) Run(name string, chart chart.Charter, vals map[string]interfac
 
 // RunWithContext executes the upgrade on the given release with context.
 func (u *Upgrade) RunWithContext(ctx context.Context, name string, ch chart.Charter, vals map[string]interface{}) (*release.Release, error) {
+	u.results.start(u.cfg)
+	rel, err := u.run(ctx, name, ch, vals)
+	u.results.finish(rel, release.HookPreUpgrade, release.HookPostUpgrade)
+	return rel, err
+}
+
+// Result returns what the last run of the upgrade did.
+func (u *Upgrade) Result() *Result {
+	return u.results.get()
+}
+
+func (u *Upgrade) run(ctx context.Context, name string, ch chart.Charter, vals map[string]interface{}) (*release.Release, error) {
 	if err := u.cfg.KubeClient.IsReachable(); err != nil {
 		return nil, err
 	}
@@ -180,7 +241,7 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, ch chart.Char
 	}
 
 	if err := chartutil.ValidateReleaseName(name); err != nil {
-		return nil, fmt.Errorf("release name is invalid: %s", name)
+		return nil, classify(ErrInvalid, fmt.Errorf("release name is invalid: %s", name))
 	}
 
 	slog.Debug("preparing upgrade", "name", name)
@@ -188,8 +249,9 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, ch chart.Char
 	if err != nil {
 		return nil, err
 	}
-
-	u.cfg.Releases.MaxHistory = u.MaxHistory
+	if err := u.cfg.emitEvent(ctx, EventPostRender, upgradedRelease); err != nil {
+		return nil, err
+	}
 
 	slog.Debug("performing update", "name", name)
 	res, err := u.performUpgrade(ctx, currentRelease, upgradedRelease, serverSideApply)
@@ -203,11 +265,29 @@ func (u *Upgrade) RunWithContext(ctx context.Context, name string, ch chart.Char
 		if err := u.cfg.Releases.Update(upgradedRelease); err != nil {
 			return res, err
 		}
+		u.cfg.notifyEvent(ctx, EventPostUpgrade, res)
 	}
 
 	return res, nil
 }
 
+// Diff returns the changes the upgrade to a release, as returned by a dry run
+// of the upgrade, makes to the deployed release and to the live state of its
+// resources.
+func (u *Upgrade) Diff(upgraded *release.Release) (*ReleaseDiff, error) {
+	if err := u.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	currentRelease, err := u.cfg.Releases.Deployed(upgraded.Name)
+	if err != nil {
+		// Upgrades of failed releases are diffed against the last one.
+		if currentRelease, err = u.cfg.Releases.Last(upgraded.Name); err != nil {
+			return nil, err
+		}
+	}
+	return u.cfg.diffRelease(currentRelease, upgraded, upgraded.Version, u.DiffOptions)
+}
+
 // isDryRun returns true if Upgrade is set to run as a DryRun
 func (u *Upgrade) isDryRun() bool {
 	if u.DryRun || u.DryRunOption == "client" || u.DryRunOption == "server" || u.DryRunOption == "true" {
@@ -259,15 +339,37 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chartv2.Chart, vals map[str
 		}
 	}
 
+	sources := u.ValuesSources
+	if !u.ResetValues && len(currentRelease.Config) > 0 && (u.ReuseValues || u.ResetThenReuseValues || len(vals) == 0) {
+		reused := common.ValuesSource{Name: fmt.Sprintf("values of revision %d", currentRelease.Version), Values: currentRelease.Config}
+		sources = append([]common.ValuesSource{reused}, sources...)
+	}
+
 	// determine if values will be reused
 	vals, err = u.reuseValues(chart, currentRelease, vals)
 	if err != nil {
 		return nil, nil, false, err
 	}
 
+	if !u.SkipSetValidation {
+		if err := checkSetPaths(chart, u.ValuesSources, u.StrictSetValidation, u.results.warn); err != nil {
+			return nil, nil, false, err
+		}
+	}
+
 	if err := chartutil.ProcessDependencies(chart, vals); err != nil {
 		return nil, nil, false, err
 	}
+	if u.LicensePolicy != nil {
+		if err := u.LicensePolicy.Check(chart); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	if u.AdvisoryPolicy != nil {
+		if err := u.AdvisoryPolicy.Check(chart, u.RepoURL); err != nil {
+			return nil, nil, false, err
+		}
+	}
 
 	// Increment revision count. This is passed to templates, and also stored on
 	// the release object.
@@ -284,9 +386,9 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chartv2.Chart, vals map[str
 	if err != nil {
 		return nil, nil, false, err
 	}
-	valuesToRender, err := util.ToRenderValuesWithSchemaValidation(chart, vals, options, caps, u.SkipSchemaValidation)
+	valuesToRender, err := util.ToRenderValuesWithWarnings(chart, vals, options, caps, u.SkipSchemaValidation, u.results.warnf(WarningValues))
 	if err != nil {
-		return nil, nil, false, err
+		return nil, nil, false, classify(ErrInvalid, err)
 	}
 
 	// Determine whether or not to interact with remote
@@ -295,7 +397,7 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chartv2.Chart, vals map[str
 		interactWithRemote = true
 	}
 
-	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret, renderOptions{ConfigChecksums: u.ConfigChecksums, Namespace: u.Namespace}, u.results.warn)
 	if err != nil {
 		return nil, nil, false, err
 	}
@@ -304,6 +406,15 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chartv2.Chart, vals map[str
 		return nil, nil, false, fmt.Errorf("user supplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels())
 	}
 
+	objectLabels, err := objectLabelsFor(chart, chartValues(valuesToRender), mergeObjectLabels(lastRelease.ObjectLabels, u.ObjectLabels))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	ignoreDifferences, err := ignoreDifferencesFor(chart, u.IgnoreDifferences)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
 	serverSideApply, err := getUpgradeServerSideValue(u.ServerSideApply, lastRelease.ApplyMethod)
 	if err != nil {
 		return nil, nil, false, err
@@ -319,26 +430,40 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chartv2.Chart, vals map[str
 		Config:    vals,
 		Info: &release.Info{
 			FirstDeployed: currentRelease.Info.FirstDeployed,
-			LastDeployed:  Timestamper(),
+			LastDeployed:  u.cfg.Now(),
 			Status:        release.StatusPendingUpgrade,
 			Description:   "Preparing upgrade", // This should be overwritten later.
+			DeployedBy:    u.cfg.Actor,
+			DeployedAs:    u.cfg.deployedAs(),
 		},
-		Version:     revision,
-		Manifest:    manifestDoc.String(),
-		Hooks:       hooks,
-		Labels:      mergeCustomLabels(lastRelease.Labels, u.Labels),
-		ApplyMethod: string(determineReleaseSSApplyMethod(serverSideApply)),
+		Version:           revision,
+		Manifest:          manifestDoc.String(),
+		Hooks:             hooks,
+		Labels:            mergeCustomLabels(lastRelease.Labels, u.Labels),
+		ApplyMethod:       string(determineReleaseSSApplyMethod(serverSideApply)),
+		ObjectLabels:      objectLabels,
+		IgnoreDifferences: ignoreDifferences,
 	}
 
 	if len(notesTxt) > 0 {
 		upgradedRelease.Info.Notes = notesTxt
 	}
+	// The health checks are resolved before the release is upgraded, for
+	// invalid checks not to fail it once upgraded.
+	if _, _, err := u.cfg.healthChecksFor(upgradedRelease, u.HealthChecks, true); err != nil {
+		return nil, nil, false, err
+	}
+	if u.isDryRun() {
+		if upgradedRelease.ValuesProvenance, err = util.ValuesProvenance(chart, vals, sources); err != nil {
+			return nil, nil, false, fmt.Errorf("unable to trace the values: %w", err)
+		}
+	}
 	err = validateManifest(u.cfg.KubeClient, manifestDoc.Bytes(), !u.DisableOpenAPIValidation)
 	return currentRelease, upgradedRelease, serverSideApply, err
 }
 
 func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedRelease *release.Release, serverSideApply bool) (*release.Release, error) {
-	current, err := u.cfg.KubeClient.Build(bytes.NewBufferString(originalRelease.Manifest), false)
+	current, err := u.cfg.KubeClient.Build(strings.NewReader(originalRelease.Manifest), false)
 	if err != nil {
 		// Checking for removed Kubernetes API error so can provide a more informative error message to the user
 		// Ref: https://github.com/helm/helm/issues/7219
@@ -349,16 +474,21 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		}
 		return upgradedRelease, fmt.Errorf("unable to build kubernetes objects from current release manifest: %w", err)
 	}
-	target, err := u.cfg.KubeClient.Build(bytes.NewBufferString(upgradedRelease.Manifest), !u.DisableOpenAPIValidation)
+	target, err := u.cfg.KubeClient.Build(strings.NewReader(upgradedRelease.Manifest), !u.DisableOpenAPIValidation)
 	if err != nil {
 		return upgradedRelease, fmt.Errorf("unable to build kubernetes objects from new release manifest: %w", err)
 	}
 
 	// It is safe to use force only on target because these are resources currently rendered by the chart.
-	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true))
+	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true, upgradedRelease.ObjectLabels))
 	if err != nil {
 		return upgradedRelease, err
 	}
+	if !u.DisableHooks {
+		if err := u.cfg.checkHookLabels(upgradedRelease); err != nil {
+			return upgradedRelease, err
+		}
+	}
 
 	// Do a basic diff using gvk + name to figure out what new resources are being created so we can validate they don't already exist
 	existingResources := make(map[string]bool)
@@ -377,7 +507,7 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 	if u.TakeOwnership {
 		toBeUpdated, err = requireAdoption(toBeCreated)
 	} else {
-		toBeUpdated, err = existingResourceConflict(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace)
+		toBeUpdated, err = existingResourceConflict(toBeCreated, upgradedRelease.Name, upgradedRelease.Namespace, upgradedRelease.ObjectLabels)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to continue with update: %w", err)
@@ -391,6 +521,21 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return nil
 	})
 
+	if u.CheckPermissions {
+		accesses := accessesFor("create", toBeCreated.Difference(toBeUpdated))
+		accesses = append(accesses, accessesFor("patch", target.Intersect(current))...)
+		// The adopted resources are patched, not created.
+		accesses = append(accesses, accessesFor("patch", toBeUpdated)...)
+		accesses = append(accesses, accessesFor("delete", current.Difference(target))...)
+		hookAccesses, err := u.cfg.hookAccesses(upgradedRelease.Hooks)
+		if err != nil {
+			return nil, err
+		}
+		if err := u.cfg.checkPermissions(ctx, append(accesses, hookAccesses...)); err != nil {
+			return nil, fmt.Errorf("unable to continue with update: %w", err)
+		}
+	}
+
 	// Run if it is a dry run
 	if u.isDryRun() {
 		slog.Debug("dry run for release", "name", upgradedRelease.Name)
@@ -402,8 +547,12 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return upgradedRelease, nil
 	}
 
+	if err := u.cfg.emitEvent(ctx, EventPreUpgrade, upgradedRelease); err != nil {
+		return nil, err
+	}
+
 	slog.Debug("creating upgraded release", "name", upgradedRelease.Name)
-	if err := u.cfg.Releases.Create(upgradedRelease); err != nil {
+	if err := u.cfg.Releases.CreateWithMaxHistory(upgradedRelease, u.MaxHistory); err != nil {
 		return nil, err
 	}
 	rChan := make(chan resultMessage)
@@ -469,6 +618,7 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		kube.ClientUpdateOptionForceReplace(u.ForceReplace),
 		kube.ClientUpdateOptionServerSideApply(serverSideApply, u.ForceConflicts),
 		kube.ClientUpdateOptionUpgradeClientSideFieldManager(upgradeClientSideFieldManager))
+	u.results.resources(results)
 	if err != nil {
 		u.cfg.recordRelease(originalRelease)
 		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
@@ -481,20 +631,25 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
 		return
 	}
+	waitStart := u.cfg.Now()
 	if u.WaitForJobs {
 		if err := waiter.WaitWithJobs(target, u.Timeout); err != nil {
+			u.results.waited(waitStart)
 			u.cfg.recordRelease(originalRelease)
 			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
 			return
 		}
 	} else {
 		if err := waiter.Wait(target, u.Timeout); err != nil {
+			u.results.waited(waitStart)
 			u.cfg.recordRelease(originalRelease)
 			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
 			return
 		}
 	}
 
+	u.results.waited(waitStart)
+
 	// post-upgrade hooks
 	if !u.DisableHooks {
 		if err := u.cfg.execHook(upgradedRelease, release.HookPostUpgrade, u.WaitStrategy, u.Timeout, serverSideApply); err != nil {
@@ -503,6 +658,11 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		}
 	}
 
+	if err := u.cfg.checkHealth(upgradedRelease, u.HealthChecks, true); err != nil {
+		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
+		return
+	}
+
 	originalRelease.Info.Status = release.StatusSuperseded
 	u.cfg.recordRelease(originalRelease)
 
@@ -524,7 +684,8 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 	u.cfg.recordRelease(rel)
 	if u.CleanupOnFail && len(created) > 0 {
 		slog.Debug("cleanup on fail set", "cleaning_resources", len(created))
-		_, errs := u.cfg.KubeClient.Delete(created)
+		deleted, errs := u.cfg.KubeClient.Delete(created)
+		u.results.resources(deleted)
 		if errs != nil {
 			return rel, fmt.Errorf(
 				"an error occurred while cleaning up resources. original upgrade error: %w: %w",
@@ -572,10 +733,12 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 		rollin.ForceConflicts = u.ForceConflicts
 		rollin.ServerSideApply = u.ServerSideApply
 		rollin.Timeout = u.Timeout
-		if rollErr := rollin.Run(rel.Name); rollErr != nil {
+		rollErr := rollin.Run(rel.Name)
+		u.results.merge(rollin.Result())
+		if rollErr != nil {
 			return rel, fmt.Errorf("an error occurred while rolling back the release. original upgrade error: %w: %w", err, rollErr)
 		}
-		return rel, fmt.Errorf("release %s failed, and has been rolled back due to rollback-on-failure being set: %w", rel.Name, err)
+		return rel, &RolledBackError{fmt.Errorf("release %s failed, and has been rolled back due to rollback-on-failure being set: %w", rel.Name, err)}
 	}
 
 	return rel, err
@@ -649,6 +812,33 @@ func mergeCustomLabels(current, desired map[string]string) map[string]string {
 	return labels
 }
 
+// mergeObjectLabels merges the labels of the objects of the last release with
+// the desired ones, as mergeCustomLabels does for the labels of the release.
+// The extra labels and annotations set to "null" are removed.
+func mergeObjectLabels(current *release.ObjectLabels, desired release.ObjectLabels) release.ObjectLabels {
+	if current == nil {
+		current = &release.ObjectLabels{}
+	}
+	merged := release.ObjectLabels{
+		ManagedBy:           cmp.Or(desired.ManagedBy, current.ManagedBy),
+		ReleaseNameKey:      cmp.Or(desired.ReleaseNameKey, current.ReleaseNameKey),
+		ReleaseNamespaceKey: cmp.Or(desired.ReleaseNamespaceKey, current.ReleaseNamespaceKey),
+		Required:            slices.Clone(current.Required),
+	}
+	if len(current.Extra) > 0 || len(desired.Extra) > 0 {
+		merged.Extra = mergeCustomLabels(current.Extra, desired.Extra)
+	}
+	if len(current.Annotations) > 0 || len(desired.Annotations) > 0 {
+		merged.Annotations = mergeCustomLabels(current.Annotations, desired.Annotations)
+	}
+	for _, key := range desired.Required {
+		if !slices.Contains(merged.Required, key) {
+			merged.Required = append(merged.Required, key)
+		}
+	}
+	return merged
+}
+
 func getUpgradeServerSideValue(serverSideOption string, releaseApplyMethod string) (bool, error) {
 	switch serverSideOption {
 	case "auto":
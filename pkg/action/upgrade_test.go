@@ -25,6 +25,7 @@ import (
 	"testing"
 	"time"
 
+	"helm.sh/helm/v4/pkg/chart/common"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/kube"
 	"helm.sh/helm/v4/pkg/storage/driver"
@@ -72,6 +73,21 @@ func TestUpgradeRelease_Success(t *testing.T) {
 	is.Equal(lastRelease.Info.Status, release.StatusDeployed)
 }
 
+func TestUpgradeRelease_Actor(t *testing.T) {
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	upAction.cfg.Actor = "bob"
+	rel := releaseStub()
+	rel.Name = "previous-release"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	assert.Equal(t, "bob", res.Info.DeployedBy)
+}
+
 func TestUpgradeRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 	req := require.New(t)
@@ -165,6 +181,8 @@ func TestUpgradeRelease_RollbackOnFailure(t *testing.T) {
 		req.Error(err)
 		is.Contains(err.Error(), "arming key removed")
 		is.Contains(err.Error(), "rollback-on-failure")
+		var rolledBack *RolledBackError
+		is.ErrorAs(err, &rolledBack)
 
 		// Now make sure it is actually upgraded
 		updatedRes, err := upAction.cfg.Releases.Get(res.Name, 3)
@@ -190,6 +208,8 @@ func TestUpgradeRelease_RollbackOnFailure(t *testing.T) {
 		req.Error(err)
 		is.Contains(err.Error(), "update fail")
 		is.Contains(err.Error(), "an error occurred while rolling back the release")
+		var rolledBack *RolledBackError
+		is.False(errors.As(err, &rolledBack))
 	})
 }
 
@@ -457,6 +477,33 @@ func TestMergeCustomLabels(t *testing.T) {
 	}
 }
 
+func TestMergeObjectLabels(t *testing.T) {
+	current := &release.ObjectLabels{
+		ManagedBy:   "platform",
+		Extra:       map[string]string{"team": "payments", "tier": "backend"},
+		Annotations: map[string]string{"example.com/owner": "payments"},
+		Required:    []string{"team"},
+	}
+	desired := release.ObjectLabels{
+		ReleaseNameKey: "example.com/release",
+		Extra:          map[string]string{"tier": "null", "environment": "prod"},
+		Required:       []string{"team", "environment"},
+	}
+
+	assert.Equal(t, release.ObjectLabels{
+		ManagedBy:      "platform",
+		ReleaseNameKey: "example.com/release",
+		Extra:          map[string]string{"team": "payments", "environment": "prod"},
+		Annotations:    map[string]string{"example.com/owner": "payments"},
+		Required:       []string{"team", "environment"},
+	}, mergeObjectLabels(current, desired))
+	assert.Equal(t, release.ObjectLabels{
+		ReleaseNameKey: "example.com/release",
+		Extra:          map[string]string{"environment": "prod"},
+		Required:       []string{"team", "environment"},
+	}, mergeObjectLabels(nil, desired))
+}
+
 func TestUpgradeRelease_Labels(t *testing.T) {
 	is := assert.New(t)
 	upAction := upgradeAction(t)
@@ -535,6 +582,30 @@ func TestUpgradeRelease_SystemLabels(t *testing.T) {
 	is.Equal(fmt.Errorf("user supplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels()), err)
 }
 
+func TestUpgradeRelease_DryRunValuesProvenance(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "previous-release"
+	rel.Info.Status = release.StatusDeployed
+	rel.Config = map[string]interface{}{"replicas": 2, "someKey": "reused"}
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	upAction.DryRun = true
+	upAction.ReuseValues = true
+	upAction.ValuesSources = []common.ValuesSource{
+		{Name: "--set someKey=fromSet", Values: map[string]interface{}{"someKey": "fromSet"}},
+	}
+	res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{"someKey": "fromSet"})
+	req.NoError(err)
+	is.Equal([]common.ValueOrigin{
+		{Path: "replicas", Value: 2, Source: "values of revision 1"},
+		{Path: "someKey", Value: "fromSet", Source: "--set someKey=fromSet"},
+	}, res.ValuesProvenance)
+}
+
 func TestUpgradeRelease_DryRun(t *testing.T) {
 	is := assert.New(t)
 	req := require.New(t)
@@ -19,13 +19,16 @@ package action
 import (
 	"fmt"
 	"maps"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/resource"
 
+	"helm.sh/helm/v4/pkg/annotations"
 	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
 var accessor = meta.NewAccessor()
@@ -33,8 +36,8 @@ var accessor = meta.NewAccessor()
 const (
 	appManagedByLabel              = "app.kubernetes.io/managed-by"
 	appManagedByHelm               = "Helm"
-	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
-	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+	helmReleaseNameAnnotation      = annotations.ReleaseName
+	helmReleaseNamespaceAnnotation = annotations.ReleaseNamespace
 )
 
 // requireAdoption returns the subset of resources that already exist in the cluster.
@@ -62,7 +65,7 @@ func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
 	return requireUpdate, err
 }
 
-func existingResourceConflict(resources kube.ResourceList, releaseName, releaseNamespace string) (kube.ResourceList, error) {
+func existingResourceConflict(resources kube.ResourceList, releaseName, releaseNamespace string, labels *release.ObjectLabels) (kube.ResourceList, error) {
 	var requireUpdate kube.ResourceList
 
 	err := resources.Visit(func(info *resource.Info, err error) error {
@@ -80,8 +83,8 @@ func existingResourceConflict(resources kube.ResourceList, releaseName, releaseN
 		}
 
 		// Allow adoption of the resource if it is managed by Helm and is annotated with correct release name and namespace.
-		if err := checkOwnership(existing, releaseName, releaseNamespace); err != nil {
-			return fmt.Errorf("%s exists and cannot be imported into the current release: %s", resourceString(info), err)
+		if err := checkOwnership(existing, releaseName, releaseNamespace, labels); err != nil {
+			return classify(ErrConflict, fmt.Errorf("%s exists and cannot be imported into the current release: %s", resourceString(info), err))
 		}
 
 		requireUpdate.Append(info)
@@ -91,7 +94,7 @@ func existingResourceConflict(resources kube.ResourceList, releaseName, releaseN
 	return requireUpdate, err
 }
 
-func checkOwnership(obj runtime.Object, releaseName, releaseNamespace string) error {
+func checkOwnership(obj runtime.Object, releaseName, releaseNamespace string, labels *release.ObjectLabels) error {
 	lbls, err := accessor.Labels(obj)
 	if err != nil {
 		return err
@@ -102,7 +105,7 @@ func checkOwnership(obj runtime.Object, releaseName, releaseNamespace string) er
 	}
 
 	var errs []error
-	if err := requireValue(lbls, appManagedByLabel, appManagedByHelm); err != nil {
+	if err := requireValue(lbls, appManagedByLabel, managedBy(labels)); err != nil {
 		errs = append(errs, fmt.Errorf("label validation error: %s", err))
 	}
 	if err := requireValue(annos, helmReleaseNameAnnotation, releaseName); err != nil {
@@ -130,34 +133,39 @@ func requireValue(meta map[string]string, k, v string) error {
 	return nil
 }
 
-// setMetadataVisitor adds release tracking metadata to all resources. If forceOwnership is enabled, existing
-// ownership metadata will be overwritten. Otherwise an error will be returned if any resource has an
-// existing and conflicting value for the managed by label or Helm release/namespace annotations.
-func setMetadataVisitor(releaseName, releaseNamespace string, forceOwnership bool) resource.VisitorFunc {
+// setMetadataVisitor adds release tracking metadata, and the labels customized by labels, to all resources.
+// If forceOwnership is enabled, existing ownership metadata will be overwritten. Otherwise an error will be
+// returned if any resource has an existing and conflicting value for the managed by label or Helm
+// release/namespace annotations.
+func setMetadataVisitor(releaseName, releaseNamespace string, forceOwnership bool, labels *release.ObjectLabels) resource.VisitorFunc {
 	return func(info *resource.Info, err error) error {
 		if err != nil {
 			return err
 		}
 
 		if !forceOwnership {
-			if err := checkOwnership(info.Object, releaseName, releaseNamespace); err != nil {
-				return fmt.Errorf("%s cannot be owned: %s", resourceString(info), err)
+			if err := checkOwnership(info.Object, releaseName, releaseNamespace, labels); err != nil {
+				return classify(ErrConflict, fmt.Errorf("%s cannot be owned: %s", resourceString(info), err))
 			}
 		}
 
-		if err := mergeLabels(info.Object, map[string]string{
-			appManagedByLabel: appManagedByHelm,
-		}); err != nil {
+		if err := mergeLabels(info.Object, releaseObjectLabels(labels, releaseName, releaseNamespace)); err != nil {
 			return fmt.Errorf(
 				"%s labels could not be updated: %s",
 				resourceString(info), err,
 			)
 		}
 
-		if err := mergeAnnotations(info.Object, map[string]string{
+		if missing, err := missingRequiredLabels(labels, info.Object); err != nil {
+			return err
+		} else if len(missing) > 0 {
+			return fmt.Errorf("%s is missing the required labels %s", resourceString(info), strings.Join(missing, ", "))
+		}
+
+		if err := mergeAnnotations(info.Object, mergeStrStrMaps(objectAnnotations(labels), map[string]string{
 			helmReleaseNameAnnotation:      releaseName,
 			helmReleaseNamespaceAnnotation: releaseNamespace,
-		}); err != nil {
+		})); err != nil {
 			return fmt.Errorf(
 				"%s annotations could not be updated: %s",
 				resourceString(info), err,
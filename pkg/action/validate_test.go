@@ -23,8 +23,10 @@ import (
 	"testing"
 
 	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -152,14 +154,14 @@ func TestExistingResourceConflict(t *testing.T) {
 	)
 
 	// Verify only existing resources are returned
-	found, err := existingResourceConflict(resources, releaseName, releaseNamespace)
+	found, err := existingResourceConflict(resources, releaseName, releaseNamespace, nil)
 	assert.NoError(t, err)
 	assert.Len(t, found, 1)
 	assert.Equal(t, found[0], existing)
 
 	// Verify that an existing resource that lacks labels/annotations results in an error
 	resources = append(resources, conflict)
-	_, err = existingResourceConflict(resources, releaseName, releaseNamespace)
+	_, err = existingResourceConflict(resources, releaseName, releaseNamespace, nil)
 	assert.Error(t, err)
 }
 
@@ -167,21 +169,21 @@ func TestCheckOwnership(t *testing.T) {
 	deployFoo := newDeploymentResource("foo", "ns-a")
 
 	// Verify that a resource that lacks labels/annotations is not owned
-	err := checkOwnership(deployFoo.Object, "rel-a", "ns-a")
+	err := checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; label validation error: missing key "app.kubernetes.io/managed-by": must be set to "Helm"; annotation validation error: missing key "meta.helm.sh/release-name": must be set to "rel-a"; annotation validation error: missing key "meta.helm.sh/release-namespace": must be set to "ns-a"`)
 
 	// Set managed by label and verify annotation error message
 	_ = accessor.SetLabels(deployFoo.Object, map[string]string{
 		appManagedByLabel: appManagedByHelm,
 	})
-	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a")
+	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; annotation validation error: missing key "meta.helm.sh/release-name": must be set to "rel-a"; annotation validation error: missing key "meta.helm.sh/release-namespace": must be set to "ns-a"`)
 
 	// Set only the release name annotation and verify missing release namespace error message
 	_ = accessor.SetAnnotations(deployFoo.Object, map[string]string{
 		helmReleaseNameAnnotation: "rel-a",
 	})
-	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a")
+	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; annotation validation error: missing key "meta.helm.sh/release-namespace": must be set to "ns-a"`)
 
 	// Set both release name and namespace annotations and verify no ownership errors
@@ -189,22 +191,22 @@ func TestCheckOwnership(t *testing.T) {
 		helmReleaseNameAnnotation:      "rel-a",
 		helmReleaseNamespaceAnnotation: "ns-a",
 	})
-	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a")
+	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil)
 	assert.NoError(t, err)
 
 	// Verify ownership error for wrong release name
-	err = checkOwnership(deployFoo.Object, "rel-b", "ns-a")
+	err = checkOwnership(deployFoo.Object, "rel-b", "ns-a", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; annotation validation error: key "meta.helm.sh/release-name" must equal "rel-b": current value is "rel-a"`)
 
 	// Verify ownership error for wrong release namespace
-	err = checkOwnership(deployFoo.Object, "rel-a", "ns-b")
+	err = checkOwnership(deployFoo.Object, "rel-a", "ns-b", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; annotation validation error: key "meta.helm.sh/release-namespace" must equal "ns-b": current value is "ns-a"`)
 
 	// Verify ownership error for wrong manager label
 	_ = accessor.SetLabels(deployFoo.Object, map[string]string{
 		appManagedByLabel: "helm",
 	})
-	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a")
+	err = checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil)
 	assert.EqualError(t, err, `invalid ownership metadata; label validation error: key "app.kubernetes.io/managed-by" must equal "Helm": current value is "helm"`)
 }
 
@@ -217,24 +219,72 @@ func TestSetMetadataVisitor(t *testing.T) {
 	)
 
 	// Set release tracking metadata and verify no error
-	err = resources.Visit(setMetadataVisitor("rel-a", "ns-a", true))
+	err = resources.Visit(setMetadataVisitor("rel-a", "ns-a", true, nil))
 	assert.NoError(t, err)
 
 	// Verify that release "b" cannot take ownership of "a"
-	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false))
+	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false, nil))
 	assert.Error(t, err)
 
 	// Force release "b" to take ownership
-	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", true))
+	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", true, nil))
 	assert.NoError(t, err)
 
 	// Check that there is now no ownership error when setting metadata without force
-	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false))
+	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false, nil))
 	assert.NoError(t, err)
 
 	// Add a new resource that is missing ownership metadata and verify error
 	resources.Append(newDeploymentResource("baz", "default"))
-	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false))
+	err = resources.Visit(setMetadataVisitor("rel-b", "ns-a", false, nil))
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), `Deployment "baz" in namespace "" cannot be owned`)
 }
+
+func TestSetMetadataVisitorObjectLabels(t *testing.T) {
+	deployFoo := newDeploymentResource("foo", "ns-a")
+	resources := kube.ResourceList{deployFoo}
+	labels := &release.ObjectLabels{
+		ManagedBy:           "platform",
+		ReleaseNameKey:      "example.com/release",
+		ReleaseNamespaceKey: "example.com/release-namespace",
+		Extra:               map[string]string{"team": "payments", appManagedByLabel: "ignored"},
+	}
+
+	require.NoError(t, resources.Visit(setMetadataVisitor("rel-a", "ns-a", true, labels)))
+	lbls, err := accessor.Labels(deployFoo.Object)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		appManagedByLabel:               "platform",
+		"example.com/release":           "rel-a",
+		"example.com/release-namespace": "ns-a",
+		"team":                          "payments",
+	}, lbls)
+
+	// The objects are owned with the customized managed-by label only.
+	assert.NoError(t, checkOwnership(deployFoo.Object, "rel-a", "ns-a", labels))
+	assert.EqualError(t, checkOwnership(deployFoo.Object, "rel-a", "ns-a", nil), `invalid ownership metadata; label validation error: key "app.kubernetes.io/managed-by" must equal "Helm": current value is "platform"`)
+}
+
+func TestSetMetadataVisitorRequiredLabels(t *testing.T) {
+	deployFoo := newDeploymentResource("foo", "ns-a")
+	resources := kube.ResourceList{deployFoo}
+	labels := &release.ObjectLabels{
+		Extra:       map[string]string{"team": "payments"},
+		Annotations: map[string]string{"example.com/cost-center": "1234", helmReleaseNameAnnotation: "ignored"},
+		Required:    []string{"team", "environment", "tier"},
+	}
+
+	err := resources.Visit(setMetadataVisitor("rel-a", "ns-a", true, labels))
+	assert.EqualError(t, err, `Deployment "foo" in namespace "" is missing the required labels environment, tier`)
+
+	labels.Required = []string{"team"}
+	require.NoError(t, resources.Visit(setMetadataVisitor("rel-a", "ns-a", true, labels)))
+	annos, err := accessor.Annotations(deployFoo.Object)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"example.com/cost-center":      "1234",
+		helmReleaseNameAnnotation:      "rel-a",
+		helmReleaseNamespaceAnnotation: "ns-a",
+	}, annos)
+}
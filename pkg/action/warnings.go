@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"log/slog"
+	"slices"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+)
+
+// WarningKind is the kind of issue a warning is about.
+type WarningKind string
+
+const (
+	// WarningAPIServer is a warning returned by the Kubernetes API server,
+	// such as for the use of a deprecated API.
+	WarningAPIServer WarningKind = "api-server"
+	// WarningValues is an issue with the values of a release, such as a value
+	// that cannot be merged with the ones of the chart.
+	WarningValues WarningKind = "values"
+	// WarningSkippedResource is a resource of a release the action did not
+	// apply, such as a hook of an unknown type.
+	WarningSkippedResource WarningKind = "skipped-resource"
+	// WarningRendering is an issue with the rendering of the manifests of a
+	// release.
+	WarningRendering WarningKind = "rendering"
+	// WarningQuota is an issue with fitting a release in the ResourceQuotas
+	// of its namespace.
+	WarningQuota WarningKind = "quota"
+)
+
+// Warning is an issue an action ran into without failing.
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Message string      `json:"message"`
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// streamWarning passes a warning to the WarningFunc of the configuration, or
+// logs it when there is none.
+func (cfg *Configuration) streamWarning(w Warning) {
+	if cfg.WarningFunc == nil {
+		slog.Warn(w.Message, slog.String("kind", string(w.Kind)))
+		return
+	}
+	cfg.WarningFunc(w)
+}
+
+// addRecorder records the warnings of the API server in the result of an
+// action while it runs.
+func (cfg *Configuration) addRecorder(r *resultRecorder) {
+	cfg.recordersMutex.Lock()
+	defer cfg.recordersMutex.Unlock()
+	cfg.recorders = append(cfg.recorders, r)
+}
+
+// removeRecorder stops recording the warnings of the API server in the result
+// of an action.
+func (cfg *Configuration) removeRecorder(r *resultRecorder) {
+	cfg.recordersMutex.Lock()
+	defer cfg.recordersMutex.Unlock()
+	cfg.recorders = slices.DeleteFunc(cfg.recorders, func(rec *resultRecorder) bool { return rec == r })
+}
+
+// warnAPIServer reports a warning of the API server. The requests of the
+// actions are not told apart, so it is recorded in the results of all the
+// running actions, and streamed once.
+func (cfg *Configuration) warnAPIServer(w Warning) {
+	cfg.recordersMutex.Lock()
+	recorders := slices.Clone(cfg.recorders)
+	cfg.recordersMutex.Unlock()
+
+	recorded := len(recorders) == 0
+	for _, r := range recorders {
+		if r.record(w) {
+			recorded = true
+		}
+	}
+	if recorded {
+		cfg.streamWarning(w)
+	}
+}
+
+// apiWarningHandler reports the warnings of the API server as warnings of the
+// actions.
+type apiWarningHandler struct {
+	cfg *Configuration
+}
+
+func (h apiWarningHandler) HandleWarningHeader(code int, _ string, message string) {
+	// Only the 299 code is used for warnings, as the default handlers of
+	// client-go assume.
+	if code != 299 || message == "" {
+		return
+	}
+	h.cfg.warnAPIServer(Warning{Kind: WarningAPIServer, Message: message})
+}
+
+// warningGetter is a RESTClientGetter whose clients report the warnings of the
+// API server to a handler.
+type warningGetter struct {
+	genericclioptions.RESTClientGetter
+	handler rest.WarningHandler
+}
+
+// ToRESTConfig returns the REST config of the wrapped getter, with the warning
+// handler.
+func (g *warningGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.RESTClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config = rest.CopyConfig(config)
+	config.WarningHandler = g.handler
+	config.WarningHandlerWithContext = nil
+	return config, nil
+}
@@ -0,0 +1,270 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package annotations is the registry of the annotations Helm understands on the
+resources of a chart.
+
+Every annotation in the helm.sh and meta.helm.sh namespaces is registered here
+with its documentation and the values it accepts, so the code acting on an
+annotation, the chart linter and the documentation all share one definition.
+*/
+package annotations
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Names of the annotations Helm understands.
+const (
+	// Hook marks a resource as a hook running on the listed events.
+	Hook = "helm.sh/hook"
+	// HookWeight orders hooks running on the same event.
+	HookWeight = "helm.sh/hook-weight"
+	// HookDeletePolicy lists when a hook resource is deleted.
+	HookDeletePolicy = "helm.sh/hook-delete-policy"
+	// HookOutputLogPolicy lists when the logs of a hook are shown.
+	HookOutputLogPolicy = "helm.sh/hook-output-log-policy"
+	// ResourcePolicy set to "keep" leaves a resource in place when the
+	// release is uninstalled or the resource is removed from the chart.
+	ResourcePolicy = "helm.sh/resource-policy"
+	// WaitPolicy set to "skip" leaves a resource out when waiting for the
+	// resources of a release to become ready.
+	WaitPolicy = "helm.sh/wait-policy"
+	// ApplyStrategy overrides how a resource is applied to the cluster.
+	ApplyStrategy = "helm.sh/apply-strategy"
+	// DeletePropagation overrides how the deletion of a resource propagates
+	// to its dependents.
+	DeletePropagation = "helm.sh/delete-propagation"
+	// ReleaseName is set by Helm to the name of the release owning a resource.
+	ReleaseName = "meta.helm.sh/release-name"
+	// ReleaseNamespace is set by Helm to the namespace of the release owning a
+	// resource.
+	ReleaseNamespace = "meta.helm.sh/release-namespace"
+)
+
+// Values of the ResourcePolicy annotation.
+const (
+	// ResourcePolicyKeep keeps a resource when it would otherwise be deleted.
+	ResourcePolicyKeep = "keep"
+)
+
+// Values of the WaitPolicy annotation.
+const (
+	// WaitPolicyWait waits for the resource. This is the default.
+	WaitPolicyWait = "wait"
+	// WaitPolicySkip does not wait for the resource.
+	WaitPolicySkip = "skip"
+)
+
+// Values of the ApplyStrategy annotation.
+const (
+	// ApplyStrategyServerSide applies the resource with server-side apply.
+	ApplyStrategyServerSide = "server-side"
+	// ApplyStrategyClientSide applies the resource with a client-side patch.
+	ApplyStrategyClientSide = "client-side"
+	// ApplyStrategyReplace replaces the resource on upgrades.
+	ApplyStrategyReplace = "replace"
+)
+
+// Values of the DeletePropagation annotation.
+const (
+	// DeletePropagationBackground deletes dependents in the background.
+	DeletePropagationBackground = "background"
+	// DeletePropagationForeground deletes dependents before the resource.
+	DeletePropagationForeground = "foreground"
+	// DeletePropagationOrphan leaves the dependents of the resource in place.
+	DeletePropagationOrphan = "orphan"
+)
+
+// Annotation describes an annotation Helm understands.
+type Annotation struct {
+	// Name is the name of the annotation.
+	Name string
+	// Description documents what the annotation does.
+	Description string
+	// Values are the values the annotation accepts. When empty, the
+	// annotation accepts any value its Validate function accepts.
+	Values []string
+	// List is true when the value is a comma separated list of values.
+	List bool
+	// Managed is true when Helm sets the annotation itself.
+	Managed bool
+
+	validate func(value string) error
+}
+
+// Validate checks a value of the annotation.
+func (a Annotation) Validate(value string) error {
+	if a.validate != nil {
+		if err := a.validate(value); err != nil {
+			return fmt.Errorf("invalid value %q for annotation %s: %w", value, a.Name, err)
+		}
+	}
+	if len(a.Values) == 0 {
+		return nil
+	}
+	values := []string{value}
+	if a.List {
+		values = ParseList(value)
+	}
+	for _, v := range values {
+		if !slices.Contains(a.Values, Normalize(v)) {
+			return fmt.Errorf("invalid value %q for annotation %s, valid values are %s", v, a.Name, strings.Join(a.Values, ", "))
+		}
+	}
+	return nil
+}
+
+var registry = map[string]Annotation{}
+
+func register(a Annotation) {
+	registry[a.Name] = a
+}
+
+func init() {
+	register(Annotation{
+		Name:        Hook,
+		Description: "Runs the resource as a hook on the listed events instead of installing it with the release.",
+		Values:      []string{"pre-install", "post-install", "pre-delete", "post-delete", "pre-upgrade", "post-upgrade", "pre-rollback", "post-rollback", "test", "test-success", "test-fixture"},
+		List:        true,
+	})
+	register(Annotation{
+		Name:        HookWeight,
+		Description: "Orders hooks running on the same event, lowest weight first.",
+		validate: func(value string) error {
+			_, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return errors.New("must be an integer")
+			}
+			return nil
+		},
+	})
+	register(Annotation{
+		Name:        HookDeletePolicy,
+		Description: "Lists when a hook resource is deleted. Defaults to before-hook-creation.",
+		Values:      []string{"before-hook-creation", "hook-succeeded", "hook-failed"},
+		List:        true,
+	})
+	register(Annotation{
+		Name:        HookOutputLogPolicy,
+		Description: "Lists when the logs of the pods of a hook are shown.",
+		Values:      []string{"hook-succeeded", "hook-failed"},
+		List:        true,
+	})
+	register(Annotation{
+		Name:        ResourcePolicy,
+		Description: "Keeps the resource when the release is uninstalled or the resource is removed from the chart.",
+		Values:      []string{ResourcePolicyKeep},
+	})
+	register(Annotation{
+		Name:        WaitPolicy,
+		Description: "Leaves the resource out when waiting for the resources of the release to become ready.",
+		Values:      []string{WaitPolicyWait, WaitPolicySkip},
+	})
+	register(Annotation{
+		Name:        ApplyStrategy,
+		Description: "Overrides how the resource is applied. Replacing only applies to upgrades and rollbacks; new resources are created.",
+		Values:      []string{ApplyStrategyServerSide, ApplyStrategyClientSide, ApplyStrategyReplace},
+	})
+	register(Annotation{
+		Name:        DeletePropagation,
+		Description: "Overrides how the deletion of the resource propagates to its dependents.",
+		Values:      []string{DeletePropagationBackground, DeletePropagationForeground, DeletePropagationOrphan},
+	})
+	register(Annotation{
+		Name:        ReleaseName,
+		Description: "Name of the release owning the resource.",
+		Managed:     true,
+	})
+	register(Annotation{
+		Name:        ReleaseNamespace,
+		Description: "Namespace of the release owning the resource.",
+		Managed:     true,
+	})
+}
+
+// Lookup returns the registered annotation with the given name.
+func Lookup(name string) (Annotation, bool) {
+	a, ok := registry[name]
+	return a, ok
+}
+
+// All returns the registered annotations, sorted by name.
+func All() []Annotation {
+	return slices.SortedFunc(maps.Values(registry), func(a, b Annotation) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// IsHelmAnnotation returns true if the annotation is in one of the namespaces
+// reserved for Helm.
+func IsHelmAnnotation(name string) bool {
+	return strings.HasPrefix(name, "helm.sh/") || strings.HasPrefix(name, "meta.helm.sh/")
+}
+
+// Validate checks the annotations in the namespaces reserved for Helm. Unknown
+// annotations in these namespaces are reported as well, as they are most
+// likely misspelled.
+func Validate(annotations map[string]string) error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(annotations)) {
+		if !IsHelmAnnotation(name) {
+			continue
+		}
+		a, ok := Lookup(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown annotation %s", name))
+			continue
+		}
+		if err := a.Validate(annotations[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalize returns a value of an annotation the way Helm compares it.
+func Normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// ParseList splits the value of a list annotation into its normalized values.
+func ParseList(value string) []string {
+	var values []string
+	for v := range strings.SplitSeq(value, ",") {
+		values = append(values, Normalize(v))
+	}
+	return values
+}
+
+// Get returns the normalized value of an annotation, or def when it is not
+// set or set to a value the annotation does not accept.
+func Get(annotations map[string]string, name, def string) string {
+	value, ok := annotations[name]
+	if !ok {
+		return def
+	}
+	if a, ok := Lookup(name); ok && a.Validate(value) != nil {
+		return def
+	}
+	return Normalize(value)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package annotations
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	all := All()
+	assert.True(t, slices.IsSortedFunc(all, func(a, b Annotation) int { return strings.Compare(a.Name, b.Name) }))
+	for _, a := range all {
+		assert.True(t, IsHelmAnnotation(a.Name), a.Name)
+		assert.NotEmpty(t, a.Description, a.Name)
+		l, ok := Lookup(a.Name)
+		assert.True(t, ok)
+		assert.Equal(t, a.Name, l.Name)
+	}
+
+	_, ok := Lookup("helm.sh/unknown")
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		err         string
+	}{
+		{
+			name: "valid",
+			annotations: map[string]string{
+				Hook:                "pre-install, Post-Upgrade",
+				HookWeight:          "-5",
+				HookDeletePolicy:    "before-hook-creation,hook-succeeded",
+				ResourcePolicy:      "Keep",
+				WaitPolicy:          "skip",
+				ApplyStrategy:       "replace",
+				DeletePropagation:   "foreground",
+				ReleaseName:         "foo",
+				"example.com/other": "anything",
+			},
+		},
+		{
+			name:        "unknown hook",
+			annotations: map[string]string{Hook: "pre-install,post-instal"},
+			err:         `invalid value "post-instal" for annotation helm.sh/hook`,
+		},
+		{
+			name:        "weight not an integer",
+			annotations: map[string]string{HookWeight: "first"},
+			err:         `invalid value "first" for annotation helm.sh/hook-weight: must be an integer`,
+		},
+		{
+			name:        "invalid wait policy",
+			annotations: map[string]string{WaitPolicy: "never"},
+			err:         "valid values are wait, skip",
+		},
+		{
+			name:        "unknown annotation",
+			annotations: map[string]string{"helm.sh/resource-polcy": "keep"},
+			err:         "unknown annotation helm.sh/resource-polcy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.annotations)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	annos := map[string]string{
+		WaitPolicy:    " Skip ",
+		ApplyStrategy: "sideways",
+		"custom/key":  " Value ",
+	}
+	assert.Equal(t, WaitPolicySkip, Get(annos, WaitPolicy, WaitPolicyWait))
+	assert.Equal(t, "", Get(annos, ApplyStrategy, ""))
+	assert.Equal(t, DeletePropagationBackground, Get(annos, DeletePropagation, DeletePropagationBackground))
+	assert.Equal(t, "value", Get(annos, "custom/key", ""))
+}
+
+func TestParseList(t *testing.T) {
+	assert.Equal(t, []string{"pre-install", "post-upgrade"}, ParseList(" pre-install ,POST-UPGRADE"))
+}
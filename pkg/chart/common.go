@@ -94,6 +94,9 @@ func (r *v2Accessor) Dependencies() []Charter {
 }
 
 func (r *v2Accessor) MetaDependencies() []Dependency {
+	if r.chrt.Metadata == nil {
+		return nil
+	}
 	var deps = make([]Dependency, len(r.chrt.Metadata.Dependencies))
 	for i, c := range r.chrt.Metadata.Dependencies {
 		deps[i] = c
@@ -101,6 +104,14 @@ func (r *v2Accessor) MetaDependencies() []Dependency {
 	return deps
 }
 
+func (r *v2Accessor) DisabledDependencies() []Dependency {
+	var deps = make([]Dependency, len(r.chrt.DisabledDependencies()))
+	for i, c := range r.chrt.DisabledDependencies() {
+		deps[i] = c
+	}
+	return deps
+}
+
 func (r *v2Accessor) Values() map[string]interface{} {
 	return r.chrt.Values
 }
@@ -163,13 +174,24 @@ func (r *v3Accessor) Dependencies() []Charter {
 }
 
 func (r *v3Accessor) MetaDependencies() []Dependency {
-	var deps = make([]Dependency, len(r.chrt.Dependencies()))
+	if r.chrt.Metadata == nil {
+		return nil
+	}
+	var deps = make([]Dependency, len(r.chrt.Metadata.Dependencies))
 	for i, c := range r.chrt.Metadata.Dependencies {
 		deps[i] = c
 	}
 	return deps
 }
 
+func (r *v3Accessor) DisabledDependencies() []Dependency {
+	var deps = make([]Dependency, len(r.chrt.DisabledDependencies()))
+	for i, c := range r.chrt.DisabledDependencies() {
+		deps[i] = c
+	}
+	return deps
+}
+
 func (r *v3Accessor) Values() map[string]interface{} {
 	return r.chrt.Values
 }
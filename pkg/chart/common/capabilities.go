@@ -60,6 +60,15 @@ type Capabilities struct {
 	HelmVersion helmversion.BuildInfo
 }
 
+// HasAPI returns true if the cluster serves the given API version. Besides a
+// group version such as "gateway.networking.k8s.io/v1", a resource can be
+// checked for with "apps/v1/Deployment".
+//
+//	{{ if .Capabilities.HasAPI "gateway.networking.k8s.io/v1" }}
+func (capabilities *Capabilities) HasAPI(apiVersion string) bool {
+	return capabilities.APIVersions.Has(apiVersion)
+}
+
 func (capabilities *Capabilities) Copy() *Capabilities {
 	return &Capabilities{
 		KubeVersion: capabilities.KubeVersion,
@@ -83,6 +92,50 @@ func (kv *KubeVersion) String() string { return kv.Version }
 // Deprecated: use KubeVersion.Version.
 func (kv *KubeVersion) GitVersion() string { return kv.Version }
 
+// Compare compares the Kubernetes version with the given version. It returns
+// -1, 0 or 1 when the Kubernetes version is lower than, equal to or higher than
+// version.
+//
+// Versions are compared on their major, minor and patch numbers only, so
+// missing numbers are zero and suffixes added by distributions, such as
+// "v1.29.3-eks-ad3f6b8", are ignored.
+func (kv *KubeVersion) Compare(version string) (int, error) {
+	current, err := k8sversion.ParseGeneric(kv.Version)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Kubernetes version %q: %w", kv.Version, err)
+	}
+	other, err := k8sversion.ParseGeneric(version)
+	if err != nil {
+		return 0, fmt.Errorf("invalid version %q: %w", version, err)
+	}
+	switch {
+	case current.LessThan(other):
+		return -1, nil
+	case other.LessThan(current):
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// AtLeast returns true if the Kubernetes version is equal to or higher than
+// the given version.
+//
+//	{{ if .Capabilities.KubeVersion.AtLeast "1.29" }}
+func (kv *KubeVersion) AtLeast(version string) (bool, error) {
+	c, err := kv.Compare(version)
+	return c >= 0, err
+}
+
+// LessThan returns true if the Kubernetes version is lower than the given
+// version.
+//
+//	{{ if .Capabilities.KubeVersion.LessThan "1.25" }}
+func (kv *KubeVersion) LessThan(version string) (bool, error) {
+	c, err := kv.Compare(version)
+	return c < 0, err
+}
+
 // ParseKubeVersion parses kubernetes version from string
 func ParseKubeVersion(version string) (*KubeVersion, error) {
 	// Based on the original k8s version parser.
@@ -98,3 +98,64 @@ func TestParseKubeVersionSuffix(t *testing.T) {
 		t.Errorf("Expected parsed KubeVersion.Minor to be 28, got %q", kv.Minor)
 	}
 }
+
+func TestCapabilitiesHasAPI(t *testing.T) {
+	caps := &Capabilities{APIVersions: VersionSet{"v1", "gateway.networking.k8s.io/v1", "apps/v1/Deployment"}}
+	for apiVersion, expected := range map[string]bool{
+		"gateway.networking.k8s.io/v1":      true,
+		"apps/v1/Deployment":                true,
+		"gateway.networking.k8s.io/v1beta1": false,
+		"apps/v1":                           false,
+	} {
+		if got := caps.HasAPI(apiVersion); got != expected {
+			t.Errorf("Expected HasAPI(%q) to be %t, got %t", apiVersion, expected, got)
+		}
+	}
+}
+
+func TestKubeVersionCompare(t *testing.T) {
+	tests := []struct {
+		kubeVersion string
+		version     string
+		compare     int
+	}{
+		{"v1.29.0", "1.29", 0},
+		{"v1.29.3", "1.29", 1},
+		{"v1.29.3", "v1.29.3", 0},
+		{"v1.28.9", "1.29", -1},
+		{"v1.30.0", "1.29.5", 1},
+		{"v1.29.3-eks-ad3f6b8", "1.29.3", 0},
+		{"v1.28+", "1.28", 0},
+		{"v2.0.0", "1.99", 1},
+	}
+	for _, tt := range tests {
+		kv := &KubeVersion{Version: tt.kubeVersion}
+		c, err := kv.Compare(tt.version)
+		if err != nil {
+			t.Fatalf("Expected %s to compare with %s, got %s", tt.kubeVersion, tt.version, err)
+		}
+		if c != tt.compare {
+			t.Errorf("Expected comparing %s with %s to be %d, got %d", tt.kubeVersion, tt.version, tt.compare, c)
+		}
+
+		atLeast, _ := kv.AtLeast(tt.version)
+		if atLeast != (tt.compare >= 0) {
+			t.Errorf("Expected %s AtLeast %s to be %t", tt.kubeVersion, tt.version, tt.compare >= 0)
+		}
+		lessThan, _ := kv.LessThan(tt.version)
+		if lessThan != (tt.compare < 0) {
+			t.Errorf("Expected %s LessThan %s to be %t", tt.kubeVersion, tt.version, tt.compare < 0)
+		}
+	}
+}
+
+func TestKubeVersionCompareInvalid(t *testing.T) {
+	kv := &KubeVersion{Version: "v1.29.0"}
+	if _, err := kv.AtLeast("latest"); err == nil {
+		t.Error("Expected an error comparing with an invalid version")
+	}
+	kv = &KubeVersion{Version: "unknown"}
+	if _, err := kv.AtLeast("1.29"); err == nil {
+		t.Error("Expected an error comparing an invalid Kubernetes version")
+	}
+}
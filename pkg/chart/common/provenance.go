@@ -0,0 +1,39 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+// ValuesSource is a set of values supplied for a chart by the user, such as
+// a values file or a --set flag.
+type ValuesSource struct {
+	// Name describes where the values come from, for example the path of a
+	// values file or a --set flag.
+	Name string
+	// Values are the values the source supplies.
+	Values map[string]interface{}
+}
+
+// ValueOrigin records where an effective value of a chart comes from.
+type ValueOrigin struct {
+	// Path is the dotted path of the value in the values of the chart.
+	Path string `json:"path"`
+	// Value is the effective value.
+	Value interface{} `json:"value"`
+	// Source describes where the value comes from: a values source supplied
+	// by the user, the values of a parent chart overriding those of its
+	// dependency, the values file of a dependency, or the defaults of a chart.
+	Source string `json:"source"`
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"log"
 	"maps"
+	"path"
+	"path/filepath"
 
 	"github.com/mitchellh/copystructure"
 
@@ -31,7 +33,7 @@ func concatPrefix(a, b string) string {
 	if a == "" {
 		return b
 	}
-	return fmt.Sprintf("%s.%s", a, b)
+	return a + "." + b
 }
 
 // CoalesceValues coalesces all of the values in a chart (and its subcharts).
@@ -44,11 +46,18 @@ func concatPrefix(a, b string) string {
 //   - A chart has access to all of the variables for it, as well as all of
 //     the values destined for its dependencies.
 func CoalesceValues(chrt chart.Charter, vals map[string]interface{}) (common.Values, error) {
+	return CoalesceValuesWithWarnings(chrt, vals, log.Printf)
+}
+
+// CoalesceValuesWithWarnings is CoalesceValues calling warn with the issues
+// found coalescing the values, such as values that cannot be merged with the
+// ones of the chart, instead of logging them.
+func CoalesceValuesWithWarnings(chrt chart.Charter, vals map[string]interface{}, warn func(format string, v ...interface{})) (common.Values, error) {
 	valsCopy, err := copyValues(vals)
 	if err != nil {
 		return vals, err
 	}
-	return coalesce(log.Printf, chrt, valsCopy, "", false)
+	return coalesce(warn, chrt, valsCopy, "", false)
 }
 
 // MergeValues is used to merge the values in a chart and its subcharts. This
@@ -74,12 +83,11 @@ func MergeValues(chrt chart.Charter, vals map[string]interface{}) (common.Values
 }
 
 func copyValues(vals map[string]interface{}) (common.Values, error) {
-	v, err := copystructure.Copy(vals)
+	valsCopy, err := copyTable(vals)
 	if err != nil {
 		return vals, err
 	}
 
-	valsCopy := v.(map[string]interface{})
 	// if we have an empty map, make sure it is initialized
 	if valsCopy == nil {
 		valsCopy = make(map[string]interface{})
@@ -88,6 +96,50 @@ func copyValues(vals map[string]interface{}) (common.Values, error) {
 	return valsCopy, nil
 }
 
+// copyTable deep copies a values table.
+//
+// Values are almost always made of the types produced by YAML and JSON
+// decoding, which are copied directly. Anything else is left to copystructure,
+// whose reflection based copy is a lot more expensive.
+func copyTable(src map[string]interface{}) (map[string]interface{}, error) {
+	if src == nil {
+		return nil, nil
+	}
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		c, err := copyValue(v)
+		if err != nil {
+			return nil, err
+		}
+		dst[k] = c
+	}
+	return dst, nil
+}
+
+func copyValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, string, bool, int, int64, float64:
+		return v, nil
+	case map[string]interface{}:
+		return copyTable(v)
+	case []interface{}:
+		if v == nil {
+			return v, nil
+		}
+		dst := make([]interface{}, len(v))
+		for i, e := range v {
+			c, err := copyValue(e)
+			if err != nil {
+				return nil, err
+			}
+			dst[i] = c
+		}
+		return dst, nil
+	default:
+		return copystructure.Copy(v)
+	}
+}
+
 type printFn func(format string, v ...interface{})
 
 // coalesce coalesces the dest values and the chart values, giving priority to the dest values.
@@ -108,6 +160,10 @@ func coalesceDeps(printf printFn, chrt chart.Charter, dest map[string]interface{
 	if err != nil {
 		return dest, err
 	}
+	valuesFiles, err := dependencyValuesFiles(ch)
+	if err != nil {
+		return dest, err
+	}
 	for _, subchart := range ch.Dependencies() {
 		sub, err := chart.NewAccessor(subchart)
 		if err != nil {
@@ -122,6 +178,15 @@ func coalesceDeps(printf printFn, chrt chart.Charter, dest map[string]interface{
 		if dv, ok := dest[sub.Name()]; ok {
 			dvmap := dv.(map[string]interface{})
 			subPrefix := concatPrefix(prefix, ch.Name())
+			// The values file of the dependency ranks below the values the
+			// parent chart has for it.
+			if name, ok := valuesFiles[sub.Name()]; ok {
+				vals, err := readDependencyValuesFile(ch, name)
+				if err != nil {
+					return dest, fmt.Errorf("dependency %s: %w", sub.Name(), err)
+				}
+				coalesceTablesFullKey(printf, dvmap, vals, concatPrefix(subPrefix, sub.Name()), true)
+			}
 			// Get globals out of dest and merge them into dvmap.
 			coalesceGlobals(printf, dvmap, dest, subPrefix, merge)
 			// Now coalesce the rest of the values.
@@ -135,6 +200,51 @@ func coalesceDeps(printf printFn, chrt chart.Charter, dest map[string]interface{
 	return dest, nil
 }
 
+// dependencyValuesFiles returns the values files of the dependencies of a
+// chart, keyed by the name the dependencies are loaded under.
+func dependencyValuesFiles(ch chart.Accessor) (map[string]string, error) {
+	var files map[string]string
+	for _, d := range ch.MetaDependencies() {
+		dep, err := chart.NewDependencyAccessor(d)
+		if err != nil {
+			return nil, err
+		}
+		if dep.ValuesFile() == "" {
+			continue
+		}
+		if files == nil {
+			files = make(map[string]string)
+		}
+		name := dep.Name()
+		if dep.Alias() != "" {
+			name = dep.Alias()
+		}
+		files[name] = dep.ValuesFile()
+	}
+	return files, nil
+}
+
+// readDependencyValuesFile reads a values file of a dependency from the files
+// of its parent chart.
+func readDependencyValuesFile(ch chart.Accessor, name string) (map[string]interface{}, error) {
+	name = path.Clean(filepath.ToSlash(name))
+	for _, f := range ch.Files() {
+		if f.Name != name {
+			continue
+		}
+		data, err := common.ConvertValues(name, f.Data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse values file %s: %w", name, err)
+		}
+		vals, err := common.ReadValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse values file %s: %w", name, err)
+		}
+		return vals, nil
+	}
+	return nil, fmt.Errorf("values file %s not found in chart %s", name, ch.Name())
+}
+
 // coalesceGlobals copies the globals out of src and merges them into dest.
 //
 // For convenience, returns dest.
@@ -210,9 +320,7 @@ func coalesceValues(printf printFn, c chart.Charter, v map[string]interface{}, p
 	// Using c.Values directly when coalescing a table can cause problems where
 	// the original c.Values is altered. Creating a deep copy stops the problem.
 	// This section is fault-tolerant as there is no ability to return an error.
-	valuesCopy, err := copystructure.Copy(ch.Values())
-	var vc map[string]interface{}
-	var ok bool
+	vc, err := copyTable(ch.Values())
 	if err != nil {
 		// If there is an error something is wrong with copying c.Values it
 		// means there is a problem in the deep copying package or something
@@ -220,15 +328,6 @@ func coalesceValues(printf printFn, c chart.Charter, v map[string]interface{}, p
 		// an error.
 		printf("warning: unable to copy values, err: %s", err)
 		vc = ch.Values()
-	} else {
-		vc, ok = valuesCopy.(map[string]interface{})
-		if !ok {
-			// c.Values has a map[string]interface{} structure. If the copy of
-			// it cannot be treated as map[string]interface{} there is something
-			// strangely wrong. Log it and use c.Values
-			printf("warning: unable to convert values copy to values type")
-			vc = ch.Values()
-		}
 	}
 
 	for key, val := range vc {
@@ -311,19 +410,18 @@ func coalesceTablesFullKey(printf printFn, dst, src map[string]interface{}, pref
 	// Because dest has higher precedence than src, dest values override src
 	// values.
 	for key, val := range src {
-		fullkey := concatPrefix(prefix, key)
 		if dv, ok := dst[key]; ok && !merge && dv == nil {
 			delete(dst, key)
 		} else if !ok {
 			dst[key] = val
 		} else if istable(val) {
 			if istable(dv) {
-				coalesceTablesFullKey(printf, dv.(map[string]interface{}), val.(map[string]interface{}), fullkey, merge)
+				coalesceTablesFullKey(printf, dv.(map[string]interface{}), val.(map[string]interface{}), concatPrefix(prefix, key), merge)
 			} else {
-				printf("warning: cannot overwrite table with non table for %s (%v)", fullkey, val)
+				printf("warning: cannot overwrite table with non table for %s (%v)", concatPrefix(prefix, key), val)
 			}
 		} else if istable(dv) && val != nil {
-			printf("warning: destination for %s is a table. Ignoring non-table value (%v)", fullkey, val)
+			printf("warning: destination for %s is a table. Ignoring non-table value (%v)", concatPrefix(prefix, key), val)
 		}
 	}
 	return dst
@@ -727,7 +727,100 @@ func TestCoalesceValuesWarnings(t *testing.T) {
 
 }
 
+func TestCoalesceValuesDependencyValuesFile(t *testing.T) {
+	umbrella := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "umbrella",
+			Dependencies: []*chart.Dependency{
+				{Name: "redis", ValuesFile: "deps/redis.yaml"},
+				{Name: "redis", Alias: "cache", ValuesFile: "deps/../deps/cache.yaml"},
+			},
+		},
+		Values: map[string]interface{}{
+			"redis": map[string]interface{}{"replicas": 3},
+		},
+		Files: []*common.File{
+			{Name: "deps/redis.yaml", Data: []byte("replicas: 2\nimage: redis:7\nauth: {enabled: true}\n")},
+			{Name: "deps/cache.yaml", Data: []byte("image: redis:6\n")},
+		},
+	}
+	redisValues := func() map[string]interface{} {
+		return map[string]interface{}{"replicas": 1, "image": "redis", "auth": map[string]interface{}{"enabled": false}, "port": 6379}
+	}
+	umbrella.AddDependency(
+		&chart.Chart{Metadata: &chart.Metadata{Name: "redis"}, Values: redisValues()},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "cache"}, Values: redisValues()},
+	)
+
+	v, err := CoalesceValues(umbrella, map[string]interface{}{
+		"redis": map[string]interface{}{"auth": nil},
+	})
+	assert.NoError(t, err)
+
+	// The values of the parent chart win over the values file, which wins over
+	// the values of the subchart.
+	assert.Equal(t, map[string]interface{}{"replicas": 3, "image": "redis:7", "port": 6379, "global": map[string]interface{}{}}, v["redis"])
+	assert.Equal(t, map[string]interface{}{"replicas": 1, "image": "redis:6", "auth": map[string]interface{}{"enabled": false}, "port": 6379, "global": map[string]interface{}{}}, v["cache"])
+
+	umbrella.Files = nil
+	_, err = CoalesceValues(umbrella, map[string]interface{}{})
+	assert.EqualError(t, err, "dependency redis: values file deps/redis.yaml not found in chart umbrella")
+}
+
 func TestConcatPrefix(t *testing.T) {
 	assert.Equal(t, "b", concatPrefix("", "b"))
 	assert.Equal(t, "a.b", concatPrefix("a", "b"))
 }
+
+func TestCopyTable(t *testing.T) {
+	type custom struct{ Name string }
+	src := map[string]interface{}{
+		"string": "value",
+		"nested": map[string]interface{}{"list": []interface{}{1, map[string]interface{}{"a": "b"}}},
+		"values": common.Values{"c": "d"},
+		"custom": &custom{Name: "e"},
+		"nil":    nil,
+	}
+
+	dst, err := copyTable(src)
+	assert.NoError(t, err)
+	assert.Equal(t, src, dst)
+
+	// Changing the copy must leave the source untouched.
+	dst["nested"].(map[string]interface{})["list"].([]interface{})[1].(map[string]interface{})["a"] = "changed"
+	dst["values"].(common.Values)["c"] = "changed"
+	dst["custom"].(*custom).Name = "changed"
+	assert.Equal(t, "b", src["nested"].(map[string]interface{})["list"].([]interface{})[1].(map[string]interface{})["a"])
+	assert.Equal(t, "d", src["values"].(common.Values)["c"])
+	assert.Equal(t, "e", src["custom"].(*custom).Name)
+
+	dst, err = copyTable(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, dst)
+}
+
+func BenchmarkCoalesceValues(b *testing.B) {
+	values := func() map[string]interface{} {
+		config := make(map[string]interface{})
+		for i := range 50 {
+			config[fmt.Sprintf("key%d", i)] = map[string]interface{}{"enabled": true, "value": i, "tags": []interface{}{"a", "b"}}
+		}
+		return map[string]interface{}{
+			"replicaCount": 2,
+			"config":       config,
+			"global":       map[string]interface{}{"registry": "example.com"},
+		}
+	}
+	umbrella := &chart.Chart{Metadata: &chart.Metadata{Name: "umbrella"}, Values: values()}
+	for i := range 20 {
+		umbrella.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: fmt.Sprintf("sub%d", i)}, Values: values()})
+	}
+	overrides := map[string]interface{}{"replicaCount": 3, "sub0": map[string]interface{}{"replicaCount": 1}}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := CoalesceValues(umbrella, overrides); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
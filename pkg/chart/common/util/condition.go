@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+)
+
+// conditionPaths matches a dependency condition made of a comma separated
+// list of value paths, such as "subchart.enabled,global.subchart.enabled".
+var conditionPaths = regexp.MustCompile(`^\s*[\w-]+(\.[\w-]+)*\s*(,\s*[\w-]+(\.[\w-]+)*\s*)*$`)
+
+// IsConditionExpression returns true if a dependency condition is a CEL
+// expression rather than a comma separated list of value paths.
+//
+// An expression that is a single path, such as "values.enabled", is read as a
+// value path. It can be written as the expression "values.enabled == true".
+func IsConditionExpression(condition string) bool {
+	return condition != "" && !conditionPaths.MatchString(condition)
+}
+
+var conditionEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("values", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+})
+
+// CompileCondition compiles a CEL dependency condition.
+//
+// The expression has access to the values of the chart declaring the
+// dependency as "values" and must evaluate to a bool, e.g.
+//
+//	values.replicas > 1 && !values.externalDB
+func CompileCondition(expr string) (cel.Program, error) {
+	env, err := conditionEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expr, iss.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("invalid condition %q: must evaluate to a bool, not %s", expr, ast.OutputType())
+	}
+	return env.Program(ast)
+}
+
+// EvalCondition evaluates a compiled dependency condition against the values
+// of the chart declaring the dependency.
+func EvalCondition(prg cel.Program, values map[string]interface{}) (bool, error) {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	out, _, err := prg.Eval(map[string]interface{}{"values": values})
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition returned non-bool value %v", out.Value())
+	}
+	return b, nil
+}
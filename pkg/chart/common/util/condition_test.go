@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+func TestIsConditionExpression(t *testing.T) {
+	for condition, expected := range map[string]bool{
+		"":                 false,
+		"subchart.enabled": false,
+		"subchart.enabled,global.sub-chart.enabled": false,
+		" subchart.enabled , global.enabled ":       false,
+		"values.enabled":                            false,
+		"values.enabled == true":                    true,
+		"!values.externalDB":                        true,
+		"values.replicas > 1 && !values.externalDB": true,
+		"has(values.redis)":                         true,
+	} {
+		assert.Equal(t, expected, IsConditionExpression(condition), condition)
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	values := common.Values{
+		"replicas":   3,
+		"ratio":      1.5,
+		"externalDB": false,
+		"redis":      map[string]interface{}{"mode": "cluster"},
+		"zones":      []interface{}{"a", "b"},
+	}
+	tests := []struct {
+		expr     string
+		expected bool
+		err      string
+	}{
+		{expr: "values.replicas > 1 && !values.externalDB", expected: true},
+		{expr: "values.replicas > 1.0", expected: true},
+		{expr: "values.ratio > 1", expected: true},
+		{expr: `values.redis.mode == "standalone"`, expected: false},
+		{expr: "size(values.zones) >= 2", expected: true},
+		{expr: "has(values.database) && values.database.enabled", expected: false},
+		{expr: "values.database.enabled", err: "no such key: database"},
+		{expr: "values.replicas", err: "non-bool value 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			prg, err := CompileCondition(tt.expr)
+			assert.NoError(t, err)
+			enabled, err := EvalCondition(prg, values)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, enabled)
+		})
+	}
+}
+
+func TestCompileConditionInvalid(t *testing.T) {
+	_, err := CompileCondition("values.replicas >")
+	assert.ErrorContains(t, err, `invalid condition "values.replicas >"`)
+
+	_, err = CompileCondition("size(values) + 1")
+	assert.ErrorContains(t, err, "must evaluate to a bool, not int")
+
+	_, err = CompileCondition("replicas > 1")
+	assert.ErrorContains(t, err, "undeclared reference to 'replicas'")
+}
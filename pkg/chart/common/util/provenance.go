@@ -0,0 +1,225 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart"
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+// valuesLayer is a set of values the values of a chart are coalesced from,
+// rooted at the values of that chart.
+type valuesLayer struct {
+	source string
+	values map[string]interface{}
+	// defaultsOf is the path of the chart whose defaults the layer holds, if
+	// any.
+	defaultsOf string
+}
+
+// provenanceScope holds the layers the values of a chart are coalesced from,
+// highest precedence first.
+type provenanceScope struct {
+	chart   chart.Accessor
+	layers  []valuesLayer
+	globals []valuesLayer
+	deps    map[string]*provenanceScope
+}
+
+// ValuesProvenance returns where every effective value of a chart and its
+// dependencies comes from. The vals are the values supplied by the user, as
+// merged from the sources, which are ordered from the lowest precedence to
+// the highest.
+//
+// Values are coalesced as CoalesceValues does. Tables are walked into, while
+// lists and scalars are reported as a whole. The origins are sorted by path.
+func ValuesProvenance(chrt chart.Charter, vals map[string]interface{}, sources []common.ValuesSource) ([]common.ValueOrigin, error) {
+	ch, err := chart.NewAccessor(chrt)
+	if err != nil {
+		return nil, err
+	}
+	final, err := CoalesceValues(chrt, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &provenanceScope{chart: ch}
+	for _, s := range slices.Backward(sources) {
+		root.layers = append(root.layers, valuesLayer{source: s.Name, values: s.Values})
+	}
+	root.layers = append(root.layers, valuesLayer{
+		source:     fmt.Sprintf("chart default (%s)", ch.ChartFullPath()),
+		values:     ch.Values(),
+		defaultsOf: ch.ChartFullPath(),
+	})
+	root.globals = globalLayers(root.layers)
+
+	var origins []common.ValueOrigin
+	if err := root.walk(final, nil, &origins); err != nil {
+		return nil, err
+	}
+	return origins, nil
+}
+
+// walk records the origins of the values at path in the coalesced values of
+// the chart.
+func (s *provenanceScope) walk(vals map[string]interface{}, path []string, origins *[]common.ValueOrigin) error {
+	keys := make([]string, 0, len(vals))
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		p := append(slices.Clone(path), k)
+		if t, ok := vals[k].(map[string]interface{}); ok {
+			if err := s.walk(t, p, origins); err != nil {
+				return err
+			}
+			continue
+		}
+		source, err := s.origin(p)
+		if err != nil {
+			return err
+		}
+		*origins = append(*origins, common.ValueOrigin{
+			Path:   strings.Join(p, "."),
+			Value:  vals[k],
+			Source: source,
+		})
+	}
+	return nil
+}
+
+// origin returns the source of the value at path in the values of the chart.
+func (s *provenanceScope) origin(path []string) (string, error) {
+	if len(path) > 1 {
+		if path[0] == common.GlobalKey {
+			return findSource(s.globals, path[1:]), nil
+		}
+		dep, err := s.dependency(path[0])
+		if err != nil {
+			return "", err
+		}
+		if dep != nil {
+			return dep.origin(path[1:])
+		}
+	}
+	return findSource(s.layers, path), nil
+}
+
+// dependency returns the scope of the dependency of the chart loaded under
+// name, or nil if there is none.
+func (s *provenanceScope) dependency(name string) (*provenanceScope, error) {
+	if dep, ok := s.deps[name]; ok {
+		return dep, nil
+	}
+	var sub chart.Accessor
+	for _, d := range s.chart.Dependencies() {
+		a, err := chart.NewAccessor(d)
+		if err != nil {
+			return nil, err
+		}
+		if a.Name() == name {
+			sub = a
+			break
+		}
+	}
+	if sub == nil {
+		return nil, nil
+	}
+
+	dep := &provenanceScope{chart: sub}
+	for _, l := range s.layers {
+		t, ok := l.values[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source := l.source
+		if l.defaultsOf != "" {
+			source = fmt.Sprintf("parent override (%s)", l.defaultsOf)
+		}
+		dep.layers = append(dep.layers, valuesLayer{source: source, values: t})
+	}
+	valuesFiles, err := dependencyValuesFiles(s.chart)
+	if err != nil {
+		return nil, err
+	}
+	if file, ok := valuesFiles[name]; ok {
+		vals, err := readDependencyValuesFile(s.chart, file)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", name, err)
+		}
+		dep.layers = append(dep.layers, valuesLayer{
+			source: fmt.Sprintf("dependency values file %s (%s)", file, s.chart.ChartFullPath()),
+			values: vals,
+		})
+	}
+	dep.layers = append(dep.layers, valuesLayer{
+		source:     fmt.Sprintf("chart default (%s)", sub.ChartFullPath()),
+		values:     sub.Values(),
+		defaultsOf: sub.ChartFullPath(),
+	})
+	// The globals of the parent chart override those of the dependency.
+	dep.globals = append(slices.Clone(s.globals), globalLayers(dep.layers)...)
+
+	if s.deps == nil {
+		s.deps = make(map[string]*provenanceScope)
+	}
+	s.deps[name] = dep
+	return dep, nil
+}
+
+// globalLayers returns the globals of the layers.
+func globalLayers(layers []valuesLayer) []valuesLayer {
+	var globals []valuesLayer
+	for _, l := range layers {
+		if t, ok := l.values[common.GlobalKey].(map[string]interface{}); ok {
+			globals = append(globals, valuesLayer{source: l.source, values: t})
+		}
+	}
+	return globals
+}
+
+// findSource returns the source of the first layer holding a value at path.
+func findSource(layers []valuesLayer, path []string) string {
+	for _, l := range layers {
+		if v, ok := lookupPath(l.values, path); ok && v != nil {
+			return l.source
+		}
+	}
+	return "unknown"
+}
+
+func lookupPath(vals map[string]interface{}, path []string) (interface{}, bool) {
+	for i, k := range path {
+		v, ok := vals[k]
+		if !ok {
+			return nil, false
+		}
+		if i == len(path)-1 {
+			return v, true
+		}
+		if vals, ok = v.(map[string]interface{}); !ok {
+			return nil, false
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestValuesProvenance(t *testing.T) {
+	umbrella := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "umbrella",
+			Dependencies: []*chart.Dependency{
+				{Name: "redis", ValuesFile: "deps/redis.yaml"},
+			},
+		},
+		Values: map[string]interface{}{
+			"replicas": 1,
+			"global":   map[string]interface{}{"registry": "docker.io"},
+			"redis":    map[string]interface{}{"replicas": 3},
+		},
+		Files: []*common.File{
+			{Name: "deps/redis.yaml", Data: []byte("image: redis:7\n")},
+		},
+	}
+	umbrella.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "redis"},
+		Values: map[string]interface{}{
+			"replicas": 1,
+			"image":    "redis",
+			"port":     6379,
+			"args":     []interface{}{"--save"},
+			"global":   map[string]interface{}{"registry": "quay.io", "pullPolicy": "Always"},
+		},
+	})
+
+	sources := []common.ValuesSource{
+		{Name: "-f prod.yaml", Values: map[string]interface{}{"replicas": 2, "redis": map[string]interface{}{"port": 6380}}},
+		{Name: "--set redis.port=6381", Values: map[string]interface{}{"redis": map[string]interface{}{"port": 6381}}},
+	}
+	vals := map[string]interface{}{"replicas": 2, "redis": map[string]interface{}{"port": 6381}}
+
+	origins, err := ValuesProvenance(umbrella, vals, sources)
+	assert.NoError(t, err)
+	assert.Equal(t, []common.ValueOrigin{
+		{Path: "global.registry", Value: "docker.io", Source: "chart default (umbrella)"},
+		{Path: "redis.args", Value: []interface{}{"--save"}, Source: "chart default (umbrella/charts/redis)"},
+		{Path: "redis.global.pullPolicy", Value: "Always", Source: "chart default (umbrella/charts/redis)"},
+		{Path: "redis.global.registry", Value: "docker.io", Source: "chart default (umbrella)"},
+		{Path: "redis.image", Value: "redis:7", Source: "dependency values file deps/redis.yaml (umbrella)"},
+		{Path: "redis.port", Value: 6381, Source: "--set redis.port=6381"},
+		{Path: "redis.replicas", Value: 3, Source: "parent override (umbrella)"},
+		{Path: "replicas", Value: 2, Source: "-f prod.yaml"},
+	}, origins)
+
+	umbrella.Files = nil
+	_, err = ValuesProvenance(umbrella, vals, sources)
+	assert.EqualError(t, err, "dependency redis: values file deps/redis.yaml not found in chart umbrella")
+}
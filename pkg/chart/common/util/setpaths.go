@@ -0,0 +1,390 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart"
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+// setFlagPrefix is the prefix of the names of the values sources of the
+// --set, --set-string, --set-json, --set-file and --set-literal flags.
+const setFlagPrefix = "--set"
+
+// maxSuggestions is the number of paths suggested for an unknown path.
+const maxSuggestions = 3
+
+// UnknownValue is a value set at a path a chart does not know.
+type UnknownValue struct {
+	// Path is the dotted path of the value.
+	Path string
+	// Source is the flag setting the value.
+	Source string
+	// Suggestions are the known paths nearest to Path, nearest first.
+	Suggestions []string
+}
+
+func (v UnknownValue) String() string {
+	msg := fmt.Sprintf("%s sets %s, which is neither in the default values nor in the values schema of the chart", v.Source, v.Path)
+	if len(v.Suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(v.Suggestions, " or "))
+	}
+	return msg
+}
+
+// UnknownValuesError is returned by ValidateSetPaths when values are set at
+// paths that are neither in the default values nor in the values schema of
+// the chart.
+type UnknownValuesError struct {
+	Values []UnknownValue
+}
+
+func (e *UnknownValuesError) Error() string {
+	msgs := make([]string, 0, len(e.Values))
+	for _, v := range e.Values {
+		msgs = append(msgs, v.String())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateSetPaths checks that the values set by the --set family of flags
+// among the sources are at paths the chart knows: paths of its default
+// values, or declared by its values schema. The sources are those of
+// ValuesProvenance; values files are not checked. The dependencies of the
+// chart must not be processed yet, so that the values of disabled ones are
+// known as well.
+//
+// Tables are walked into, while lists and scalars are checked as a whole.
+// Values below an empty or null default, or below an object of the schema
+// allowing additional properties, are known, as are globals and the conditions and
+// tags enabling dependencies. The values of a
+// dependency are checked against the dependency as well. Charts with neither
+// default values nor a schema are not checked.
+func ValidateSetPaths(chrt chart.Charter, sources []common.ValuesSource) error {
+	ch, err := chart.NewAccessor(chrt)
+	if err != nil {
+		return err
+	}
+	scope, err := newPathScope(ch)
+	if err != nil {
+		return err
+	}
+	var unknown []UnknownValue
+	for _, s := range sources {
+		if !strings.HasPrefix(s.Name, setFlagPrefix) {
+			continue
+		}
+		for _, path := range leafPaths(s.Values, nil) {
+			if path[0] == common.GlobalKey {
+				continue
+			}
+			known, err := scope.known(path)
+			if err != nil {
+				return err
+			}
+			if !known {
+				p := strings.Join(path, ".")
+				unknown = append(unknown, UnknownValue{Path: p, Source: s.Name, Suggestions: scope.suggest(p)})
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return &UnknownValuesError{Values: unknown}
+	}
+	return nil
+}
+
+// pathScope knows the value paths of a chart.
+type pathScope struct {
+	chart    chart.Accessor
+	defaults map[string]interface{}
+	schema   map[string]interface{}
+	deps     map[string]*pathScope
+}
+
+func newPathScope(ch chart.Accessor) (*pathScope, error) {
+	s := &pathScope{chart: ch, defaults: ch.Values()}
+	if raw := ch.Schema(); len(raw) > 0 {
+		if err := json.Unmarshal(raw, &s.schema); err != nil {
+			return nil, fmt.Errorf("chart %s has an invalid values schema: %w", ch.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+// known reports whether the chart knows the value path.
+func (s *pathScope) known(path []string) (bool, error) {
+	if len(s.defaults) == 0 && s.schema == nil {
+		return true, nil
+	}
+	if inDefaults(s.defaults, path) || inSchema(s.schema, path) {
+		return true, nil
+	}
+	switch ok, err := s.enablesDependency(path); {
+	case err != nil:
+		return false, err
+	case ok:
+		return true, nil
+	}
+	if len(path) > 1 {
+		dep, err := s.dependency(path[0])
+		if err != nil || dep == nil {
+			return false, err
+		}
+		return dep.known(path[1:])
+	}
+	return false, nil
+}
+
+// enablesDependency reports whether the path is one of the conditions or
+// tags enabling the dependencies of the chart.
+func (s *pathScope) enablesDependency(path []string) (bool, error) {
+	p := strings.Join(path, ".")
+	for _, md := range s.chart.MetaDependencies() {
+		d, err := chart.NewDependencyAccessor(md)
+		if err != nil {
+			return false, err
+		}
+		if !IsConditionExpression(d.Condition()) {
+			for _, c := range strings.Split(d.Condition(), ",") {
+				if strings.TrimSpace(c) == p {
+					return true, nil
+				}
+			}
+		}
+		if len(path) == 2 && path[0] == "tags" && slices.Contains(d.Tags(), path[1]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dependency returns the scope of the dependency of the chart loaded under
+// name, or nil if there is none.
+func (s *pathScope) dependency(name string) (*pathScope, error) {
+	if dep, ok := s.deps[name]; ok {
+		return dep, nil
+	}
+	// The dependencies are not processed yet, so aliases are looked up in the
+	// metadata.
+	chartName := name
+	for _, md := range s.chart.MetaDependencies() {
+		d, err := chart.NewDependencyAccessor(md)
+		if err != nil {
+			return nil, err
+		}
+		if d.Alias() == name {
+			chartName = d.Name()
+			break
+		}
+	}
+	for _, d := range s.chart.Dependencies() {
+		a, err := chart.NewAccessor(d)
+		if err != nil {
+			return nil, err
+		}
+		if a.Name() != chartName {
+			continue
+		}
+		dep, err := newPathScope(a)
+		if err != nil {
+			return nil, err
+		}
+		if s.deps == nil {
+			s.deps = make(map[string]*pathScope)
+		}
+		s.deps[name] = dep
+		return dep, nil
+	}
+	return nil, nil
+}
+
+// inDefaults reports whether the path is in the default values, or below an
+// empty or null default.
+func inDefaults(defaults map[string]interface{}, path []string) bool {
+	vals := defaults
+	for i, k := range path {
+		v, ok := vals[k]
+		if !ok {
+			return i > 0 && len(vals) == 0
+		}
+		if v == nil {
+			return true
+		}
+		t, ok := v.(map[string]interface{})
+		if !ok {
+			return i == len(path)-1
+		}
+		vals = t
+	}
+	return true
+}
+
+// inSchema reports whether the path is declared by the schema, or below an
+// object of the schema allowing additional properties, as objects do unless
+// their additionalProperties is false.
+func inSchema(schema map[string]interface{}, path []string) bool {
+	if schema == nil {
+		return false
+	}
+	node := schema
+	for _, k := range path {
+		props, _ := node["properties"].(map[string]interface{})
+		if prop, ok := props[k].(map[string]interface{}); ok {
+			node = prop
+			continue
+		}
+		if len(props) == 0 {
+			// An object without declared properties holds anything.
+			return true
+		}
+		if _, ok := node["patternProperties"]; ok {
+			return true
+		}
+		// Objects allow additional properties unless the schema forbids them.
+		additional, ok := node["additionalProperties"].(bool)
+		return !ok || additional
+	}
+	return true
+}
+
+// suggest returns the known paths nearest to path.
+func (s *pathScope) suggest(path string) []string {
+	type candidate struct {
+		path     string
+		distance int
+	}
+	var candidates []candidate
+	threshold := max(2, len(path)/3)
+	for _, p := range s.paths() {
+		d := levenshtein(strings.ToLower(path), strings.ToLower(p))
+		if strings.HasPrefix(p, path) {
+			// Truncated paths are close whatever their length.
+			d = min(d, threshold)
+		}
+		if d <= threshold {
+			candidates = append(candidates, candidate{p, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].path < candidates[j].path
+	})
+	var suggestions []string
+	for _, c := range candidates {
+		if len(suggestions) == maxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, c.path)
+	}
+	return suggestions
+}
+
+// paths returns the paths of the default values and the schema of the chart
+// and its dependencies, tables and objects included.
+func (s *pathScope) paths() []string {
+	var paths []string
+	var walkDefaults func(vals map[string]interface{}, prefix string)
+	walkDefaults = func(vals map[string]interface{}, prefix string) {
+		for k, v := range vals {
+			p := prefix + k
+			paths = append(paths, p)
+			if t, ok := v.(map[string]interface{}); ok {
+				walkDefaults(t, p+".")
+			}
+		}
+	}
+	walkDefaults(s.defaults, "")
+	var walkSchema func(node map[string]interface{}, prefix string)
+	walkSchema = func(node map[string]interface{}, prefix string) {
+		props, _ := node["properties"].(map[string]interface{})
+		for k, v := range props {
+			p := prefix + k
+			paths = append(paths, p)
+			if t, ok := v.(map[string]interface{}); ok {
+				walkSchema(t, p+".")
+			}
+		}
+	}
+	walkSchema(s.schema, "")
+	var names []string
+	for _, d := range s.chart.Dependencies() {
+		if a, err := chart.NewAccessor(d); err == nil {
+			names = append(names, a.Name())
+		}
+	}
+	for _, md := range s.chart.MetaDependencies() {
+		if d, err := chart.NewDependencyAccessor(md); err == nil && d.Alias() != "" {
+			names = append(names, d.Alias())
+		}
+	}
+	for _, name := range names {
+		if dep, err := s.dependency(name); err == nil && dep != nil {
+			for _, p := range dep.paths() {
+				paths = append(paths, name+"."+p)
+			}
+		}
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// leafPaths returns the paths of the values, walking into tables.
+func leafPaths(vals map[string]interface{}, prefix []string) [][]string {
+	var paths [][]string
+	for k, v := range vals {
+		p := append(slices.Clone(prefix), k)
+		if t, ok := v.(map[string]interface{}); ok && len(t) > 0 {
+			paths = append(paths, leafPaths(t, p)...)
+			continue
+		}
+		paths = append(paths, p)
+	}
+	slices.SortFunc(paths, func(a, b []string) int {
+		return strings.Compare(strings.Join(a, "."), strings.Join(b, "."))
+	})
+	return paths
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,151 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestValidateSetPaths(t *testing.T) {
+	web := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name: "web",
+			Dependencies: []*chart.Dependency{
+				{Name: "redis", Alias: "cache", Condition: "cache.enabled", Tags: []string{"backend"}},
+			},
+		},
+		Values: map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": "nginx",
+				"tag":        "1.27",
+			},
+			"podAnnotations": map[string]interface{}{},
+			"resources":      nil,
+			"replicas":       1,
+		},
+		Schema: []byte(`{
+			"properties": {
+				"ingress": {
+					"properties": {
+						"host": {"type": "string"},
+						"tls": {"type": "boolean"}
+					},
+					"additionalProperties": false
+				},
+				"labels": {
+					"properties": {"team": {"type": "string"}},
+					"additionalProperties": {"type": "string"}
+				},
+				"probe": {
+					"properties": {"path": {"type": "string"}}
+				},
+				"env": {"type": "object"}
+			},
+			"additionalProperties": false
+		}`),
+	}
+	web.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "redis"},
+		Values:   map[string]interface{}{"port": 6379},
+	})
+
+	set := func(name string, vals map[string]interface{}) []common.ValuesSource {
+		return []common.ValuesSource{{Name: name, Values: vals}}
+	}
+	tests := []struct {
+		name    string
+		sources []common.ValuesSource
+		unknown []UnknownValue
+	}{
+		{
+			name:    "default values",
+			sources: set("--set image.tag=1.28,replicas=2", map[string]interface{}{"image": map[string]interface{}{"tag": "1.28"}, "replicas": 2}),
+		},
+		{
+			name:    "below empty and null defaults",
+			sources: set("--set-json", map[string]interface{}{"podAnnotations": map[string]interface{}{"a": "b"}, "resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1"}}}),
+		},
+		{
+			name:    "declared by the schema",
+			sources: set("--set ingress.host=example.com", map[string]interface{}{"ingress": map[string]interface{}{"host": "example.com"}, "labels": map[string]interface{}{"app": "web"}, "env": map[string]interface{}{"A": "1"}}),
+		},
+		{
+			name:    "below an object without additionalProperties",
+			sources: set("--set probe.port=8080", map[string]interface{}{"probe": map[string]interface{}{"port": 8080}}),
+		},
+		{
+			name:    "globals, conditions, tags and dependencies",
+			sources: set("--set", map[string]interface{}{"global": map[string]interface{}{"any": 1}, "cache": map[string]interface{}{"enabled": true, "port": 6380}, "tags": map[string]interface{}{"backend": false}}),
+		},
+		{
+			name: "values files are not checked",
+			sources: []common.ValuesSource{
+				{Name: "-f values.yaml", Values: map[string]interface{}{"unknown": true}},
+			},
+		},
+		{
+			name:    "misspelled path",
+			sources: set("--set image.repositry=httpd", map[string]interface{}{"image": map[string]interface{}{"repositry": "httpd"}}),
+			unknown: []UnknownValue{{Path: "image.repositry", Source: "--set image.repositry=httpd", Suggestions: []string{"image.repository"}}},
+		},
+		{
+			name:    "truncated path and unknown dependency value",
+			sources: set("--set ingress.ho=a,cache.prot=1", map[string]interface{}{"ingress": map[string]interface{}{"ho": "a"}, "cache": map[string]interface{}{"prot": 1}}),
+			unknown: []UnknownValue{
+				{Path: "cache.prot", Source: "--set ingress.ho=a,cache.prot=1", Suggestions: []string{"cache.port"}},
+				{Path: "ingress.ho", Source: "--set ingress.ho=a,cache.prot=1", Suggestions: []string{"ingress.host", "ingress", "ingress.tls"}},
+			},
+		},
+		{
+			name:    "below a scalar",
+			sources: set("--set replicas.min=1", map[string]interface{}{"replicas": map[string]interface{}{"min": 1}}),
+			unknown: []UnknownValue{{Path: "replicas.min", Source: "--set replicas.min=1", Suggestions: []string{"replicas"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSetPaths(web, tt.sources)
+			if tt.unknown == nil {
+				require.NoError(t, err)
+				return
+			}
+			var unknownErr *UnknownValuesError
+			require.ErrorAs(t, err, &unknownErr)
+			assert.Equal(t, tt.unknown, unknownErr.Values)
+		})
+	}
+}
+
+func TestValidateSetPathsWithoutDefaults(t *testing.T) {
+	ch := &chart.Chart{Metadata: &chart.Metadata{Name: "bare"}}
+	sources := []common.ValuesSource{{Name: "--set anything=1", Values: map[string]interface{}{"anything": 1}}}
+	assert.NoError(t, ValidateSetPaths(ch, sources))
+}
+
+func TestUnknownValuesError(t *testing.T) {
+	err := &UnknownValuesError{Values: []UnknownValue{
+		{Path: "image.repositry", Source: "--set image.repositry=httpd", Suggestions: []string{"image.repository"}},
+		{Path: "debug", Source: "--set debug=true"},
+	}}
+	assert.EqualError(t, err, "--set image.repositry=httpd sets image.repositry, which is neither in the default values nor in the values schema of the chart (did you mean image.repository?); --set debug=true sets debug, which is neither in the default values nor in the values schema of the chart")
+}
@@ -18,6 +18,7 @@ package util
 
 import (
 	"fmt"
+	"log"
 
 	"helm.sh/helm/v4/pkg/chart"
 	"helm.sh/helm/v4/pkg/chart/common"
@@ -34,6 +35,13 @@ func ToRenderValues(chrt chart.Charter, chrtVals map[string]interface{}, options
 //
 // This takes both ReleaseOptions and Capabilities to merge into the render values.
 func ToRenderValuesWithSchemaValidation(chrt chart.Charter, chrtVals map[string]interface{}, options common.ReleaseOptions, caps *common.Capabilities, skipSchemaValidation bool) (common.Values, error) {
+	return ToRenderValuesWithWarnings(chrt, chrtVals, options, caps, skipSchemaValidation, log.Printf)
+}
+
+// ToRenderValuesWithWarnings is ToRenderValuesWithSchemaValidation calling warn
+// with the issues found coalescing the values instead of logging them, see
+// CoalesceValuesWithWarnings.
+func ToRenderValuesWithWarnings(chrt chart.Charter, chrtVals map[string]interface{}, options common.ReleaseOptions, caps *common.Capabilities, skipSchemaValidation bool, warn func(format string, v ...interface{})) (common.Values, error) {
 	if caps == nil {
 		caps = common.DefaultCapabilities
 	}
@@ -54,7 +62,7 @@ func ToRenderValuesWithSchemaValidation(chrt chart.Charter, chrtVals map[string]
 		},
 	}
 
-	vals, err := CoalesceValues(chrt, chrtVals)
+	vals, err := CoalesceValuesWithWarnings(chrt, chrtVals, warn)
 	if err != nil {
 		return common.Values(top), err
 	}
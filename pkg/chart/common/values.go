@@ -109,12 +109,17 @@ func ReadValues(data []byte) (vals Values, err error) {
 	return vals, err
 }
 
-// ReadValuesFile will parse a YAML file into a map of values.
+// ReadValuesFile will parse a YAML file into a map of values. Files named
+// *.toml or *.json5 are read as TOML or JSON5.
 func ReadValuesFile(filename string) (Values, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return map[string]interface{}{}, err
 	}
+	data, err = ConvertValues(filename, data)
+	if err != nil {
+		return map[string]interface{}{}, err
+	}
 	return ReadValues(data)
 }
 
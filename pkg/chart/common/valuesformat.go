@@ -0,0 +1,363 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConvertValues converts the content of a values file in TOML, named *.toml,
+// or in JSON5, named *.json5, to JSON, which is read like YAML. The content of
+// other files is returned as is. The format of values fetched from a URL is
+// that of the path of the URL, whatever its query.
+func ConvertValues(filename string, data []byte) ([]byte, error) {
+	switch valuesFormat(filename) {
+	case ".toml":
+		vals := map[string]interface{}{}
+		if err := toml.Unmarshal(data, &vals); err != nil {
+			return nil, fmt.Errorf("cannot parse TOML: %w", err)
+		}
+		return json.Marshal(vals)
+	case ".json5":
+		out, err := json5ToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse JSON5: %w", err)
+		}
+		return out, nil
+	}
+	return data, nil
+}
+
+// valuesFormat returns the lower case extension of the path of a values file,
+// or of the path of its URL.
+func valuesFormat(filename string) string {
+	// Single letter schemes are Windows drive letters.
+	if u, err := url.Parse(filename); err == nil && len(u.Scheme) > 1 {
+		filename = u.Path
+	}
+	return strings.ToLower(path.Ext(filename))
+}
+
+// json5ToJSON converts a JSON5 document to JSON: comments are removed,
+// trailing commas dropped, identifier keys and single quoted strings quoted,
+// and numbers written as JSON numbers. Infinity and NaN have no JSON
+// equivalent and are rejected, as is anything else that is not JSON5.
+func json5ToJSON(data []byte) ([]byte, error) {
+	s := &json5Scanner{data: data}
+	if err := s.skipSpace(); err != nil {
+		return nil, err
+	}
+	if err := s.value(); err != nil {
+		return nil, err
+	}
+	if err := s.skipSpace(); err != nil {
+		return nil, err
+	}
+	if s.pos < len(s.data) {
+		return nil, s.unexpected("the end of the document")
+	}
+	// Numbers such as 01 are scanned as numbers, but are neither JSON5 nor JSON.
+	if !json.Valid(s.out.Bytes()) {
+		return nil, errors.New("invalid number")
+	}
+	return s.out.Bytes(), nil
+}
+
+type json5Scanner struct {
+	data []byte
+	pos  int
+	out  bytes.Buffer
+}
+
+func (s *json5Scanner) errorf(format string, args ...interface{}) error {
+	line := bytes.Count(s.data[:min(s.pos, len(s.data))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips white space and comments.
+func (s *json5Scanner) skipSpace() error {
+	for s.pos < len(s.data) {
+		r, size := utf8.DecodeRune(s.data[s.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\uFEFF':
+			s.pos += size
+		case bytes.HasPrefix(s.data[s.pos:], []byte("//")):
+			end := bytes.IndexByte(s.data[s.pos:], '\n')
+			if end < 0 {
+				s.pos = len(s.data)
+			} else {
+				s.pos += end + 1
+			}
+		case bytes.HasPrefix(s.data[s.pos:], []byte("/*")):
+			end := bytes.Index(s.data[s.pos+2:], []byte("*/"))
+			if end < 0 {
+				return s.errorf("unterminated comment")
+			}
+			s.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// string writes a string quoted with quote as a JSON string.
+func (s *json5Scanner) string(quote byte) error {
+	s.pos++
+	var b strings.Builder
+	for {
+		if s.pos >= len(s.data) {
+			return s.errorf("unterminated string")
+		}
+		r, size := utf8.DecodeRune(s.data[s.pos:])
+		s.pos += size
+		switch {
+		case r == rune(quote):
+			out, err := json.Marshal(b.String())
+			if err != nil {
+				return err
+			}
+			s.out.Write(out)
+			return nil
+		case r == '\n' || r == '\r':
+			return s.errorf("unescaped line break in string")
+		case r != '\\':
+			b.WriteRune(r)
+			continue
+		}
+		if s.pos >= len(s.data) {
+			return s.errorf("unterminated string")
+		}
+		r, size = utf8.DecodeRune(s.data[s.pos:])
+		s.pos += size
+		switch r {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case 'x', 'u':
+			n := 2
+			if r == 'u' {
+				n = 4
+			}
+			if s.pos+n > len(s.data) {
+				return s.errorf("invalid escape sequence")
+			}
+			code, err := strconv.ParseUint(string(s.data[s.pos:s.pos+n]), 16, 32)
+			if err != nil {
+				return s.errorf("invalid escape sequence \\%c%s", r, s.data[s.pos:s.pos+n])
+			}
+			s.pos += n
+			if r == 'u' && utf16IsHighSurrogate(rune(code)) && bytes.HasPrefix(s.data[s.pos:], []byte(`\u`)) && s.pos+6 <= len(s.data) {
+				if low, err := strconv.ParseUint(string(s.data[s.pos+2:s.pos+6]), 16, 32); err == nil && low >= 0xDC00 && low < 0xE000 {
+					code = uint64((rune(code)-0xD800)<<10|(rune(low)-0xDC00)) + 0x10000
+					s.pos += 6
+				}
+			}
+			b.WriteRune(rune(code))
+		case '\r':
+			// A line continuation
+			if s.pos < len(s.data) && s.data[s.pos] == '\n' {
+				s.pos++
+			}
+		case '\n', '\u2028', '\u2029':
+			// A line continuation
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func utf16IsHighSurrogate(r rune) bool {
+	return r >= 0xD800 && r < 0xDC00
+}
+
+// number writes a number as a JSON number.
+func (s *json5Scanner) number() error {
+	start := s.pos
+	for s.pos < len(s.data) && strings.IndexByte("+-.0123456789abcdefABCDEFxXIinftyNa", s.data[s.pos]) >= 0 {
+		s.pos++
+	}
+	lit := string(s.data[start:s.pos])
+	sign := ""
+	switch lit[0] {
+	case '-':
+		sign, lit = "-", lit[1:]
+	case '+':
+		lit = lit[1:]
+	}
+	if strings.HasPrefix(lit, "0x") || strings.HasPrefix(lit, "0X") {
+		n, err := strconv.ParseUint(lit[2:], 16, 64)
+		if err != nil {
+			return s.errorf("invalid number %s", s.data[start:s.pos])
+		}
+		s.out.WriteString(sign + strconv.FormatUint(n, 10))
+		return nil
+	}
+	if strings.HasPrefix(lit, ".") {
+		lit = "0" + lit
+	}
+	lit = strings.Replace(lit, ".e", ".0e", 1)
+	lit = strings.Replace(lit, ".E", ".0E", 1)
+	if strings.HasSuffix(lit, ".") {
+		lit += "0"
+	}
+	if _, err := strconv.ParseFloat(lit, 64); err != nil || strings.ContainsAny(strings.ToLower(lit), "in") {
+		return s.errorf("invalid or unsupported number %s", s.data[start:s.pos])
+	}
+	s.out.WriteString(sign + lit)
+	return nil
+}
+
+// unexpected returns an error for the character at the current position, or
+// for the end of the document, when expecting something else.
+func (s *json5Scanner) unexpected(expected string) error {
+	if s.pos >= len(s.data) {
+		return s.errorf("unexpected end of document, expected %s", expected)
+	}
+	r, _ := utf8.DecodeRune(s.data[s.pos:])
+	return s.errorf("unexpected character %q, expected %s", r, expected)
+}
+
+// value writes a value as JSON.
+func (s *json5Scanner) value() error {
+	if s.pos >= len(s.data) {
+		return s.unexpected("a value")
+	}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		return s.elements('{', '}', s.member)
+	case c == '[':
+		return s.elements('[', ']', s.value)
+	case c == '"' || c == '\'':
+		return s.string(c)
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return s.number()
+	}
+	id := s.identifier()
+	switch id {
+	case "":
+		return s.unexpected("a value")
+	case "true", "false", "null":
+		s.out.WriteString(id)
+		return nil
+	case "Infinity", "NaN":
+		return s.errorf("%s has no JSON equivalent", id)
+	}
+	return s.errorf("unexpected identifier %s, expected a value", id)
+}
+
+// elements writes an object or an array, whose elements element writes, as
+// JSON. The elements are separated by commas, and may be followed by one.
+func (s *json5Scanner) elements(open, closing byte, element func() error) error {
+	s.out.WriteByte(open)
+	s.pos++
+	for first := true; ; first = false {
+		if err := s.skipSpace(); err != nil {
+			return err
+		}
+		if s.pos < len(s.data) && s.data[s.pos] == closing {
+			break
+		}
+		if !first {
+			s.out.WriteByte(',')
+		}
+		if err := element(); err != nil {
+			return err
+		}
+		if err := s.skipSpace(); err != nil {
+			return err
+		}
+		if s.pos < len(s.data) && s.data[s.pos] == ',' {
+			s.pos++
+			continue
+		}
+		if s.pos < len(s.data) && s.data[s.pos] == closing {
+			break
+		}
+		return s.unexpected(fmt.Sprintf("',' or '%c'", closing))
+	}
+	s.out.WriteByte(closing)
+	s.pos++
+	return nil
+}
+
+// member writes a member of an object as JSON, its key quoted.
+func (s *json5Scanner) member() error {
+	if c := s.data[s.pos]; c == '"' || c == '\'' {
+		if err := s.string(c); err != nil {
+			return err
+		}
+	} else {
+		id := s.identifier()
+		if id == "" {
+			return s.unexpected("a key")
+		}
+		out, err := json.Marshal(id)
+		if err != nil {
+			return err
+		}
+		s.out.Write(out)
+	}
+	if err := s.skipSpace(); err != nil {
+		return err
+	}
+	if s.pos >= len(s.data) || s.data[s.pos] != ':' {
+		return s.unexpected("':'")
+	}
+	s.out.WriteByte(':')
+	s.pos++
+	if err := s.skipSpace(); err != nil {
+		return err
+	}
+	return s.value()
+}
+
+// identifier scans an identifier, which does not start with a digit, and
+// returns it, or "" if there is none at the current position.
+func (s *json5Scanner) identifier() string {
+	start := s.pos
+	for s.pos < len(s.data) {
+		r, size := utf8.DecodeRune(s.data[s.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (s.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		s.pos += size
+	}
+	return string(s.data[start:s.pos])
+}
@@ -0,0 +1,142 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertValuesTOML(t *testing.T) {
+	data, err := ConvertValues("values.toml", []byte(`
+replicaCount = 2
+
+[image]
+repository = "nginx"
+tag = "1.27"
+
+[[ingress.hosts]]
+host = "example.com"
+paths = ["/", "/api"]
+`))
+	require.NoError(t, err)
+	vals, err := ReadValues(data)
+	require.NoError(t, err)
+	assert.Equal(t, Values{
+		"replicaCount": float64(2),
+		"image":        map[string]interface{}{"repository": "nginx", "tag": "1.27"},
+		"ingress": map[string]interface{}{"hosts": []interface{}{
+			map[string]interface{}{"host": "example.com", "paths": []interface{}{"/", "/api"}},
+		}},
+	}, vals)
+
+	_, err = ConvertValues("values.toml", []byte("image = "))
+	assert.ErrorContains(t, err, "cannot parse TOML")
+}
+
+func TestConvertValuesJSON5(t *testing.T) {
+	data, err := ConvertValues("VALUES.JSON5", []byte(`// The values of the chart
+{
+  /* The number of pods */
+  replicaCount: 2,
+  image: {
+    repository: 'nginx',
+    tag: "1.27", // pinned
+  },
+  $schema: 'it\'s "quoted"',
+  hosts: ['example.com', 'www.example.com',],
+  ratio: .5,
+  limit: +10.,
+  mask: 0xFF,
+  note: 'line \
+continued\x21',
+  enabled: true,
+  extra: null,
+}
+`))
+	require.NoError(t, err)
+	vals, err := ReadValues(data)
+	require.NoError(t, err)
+	assert.Equal(t, Values{
+		"replicaCount": float64(2),
+		"image":        map[string]interface{}{"repository": "nginx", "tag": "1.27"},
+		"$schema":      `it's "quoted"`,
+		"hosts":        []interface{}{"example.com", "www.example.com"},
+		"ratio":        0.5,
+		"limit":        float64(10),
+		"mask":         float64(255),
+		"note":         "line continued!",
+		"enabled":      true,
+		"extra":        nil,
+	}, vals)
+
+	for _, invalid := range []string{
+		`{a: Infinity}`,
+		`{a: -Infinity}`,
+		`{a: NaN}`,
+		`{a: b}`,
+		`{a: 'unterminated}`,
+		`{a: 1 /* unterminated`,
+		`{a: [1, 2}`,
+		`{a: [1,,2]}`,
+		`{a: [,]}`,
+		`{a: [1 2]}`,
+		`{,}`,
+		`{a: 1 b: 2}`,
+		`{a: 1,, b: 2}`,
+		`{1a: 2}`,
+		`{a:}`,
+		`{a: 01}`,
+		`{a: 1} {}`,
+		``,
+	} {
+		_, err := ConvertValues("values.json5", []byte(invalid))
+		assert.ErrorContains(t, err, "cannot parse JSON5", invalid)
+	}
+}
+
+func TestJSON5ToJSON(t *testing.T) {
+	for in, out := range map[string]string{
+		`[1, 2,]`:               `[1,2]`,
+		`{}`:                    `{}`,
+		`{a: [], b: {},}`:       `{"a":[],"b":{}}`,
+		`'top level'`:           `"top level"`,
+		`-.5e3`:                 `-0.5e3`,
+		`{_a1: [[true, null]]}`: `{"_a1":[[true,null]]}`,
+	} {
+		converted, err := json5ToJSON([]byte(in))
+		require.NoError(t, err, in)
+		assert.Equal(t, out, string(converted), in)
+	}
+}
+
+func TestConvertValuesURL(t *testing.T) {
+	data, err := ConvertValues("https://example.com/values.json5?ref=main", []byte(`{replicaCount: 2}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"replicaCount": 2}`, string(data))
+}
+
+func TestConvertValuesOther(t *testing.T) {
+	data := []byte("replicaCount: 2 # YAML\n")
+	for _, name := range []string{"values.yaml", "values.json", "https://example.com/values", "https://example.com/values?format=.json5", "-"} {
+		converted, err := ConvertValues(name, data)
+		require.NoError(t, err)
+		assert.Equal(t, data, converted)
+	}
+}
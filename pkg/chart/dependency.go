@@ -47,6 +47,34 @@ func (r *v2DependencyAccessor) Name() string {
 	return r.dep.Name
 }
 
+func (r *v2DependencyAccessor) Alias() string {
+	return r.dep.Alias
+}
+
+func (r *v2DependencyAccessor) Version() string {
+	return r.dep.Version
+}
+
+func (r *v2DependencyAccessor) Repository() string {
+	return r.dep.Repository
+}
+
+func (r *v2DependencyAccessor) ValuesFile() string {
+	return r.dep.ValuesFile
+}
+
+func (r *v2DependencyAccessor) Condition() string {
+	return r.dep.Condition
+}
+
+func (r *v2DependencyAccessor) Tags() []string {
+	return r.dep.Tags
+}
+
+func (r *v2DependencyAccessor) Enabled() bool {
+	return r.dep.Enabled
+}
+
 type v3DependencyAccessor struct {
 	dep *v3chart.Dependency
 }
@@ -54,3 +82,31 @@ type v3DependencyAccessor struct {
 func (r *v3DependencyAccessor) Name() string {
 	return r.dep.Name
 }
+
+func (r *v3DependencyAccessor) Alias() string {
+	return r.dep.Alias
+}
+
+func (r *v3DependencyAccessor) Version() string {
+	return r.dep.Version
+}
+
+func (r *v3DependencyAccessor) Repository() string {
+	return r.dep.Repository
+}
+
+func (r *v3DependencyAccessor) ValuesFile() string {
+	return r.dep.ValuesFile
+}
+
+func (r *v3DependencyAccessor) Condition() string {
+	return r.dep.Condition
+}
+
+func (r *v3DependencyAccessor) Tags() []string {
+	return r.dep.Tags
+}
+
+func (r *v3DependencyAccessor) Enabled() bool {
+	return r.dep.Enabled
+}
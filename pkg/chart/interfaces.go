@@ -33,6 +33,7 @@ type Accessor interface {
 	IsLibraryChart() bool
 	Dependencies() []Charter
 	MetaDependencies() []Dependency
+	DisabledDependencies() []Dependency
 	Values() map[string]interface{}
 	Schema() []byte
 	Deprecated() bool
@@ -40,4 +41,11 @@ type Accessor interface {
 
 type DependencyAccessor interface {
 	Name() string
+	Alias() string
+	Version() string
+	Repository() string
+	ValuesFile() string
+	Condition() string
+	Tags() []string
+	Enabled() bool
 }
@@ -54,19 +54,33 @@ type BufferedFile struct {
 // performs important path security checks and should always be used before
 // expanding a tarball
 func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
+	return loadArchiveFiles(in, false)
+}
+
+// LoadArchiveFilesPrefix reads in the files out of the beginning of an
+// archive, such as the first bytes of a download. The file cut off by the end
+// of the input is left out. It performs the same checks as LoadArchiveFiles.
+func LoadArchiveFilesPrefix(in io.Reader) ([]*BufferedFile, error) {
+	return loadArchiveFiles(in, true)
+}
+
+func loadArchiveFiles(in io.Reader, prefix bool) ([]*BufferedFile, error) {
+	files := []*BufferedFile{}
 	unzipped, err := gzip.NewReader(in)
+	if prefix && errors.Is(err, io.ErrUnexpectedEOF) {
+		return files, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	defer unzipped.Close()
 
-	files := []*BufferedFile{}
 	tr := tar.NewReader(unzipped)
 	remainingSize := MaxDecompressedChartSize
 	for {
 		b := bytes.NewBuffer(nil)
 		hd, err := tr.Next()
-		if err == io.EOF {
+		if err == io.EOF || (prefix && errors.Is(err, io.ErrUnexpectedEOF)) {
 			break
 		}
 		if err != nil {
@@ -133,6 +147,9 @@ func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
 		limitedReader := io.LimitReader(tr, remainingSize)
 
 		bytesWritten, err := io.Copy(b, limitedReader)
+		if prefix && errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -20,6 +20,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"strings"
 	"testing"
 )
 
@@ -90,3 +91,45 @@ func TestLoadArchiveFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadArchiveFilesPrefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for _, f := range []struct{ name, data string }{
+		{"chart/Chart.yaml", "name: chart\n"},
+		{"chart/values.yaml", strings.Repeat("# padding\n", 1000)},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0644, Size: int64(len(f.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = tw.Close()
+	_ = gzw.Close()
+
+	// Cut the archive in the middle of values.yaml.
+	head := buf.Bytes()[:buf.Len()-20]
+
+	if _, err := LoadArchiveFiles(bytes.NewReader(head)); err == nil {
+		t.Fatal("expected an error loading a truncated archive")
+	}
+
+	files, err := LoadArchiveFilesPrefix(bytes.NewReader(head))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "Chart.yaml" {
+		t.Fatalf("expected only Chart.yaml, got %v", files)
+	}
+
+	files, err = LoadArchiveFilesPrefix(bytes.NewReader(buf.Bytes()[:5]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected no files, got %v", files)
+	}
+}
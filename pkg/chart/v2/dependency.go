@@ -15,7 +15,10 @@ limitations under the License.
 
 package v2
 
-import "time"
+import (
+	"path/filepath"
+	"time"
+)
 
 // Dependency describes a chart upon which another chart depends.
 //
@@ -31,6 +34,9 @@ type Dependency struct {
 	// A lock file will always produce a single version, while a dependency
 	// may contain a semantic version range.
 	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Channel is the release channel to track, such as "stable" or "beta". It
+	// is resolved with the channels declared in the repository index.
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
 	// The URL to the repository.
 	//
 	// Appending `index.yaml` to this string should result in a URL that can be
@@ -47,6 +53,16 @@ type Dependency struct {
 	ImportValues []interface{} `json:"import-values,omitempty" yaml:"import-values,omitempty"`
 	// Alias usable alias to be used for the chart
 	Alias string `json:"alias,omitempty" yaml:"alias,omitempty"`
+	// ValuesFile is the path, relative to the root of the parent chart, of a
+	// values file holding the values of the parent chart for this dependency.
+	// The values of the parent chart for the dependency take precedence over it.
+	ValuesFile string `json:"valuesFile,omitempty" yaml:"valuesFile,omitempty"`
+	// Digest is the digest of the OCI manifest of the chart version the
+	// dependency was resolved to. It is only recorded in lock files.
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// ArchiveDigest is the SHA256 digest of the chart archive the dependency
+	// was resolved to, as "sha256:<hex>". It is only recorded in lock files.
+	ArchiveDigest string `json:"archiveDigest,omitempty" yaml:"archiveDigest,omitempty"`
 }
 
 // Validate checks for common problems with the dependency datastructure in
@@ -58,6 +74,9 @@ func (d *Dependency) Validate() error {
 	}
 	d.Name = sanitizeString(d.Name)
 	d.Version = sanitizeString(d.Version)
+	d.Channel = sanitizeString(d.Channel)
+	d.Digest = sanitizeString(d.Digest)
+	d.ArchiveDigest = sanitizeString(d.ArchiveDigest)
 	d.Repository = sanitizeString(d.Repository)
 	d.Condition = sanitizeString(d.Condition)
 	for i := range d.Tags {
@@ -66,6 +85,12 @@ func (d *Dependency) Validate() error {
 	if d.Alias != "" && !aliasNameFormat.MatchString(d.Alias) {
 		return ValidationErrorf("dependency %q has disallowed characters in the alias", d.Name)
 	}
+	if d.ValuesFile != "" {
+		d.ValuesFile = sanitizeString(d.ValuesFile)
+		if !filepath.IsLocal(filepath.FromSlash(d.ValuesFile)) {
+			return ValidationErrorf("dependency %q has a values file outside of the chart: %s", d.Name, d.ValuesFile)
+		}
+	}
 	return nil
 }
 
@@ -27,6 +27,8 @@ import (
 type linterOptions struct {
 	KubeVersion          *common.KubeVersion
 	SkipSchemaValidation bool
+	DisabledChecks       []string
+	FailOnRemovedAPIs    bool
 }
 
 type LinterOption func(lo *linterOptions)
@@ -43,6 +45,23 @@ func WithSkipSchemaValidation(skipSchemaValidation bool) LinterOption {
 	}
 }
 
+// WithDisabledChecks disables the template checks with the given names, among
+// rules.TemplateCheckNames.
+func WithDisabledChecks(checks []string) LinterOption {
+	return func(lo *linterOptions) {
+		lo.DisabledChecks = checks
+	}
+}
+
+// WithFailOnRemovedAPIs reports as errors the APIs that the kubernetes version
+// of the lint no longer serves. Deprecated APIs are otherwise warnings, to
+// allow charts to support out-of-date kubernetes versions.
+func WithFailOnRemovedAPIs(failOnRemovedAPIs bool) LinterOption {
+	return func(lo *linterOptions) {
+		lo.FailOnRemovedAPIs = failOnRemovedAPIs
+	}
+}
+
 func RunAll(baseDir string, values map[string]interface{}, namespace string, options ...LinterOption) support.Linter {
 
 	chartDir, _ := filepath.Abs(baseDir)
@@ -58,7 +77,8 @@ func RunAll(baseDir string, values map[string]interface{}, namespace string, opt
 
 	rules.Chartfile(&result)
 	rules.ValuesWithOverrides(&result, values, lo.SkipSchemaValidation)
-	rules.TemplatesWithSkipSchemaValidation(&result, values, namespace, lo.KubeVersion, lo.SkipSchemaValidation)
+	rules.TemplatesWithRemovedAPIErrors(&result, values, namespace, lo.KubeVersion, lo.SkipSchemaValidation, lo.FailOnRemovedAPIs)
+	rules.TemplateChecks(&result, values, namespace, lo.KubeVersion, lo.DisabledChecks)
 	rules.Dependencies(&result)
 	rules.Crds(&result)
 
@@ -16,7 +16,12 @@ limitations under the License.
 
 package rules // import "helm.sh/helm/v4/pkg/chart/v2/lint/rules"
 
-import "testing"
+import (
+	"testing"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
+)
 
 func TestValidateNoDeprecations(t *testing.T) {
 	deprecated := &k8sYamlStruct{
@@ -39,3 +44,28 @@ func TestValidateNoDeprecations(t *testing.T) {
 		t.Errorf("Expected a v1 Pod to not be deprecated")
 	}
 }
+
+func TestDeprecationSeverity(t *testing.T) {
+	// autoscaling/v2beta1 is deprecated in v1.22 and removed in v1.25.
+	hpa := &k8sYamlStruct{APIVersion: "autoscaling/v2beta1", Kind: "HorizontalPodAutoscaler"}
+	for _, tt := range []struct {
+		kubeVersion     *common.KubeVersion
+		removedAsErrors bool
+		severity        int
+	}{
+		{&common.KubeVersion{Version: "v1.22.0", Major: "1", Minor: "22"}, true, support.WarningSev},
+		{&common.KubeVersion{Version: "v1.25.0", Major: "1", Minor: "25"}, true, support.ErrorSev},
+		{&common.KubeVersion{Version: "v1.26.0", Major: "1", Minor: "26"}, true, support.ErrorSev},
+		// Deprecations are warnings unless asked otherwise, see
+		// https://github.com/helm/helm/issues/8596
+		{&common.KubeVersion{Version: "v1.26.0", Major: "1", Minor: "26"}, false, support.WarningSev},
+	} {
+		err := validateNoDeprecations(hpa, tt.kubeVersion)
+		if err == nil {
+			t.Fatalf("Expected %s to be flagged for Kubernetes %s", hpa.APIVersion, tt.kubeVersion)
+		}
+		if severity := deprecationSeverity(err, tt.removedAsErrors); severity != tt.severity {
+			t.Errorf("Expected severity %d for Kubernetes %s, got %d", tt.severity, tt.kubeVersion, severity)
+		}
+	}
+}
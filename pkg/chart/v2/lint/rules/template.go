@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apimachinery/pkg/util/yaml"
 
+	"helm.sh/helm/v4/pkg/annotations"
 	"helm.sh/helm/v4/pkg/chart/common"
 	"helm.sh/helm/v4/pkg/chart/common/util"
 	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
@@ -53,6 +54,13 @@ func TemplatesWithKubeVersion(linter *support.Linter, values map[string]interfac
 
 // TemplatesWithSkipSchemaValidation lints the templates in the Linter, allowing to specify the kubernetes version and if schema validation is enabled or not.
 func TemplatesWithSkipSchemaValidation(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *common.KubeVersion, skipSchemaValidation bool) {
+	TemplatesWithRemovedAPIErrors(linter, values, namespace, kubeVersion, skipSchemaValidation, false)
+}
+
+// TemplatesWithRemovedAPIErrors lints the templates in the Linter like
+// TemplatesWithSkipSchemaValidation, reporting as errors rather than warnings
+// the APIs no longer served by the kubernetes version when removedAPIErrors is set.
+func TemplatesWithRemovedAPIErrors(linter *support.Linter, values map[string]interface{}, namespace string, kubeVersion *common.KubeVersion, skipSchemaValidation, removedAPIErrors bool) {
 	fpath := "templates/"
 	templatesPath := filepath.Join(linter.ChartDir, fpath)
 
@@ -163,10 +171,12 @@ func TemplatesWithSkipSchemaValidation(linter *support.Linter, values map[string
 					// NOTE: set to warnings to allow users to support out-of-date kubernetes
 					// Refs https://github.com/helm/helm/issues/8596
 					linter.RunLinterRule(support.WarningSev, fpath, validateMetadataName(yamlStruct))
-					linter.RunLinterRule(support.WarningSev, fpath, validateNoDeprecations(yamlStruct, kubeVersion))
+					deprecated := validateNoDeprecations(yamlStruct, kubeVersion)
+					linter.RunLinterRule(deprecationSeverity(deprecated, removedAPIErrors), fpath, deprecated)
 
 					linter.RunLinterRule(support.ErrorSev, fpath, validateMatchSelector(yamlStruct, renderedContent))
 					linter.RunLinterRule(support.ErrorSev, fpath, validateListAnnotations(yamlStruct, renderedContent))
+					linter.RunLinterRule(support.WarningSev, fpath, validateHelmAnnotations(yamlStruct))
 				}
 			}
 		}
@@ -327,14 +337,23 @@ func validateListAnnotations(yamlStruct *k8sYamlStruct, manifest string) error {
 		}
 
 		for _, i := range m.Items {
-			if _, ok := i.Metadata.Annotations["helm.sh/resource-policy"]; ok {
-				return errors.New("annotation 'helm.sh/resource-policy' within List objects are ignored")
+			if _, ok := i.Metadata.Annotations[annotations.ResourcePolicy]; ok {
+				return fmt.Errorf("annotation '%s' within List objects are ignored", annotations.ResourcePolicy)
 			}
 		}
 	}
 	return nil
 }
 
+// validateHelmAnnotations checks the values of the Helm annotations of a
+// resource, and reports annotations reserved for Helm that it does not know.
+func validateHelmAnnotations(yamlStruct *k8sYamlStruct) error {
+	if err := annotations.Validate(yamlStruct.Metadata.Annotations); err != nil {
+		return fmt.Errorf("%s %q: %w", yamlStruct.Kind, yamlStruct.Metadata.Name, err)
+	}
+	return nil
+}
+
 // k8sYamlStruct stubs a Kubernetes YAML file.
 type k8sYamlStruct struct {
 	APIVersion string `json:"apiVersion"`
@@ -343,6 +362,7 @@ type k8sYamlStruct struct {
 }
 
 type k8sYamlMetadata struct {
-	Namespace string
-	Name      string
+	Namespace   string
+	Name        string
+	Annotations map[string]string
 }
@@ -401,6 +401,33 @@ func TestEmptyWithCommentsManifests(t *testing.T) {
 		t.Fatalf("Expected 0 lint errors, got %d", l)
 	}
 }
+func TestValidateHelmAnnotations(t *testing.T) {
+	md := &k8sYamlStruct{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Metadata: k8sYamlMetadata{
+			Name: "migrate",
+			Annotations: map[string]string{
+				"helm.sh/hook":        "pre-upgrade",
+				"helm.sh/wait-policy": "skip",
+				"example.com/owner":   "team",
+			},
+		},
+	}
+	if err := validateHelmAnnotations(md); err != nil {
+		t.Fatalf("expected valid Helm annotations to pass, got: %s", err)
+	}
+
+	md.Metadata.Annotations["helm.sh/hook-delete-policy"] = "hook-succeded"
+	err := validateHelmAnnotations(md)
+	if err == nil {
+		t.Fatal("expected an invalid hook delete policy to fail")
+	}
+	if !strings.Contains(err.Error(), `Job "migrate": invalid value "hook-succeded" for annotation helm.sh/hook-delete-policy`) {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
 func TestValidateListAnnotations(t *testing.T) {
 	md := &k8sYamlStruct{
 		APIVersion: "v1",
@@ -0,0 +1,178 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+const checksHelpers = `{{- define "checks.labels" -}}
+app: checks
+tier: web
+{{- end -}}
+`
+
+const checksIndent = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: indent
+  labels:
+    {{ include "checks.labels" . | indent 4 }}
+  annotations: {{- include "checks.labels" . | nindent 4 }}
+data:
+  labels: |
+{{ include "checks.labels" . | indent 4 }}
+`
+
+const checksToYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: toyaml
+data:
+  missing: |
+    {{- toYaml .Values.missing | nindent 4 }}
+  present: |
+    {{- .Values.present | toYaml | nindent 4 }}
+  {{- if .Values.guarded }}
+  guarded: {{ toYaml .Values.guarded }}
+  {{- end }}
+  {{- with .Values.present }}
+  nested: {{ toYaml .missing }}
+  {{- end }}
+`
+
+const checksQuoting = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: quoting
+  labels:
+    enabled: {{ .Values.enabled }}
+spec:
+  selector:
+    matchLabels:
+      app: quoting
+  template:
+    metadata:
+      labels:
+        app: quoting
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        env:
+        - name: DEBUG
+          value: {{ .Values.enabled }}
+        - name: MODE
+          value: {{ .Values.mode | quote }}
+        ports:
+        - containerPort: {{ .Values.port | quote }}
+        - containerPort: {{ .Values.port }}
+`
+
+const checksChomp = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: chomp
+data:
+  a: b
+  {{- .Values.extra }}
+  mode: {{ .Values.mode -}}
+  x
+  {{- $name := .Values.mode }}
+  name: {{ $name }}
+`
+
+func TestTemplateChecks(t *testing.T) {
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "checks",
+			Version:    "0.1.0",
+		},
+		Raw: []*common.File{
+			{Name: "values.yaml", Data: []byte("present:\n  a: 1\nenabled: true\nmode: debug\nport: 8080\nextra: \"\"\n")},
+		},
+		Templates: []*common.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(checksHelpers)},
+			{Name: "templates/indent.yaml", Data: []byte(checksIndent)},
+			{Name: "templates/toyaml.yaml", Data: []byte(checksToYAML)},
+			{Name: "templates/quoting.yaml", Data: []byte(checksQuoting)},
+			{Name: "templates/chomp.yaml", Data: []byte(checksChomp)},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	chartDir := filepath.Join(tmpdir, mychart.Name())
+
+	expected := []string{
+		`templates/chomp.yaml: line 7: {{- removes the line break before {{.Values.extra}}, joining its output to the line "  a: b"`,
+		`templates/chomp.yaml: line 8: -}} removes the line break after {{.Values.mode}}, joining the line "x" to its output`,
+		`templates/indent.yaml: line 6: the output of {{include "checks.labels" . | indent 4}} does not start a line`,
+		`templates/quoting.yaml: Deployment "quoting": metadata.labels.enabled is true, which is not a string: quote it`,
+		`templates/quoting.yaml: Deployment "quoting": spec.template.spec.containers[0].env[0].value is true, which is not a string: quote it`,
+		`templates/quoting.yaml: Deployment "quoting": spec.template.spec.containers[0].ports[0].containerPort is the string "8080", which is not a number: do not quote it`,
+		`templates/toyaml.yaml: line 7: {{toYaml .Values.missing | nindent 4}} renders "null", as .Values.missing is null`,
+	}
+
+	linter := support.Linter{ChartDir: chartDir}
+	TemplateChecks(&linter, values, namespace, nil, nil)
+	var got []string
+	for _, msg := range linter.Messages {
+		if msg.Severity != support.WarningSev {
+			t.Errorf("expected a warning, got %s", msg)
+		}
+		got = append(got, msg.Path+": "+msg.Err.Error())
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d messages, got %d:\n%s", len(expected), len(got), strings.Join(got, "\n"))
+	}
+	for _, want := range expected {
+		found := false
+		for _, g := range got {
+			found = found || strings.HasPrefix(g, want)
+		}
+		if !found {
+			t.Errorf("expected a message starting with %q, got:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+
+	linter = support.Linter{ChartDir: chartDir}
+	TemplateChecks(&linter, values, namespace, nil, []string{IndentCheck, QuotingCheck})
+	for _, msg := range linter.Messages {
+		if strings.Contains(msg.Path, "indent") || strings.Contains(msg.Path, "quoting") {
+			t.Errorf("expected the disabled checks not to run, got %s", msg)
+		}
+	}
+	if len(linter.Messages) != 3 {
+		t.Errorf("expected 3 messages with indent and quoting disabled, got %d", len(linter.Messages))
+	}
+
+	linter = support.Linter{ChartDir: chartDir}
+	TemplateChecks(&linter, values, namespace, nil, TemplateCheckNames)
+	if len(linter.Messages) != 0 {
+		t.Errorf("expected no messages with all checks disabled, got %v", linter.Messages)
+	}
+}
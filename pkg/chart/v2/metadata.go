@@ -44,6 +44,54 @@ func (m *Maintainer) Validate() error {
 	return nil
 }
 
+// Conflict is a chart that cannot be installed in the same cluster as the
+// chart declaring it, such as another ingress controller claiming the same
+// ingress class.
+type Conflict struct {
+	// Name is the name of the conflicting chart.
+	Name string `json:"name"`
+	// Version is a SemVer range of the conflicting versions of the chart. All
+	// its versions conflict when it is empty.
+	Version string `json:"version,omitempty"`
+	// Reason explains the conflict to the users trying to install both.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Validate checks valid data and sanitizes string characters.
+func (c *Conflict) Validate() error {
+	if c == nil {
+		return ValidationError("conflicts must not contain empty or null nodes")
+	}
+	c.Name = sanitizeString(c.Name)
+	c.Version = sanitizeString(c.Version)
+	c.Reason = sanitizeString(c.Reason)
+	if c.Name == "" {
+		return ValidationError("conflicts must have a name")
+	}
+	if c.Version != "" {
+		if _, err := semver.NewConstraint(c.Version); err != nil {
+			return ValidationErrorf("conflict %q has an invalid version range %q", c.Name, c.Version)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether a version of a chart conflicts.
+func (c *Conflict) Matches(name, version string) bool {
+	if name != c.Name {
+		return false
+	}
+	if c.Version == "" {
+		return true
+	}
+	constraint, err := semver.NewConstraint(c.Version)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	return err == nil && constraint.Check(v)
+}
+
 // Metadata for a Chart file. This models the structure of a Chart.yaml file.
 type Metadata struct {
 	// The name of the chart. Required.
@@ -81,6 +129,9 @@ type Metadata struct {
 	Dependencies []*Dependency `json:"dependencies,omitempty"`
 	// Specifies the chart type: application or library
 	Type string `json:"type,omitempty"`
+	// Conflicts are the charts that cannot be installed in the same cluster
+	// as this chart.
+	Conflicts []*Conflict `json:"conflicts,omitempty"`
 }
 
 // Validate checks the metadata for known issues and sanitizes string
@@ -132,6 +183,12 @@ func (md *Metadata) Validate() error {
 		}
 	}
 
+	for _, c := range md.Conflicts {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+
 	// Aliases need to be validated here to make sure that the alias name does
 	// not contain any illegal characters.
 	dependencies := map[string]*Dependency{}
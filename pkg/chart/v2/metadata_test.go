@@ -172,6 +172,16 @@ func TestValidate(t *testing.T) {
 			},
 			ValidationError("maintainers must not contain empty or null nodes"),
 		},
+		{
+			"conflict without name",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Conflicts: []*Conflict{{Version: "^1"}}},
+			ValidationError("conflicts must have a name"),
+		},
+		{
+			"conflict with invalid version range",
+			&Metadata{APIVersion: "v2", Name: "test", Version: "1.0", Conflicts: []*Conflict{{Name: "other", Version: "one"}}},
+			ValidationError("conflict \"other\" has an invalid version range \"one\""),
+		},
 		{
 			"version invalid",
 			&Metadata{APIVersion: "v2", Name: "test", Version: "1.2.3.4"},
@@ -199,3 +209,23 @@ func TestValidate_sanitize(t *testing.T) {
 		t.Fatal("maintainer name was not sanitized")
 	}
 }
+
+func TestConflictMatches(t *testing.T) {
+	tests := []struct {
+		conflict Conflict
+		name     string
+		version  string
+		matches  bool
+	}{
+		{Conflict{Name: "ingress-nginx"}, "ingress-nginx", "4.11.0", true},
+		{Conflict{Name: "ingress-nginx"}, "traefik", "4.11.0", false},
+		{Conflict{Name: "ingress-nginx", Version: "<4.0.0"}, "ingress-nginx", "3.40.0", true},
+		{Conflict{Name: "ingress-nginx", Version: "<4.0.0"}, "ingress-nginx", "4.11.0", false},
+		{Conflict{Name: "ingress-nginx", Version: "<4.0.0"}, "ingress-nginx", "invalid", false},
+	}
+	for _, tt := range tests {
+		if got := tt.conflict.Matches(tt.name, tt.version); got != tt.matches {
+			t.Errorf("expected %+v matching %s-%s to be %t", tt.conflict, tt.name, tt.version, tt.matches)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// AdvisoryEcosystem is the OSV ecosystem of Helm charts. Affected packages
+// without an ecosystem are taken to be charts as well.
+const AdvisoryEcosystem = "Helm"
+
+// Severity is the severity of a security advisory.
+type Severity int
+
+const (
+	SeverityLow Severity = iota
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+	// SeverityUnknown is the severity of advisories that do not have one, or
+	// one that is not understood. It ranks above all the others, so that such
+	// advisories are reported whatever the threshold.
+	SeverityUnknown
+)
+
+var severityNames = map[Severity]string{
+	SeverityLow:      "low",
+	SeverityMedium:   "medium",
+	SeverityHigh:     "high",
+	SeverityCritical: "critical",
+	SeverityUnknown:  "unknown",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity parses a severity, case-insensitively: "low", "medium" (or
+// "moderate"), "high" or "critical".
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return SeverityLow, nil
+	case "medium", "moderate":
+		return SeverityMedium, nil
+	case "high":
+		return SeverityHigh, nil
+	case "critical":
+		return SeverityCritical, nil
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q: expected low, medium, high or critical", s)
+}
+
+// severityFromScore maps a CVSS base score to its qualitative severity. CVSS
+// v2 has no critical severity, its scores of 7 and above are high.
+func severityFromScore(score float64, v2 bool) Severity {
+	switch {
+	case score >= 9 && !v2:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	}
+	return SeverityLow
+}
+
+// Advisory is a security advisory in the OSV format
+// (https://ossf.github.io/osv-schema/). Only the fields needed to match
+// charts are kept.
+type Advisory struct {
+	ID       string   `json:"id"`
+	Summary  string   `json:"summary,omitempty"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity,omitempty"`
+	Affected         []AdvisoryAffected     `json:"affected,omitempty"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific,omitempty"`
+}
+
+// AdvisoryAffected is a package affected by an advisory, and the versions of
+// it that are affected.
+type AdvisoryAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem,omitempty"`
+		Name      string `json:"name"`
+		// Purl is the package URL of the chart, of type helm, such as
+		// "pkg:helm/mariadb?repository_url=https://charts.example.com".
+		// Its repository_url, if any, limits the advisory to the charts of
+		// that repository.
+		Purl string `json:"purl,omitempty"`
+	} `json:"package"`
+	Ranges           []AdvisoryRange        `json:"ranges,omitempty"`
+	Versions         []string               `json:"versions,omitempty"`
+	DatabaseSpecific map[string]interface{} `json:"database_specific,omitempty"`
+}
+
+// AdvisoryRange is a range of affected versions, made of the events at which
+// the vulnerability is introduced and fixed.
+type AdvisoryRange struct {
+	Type   string          `json:"type"`
+	Events []AdvisoryEvent `json:"events"`
+}
+
+// AdvisoryEvent is an event of an AdvisoryRange. Only one of its fields is set.
+type AdvisoryEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+}
+
+// SeverityOf returns the severity of an advisory. It is read from the
+// "severity" of the database_specific field of the affected package or of
+// the advisory, as GitHub advisories have it, or else computed from the CVSS
+// v3 or v2 vector of the advisory. CVSS v4 vectors are not scored.
+func (a *Advisory) SeverityOf(affected *AdvisoryAffected) Severity {
+	for _, specific := range []map[string]interface{}{affected.DatabaseSpecific, a.DatabaseSpecific} {
+		if s, ok := specific["severity"].(string); ok {
+			if sev, err := ParseSeverity(s); err == nil {
+				return sev
+			}
+		}
+	}
+	for _, s := range a.Severity {
+		if s.Type == "CVSS_V3" {
+			if score, err := cvss3BaseScore(s.Score); err == nil {
+				return severityFromScore(score, false)
+			}
+		}
+	}
+	for _, s := range a.Severity {
+		if s.Type == "CVSS_V2" {
+			if score, err := cvss2BaseScore(s.Score); err == nil {
+				return severityFromScore(score, true)
+			}
+		}
+	}
+	return SeverityUnknown
+}
+
+// chart returns the name of the affected chart, and its repository if the
+// advisory limits itself to one.
+func (a *AdvisoryAffected) chart() (name, repository string) {
+	purl, ok := strings.CutPrefix(a.Package.Purl, "pkg:helm/")
+	if !ok {
+		return a.Package.Name, ""
+	}
+	purl, _, _ = strings.Cut(purl, "#")
+	purl, qualifiers, _ := strings.Cut(purl, "?")
+	purl, _, _ = strings.Cut(purl, "@")
+	name = purl[strings.LastIndex(purl, "/")+1:]
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if q, err := url.ParseQuery(qualifiers); err == nil {
+		repository = q.Get("repository_url")
+	}
+	return name, repository
+}
+
+// sameRepository reports whether two chart repository URLs are the same,
+// whatever their scheme, case and trailing slash.
+func sameRepository(a, b string) bool {
+	normalize := func(u string) string {
+		if _, rest, ok := strings.Cut(u, "://"); ok {
+			u = rest
+		}
+		return strings.ToLower(strings.TrimSuffix(u, "/"))
+	}
+	return normalize(a) == normalize(b)
+}
+
+// affects reports whether the affected package is the given chart version,
+// and returns the versions above it that fix it. When both the advisory and
+// the chart name a repository, they must be the same.
+func (a *AdvisoryAffected) affects(name, repository string, version *semver.Version) (bool, []string) {
+	if a.Package.Ecosystem != "" && !strings.EqualFold(a.Package.Ecosystem, AdvisoryEcosystem) {
+		return false, nil
+	}
+	affectedName, affectedRepository := a.chart()
+	if affectedName != name || affectedRepository != "" && repository != "" && !sameRepository(affectedRepository, repository) {
+		return false, nil
+	}
+	for _, v := range a.Versions {
+		if sv, err := semver.NewVersion(v); err == nil && sv.Equal(version) {
+			return true, nil
+		}
+	}
+	for _, r := range a.Ranges {
+		// Chart versions are semantic versions, so the ranges of the Helm
+		// ecosystem are semantic version ranges.
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+		// Events are evaluated in version order: the version is affected
+		// when the last event at or below it introduces the vulnerability.
+		affected := false
+		var fixed []string
+		for _, e := range sortedEvents(r.Events) {
+			switch {
+			case e.introduced != nil:
+				if !version.LessThan(e.introduced) {
+					affected = true
+				}
+			case e.fixed != nil:
+				if version.LessThan(e.fixed) {
+					fixed = append(fixed, e.fixed.Original())
+				} else {
+					affected = false
+				}
+			case e.lastAffected != nil:
+				if version.GreaterThan(e.lastAffected) {
+					affected = false
+				}
+			}
+		}
+		if affected {
+			return true, fixed
+		}
+	}
+	return false, nil
+}
+
+type parsedEvent struct {
+	introduced, fixed, lastAffected *semver.Version
+}
+
+func (e parsedEvent) version() *semver.Version {
+	switch {
+	case e.introduced != nil:
+		return e.introduced
+	case e.fixed != nil:
+		return e.fixed
+	}
+	return e.lastAffected
+}
+
+// sortedEvents parses the events of a range, ignoring the ones that are not
+// semantic versions, and sorts them by version. An introduced version of "0"
+// is the lowest version.
+func sortedEvents(events []AdvisoryEvent) []parsedEvent {
+	var sorted []parsedEvent
+	for _, e := range events {
+		var ev parsedEvent
+		var err error
+		switch {
+		case e.Introduced != "":
+			ev.introduced, err = semver.NewVersion(e.Introduced)
+		case e.Fixed != "":
+			ev.fixed, err = semver.NewVersion(e.Fixed)
+		case e.LastAffected != "":
+			ev.lastAffected, err = semver.NewVersion(e.LastAffected)
+		default:
+			continue
+		}
+		if err == nil {
+			sorted = append(sorted, ev)
+		}
+	}
+	slices.SortStableFunc(sorted, func(a, b parsedEvent) int {
+		return a.version().Compare(b.version())
+	})
+	return sorted
+}
+
+// AdvisoryFeed is a list of security advisories.
+type AdvisoryFeed struct {
+	Advisories []Advisory
+}
+
+// ParseAdvisoryFeed parses a feed of OSV advisories, in JSON or YAML. The
+// feed is either a list of advisories, an object with the list under "vulns",
+// as the OSV API returns them, or a single advisory.
+func ParseAdvisoryFeed(data []byte) (*AdvisoryFeed, error) {
+	feed := &AdvisoryFeed{}
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "-") {
+		if err := yaml.Unmarshal(data, &feed.Advisories); err != nil {
+			return nil, fmt.Errorf("unable to parse advisories: %w", err)
+		}
+		return feed, nil
+	}
+	var list struct {
+		Vulns []Advisory `json:"vulns"`
+	}
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("unable to parse advisories: %w", err)
+	}
+	if list.Vulns != nil {
+		feed.Advisories = list.Vulns
+		return feed, nil
+	}
+	var single Advisory
+	if err := yaml.Unmarshal(data, &single); err != nil {
+		return nil, fmt.Errorf("unable to parse advisories: %w", err)
+	}
+	if single.ID != "" {
+		feed.Advisories = []Advisory{single}
+	}
+	return feed, nil
+}
+
+// AdvisoryMatch is an advisory affecting a chart.
+type AdvisoryMatch struct {
+	// Chart is the path of the chart in the chart tree, for example
+	// "wordpress/charts/mariadb".
+	Chart string `json:"chart"`
+	// Name and Version are the name and the version of the affected chart.
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// ID and Summary are the ones of the advisory.
+	ID      string `json:"id"`
+	Summary string `json:"summary,omitempty"`
+	// Severity is the severity of the advisory.
+	Severity Severity `json:"-"`
+	// Fixed are the versions fixing the advisory, if known.
+	Fixed []string `json:"fixed,omitempty"`
+}
+
+func (m AdvisoryMatch) String() string {
+	s := fmt.Sprintf("%s %s: %s (%s)", m.Chart, m.Version, m.ID, m.Severity)
+	if m.Summary != "" {
+		s += ": " + m.Summary
+	}
+	if len(m.Fixed) > 0 {
+		s += fmt.Sprintf(" (fixed in %s)", strings.Join(m.Fixed, ", "))
+	}
+	return s
+}
+
+// Affecting returns the advisories of the feed affecting a version of a
+// chart from a repository, or from an unknown one if repository is empty.
+// Versions that are not semantic versions are affected by none.
+func (f *AdvisoryFeed) Affecting(name, version, repository string) []AdvisoryMatch {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return nil
+	}
+	var matches []AdvisoryMatch
+	for i := range f.Advisories {
+		a := &f.Advisories[i]
+		for j := range a.Affected {
+			ok, fixed := a.Affected[j].affects(name, repository, v)
+			if !ok {
+				continue
+			}
+			matches = append(matches, AdvisoryMatch{
+				Chart:    name,
+				Name:     name,
+				Version:  version,
+				ID:       a.ID,
+				Summary:  a.Summary,
+				Severity: a.SeverityOf(&a.Affected[j]),
+				Fixed:    fixed,
+			})
+			break
+		}
+	}
+	return matches
+}
+
+// Advisories returns the advisories of the feed affecting a chart and all its
+// dependencies, the chart first. repository is the repository of the chart,
+// if known, and those of its dependencies are read from Chart.lock or else
+// Chart.yaml. Dependencies that are locked in Chart.lock but not in the
+// charts directory are matched with their locked version.
+func Advisories(c *chart.Chart, feed *AdvisoryFeed, repository string) []AdvisoryMatch {
+	var matches []AdvisoryMatch
+	if c.Metadata != nil {
+		for _, m := range feed.Affecting(c.Name(), c.Metadata.Version, repository) {
+			m.Chart = c.ChartFullPath()
+			matches = append(matches, m)
+		}
+	}
+	repositories := make(map[string]string)
+	if c.Metadata != nil {
+		for _, dep := range c.Metadata.Dependencies {
+			if dep != nil {
+				repositories[dep.Name] = dependencyRepository(dep.Repository)
+			}
+		}
+	}
+	if c.Lock != nil {
+		for _, dep := range c.Lock.Dependencies {
+			if dep != nil {
+				repositories[dep.Name] = dependencyRepository(dep.Repository)
+			}
+		}
+	}
+	loaded := make(map[string]bool)
+	for _, dep := range c.Dependencies() {
+		loaded[dep.Name()] = true
+		matches = append(matches, Advisories(dep, feed, repositories[dep.Name()])...)
+	}
+	if c.Lock != nil {
+		for _, dep := range c.Lock.Dependencies {
+			if dep == nil || loaded[dep.Name] {
+				continue
+			}
+			for _, m := range feed.Affecting(dep.Name, dep.Version, repositories[dep.Name]) {
+				m.Chart = c.ChartFullPath() + "/charts/" + dep.Name
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
+
+// dependencyRepository returns the repository URL of a dependency, or "" for
+// local dependencies and for repositories referenced by name, whose URL is
+// not known here.
+func dependencyRepository(repository string) string {
+	if strings.HasPrefix(repository, "file://") || strings.HasPrefix(repository, "@") || strings.HasPrefix(repository, "alias:") {
+		return ""
+	}
+	return repository
+}
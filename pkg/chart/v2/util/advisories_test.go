@@ -0,0 +1,194 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const testAdvisories = `[
+  {
+    "id": "HELM-2026-0001",
+    "summary": "mariadb root password in a ConfigMap",
+    "affected": [{
+      "package": {"ecosystem": "Helm", "name": "mariadb"},
+      "ranges": [{"type": "SEMVER", "events": [
+        {"introduced": "0"}, {"fixed": "1.2.0"}, {"introduced": "2.0.0"}, {"fixed": "2.1.0"}
+      ]}]
+    }],
+    "database_specific": {"severity": "HIGH"}
+  },
+  {
+    "id": "HELM-2026-0002",
+    "affected": [{
+      "package": {"name": "wordpress"},
+      "versions": ["1.0.0"]
+    }],
+    "severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"}]
+  },
+  {
+    "id": "HELM-2026-0003",
+    "affected": [{
+      "package": {"ecosystem": "npm", "name": "wordpress"},
+      "versions": ["1.0.0"]
+    }]
+  },
+  {
+    "id": "HELM-2026-0004",
+    "affected": [{
+      "package": {"ecosystem": "Helm", "name": "memcached"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "3.0.0"}, {"last_affected": "3.1.0"}]}]
+    }]
+  },
+  {
+    "id": "HELM-2026-0005",
+    "affected": [{
+      "package": {"ecosystem": "Helm", "name": "redis", "purl": "pkg:helm/stable/redis?repository_url=https://charts.example.com/stable"},
+      "versions": ["1.0.0"]
+    }],
+    "severity": [{"type": "CVSS_V2", "score": "AV:N/AC:L/Au:N/C:C/I:C/A:C"}]
+  }
+]`
+
+func TestParseAdvisoryFeed(t *testing.T) {
+	feed, err := ParseAdvisoryFeed([]byte(testAdvisories))
+	require.NoError(t, err)
+	assert.Len(t, feed.Advisories, 5)
+
+	feed, err = ParseAdvisoryFeed([]byte(`{"vulns": [{"id": "A"}, {"id": "B"}]}`))
+	require.NoError(t, err)
+	assert.Len(t, feed.Advisories, 2)
+
+	feed, err = ParseAdvisoryFeed([]byte("id: A\nsummary: single\n"))
+	require.NoError(t, err)
+	require.Len(t, feed.Advisories, 1)
+	assert.Equal(t, "single", feed.Advisories[0].Summary)
+
+	_, err = ParseAdvisoryFeed([]byte(`[{"id": 1`))
+	assert.Error(t, err)
+}
+
+func TestAdvisoryFeedAffecting(t *testing.T) {
+	feed, err := ParseAdvisoryFeed([]byte(testAdvisories))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, version string
+		ids           []string
+		fixed         []string
+	}{
+		{"mariadb", "1.1.9", []string{"HELM-2026-0001"}, []string{"1.2.0", "2.1.0"}},
+		{"mariadb", "1.2.0", nil, nil},
+		{"mariadb", "2.0.5", []string{"HELM-2026-0001"}, []string{"2.1.0"}},
+		{"mariadb", "2.1.0", nil, nil},
+		{"mariadb", "not-a-version", nil, nil},
+		{"wordpress", "1.0.0", []string{"HELM-2026-0002"}, nil},
+		{"wordpress", "1.0.1", nil, nil},
+		{"memcached", "3.1.0", []string{"HELM-2026-0004"}, nil},
+		{"memcached", "3.1.1", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"-"+tt.version, func(t *testing.T) {
+			var ids []string
+			var fixed []string
+			for _, m := range feed.Affecting(tt.name, tt.version, "") {
+				ids = append(ids, m.ID)
+				fixed = append(fixed, m.Fixed...)
+			}
+			assert.Equal(t, tt.ids, ids)
+			assert.Equal(t, tt.fixed, fixed)
+		})
+	}
+}
+
+func TestAdvisoryFeedAffectingRepository(t *testing.T) {
+	feed, err := ParseAdvisoryFeed([]byte(testAdvisories))
+	require.NoError(t, err)
+
+	tests := []struct {
+		repository string
+		affected   bool
+	}{
+		// Charts from an unknown repository are matched by name.
+		{"", true},
+		{"https://charts.example.com/stable", true},
+		{"https://Charts.example.com/stable/", true},
+		{"oci://charts.example.com/stable", true},
+		{"https://charts.example.com/incubator", false},
+		{"https://mirror.example.com/stable", false},
+	}
+	for _, tt := range tests {
+		matches := feed.Affecting("redis", "1.0.0", tt.repository)
+		assert.Equal(t, tt.affected, len(matches) == 1, "redis from %q", tt.repository)
+	}
+	// The advisories without a repository affect the charts of any repository.
+	assert.Len(t, feed.Affecting("wordpress", "1.0.0", "https://charts.example.com/stable"), 1)
+}
+
+func TestAdvisorySeverity(t *testing.T) {
+	feed, err := ParseAdvisoryFeed([]byte(testAdvisories))
+	require.NoError(t, err)
+
+	severities := map[string]Severity{}
+	for _, chart := range [][2]string{{"mariadb", "1.0.0"}, {"wordpress", "1.0.0"}, {"memcached", "3.0.0"}, {"redis", "1.0.0"}} {
+		for _, m := range feed.Affecting(chart[0], chart[1], "") {
+			severities[m.ID] = m.Severity
+		}
+	}
+	assert.Equal(t, map[string]Severity{
+		"HELM-2026-0001": SeverityHigh,
+		"HELM-2026-0002": SeverityCritical,
+		"HELM-2026-0004": SeverityUnknown,
+		// CVSS v2 has no critical severity.
+		"HELM-2026-0005": SeverityHigh,
+	}, severities)
+
+	s, err := ParseSeverity("Moderate")
+	require.NoError(t, err)
+	assert.Equal(t, SeverityMedium, s)
+	_, err = ParseSeverity("severe")
+	assert.Error(t, err)
+}
+
+func TestAdvisories(t *testing.T) {
+	feed, err := ParseAdvisoryFeed([]byte(testAdvisories))
+	require.NoError(t, err)
+
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "wordpress", Version: "1.0.0"}}
+	mariadb := &chart.Chart{Metadata: &chart.Metadata{Name: "mariadb", Version: "2.0.0"}}
+	c.AddDependency(mariadb)
+	c.Lock = &chart.Lock{Dependencies: []*chart.Dependency{
+		{Name: "mariadb", Version: "2.0.0"},
+		{Name: "memcached", Version: "3.0.1"},
+		{Name: "redis", Version: "1.0.0", Repository: "https://charts.example.com/incubator"},
+	}}
+
+	var got []string
+	for _, m := range Advisories(c, feed, "") {
+		got = append(got, m.String())
+	}
+	assert.Equal(t, []string{
+		"wordpress 1.0.0: HELM-2026-0002 (critical)",
+		"wordpress/charts/mariadb 2.0.0: HELM-2026-0001 (high): mariadb root password in a ConfigMap (fixed in 2.1.0)",
+		"wordpress/charts/memcached 3.0.1: HELM-2026-0004 (unknown)",
+	}, got)
+}
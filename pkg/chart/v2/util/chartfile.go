@@ -70,6 +70,47 @@ func SaveChartfile(filename string, cf *chart.Metadata) error {
 	return os.WriteFile(filename, out, 0644)
 }
 
+// AddChartfileDependency adds a dependency to the Chart.yaml file of the chart
+// directory chartDir, keeping the comments and formatting of the file.
+func AddChartfileDependency(chartDir string, dep *chart.Dependency) error {
+	filename := filepath.Join(chartDir, ChartfileName)
+	doc, err := loadDependenciesChartfile(filename)
+	if err != nil {
+		return err
+	}
+	if err := doc.AddDependency(dep); err != nil {
+		return err
+	}
+	return doc.WriteFile(filename, 0644)
+}
+
+// RemoveChartfileDependency removes the dependency loaded under name, its
+// alias or else its name, from the Chart.yaml file of the chart directory
+// chartDir, keeping the comments and formatting of the file.
+func RemoveChartfileDependency(chartDir, name string) error {
+	filename := filepath.Join(chartDir, ChartfileName)
+	doc, err := loadDependenciesChartfile(filename)
+	if err != nil {
+		return err
+	}
+	if !doc.RemoveDependency(name) {
+		return fmt.Errorf("the chart has no dependency named %q", name)
+	}
+	return doc.WriteFile(filename, 0644)
+}
+
+// loadDependenciesChartfile loads a Chart.yaml file to edit its dependencies.
+func loadDependenciesChartfile(filename string) (*YAMLDocument, error) {
+	md, err := LoadChartfile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if md.APIVersion == chart.APIVersionV1 {
+		return nil, fmt.Errorf("chart %s has apiVersion %s, which declares its dependencies in requirements.yaml", md.Name, chart.APIVersionV1)
+	}
+	return LoadYAMLDocument(filename)
+}
+
 // IsChartDir validate a chart directory.
 //
 // Checks for a valid Chart.yaml.
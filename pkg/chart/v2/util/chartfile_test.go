@@ -17,6 +17,8 @@ limitations under the License.
 package util
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	chart "helm.sh/helm/v4/pkg/chart/v2"
@@ -119,3 +121,43 @@ func TestIsChartDir(t *testing.T) {
 		return
 	}
 }
+
+func TestChartfileDependencies(t *testing.T) {
+	dir := t.TempDir()
+	chartfile := filepath.Join(dir, ChartfileName)
+	if err := os.WriteFile(chartfile, []byte("apiVersion: v2\nname: frontend # the web frontend\nversion: 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddChartfileDependency(dir, &chart.Dependency{Name: "redis", Version: "17.0.0", Repository: "@stable", Alias: "cache"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddChartfileDependency(dir, &chart.Dependency{Name: "memcached", Alias: "cache"}); err == nil {
+		t.Error("expected an error adding a dependency with the same alias")
+	}
+	md, err := LoadChartfile(chartfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(md.Dependencies) != 1 || md.Dependencies[0].Name != "redis" || md.Dependencies[0].Alias != "cache" {
+		t.Errorf("unexpected dependencies: %v", md.Dependencies)
+	}
+
+	if err := RemoveChartfileDependency(dir, "redis"); err == nil {
+		t.Error("expected an error removing a dependency by the name it is not loaded under")
+	}
+	if err := RemoveChartfileDependency(dir, "cache"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(chartfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "apiVersion: v2\nname: frontend # the web frontend\nversion: 0.1.0\n" {
+		t.Errorf("unexpected Chart.yaml:\n%s", data)
+	}
+
+	if err := AddChartfileDependency("testdata/frobnitz", &chart.Dependency{Name: "redis"}); err == nil {
+		t.Error("expected an error adding a dependency to a chart with apiVersion v1")
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// The weights of the base metrics of CVSS v3.x
+// (https://www.first.org/cvss/v3.1/specification-document#7-4-Metric-Values).
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"S":  {"U": 0, "C": 0},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// The weights of the base metrics of CVSS v2
+// (https://www.first.org/cvss/v2/guide#3-2-1-Base-Equation).
+var cvss2Weights = map[string]map[string]float64{
+	"AV": {"L": 0.395, "A": 0.646, "N": 1},
+	"AC": {"H": 0.35, "M": 0.61, "L": 0.71},
+	"Au": {"M": 0.45, "S": 0.56, "N": 0.704},
+	"C":  {"N": 0, "P": 0.275, "C": 0.66},
+	"I":  {"N": 0, "P": 0.275, "C": 0.66},
+	"A":  {"N": 0, "P": 0.275, "C": 0.66},
+}
+
+// cvssMetrics parses the metrics of a CVSS vector, and returns the weights of
+// its base metrics. Metrics other than the base metrics are ignored.
+func cvssMetrics(metrics []string, weights map[string]map[string]float64) (map[string]string, map[string]float64, error) {
+	values := make(map[string]string, len(weights))
+	w := make(map[string]float64, len(weights))
+	for _, m := range metrics {
+		name, value, ok := strings.Cut(m, ":")
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid CVSS metric %q", m)
+		}
+		known, isBase := weights[name]
+		if !isBase {
+			continue
+		}
+		weight, ok := known[value]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid value %q of CVSS metric %s", value, name)
+		}
+		values[name] = value
+		w[name] = weight
+	}
+	for name := range weights {
+		if _, ok := values[name]; !ok {
+			return nil, nil, fmt.Errorf("missing CVSS base metric %s", name)
+		}
+	}
+	return values, w, nil
+}
+
+// cvss3BaseScore computes the base score of a CVSS v3.0 or v3.1 vector, such
+// as "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H".
+func cvss3BaseScore(vector string) (float64, error) {
+	metrics := strings.Split(vector, "/")
+	if metrics[0] != "CVSS:3.0" && metrics[0] != "CVSS:3.1" {
+		return 0, fmt.Errorf("%q is not a CVSS v3 vector", vector)
+	}
+	values, w, err := cvssMetrics(metrics[1:], cvss3Weights)
+	if err != nil {
+		return 0, err
+	}
+	changed := values["S"] == "C"
+	// The privileges required weigh more when the scope changes.
+	if changed {
+		switch values["PR"] {
+		case "L":
+			w["PR"] = 0.68
+		case "H":
+			w["PR"] = 0.5
+		}
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, nil
+	}
+	exploitability := 8.22 * w["AV"] * w["AC"] * w["PR"] * w["UI"]
+	if changed {
+		return cvss3Roundup(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return cvss3Roundup(math.Min(impact+exploitability, 10)), nil
+}
+
+// cvss3Roundup returns the smallest number, to one decimal place, that is at
+// least x, avoiding floating point errors as CVSS v3.1 specifies.
+func cvss3Roundup(x float64) float64 {
+	i := math.Round(x * 100000)
+	if math.Mod(i, 10000) == 0 {
+		return i / 100000
+	}
+	return (math.Floor(i/10000) + 1) / 10
+}
+
+// cvss2BaseScore computes the base score of a CVSS v2 vector, such as
+// "AV:N/AC:L/Au:N/C:P/I:P/A:P".
+func cvss2BaseScore(vector string) (float64, error) {
+	_, w, err := cvssMetrics(strings.Split(vector, "/"), cvss2Weights)
+	if err != nil {
+		return 0, err
+	}
+	impact := 10.41 * (1 - (1-w["C"])*(1-w["I"])*(1-w["A"]))
+	if impact == 0 {
+		return 0, nil
+	}
+	exploitability := 20 * w["AV"] * w["AC"] * w["Au"]
+	return math.Round((0.6*impact+0.4*exploitability-1.5)*1.176*10) / 10, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCVSSBaseScore(t *testing.T) {
+	tests := []struct {
+		vector string
+		score  float64
+	}{
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", 9.8},
+		{"CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:H/I:H/A:H", 9.9},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", 6.1},
+		{"CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N", 5.5},
+		{"CVSS:3.1/AV:N/AC:H/PR:N/UI:R/S:U/C:L/I:N/A:N", 3.1},
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", 0},
+		// Temporal and environmental metrics do not change the base score.
+		{"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H/E:U/RL:O", 9.8},
+		{"AV:N/AC:L/Au:N/C:P/I:P/A:P", 7.5},
+		{"AV:N/AC:L/Au:N/C:C/I:C/A:C", 10},
+		{"AV:N/AC:M/Au:N/C:N/I:P/A:N", 4.3},
+		{"AV:N/AC:L/Au:N/C:N/I:N/A:N", 0},
+	}
+	for _, tt := range tests {
+		score, err := cvss3BaseScore(tt.vector)
+		if err != nil {
+			score, err = cvss2BaseScore(tt.vector)
+		}
+		if assert.NoError(t, err, tt.vector) {
+			assert.Equal(t, tt.score, score, tt.vector)
+		}
+	}
+
+	for _, vector := range []string{
+		"9.8",
+		"CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N",
+		"CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H",
+		"CVSS:3.1/AV:X/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H",
+	} {
+		_, err := cvss3BaseScore(vector)
+		assert.Error(t, err, vector)
+	}
+	_, err := cvss2BaseScore("AV:N/AC:L/Au:N/C:P/I:P")
+	assert.Error(t, err)
+}
@@ -36,11 +36,17 @@ func ProcessDependencies(c *chart.Chart, v common.Values) error {
 }
 
 // processDependencyConditions disables charts based on condition path value in values
-func processDependencyConditions(reqs []*chart.Dependency, cvals common.Values, cpath string) {
+func processDependencyConditions(reqs []*chart.Dependency, cvals common.Values, cpath string) error {
 	if reqs == nil {
-		return
+		return nil
 	}
 	for _, r := range reqs {
+		if util.IsConditionExpression(r.Condition) {
+			if err := processDependencyConditionExpression(r, cvals, cpath); err != nil {
+				return err
+			}
+			continue
+		}
 		for c := range strings.SplitSeq(strings.TrimSpace(r.Condition), ",") {
 			if len(c) > 0 {
 				// retrieve value
@@ -59,6 +65,32 @@ func processDependencyConditions(reqs []*chart.Dependency, cvals common.Values,
 			}
 		}
 	}
+	return nil
+}
+
+// processDependencyConditionExpression enables or disables a dependency using
+// its condition as a CEL expression over the values of the parent chart.
+func processDependencyConditionExpression(r *chart.Dependency, cvals common.Values, cpath string) error {
+	prg, err := util.CompileCondition(r.Condition)
+	if err != nil {
+		return fmt.Errorf("dependency %s: %w", r.Name, err)
+	}
+	vals := cvals
+	if cpath != "" {
+		if vals, err = cvals.Table(strings.TrimSuffix(cpath, ".")); err != nil {
+			vals = common.Values{}
+		}
+	}
+	enabled, err := util.EvalCondition(prg, vals)
+	if err != nil {
+		// Like a path that has no value, an expression that cannot be
+		// evaluated, e.g. because it refers to a missing value, leaves the
+		// dependency enabled.
+		slog.Warn("unable to evaluate condition", "condition", r.Condition, "chart", r.Name, slog.Any("error", err))
+		return nil
+	}
+	r.Enabled = enabled
+	return nil
 }
 
 // processDependencyTags disables charts based on tags in values
@@ -185,7 +217,9 @@ Loop:
 	}
 	// flag dependencies as enabled/disabled
 	processDependencyTags(c.Metadata.Dependencies, cvals)
-	processDependencyConditions(c.Metadata.Dependencies, cvals, path)
+	if err := processDependencyConditions(c.Metadata.Dependencies, cvals, path); err != nil {
+		return err
+	}
 	// make a map of charts to remove
 	rm := map[string]struct{}{}
 	for _, r := range c.Metadata.Dependencies {
@@ -208,6 +242,9 @@ Loop:
 	for _, n := range c.Metadata.Dependencies {
 		if _, ok := rm[n.Name]; !ok {
 			cdMetadata = append(cdMetadata, n)
+		} else {
+			// remember them for templates to tell which are disabled
+			c.AddDisabledDependency(n)
 		}
 	}
 
@@ -17,6 +17,7 @@ package util
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"testing"
@@ -568,3 +569,88 @@ func TestChartWithDependencyAliasedTwiceAndDoublyReferencedSubDependency(t *test
 	}
 	validateDependencyTree(t, c)
 }
+
+func TestDependencyEnabledConditionExpression(t *testing.T) {
+	newChart := func() *chart.Chart {
+		c := &chart.Chart{
+			Metadata: &chart.Metadata{
+				Name: "parent",
+				Dependencies: []*chart.Dependency{
+					{Name: "ha", Condition: "values.replicas > 1 && !values.externalDB"},
+					{Name: "db", Condition: "!has(values.externalDB) || !values.externalDB"},
+					{Name: "child", Condition: "child.enabled"},
+				},
+			},
+			Values: map[string]interface{}{"replicas": 1},
+		}
+		child := &chart.Chart{
+			Metadata: &chart.Metadata{
+				Name:         "child",
+				Dependencies: []*chart.Dependency{{Name: "grandchild", Condition: `values.mode == "cluster"`}},
+			},
+			Values: map[string]interface{}{"mode": "standalone"},
+		}
+		child.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "grandchild"}})
+		c.AddDependency(&chart.Chart{Metadata: &chart.Metadata{Name: "ha"}}, &chart.Chart{Metadata: &chart.Metadata{Name: "db"}}, child)
+		return c
+	}
+	names := func(c *chart.Chart) []string {
+		var names []string
+		for _, d := range c.Dependencies() {
+			names = append(names, d.Name())
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		values   map[string]interface{}
+		expected []string
+		nested   []string
+	}{
+		{
+			name:     "defaults",
+			expected: []string{"db", "child"},
+		},
+		{
+			name:     "scaled out with external database",
+			values:   map[string]interface{}{"replicas": 3, "externalDB": true},
+			expected: []string{"child"},
+		},
+		{
+			name:     "scaled out",
+			values:   map[string]interface{}{"replicas": 3, "externalDB": false},
+			expected: []string{"ha", "db", "child"},
+		},
+		{
+			name:     "nested condition uses the values of its chart",
+			values:   map[string]interface{}{"child": map[string]interface{}{"mode": "cluster"}},
+			expected: []string{"db", "child"},
+			nested:   []string{"grandchild"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newChart()
+			if err := processDependencyEnabled(c, tt.values, ""); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got := names(c); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected dependencies %v, got %v", tt.expected, got)
+			}
+			for _, d := range c.Dependencies() {
+				if d.Name() == "child" {
+					if got := names(d); !slices.Equal(got, tt.nested) {
+						t.Errorf("expected nested dependencies %v, got %v", tt.nested, got)
+					}
+				}
+			}
+		})
+	}
+
+	c := newChart()
+	c.Metadata.Dependencies[0].Condition = "values.replicas >"
+	if err := processDependencyEnabled(c, nil, ""); err == nil {
+		t.Error("expected an error for an invalid condition")
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// LicenseAnnotation is the chart annotation holding the SPDX license
+// expression of a chart.
+const LicenseAnnotation = "artifacthub.io/license"
+
+// licenseFileNames are the names of the license files of a chart, compared
+// case-insensitively and without their extension.
+var licenseFileNames = []string{"license", "licence", "copying"}
+
+// License is the license information of a chart.
+type License struct {
+	// Chart is the path of the chart in the chart tree, for example
+	// "wordpress/charts/mariadb".
+	Chart string `json:"chart"`
+	// Version is the version of the chart.
+	Version string `json:"version"`
+	// Expression is the SPDX license expression of the chart. It is read from
+	// the LicenseAnnotation annotation of the chart or, when the chart has
+	// none, made of the licenses recognized in its license files.
+	Expression string `json:"expression,omitempty"`
+	// Files are the license files at the root of the chart.
+	Files []string `json:"files,omitempty"`
+}
+
+// Licenses returns the license information of a chart and all its
+// dependencies, the chart first.
+func Licenses(c *chart.Chart) []License {
+	l := License{Chart: c.ChartFullPath()}
+	if c.Metadata != nil {
+		l.Version = c.Metadata.Version
+		l.Expression = strings.TrimSpace(c.Metadata.Annotations[LicenseAnnotation])
+	}
+	var detected []string
+	for _, f := range c.Files {
+		if f == nil || strings.Contains(f.Name, "/") || !isLicenseFile(f.Name) {
+			continue
+		}
+		l.Files = append(l.Files, f.Name)
+		if id := DetectLicense(f.Data); id != "" {
+			detected = append(detected, id)
+		}
+	}
+	if l.Expression == "" {
+		l.Expression = strings.Join(detected, " AND ")
+	}
+
+	licenses := []License{l}
+	for _, dep := range c.Dependencies() {
+		licenses = append(licenses, Licenses(dep)...)
+	}
+	return licenses
+}
+
+func isLicenseFile(name string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	for _, n := range licenseFileNames {
+		if name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// licenseMarkers are phrases identifying common licenses in license files.
+// Each license is identified by all its phrases, and the first one matching
+// wins, so more specific licenses come first.
+var licenseMarkers = []struct {
+	id      string
+	phrases []string
+}{
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"AGPL-3.0", []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license", "version 2"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"SSPL-1.0", []string{"server side public license"}},
+	{"BUSL-1.1", []string{"business source license 1.1"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"Unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+}
+
+// DetectLicense returns the SPDX identifier of the license in the text of a
+// license file, or an empty string if the license is not recognized.
+func DetectLicense(data []byte) string {
+	text := strings.Join(strings.Fields(string(bytes.ToLower(data))), " ")
+	for _, m := range licenseMarkers {
+		matches := true
+		for _, p := range m.phrases {
+			if !strings.Contains(text, p) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return m.id
+		}
+	}
+	return ""
+}
+
+// LicenseIDs returns the license identifiers of an SPDX license expression,
+// leaving out operators and license exceptions.
+func LicenseIDs(expr string) []string {
+	var ids []string
+	tokens := tokenizeLicenseExpression(expr)
+	for i, t := range tokens {
+		switch {
+		case t == "(" || t == ")" || isLicenseOperator(t):
+		case i > 0 && strings.EqualFold(tokens[i-1], "WITH"):
+		default:
+			ids = append(ids, t)
+		}
+	}
+	return ids
+}
+
+// EvalLicenseExpression reports whether an SPDX license expression is
+// satisfied when the licenses for which allowed returns true can be chosen:
+// one of the sides of an OR must be allowed, and both sides of an AND.
+// License exceptions are ignored, and the "+" suffix of a license is removed
+// before it is checked.
+func EvalLicenseExpression(expr string, allowed func(id string) bool) (bool, error) {
+	p := &licenseParser{tokens: tokenizeLicenseExpression(expr), allowed: allowed}
+	if len(p.tokens) == 0 {
+		return false, errors.New("empty license expression")
+	}
+	ok, err := p.or()
+	if err != nil {
+		return false, fmt.Errorf("invalid license expression %q: %w", expr, err)
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("invalid license expression %q: unexpected %q", expr, p.tokens[p.pos])
+	}
+	return ok, nil
+}
+
+func tokenizeLicenseExpression(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	return strings.Fields(expr)
+}
+
+func isLicenseOperator(t string) bool {
+	return strings.EqualFold(t, "AND") || strings.EqualFold(t, "OR") || strings.EqualFold(t, "WITH")
+}
+
+// licenseParser evaluates SPDX license expressions, where WITH binds tighter
+// than AND, which binds tighter than OR.
+type licenseParser struct {
+	tokens  []string
+	pos     int
+	allowed func(string) bool
+}
+
+func (p *licenseParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && strings.EqualFold(p.tokens[p.pos], op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *licenseParser) or() (bool, error) {
+	ok, err := p.and()
+	if err != nil {
+		return false, err
+	}
+	for p.accept("OR") {
+		right, err := p.and()
+		if err != nil {
+			return false, err
+		}
+		ok = ok || right
+	}
+	return ok, nil
+}
+
+func (p *licenseParser) and() (bool, error) {
+	ok, err := p.license()
+	if err != nil {
+		return false, err
+	}
+	for p.accept("AND") {
+		right, err := p.license()
+		if err != nil {
+			return false, err
+		}
+		ok = ok && right
+	}
+	return ok, nil
+}
+
+func (p *licenseParser) license() (bool, error) {
+	if p.pos >= len(p.tokens) {
+		return false, errors.New("unexpected end")
+	}
+	var ok bool
+	switch t := p.tokens[p.pos]; {
+	case t == "(":
+		p.pos++
+		var err error
+		if ok, err = p.or(); err != nil {
+			return false, err
+		}
+		if !p.accept(")") {
+			return false, errors.New("missing closing parenthesis")
+		}
+	case t == ")" || isLicenseOperator(t):
+		return false, fmt.Errorf("unexpected %q", t)
+	default:
+		p.pos++
+		ok = p.allowed(strings.TrimSuffix(t, "+"))
+	}
+	if p.accept("WITH") {
+		if p.pos >= len(p.tokens) {
+			return false, errors.New("missing license exception")
+		}
+		p.pos++
+	}
+	return ok, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestLicenses(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:        "wordpress",
+			Version:     "1.0.0",
+			Annotations: map[string]string{LicenseAnnotation: "Apache-2.0"},
+		},
+		Files: []*common.File{
+			{Name: "LICENSE", Data: []byte("Apache License\nVersion 2.0, January 2004")},
+		},
+	}
+	mariadb := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "mariadb", Version: "2.0.0"},
+		Files: []*common.File{
+			{Name: "COPYING.md", Data: []byte("GNU GENERAL PUBLIC LICENSE\n   Version 2, June 1991")},
+			{Name: "docs/LICENSE", Data: []byte("MIT License\n\nPermission is hereby granted, free of charge")},
+		},
+	}
+	c.AddDependency(mariadb, &chart.Chart{Metadata: &chart.Metadata{Name: "common", Version: "3.0.0"}})
+
+	assert.Equal(t, []License{
+		{Chart: "wordpress", Version: "1.0.0", Expression: "Apache-2.0", Files: []string{"LICENSE"}},
+		{Chart: "wordpress/charts/mariadb", Version: "2.0.0", Expression: "GPL-2.0", Files: []string{"COPYING.md"}},
+		{Chart: "wordpress/charts/common", Version: "3.0.0"},
+	}, Licenses(c))
+}
+
+func TestDetectLicense(t *testing.T) {
+	tests := map[string]string{
+		"                   Apache License\n             Version 2.0, January 2004":                          "Apache-2.0",
+		"MIT License\n\nPermission is hereby granted, free of charge, to any person":                         "MIT",
+		"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n... GNU General Public License ...":     "LGPL-3.0",
+		"Redistribution and use in source and binary forms ... Neither the name of the copyright holder nor": "BSD-3-Clause",
+		"All rights reserved.": "",
+	}
+	for text, id := range tests {
+		assert.Equal(t, id, DetectLicense([]byte(text)), text)
+	}
+}
+
+func TestEvalLicenseExpression(t *testing.T) {
+	allowed := func(id string) bool { return slices.Contains([]string{"MIT", "Apache-2.0"}, id) }
+	tests := []struct {
+		expr    string
+		ok      bool
+		wantErr string
+	}{
+		{expr: "MIT", ok: true},
+		{expr: "GPL-3.0", ok: false},
+		{expr: "MIT OR GPL-3.0", ok: true},
+		{expr: "MIT AND GPL-3.0", ok: false},
+		{expr: "GPL-3.0 OR MIT AND Apache-2.0", ok: true},
+		{expr: "(GPL-3.0 OR MIT) AND Apache-2.0", ok: true},
+		{expr: "(GPL-3.0 OR MIT) AND BSD-2-Clause", ok: false},
+		{expr: "Apache-2.0 WITH LLVM-exception and MIT", ok: true},
+		{expr: "Apache-2.0+", ok: true},
+		{expr: "MIT AND", wantErr: `invalid license expression "MIT AND": unexpected end`},
+		{expr: "(MIT", wantErr: `invalid license expression "(MIT": missing closing parenthesis`},
+		{expr: "MIT Apache-2.0", wantErr: `invalid license expression "MIT Apache-2.0": unexpected "Apache-2.0"`},
+		{expr: " ", wantErr: "empty license expression"},
+	}
+	for _, tt := range tests {
+		ok, err := EvalLicenseExpression(tt.expr, allowed)
+		if tt.wantErr != "" {
+			assert.EqualError(t, err, tt.wantErr, tt.expr)
+			continue
+		}
+		assert.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.ok, ok, tt.expr)
+	}
+}
+
+func TestLicenseIDs(t *testing.T) {
+	assert.Equal(t, []string{"MIT", "Apache-2.0", "GPL-2.0"}, LicenseIDs("(MIT OR Apache-2.0 WITH LLVM-exception) AND GPL-2.0"))
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"helm.sh/helm/v4/pkg/chart/common"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// ValuesSplit is the split of the values of an umbrella chart between the
+// chart and its dependencies.
+type ValuesSplit struct {
+	// Values are the values of the chart itself, along with the globals.
+	Values map[string]interface{}
+	// Dependencies are the values of the chart for each of its dependencies,
+	// keyed by the name the dependencies are loaded under: their alias, or
+	// else their name.
+	Dependencies map[string]map[string]interface{}
+}
+
+// SplitValues splits the values of an umbrella chart into the values of the
+// chart itself and the values it has for each of its dependencies.
+//
+// A dependency with an alias only gets the values under its alias. Globals
+// stay with the values of the chart, from which they are passed down to all
+// the dependencies, and so do the values for a dependency that are not a
+// table. The values are not copied.
+func SplitValues(c *chart.Chart, vals map[string]interface{}) ValuesSplit {
+	split := ValuesSplit{
+		Values:       make(map[string]interface{}, len(vals)),
+		Dependencies: make(map[string]map[string]interface{}),
+	}
+	names := make(map[string]bool)
+	if c.Metadata != nil {
+		for _, dep := range c.Metadata.Dependencies {
+			if dep == nil {
+				continue
+			}
+			name := dep.Name
+			if dep.Alias != "" {
+				name = dep.Alias
+			}
+			names[name] = true
+		}
+	}
+	for k, v := range vals {
+		if table, ok := v.(map[string]interface{}); ok && names[k] && k != common.GlobalKey {
+			split.Dependencies[k] = table
+			continue
+		}
+		split.Values[k] = v
+	}
+	return split
+}
@@ -0,0 +1,56 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+func TestSplitValues(t *testing.T) {
+	c := &chart.Chart{Metadata: &chart.Metadata{
+		Name: "umbrella",
+		Dependencies: []*chart.Dependency{
+			{Name: "mariadb", Alias: "db"},
+			{Name: "memcached"},
+			{Name: "redis"},
+		},
+	}}
+	vals := map[string]interface{}{
+		"global":    map[string]interface{}{"env": "prod"},
+		"replicas":  2,
+		"db":        map[string]interface{}{"user": "app"},
+		"mariadb":   map[string]interface{}{"user": "ignored"},
+		"memcached": map[string]interface{}{"size": 128},
+		"redis":     nil,
+	}
+
+	split := SplitValues(c, vals)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"db":        {"user": "app"},
+		"memcached": {"size": 128},
+	}, split.Dependencies)
+	assert.Equal(t, map[string]interface{}{
+		"global":   map[string]interface{}{"env": "prod"},
+		"replicas": 2,
+		"mariadb":  map[string]interface{}{"user": "ignored"},
+		"redis":    nil,
+	}, split.Values)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// YAMLDocument is a YAML file, such as Chart.yaml or values.yaml, edited in
+// place: unlike unmarshaling the file and marshaling it back, editing it keeps
+// its comments, the order of its keys and the style of its values. Only the
+// indentation is normalized to two spaces.
+type YAMLDocument struct {
+	root *yaml.Node
+}
+
+// LoadYAMLDocument reads a YAML file to edit.
+func LoadYAMLDocument(filename string) (*YAMLDocument, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseYAMLDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", filename, err)
+	}
+	return doc, nil
+}
+
+// ParseYAMLDocument parses YAML to edit. The top level of the document must
+// be a map, or empty.
+func ParseYAMLDocument(data []byte) (*YAMLDocument, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		// An empty document
+		root = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(root.Content) == 0 {
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("the document is not a map")
+	}
+	return &YAMLDocument{root: &root}, nil
+}
+
+// Get returns the node at a path of keys, or nil if there is none.
+func (d *YAMLDocument) Get(path ...string) *yaml.Node {
+	node := d.root.Content[0]
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		_, node = mappingValue(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Set sets the value at a path of keys, creating the maps on the way. A value
+// replacing another one keeps its comments.
+func (d *YAMLDocument) Set(value interface{}, path ...string) error {
+	if len(path) == 0 {
+		return errors.New("empty path")
+	}
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return err
+	}
+
+	parent := d.root.Content[0]
+	for i, key := range path[:len(path)-1] {
+		_, next := mappingValue(parent, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		} else if next.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a map", strings.Join(path[:i+1], "."))
+		}
+		parent = next
+	}
+
+	key := path[len(path)-1]
+	if _, old := mappingValue(parent, key); old != nil {
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		if node.Kind == yaml.ScalarNode && old.Kind == yaml.ScalarNode && node.Tag == old.Tag {
+			// Keep quoting the value the way it was
+			node.Style = old.Style
+		}
+		*old = node
+		return nil
+	}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, &node)
+	return nil
+}
+
+// Delete removes the value at a path of keys, along with its comments. It
+// reports whether there was a value to remove.
+func (d *YAMLDocument) Delete(path ...string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	parent := d.Get(path[:len(path)-1]...)
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+	i, _ := mappingValue(parent, path[len(path)-1])
+	if i < 0 {
+		return false
+	}
+	parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+	return true
+}
+
+// Bytes returns the edited document.
+func (d *YAMLDocument) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile writes the edited document to a file.
+func (d *YAMLDocument) WriteFile(filename string, perm os.FileMode) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, perm)
+}
+
+// AddDependency appends a dependency to the dependencies of a Chart.yaml. It
+// fails if the chart already has a dependency loaded under the same name:
+// its alias, or else its name.
+func (d *YAMLDocument) AddDependency(dep *chart.Dependency) error {
+	if err := dep.Validate(); err != nil {
+		return err
+	}
+	deps := d.Get("dependencies")
+	if deps == nil || deps.Tag == "!!null" {
+		return d.Set([]*chart.Dependency{dep}, "dependencies")
+	}
+	if deps.Kind != yaml.SequenceNode {
+		return errors.New("dependencies is not a list")
+	}
+	if dependencyIndex(deps, dependencyName(dep)) >= 0 {
+		return fmt.Errorf("the chart already has a dependency named %q", dependencyName(dep))
+	}
+	var node yaml.Node
+	if err := node.Encode(dep); err != nil {
+		return err
+	}
+	deps.Content = append(deps.Content, &node)
+	return nil
+}
+
+// RemoveDependency removes the dependency loaded under a name, its alias or
+// else its name, from the dependencies of a Chart.yaml. It reports whether
+// the chart had this dependency.
+func (d *YAMLDocument) RemoveDependency(name string) bool {
+	deps := d.Get("dependencies")
+	if deps == nil || deps.Kind != yaml.SequenceNode {
+		return false
+	}
+	i := dependencyIndex(deps, name)
+	if i < 0 {
+		return false
+	}
+	deps.Content = append(deps.Content[:i], deps.Content[i+1:]...)
+	if len(deps.Content) == 0 {
+		d.Delete("dependencies")
+	}
+	return true
+}
+
+// dependencyIndex returns the index of the dependency loaded under a name in
+// a list of dependencies, or -1.
+func dependencyIndex(deps *yaml.Node, name string) int {
+	for i, node := range deps.Content {
+		var dep chart.Dependency
+		if err := node.Decode(&dep); err == nil && dependencyName(&dep) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// dependencyName returns the name a dependency is loaded under.
+func dependencyName(dep *chart.Dependency) string {
+	if dep.Alias != "" {
+		return dep.Alias
+	}
+	return dep.Name
+}
+
+// mappingValue returns the index of the key and the value of a key of a map,
+// or -1 and nil.
+func mappingValue(m *yaml.Node, key string) (int, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return i, m.Content[i+1]
+		}
+	}
+	return -1, nil
+}
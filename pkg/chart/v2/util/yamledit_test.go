@@ -0,0 +1,106 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+const editedChartfile = `# The chart of the frontend
+apiVersion: v2
+name: frontend
+version: "1.0.0" # bumped by the release pipeline
+dependencies:
+  # The cache
+  - name: redis
+    version: 17.0.0
+    repository: https://example.com/charts
+`
+
+func TestYAMLDocumentSet(t *testing.T) {
+	doc, err := ParseYAMLDocument([]byte(editedChartfile))
+	require.NoError(t, err)
+
+	require.NoError(t, doc.Set("1.1.0", "version"))
+	require.NoError(t, doc.Set("nginx", "annotations", "example.com/image"))
+	assert.Error(t, doc.Set("x", "name", "first"))
+
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `# The chart of the frontend
+apiVersion: v2
+name: frontend
+version: "1.1.0" # bumped by the release pipeline
+dependencies:
+  # The cache
+  - name: redis
+    version: 17.0.0
+    repository: https://example.com/charts
+annotations:
+  example.com/image: nginx
+`, string(data))
+
+	assert.True(t, doc.Delete("annotations", "example.com/image"))
+	assert.False(t, doc.Delete("annotations", "example.com/image"))
+	assert.False(t, doc.Delete("home"))
+}
+
+func TestYAMLDocumentDependencies(t *testing.T) {
+	doc, err := ParseYAMLDocument([]byte(editedChartfile))
+	require.NoError(t, err)
+
+	require.NoError(t, doc.AddDependency(&chart.Dependency{Name: "postgresql", Version: "^12", Repository: "oci://example.com/charts"}))
+	assert.ErrorContains(t, doc.AddDependency(&chart.Dependency{Name: "redis", Repository: "https://example.com/charts"}), `already has a dependency named "redis"`)
+	require.NoError(t, doc.AddDependency(&chart.Dependency{Name: "redis", Alias: "sessions", Repository: "https://example.com/charts"}))
+	assert.True(t, doc.RemoveDependency("sessions"))
+	assert.False(t, doc.RemoveDependency("memcached"))
+
+	data, err := doc.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, `# The chart of the frontend
+apiVersion: v2
+name: frontend
+version: "1.0.0" # bumped by the release pipeline
+dependencies:
+  # The cache
+  - name: redis
+    version: 17.0.0
+    repository: https://example.com/charts
+  - name: postgresql
+    version: ^12
+    repository: oci://example.com/charts
+`, string(data))
+
+	assert.True(t, doc.RemoveDependency("redis"))
+	assert.True(t, doc.RemoveDependency("postgresql"))
+	assert.Nil(t, doc.Get("dependencies"))
+
+	empty, err := ParseYAMLDocument(nil)
+	require.NoError(t, err)
+	require.NoError(t, empty.AddDependency(&chart.Dependency{Name: "redis", Repository: "@stable"}))
+	data, err = empty.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, "dependencies:\n  - name: redis\n    repository: '@stable'\n", string(data))
+
+	_, err = ParseYAMLDocument([]byte("- a list\n"))
+	assert.Error(t, err)
+}
@@ -25,14 +25,18 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"helm.sh/helm/v4/internal/version"
 	"helm.sh/helm/v4/pkg/helmpath"
@@ -53,7 +57,13 @@ type EnvSettings struct {
 	namespace string
 	config    *genericclioptions.ConfigFlags
 
-	// KubeConfig is the path to the kubeconfig file
+	// stdin is where the kubeconfig is read from when KubeConfig is KubeConfigStdin.
+	stdin          io.Reader
+	stdinOnce      sync.Once
+	stdinConfig    *clientcmdapi.Config
+	stdinConfigErr error
+
+	// KubeConfig is the path to the kubeconfig file, or KubeConfigStdin to read it from standard input
 	KubeConfig string
 	// KubeContext is the name of the kubeconfig context.
 	KubeContext string
@@ -73,10 +83,18 @@ type EnvSettings struct {
 	// KubeTLSServerName overrides the name to use for server certificate validation.
 	// If it is not provided, the hostname used to contact the server is used
 	KubeTLSServerName string
+	// KubeExecTimeout bounds each invocation of the exec credential plugin of the kubeconfig user.
+	KubeExecTimeout time.Duration
+	// KubeExecRetries is how many times a failed invocation of the exec credential plugin is retried.
+	KubeExecRetries int
+	// KubeExecCache indicates whether the credentials of the exec credential plugin are cached on disk until they expire.
+	KubeExecCache bool
 	// Debug indicates whether or not Helm is running in Debug mode.
 	Debug bool
 	// RegistryConfig is the path to the registry config file.
 	RegistryConfig string
+	// RegistryMirrorsConfig is the path to the file configuring the mirrors of registries.
+	RegistryMirrorsConfig string
 	// RepositoryConfig is the path to the repositories file.
 	RepositoryConfig string
 	// RepositoryCache is the path to the repository cache directory.
@@ -93,10 +111,14 @@ type EnvSettings struct {
 	ColorMode string
 	// ContentCache is the location where cached charts are stored
 	ContentCache string
+	// RecordActor records the name of the user running Helm in the releases it
+	// creates.
+	RecordActor bool
 }
 
 func New() *EnvSettings {
 	env := &EnvSettings{
+		stdin:                     os.Stdin,
 		namespace:                 os.Getenv("HELM_NAMESPACE"),
 		MaxHistory:                envIntOr("HELM_MAX_HISTORY", defaultMaxHistory),
 		KubeContext:               os.Getenv("HELM_KUBECONTEXT"),
@@ -107,14 +129,19 @@ func New() *EnvSettings {
 		KubeCaFile:                os.Getenv("HELM_KUBECAFILE"),
 		KubeTLSServerName:         os.Getenv("HELM_KUBETLS_SERVER_NAME"),
 		KubeInsecureSkipTLSVerify: envBoolOr("HELM_KUBEINSECURE_SKIP_TLS_VERIFY", false),
+		KubeExecTimeout:           envDurationOr("HELM_KUBEEXEC_TIMEOUT", 0),
+		KubeExecRetries:           envIntOr("HELM_KUBEEXEC_RETRIES", 0),
+		KubeExecCache:             envBoolOr("HELM_KUBEEXEC_CACHE", false),
 		PluginsDirectory:          envOr("HELM_PLUGINS", helmpath.DataPath("plugins")),
 		RegistryConfig:            envOr("HELM_REGISTRY_CONFIG", helmpath.ConfigPath("registry/config.json")),
+		RegistryMirrorsConfig:     envOr("HELM_REGISTRY_MIRRORS_CONFIG", helmpath.ConfigPath("registries.yaml")),
 		RepositoryConfig:          envOr("HELM_REPOSITORY_CONFIG", helmpath.ConfigPath("repositories.yaml")),
 		RepositoryCache:           envOr("HELM_REPOSITORY_CACHE", helmpath.CachePath("repository")),
 		ContentCache:              envOr("HELM_CONTENT_CACHE", helmpath.CachePath("content")),
 		BurstLimit:                envIntOr("HELM_BURST_LIMIT", defaultBurstLimit),
 		QPS:                       envFloat32Or("HELM_QPS", defaultQPS),
 		ColorMode:                 envColorMode(),
+		RecordActor:               envBoolOr("HELM_RECORD_ACTOR", false),
 	}
 	env.Debug, _ = strconv.ParseBool(os.Getenv("HELM_DEBUG"))
 
@@ -133,6 +160,7 @@ func New() *EnvSettings {
 		WrapConfigFn: func(config *rest.Config) *rest.Config {
 			config.Burst = env.BurstLimit
 			config.QPS = env.QPS
+			config = kube.WrapExecCredential(config, env.execCredentialOptions())
 			config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
 				return &kube.RetryingRoundTripper{Wrapped: rt}
 			})
@@ -151,24 +179,60 @@ func New() *EnvSettings {
 // AddFlags binds flags to the given flagset.
 func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVarP(&s.namespace, "namespace", "n", s.namespace, "namespace scope for this request")
-	fs.StringVar(&s.KubeConfig, "kubeconfig", "", "path to the kubeconfig file")
+	fs.StringVar(&s.KubeConfig, "kubeconfig", "", "path to the kubeconfig file, or - to read it from standard input")
 	fs.StringVar(&s.KubeContext, "kube-context", s.KubeContext, "name of the kubeconfig context to use")
 	fs.StringVar(&s.KubeToken, "kube-token", s.KubeToken, "bearer token used for authentication")
 	fs.StringVar(&s.KubeAsUser, "kube-as-user", s.KubeAsUser, "username to impersonate for the operation")
 	fs.StringArrayVar(&s.KubeAsGroups, "kube-as-group", s.KubeAsGroups, "group to impersonate for the operation, this flag can be repeated to specify multiple groups.")
+	fs.StringVar(&s.KubeAsUser, "as", s.KubeAsUser, "username to impersonate for the operation, same as --kube-as-user")
+	fs.StringArrayVar(&s.KubeAsGroups, "as-group", s.KubeAsGroups, "group to impersonate for the operation, same as --kube-as-group")
 	fs.StringVar(&s.KubeAPIServer, "kube-apiserver", s.KubeAPIServer, "the address and the port for the Kubernetes API server")
 	fs.StringVar(&s.KubeCaFile, "kube-ca-file", s.KubeCaFile, "the certificate authority file for the Kubernetes API server connection")
 	fs.StringVar(&s.KubeTLSServerName, "kube-tls-server-name", s.KubeTLSServerName, "server name to use for Kubernetes API server certificate validation. If it is not provided, the hostname used to contact the server is used")
 	fs.BoolVar(&s.KubeInsecureSkipTLSVerify, "kube-insecure-skip-tls-verify", s.KubeInsecureSkipTLSVerify, "if true, the Kubernetes API server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
+	fs.DurationVar(&s.KubeExecTimeout, "kube-exec-timeout", s.KubeExecTimeout, "time to wait for each invocation of the kubeconfig exec credential plugin (e.g. 30s). 0 means no timeout")
+	fs.IntVar(&s.KubeExecRetries, "kube-exec-retries", s.KubeExecRetries, "number of times a failed invocation of the kubeconfig exec credential plugin is retried")
+	fs.BoolVar(&s.KubeExecCache, "kube-exec-cache", s.KubeExecCache, "cache the credentials of the kubeconfig exec credential plugin on disk until they expire")
 	fs.BoolVar(&s.Debug, "debug", s.Debug, "enable verbose output")
 	fs.StringVar(&s.RegistryConfig, "registry-config", s.RegistryConfig, "path to the registry config file")
+	fs.StringVar(&s.RegistryMirrorsConfig, "registry-mirrors-config", s.RegistryMirrorsConfig, "path to the file configuring the mirrors of registries")
 	fs.StringVar(&s.RepositoryConfig, "repository-config", s.RepositoryConfig, "path to the file containing repository names and URLs")
 	fs.StringVar(&s.RepositoryCache, "repository-cache", s.RepositoryCache, "path to the directory containing cached repository indexes")
 	fs.StringVar(&s.ContentCache, "content-cache", s.ContentCache, "path to the directory containing cached content (e.g. charts)")
+	fs.BoolVar(&s.RecordActor, "record-actor", s.RecordActor, "record the name of the user running Helm in the releases it creates")
 	fs.IntVar(&s.BurstLimit, "burst-limit", s.BurstLimit, "client-side default throttling limit")
 	fs.Float32Var(&s.QPS, "qps", s.QPS, "queries per second used when communicating with the Kubernetes API, not including bursting")
 	fs.StringVar(&s.ColorMode, "color", s.ColorMode, "use colored output (never, auto, always)")
 	fs.StringVar(&s.ColorMode, "colour", s.ColorMode, "use colored output (never, auto, always)")
+	fs.Var((*noColorValue)(&s.ColorMode), "no-color", "disable colored output, same as --color=never")
+	fs.Lookup("no-color").NoOptDefVal = "true"
+}
+
+// noColorValue sets the color mode to never when the --no-color flag is given.
+type noColorValue string
+
+func (v *noColorValue) String() string {
+	return strconv.FormatBool(*v == "never")
+}
+
+func (v *noColorValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if b {
+		*v = "never"
+	}
+	return nil
+}
+
+func (v *noColorValue) Type() string {
+	return "bool"
+}
+
+// IsBoolFlag allows --no-color to be given without a value.
+func (v *noColorValue) IsBoolFlag() bool {
+	return true
 }
 
 func envOr(name, def string) string {
@@ -214,6 +278,18 @@ func envFloat32Or(name string, def float32) float32 {
 	return float32(ret)
 }
 
+func envDurationOr(name string, def time.Duration) time.Duration {
+	if name == "" {
+		return def
+	}
+	envVal := envOr(name, def.String())
+	ret, err := time.ParseDuration(envVal)
+	if err != nil {
+		return def
+	}
+	return ret
+}
+
 func envCSV(name string) (ls []string) {
 	trimmed := strings.Trim(os.Getenv(name), ", ")
 	if trimmed != "" {
@@ -241,20 +317,22 @@ func envColorMode() string {
 
 func (s *EnvSettings) EnvVars() map[string]string {
 	envvars := map[string]string{
-		"HELM_BIN":               os.Args[0],
-		"HELM_CACHE_HOME":        helmpath.CachePath(""),
-		"HELM_CONFIG_HOME":       helmpath.ConfigPath(""),
-		"HELM_DATA_HOME":         helmpath.DataPath(""),
-		"HELM_DEBUG":             fmt.Sprint(s.Debug),
-		"HELM_PLUGINS":           s.PluginsDirectory,
-		"HELM_REGISTRY_CONFIG":   s.RegistryConfig,
-		"HELM_REPOSITORY_CACHE":  s.RepositoryCache,
-		"HELM_CONTENT_CACHE":     s.ContentCache,
-		"HELM_REPOSITORY_CONFIG": s.RepositoryConfig,
-		"HELM_NAMESPACE":         s.Namespace(),
-		"HELM_MAX_HISTORY":       strconv.Itoa(s.MaxHistory),
-		"HELM_BURST_LIMIT":       strconv.Itoa(s.BurstLimit),
-		"HELM_QPS":               strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
+		"HELM_BIN":                     os.Args[0],
+		"HELM_CACHE_HOME":              helmpath.CachePath(""),
+		"HELM_CONFIG_HOME":             helmpath.ConfigPath(""),
+		"HELM_DATA_HOME":               helmpath.DataPath(""),
+		"HELM_DEBUG":                   fmt.Sprint(s.Debug),
+		"HELM_PLUGINS":                 s.PluginsDirectory,
+		"HELM_REGISTRY_CONFIG":         s.RegistryConfig,
+		"HELM_REGISTRY_MIRRORS_CONFIG": s.RegistryMirrorsConfig,
+		"HELM_REPOSITORY_CACHE":        s.RepositoryCache,
+		"HELM_CONTENT_CACHE":           s.ContentCache,
+		"HELM_REPOSITORY_CONFIG":       s.RepositoryConfig,
+		"HELM_NAMESPACE":               s.Namespace(),
+		"HELM_MAX_HISTORY":             strconv.Itoa(s.MaxHistory),
+		"HELM_BURST_LIMIT":             strconv.Itoa(s.BurstLimit),
+		"HELM_QPS":                     strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
+		"HELM_RECORD_ACTOR":            strconv.FormatBool(s.RecordActor),
 
 		// broken, these are populated from helm flags and not kubeconfig.
 		"HELM_KUBECONTEXT":                  s.KubeContext,
@@ -265,16 +343,34 @@ func (s *EnvSettings) EnvVars() map[string]string {
 		"HELM_KUBECAFILE":                   s.KubeCaFile,
 		"HELM_KUBEINSECURE_SKIP_TLS_VERIFY": strconv.FormatBool(s.KubeInsecureSkipTLSVerify),
 		"HELM_KUBETLS_SERVER_NAME":          s.KubeTLSServerName,
+		"HELM_KUBEEXEC_TIMEOUT":             s.KubeExecTimeout.String(),
+		"HELM_KUBEEXEC_RETRIES":             strconv.Itoa(s.KubeExecRetries),
+		"HELM_KUBEEXEC_CACHE":               strconv.FormatBool(s.KubeExecCache),
 	}
-	if s.KubeConfig != "" {
+	// The kubeconfig read from standard input has no file to point to, see
+	// PluginEnvVars.
+	if s.KubeConfig != "" && s.KubeConfig != KubeConfigStdin {
 		envvars["KUBECONFIG"] = s.KubeConfig
 	}
 	return envvars
 }
 
+// execCredentialOptions returns the options of the exec credential plugin
+// of the kubeconfig user.
+func (s *EnvSettings) execCredentialOptions() kube.ExecCredentialOptions {
+	opts := kube.ExecCredentialOptions{
+		Timeout: s.KubeExecTimeout,
+		Retries: s.KubeExecRetries,
+	}
+	if s.KubeExecCache {
+		opts.CacheDir = helmpath.CachePath("kube", "exec-credentials")
+	}
+	return opts
+}
+
 // Namespace gets the namespace from the configuration
 func (s *EnvSettings) Namespace() string {
-	if ns, _, err := s.config.ToRawKubeConfigLoader().Namespace(); err == nil {
+	if ns, _, err := s.RESTClientGetter().ToRawKubeConfigLoader().Namespace(); err == nil {
 		return ns
 	}
 	if s.namespace != "" {
@@ -288,8 +384,12 @@ func (s *EnvSettings) SetNamespace(namespace string) {
 	s.namespace = namespace
 }
 
-// RESTClientGetter gets the kubeconfig from EnvSettings
+// RESTClientGetter gets the kubeconfig from EnvSettings. When the kubeconfig
+// is read from standard input, it is only read once.
 func (s *EnvSettings) RESTClientGetter() genericclioptions.RESTClientGetter {
+	if s.KubeConfig == KubeConfigStdin {
+		return s.stdinRESTClientGetter()
+	}
 	return s.config
 }
 
@@ -110,6 +110,16 @@ func TestEnvSettings(t *testing.T) {
 			kubeTLSServer: "example.org",
 			kubeInsecure:  true,
 		},
+		{
+			name:         "with short impersonation flags set",
+			args:         "--as=poro --as-group=admins --as-group=teatime",
+			ns:           "default",
+			maxhistory:   defaultMaxHistory,
+			burstLimit:   defaultBurstLimit,
+			qps:          defaultQPS,
+			kubeAsUser:   "poro",
+			kubeAsGroups: []string{"admins", "teatime"},
+		},
 		{
 			name:       "invalid kubeconfig",
 			ns:         "testns",
@@ -272,3 +282,32 @@ func resetEnv() func() {
 		}
 	}
 }
+
+func TestNoColorFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"default", nil, "auto"},
+		{"no-color", []string{"--no-color"}, "never"},
+		{"no-color=false", []string{"--color=always", "--no-color=false"}, "always"},
+		{"no-color overrides color", []string{"--no-color", "--color=always"}, "always"},
+		{"color overridden by no-color", []string{"--color=always", "--no-color"}, "never"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("HELM_COLOR", "")
+			settings := New()
+			flags := pflag.NewFlagSet("testing", pflag.ContinueOnError)
+			settings.AddFlags(flags)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if settings.ColorMode != tt.want {
+				t.Errorf("expected color mode %q, got %q", tt.want, settings.ColorMode)
+			}
+		})
+	}
+}
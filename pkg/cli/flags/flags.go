@@ -0,0 +1,199 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package flags maps the standard Helm command line flags onto the option
+structs used by the actions.
+
+The helm CLI registers its flags through this package, so tools embedding Helm
+can either bind the same flags to their own pflag.FlagSet or convert CLI style
+arguments into options and get exactly the semantics of the helm binary.
+*/
+package flags
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/util/homedir"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/values"
+	"helm.sh/helm/v4/pkg/kube"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// AddValueOptionsFlags binds the flags used to pass values to a chart
+// (-f/--values, --set, --set-string, --set-file, --set-json and --set-literal)
+// to the given options.
+func AddValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
+	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML, JSON, TOML or JSON5 file or a URL (can specify multiple)")
+	f.StringArrayVar(&v.Values, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	f.StringArrayVar(&v.StringValues, "set-string", []string{}, "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	f.StringArrayVar(&v.FileValues, "set-file", []string{}, "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+	f.StringArrayVar(&v.JSONValues, "set-json", []string{}, "set JSON values on the command line (can specify multiple or separate values with commas: key1=jsonval1,key2=jsonval2 or using json format: {\"key1\": jsonval1, \"key2\": \"jsonval2\"})")
+	f.StringArrayVar(&v.LiteralValues, "set-literal", []string{}, "set a literal STRING value on the command line")
+}
+
+// AddChartPathOptionsFlags binds the flags used to locate a chart (--version,
+// --repo, --verify and the repository credentials) to the given options.
+func AddChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
+	f.StringVar(&c.Version, "version", "", "specify a version constraint for the chart version to use. This constraint can be a specific tag (e.g. 1.1.1) or it may reference a valid range (e.g. ^2.0.0). If this is not specified, the latest version is used")
+	f.BoolVar(&c.Verify, "verify", false, "verify the package before using it")
+	f.StringVar(&c.Keyring, "keyring", DefaultKeyring(), "location of public keys used for verification")
+	f.StringVar(&c.RepoURL, "repo", "", "chart repository url where to locate the requested chart")
+	f.StringVar(&c.Username, "username", "", "chart repository username where to locate the requested chart")
+	f.StringVar(&c.Password, "password", "", "chart repository password where to locate the requested chart")
+	f.StringVar(&c.CertFile, "cert-file", "", "identify HTTPS client using this SSL certificate file")
+	f.StringVar(&c.KeyFile, "key-file", "", "identify HTTPS client using this SSL key file")
+	f.BoolVar(&c.InsecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart download")
+	f.BoolVar(&c.PlainHTTP, "plain-http", false, "use insecure HTTP connections for the chart download")
+	f.StringVar(&c.CaFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&c.PassCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
+}
+
+// AddObjectLabelsFlags binds the flags customizing the labels of the objects
+// of a release (--managed-by, --release-name-label, --release-namespace-label,
+// --object-labels, --object-annotations and --require-object-labels) to the
+// given labels.
+func AddObjectLabelsFlags(f *pflag.FlagSet, l *release.ObjectLabels) {
+	f.StringVar(&l.ManagedBy, "managed-by", "", "value of the app.kubernetes.io/managed-by label set on the objects of the release. Defaults to \"Helm\"")
+	f.StringVar(&l.ReleaseNameKey, "release-name-label", "", "key of a label set to the release name on the objects of the release")
+	f.StringVar(&l.ReleaseNamespaceKey, "release-namespace-label", "", "key of a label set to the release namespace on the objects of the release")
+	f.StringToStringVar(&l.Extra, "object-labels", nil, "labels set on all the objects of the release. Should be separated by comma")
+	f.StringToStringVar(&l.Annotations, "object-annotations", nil, "annotations set on all the objects of the release. Should be separated by comma")
+	f.StringSliceVar(&l.Required, "require-object-labels", nil, "keys of the labels all the objects of the release must have, set by their templates or --object-labels. Should be separated by comma")
+}
+
+// AddWaitFlag binds the --wait flag to the given wait strategy.
+//
+// The strategy defaults to kube.HookOnlyStrategy, and to
+// kube.StatusWatcherStrategy when --wait is given without a value.
+func AddWaitFlag(f *pflag.FlagSet, wait *kube.WaitStrategy) {
+	f.Var(
+		newWaitValue(kube.HookOnlyStrategy, wait),
+		"wait",
+		"if specified, will wait until all resources are in the expected state before marking the operation as successful. It will wait for as long as --timeout. Valid inputs are 'watcher' and 'legacy'",
+	)
+	// Sets the strategy to use the watcher strategy if `--wait` is used without an argument
+	f.Lookup("wait").NoOptDefVal = string(kube.StatusWatcherStrategy)
+}
+
+// ParseWaitStrategy converts a --wait flag value into a wait strategy.
+//
+// The deprecated boolean values "true" and "false" are accepted as well.
+func ParseWaitStrategy(s string) (kube.WaitStrategy, error) {
+	var ws kube.WaitStrategy
+	if err := newWaitValue(kube.HookOnlyStrategy, &ws).Set(s); err != nil {
+		return "", err
+	}
+	return ws, nil
+}
+
+// ValueOptionsFromArgs parses command line arguments such as
+// []string{"-f", "values.yaml", "--set", "a=b"} into values options.
+func ValueOptionsFromArgs(args []string) (*values.Options, error) {
+	v := &values.Options{}
+	if err := parse(args, func(f *pflag.FlagSet) { AddValueOptionsFlags(f, v) }); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ChartPathOptionsFromArgs parses command line arguments such as
+// []string{"--version", "^1.0.0", "--repo", "https://example.com"} into chart
+// path options.
+func ChartPathOptionsFromArgs(args []string) (*action.ChartPathOptions, error) {
+	c := &action.ChartPathOptions{}
+	if err := parse(args, func(f *pflag.FlagSet) { AddChartPathOptionsFlags(f, c) }); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// WaitStrategyFromArgs parses command line arguments such as
+// []string{"--wait=legacy"} into a wait strategy.
+func WaitStrategyFromArgs(args []string) (kube.WaitStrategy, error) {
+	var ws kube.WaitStrategy
+	if err := parse(args, func(f *pflag.FlagSet) { AddWaitFlag(f, &ws) }); err != nil {
+		return "", err
+	}
+	return ws, nil
+}
+
+// DefaultKeyring returns the location of the keyring used to verify charts
+// when none is given.
+func DefaultKeyring() string {
+	if v, ok := os.LookupEnv("GNUPGHOME"); ok {
+		return filepath.Join(v, "pubring.gpg")
+	}
+	return filepath.Join(homedir.HomeDir(), ".gnupg", "pubring.gpg")
+}
+
+// parse parses args with a flag set holding only the flags registered by bind.
+// Positional arguments are not allowed.
+func parse(args []string, bind func(*pflag.FlagSet)) error {
+	f := pflag.NewFlagSet("helm", pflag.ContinueOnError)
+	f.SetOutput(io.Discard)
+	bind(f)
+	if err := f.Parse(args); err != nil {
+		return err
+	}
+	if f.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", f.Args())
+	}
+	return nil
+}
+
+type waitValue kube.WaitStrategy
+
+func newWaitValue(defaultValue kube.WaitStrategy, ws *kube.WaitStrategy) *waitValue {
+	*ws = defaultValue
+	return (*waitValue)(ws)
+}
+
+func (ws *waitValue) String() string {
+	if ws == nil {
+		return ""
+	}
+	return string(*ws)
+}
+
+func (ws *waitValue) Set(s string) error {
+	switch s {
+	case string(kube.StatusWatcherStrategy), string(kube.LegacyStrategy):
+		*ws = waitValue(s)
+		return nil
+	case "true":
+		slog.Warn("--wait=true is deprecated (boolean value) and can be replaced with --wait=watcher")
+		*ws = waitValue(kube.StatusWatcherStrategy)
+		return nil
+	case "false":
+		slog.Warn("--wait=false is deprecated (boolean value) and can be replaced by omitting the --wait flag")
+		*ws = waitValue(kube.HookOnlyStrategy)
+		return nil
+	default:
+		return fmt.Errorf("invalid wait input %q. Valid inputs are %s, and %s", s, kube.StatusWatcherStrategy, kube.LegacyStrategy)
+	}
+}
+
+func (ws *waitValue) Type() string {
+	return "WaitStrategy"
+}
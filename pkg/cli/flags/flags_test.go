@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+func TestValueOptionsFromArgs(t *testing.T) {
+	v, err := ValueOptionsFromArgs([]string{"-f", "a.yaml,b.yaml", "--set", "a=b,c=d", "--set-string", "e=1", "--set-json", `f={"g":1}`})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.yaml", "b.yaml"}, v.ValueFiles)
+	assert.Equal(t, []string{"a=b,c=d"}, v.Values)
+	assert.Equal(t, []string{"e=1"}, v.StringValues)
+	assert.Equal(t, []string{`f={"g":1}`}, v.JSONValues)
+
+	_, err = ValueOptionsFromArgs([]string{"--version", "1.0.0"})
+	assert.Error(t, err, "expected flags that are not values flags to be rejected")
+
+	_, err = ValueOptionsFromArgs([]string{"chart"})
+	assert.Error(t, err, "expected positional arguments to be rejected")
+}
+
+func TestChartPathOptionsFromArgs(t *testing.T) {
+	t.Setenv("GNUPGHOME", "/gnupg")
+
+	c, err := ChartPathOptionsFromArgs([]string{"--version", "^1.0.0", "--repo", "https://example.com", "--plain-http"})
+	require.NoError(t, err)
+	assert.Equal(t, "^1.0.0", c.Version)
+	assert.Equal(t, "https://example.com", c.RepoURL)
+	assert.True(t, c.PlainHTTP)
+	assert.Equal(t, DefaultKeyring(), c.Keyring)
+}
+
+func TestWaitStrategyFromArgs(t *testing.T) {
+	for _, tt := range []struct {
+		args     []string
+		expected kube.WaitStrategy
+	}{
+		{args: nil, expected: kube.HookOnlyStrategy},
+		{args: []string{"--wait"}, expected: kube.StatusWatcherStrategy},
+		{args: []string{"--wait=legacy"}, expected: kube.LegacyStrategy},
+		{args: []string{"--wait=true"}, expected: kube.StatusWatcherStrategy},
+		{args: []string{"--wait=false"}, expected: kube.HookOnlyStrategy},
+	} {
+		ws, err := WaitStrategyFromArgs(tt.args)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, ws, "args: %v", tt.args)
+	}
+
+	_, err := WaitStrategyFromArgs([]string{"--wait=forever"})
+	assert.Error(t, err)
+
+	_, err = ParseWaitStrategy("forever")
+	assert.Error(t, err)
+}
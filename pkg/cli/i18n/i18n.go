@@ -0,0 +1,208 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package i18n localizes the messages the Helm CLI prints for its users.
+
+Messages are identified by their English text, which is also used when no
+translation is available. Translations are grouped in catalogs, one for each
+locale, which are either compiled in with Register or loaded from files with
+LoadDir. Error messages are not translated, so they can be searched for and
+reported upstream. Errors created with Errorf carry a code too, which stays the
+same when their message is reworded.
+
+The locale is selected from the HELM_LOCALE, LC_ALL, LC_MESSAGES and LANG
+environment variables, in that order. A locale such as "pt_BR.UTF-8" uses the
+"pt-BR" catalog, falling back to the "pt" catalog for missing messages.
+*/
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Catalog maps English messages to their translation.
+//
+// Messages are the format strings passed to T, Sprintf or Fprintf without a
+// trailing newline.
+type Catalog map[string]string
+
+var (
+	mu       sync.RWMutex
+	catalogs = make(map[string]Catalog)
+	// chain lists the catalogs used for the current locale, most specific first.
+	chain []string
+)
+
+// Register adds the messages of the catalog to the catalog of the given
+// locale. Messages already registered for the locale are replaced.
+func Register(locale string, c Catalog) {
+	mu.Lock()
+	defer mu.Unlock()
+	key := normalize(locale)
+	if catalogs[key] == nil {
+		catalogs[key] = make(Catalog, len(c))
+	}
+	for k, v := range c {
+		catalogs[key][k] = v
+	}
+}
+
+// LoadDir registers the catalogs found in dir. Every YAML or JSON file in dir
+// holds the catalog of the locale it is named after, such as "de.yaml" or
+// "pt_BR.json". A missing directory is not an error.
+func LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		var c Catalog
+		if err := yaml.Unmarshal(data, &c); err != nil {
+			return fmt.Errorf("failed to load message catalog %s: %w", e.Name(), err)
+		}
+		Register(strings.TrimSuffix(e.Name(), ext), c)
+	}
+	return nil
+}
+
+// SetLocale selects the locale messages are translated to. An empty locale,
+// "C" and "POSIX" disable translations.
+func SetLocale(locale string) {
+	mu.Lock()
+	defer mu.Unlock()
+	chain = nil
+	key := normalize(locale)
+	if key == "" || key == "c" || key == "posix" {
+		return
+	}
+	chain = append(chain, key)
+	if lang, _, ok := strings.Cut(key, "-"); ok {
+		chain = append(chain, lang)
+	}
+}
+
+// Locale returns the selected locale, or an empty string when messages are
+// not translated.
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(chain) == 0 {
+		return ""
+	}
+	return chain[0]
+}
+
+// LocaleFromEnv returns the locale selected by the environment.
+func LocaleFromEnv() string {
+	for _, name := range []string{"HELM_LOCALE", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// T returns the translation of msg for the selected locale, or msg itself
+// when it has no translation.
+func T(msg string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(chain) == 0 {
+		return msg
+	}
+	key, newline := strings.CutSuffix(msg, "\n")
+	for _, locale := range chain {
+		if translated, ok := catalogs[locale][key]; ok && translated != "" {
+			if newline {
+				return translated + "\n"
+			}
+			return translated
+		}
+	}
+	return msg
+}
+
+// Sprintf formats according to the translation of format.
+func Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(T(format), a...)
+}
+
+// Fprintf formats according to the translation of format and writes to w.
+func Fprintf(w io.Writer, format string, a ...interface{}) (int, error) {
+	return fmt.Fprintf(w, T(format), a...)
+}
+
+// Fprintln writes the translation of msg followed by a newline to w.
+func Fprintln(w io.Writer, msg string) (int, error) {
+	return fmt.Fprintln(w, T(msg))
+}
+
+// Error is an error identified by a code, such as "HELM1001". Its message is
+// prefixed with the code and never translated.
+type Error struct {
+	Code string
+	err  error
+}
+
+// Errorf formats an error identified by code. Like fmt.Errorf, it wraps the
+// operand of a %w verb.
+func Errorf(code, format string, a ...interface{}) error {
+	return &Error{Code: code, err: fmt.Errorf(format, a...)}
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Code returns the code of the first error in the chain of err that has one,
+// or an empty string when there is none.
+func Code(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return ""
+}
+
+// normalize turns a locale such as "pt_BR.UTF-8@latin" into "pt-br".
+func normalize(locale string) string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
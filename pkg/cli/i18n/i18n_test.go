@@ -0,0 +1,135 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package i18n
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reset(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		catalogs = make(map[string]Catalog)
+		chain = nil
+	})
+}
+
+func TestT(t *testing.T) {
+	reset(t)
+	Register("de", Catalog{
+		"%q has been added to your repositories": "%q wurde zu Ihren Repositories hinzugefügt",
+		"Installed plugin: %s":                   "Plugin installiert: %s",
+	})
+	Register("de_AT", Catalog{
+		"Installed plugin: %s": "Plugin installiert (AT): %s",
+	})
+
+	tests := []struct {
+		locale string
+		msg    string
+		want   string
+	}{
+		{"", "Installed plugin: %s\n", "Installed plugin: %s\n"},
+		{"C", "Installed plugin: %s\n", "Installed plugin: %s\n"},
+		{"POSIX", "Installed plugin: %s\n", "Installed plugin: %s\n"},
+		{"fr_FR.UTF-8", "Installed plugin: %s\n", "Installed plugin: %s\n"},
+		{"de", "Installed plugin: %s\n", "Plugin installiert: %s\n"},
+		{"de_DE.UTF-8", "Installed plugin: %s", "Plugin installiert: %s"},
+		{"de_AT.UTF-8@euro", "Installed plugin: %s\n", "Plugin installiert (AT): %s\n"},
+		{"de-AT", "%q has been added to your repositories\n", "%q wurde zu Ihren Repositories hinzugefügt\n"},
+		{"de", "Rollback was a success! Happy Helming!\n", "Rollback was a success! Happy Helming!\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale+"/"+tt.msg, func(t *testing.T) {
+			SetLocale(tt.locale)
+			assert.Equal(t, tt.want, T(tt.msg))
+		})
+	}
+}
+
+func TestFprintf(t *testing.T) {
+	reset(t)
+	Register("nl", Catalog{"Updated plugin: %s": "Plugin bijgewerkt: %s"})
+	SetLocale("nl_NL")
+
+	var buf bytes.Buffer
+	_, err := Fprintf(&buf, "Updated plugin: %s\n", "diff")
+	require.NoError(t, err)
+	_, err = Fprintln(&buf, "Updated plugin: %s")
+	require.NoError(t, err)
+	assert.Equal(t, "Plugin bijgewerkt: diff\nPlugin bijgewerkt: %s\n", buf.String())
+	assert.Equal(t, "Plugin bijgewerkt: x", Sprintf("Updated plugin: %s", "x"))
+}
+
+func TestLoadDir(t *testing.T) {
+	reset(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pt_BR.yaml"), []byte(`"Installed plugin: %s": "Plugin instalado: %s"`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "es.json"), []byte(`{"Installed plugin: %s": "Complemento instalado: %s"}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a catalog"), 0o644))
+
+	require.NoError(t, LoadDir(dir))
+
+	SetLocale("pt_BR.UTF-8")
+	assert.Equal(t, "pt-br", Locale())
+	assert.Equal(t, "Plugin instalado: %s", T("Installed plugin: %s"))
+	SetLocale("es_MX")
+	assert.Equal(t, "Complemento instalado: %s", T("Installed plugin: %s"))
+
+	assert.NoError(t, LoadDir(filepath.Join(dir, "missing")))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr.yaml"), []byte("- not\n- a map\n"), 0o644))
+	assert.ErrorContains(t, LoadDir(dir), "fr.yaml")
+}
+
+func TestLocaleFromEnv(t *testing.T) {
+	t.Setenv("HELM_LOCALE", "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "nl_NL.UTF-8")
+	assert.Equal(t, "nl_NL.UTF-8", LocaleFromEnv())
+
+	t.Setenv("LC_MESSAGES", "fr_FR")
+	assert.Equal(t, "fr_FR", LocaleFromEnv())
+
+	t.Setenv("LC_ALL", "de_DE")
+	assert.Equal(t, "de_DE", LocaleFromEnv())
+
+	t.Setenv("HELM_LOCALE", "ja")
+	assert.Equal(t, "ja", LocaleFromEnv())
+}
+
+func TestErrorf(t *testing.T) {
+	reset(t)
+	Register("de", Catalog{"no repo named %q found": "kein Repository namens %q gefunden"})
+	SetLocale("de")
+
+	err := Errorf("HELM1008", "no repo named %q found: %w", "stable", os.ErrNotExist)
+	assert.EqualError(t, err, `HELM1008: no repo named "stable" found: file does not exist`, "error messages should not be translated")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, "HELM1008", Code(fmt.Errorf("remove: %w", err)))
+	assert.Empty(t, Code(os.ErrNotExist))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"helm.sh/helm/v4/pkg/kube"
+)
+
+// KubeConfigStdin is the value of --kubeconfig reading the kubeconfig from
+// standard input rather than from a file.
+const KubeConfigStdin = "-"
+
+// stdinRESTClientGetter returns a RESTClientGetter for the kubeconfig read
+// from standard input, applying the flags overriding its settings.
+func (s *EnvSettings) stdinRESTClientGetter() genericclioptions.RESTClientGetter {
+	s.stdinOnce.Do(func() {
+		s.stdinConfig, s.stdinConfigErr = readKubeConfig(s.stdin)
+	})
+	var clientConfig clientcmd.ClientConfig
+	if s.stdinConfigErr != nil {
+		clientConfig = &errClientConfig{err: s.stdinConfigErr}
+	} else {
+		clientConfig = clientcmd.NewDefaultClientConfig(*s.stdinConfig, s.kubeConfigOverrides())
+	}
+	return kube.NewRESTClientGetter(clientConfig, s.config.WrapConfigFn)
+}
+
+// PluginEnvVars returns EnvVars for a plugin about to run. As plugins cannot
+// read standard input again, KUBECONFIG names a file holding a copy of the
+// kubeconfig read from it, if any, which cleanup removes: call it once the
+// plugin exits. cleanup is never nil, and the environment is returned without
+// KUBECONFIG when the copy cannot be written.
+func (s *EnvSettings) PluginEnvVars() (envvars map[string]string, cleanup func(), err error) {
+	envvars = s.EnvVars()
+	cleanup = func() {}
+	if s.KubeConfig != KubeConfigStdin {
+		return envvars, cleanup, nil
+	}
+	file, err := s.writeStdinKubeConfig()
+	if err != nil {
+		return envvars, cleanup, err
+	}
+	envvars["KUBECONFIG"] = file
+	return envvars, func() { os.Remove(file) }, nil
+}
+
+// writeStdinKubeConfig writes the kubeconfig read from standard input to a
+// temporary file only readable by the user, and returns its path.
+func (s *EnvSettings) writeStdinKubeConfig() (string, error) {
+	s.stdinRESTClientGetter()
+	if s.stdinConfigErr != nil {
+		return "", s.stdinConfigErr
+	}
+	data, err := clientcmd.Write(*s.stdinConfig)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "helm-kubeconfig-*.yaml")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func readKubeConfig(in io.Reader) (*clientcmdapi.Config, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("reading the kubeconfig from standard input: %w", err)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the kubeconfig read from standard input: %w", err)
+	}
+	return config, nil
+}
+
+// kubeConfigOverrides returns the overrides of the kubeconfig set by flags,
+// as genericclioptions.ConfigFlags applies them to kubeconfig files.
+func (s *EnvSettings) kubeConfigOverrides() *clientcmd.ConfigOverrides {
+	overrides := &clientcmd.ConfigOverrides{ClusterDefaults: clientcmd.ClusterDefaults}
+	overrides.AuthInfo.Token = s.KubeToken
+	overrides.AuthInfo.Impersonate = s.KubeAsUser
+	overrides.AuthInfo.ImpersonateGroups = s.KubeAsGroups
+	overrides.ClusterInfo.Server = s.KubeAPIServer
+	overrides.ClusterInfo.TLSServerName = s.KubeTLSServerName
+	overrides.ClusterInfo.CertificateAuthority = s.KubeCaFile
+	overrides.ClusterInfo.InsecureSkipTLSVerify = s.KubeInsecureSkipTLSVerify
+	overrides.CurrentContext = s.KubeContext
+	overrides.Context.Namespace = s.namespace
+	return overrides
+}
+
+// errClientConfig is a clientcmd.ClientConfig failing with the error met
+// while loading the kubeconfig.
+type errClientConfig struct {
+	err error
+}
+
+func (c *errClientConfig) RawConfig() (clientcmdapi.Config, error) {
+	return clientcmdapi.Config{}, c.err
+}
+
+func (c *errClientConfig) ClientConfig() (*rest.Config, error) {
+	return nil, c.err
+}
+
+func (c *errClientConfig) Namespace() (string, bool, error) {
+	return "", false, c.err
+}
+
+func (c *errClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return clientcmd.NewDefaultClientConfigLoadingRules()
+}
@@ -0,0 +1,169 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const stdinKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    namespace: dev-ns
+- name: prod
+  context:
+    cluster: prod
+    namespace: prod-ns
+current-context: dev
+`
+
+func TestKubeConfigStdin(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantHost      string
+		wantNamespace string
+	}{
+		{
+			name:          "current context",
+			args:          []string{"--kubeconfig", "-"},
+			wantHost:      "https://dev.example.com:6443",
+			wantNamespace: "dev-ns",
+		},
+		{
+			name:          "overridden context and namespace",
+			args:          []string{"--kubeconfig=-", "--kube-context", "prod", "-n", "other"},
+			wantHost:      "https://prod.example.com:6443",
+			wantNamespace: "other",
+		},
+		{
+			name:          "overridden API server",
+			args:          []string{"--kubeconfig=-", "--kube-apiserver", "https://127.0.0.1:6443"},
+			wantHost:      "https://127.0.0.1:6443",
+			wantNamespace: "dev-ns",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer resetEnv()()
+
+			settings := New()
+			settings.stdin = strings.NewReader(stdinKubeConfig)
+			fs := pflag.NewFlagSet("testing", pflag.ContinueOnError)
+			settings.AddFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if ns := settings.Namespace(); ns != tt.wantNamespace {
+				t.Errorf("expected namespace %q, got %q", tt.wantNamespace, ns)
+			}
+			// The kubeconfig is only read once from standard input.
+			config, err := settings.RESTClientGetter().ToRESTConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Host != tt.wantHost {
+				t.Errorf("expected host %q, got %q", tt.wantHost, config.Host)
+			}
+			if config.Burst != settings.BurstLimit {
+				t.Errorf("expected burst limit %d, got %d", settings.BurstLimit, config.Burst)
+			}
+		})
+	}
+}
+
+func TestKubeConfigStdinInvalid(t *testing.T) {
+	defer resetEnv()()
+
+	settings := New()
+	settings.KubeConfig = KubeConfigStdin
+	settings.stdin = strings.NewReader("not: [a kubeconfig")
+
+	if ns := settings.Namespace(); ns != "default" {
+		t.Errorf("expected namespace %q, got %q", "default", ns)
+	}
+	_, err := settings.RESTClientGetter().ToRESTConfig()
+	if err == nil || !strings.HasPrefix(err.Error(), "parsing the kubeconfig read from standard input: ") {
+		t.Errorf("expected an error parsing the kubeconfig, got %v", err)
+	}
+}
+
+func TestKubeConfigStdinEnvVars(t *testing.T) {
+	defer resetEnv()()
+
+	settings := New()
+	settings.KubeConfig = KubeConfigStdin
+	settings.stdin = strings.NewReader(stdinKubeConfig)
+
+	if file, ok := settings.EnvVars()["KUBECONFIG"]; ok {
+		t.Errorf("expected KUBECONFIG to be unset outside of plugins, got %q", file)
+	}
+
+	envvars, cleanup, err := settings.PluginEnvVars()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := envvars["KUBECONFIG"]
+	if file == "" || file == KubeConfigStdin {
+		t.Fatalf("expected KUBECONFIG to be a file, got %q", file)
+	}
+	config, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CurrentContext != "dev" {
+		t.Errorf("expected the current context %q, got %q", "dev", config.CurrentContext)
+	}
+
+	cleanup()
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", file, err)
+	}
+}
+
+func TestKubeConfigStdinInvalidEnvVars(t *testing.T) {
+	defer resetEnv()()
+
+	settings := New()
+	settings.KubeConfig = KubeConfigStdin
+	settings.stdin = strings.NewReader("not: [a kubeconfig")
+
+	envvars, cleanup, err := settings.PluginEnvVars()
+	defer cleanup()
+	if err == nil {
+		t.Error("expected an error parsing the kubeconfig")
+	}
+	if file, ok := envvars["KUBECONFIG"]; ok {
+		t.Errorf("expected KUBECONFIG to be unset, got %q", file)
+	}
+}
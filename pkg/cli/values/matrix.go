@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// MatrixEntry is a single permutation of values files in a values matrix.
+type MatrixEntry struct {
+	// Name identifies the permutation. It is used to name output directories.
+	Name string `json:"name"`
+	// ValueFiles are the values files of the permutation, in merge order.
+	ValueFiles []string `json:"values"`
+}
+
+// matrixFile is the format of a values matrix file.
+//
+// Combinations are listed explicitly, while axes are expanded into their
+// cartesian product, taking one values file from each axis:
+//
+//	combinations:
+//	- name: prod-ha
+//	  values: [prod.yaml, ha.yaml]
+//	axes:
+//	- [dev.yaml, prod.yaml]
+//	- [small.yaml, large.yaml]
+type matrixFile struct {
+	Combinations []MatrixEntry `json:"combinations"`
+	Axes         [][]string    `json:"axes"`
+}
+
+// LoadMatrix loads the values permutations described by path.
+//
+// When path is a directory, every YAML or JSON file in it is a permutation on
+// its own. Otherwise path is read as a matrix file. Relative values file paths
+// in a matrix file are resolved against the directory holding the matrix file.
+func LoadMatrix(path string) ([]MatrixEntry, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return loadMatrixDir(path)
+	}
+	return loadMatrixFile(path)
+}
+
+func loadMatrixDir(dir string) ([]MatrixEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []MatrixEntry
+	for _, f := range files {
+		if f.IsDir() || !isValuesFile(f.Name()) {
+			continue
+		}
+		entries = append(entries, MatrixEntry{
+			Name:       strings.TrimSuffix(f.Name(), filepath.Ext(f.Name())),
+			ValueFiles: []string{filepath.Join(dir, f.Name())},
+		})
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no values files found in %s", dir)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+func loadMatrixFile(path string) ([]MatrixEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var mf matrixFile
+	if err := yaml.UnmarshalStrict(data, &mf); err != nil {
+		return nil, fmt.Errorf("failed to parse values matrix %s: %w", path, err)
+	}
+
+	entries := append([]MatrixEntry{}, mf.Combinations...)
+	entries = append(entries, expandAxes(mf.Axes)...)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("values matrix %s defines no permutations", path)
+	}
+
+	base := filepath.Dir(path)
+	seen := make(map[string]bool, len(entries))
+	for i := range entries {
+		if entries[i].Name == "" {
+			return nil, fmt.Errorf("values matrix %s: permutation %d has no name", path, i)
+		}
+		if seen[entries[i].Name] {
+			return nil, fmt.Errorf("values matrix %s: duplicate permutation %q", path, entries[i].Name)
+		}
+		seen[entries[i].Name] = true
+		for j, f := range entries[i].ValueFiles {
+			if !filepath.IsAbs(f) && !strings.Contains(f, "://") {
+				entries[i].ValueFiles[j] = filepath.Join(base, f)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// expandAxes returns the cartesian product of the given axes.
+func expandAxes(axes [][]string) []MatrixEntry {
+	if len(axes) == 0 {
+		return nil
+	}
+	combos := [][]string{{}}
+	for _, axis := range axes {
+		var next [][]string
+		for _, c := range combos {
+			for _, f := range axis {
+				next = append(next, append(append([]string{}, c...), f))
+			}
+		}
+		combos = next
+	}
+	entries := make([]MatrixEntry, 0, len(combos))
+	for _, c := range combos {
+		names := make([]string, 0, len(c))
+		for _, f := range c {
+			base := filepath.Base(f)
+			names = append(names, strings.TrimSuffix(base, filepath.Ext(base)))
+		}
+		entries = append(entries, MatrixEntry{Name: strings.Join(names, "-"), ValueFiles: c})
+	}
+	return entries
+}
+
+func isValuesFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json", ".toml", ".json5":
+		return true
+	}
+	return false
+}
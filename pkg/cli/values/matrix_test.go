@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMatrixDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"prod.yaml", "dev.yml", "ci.json", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := LoadMatrix(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []MatrixEntry{
+		{Name: "ci", ValueFiles: []string{filepath.Join(dir, "ci.json")}},
+		{Name: "dev", ValueFiles: []string{filepath.Join(dir, "dev.yml")}},
+		{Name: "prod", ValueFiles: []string{filepath.Join(dir, "prod.yaml")}},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+
+	if _, err := LoadMatrix(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without values files")
+	}
+}
+
+func TestLoadMatrixFile(t *testing.T) {
+	dir := t.TempDir()
+	matrix := `combinations:
+- name: prod-ha
+  values: [prod.yaml, /abs/ha.yaml]
+axes:
+- [dev.yaml, prod.yaml]
+- [small.yaml, large.yaml]
+`
+	path := filepath.Join(dir, "matrix.yaml")
+	if err := os.WriteFile(path, []byte(matrix), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := LoadMatrix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	expectedNames := []string{"prod-ha", "dev-small", "dev-large", "prod-small", "prod-large"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("expected permutations %v, got %v", expectedNames, names)
+	}
+	expectedFiles := []string{filepath.Join(dir, "prod.yaml"), "/abs/ha.yaml"}
+	if !reflect.DeepEqual(entries[0].ValueFiles, expectedFiles) {
+		t.Errorf("expected values files %v, got %v", expectedFiles, entries[0].ValueFiles)
+	}
+
+	dup := filepath.Join(dir, "dup.yaml")
+	if err := os.WriteFile(dup, []byte("combinations:\n- name: a\n- name: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMatrix(dup); err == nil {
+		t.Error("expected an error for duplicate permutation names")
+	}
+}
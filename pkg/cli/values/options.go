@@ -25,6 +25,7 @@ import (
 	"os"
 	"strings"
 
+	"helm.sh/helm/v4/pkg/chart/common"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/strvals"
@@ -43,18 +44,31 @@ type Options struct {
 // MergeValues merges values from files specified via -f/--values and directly
 // via --set-json, --set, --set-string, or --set-file, marshaling them to YAML
 func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, error) {
+	base, _, err := opts.MergeValuesWithSources(p)
+	return base, err
+}
+
+// MergeValuesWithSources merges values like MergeValues, and also returns the
+// values supplied by each file and flag, in the order they are merged in.
+func (opts *Options) MergeValuesWithSources(p getter.Providers) (map[string]interface{}, []common.ValuesSource, error) {
 	base := map[string]interface{}{}
+	var sources []common.ValuesSource
 
 	// User specified a values files via -f/--values
 	for _, filePath := range opts.ValueFiles {
 		raw, err := readFile(filePath, p)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		raw, err = common.ConvertValues(filePath, raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
 		}
 		currentMap, err := loader.LoadValues(bytes.NewReader(raw))
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", filePath, err)
 		}
+		sources = append(sources, common.ValuesSource{Name: "-f " + filePath, Values: currentMap})
 		// Merge with the previous map
 		base = loader.MergeMaps(base, currentMap)
 	}
@@ -62,57 +76,81 @@ func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, er
 	// User specified a value via --set-json
 	for _, value := range opts.JSONValues {
 		trimmedValue := strings.TrimSpace(value)
+		source := map[string]interface{}{}
 		if len(trimmedValue) > 0 && trimmedValue[0] == '{' {
 			// If value is JSON object format, parse it as map
 			var jsonMap map[string]interface{}
 			if err := json.Unmarshal([]byte(trimmedValue), &jsonMap); err != nil {
-				return nil, fmt.Errorf("failed parsing --set-json data JSON: %s", value)
+				return nil, nil, fmt.Errorf("failed parsing --set-json data JSON: %s", value)
 			}
 			base = loader.MergeMaps(base, jsonMap)
+			source = jsonMap
 		} else {
 			// Otherwise, parse it as key=value format
 			if err := strvals.ParseJSON(value, base); err != nil {
-				return nil, fmt.Errorf("failed parsing --set-json data %s", value)
+				return nil, nil, fmt.Errorf("failed parsing --set-json data %s", value)
 			}
+			_ = strvals.ParseJSON(value, source)
 		}
+		sources = append(sources, common.ValuesSource{Name: "--set-json " + value, Values: source})
 	}
 
 	// User specified a value via --set
 	for _, value := range opts.Values {
 		if err := strvals.ParseInto(value, base); err != nil {
-			return nil, fmt.Errorf("failed parsing --set data: %w", err)
+			return nil, nil, fmt.Errorf("failed parsing --set data: %w", err)
 		}
+		sources = append(sources, flagSource("--set", value, strvals.ParseInto))
 	}
 
 	// User specified a value via --set-string
 	for _, value := range opts.StringValues {
 		if err := strvals.ParseIntoString(value, base); err != nil {
-			return nil, fmt.Errorf("failed parsing --set-string data: %w", err)
+			return nil, nil, fmt.Errorf("failed parsing --set-string data: %w", err)
 		}
+		sources = append(sources, flagSource("--set-string", value, strvals.ParseIntoString))
 	}
 
 	// User specified a value via --set-file
 	for _, value := range opts.FileValues {
+		// Files are read once, even though the value is parsed twice.
+		read := map[string]interface{}{}
 		reader := func(rs []rune) (interface{}, error) {
+			if v, ok := read[string(rs)]; ok {
+				return v, nil
+			}
 			bytes, err := readFile(string(rs), p)
 			if err != nil {
 				return nil, err
 			}
+			read[string(rs)] = string(bytes)
 			return string(bytes), err
 		}
 		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
-			return nil, fmt.Errorf("failed parsing --set-file data: %w", err)
+			return nil, nil, fmt.Errorf("failed parsing --set-file data: %w", err)
 		}
+		sources = append(sources, flagSource("--set-file", value, func(s string, dest map[string]interface{}) error {
+			return strvals.ParseIntoFile(s, dest, reader)
+		}))
 	}
 
 	// User specified a value via --set-literal
 	for _, value := range opts.LiteralValues {
 		if err := strvals.ParseLiteralInto(value, base); err != nil {
-			return nil, fmt.Errorf("failed parsing --set-literal data: %w", err)
+			return nil, nil, fmt.Errorf("failed parsing --set-literal data: %w", err)
 		}
+		sources = append(sources, flagSource("--set-literal", value, strvals.ParseLiteralInto))
 	}
 
-	return base, nil
+	return base, sources, nil
+}
+
+// flagSource returns the values a flag supplies on its own. The value has
+// already been parsed successfully into the merged values.
+func flagSource(flag, value string, parse func(string, map[string]interface{}) error) common.ValuesSource {
+	vals := map[string]interface{}{}
+	_ = parse(value, vals)
+	return common.ValuesSource{Name: flag + " " + value, Values: vals}
 }
 
 // readFile load a file from stdin, the local directory, or a remote file with a url.
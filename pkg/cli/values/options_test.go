@@ -26,6 +26,7 @@ import (
 	"strings"
 	"testing"
 
+	"helm.sh/helm/v4/pkg/chart/common"
 	"helm.sh/helm/v4/pkg/getter"
 )
 
@@ -387,3 +388,76 @@ func TestMergeValuesCLI(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeValuesWithSources(t *testing.T) {
+	dir := t.TempDir()
+	valuesFile := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("foo: file\nbar: file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileValue := filepath.Join(dir, "value.txt")
+	if err := os.WriteFile(fileValue, []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{
+		ValueFiles: []string{valuesFile},
+		Values:     []string{"foo=set,list[1]=x"},
+		FileValues: []string{"baz=" + fileValue},
+	}
+	vals, sources, err := opts.MergeValuesWithSources(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"foo":  "set",
+		"bar":  "file",
+		"list": []interface{}{nil, "x"},
+		"baz":  "from file",
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected %v, got %v", expected, vals)
+	}
+
+	expectedSources := []common.ValuesSource{
+		{Name: "-f " + valuesFile, Values: map[string]interface{}{"foo": "file", "bar": "file"}},
+		{Name: "--set foo=set,list[1]=x", Values: map[string]interface{}{"foo": "set", "list": []interface{}{nil, "x"}}},
+		{Name: "--set-file baz=" + fileValue, Values: map[string]interface{}{"baz": "from file"}},
+	}
+	if !reflect.DeepEqual(sources, expectedSources) {
+		t.Errorf("expected sources %v, got %v", expectedSources, sources)
+	}
+}
+
+func TestMergeValuesTOMLAndJSON5(t *testing.T) {
+	dir := t.TempDir()
+	tomlFile := filepath.Join(dir, "values.toml")
+	if err := os.WriteFile(tomlFile, []byte("foo = \"toml\"\n\n[image]\ntag = \"1.0\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	json5File := filepath.Join(dir, "values.json5")
+	if err := os.WriteFile(json5File, []byte("// Overrides\n{\n  image: {tag: '2.0',},\n  bar: 'json5', // trailing comma\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := Options{ValueFiles: []string{tomlFile, json5File}}
+	vals, err := opts.MergeValues(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"foo":   "toml",
+		"bar":   "json5",
+		"image": map[string]interface{}{"tag": "2.0"},
+	}
+	if !reflect.DeepEqual(vals, expected) {
+		t.Errorf("expected %v, got %v", expected, vals)
+	}
+
+	if err := os.WriteFile(json5File, []byte("{bar: Infinity}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := opts.MergeValues(getter.Providers{}); err == nil || !strings.Contains(err.Error(), "failed to parse "+json5File) {
+		t.Errorf("expected a parse error of %s, got %v", json5File, err)
+	}
+}
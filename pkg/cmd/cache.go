@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/downloader"
+)
+
+const cacheHelp = `
+This command consists of multiple subcommands to manage the content cache.
+
+The content cache holds the charts downloaded by 'helm pull', 'helm install',
+'helm upgrade' and the dependency commands, keyed by the SHA256 digest of
+their content, along with their provenance files. A chart whose digest is
+known, from the index of its repository or the lock file of a chart, is read
+from the cache instead of being downloaded again. The cache is shared by all
+the repositories, and is located with --content-cache or $HELM_CONTENT_CACHE.
+`
+
+func newCacheCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache list|prune|verify",
+		Short: "list, prune, and verify the cached charts",
+		Long:  cacheHelp,
+		Args:  require.NoArgs,
+	}
+
+	cmd.AddCommand(newCacheListCmd(out))
+	cmd.AddCommand(newCachePruneCmd(out))
+	cmd.AddCommand(newCacheVerifyCmd(out))
+
+	return cmd
+}
+
+// contentCache returns the content cache of the settings.
+func contentCache() *downloader.DiskCache {
+	return &downloader.DiskCache{Root: settings.ContentCache}
+}
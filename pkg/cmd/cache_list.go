@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/downloader"
+)
+
+func newCacheListCmd(out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	cmd := &cobra.Command{
+		Use:               "list",
+		Aliases:           []string{"ls"},
+		Short:             "list the cached charts, the least recently used first",
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			entries, err := contentCache().Entries()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 && outfmt != output.JSON && outfmt != output.YAML {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no cached charts to show")
+				return nil
+			}
+			return outfmt.Write(out, &cacheListWriter{entries})
+		},
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+type cacheListWriter struct {
+	entries []downloader.CacheEntry
+}
+
+func (w *cacheListWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("DIGEST", "TYPE", "SIZE", "LAST USED")
+	for _, e := range w.entries {
+		table.AddRow(e.Digest, strings.TrimPrefix(e.Type, "."), e.Size, e.LastUsed.Format(time.DateTime))
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *cacheListWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.list())
+}
+
+func (w *cacheListWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.list())
+}
+
+// list returns the entries, as an empty list instead of null when there are
+// none.
+func (w *cacheListWriter) list() []downloader.CacheEntry {
+	return append(make([]downloader.CacheEntry, 0, len(w.entries)), w.entries...)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const cachePruneDesc = `
+Remove charts from the content cache.
+
+With --older-than, the charts not used for that long are removed. With
+--max-size, the least recently used charts are then removed until the cache
+is no larger than that size, given as a quantity such as 500Mi or 2Gi. The
+provenance file of a chart is removed with it. With --all, the whole cache is
+emptied.
+`
+
+func newCachePruneCmd(out io.Writer) *cobra.Command {
+	var olderThan time.Duration
+	var maxSize string
+	var all bool
+
+	cmd := &cobra.Command{
+		Use:               "prune",
+		Short:             "remove the least recently used charts from the cache",
+		Long:              cachePruneDesc,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			var before time.Time
+			if olderThan > 0 {
+				before = time.Now().Add(-olderThan)
+			}
+			var limit int64
+			if maxSize != "" {
+				q, err := resource.ParseQuantity(maxSize)
+				if err != nil {
+					return fmt.Errorf("invalid --max-size %q: %w", maxSize, err)
+				}
+				// A limit of zero would not prune anything.
+				limit = max(q.Value(), 1)
+			}
+			if all {
+				before, limit = time.Time{}, 1
+			}
+			if before.IsZero() && limit == 0 {
+				return errors.New("one of --older-than, --max-size or --all is required")
+			}
+
+			pruned, err := contentCache().Prune(before, limit)
+			var size int64
+			for _, e := range pruned {
+				size += e.Size
+			}
+			fmt.Fprintf(out, "Removed %d files (%d bytes) from the content cache\n", len(pruned), size)
+			return err
+		},
+	}
+
+	f := cmd.Flags()
+	f.DurationVar(&olderThan, "older-than", 0, "remove the charts not used for this long")
+	f.StringVar(&maxSize, "max-size", "", "remove the least recently used charts until the cache is no larger than this size")
+	f.BoolVar(&all, "all", false, "remove all the charts")
+
+	return cmd
+}
@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v4/pkg/downloader"
+)
+
+func TestCacheCmd(t *testing.T) {
+	dir := t.TempDir()
+	cache := &downloader.DiskCache{Root: dir}
+	var keys [][sha256.Size]byte
+	for i, content := range []string{"old chart", "new chart"} {
+		key := sha256.Sum256([]byte(content))
+		p, err := cache.Put(key, bytes.NewReader([]byte(content)), downloader.CacheChart)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-1) * 48 * time.Hour)
+		if err := os.Chtimes(p, used, used); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("cache list --content-cache %s", dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDigest, newDigest := fmt.Sprintf("sha256:%x", keys[0]), fmt.Sprintf("sha256:%x", keys[1])
+	if !strings.HasPrefix(out, "DIGEST") || strings.Index(out, oldDigest) > strings.Index(out, newDigest) {
+		t.Errorf("expected the charts listed by last use, got:\n%s", out)
+	}
+
+	_, out, err = executeActionCommand(fmt.Sprintf("cache verify --content-cache %s", dir))
+	if err != nil || out != "All the cached charts match their digests\n" {
+		t.Errorf("expected the cache to be verified, got %v:\n%s", err, out)
+	}
+
+	corrupted := &downloader.DiskCache{Root: t.TempDir()}
+	p, err := corrupted.Put(keys[0], strings.NewReader("old chart"), downloader.CacheChart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = executeActionCommand(fmt.Sprintf("cache verify --content-cache %s", corrupted.Root)); err == nil {
+		t.Error("expected an error verifying a corrupted cache")
+	}
+	_, out, err = executeActionCommand(fmt.Sprintf("cache verify --content-cache %s --remove", corrupted.Root))
+	if err != nil || !strings.HasSuffix(out, "Removed 1 corrupted charts from the content cache\n") {
+		t.Errorf("expected the corrupted chart to be removed, got %v:\n%s", err, out)
+	}
+	if _, err := os.Stat(p); err == nil {
+		t.Error("expected the corrupted chart to be removed")
+	}
+
+	_, _, err = executeActionCommand(fmt.Sprintf("cache prune --content-cache %s", dir))
+	if err == nil {
+		t.Error("expected an error pruning without a limit")
+	}
+	_, out, err = executeActionCommand(fmt.Sprintf("cache prune --content-cache %s --older-than 24h", dir))
+	if err != nil || out != "Removed 1 files (9 bytes) from the content cache\n" {
+		t.Errorf("expected the old chart to be pruned, got %v:\n%s", err, out)
+	}
+	if _, err := cache.Get(keys[1], downloader.CacheChart); err != nil {
+		t.Errorf("expected the new chart to be kept: %s", err)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const cacheVerifyDesc = `
+Verify that the content of each chart of the content cache matches the digest
+it is cached under.
+
+The command fails listing the corrupted charts. With --remove, they are
+removed from the cache, to be downloaded again the next time they are needed.
+`
+
+func newCacheVerifyCmd(out io.Writer) *cobra.Command {
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:               "verify",
+		Short:             "verify the content of the cached charts",
+		Long:              cacheVerifyDesc,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			corrupted, err := contentCache().Verify(remove)
+			if err != nil {
+				return err
+			}
+			for _, e := range corrupted {
+				fmt.Fprintf(out, "Corrupted: %s (%s)\n", e.Digest, e.Path)
+			}
+			switch {
+			case len(corrupted) == 0:
+				fmt.Fprintln(out, "All the cached charts match their digests")
+				return nil
+			case remove:
+				fmt.Fprintf(out, "Removed %d corrupted charts from the content cache\n", len(corrupted))
+				return nil
+			}
+			return fmt.Errorf("%d cached charts do not match their digests: remove them with --remove", len(corrupted))
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "remove the corrupted charts from the cache")
+
+	return cmd
+}
@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/action"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const convertDesc = `
+This command converts a directory of Kubernetes manifests, or a kustomization,
+into a chart.
+
+When the directory has a kustomization file, the kustomization is built and its
+resources are converted. Otherwise, the YAML and JSON files of the directory
+and of its subdirectories are read.
+
+Each resource is written to a template of the chart, without its namespace so
+that the chart is installed in the namespace of the release. The chart is
+parameterized with values:
+
+- namePrefix is prepended to the names of the resources, and to the references
+  to them in pod specs, ingresses and role bindings.
+- The replicas of the deployments, stateful sets and replica sets are extracted
+  to <workload>.replicas.
+- The images of the containers of the workloads are extracted to
+  <workload>.images.<container>, with their repository, tag and digest.
+
+The fields extracted to values are listed once the chart is written. The chart
+is created in the directory given with --destination, and is named after the
+converted directory unless --name is set.
+`
+
+type convertOptions struct {
+	destination string // --destination
+}
+
+func newConvertCmd(out io.Writer) *cobra.Command {
+	client := action.NewConvert()
+	o := &convertOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "convert DIRECTORY",
+		Short: "convert Kubernetes manifests or a kustomization into a chart",
+		Long:  convertDesc,
+		Args:  require.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.run(out, client, args[0])
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Name, "name", "", "name of the chart. Defaults to the name of the converted directory")
+	f.StringVar(&client.Version, "version", client.Version, "version of the chart")
+	f.StringVar(&client.AppVersion, "app-version", "", "version of the application of the chart")
+	f.StringVarP(&o.destination, "destination", "d", ".", "location to write the chart")
+
+	return cmd
+}
+
+func (o *convertOptions) run(out io.Writer, client *action.Convert, src string) error {
+	result, err := client.Run(src)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(o.destination, result.Chart.Name())
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+	if err := os.MkdirAll(o.destination, 0755); err != nil {
+		return err
+	}
+	if err := chartutil.SaveDir(result.Chart, o.destination); err != nil {
+		return err
+	}
+
+	from := "the manifests"
+	if result.Kustomization {
+		from = "the kustomization"
+	}
+	fmt.Fprintf(out, "Converted %d resources of %s into the chart %s\n", result.Resources, from, result.Chart.Name())
+	if len(result.Parameters) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out, "Extracted to values.yaml:")
+	for _, p := range result.Parameters {
+		fmt.Fprintf(out, "  %s (%s)\n", p, p.Value)
+	}
+	return nil
+}
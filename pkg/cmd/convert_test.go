@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v4/internal/test"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+)
+
+func TestConvertCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []cmdTestCase{{
+		name:   "convert a directory of manifests",
+		cmd:    fmt.Sprintf("convert testdata/convert/manifests --name shop -d %s", dir),
+		golden: "output/convert-manifests.txt",
+	}, {
+		name:   "convert a kustomization",
+		cmd:    fmt.Sprintf("convert testdata/convert/overlay --app-version 1.1.0 -d %s", dir),
+		golden: "output/convert-kustomization.txt",
+	}, {
+		name:      "convert into an existing chart",
+		cmd:       fmt.Sprintf("convert testdata/convert/manifests --name shop -d %s", dir),
+		wantError: true,
+	}, {
+		name:      "convert a file",
+		cmd:       fmt.Sprintf("convert testdata/convert/base/deployment.yaml -d %s", dir),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+
+	c, err := loader.Load(filepath.Join(dir, "overlay"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AppVersion() != "1.1.0" {
+		t.Errorf("expected the app version 1.1.0, got %q", c.AppVersion())
+	}
+	for _, name := range []string{"values.yaml", "templates/deployment-web.yaml", "templates/configmap-web-config.yaml"} {
+		data, err := os.ReadFile(filepath.Join(dir, "shop", filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.AssertGoldenString(t, string(data), "output/convert-manifests-"+filepath.Base(name))
+	}
+}
@@ -24,6 +24,9 @@ import (
 
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/downloader"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/registry"
 )
 
 const dependencyDesc = `
@@ -59,6 +62,9 @@ appending '/index.yaml' to the URL, it should be able to retrieve the chart
 repository's index. Note: 'repository' can be an alias. The alias must start
 with 'alias:' or '@'.
 
+'helm dependency add' and 'helm dependency remove' edit the dependencies in
+'Chart.yaml', keeping its comments and formatting.
+
 Starting from 2.2.0, repository can be defined as the path to the directory of
 the dependency charts stored locally. The path should start with a prefix of
 "file://". For example,
@@ -72,6 +78,32 @@ the dependency charts stored locally. The path should start with a prefix of
 If the dependency chart is retrieved locally, it is not required to have the
 repository added to helm by "helm add repo". Version matching is also supported
 for this case.
+
+The values for a dependency can be kept in a file of their own instead of in
+the 'values.yaml' file of the chart. The 'valuesFile' path is relative to the
+root of the chart, and the values in 'values.yaml' for the dependency take
+precedence over the values in the file:
+
+    # Chart.yaml
+    dependencies:
+    - name: redis
+      version: "17.0.0"
+      repository: "https://example.com/charts"
+      valuesFile: "deps/redis.yaml"
+
+'helm dependency split-values' moves the values of an umbrella chart for its
+dependencies out of 'values.yaml' and into such files.
+
+The 'condition' of a dependency is either a comma separated list of value paths,
+enabling the dependency with the first of them that is set, or a CEL expression
+over the values of the chart, which are available as 'values':
+
+    # Chart.yaml
+    dependencies:
+    - name: redis
+      version: "17.0.0"
+      repository: "https://example.com/charts"
+      condition: "values.replicas > 1 && !values.externalCache"
 `
 
 const dependencyListDesc = `
@@ -85,7 +117,7 @@ This will produce an error if the chart cannot be loaded.
 
 func newDependencyCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "dependency update|build|list",
+		Use:     "dependency update|build|list|add|remove|split-values",
 		Aliases: []string{"dep", "dependencies"},
 		Short:   "manage a chart's dependencies",
 		Long:    dependencyDesc,
@@ -95,6 +127,9 @@ func newDependencyCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd.AddCommand(newDependencyListCmd(out))
 	cmd.AddCommand(newDependencyUpdateCmd(cfg, out))
 	cmd.AddCommand(newDependencyBuildCmd(out))
+	cmd.AddCommand(newDependencyAddCmd(out))
+	cmd.AddCommand(newDependencyRemoveCmd(out))
+	cmd.AddCommand(newDependencySplitValuesCmd(out))
 
 	return cmd
 }
@@ -133,4 +168,25 @@ func addDependencySubcommandFlags(f *pflag.FlagSet, client *action.Dependency) {
 	f.BoolVar(&client.InsecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart download")
 	f.BoolVar(&client.PlainHTTP, "plain-http", false, "use insecure HTTP connections for the chart download")
 	f.StringVar(&client.CaFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.StringVar(&client.DigestAlgorithm, "lock-digest-algorithm", "", "hash algorithm of the lock file digest (sha256, sha512). Defaults to the algorithm of the existing lock file, or sha256")
+	f.BoolVar(&client.ArchiveDigests, "lock-archive-digests", false, "record the digest of the archive of each dependency in the lock file, verified when building the dependencies. Kept on when the existing lock file records them")
+	addRetryFlags(f, &client.Retries, &client.RetryBackoff)
+	f.IntVar(&client.Concurrency, "concurrency", client.Concurrency, "maximum number of charts downloaded at once. With more than one, the errors of all the failed downloads are reported")
+}
+
+// newDependencyManager returns a downloader.Manager used by commands that
+// build the dependencies of a chart before operating on it.
+func newDependencyManager(out io.Writer, chartPath, keyring string, getters getter.Providers, registryClient *registry.Client) *downloader.Manager {
+	return &downloader.Manager{
+		Out:              out,
+		ChartPath:        chartPath,
+		Keyring:          keyring,
+		SkipUpdate:       false,
+		Getters:          getters,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+		ContentCache:     settings.ContentCache,
+		Debug:            settings.Debug,
+		RegistryClient:   registryClient,
+	}
 }
@@ -0,0 +1,126 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/action"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const dependencyAddDesc = `
+Add a dependency to the Chart.yaml file of a chart directory.
+
+The dependency is appended to the dependencies of the chart, keeping the
+comments and formatting of Chart.yaml. A chart cannot have two dependencies
+with the same name. Run 'helm dependency update' afterwards to download the
+dependency and update Chart.lock, or add it with --update.
+
+    $ helm dependency add redis ./mychart --version "^17.0.0" --repository https://example.com/charts
+
+The dependency can be given an alias to load it under, a condition enabling it
+and tags:
+
+    $ helm dependency add redis ./mychart --repository @bitnami --alias cache --condition cache.enabled --tags backend
+`
+
+const dependencyRemoveDesc = `
+Remove a dependency, given by its alias or else its name, from the Chart.yaml
+file of a chart directory.
+
+The comments and formatting of Chart.yaml are kept. Run 'helm dependency
+update' afterwards to remove the dependency from charts/ and Chart.lock, or
+remove it with --update.
+`
+
+func newDependencyAddCmd(out io.Writer) *cobra.Command {
+	client := action.NewDependency()
+	dep := &chart.Dependency{}
+	var update bool
+
+	cmd := &cobra.Command{
+		Use:   "add NAME [CHART]",
+		Short: "add a dependency to Chart.yaml",
+		Long:  dependencyAddDesc,
+		Args:  require.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			dep.Name = args[0]
+			chartpath := "."
+			if len(args) > 1 {
+				chartpath = filepath.Clean(args[1])
+			}
+			if err := client.Add(chartpath, dep); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Added dependency %s to %s\n", dep.Name, filepath.Join(chartpath, "Chart.yaml"))
+			if update {
+				return runDependencyUpdate(out, chartpath, client)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&dep.Version, "version", "", "version or version range of the dependency")
+	f.StringVar(&dep.Repository, "repository", "", "URL or alias of the repository of the dependency, or file:// path to its chart directory")
+	f.StringVar(&dep.Alias, "alias", "", "name to load the dependency under")
+	f.StringVar(&dep.Condition, "condition", "", "comma separated value paths, or CEL expression, enabling the dependency")
+	f.StringSliceVar(&dep.Tags, "tags", nil, "tags enabling or disabling the dependency with other ones")
+	f.BoolVar(&update, "update", false, "update charts/ and Chart.lock after adding the dependency")
+	addDependencySubcommandFlags(f, client)
+
+	return cmd
+}
+
+func newDependencyRemoveCmd(out io.Writer) *cobra.Command {
+	client := action.NewDependency()
+	var update bool
+
+	cmd := &cobra.Command{
+		Use:     "remove NAME [CHART]",
+		Aliases: []string{"rm"},
+		Short:   "remove a dependency from Chart.yaml",
+		Long:    dependencyRemoveDesc,
+		Args:    require.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			chartpath := "."
+			if len(args) > 1 {
+				chartpath = filepath.Clean(args[1])
+			}
+			if err := client.Remove(chartpath, args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Removed dependency %s from %s\n", args[0], filepath.Join(chartpath, "Chart.yaml"))
+			if update {
+				return runDependencyUpdate(out, chartpath, client)
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&update, "update", false, "update charts/ and Chart.lock after removing the dependency")
+	addDependencySubcommandFlags(f, client)
+
+	return cmd
+}
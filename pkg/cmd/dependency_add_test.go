@@ -0,0 +1,130 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDependencyAddRemoveCmd(t *testing.T) {
+	chartPath := t.TempDir()
+	chartfile := filepath.Join(chartPath, "Chart.yaml")
+	if err := os.WriteFile(chartfile, []byte(`# The chart of the frontend
+apiVersion: v2
+name: frontend
+version: 1.0.0
+dependencies:
+  # The cache
+  - name: redis
+    version: 17.0.0
+    repository: https://example.com/charts
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []cmdTestCase{{
+		name: "add a dependency",
+		cmd:  fmt.Sprintf("dependency add postgresql %s --version ^12.0.0 --repository oci://example.com/charts", chartPath),
+	}, {
+		name:      "add a dependency twice",
+		cmd:       fmt.Sprintf("dependency add postgresql %s --repository oci://example.com/charts", chartPath),
+		wantError: true,
+	}, {
+		name: "add a dependency with an alias, a condition and tags",
+		cmd:  fmt.Sprintf("dependency add postgresql %s --repository oci://example.com/charts --alias db --condition db.enabled --tags backend,storage", chartPath),
+	}, {
+		name:      "add a dependency with an invalid alias",
+		cmd:       fmt.Sprintf("dependency add postgresql %s --alias ../db", chartPath),
+		wantError: true,
+	}, {
+		name: "remove a dependency",
+		cmd:  fmt.Sprintf("dependency remove redis %s", chartPath),
+	}, {
+		name:      "remove a missing dependency",
+		cmd:       fmt.Sprintf("dependency remove redis %s", chartPath),
+		wantError: true,
+	}, {
+		name:      "add a dependency to a chart with apiVersion v1",
+		cmd:       "dependency add redis testdata/testcharts/alpine",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+
+	data, err := os.ReadFile(chartfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# The chart of the frontend
+apiVersion: v2
+name: frontend
+version: 1.0.0
+dependencies:
+  - name: postgresql
+    version: ^12.0.0
+    repository: oci://example.com/charts
+  - name: postgresql
+    repository: oci://example.com/charts
+    condition: db.enabled
+    tags:
+      - backend
+      - storage
+    alias: db
+`
+	if string(data) != expected {
+		t.Errorf("expected Chart.yaml:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestDependencyAddRemoveCmdUpdate(t *testing.T) {
+	dir := t.TempDir()
+	chartPath := filepath.Join(dir, "parent")
+	for _, name := range []string{"parent", "child", "sibling"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "Chart.yaml"), []byte("apiVersion: v2\nname: "+name+"\nversion: 0.1.0\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []cmdTestCase{{
+		name: "add a dependency and update the charts",
+		cmd:  fmt.Sprintf("dependency add child %s --version 0.1.0 --repository file://../child --update", chartPath),
+	}, {
+		name: "add another dependency and update the charts",
+		cmd:  fmt.Sprintf("dependency add sibling %s --version 0.1.0 --repository file://../sibling --update", chartPath),
+	}}
+	runTestCmd(t, tests)
+	for _, archive := range []string{"child-0.1.0.tgz", "sibling-0.1.0.tgz"} {
+		if _, err := os.Stat(filepath.Join(chartPath, "charts", archive)); err != nil {
+			t.Errorf("expected the dependency to be downloaded: %s", err)
+		}
+	}
+
+	tests = []cmdTestCase{{
+		name: "remove a dependency and update the charts",
+		cmd:  fmt.Sprintf("dependency remove child %s --update", chartPath),
+	}}
+	runTestCmd(t, tests)
+	if _, err := os.Stat(filepath.Join(chartPath, "charts", "child-0.1.0.tgz")); err == nil {
+		t.Error("expected the dependency to be removed")
+	}
+}
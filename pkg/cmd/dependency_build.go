@@ -18,13 +18,12 @@ package cmd
 import (
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
-	"k8s.io/client-go/util/homedir"
 
 	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/flags"
 	"helm.sh/helm/v4/pkg/cmd/require"
 	"helm.sh/helm/v4/pkg/downloader"
 	"helm.sh/helm/v4/pkg/getter"
@@ -39,6 +38,15 @@ does.
 
 If no lock file is found, 'helm dependency build' will mirror the behavior
 of 'helm dependency update'.
+
+With --offline, the network is never accessed. Dependencies are resolved from
+the local repository cache and taken from the charts/ directory or the content
+cache, and the build fails listing the dependencies that would need the
+network. This makes builds hermetic, e.g. in CI environments.
+
+With --concurrency greater than one, that many charts are downloaded at once
+and the errors of all the failed downloads are reported, instead of stopping
+at the first one.
 `
 
 func newDependencyBuildCmd(out io.Writer) *cobra.Command {
@@ -65,12 +73,18 @@ func newDependencyBuildCmd(out io.Writer) *cobra.Command {
 				ChartPath:        chartpath,
 				Keyring:          client.Keyring,
 				SkipUpdate:       client.SkipRefresh,
+				DigestAlgorithm:  client.DigestAlgorithm,
+				ArchiveDigests:   client.ArchiveDigests,
+				Offline:          client.Offline,
 				Getters:          getter.All(settings),
 				RegistryClient:   registryClient,
 				RepositoryConfig: settings.RepositoryConfig,
 				RepositoryCache:  settings.RepositoryCache,
 				ContentCache:     settings.ContentCache,
 				Debug:            settings.Debug,
+				Retries:          client.Retries,
+				RetryBackoff:     client.RetryBackoff,
+				Concurrency:      client.Concurrency,
 			}
 			if client.Verify {
 				man.Verify = downloader.VerifyIfPossible
@@ -85,14 +99,12 @@ func newDependencyBuildCmd(out io.Writer) *cobra.Command {
 
 	f := cmd.Flags()
 	addDependencySubcommandFlags(f, client)
+	f.BoolVar(&client.Offline, "offline", false, "never access the network and use only the local repository cache, content cache and charts directory")
 
 	return cmd
 }
 
 // defaultKeyring returns the expanded path to the default keyring.
 func defaultKeyring() string {
-	if v, ok := os.LookupEnv("GNUPGHOME"); ok {
-		return filepath.Join(v, "pubring.gpg")
-	}
-	return filepath.Join(homedir.HomeDir(), ".gnupg", "pubring.gpg")
+	return flags.DefaultKeyring()
 }
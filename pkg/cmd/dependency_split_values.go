@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const dependencySplitValuesDesc = `
+Split the values.yaml file of an umbrella chart into a values file for each of
+its dependencies.
+
+The values the chart has for a dependency, under the alias of the dependency if
+it has one and under its name otherwise, are moved to a values file in the
+directory given with --dir, and the file is set as the valuesFile of the
+dependency in Chart.yaml. The values of the chart itself and the globals stay
+in values.yaml. The values of a dependency that already has a values file are
+merged into that file.
+
+The chart is rendered with and without the split values, and the split is only
+written when both renders are identical. As values.yaml and Chart.yaml are
+rewritten, their comments are not kept. With --dry-run, the files are printed
+instead of written.
+`
+
+func newDependencySplitValuesCmd(out io.Writer) *cobra.Command {
+	client := action.NewSplitValues()
+
+	cmd := &cobra.Command{
+		Use:   "split-values CHART",
+		Short: "split the values of an umbrella chart into values files of its dependencies",
+		Long:  dependencySplitValuesDesc,
+		Args:  require.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			chartpath := "."
+			if len(args) > 0 {
+				chartpath = filepath.Clean(args[0])
+			}
+			result, err := client.Run(chartpath)
+			if err != nil {
+				return err
+			}
+			files := slices.Sorted(maps.Keys(result.Files))
+			if client.DryRun {
+				for _, name := range files {
+					fmt.Fprintf(out, "---\n# Source: %s\n%s", name, result.Files[name])
+				}
+				return nil
+			}
+			for _, name := range slices.Sorted(maps.Keys(result.ValuesFiles)) {
+				fmt.Fprintf(out, "Moved the values of %s to %s\n", name, result.ValuesFiles[name])
+			}
+			fmt.Fprintln(out, "Updated values.yaml and Chart.yaml")
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Dir, "dir", client.Dir, "directory of the chart to write the values files of the dependencies to")
+	f.BoolVar(&client.DryRun, "dry-run", false, "print the split values instead of writing them")
+
+	return cmd
+}
@@ -0,0 +1,67 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+)
+
+func TestDependencySplitValuesCmd(t *testing.T) {
+	chartPath := filepath.Join(t.TempDir(), "chart-with-split-values")
+	if err := os.CopyFS(chartPath, os.DirFS("testdata/testcharts/chart-with-split-values")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []cmdTestCase{{
+		name:   "split the values of an umbrella chart in a dry run",
+		cmd:    fmt.Sprintf("dependency split-values %s --dry-run", chartPath),
+		golden: "output/dependency-split-values-dry-run.txt",
+	}, {
+		name:   "split the values of an umbrella chart",
+		cmd:    fmt.Sprintf("dependency split-values %s --dir deps", chartPath),
+		golden: "output/dependency-split-values.txt",
+	}, {
+		name:      "split the values of an umbrella chart again",
+		cmd:       fmt.Sprintf("dependency split-values %s", chartPath),
+		wantError: true,
+	}, {
+		name:      "split the values of a packaged chart",
+		cmd:       "dependency split-values testdata/testcharts/compressedchart-0.1.0.tgz",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+
+	md, err := chartutil.LoadChartfile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Dependencies[0].ValuesFile != "deps/web.yaml" || md.Dependencies[1].ValuesFile != "deps/cache.yaml" {
+		t.Errorf("expected the values files of the dependencies to be set, got %q and %q", md.Dependencies[0].ValuesFile, md.Dependencies[1].ValuesFile)
+	}
+	data, err := os.ReadFile(filepath.Join(chartPath, "deps", "web.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "port: 8443\n" {
+		t.Errorf("unexpected values file of web: %q", data)
+	}
+}
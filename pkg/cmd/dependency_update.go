@@ -41,6 +41,10 @@ rebuild the dependencies to an exact version.
 Dependencies are not required to be represented in 'Chart.yaml'. For that
 reason, an update command will not remove charts unless they are (a) present
 in the Chart.yaml file, but (b) at the wrong version.
+
+With --explain, this prints how the version of each dependency was chosen: the
+candidate versions considered, where they came from, and which of them satisfy
+the version constraint.
 `
 
 // newDependencyUpdateCmd creates a new dependency update command.
@@ -58,33 +62,46 @@ func newDependencyUpdateCmd(_ *action.Configuration, out io.Writer) *cobra.Comma
 			if len(args) > 0 {
 				chartpath = filepath.Clean(args[0])
 			}
-			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
-				client.InsecureSkipTLSverify, client.PlainHTTP, client.Username, client.Password)
-			if err != nil {
-				return fmt.Errorf("missing registry client: %w", err)
-			}
-
-			man := &downloader.Manager{
-				Out:              out,
-				ChartPath:        chartpath,
-				Keyring:          client.Keyring,
-				SkipUpdate:       client.SkipRefresh,
-				Getters:          getter.All(settings),
-				RegistryClient:   registryClient,
-				RepositoryConfig: settings.RepositoryConfig,
-				RepositoryCache:  settings.RepositoryCache,
-				ContentCache:     settings.ContentCache,
-				Debug:            settings.Debug,
-			}
-			if client.Verify {
-				man.Verify = downloader.VerifyAlways
-			}
-			return man.Update()
+			return runDependencyUpdate(out, chartpath, client)
 		},
 	}
 
 	f := cmd.Flags()
 	addDependencySubcommandFlags(f, client)
+	f.BoolVar(&client.Explain, "explain", false, "print how the version of each dependency was chosen")
 
 	return cmd
 }
+
+// runDependencyUpdate updates the charts/ directory of the chart at chartpath
+// based on the contents of its Chart.yaml.
+func runDependencyUpdate(out io.Writer, chartpath string, client *action.Dependency) error {
+	registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
+		client.InsecureSkipTLSverify, client.PlainHTTP, client.Username, client.Password)
+	if err != nil {
+		return fmt.Errorf("missing registry client: %w", err)
+	}
+
+	man := &downloader.Manager{
+		Out:              out,
+		ChartPath:        chartpath,
+		Keyring:          client.Keyring,
+		SkipUpdate:       client.SkipRefresh,
+		DigestAlgorithm:  client.DigestAlgorithm,
+		ArchiveDigests:   client.ArchiveDigests,
+		Explain:          client.Explain,
+		Getters:          getter.All(settings),
+		RegistryClient:   registryClient,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+		ContentCache:     settings.ContentCache,
+		Debug:            settings.Debug,
+		Retries:          client.Retries,
+		RetryBackoff:     client.RetryBackoff,
+		Concurrency:      client.Concurrency,
+	}
+	if client.Verify {
+		man.Verify = downloader.VerifyAlways
+	}
+	return man.Update()
+}
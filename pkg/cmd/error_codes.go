@@ -0,0 +1,54 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+// The codes of the errors of the repo and plugin commands. The messages of
+// the errors are not translated, and their codes stay the same when they are
+// reworded, so they can be searched for whatever the language of the user.
+const (
+	// ErrCodeRepoDeprecated is the code of the error adding a repository
+	// that is no longer available.
+	ErrCodeRepoDeprecated = "HELM1001"
+	// ErrCodeRepoInvalidProxy is the code of the error adding a repository
+	// with an invalid proxy URL.
+	ErrCodeRepoInvalidProxy = "HELM1002"
+	// ErrCodeRepoInvalidMirrorOrder is the code of the error adding a
+	// repository with an unknown order of its mirrors.
+	ErrCodeRepoInvalidMirrorOrder = "HELM1003"
+	// ErrCodeRepoInvalidName is the code of the error adding a repository
+	// whose name contains a '/'.
+	ErrCodeRepoInvalidName = "HELM1004"
+	// ErrCodeRepoExists is the code of the error adding a repository whose
+	// name is already taken.
+	ErrCodeRepoExists = "HELM1005"
+	// ErrCodeRepoUnreachable is the code of the error adding a repository
+	// whose index cannot be downloaded.
+	ErrCodeRepoUnreachable = "HELM1006"
+	// ErrCodeNoRepositories is the code of the error removing or updating
+	// repositories when none is configured.
+	ErrCodeNoRepositories = "HELM1007"
+	// ErrCodeRepoNotFound is the code of the error removing or updating a
+	// repository that is not configured.
+	ErrCodeRepoNotFound = "HELM1008"
+
+	// ErrCodePluginNotFound is the code of the error uninstalling or
+	// updating a plugin that is not installed.
+	ErrCodePluginNotFound = "HELM2001"
+	// ErrCodePluginUnverifiable is the code of the error installing a plugin
+	// with verification from a source that does not support it.
+	ErrCodePluginUnverifiable = "HELM2002"
+)
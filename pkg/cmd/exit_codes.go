@@ -0,0 +1,127 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"oras.land/oras-go/v2/errdef"
+
+	"helm.sh/helm/v4/pkg/action"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/downloader"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/repo/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// The exit codes of the install, upgrade, rollback and test commands by class
+// of failure, so that automation can tell failures apart without parsing the
+// error messages.
+const (
+	// ExitCodeError is the exit code of the failures of no other class.
+	ExitCodeError = 1
+	// ExitCodeInvalid is the exit code of the failures caused by invalid
+	// input, such as values not matching the schema of the chart, templates
+	// failing to render, or manifests rejected by the cluster.
+	ExitCodeInvalid = 2
+	// ExitCodeNotFound is the exit code of the failures caused by a release,
+	// a revision, a chart or a resource not found.
+	ExitCodeNotFound = 3
+	// ExitCodeTimeout is the exit code of the failures caused by a timeout,
+	// such as the resources of a release not ready in time.
+	ExitCodeTimeout = 4
+	// ExitCodeConflict is the exit code of the failures caused by a conflict
+	// with other releases or resources, such as another operation in
+	// progress on the release.
+	ExitCodeConflict = 5
+	// ExitCodeRolledBack is the exit code of the installs and upgrades that
+	// failed, and whose changes were reverted by --rollback-on-failure.
+	ExitCodeRolledBack = 6
+)
+
+const exitCodesHelp = `
+Exit codes:
+
+    0  success
+    1  failure of no other class
+    2  invalid input, such as values not matching the schema of the chart
+    3  release, revision, chart or resource not found
+    4  timeout
+    5  conflict, such as another operation in progress on the release
+    6  failure reverted by --rollback-on-failure
+`
+
+// exitCode returns the exit code of the class of an error.
+func exitCode(err error) int {
+	var rolledBack *action.RolledBackError
+	var httpErr *getter.HTTPStatusError
+	var incompatibleErr *action.IncompatibleOptionsError
+	var validationErr chart.ValidationError
+	var repoErr downloader.ErrRepoNotFound
+	switch {
+	case errors.As(err, &rolledBack):
+		return ExitCodeRolledBack
+	case errors.Is(err, context.DeadlineExceeded), wait.Interrupted(err):
+		return ExitCodeTimeout
+	case errors.Is(err, action.ErrConflict),
+		errors.Is(err, driver.ErrReleaseExists),
+		apierrors.IsConflict(err),
+		apierrors.IsAlreadyExists(err):
+		return ExitCodeConflict
+	case errors.Is(err, driver.ErrReleaseNotFound),
+		errors.Is(err, driver.ErrNoDeployedReleases),
+		errors.Is(err, fs.ErrNotExist),
+		errors.Is(err, repo.ChartNotFoundError{}),
+		errors.As(err, &repoErr),
+		errors.Is(err, errdef.ErrNotFound),
+		errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound,
+		apierrors.IsNotFound(err):
+		return ExitCodeNotFound
+	case errors.Is(err, action.ErrInvalid),
+		errors.As(err, &incompatibleErr),
+		errors.As(err, &validationErr),
+		apierrors.IsInvalid(err),
+		apierrors.IsBadRequest(err),
+		meta.IsNoMatchError(err):
+		return ExitCodeInvalid
+	}
+	return ExitCodeError
+}
+
+// withExitCodes makes the errors of a command exit with the code of their
+// class.
+func withExitCodes(run func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := run(cmd, args)
+		if err == nil {
+			return nil
+		}
+		var cerr CommandError
+		if errors.As(err, &cerr) {
+			return err
+		}
+		return CommandError{error: err, ExitCode: exitCode(err)}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/action"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"other", errors.New("boom"), ExitCodeError},
+		{"invalid", fmt.Errorf("render: %w", action.ErrInvalid), ExitCodeInvalid},
+		{"validation", chart.ValidationError("chart.metadata.name is required"), ExitCodeInvalid},
+		{"release not found", fmt.Errorf("get: %w", driver.ErrReleaseNotFound), ExitCodeNotFound},
+		{"path not found", fmt.Errorf("locate: %w", fs.ErrNotExist), ExitCodeNotFound},
+		{"timeout", fmt.Errorf("wait: %w", context.DeadlineExceeded), ExitCodeTimeout},
+		{"conflict", fmt.Errorf("upgrade: %w", action.ErrConflict), ExitCodeConflict},
+		{"rolled back", &action.RolledBackError{}, ExitCodeRolledBack},
+		{"rolled back timeout", fmt.Errorf("upgrade: %w", &action.RolledBackError{}), ExitCodeRolledBack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCommandExitCodes(t *testing.T) {
+	rels := []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "funny-honey", Version: 1}),
+		release.Mock(&release.MockReleaseOptions{Name: "pending-honey", Status: release.StatusPendingUpgrade}),
+	}
+	tests := []struct {
+		name string
+		cmd  string
+		want int
+	}{
+		{"rollback to a missing revision", "rollback funny-honey 3", ExitCodeNotFound},
+		{"rollback a missing release", "rollback missing-honey 1", ExitCodeNotFound},
+		{"upgrade a pending release", "upgrade pending-honey testdata/testcharts/empty", ExitCodeConflict},
+		{"install an invalid release name", "install Funny_Honey testdata/testcharts/empty", ExitCodeInvalid},
+		{"install a missing chart", "install funny-honey ./testdata/testcharts/missing", ExitCodeNotFound},
+		{"test a missing release", "test missing-honey", ExitCodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storageFixture()
+			for _, rel := range rels {
+				if err := store.Create(rel); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, out, err := executeActionCommandC(store, tt.cmd)
+			var cerr CommandError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("expected a command error, got %v with the following output:\n%s", err, out)
+			}
+			if cerr.ExitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d: %v", tt.want, cerr.ExitCode, err)
+			}
+		})
+	}
+}
@@ -17,13 +17,16 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"log/slog"
+	"maps"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -31,12 +34,17 @@ import (
 	"k8s.io/klog/v2"
 
 	"helm.sh/helm/v4/pkg/action"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/cli/flags"
 	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cli/values"
+	"helm.sh/helm/v4/pkg/downloader"
+	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/kube"
 	"helm.sh/helm/v4/pkg/postrenderer"
+	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
@@ -47,86 +55,51 @@ const (
 )
 
 func addValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
-	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML file or a URL (can specify multiple)")
-	f.StringArrayVar(&v.Values, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
-	f.StringArrayVar(&v.StringValues, "set-string", []string{}, "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
-	f.StringArrayVar(&v.FileValues, "set-file", []string{}, "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
-	f.StringArrayVar(&v.JSONValues, "set-json", []string{}, "set JSON values on the command line (can specify multiple or separate values with commas: key1=jsonval1,key2=jsonval2 or using json format: {\"key1\": jsonval1, \"key2\": \"jsonval2\"})")
-	f.StringArrayVar(&v.LiteralValues, "set-literal", []string{}, "set a literal STRING value on the command line")
+	flags.AddValueOptionsFlags(f, v)
 }
 
 func AddWaitFlag(cmd *cobra.Command, wait *kube.WaitStrategy) {
-	cmd.Flags().Var(
-		newWaitValue(kube.HookOnlyStrategy, wait),
-		"wait",
-		"if specified, will wait until all resources are in the expected state before marking the operation as successful. It will wait for as long as --timeout. Valid inputs are 'watcher' and 'legacy'",
-	)
-	// Sets the strategy to use the watcher strategy if `--wait` is used without an argument
-	cmd.Flags().Lookup("wait").NoOptDefVal = string(kube.StatusWatcherStrategy)
+	flags.AddWaitFlag(cmd.Flags(), wait)
 }
 
-type waitValue kube.WaitStrategy
-
-func newWaitValue(defaultValue kube.WaitStrategy, ws *kube.WaitStrategy) *waitValue {
-	*ws = defaultValue
-	return (*waitValue)(ws)
-}
-
-func (ws *waitValue) String() string {
-	if ws == nil {
-		return ""
-	}
-	return string(*ws)
-}
-
-func (ws *waitValue) Set(s string) error {
-	switch s {
-	case string(kube.StatusWatcherStrategy), string(kube.LegacyStrategy):
-		*ws = waitValue(s)
-		return nil
-	case "true":
-		slog.Warn("--wait=true is deprecated (boolean value) and can be replaced with --wait=watcher")
-		*ws = waitValue(kube.StatusWatcherStrategy)
-		return nil
-	case "false":
-		slog.Warn("--wait=false is deprecated (boolean value) and can be replaced by omitting the --wait flag")
-		*ws = waitValue(kube.HookOnlyStrategy)
-		return nil
-	default:
-		return fmt.Errorf("invalid wait input %q. Valid inputs are %s, and %s", s, kube.StatusWatcherStrategy, kube.LegacyStrategy)
-	}
+func addObjectLabelsFlags(f *pflag.FlagSet, l *release.ObjectLabels) {
+	flags.AddObjectLabelsFlags(f, l)
 }
 
-func (ws *waitValue) Type() string {
-	return "WaitStrategy"
+func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
+	flags.AddChartPathOptionsFlags(f, c)
 }
 
-func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
-	f.StringVar(&c.Version, "version", "", "specify a version constraint for the chart version to use. This constraint can be a specific tag (e.g. 1.1.1) or it may reference a valid range (e.g. ^2.0.0). If this is not specified, the latest version is used")
-	f.BoolVar(&c.Verify, "verify", false, "verify the package before using it")
-	f.StringVar(&c.Keyring, "keyring", defaultKeyring(), "location of public keys used for verification")
-	f.StringVar(&c.RepoURL, "repo", "", "chart repository url where to locate the requested chart")
-	f.StringVar(&c.Username, "username", "", "chart repository username where to locate the requested chart")
-	f.StringVar(&c.Password, "password", "", "chart repository password where to locate the requested chart")
-	f.StringVar(&c.CertFile, "cert-file", "", "identify HTTPS client using this SSL certificate file")
-	f.StringVar(&c.KeyFile, "key-file", "", "identify HTTPS client using this SSL key file")
-	f.BoolVar(&c.InsecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart download")
-	f.BoolVar(&c.PlainHTTP, "plain-http", false, "use insecure HTTP connections for the chart download")
-	f.StringVar(&c.CaFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
-	f.BoolVar(&c.PassCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
+// addRetryFlags adds the flags retrying the downloads of charts.
+func addRetryFlags(f *pflag.FlagSet, retries *int, backoff *time.Duration) {
+	f.IntVar(retries, "retries", 0, "number of times a chart download failing with a network or server error is retried. Interrupted downloads from HTTP repositories resume where they stopped")
+	f.DurationVar(backoff, "retry-backoff", downloader.DefaultRetryBackoff, "time to wait before the first retry of a chart download, doubled before each of the next ones")
 }
 
 // bindOutputFlag will add the output flag to the given command and bind the
 // value to the given format pointer
 func bindOutputFlag(cmd *cobra.Command, varRef *output.Format) {
-	cmd.Flags().VarP(newOutputValue(output.Table, varRef), outputFlag, "o",
-		fmt.Sprintf("prints the output in the specified format. Allowed values: %s", strings.Join(output.Formats(), ", ")))
+	bindOutputFlagWithFormats(cmd, varRef, nil)
+}
+
+// bindOutputFlagWithFormats binds the output flag, accepting the given
+// command specific formats, with their description, on top of the standard ones.
+func bindOutputFlagWithFormats(cmd *cobra.Command, varRef *output.Format, extra map[string]string) {
+	formats := output.Formats()
+	for _, format := range slices.Sorted(maps.Keys(extra)) {
+		formats = append(formats, format)
+	}
+	cmd.Flags().VarP(newOutputValue(output.Table, varRef, extra), outputFlag, "o",
+		fmt.Sprintf("prints the output in the specified format. Allowed values: %s", strings.Join(formats, ", ")))
 
 	err := cmd.RegisterFlagCompletionFunc(outputFlag, func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		var formatNames []string
 		for format, desc := range output.FormatsWithDesc() {
 			formatNames = append(formatNames, fmt.Sprintf("%s\t%s", format, desc))
 		}
+		for format, desc := range extra {
+			formatNames = append(formatNames, fmt.Sprintf("%s\t%s", format, desc))
+		}
 
 		// Sort the results to get a deterministic order for the tests
 		sort.Strings(formatNames)
@@ -138,18 +111,18 @@ func bindOutputFlag(cmd *cobra.Command, varRef *output.Format) {
 	}
 }
 
-type outputValue output.Format
+type outputValue struct {
+	format *output.Format
+	extra  map[string]string
+}
 
-func newOutputValue(defaultValue output.Format, p *output.Format) *outputValue {
+func newOutputValue(defaultValue output.Format, p *output.Format, extra map[string]string) *outputValue {
 	*p = defaultValue
-	return (*outputValue)(p)
+	return &outputValue{format: p, extra: extra}
 }
 
 func (o *outputValue) String() string {
-	// It is much cleaner looking (and technically less allocations) to just
-	// convert to a string rather than type asserting to the underlying
-	// output.Format
-	return string(*o)
+	return string(*o.format)
 }
 
 func (o *outputValue) Type() string {
@@ -157,11 +130,15 @@ func (o *outputValue) Type() string {
 }
 
 func (o *outputValue) Set(s string) error {
+	if _, ok := o.extra[s]; ok {
+		*o.format = output.Format(s)
+		return nil
+	}
 	outfmt, err := output.ParseFormat(s)
 	if err != nil {
 		return err
 	}
-	*o = outputValue(outfmt)
+	*o.format = outfmt
 	return nil
 }
 
@@ -250,6 +227,207 @@ func (p *postRendererArgsSlice) GetSlice() []string {
 	return p.options.args
 }
 
+// bindLicensePolicyFlag adds the --license-policy flag, loading the license
+// policy file as soon as the flag is parsed.
+func bindLicensePolicyFlag(f *pflag.FlagSet, varRef **action.LicensePolicy) {
+	f.Var(&licensePolicyValue{policy: varRef}, "license-policy", "path to a license policy file. Charts with dependencies under licenses the policy does not allow are not installed")
+}
+
+type licensePolicyValue struct {
+	policy **action.LicensePolicy
+	path   string
+}
+
+func (l *licensePolicyValue) String() string {
+	return l.path
+}
+
+func (l *licensePolicyValue) Type() string {
+	return "string"
+}
+
+func (l *licensePolicyValue) Set(val string) error {
+	policy, err := action.LoadLicensePolicy(val)
+	if err != nil {
+		return err
+	}
+	l.path = val
+	*l.policy = policy
+	return nil
+}
+
+// bindAdvisoryFlags adds the --advisories and --advisory-severity flags,
+// loading the advisory feed as soon as the flag is parsed.
+func bindAdvisoryFlags(f *pflag.FlagSet, varRef **action.AdvisoryPolicy) {
+	a := &advisoryFlags{policy: varRef}
+	f.Var((*advisoryFeedValue)(a), "advisories", "URL or path of a feed of OSV security advisories. Charts affected by advisories, or with dependencies that are, are rejected")
+	f.Var((*advisorySeverityValue)(a), "advisory-severity", "lowest severity of the advisories rejecting charts, one of low, medium, high and critical. Advisories without a known severity always reject charts")
+}
+
+type advisoryFlags struct {
+	policy    **action.AdvisoryPolicy
+	source    string
+	threshold chartutil.Severity
+}
+
+type advisoryFeedValue advisoryFlags
+
+func (a *advisoryFeedValue) String() string {
+	return a.source
+}
+
+func (a *advisoryFeedValue) Type() string {
+	return "string"
+}
+
+func (a *advisoryFeedValue) Set(val string) error {
+	feed, err := action.LoadAdvisoryFeed(val, getter.All(settings))
+	if err != nil {
+		return err
+	}
+	a.source = val
+	*a.policy = &action.AdvisoryPolicy{Feed: feed, Threshold: a.threshold}
+	return nil
+}
+
+type advisorySeverityValue advisoryFlags
+
+func (a *advisorySeverityValue) String() string {
+	return a.threshold.String()
+}
+
+func (a *advisorySeverityValue) Type() string {
+	return "string"
+}
+
+func (a *advisorySeverityValue) Set(val string) error {
+	threshold, err := chartutil.ParseSeverity(val)
+	if err != nil {
+		return err
+	}
+	a.threshold = threshold
+	if *a.policy != nil {
+		(*a.policy).Threshold = threshold
+	}
+	return nil
+}
+
+// addHealthCheckFlags adds the flags checking the health of a release once it
+// is deployed.
+func addHealthCheckFlags(f *pflag.FlagSet, h *action.HealthChecks) {
+	f.Var((*healthCheckURLValue)(h), "health-check-url", "URL checked once the release is deployed. The release fails unless a GET request to it gets a 2xx response within --health-check-window. Can be specified multiple times")
+	f.BoolVar(&h.FromChart, "health-checks", false, "if set, also run the health checks declared by the chart in its helm.sh/health-checks annotation once the release is deployed")
+	f.BoolVar(&h.AllowExec, "allow-exec-health-checks", false, "if set, allow the health checks declared by the chart to run commands on this machine")
+	f.DurationVar(&h.Window, "health-check-window", 0, "time within which the health checks must pass. Defaults to the helm.sh/health-check-window annotation of the chart, or else to 1m")
+}
+
+// healthCheckGetterOptions returns the options of the requests of the HTTP
+// health checks, sent with the TLS settings of the chart download.
+func healthCheckGetterOptions(o action.ChartPathOptions) []getter.Option {
+	return []getter.Option{
+		getter.WithTLSClientConfig(o.CertFile, o.KeyFile, o.CaFile),
+		getter.WithInsecureSkipVerifyTLS(o.InsecureSkipTLSverify),
+	}
+}
+
+type healthCheckURLValue action.HealthChecks
+
+func (h *healthCheckURLValue) String() string {
+	var urls []string
+	for _, hc := range h.Checks {
+		if hc.HTTP != nil {
+			urls = append(urls, hc.HTTP.URL)
+		}
+	}
+	return "[" + strings.Join(urls, ",") + "]"
+}
+
+func (h *healthCheckURLValue) Type() string {
+	return "stringArray"
+}
+
+func (h *healthCheckURLValue) Set(val string) error {
+	if val == "" {
+		return errors.New("the URL of a health check must not be empty")
+	}
+	h.Checks = append(h.Checks, action.HealthCheck{Name: val, HTTP: &action.HTTPHealthCheck{URL: val}})
+	return nil
+}
+
+// addDiffFlags adds the flags controlling how --show-diff renders the diffs.
+func addDiffFlags(f *pflag.FlagSet, opts *action.DiffOptions, words *bool) {
+	f.IntVar(&opts.Context, "diff-context", action.DefaultDiffContext, "with --show-diff, number of unchanged lines shown around the changes")
+	f.BoolVar(words, "diff-words", false, "with --show-diff, highlight the words that changed in the changed lines, when colors are enabled")
+	f.StringArrayVar(&opts.IgnorePaths, "diff-ignore-path", nil, "with --show-diff, leave the field at this JSON pointer, such as /metadata/labels or /spec/template/spec/containers/*/image, out of the diffs. Can be specified multiple times")
+	f.Var((*diffFormatValue)(&opts.Format), "diff-output", fmt.Sprintf("with --show-diff, format of the diffs. Allowed values: %s, %s", action.DiffFormatUnified, action.DiffFormatJSONPatch))
+}
+
+type diffFormatValue action.DiffFormat
+
+func (d *diffFormatValue) String() string {
+	if *d == "" {
+		return string(action.DiffFormatUnified)
+	}
+	return string(*d)
+}
+
+func (d *diffFormatValue) Type() string {
+	return "format"
+}
+
+func (d *diffFormatValue) Set(val string) error {
+	switch format := action.DiffFormat(val); format {
+	case action.DiffFormatUnified, action.DiffFormatJSONPatch:
+		*d = diffFormatValue(format)
+		return nil
+	}
+	return fmt.Errorf("invalid diff format %q, expected %s or %s", val, action.DiffFormatUnified, action.DiffFormatJSONPatch)
+}
+
+// addIgnoreDifferencesFlag adds the flag listing the fields of the objects of a
+// release left out of its diffs.
+func addIgnoreDifferencesFlag(f *pflag.FlagSet, rules *[]release.IgnoreDifference) {
+	f.Var((*ignoreDifferencesValue)(rules), "ignore-difference", "leave a field of the objects of the release out of its diffs with their live state and with other revisions, such as a field mutated by a controller. The field is a JSON pointer or a JSONPath, optionally selecting the objects by kind and name, such as Deployment/web:/spec/replicas or Deployment:.spec.template.spec.containers[?(@.name=='istio-proxy')]. Can be specified multiple times")
+}
+
+type ignoreDifferencesValue []release.IgnoreDifference
+
+func (v *ignoreDifferencesValue) String() string {
+	var rules []string
+	for _, r := range *v {
+		rules = append(rules, strings.Join(append(r.JSONPointers, r.JSONPaths...), ","))
+	}
+	return "[" + strings.Join(rules, ",") + "]"
+}
+
+func (v *ignoreDifferencesValue) Type() string {
+	return "stringArray"
+}
+
+func (v *ignoreDifferencesValue) Set(val string) error {
+	var rule release.IgnoreDifference
+	path := val
+	// The fields start with one of these characters, and the kinds do not.
+	if !strings.HasPrefix(val, "/") && !strings.HasPrefix(val, ".") && !strings.HasPrefix(val, "$") && !strings.HasPrefix(val, "{") {
+		selector, field, ok := strings.Cut(val, ":")
+		if !ok {
+			return fmt.Errorf("invalid ignored difference %q, expected [KIND[/NAME]:]FIELD", val)
+		}
+		rule.Kind, rule.Name, _ = strings.Cut(selector, "/")
+		path = field
+	}
+	switch {
+	case strings.HasPrefix(path, "/"):
+		rule.JSONPointers = []string{path}
+	case path != "":
+		rule.JSONPaths = []string{path}
+	default:
+		return fmt.Errorf("invalid ignored difference %q: missing field", val)
+	}
+	*v = append(*v, rule)
+	return nil
+}
+
 func compVersionFlag(chartRef string, _ string) ([]string, cobra.ShellCompDirective) {
 	chartInfo := strings.Split(chartRef, "/")
 	if len(chartInfo) != 2 {
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 
 	"helm.sh/helm/v4/pkg/action"
@@ -29,6 +30,11 @@ import (
 )
 
 func outputFlagCompletionTest(t *testing.T, cmdName string) {
+	t.Helper()
+	outputFlagCompletionTestWithGolden(t, cmdName, "output/output-comp.txt")
+}
+
+func outputFlagCompletionTestWithGolden(t *testing.T, cmdName, golden string) {
 	t.Helper()
 	releasesMockWithStatus := func(info *release.Info, hooks ...*release.Hook) []*release.Release {
 		info.LastDeployed = time.Unix(1452902400, 0).UTC()
@@ -62,35 +68,35 @@ func outputFlagCompletionTest(t *testing.T, cmdName string) {
 	tests := []cmdTestCase{{
 		name:   "completion for output flag long and before arg",
 		cmd:    fmt.Sprintf("__complete %s --output ''", cmdName),
-		golden: "output/output-comp.txt",
+		golden: golden,
 		rels: releasesMockWithStatus(&release.Info{
 			Status: release.StatusDeployed,
 		}),
 	}, {
 		name:   "completion for output flag long and after arg",
 		cmd:    fmt.Sprintf("__complete %s aramis --output ''", cmdName),
-		golden: "output/output-comp.txt",
+		golden: golden,
 		rels: releasesMockWithStatus(&release.Info{
 			Status: release.StatusDeployed,
 		}),
 	}, {
 		name:   "completion for output flag short and before arg",
 		cmd:    fmt.Sprintf("__complete %s -o ''", cmdName),
-		golden: "output/output-comp.txt",
+		golden: golden,
 		rels: releasesMockWithStatus(&release.Info{
 			Status: release.StatusDeployed,
 		}),
 	}, {
 		name:   "completion for output flag short and after arg",
 		cmd:    fmt.Sprintf("__complete %s aramis -o ''", cmdName),
-		golden: "output/output-comp.txt",
+		golden: golden,
 		rels: releasesMockWithStatus(&release.Info{
 			Status: release.StatusDeployed,
 		}),
 	}, {
 		name:   "completion for output flag, no filter",
 		cmd:    fmt.Sprintf("__complete %s --output jso", cmdName),
-		golden: "output/output-comp.txt",
+		golden: golden,
 		rels: releasesMockWithStatus(&release.Info{
 			Status: release.StatusDeployed,
 		}),
@@ -120,3 +126,43 @@ func TestPostRendererFlagSetOnce(t *testing.T) {
 	err = str.Set("cat")
 	require.Error(t, err)
 }
+
+func TestHealthCheckFlags(t *testing.T) {
+	var h action.HealthChecks
+	f := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	addHealthCheckFlags(f, &h)
+	require.NoError(t, f.Parse([]string{
+		"--health-check-url", "http://api.example.com/healthz",
+		"--health-check-url", "http://web.example.com/",
+		"--health-check-window", "2m",
+		"--health-checks",
+	}))
+	require.Equal(t, action.HealthChecks{
+		Checks: []action.HealthCheck{
+			{Name: "http://api.example.com/healthz", HTTP: &action.HTTPHealthCheck{URL: "http://api.example.com/healthz"}},
+			{Name: "http://web.example.com/", HTTP: &action.HTTPHealthCheck{URL: "http://web.example.com/"}},
+		},
+		FromChart: true,
+		Window:    2 * time.Minute,
+	}, h)
+	require.Equal(t, "[http://api.example.com/healthz,http://web.example.com/]", f.Lookup("health-check-url").Value.String())
+	require.Error(t, f.Set("health-check-url", ""))
+}
+
+func TestIgnoreDifferencesFlag(t *testing.T) {
+	var rules []release.IgnoreDifference
+	f := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	addIgnoreDifferencesFlag(f, &rules)
+	require.NoError(t, f.Parse([]string{
+		"--ignore-difference", "Deployment/web:/spec/replicas",
+		"--ignore-difference", "Deployment:.spec.template.spec.containers[?(@.name=='istio-proxy')]",
+		"--ignore-difference", "/metadata/annotations/checksum~1config",
+	}))
+	require.Equal(t, []release.IgnoreDifference{
+		{Kind: "Deployment", Name: "web", JSONPointers: []string{"/spec/replicas"}},
+		{Kind: "Deployment", JSONPaths: []string{".spec.template.spec.containers[?(@.name=='istio-proxy')]"}},
+		{JSONPointers: []string{"/metadata/annotations/checksum~1config"}},
+	}, rules)
+	require.Error(t, f.Set("ignore-difference", "Deployment"))
+	require.Error(t, f.Set("ignore-difference", "Deployment:"))
+}
@@ -24,16 +24,28 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
 const getHooksHelp = `
 This command downloads hooks for a given release.
 
 Hooks are formatted in YAML and separated by the YAML '---\n' separator.
+
+With '--output json' or '--output yaml' the hooks are printed together with the
+outcome of their last execution: when it started and completed, whether it
+succeeded, why it failed, the exit code of the pods it ran and how many times
+they were retried.
 `
 
+type hooksWriter struct {
+	hooks []*release.Hook
+}
+
 func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	var outfmt output.Format
 	client := action.NewGet(cfg)
 
 	cmd := &cobra.Command{
@@ -52,10 +64,7 @@ func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			for _, hook := range res.Hooks {
-				fmt.Fprintf(out, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
-			}
-			return nil
+			return outfmt.Write(out, &hooksWriter{res.Hooks})
 		},
 	}
 
@@ -71,5 +80,22 @@ func newGetHooksCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		log.Fatal(err)
 	}
 
+	bindOutputFlag(cmd, &outfmt)
+
 	return cmd
 }
+
+func (h hooksWriter) WriteTable(out io.Writer) error {
+	for _, hook := range h.hooks {
+		fmt.Fprintf(out, "---\n# Source: %s\n%s\n", hook.Path, hook.Manifest)
+	}
+	return nil
+}
+
+func (h hooksWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, h.hooks)
+}
+
+func (h hooksWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, h.hooks)
+}
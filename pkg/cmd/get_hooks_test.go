@@ -18,11 +18,24 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
 
 func TestGetHooks(t *testing.T) {
+	failed := release.Mock(&release.MockReleaseOptions{Name: "aeneas"})
+	exitCode := int32(1)
+	failed.Hooks[0].LastRun = release.HookExecution{
+		StartedAt:   time.Date(1977, 9, 2, 22, 4, 5, 0, time.UTC),
+		CompletedAt: time.Date(1977, 9, 2, 22, 5, 35, 0, time.UTC),
+		Phase:       release.HookPhaseFailed,
+		Event:       release.HookPreInstall,
+		Message:     "job failed: BackoffLimitExceeded",
+		ExitCode:    &exitCode,
+		Retries:     6,
+	}
+
 	tests := []cmdTestCase{{
 		name:   "get hooks with release",
 		cmd:    "get hooks aeneas",
@@ -33,6 +46,21 @@ func TestGetHooks(t *testing.T) {
 		cmd:       "get hooks",
 		golden:    "output/get-hooks-no-args.txt",
 		wantError: true,
+	}, {
+		name:   "get hooks with failed hook in table format",
+		cmd:    "get hooks aeneas",
+		golden: "output/get-hooks.txt",
+		rels:   []*release.Release{failed},
+	}, {
+		name:   "get hooks with last run in json format",
+		cmd:    "get hooks aeneas --output json",
+		golden: "output/get-hooks.json",
+		rels:   []*release.Release{failed},
+	}, {
+		name:   "get hooks with last run in yaml format",
+		cmd:    "get hooks aeneas --output yaml",
+		golden: "output/get-hooks.yaml",
+		rels:   []*release.Release{failed},
 	}}
 	runTestCmd(t, tests)
 }
@@ -45,6 +73,10 @@ func TestGetHooksRevisionCompletion(t *testing.T) {
 	revisionFlagCompletionTest(t, "get hooks")
 }
 
+func TestGetHooksOutputCompletion(t *testing.T) {
+	outputFlagCompletionTest(t, "get hooks")
+}
+
 func TestGetHooksFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "get hooks", false)
 	checkFileCompletion(t, "get hooks myrelease", false)
@@ -20,11 +20,13 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
+	coloroutput "helm.sh/helm/v4/internal/cli/output"
 	"helm.sh/helm/v4/pkg/action"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/cli/output"
@@ -47,8 +49,19 @@ The historical release set is printed as a formatted table, e.g:
     2           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Upgraded successfully
     3           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Rolled back to 2
     4           Mon Oct 3 10:15:13 2016     deployed        alpine-0.1.0      1.0             Upgraded successfully
+
+Use '--output timeline' to show for how long each revision was deployed, who
+deployed it and how its status changed, and '--output dot' to export the history
+as a Graphviz graph linking rollbacks to the revision they restored:
+
+    $ helm history angry-bird --output dot | dot -Tsvg > angry-bird.svg
 `
 
+const (
+	timelineFormat = "timeline"
+	dotFormat      = "dot"
+)
+
 func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewHistory(cfg)
 	var outfmt output.Format
@@ -71,13 +84,22 @@ func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				return err
 			}
 
-			return outfmt.Write(out, history)
+			switch outfmt {
+			case timelineFormat:
+				return writeHistoryTimeline(out, history)
+			case dotFormat:
+				return writeHistoryDOT(out, args[0], history)
+			}
+			return outfmt.Write(out, historyWriter{history, settings.ShouldDisableColor()})
 		},
 	}
 
 	f := cmd.Flags()
 	f.IntVar(&client.Max, "max", 256, "maximum number of revision to include in history")
-	bindOutputFlag(cmd, &outfmt)
+	bindOutputFlagWithFormats(cmd, &outfmt, map[string]string{
+		timelineFormat: "Output the revisions as a timeline with durations, actors and status changes",
+		dotFormat:      "Output the revisions as a Graphviz DOT graph",
+	})
 
 	return cmd
 }
@@ -89,6 +111,7 @@ type releaseInfo struct {
 	Chart       string    `json:"chart"`
 	AppVersion  string    `json:"app_version"`
 	Description string    `json:"description"`
+	DeployedBy  string    `json:"deployed_by,omitempty"`
 }
 
 type releaseHistory []releaseInfo
@@ -101,11 +124,25 @@ func (r releaseHistory) WriteYAML(out io.Writer) error {
 	return output.EncodeYAML(out, r)
 }
 
-func (r releaseHistory) WriteTable(out io.Writer) error {
+// historyWriter writes the release history, colorizing the table output.
+type historyWriter struct {
+	releaseHistory
+	noColor bool
+}
+
+func (w historyWriter) WriteTable(out io.Writer) error {
 	tbl := uitable.New()
-	tbl.AddRow("REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION", "DESCRIPTION")
-	for _, item := range r {
-		tbl.AddRow(item.Revision, item.Updated.Format(time.ANSIC), item.Status, item.Chart, item.AppVersion, item.Description)
+	tbl.AddRow(
+		coloroutput.ColorizeHeader("REVISION", w.noColor),
+		coloroutput.ColorizeHeader("UPDATED", w.noColor),
+		coloroutput.ColorizeHeader("STATUS", w.noColor),
+		coloroutput.ColorizeHeader("CHART", w.noColor),
+		coloroutput.ColorizeHeader("APP VERSION", w.noColor),
+		coloroutput.ColorizeHeader("DESCRIPTION", w.noColor),
+	)
+	for _, item := range w.releaseHistory {
+		status := coloroutput.ColorizeStatus(release.Status(item.Status), w.noColor)
+		tbl.AddRow(item.Revision, item.Updated.Format(time.ANSIC), status, item.Chart, item.AppVersion, item.Description)
 	}
 	return output.EncodeTable(out, tbl)
 }
@@ -147,6 +184,7 @@ func getReleaseHistory(rls []*release.Release) (history releaseHistory) {
 			Chart:       c,
 			AppVersion:  a,
 			Description: d,
+			DeployedBy:  r.Info.DeployedBy,
 		}
 		if !r.Info.LastDeployed.IsZero() {
 			rInfo.Updated = r.Info.LastDeployed
@@ -158,6 +196,95 @@ func getReleaseHistory(rls []*release.Release) (history releaseHistory) {
 	return history
 }
 
+// writeHistoryTimeline writes the revisions, oldest first, with how long each
+// revision was the latest one, who deployed it and how its status changed.
+func writeHistoryTimeline(out io.Writer, history releaseHistory) error {
+	tbl := uitable.New()
+	tbl.AddRow("DEPLOYED", "REVISION", "DURATION", "ACTOR", "STATUS", "CHART", "DESCRIPTION")
+	for i, item := range history {
+		duration := "-"
+		if i+1 < len(history) && !item.Updated.IsZero() && !history[i+1].Updated.IsZero() {
+			duration = history[i+1].Updated.Sub(item.Updated).Round(time.Second).String()
+		}
+		actor := item.DeployedBy
+		if actor == "" {
+			actor = "-"
+		}
+		tbl.AddRow(item.Updated.Format(time.ANSIC), item.Revision, duration, actor, statusTransition(item.Status), item.Chart, item.Description)
+	}
+	return output.EncodeTable(out, tbl)
+}
+
+// statusTransition describes how a revision got to its current status.
+// Revisions that were replaced or uninstalled had been deployed first.
+func statusTransition(status string) string {
+	switch release.Status(status) {
+	case release.StatusSuperseded, release.StatusUninstalled, release.StatusUninstalling:
+		return fmt.Sprintf("%s -> %s", release.StatusDeployed, status)
+	}
+	return status
+}
+
+// writeHistoryDOT writes the revisions as a Graphviz graph. Each revision
+// points to the next one, and rollbacks are linked to the revision they
+// restored with a dashed edge, unless that revision is not in the history,
+// such as when it is older than the revisions of --max.
+func writeHistoryDOT(out io.Writer, name string, history releaseHistory) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", strconv.Quote(name))
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box, style=rounded];\n")
+	for _, item := range history {
+		label := fmt.Sprintf("revision %d\n%s\n%s", item.Revision, item.Chart, item.Status)
+		if item.DeployedBy != "" {
+			label += "\nby " + item.DeployedBy
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s, color=%s];\n", dotNode(item.Revision), strconv.Quote(label), dotColor(item.Status))
+	}
+	for i := 1; i < len(history); i++ {
+		fmt.Fprintf(&b, "\t%s -> %s;\n", dotNode(history[i-1].Revision), dotNode(history[i].Revision))
+	}
+	revisions := make(map[int]bool, len(history))
+	for _, item := range history {
+		revisions[item.Revision] = true
+	}
+	for _, item := range history {
+		if source, ok := rollbackSource(item.Description); ok && revisions[source] {
+			fmt.Fprintf(&b, "\t%s -> %s [style=dashed, label=\"rollback\"];\n", dotNode(source), dotNode(item.Revision))
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func dotNode(revision int) string {
+	return fmt.Sprintf("r%d", revision)
+}
+
+func dotColor(status string) string {
+	switch release.Status(status) {
+	case release.StatusDeployed:
+		return "green"
+	case release.StatusFailed, release.StatusUnknown:
+		return "red"
+	case release.StatusPendingInstall, release.StatusPendingUpgrade, release.StatusPendingRollback, release.StatusUninstalling:
+		return "orange"
+	}
+	return "gray"
+}
+
+// rollbackSource returns the revision restored by a rollback, parsed from the
+// description the rollback action gives the revisions it creates.
+func rollbackSource(description string) (int, bool) {
+	v, ok := strings.CutPrefix(description, "Rollback to ")
+	if !ok {
+		return 0, false
+	}
+	revision, err := strconv.Atoi(v)
+	return revision, err == nil
+}
+
 func formatChartName(c *chart.Chart) string {
 	if c == nil || c.Metadata == nil {
 		// This is an edge case that has happened in prod, though we don't
@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -70,8 +71,52 @@ func TestHistoryCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestHistoryTimelineAndDOT(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+	mk := func(vers int, status release.Status, after time.Duration, actor, description string) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{
+			Name:    "angry-bird",
+			Version: vers,
+			Status:  status,
+		})
+		rel.Info.LastDeployed = start.Add(after)
+		rel.Info.DeployedBy = actor
+		rel.Info.Description = description
+		return rel
+	}
+	rels := []*release.Release{
+		mk(4, release.StatusDeployed, 26*time.Hour, "alice", "Rollback to 2"),
+		mk(3, release.StatusFailed, 25*time.Hour+30*time.Minute, "bob", "Upgrade \"angry-bird\" failed"),
+		mk(2, release.StatusSuperseded, 2*time.Hour, "alice", "Upgrade complete"),
+		mk(1, release.StatusSuperseded, 0, "", "Install complete"),
+	}
+
+	tests := []cmdTestCase{{
+		name:   "get history as a timeline",
+		cmd:    "history angry-bird --output timeline",
+		rels:   rels,
+		golden: "output/history-timeline.txt",
+	}, {
+		name:   "get history as a DOT graph",
+		cmd:    "history angry-bird -o dot",
+		rels:   rels,
+		golden: "output/history.dot",
+	}, {
+		name:   "get history as a DOT graph without the revision restored by a rollback",
+		cmd:    "history angry-bird -o dot --max 2",
+		rels:   rels,
+		golden: "output/history-max.dot",
+	}, {
+		name:      "invalid output format",
+		cmd:       "history angry-bird -o graph",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestHistoryOutputCompletion(t *testing.T) {
-	outputFlagCompletionTest(t, "history")
+	outputFlagCompletionTestWithGolden(t, "history", "output/history-output-comp.txt")
 }
 
 func revisionFlagCompletionTest(t *testing.T, cmdName string) {
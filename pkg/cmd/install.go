@@ -38,7 +38,6 @@ import (
 	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
-	"helm.sh/helm/v4/pkg/downloader"
 	"helm.sh/helm/v4/pkg/getter"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -99,12 +98,32 @@ And in the following example, 'foo' is set to '{"key1":"value1","key2":"bar"}':
     $ helm install --set-json='foo={"key1":"value1","key2":"value2"}' --set-json='foo.key2="bar"' myredis ./redis
 
 To check the generated manifests of a release without installing the chart,
-the --debug and --dry-run flags can be combined.
+the --debug and --dry-run flags can be combined. The output then also lists
+where each value comes from: a values file, a --set flag, the values a parent
+chart sets for its dependency, a dependency values file, or the defaults of a
+chart.
 
 The --dry-run flag will output all generated chart manifests, including Secrets
 which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
 
+The --license-policy flag blocks installing charts when the chart or one of its
+dependencies is under a license the policy does not allow. 'helm show licenses'
+lists the licenses of a chart and its dependencies. A policy file lists SPDX
+license identifiers:
+
+    allowed: [Apache-2.0, MIT, BSD-3-Clause]  # when set, only these are allowed
+    denied: [AGPL-3.0]
+    requireLicense: true                      # block charts without a license
+
+The --advisories flag blocks installing charts when the chart or one of its
+dependencies is affected by a security advisory of an OSV feed, given as a URL
+or a file. Advisories match the name and the version of charts, and those with
+a severity below --advisory-severity are ignored:
+
+    $ helm install --advisories https://example.com/helm-advisories.json \
+        --advisory-severity high myredis ./redis
+
 If --verify is set, the chart MUST have a provenance file, and the provenance
 file MUST pass all verification steps.
 
@@ -139,12 +158,12 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "install [NAME] [CHART]",
 		Short: "install a chart",
-		Long:  installDesc,
+		Long:  installDesc + exitCodesHelp,
 		Args:  require.MinimumNArgs(1),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compInstall(args, toComplete, client)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: withExitCodes(func(_ *cobra.Command, args []string) error {
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
 				client.InsecureSkipTLSverify, client.PlainHTTP, client.Username, client.Password)
 			if err != nil {
@@ -170,7 +189,7 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				hideNotes:    client.HideNotes,
 				noColor:      settings.ShouldDisableColor(),
 			})
-		},
+		}),
 	}
 
 	addInstallFlags(cmd, cmd.Flags(), client, valueOpts)
@@ -199,24 +218,36 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.BoolVar(&client.ForceConflicts, "force-conflicts", false, "if set server-side apply will force changes against conflicts")
 	f.BoolVar(&client.ServerSideApply, "server-side", true, "object updates run in the server instead of the client")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "prevent hooks from running during install")
-	f.BoolVar(&client.Replace, "replace", false, "reuse the given name, only if that name is a deleted release which remains in the history. This is unsafe in production")
+	f.BoolVar(&client.Replace, "replace", false, "reuse the given name, only if that name is a deleted release which remains in the history. The new install continues the archived history of the release. This is unsafe in production")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVarP(&client.GenerateName, "generate-name", "g", false, "generate the name (and omit the NAME parameter)")
 	f.StringVar(&client.NameTemplate, "name-template", "", "specify template used to name the release")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
-	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
+	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing or out of date with Chart.lock before installing the chart")
 	f.BoolVar(&client.DisableOpenAPIValidation, "disable-openapi-validation", false, "if set, the installation process will not validate rendered templates against the Kubernetes OpenAPI Schema")
 	f.BoolVar(&client.RollbackOnFailure, "rollback-on-failure", false, "if set, Helm will rollback (uninstall) the installation upon failure. The --wait flag will be default to \"watcher\" if --rollback-on-failure is set")
 	f.MarkDeprecated("atomic", "use --rollback-on-failure instead")
 	f.BoolVar(&client.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed. By default, CRDs are installed if not already present")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
+	f.BoolVar(&client.SkipSetValidation, "skip-set-validation", false, "if set, do not check that the values set with --set and related flags are in the default values or the values schema of the chart")
+	f.BoolVar(&client.StrictSetValidation, "strict-set-validation", false, "if set, fail instead of warning when the values set with --set and related flags are neither in the default values nor in the values schema of the chart")
+	f.BoolVar(&client.IgnoreChartConflicts, "ignore-chart-conflicts", false, "if set, install the chart even though it conflicts with charts deployed in the cluster")
+	f.BoolVar(&client.SkipInstallConstraints, "skip-install-constraints", false, "if set, do not check the constraints the chart annotations declare on its releases, such as their namespace, their name, or being the only release of the chart")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be divided by comma.")
+	addObjectLabelsFlags(f, &client.ObjectLabels)
+	addIgnoreDifferencesFlag(f, &client.IgnoreDifferences)
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.ConfigChecksums, "config-checksums", false, "if set, annotate the pod templates of workloads with the checksum of the ConfigMaps and Secrets of the release they reference, so that they roll out when their configuration changes")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in install output. Does not affect presence in chart metadata")
 	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, install will ignore the check for helm annotations and take ownership of the existing resources")
+	f.BoolVar(&client.CheckPermissions, "check-permissions", false, "if set, check that you are allowed to create all the resources of the release before installing it, and list all the missing permissions")
+	f.BoolVar(&client.CheckQuota, "check-quota", false, "if set, estimate the CPU, memory, storage and objects the release uses and fail before installing it if they do not fit in the resource quotas of its namespace")
+	bindLicensePolicyFlag(f, &client.LicensePolicy)
+	bindAdvisoryFlags(f, &client.AdvisoryPolicy)
+	addHealthCheckFlags(f, &client.HealthChecks)
 	addValueOptionsFlags(f, valueOpts)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	AddWaitFlag(cmd, &client.WaitStrategy)
@@ -259,13 +290,20 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 	slog.Debug("Chart path", "path", cp)
 
 	p := getter.All(settings)
-	vals, err := valueOpts.MergeValues(p)
+	vals, sources, err := valueOpts.MergeValuesWithSources(p)
 	if err != nil {
 		return nil, err
 	}
-
-	// Check chart dependencies to make sure all are present in /charts
-	chartRequested, err := loader.Load(cp)
+	client.ValuesSources = sources
+
+	// Check chart dependencies to make sure all are present in /charts,
+	// building them first when requested.
+	var chartRequested chart.Charter
+	if client.DependencyUpdate {
+		chartRequested, err = action.UpdateDependencies(newDependencyManager(out, cp, client.Keyring, p, client.GetRegistryClient()))
+	} else {
+		chartRequested, err = loader.Load(cp)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -288,33 +326,12 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 		// As of Helm 2.4.0, this is treated as a stopping condition:
 		// https://github.com/helm/helm/issues/2209
 		if err := action.CheckDependencies(chartRequested, req); err != nil {
-			if client.DependencyUpdate {
-				man := &downloader.Manager{
-					Out:              out,
-					ChartPath:        cp,
-					Keyring:          client.Keyring,
-					SkipUpdate:       false,
-					Getters:          p,
-					RepositoryConfig: settings.RepositoryConfig,
-					RepositoryCache:  settings.RepositoryCache,
-					ContentCache:     settings.ContentCache,
-					Debug:            settings.Debug,
-					RegistryClient:   client.GetRegistryClient(),
-				}
-				if err := man.Update(); err != nil {
-					return nil, err
-				}
-				// Reload the chart with the updated Chart.lock file.
-				if chartRequested, err = loader.Load(cp); err != nil {
-					return nil, fmt.Errorf("failed reloading chart after repo update: %w", err)
-				}
-			} else {
-				return nil, fmt.Errorf("an error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies: %w", err)
-			}
+			return nil, fmt.Errorf("an error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies: %w", err)
 		}
 	}
 
 	client.Namespace = settings.Namespace()
+	client.HealthChecks.GetterOptions = healthCheckGetterOptions(client.ChartPathOptions)
 
 	// Validate DryRunOption member is one of the allowed values
 	if err := validateDryRunOptionFlag(client.DryRunOption); err != nil {
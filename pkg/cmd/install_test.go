@@ -84,6 +84,13 @@ func TestInstall(t *testing.T) {
 			golden: "output/install-with-multiple-values-files.txt",
 		},
 		// Install, no charts
+		// Install, values set at paths the chart does not know
+		{
+			name:      "install with an unknown value path",
+			cmd:       "install virgil testdata/testcharts/alpine --set nmae=bar --strict-set-validation",
+			golden:    "output/install-unknown-set-path.txt",
+			wantError: true,
+		},
 		{
 			name:      "install with no chart specified",
 			cmd:       "install",
@@ -263,6 +270,28 @@ func TestInstall(t *testing.T) {
 			cmd:    "install secrets testdata/testcharts/chart-with-secret --dry-run",
 			golden: "output/install-dry-run-with-secret.txt",
 		},
+		{
+			name:   "dry-run with debug reporting the provenance of values",
+			cmd:    "install virgil testdata/testcharts/alpine --dry-run --debug -f testdata/testcharts/alpine/extra_values.yaml --set test.Name=from-set,image=alpine:3",
+			golden: "output/install-dry-run-values-provenance.txt",
+		},
+		{
+			name:      "install with a license policy denying a dependency",
+			cmd:       "install licensed testdata/testcharts/chart-with-licenses --license-policy testdata/license-policy.yaml",
+			wantError: true,
+			golden:    "output/install-license-policy.txt",
+		},
+		{
+			name:      "install a chart with a dependency affected by a security advisory",
+			cmd:       "install advised testdata/testcharts/chart-with-advisories --advisories testdata/advisories.json --advisory-severity high",
+			wantError: true,
+			golden:    "output/install-advisories.txt",
+		},
+		{
+			name:   "install a chart with security advisories below the severity",
+			cmd:    "install advised testdata/testcharts/chart-with-advisories --advisories testdata/advisories.json --advisory-severity critical",
+			golden: "output/install-advisories-below-severity.txt",
+		},
 		{
 			name:   "dry-run hiding secret",
 			cmd:    "install secrets testdata/testcharts/chart-with-secret --dry-run --hide-secret",
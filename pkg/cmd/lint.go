@@ -22,15 +22,20 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
+	coloroutput "helm.sh/helm/v4/internal/cli/output"
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/v2/lint/rules"
 	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/engine"
 	"helm.sh/helm/v4/pkg/getter"
 )
 
@@ -41,12 +46,55 @@ the chart is well-formed.
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+To check a chart against several Kubernetes versions at once, pass them with
+'--kube-versions'. The chart is linted once per version, including the
+capabilities and deprecated API checks for that version, and a compatibility
+matrix summarizing the result for each chart and version is printed, with the
+number of errors and warnings of that version. APIs no longer served by a
+version are errors for that version.
+
+The templates are also checked for common templating mistakes: indent applied
+to output that does not start a line (use nindent), toYaml of null values,
+labels, annotations and environment variables rendered as booleans or numbers
+and ports rendered as strings, and trim markers joining the output of an action
+to the line before or after it. Each of these checks can be turned off with
+'--disable-check', naming one of indent, toyaml-nil, quoting or chomp.
+
+With '--advisories', set to the URL or the path of a feed of OSV security
+advisories, the linter emits an [ERROR] message for each advisory affecting the
+chart or one of its dependencies, matching their names and versions. Advisories
+with a severity below '--advisory-severity' are ignored.
+
+With '--coverage', the templates are also rendered with the values of the
+lint and with each values set of the chart (the ci/*-values.yaml files used
+by chart testing), and a coverage report lists the template files, named
+templates and branches of if, with and range actions that none of them
+rendered.
 `
 
+// lintCounts returns the number of errors and warnings of a lint result.
+func lintCounts(result *action.LintResult) (errs, warnings int) {
+	if len(result.Messages) == 0 {
+		return len(result.Errors), 0
+	}
+	for _, msg := range result.Messages {
+		switch msg.Severity {
+		case support.ErrorSev:
+			errs++
+		case support.WarningSev:
+			warnings++
+		}
+	}
+	return errs, warnings
+}
+
 func newLintCmd(out io.Writer) *cobra.Command {
 	client := action.NewLint()
 	valueOpts := &values.Options{}
 	var kubeVersion string
+	var kubeVersions []string
+	var dependencyUpdate bool
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
@@ -56,6 +104,12 @@ func newLintCmd(out io.Writer) *cobra.Command {
 		RunE: func(_ *cobra.Command, args []string) error {
 			paths := args
 
+			for _, check := range client.DisabledChecks {
+				if !slices.Contains(rules.TemplateCheckNames, check) {
+					return fmt.Errorf("unknown template check %q: the checks are %s", check, strings.Join(rules.TemplateCheckNames, ", "))
+				}
+			}
+
 			if kubeVersion != "" {
 				parsedKubeVersion, err := common.ParseKubeVersion(kubeVersion)
 				if err != nil {
@@ -64,6 +118,41 @@ func newLintCmd(out io.Writer) *cobra.Command {
 				client.KubeVersion = parsedKubeVersion
 			}
 
+			// Each chart is linted once per Kubernetes version. Without
+			// --kube-versions this is the single version given by --kube-version.
+			versions := []*common.KubeVersion{client.KubeVersion}
+			if len(kubeVersions) > 0 {
+				if kubeVersion != "" {
+					return errors.New("--kube-version and --kube-versions cannot be used together")
+				}
+				// A version of the matrix no longer serving an API of the
+				// chart fails, rather than warns about the deprecation.
+				client.FailOnRemovedAPIs = true
+				versions = versions[:0]
+				for _, v := range kubeVersions {
+					parsedKubeVersion, err := common.ParseKubeVersion(v)
+					if err != nil {
+						return fmt.Errorf("invalid kube version '%s': %s", v, err)
+					}
+					versions = append(versions, parsedKubeVersion)
+				}
+			}
+
+			if dependencyUpdate {
+				registryClient, err := newRegistryClient("", "", "", false, false, "", "")
+				if err != nil {
+					return fmt.Errorf("missing registry client: %w", err)
+				}
+				for _, p := range paths {
+					if strings.HasSuffix(p, ".tgz") || strings.HasSuffix(p, ".tar.gz") {
+						continue
+					}
+					if _, err := action.UpdateDependencies(newDependencyManager(out, p, defaultKeyring(), getter.All(settings), registryClient)); err != nil {
+						return err
+					}
+				}
+			}
+
 			if client.WithSubcharts {
 				for _, p := range paths {
 					filepath.Walk(filepath.Join(p, "charts"), func(path string, info os.FileInfo, _ error) error {
@@ -89,45 +178,86 @@ func newLintCmd(out io.Writer) *cobra.Command {
 			failed := 0
 			errorsOrWarnings := 0
 
+			compatibility := uitable.New()
+			if len(kubeVersions) > 0 {
+				header := []interface{}{"CHART"}
+				for _, v := range versions {
+					header = append(header, v.String())
+				}
+				compatibility.AddRow(header...)
+			}
+
 			for _, path := range paths {
-				result := client.Run([]string{path}, vals)
+				chartFailed := false
+				row := []interface{}{path}
+				for _, kv := range versions {
+					client.KubeVersion = kv
+					result := client.Run([]string{path}, vals)
 
-				// If there is no errors/warnings and quiet flag is set
-				// go to the next chart
-				hasWarningsOrErrors := action.HasWarningsOrErrors(result)
-				if hasWarningsOrErrors {
-					errorsOrWarnings++
-				}
-				if client.Quiet && !hasWarningsOrErrors {
-					continue
-				}
+					// If there is no errors/warnings and quiet flag is set
+					// go to the next chart
+					hasWarningsOrErrors := action.HasWarningsOrErrors(result)
+					if hasWarningsOrErrors {
+						errorsOrWarnings++
+					}
+					// The counts are those of the lint against this version
+					// only, not of all the versions so far.
+					status := "OK"
+					switch {
+					case len(result.Errors) != 0:
+						chartFailed = true
+						status = "FAILED"
+					case hasWarningsOrErrors:
+						status = "WARNINGS"
+					}
+					cell := coloroutput.ColorizeLintResult(status, settings.ShouldDisableColor())
+					if errs, warnings := lintCounts(result); errs+warnings > 0 {
+						cell += fmt.Sprintf(" (%d error(s), %d warning(s))", errs, warnings)
+					}
+					row = append(row, cell)
+					if client.Quiet && !hasWarningsOrErrors && len(result.Coverage) == 0 {
+						continue
+					}
+
+					if len(kubeVersions) > 0 {
+						fmt.Fprintf(&message, "==> Linting %s (Kubernetes %s)\n", path, kv)
+					} else {
+						fmt.Fprintf(&message, "==> Linting %s\n", path)
+					}
 
-				fmt.Fprintf(&message, "==> Linting %s\n", path)
+					// All the Errors that are generated by a chart
+					// that failed a lint will be included in the
+					// results.Messages so we only need to print
+					// the Errors if there are no Messages.
+					if len(result.Messages) == 0 {
+						for _, err := range result.Errors {
+							fmt.Fprintf(&message, "Error %s\n", err)
+						}
+					}
 
-				// All the Errors that are generated by a chart
-				// that failed a lint will be included in the
-				// results.Messages so we only need to print
-				// the Errors if there are no Messages.
-				if len(result.Messages) == 0 {
-					for _, err := range result.Errors {
-						fmt.Fprintf(&message, "Error %s\n", err)
+					for _, msg := range result.Messages {
+						if !client.Quiet || msg.Severity > support.InfoSev {
+							fmt.Fprintf(&message, "%s\n", coloroutput.ColorizeLintMessage(msg, settings.ShouldDisableColor()))
+						}
 					}
-				}
 
-				for _, msg := range result.Messages {
-					if !client.Quiet || msg.Severity > support.InfoSev {
-						fmt.Fprintf(&message, "%s\n", msg)
+					for _, coverage := range result.Coverage {
+						writeLintCoverage(&message, coverage)
 					}
-				}
 
-				if len(result.Errors) != 0 {
+					// Adding extra new line here to break up the
+					// results, stops this from being a big wall of
+					// text and makes it easier to follow.
+					fmt.Fprint(&message, "\n")
+				}
+				if chartFailed {
 					failed++
 				}
+				compatibility.AddRow(row...)
+			}
 
-				// Adding extra new line here to break up the
-				// results, stops this from being a big wall of
-				// text and makes it easier to follow.
-				fmt.Fprint(&message, "\n")
+			if len(kubeVersions) > 0 {
+				fmt.Fprintf(&message, "COMPATIBILITY MATRIX:\n%s\n\n", compatibility.String())
 			}
 
 			fmt.Fprint(out, message.String())
@@ -148,8 +278,81 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.WithSubcharts, "with-subcharts", false, "lint dependent charts")
 	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
+	f.BoolVar(&dependencyUpdate, "dependency-update", false, "update dependencies if they are missing or out of date with Chart.lock before linting the chart")
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringSliceVar(&client.DisabledChecks, "disable-check", []string{}, "turn off a template check, one of indent, toyaml-nil, quoting and chomp (can specify multiple or separate values with commas: indent,chomp)")
+	bindAdvisoryFlags(f, &client.AdvisoryPolicy)
+	f.BoolVar(&client.Coverage, "coverage", false, "report the template files, named templates and branches that neither the values of the lint nor the ci/*-values.yaml values sets of the chart render")
+	f.StringSliceVar(&kubeVersions, "kube-versions", []string{}, "lint against each of the given Kubernetes versions and print a compatibility matrix, failing the versions that no longer serve an API of the chart (e.g. 1.27,1.29,1.31)")
 	addValueOptionsFlags(f, valueOpts)
 
 	return cmd
 }
+
+// writeLintCoverage writes the template coverage report of a chart.
+func writeLintCoverage(out io.Writer, coverage *action.LintCoverage) {
+	fmt.Fprintln(out, "Template coverage:")
+	for _, set := range coverage.ValuesSets {
+		if set.Err != nil {
+			fmt.Fprintf(out, "  values set %s could not be rendered: %s\n", set.Name, set.Err)
+		}
+	}
+
+	// The counts of the blocks of each file, and in total.
+	type counts struct {
+		files, filesHit, defines, definesHit, branches, branchesHit int
+	}
+	files := make(map[string]*counts)
+	var names []string
+	var total counts
+	var missed []engine.CoverageBlock
+	for _, b := range coverage.Blocks {
+		c, ok := files[b.File]
+		if !ok {
+			c = &counts{}
+			files[b.File] = c
+			names = append(names, b.File)
+		}
+		hit := 0
+		if b.Hits > 0 {
+			hit = 1
+		} else {
+			missed = append(missed, b)
+		}
+		for _, c := range []*counts{c, &total} {
+			switch b.Kind {
+			case engine.CoverageFile:
+				c.files++
+				c.filesHit += hit
+			case engine.CoverageDefine:
+				c.defines++
+				c.definesHit += hit
+			default:
+				c.branches++
+				c.branchesHit += hit
+			}
+		}
+	}
+
+	ratio := func(hit, all int) string {
+		if all == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%d/%d", hit, all)
+	}
+	table := uitable.New()
+	table.AddRow("FILE", "RENDERED", "DEFINES", "BRANCHES")
+	for _, name := range names {
+		c := files[name]
+		table.AddRow(name, ratio(c.filesHit, c.files), ratio(c.definesHit, c.defines), ratio(c.branchesHit, c.branches))
+	}
+	table.AddRow("TOTAL", ratio(total.filesHit, total.files), ratio(total.definesHit, total.defines), ratio(total.branchesHit, total.branches))
+	fmt.Fprintln(out, table.String())
+
+	if len(missed) > 0 {
+		fmt.Fprintln(out, "Not rendered by any values set:")
+		for _, b := range missed {
+			fmt.Fprintf(out, "  %s\n", b)
+		}
+	}
+}
@@ -70,6 +70,11 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 %s", testChart),
 		golden:    "output/lint-chart-with-deprecated-api.txt",
 		wantError: false,
+	}, {
+		name:      "lint chart with removed api version using kube version flag",
+		cmd:       fmt.Sprintf("lint --kube-version 1.26.0 %s", testChart),
+		golden:    "output/lint-chart-with-removed-api.txt",
+		wantError: false,
 	}, {
 		name:      "lint chart with deprecated api version using kube version and strict flag",
 		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --strict %s", testChart),
@@ -87,6 +92,59 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --kube-version 1.21.0 --strict %s", testChart),
 		golden:    "output/lint-chart-with-deprecated-api-old-k8s.txt",
 		wantError: false,
+	}, {
+		name:      "lint chart with deprecated api version against multiple kube versions",
+		cmd:       fmt.Sprintf("lint --kube-versions 1.21.0,1.22.0,1.26.0 %s", testChart),
+		golden:    "output/lint-chart-with-deprecated-api-kube-versions.txt",
+		wantError: true,
+	}, {
+		name:      "lint with both kube version flags should fail",
+		cmd:       fmt.Sprintf("lint --kube-version 1.22.0 --kube-versions 1.26.0 %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithCoverage(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-coverage"
+	tests := []cmdTestCase{{
+		name:   "lint chart with template coverage",
+		cmd:    fmt.Sprintf("lint --coverage %s", testChart),
+		golden: "output/lint-coverage.txt",
+	}, {
+		name:   "lint chart with template coverage of the given values using --quiet flag",
+		cmd:    fmt.Sprintf("lint --quiet --coverage --set tls=true %s", testChart),
+		golden: "output/lint-coverage-quiet.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithDisabledChecks(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "lint with an unknown template check",
+		cmd:       "lint --disable-check indent,quotes testdata/testcharts/alpine",
+		golden:    "output/lint-unknown-check.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithAdvisories(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-advisories"
+	tests := []cmdTestCase{{
+		name:      "lint chart affected by security advisories",
+		cmd:       fmt.Sprintf("lint --advisories testdata/advisories.json %s", testChart),
+		golden:    "output/lint-advisories.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart affected by security advisories above a severity",
+		cmd:       fmt.Sprintf("lint --quiet --advisories testdata/advisories.json --advisory-severity high %s", testChart),
+		golden:    "output/lint-advisories-high.txt",
+		wantError: true,
+	}, {
+		name:      "lint with an unknown advisory severity",
+		cmd:       fmt.Sprintf("lint --advisories testdata/advisories.json --advisory-severity severe %s", testChart),
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
@@ -22,6 +22,7 @@ import (
 	"os"
 	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
@@ -58,11 +59,21 @@ By default, up to 256 items may be returned. To limit this, use the '--max' flag
 Setting '--max' to 0 will not return all results. Rather, it will return the
 server's default, which may be much higher than 256. Pairing the '--max'
 flag with the '--offset' flag allows you to page through results.
+
+Releases can get trapped in a pending state when the operation that put them
+there is interrupted. The '--pending-older-than' flag lists the releases that
+have been pending for at least the given duration, and '--stuck' reports them
+together with the command that most likely recovers them:
+
+    $ helm list --all-namespaces --stuck
+    NAME     NAMESPACE  REVISION  STATUS           PENDING FOR  SUGGESTION
+    frontend web        7         pending-upgrade  2h13m5s      helm rollback frontend --namespace web
 `
 
 func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewList(cfg)
 	var outfmt output.Format
+	var stuck bool
 
 	cmd := &cobra.Command{
 		Use:               "list",
@@ -79,6 +90,14 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			client.SetStateMask()
 
+			if stuck {
+				results, err := client.Stuck(client.PendingOlderThan)
+				if err != nil {
+					return err
+				}
+				return outfmt.Write(out, newStuckReleaseWriter(results, client.NoHeaders))
+			}
+
 			results, err := client.Run()
 			if err != nil {
 				return err
@@ -124,6 +143,8 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Deployed, "deployed", false, "show deployed releases. If no other is specified, this will be automatically enabled")
 	f.BoolVar(&client.Failed, "failed", false, "show failed releases")
 	f.BoolVar(&client.Pending, "pending", false, "show pending releases")
+	f.DurationVar(&client.PendingOlderThan, "pending-older-than", 0, "show only releases that have been pending or uninstalling for at least this long (e.g. 30m)")
+	f.BoolVar(&stuck, "stuck", false, fmt.Sprintf("report releases stuck in a pending state for longer than --pending-older-than (default %s), with how to recover them", action.DefaultStuckThreshold))
 	f.BoolVarP(&client.AllNamespaces, "all-namespaces", "A", false, "list releases across all namespaces")
 	f.IntVarP(&client.Limit, "max", "m", 256, "maximum number of releases to fetch")
 	f.IntVar(&client.Offset, "offset", 0, "next release index in the list, used to offset from start value")
@@ -229,6 +250,55 @@ func (w *releaseListWriter) WriteYAML(out io.Writer) error {
 	return output.EncodeYAML(out, w.releases)
 }
 
+type stuckReleaseElement struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Status     string `json:"status"`
+	PendingFor string `json:"pending_for"`
+	Suggestion string `json:"suggestion"`
+}
+
+type stuckReleaseWriter struct {
+	releases  []stuckReleaseElement
+	noHeaders bool
+}
+
+func newStuckReleaseWriter(stuck []action.StuckRelease, noHeaders bool) *stuckReleaseWriter {
+	// Initialize the array so no results returns an empty array instead of null
+	elements := make([]stuckReleaseElement, 0, len(stuck))
+	for _, s := range stuck {
+		elements = append(elements, stuckReleaseElement{
+			Name:       s.Release.Name,
+			Namespace:  s.Release.Namespace,
+			Revision:   strconv.Itoa(s.Release.Version),
+			Status:     s.Release.Info.Status.String(),
+			PendingFor: s.PendingFor.Round(time.Second).String(),
+			Suggestion: s.Suggestion,
+		})
+	}
+	return &stuckReleaseWriter{elements, noHeaders}
+}
+
+func (w *stuckReleaseWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	if !w.noHeaders {
+		table.AddRow("NAME", "NAMESPACE", "REVISION", "STATUS", "PENDING FOR", "SUGGESTION")
+	}
+	for _, r := range w.releases {
+		table.AddRow(r.Name, r.Namespace, r.Revision, r.Status, r.PendingFor, r.Suggestion)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *stuckReleaseWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.releases)
+}
+
+func (w *stuckReleaseWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.releases)
+}
+
 // Returns all releases from 'releases', except those with names matching 'ignoredReleases'
 func filterReleases(releases []*release.Release, ignoredReleaseNames []string) []*release.Release {
 	// if ignoredReleaseNames is nil, just return releases
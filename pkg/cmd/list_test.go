@@ -244,3 +244,43 @@ func TestListOutputCompletion(t *testing.T) {
 func TestListFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "list", false)
 }
+
+func TestListPendingCmd(t *testing.T) {
+	now := testTimestamper()
+	mk := func(name string, status release.Status, age time.Duration) *release.Release {
+		return &release.Release{
+			Name:      name,
+			Version:   3,
+			Namespace: "default",
+			Info: &release.Info{
+				LastDeployed: now.Add(-age),
+				Status:       status,
+			},
+			Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "chickadee", Version: "1.0.0"}},
+		}
+	}
+	rels := []*release.Release{
+		mk("drax", release.StatusPendingUpgrade, 2*time.Hour),
+		mk("gamora", release.StatusPendingInstall, 40*time.Minute),
+		mk("groot", release.StatusUninstalling, 5*time.Minute),
+		mk("rocket", release.StatusDeployed, 3*time.Hour),
+	}
+
+	tests := []cmdTestCase{{
+		name:   "list releases pending for a while",
+		cmd:    "list --pending-older-than 30m",
+		golden: "output/list-pending-older-than.txt",
+		rels:   rels,
+	}, {
+		name:   "report stuck releases",
+		cmd:    "list --stuck",
+		golden: "output/list-stuck.txt",
+		rels:   rels,
+	}, {
+		name:   "report stuck releases with a threshold",
+		cmd:    "list --stuck --pending-older-than 1m -o json",
+		golden: "output/list-stuck.json",
+		rels:   rels,
+	}}
+	runTestCmd(t, tests)
+}
@@ -104,8 +104,13 @@ func loadCLIPlugins(baseCmd *cobra.Command, out io.Writer) {
 				}
 
 				// Prepare environment
+				envVars, cleanup, err := settings.PluginEnvVars()
+				defer cleanup()
+				if err != nil {
+					return err
+				}
 				env := os.Environ()
-				for k, v := range settings.EnvVars() {
+				for k, v := range envVars {
 					env = append(env, fmt.Sprintf("%s=%s", k, v))
 				}
 
@@ -364,7 +369,8 @@ func pluginDynamicComp(plug plugin.Plugin, cmd *cobra.Command, args []string, to
 	cobra.CompDebugln(fmt.Sprintf("calling %s with args %v", main, argv), settings.Debug)
 	buf := new(bytes.Buffer)
 
-	// Prepare environment
+	// Prepare environment. Completing must not consume a kubeconfig given
+	// on standard input, so it is not passed to the plugin.
 	env := os.Environ()
 	for k, v := range settings.EnvVars() {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
@@ -17,14 +17,23 @@ package cmd
 
 import (
 	"io"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v4/internal/plugin"
+	"helm.sh/helm/v4/internal/plugin/installer"
 )
 
 const pluginHelp = `
 Manage client-side Helm plugins.
+
+Plugins of type 'event/v1' are invoked on the lifecycle events of the actions
+they list in the 'events' of their configuration: pre-install, post-install,
+pre-upgrade, post-upgrade, pre-rollback, post-rollback, pre-uninstall,
+post-uninstall and post-render. The event and the release are passed as JSON
+on stdin. A plugin failing a pre event or the post-render event aborts the
+action; failures on the other post events are reported as warnings.
 `
 
 func newPluginCmd(out io.Writer) *cobra.Command {
@@ -53,3 +62,41 @@ func runHook(p plugin.Plugin, event string) error {
 
 	return nil
 }
+
+// pluginLockPath returns the path of the lock recording the installed plugins.
+func pluginLockPath() string {
+	return filepath.Join(settings.PluginsDirectory, installer.LockFileName)
+}
+
+// lockPlugin records an installed plugin in the plugins lock.
+func lockPlugin(p plugin.Plugin, source, constraint string) error {
+	lock, err := installer.LoadLock(pluginLockPath())
+	if err != nil {
+		return err
+	}
+	digest, err := installer.DigestDir(p.Dir())
+	if err != nil {
+		return err
+	}
+	lock.Set(&installer.LockedPlugin{
+		Name:       p.Metadata().Name,
+		Version:    p.Metadata().Version,
+		Source:     source,
+		Constraint: constraint,
+		Digest:     digest,
+	})
+	return lock.Save(pluginLockPath())
+}
+
+// unlockPlugin removes a plugin from the plugins lock.
+func unlockPlugin(name string) error {
+	lock, err := installer.LoadLock(pluginLockPath())
+	if err != nil {
+		return err
+	}
+	if lock.Get(name) == nil {
+		return nil
+	}
+	lock.Remove(name)
+	return lock.Save(pluginLockPath())
+}
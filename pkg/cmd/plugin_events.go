@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"helm.sh/helm/v4/internal/plugin"
+	"helm.sh/helm/v4/internal/plugin/schema"
+	"helm.sh/helm/v4/pkg/action"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// pluginEventHandler dispatches the lifecycle events of the actions to the
+// installed event plugins registered for them. The plugins are loaded on the
+// first event, so commands not emitting events do not pay for it.
+type pluginEventHandler struct {
+	pluginsDirs []string
+	load        func() ([]plugin.Plugin, error)
+}
+
+var _ action.EventHandler = (*pluginEventHandler)(nil)
+
+func newPluginEventHandler(pluginsDirectory string) *pluginEventHandler {
+	h := &pluginEventHandler{pluginsDirs: filepath.SplitList(pluginsDirectory)}
+	h.load = sync.OnceValues(func() ([]plugin.Plugin, error) {
+		return plugin.FindPlugins(h.pluginsDirs, plugin.Descriptor{Type: "event/v1"})
+	})
+	return h
+}
+
+// HandleEvent invokes the event plugins registered for the event, in name
+// order. The output of the plugins goes to stderr.
+func (h *pluginEventHandler) HandleEvent(ctx context.Context, event string, rel *release.Release) error {
+	plugins, err := h.load()
+	if err != nil {
+		return fmt.Errorf("failed to load event plugins: %w", err)
+	}
+	var errs []error
+	for _, p := range plugins {
+		config, ok := p.Metadata().Config.(*schema.ConfigEventV1)
+		if !ok || !slices.Contains(config.Events, event) {
+			continue
+		}
+		slog.Debug("invoking event plugin", "plugin", p.Metadata().Name, "event", event, "release", rel.Name)
+		input := &plugin.Input{
+			Message: schema.InputMessageEventV1{
+				Event:   event,
+				Release: eventRelease(rel),
+			},
+			Stdout: os.Stderr,
+			Stderr: os.Stderr,
+		}
+		if _, err := p.Invoke(ctx, input); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// eventRelease describes a release to the event plugins.
+func eventRelease(rel *release.Release) schema.EventReleaseV1 {
+	r := schema.EventReleaseV1{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Values:    rel.Config,
+		Manifest:  rel.Manifest,
+	}
+	if rel.Info != nil {
+		r.Status = rel.Info.Status.String()
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		r.Chart = rel.Chart.Metadata.Name
+		r.ChartVersion = rel.Chart.Metadata.Version
+		r.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/internal/plugin/schema"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func writeEventPlugin(t *testing.T, dir, name, events, command string) {
+	t.Helper()
+	pluginDir := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(pluginDir, 0755))
+	pluginYAML := `apiVersion: v1
+name: ` + name + `
+type: event/v1
+runtime: subprocess
+config:
+  events: ` + events + `
+runtimeConfig:
+  platformCommand:
+  - command: sh
+    args: ["-c", ` + command + `]
+`
+	require.NoError(t, os.WriteFile(filepath.Join(pluginDir, "plugin.yaml"), []byte(pluginYAML), 0644))
+}
+
+func TestPluginEventHandler(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "event.json")
+	writeEventPlugin(t, dir, "audit", "[post-install]", `"cat > `+out+`"`)
+	writeEventPlugin(t, dir, "policy", "[pre-install]", `"echo denied >&2; exit 1"`)
+
+	rel := &release.Release{
+		Name:      "demo",
+		Namespace: "default",
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "hello", Version: "0.1.0"}},
+		Config:    map[string]interface{}{"replicas": 2},
+		Manifest:  "kind: ConfigMap\n",
+	}
+
+	h := newPluginEventHandler(dir)
+	require.NoError(t, h.HandleEvent(t.Context(), "post-install", rel))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var msg schema.InputMessageEventV1
+	require.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "post-install", msg.Event)
+	assert.Equal(t, schema.EventReleaseV1{
+		Name:         "demo",
+		Namespace:    "default",
+		Revision:     1,
+		Status:       "deployed",
+		Chart:        "hello",
+		ChartVersion: "0.1.0",
+		Values:       map[string]any{"replicas": float64(2)},
+		Manifest:     "kind: ConfigMap\n",
+	}, msg.Release)
+
+	assert.ErrorContains(t, h.HandleEvent(t.Context(), "pre-install", rel), `plugin "policy" exited with error`)
+
+	// Events no plugin is registered for are no-ops.
+	assert.NoError(t, h.HandleEvent(t.Context(), "pre-upgrade", rel))
+}
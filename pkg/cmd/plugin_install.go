@@ -19,12 +19,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"path"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v4/internal/plugin"
 	"helm.sh/helm/v4/internal/plugin/installer"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cmd/require"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/registry"
@@ -55,6 +57,12 @@ alongside the tarball.
 For local development, plugins installed from local directories are automatically
 treated as "local dev" and do not require signatures.
 Use --verify=false to skip signature verification for remote plugins.
+
+Use --version to install the latest version matching a semantic version
+constraint, such as '~1.2.0', from the tags of a VCS repository or an OCI
+repository. Installed plugins are recorded with their versions and digests in
+the plugins.lock file of the plugins directory. Updates of plugins installed
+with a constraint honor it.
 `
 
 func newPluginInstallCmd(out io.Writer) *cobra.Command {
@@ -80,7 +88,7 @@ func newPluginInstallCmd(out io.Writer) *cobra.Command {
 			return o.run(out)
 		},
 	}
-	cmd.Flags().StringVar(&o.version, "version", "", "specify a version constraint. If this is not specified, the latest version is installed")
+	cmd.Flags().StringVar(&o.version, "version", "", "specify a version constraint, matched against the tags of VCS and OCI plugin repositories. If this is not specified, the latest version is installed")
 	cmd.Flags().BoolVar(&o.verify, "verify", true, "verify the plugin signature before installing")
 	cmd.Flags().StringVar(&o.keyring, "keyring", defaultKeyring(), "location of public keys used for verification")
 
@@ -111,13 +119,45 @@ func (o *pluginInstallOptions) newInstallerForSource() (installer.Installer, err
 			getter.WithBasicAuth(o.username, o.password),
 		}
 
-		return installer.NewOCIInstaller(o.source, options...)
+		source := o.source
+		if o.version != "" {
+			var err error
+			if source, err = o.resolveOCIVersion(); err != nil {
+				return nil, err
+			}
+		}
+
+		return installer.NewOCIInstaller(source, options...)
 	}
 
 	// For non-OCI sources, use the original logic
 	return installer.NewForSource(o.source, o.version)
 }
 
+// resolveOCIVersion pins the OCI reference of the plugin to the latest tag of
+// its repository matching the version constraint.
+func (o *pluginInstallOptions) resolveOCIVersion() (string, error) {
+	ref := strings.TrimPrefix(o.source, fmt.Sprintf("%s://", registry.OCIScheme))
+	if strings.Contains(ref, "@") || strings.Contains(path.Base(ref), ":") {
+		return "", fmt.Errorf("--version cannot be used with a tag or digest in %s", o.source)
+	}
+	registryClient, err := newRegistryClient(o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify, o.plainHTTP, o.username, o.password)
+	if err != nil {
+		return "", err
+	}
+	tags, err := registryClient.Tags(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the tags of %s: %w", o.source, err)
+	}
+	tag, err := registry.GetTagMatchingVersionOrConstraint(tags, o.version)
+	if err != nil {
+		return "", fmt.Errorf("plugin %s: %w", o.source, err)
+	}
+	slog.Debug("resolved plugin version", "source", o.source, "constraint", o.version, "version", tag)
+	// Tags cannot contain "+", see https://github.com/helm/helm/issues/10166
+	return fmt.Sprintf("%s:%s", o.source, strings.ReplaceAll(tag, "+", "_")), nil
+}
+
 func (o *pluginInstallOptions) run(out io.Writer) error {
 	installer.Debug = settings.Debug
 
@@ -137,7 +177,7 @@ func (o *pluginInstallOptions) run(out io.Writer) error {
 	} else if shouldVerify {
 		// For remote installations, check if verification is supported
 		if verifier, ok := i.(installer.Verifier); !ok || !verifier.SupportsVerification() {
-			return fmt.Errorf("plugin source does not support verification. Use --verify=false to skip verification")
+			return i18n.Errorf(ErrCodePluginUnverifiable, "plugin source does not support verification. Use --verify=false to skip verification")
 		}
 	} else {
 		// User explicitly disabled verification
@@ -180,6 +220,17 @@ func (o *pluginInstallOptions) run(out io.Writer) error {
 		return err
 	}
 
-	fmt.Fprintf(out, "Installed plugin: %s\n", p.Metadata().Name)
+	// Local and HTTP sources have a single version, so there is no constraint
+	// to honor on later updates.
+	constraint := ""
+	switch i.(type) {
+	case *installer.VCSInstaller, *installer.OCIInstaller:
+		constraint = o.version
+	}
+	if err := lockPlugin(p, o.source, constraint); err != nil {
+		return fmt.Errorf("plugin is installed but could not be locked: %w", err)
+	}
+
+	i18n.Fprintf(out, "Installed plugin: %s\n", p.Metadata().Name)
 	return nil
 }
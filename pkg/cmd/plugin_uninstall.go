@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v4/internal/plugin"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 )
 
 type pluginUninstallOptions struct {
@@ -72,10 +73,10 @@ func (o *pluginUninstallOptions) run(out io.Writer) error {
 			if err := uninstallPlugin(found); err != nil {
 				errorPlugins = append(errorPlugins, fmt.Errorf("failed to uninstall plugin %s, got error (%v)", name, err))
 			} else {
-				fmt.Fprintf(out, "Uninstalled plugin: %s\n", name)
+				i18n.Fprintf(out, "Uninstalled plugin: %s\n", name)
 			}
 		} else {
-			errorPlugins = append(errorPlugins, fmt.Errorf("plugin: %s not found", name))
+			errorPlugins = append(errorPlugins, i18n.Errorf(ErrCodePluginNotFound, "plugin: %s not found", name))
 		}
 	}
 	if len(errorPlugins) > 0 {
@@ -118,6 +119,10 @@ func uninstallPlugin(p plugin.Plugin) error {
 		}
 	}
 
+	if err := unlockPlugin(pluginName); err != nil {
+		return err
+	}
+
 	return runHook(p, plugin.Delete)
 }
 
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"helm.sh/helm/v4/internal/plugin"
+	"helm.sh/helm/v4/internal/plugin/installer"
 	"helm.sh/helm/v4/internal/test/ensure"
 	"helm.sh/helm/v4/pkg/cli"
 )
@@ -144,3 +145,51 @@ command: $HELM_PLUGIN_DIR/test-plugin
 		t.Error("other version tarball should NOT be removed")
 	}
 }
+
+func TestPluginUninstallUnlocksPlugin(t *testing.T) {
+	ensure.HelmHome(t)
+
+	pluginsDir := t.TempDir()
+	defer func(dir string) { settings.PluginsDirectory = dir }(settings.PluginsDirectory)
+	settings.PluginsDirectory = pluginsDir
+
+	pluginDir := filepath.Join(pluginsDir, "test-plugin")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pluginYAML := `name: test-plugin
+version: 1.2.3
+description: Test plugin
+command: $HELM_PLUGIN_DIR/test-plugin
+`
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin.yaml"), []byte(pluginYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := plugin.LoadDir(pluginDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lockPlugin(p, "https://example.com/test-plugin", "~1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := installer.LoadLock(pluginLockPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := lock.Get("test-plugin")
+	if locked == nil || locked.Version != "1.2.3" || locked.Constraint != "~1.2.0" || locked.Digest == "" {
+		t.Fatalf("unexpected lock entry %+v", locked)
+	}
+
+	if err := uninstallPlugin(p); err != nil {
+		t.Fatal(err)
+	}
+	lock, err = installer.LoadLock(pluginLockPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.Get("test-plugin") != nil {
+		t.Error("plugin should be removed from the lock")
+	}
+}
@@ -26,12 +26,23 @@ import (
 
 	"helm.sh/helm/v4/internal/plugin"
 	"helm.sh/helm/v4/internal/plugin/installer"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 )
 
 type pluginUpdateOptions struct {
 	names []string
+	to    string
 }
 
+const pluginUpdateDesc = `
+This command updates plugins installed from a VCS repository.
+
+Use --to to move the plugins to the latest tag matching a semantic version
+constraint, such as '^2.0.0'. The constraint is recorded in the plugins.lock
+file of the plugins directory, and later updates without --to honor the
+constraint a plugin was installed or last updated with.
+`
+
 func newPluginUpdateCmd(out io.Writer) *cobra.Command {
 	o := &pluginUpdateOptions{}
 
@@ -39,6 +50,7 @@ func newPluginUpdateCmd(out io.Writer) *cobra.Command {
 		Use:     "update <plugin>...",
 		Aliases: []string{"up"},
 		Short:   "update one or more Helm plugins",
+		Long:    pluginUpdateDesc,
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compListPlugins(toComplete, args), cobra.ShellCompDirectiveNoFileComp
 		},
@@ -49,6 +61,7 @@ func newPluginUpdateCmd(out io.Writer) *cobra.Command {
 			return o.run(out)
 		},
 	}
+	cmd.Flags().StringVar(&o.to, "to", "", "update to the latest version matching this version constraint")
 	return cmd
 }
 
@@ -67,17 +80,27 @@ func (o *pluginUpdateOptions) run(out io.Writer) error {
 	if err != nil {
 		return err
 	}
+	lock, err := installer.LoadLock(pluginLockPath())
+	if err != nil {
+		return err
+	}
 	var errorPlugins []error
 
 	for _, name := range o.names {
 		if found := findPlugin(plugins, name); found != nil {
-			if err := updatePlugin(found); err != nil {
+			constraint := o.to
+			if constraint == "" {
+				if locked := lock.Get(name); locked != nil {
+					constraint = locked.Constraint
+				}
+			}
+			if err := updatePlugin(found, constraint); err != nil {
 				errorPlugins = append(errorPlugins, fmt.Errorf("failed to update plugin %s, got error (%v)", name, err))
 			} else {
-				fmt.Fprintf(out, "Updated plugin: %s\n", name)
+				i18n.Fprintf(out, "Updated plugin: %s\n", name)
 			}
 		} else {
-			errorPlugins = append(errorPlugins, fmt.Errorf("plugin: %s not found", name))
+			errorPlugins = append(errorPlugins, i18n.Errorf(ErrCodePluginNotFound, "plugin: %s not found", name))
 		}
 	}
 	if len(errorPlugins) > 0 {
@@ -86,7 +109,7 @@ func (o *pluginUpdateOptions) run(out io.Writer) error {
 	return nil
 }
 
-func updatePlugin(p plugin.Plugin) error {
+func updatePlugin(p plugin.Plugin, constraint string) error {
 	exactLocation, err := filepath.EvalSymlinks(p.Dir())
 	if err != nil {
 		return err
@@ -100,6 +123,13 @@ func updatePlugin(p plugin.Plugin) error {
 	if err != nil {
 		return err
 	}
+	vcsInstaller, ok := i.(*installer.VCSInstaller)
+	if constraint != "" {
+		if !ok {
+			return errors.New("version constraints are only supported for plugins installed from a VCS repository")
+		}
+		vcsInstaller.Version = constraint
+	}
 	if err := installer.Update(i); err != nil {
 		return err
 	}
@@ -110,5 +140,13 @@ func updatePlugin(p plugin.Plugin) error {
 		return err
 	}
 
-	return runHook(updatedPlugin, plugin.Update)
+	if err := runHook(updatedPlugin, plugin.Update); err != nil {
+		return err
+	}
+
+	source := ""
+	if ok {
+		source = vcsInstaller.Repo.Remote()
+	}
+	return lockPlugin(updatedPlugin, source, constraint)
 }
@@ -41,6 +41,11 @@ directory for the chart and uncompress into that directory.
 If the --verify flag is specified, the requested chart MUST have a provenance
 file, and MUST pass the verification process. Failure in any part of this will
 result in an error, and the chart will not be saved locally.
+
+If the --advisories flag is set to the URL or the path of a feed of OSV security
+advisories, charts affected by an advisory, or with dependencies that are, are
+not saved locally either. Advisories with a severity below --advisory-severity
+are ignored.
 `
 
 func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -87,8 +92,10 @@ func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored.")
 	f.BoolVar(&client.Untar, "untar", false, "if set to true, will untar the chart after downloading it")
 	f.BoolVar(&client.VerifyLater, "prov", false, "fetch the provenance file, but don't perform verification")
+	bindAdvisoryFlags(f, &client.AdvisoryPolicy)
 	f.StringVar(&client.UntarDir, "untardir", ".", "if untar is specified, this flag specifies the name of the directory into which the chart is expanded")
 	f.StringVarP(&client.DestDir, "destination", "d", ".", "location to write the chart. If this and untardir are specified, untardir is appended to this")
+	addRetryFlags(f, &client.Retries, &client.RetryBackoff)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 
 	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
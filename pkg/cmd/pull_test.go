@@ -22,6 +22,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v4/pkg/repo/v1/repotest"
@@ -97,6 +98,12 @@ func TestPullCmd(t *testing.T) {
 			failExpect: "Failed to fetch provenance",
 			wantError:  true,
 		},
+		{
+			name:       "Fail fetching a chart affected by a security advisory",
+			args:       "test/reqtest --advisories testdata/advisories.json",
+			failExpect: "HELM-2026-0003",
+			wantError:  true,
+		},
 		{
 			name:       "Fetch and untar",
 			args:       "test/signtest --untar --untardir signtest",
@@ -497,6 +504,43 @@ func TestPullWithCredentialsCmdOCIRegistry(t *testing.T) {
 	runPullTests(t, tests, srv.Root(), "--plain-http")
 }
 
+func TestPullVerifyOCIReferrer(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testcharts/*.tgz*"),
+	)
+	defer srv.Stop()
+
+	ociSrv, err := repotest.NewOCIServer(t, srv.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ociSrv.Run(t)
+
+	outdir := srv.Root()
+	flags := fmt.Sprintf("--registry-config %s --repository-cache %s --content-cache %s --plain-http",
+		filepath.Join(outdir, "config.json"), outdir, t.TempDir())
+
+	// The provenance file is attached to the chart instead of being a layer of it.
+	_, _, err = executeActionCommand(fmt.Sprintf("push testdata/testcharts/signtest-0.1.0.tgz oci://%s/u/ocitestuser --prov-referrer %s",
+		ociSrv.RegistryURL, flags))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("pull oci://%s/u/ocitestuser/signtest --version 0.1.0 --verify --keyring testdata/helm-test-key.pub -d '%s' %s",
+		ociSrv.RegistryURL, outdir, flags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Chart Hash Verified: ") {
+		t.Errorf("expected the chart to be verified, got %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(outdir, "signtest-0.1.0.tgz.prov")); err != nil {
+		t.Errorf("expected the provenance file to be saved: %s", err)
+	}
+}
+
 func TestPullFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "pull", false)
 	checkFileCompletion(t, "pull repo/chart", false)
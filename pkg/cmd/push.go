@@ -19,12 +19,17 @@ package cmd
 import (
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/cmd/require"
 	"helm.sh/helm/v4/pkg/pusher"
+	"helm.sh/helm/v4/pkg/registry"
 )
 
 const pushDesc = `
@@ -32,6 +37,35 @@ Upload a chart to a registry.
 
 If the chart has an associated provenance file,
 it will also be uploaded.
+
+With '--separate-layers', the values.yaml, values.schema.json and README of the
+chart are also pushed to OCI registries as separate layers, next to the chart.
+'helm show values' and 'helm show readme' then only download these layers
+instead of the whole chart.
+
+Artifacts can be attached to charts pushed to OCI registries as OCI referrers,
+manifests whose subject is the manifest of the chart: SBOMs with '--sbom', in
+the SPDX or CycloneDX JSON formats, and any other artifact, such as a
+signature, with '--attach ARTIFACT_TYPE=FILE'. With '--prov-referrer', the
+provenance file is attached the same way instead of being pushed as a layer of
+the chart. 'helm pull --verify' finds it either way. Registries without the
+referrers API of OCI 1.1 get the referrers tag schema instead.
+
+With '--chunk-size', the layers of the chart larger than the given size, such
+as '64Mi', are uploaded to OCI registries in chunks instead of in a single
+request. A chunk failing to upload is resumed from the last byte received by
+the registry, so that large charts, such as the ones bundling images, are not
+uploaded again in full when the connection drops.
+
+Examples:
+
+    # Push a signed chart with its SBOM
+    $ helm push mychart-0.1.0.tgz oci://registry.example.com/charts \
+        --prov-referrer --sbom mychart.spdx.json
+
+    # Attach a Sigstore bundle
+    $ helm push mychart-0.1.0.tgz oci://registry.example.com/charts \
+        --attach application/vnd.dev.sigstore.bundle.v0.3+json=mychart.sigstore.json
 `
 
 type registryPushOptions struct {
@@ -40,6 +74,11 @@ type registryPushOptions struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	separateLayers        bool
+	provReferrer          bool
+	sboms                 []string
+	attachments           []string
+	chunkSize             string
 	password              string
 	username              string
 }
@@ -78,12 +117,28 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				return fmt.Errorf("missing registry client: %w", err)
 			}
 			cfg.RegistryClient = registryClient
+			attachments, err := readAttachments(o.sboms, o.attachments)
+			if err != nil {
+				return err
+			}
+			var chunkSize int64
+			if o.chunkSize != "" {
+				q, err := resource.ParseQuantity(o.chunkSize)
+				if err != nil || q.Value() <= 0 {
+					return fmt.Errorf("invalid --chunk-size %q, expected a size such as 64Mi", o.chunkSize)
+				}
+				chunkSize = q.Value()
+			}
 			chartRef := args[0]
 			remote := args[1]
 			client := action.NewPushWithOpts(action.WithPushConfig(cfg),
 				action.WithTLSClientConfig(o.certFile, o.keyFile, o.caFile),
 				action.WithInsecureSkipTLSVerify(o.insecureSkipTLSverify),
 				action.WithPlainHTTP(o.plainHTTP),
+				action.WithSeparateLayers(o.separateLayers),
+				action.WithProvReferrer(o.provReferrer),
+				action.WithAttachments(attachments...),
+				action.WithChunkSize(chunkSize),
 				action.WithPushOptWriter(out))
 			client.Settings = settings
 			output, err := client.Run(chartRef, remote)
@@ -101,8 +156,42 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart upload")
 	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the chart upload")
+	f.BoolVar(&o.separateLayers, "separate-layers", false, "also push the values, schema and README of the chart as separate layers, so 'helm show' can read them without pulling the chart")
+	f.BoolVar(&o.provReferrer, "prov-referrer", false, "attach the provenance file to the chart as an OCI referrer, instead of pushing it as a layer of the chart")
+	f.StringArrayVar(&o.sboms, "sbom", nil, "attach an SBOM file, in the SPDX or CycloneDX JSON format, to the chart as an OCI referrer (can specify multiple)")
+	f.StringArrayVar(&o.attachments, "attach", nil, "attach a file to the chart as an OCI referrer of an artifact type, such as a signature, as ARTIFACT_TYPE=FILE (can specify multiple)")
+	f.StringVar(&o.chunkSize, "chunk-size", "", "upload the layers of the chart larger than this size, such as 64Mi, in chunks resumed when failing")
 	f.StringVar(&o.username, "username", "", "chart repository username where to locate the requested chart")
 	f.StringVar(&o.password, "password", "", "chart repository password where to locate the requested chart")
 
 	return cmd
 }
+
+// readAttachments reads the SBOM files, whose artifact type is their format,
+// and the ARTIFACT_TYPE=FILE attachments to push with a chart.
+func readAttachments(sboms, attachments []string) ([]registry.Attachment, error) {
+	var result []registry.Attachment
+	for _, file := range sboms {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		artifactType, err := registry.SBOMArtifactType(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		result = append(result, registry.Attachment{ArtifactType: artifactType, Name: filepath.Base(file), Data: data})
+	}
+	for _, a := range attachments {
+		artifactType, file, ok := strings.Cut(a, "=")
+		if !ok || artifactType == "" || file == "" {
+			return nil, fmt.Errorf("invalid attachment %q, expected ARTIFACT_TYPE=FILE", a)
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, registry.Attachment{ArtifactType: artifactType, Name: filepath.Base(file), Data: data})
+	}
+	return result, nil
+}
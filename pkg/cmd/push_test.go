@@ -17,7 +17,12 @@ limitations under the License.
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v4/pkg/registry"
 )
 
 func TestPushFileCompletion(t *testing.T) {
@@ -25,3 +30,45 @@ func TestPushFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "push package.tgz", false)
 	checkFileCompletion(t, "push package.tgz oci://localhost:5000", false)
 }
+
+func TestReadAttachments(t *testing.T) {
+	dir := t.TempDir()
+	sbom := filepath.Join(dir, "chart.cdx.json")
+	if err := os.WriteFile(sbom, []byte(`{"bomFormat":"CycloneDX","specVersion":"1.5"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "chart.sigstore.json")
+	if err := os.WriteFile(bundle, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	attachments, err := readAttachments([]string{sbom}, []string{registry.SigstoreBundleArtifactType + "=" + bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(attachments))
+	}
+	if a := attachments[0]; a.ArtifactType != registry.CycloneDXArtifactType || a.Name != "chart.cdx.json" {
+		t.Errorf("expected the CycloneDX SBOM, got %s %s", a.ArtifactType, a.Name)
+	}
+	if a := attachments[1]; a.ArtifactType != registry.SigstoreBundleArtifactType || a.Name != "chart.sigstore.json" {
+		t.Errorf("expected the Sigstore bundle, got %s %s", a.ArtifactType, a.Name)
+	}
+
+	if _, err := readAttachments([]string{bundle}, nil); err == nil {
+		t.Error("expected an error for an SBOM of an unknown format")
+	}
+	if _, err := readAttachments(nil, []string{bundle}); err == nil {
+		t.Error("expected an error for an attachment without artifact type")
+	}
+}
+
+func TestPushInvalidChunkSize(t *testing.T) {
+	for _, size := range []string{"big", "0", "-1Mi"} {
+		_, _, err := executeActionCommand("push testdata/testcharts/compressedchart-0.1.0.tgz oci://localhost:5000/charts --chunk-size " + size)
+		if err == nil || !strings.Contains(err.Error(), "invalid --chunk-size") {
+			t.Errorf("expected an invalid chunk size error for %q, got %v", size, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+const registryListDesc = `
+List the registries with stored credentials.
+
+For each registry, the method the credentials authenticate with, where they
+are stored, when their token expires if it carries an expiry, and when Helm
+last authenticated with them are shown. Credentials only found in the Docker
+configuration Helm falls back to are not listed.
+`
+
+func newRegistryListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	cmd := &cobra.Command{
+		Use:               "list",
+		Aliases:           []string{"ls"},
+		Short:             "list the registries with stored credentials",
+		Long:              registryListDesc,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			creds, err := action.NewRegistryList(cfg).Run()
+			if err != nil {
+				return err
+			}
+			if len(creds) == 0 && outfmt != output.JSON && outfmt != output.YAML {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no registries to show")
+				return nil
+			}
+			return outfmt.Write(out, &registryListWriter{creds})
+		},
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+type registryListWriter struct {
+	creds []registry.Credential
+}
+
+func (w *registryListWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("REGISTRY", "AUTH", "STORE", "USERNAME", "EXPIRES", "LAST USED")
+	for _, c := range w.creds {
+		table.AddRow(c.Registry, c.AuthMethod, c.Store, c.Username, formatOptionalTime(c.Expires), formatOptionalTime(c.LastUsed))
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *registryListWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.creds)
+}
+
+func (w *registryListWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.creds)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryListCmd(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	t.Setenv("HELM_REGISTRY_CONFIG", filepath.Join(dir, "config.json"))
+
+	runTestCmd(t, []cmdTestCase{{
+		name:   "list without credentials",
+		cmd:    "registry list",
+		golden: "output/registry-list-empty.txt",
+	}})
+
+	credentials := `{"auths": {
+  "registry.example.com": {"auth": "dXNlcjpwYXNz"},
+  "token.example.com": {"identitytoken": "eyJhbGciOiJub25lIn0.eyJleHAiOjE3OTAwMDAwMDB9.sig"}
+}}`
+	usage := `{"registry.example.com": "2026-10-01T12:00:00Z"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config-usage.json"), []byte(usage), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runTestCmd(t, []cmdTestCase{{
+		name:   "list credentials",
+		cmd:    "registry list",
+		golden: "output/registry-list.txt",
+	}, {
+		name:   "list credentials as json",
+		cmd:    "registry list -o json",
+		golden: "output/registry-list.json",
+	}, {
+		name:      "logout without host",
+		cmd:       "registry logout",
+		golden:    "output/registry-logout-no-args.txt",
+		wantError: true,
+	}, {
+		name:      "logout of all registries with host",
+		cmd:       "registry logout --all registry.example.com",
+		golden:    "output/registry-logout-all-args.txt",
+		wantError: true,
+	}, {
+		name: "logout of all registries",
+		cmd:  "registry logout --all",
+	}, {
+		name:   "list after logging out",
+		cmd:    "registry list",
+		golden: "output/registry-list-empty.txt",
+	}})
+}
+
+func TestRegistryListCompletion(t *testing.T) {
+	checkFileCompletion(t, "registry list", false)
+}
@@ -27,18 +27,35 @@ import (
 
 const registryLogoutDesc = `
 Remove credentials stored for a remote registry.
+
+Use '--all' to remove the credentials of all the registries listed by
+'helm registry list'.
 `
 
 func newRegistryLogoutCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
-	return &cobra.Command{
+	client := action.NewRegistryLogout(cfg)
+
+	cmd := &cobra.Command{
 		Use:               "logout [host]",
 		Short:             "logout from a registry",
 		Long:              registryLogoutDesc,
-		Args:              require.MinimumNArgs(1),
 		ValidArgsFunction: cobra.NoFileCompletions,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if client.All {
+				return require.NoArgs(cmd, args)
+			}
+			return require.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(_ *cobra.Command, args []string) error {
-			hostname := args[0]
-			return action.NewRegistryLogout(cfg).Run(out, hostname)
+			var hostname string
+			if len(args) > 0 {
+				hostname = args[0]
+			}
+			return client.Run(out, hostname)
 		},
 	}
+
+	cmd.Flags().BoolVar(&client.All, "all", false, "logout from all the registries with stored credentials")
+
+	return cmd
 }
@@ -17,11 +17,15 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -36,6 +40,11 @@ The test command runs the tests for a release.
 
 The argument this command takes is the name of a deployed release.
 The tests to be run are defined in the chart that was installed.
+
+The test fixtures of the chart, the hooks of the 'test-fixture' event, are
+created before the tests, in the order of their weights. They are always
+deleted after the tests, even when the tests fail or are interrupted with
+Ctrl-C.
 `
 
 func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -47,7 +56,7 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	cmd := &cobra.Command{
 		Use:   "test [RELEASE]",
 		Short: "run tests for a release",
-		Long:  releaseTestHelp,
+		Long:  releaseTestHelp + exitCodesHelp,
 		Args:  require.ExactArgs(1),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) != 0 {
@@ -55,7 +64,7 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			}
 			return compListReleases(toComplete, args, cfg)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: withExitCodes(func(_ *cobra.Command, args []string) error {
 			client.Namespace = settings.Namespace()
 			notName := regexp.MustCompile(`^!\s?name=`)
 			for _, f := range filter {
@@ -65,7 +74,20 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 					client.Filters[action.ExcludeNameFilter] = append(client.Filters[action.ExcludeNameFilter], notName.ReplaceAllLiteralString(f, ""))
 				}
 			}
-			rel, runErr := client.Run(args[0])
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cSignal := make(chan os.Signal, 2)
+			signal.Notify(cSignal, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(cSignal)
+			go func() {
+				select {
+				case <-cSignal:
+					fmt.Fprintf(out, "Tests of release %s have been cancelled, deleting the test fixtures.\n", args[0])
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			rel, runErr := client.RunWithContext(ctx, args[0])
 			// We only return an error if we weren't even able to get the
 			// release, otherwise we keep going so we can print status and logs
 			// if requested
@@ -92,7 +114,7 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			}
 
 			return runErr
-		},
+		}),
 	}
 
 	f := cmd.Flags()
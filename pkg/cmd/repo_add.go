@@ -22,8 +22,11 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -32,6 +35,8 @@ import (
 	"golang.org/x/term"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/internal/tlsutil"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cmd/require"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/repo/v1"
@@ -58,6 +63,13 @@ type repoAddOptions struct {
 	keyFile               string
 	caFile                string
 	insecureSkipTLSverify bool
+	minTLSVersion         string
+	proxy                 string
+
+	mirrors     []string
+	mirrorOrder string
+
+	credentialHelper string
 
 	repoFile  string
 	repoCache string
@@ -95,8 +107,13 @@ func newRepoAddCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&o.keyFile, "key-file", "", "identify HTTPS client using this SSL key file")
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the repository")
+	f.StringVar(&o.minTLSVersion, "min-tls-version", "", "minimum TLS version accepted from the repository: 1.0, 1.1, 1.2 or 1.3")
+	f.StringVar(&o.proxy, "proxy", "", "URL of the proxy to reach the repository through, instead of the one set in the environment")
 	f.BoolVar(&o.allowDeprecatedRepos, "allow-deprecated-repos", false, "by default, this command will not allow adding official repos that have been permanently deleted. This disables that behavior")
 	f.BoolVar(&o.passCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
+	f.StringSliceVar(&o.mirrors, "mirror", nil, "URL of a mirror of the repository, to download from when the repository cannot be reached (can specify multiple)")
+	f.StringVar(&o.mirrorOrder, "mirror-order", repo.MirrorOrderListed, fmt.Sprintf("order the repository and its mirrors are tried in: %q, or %q to try the fastest to respond first", repo.MirrorOrderListed, repo.MirrorOrderLatency))
+	f.StringVar(&o.credentialHelper, "credential-helper", "", fmt.Sprintf("keep the username and password in a credential helper instead of the repositories file: %q for the keychain of the OS, or the suffix of a docker-credential-<suffix> program", repo.CredentialHelperKeychain))
 	f.DurationVar(&o.timeout, "timeout", getter.DefaultHTTPTimeout*time.Second, "time to wait for the index file download to complete")
 
 	return cmd
@@ -107,7 +124,7 @@ func (o *repoAddOptions) run(out io.Writer) error {
 	if !o.allowDeprecatedRepos {
 		for oldURL, newURL := range deprecatedRepos {
 			if strings.Contains(o.url, oldURL) {
-				return fmt.Errorf("repo %q is no longer available; try %q instead", o.url, newURL)
+				return i18n.Errorf(ErrCodeRepoDeprecated, "repo %q is no longer available; try %q instead", o.url, newURL)
 			}
 		}
 	}
@@ -178,11 +195,32 @@ func (o *repoAddOptions) run(out io.Writer) error {
 		KeyFile:               o.keyFile,
 		CAFile:                o.caFile,
 		InsecureSkipTLSverify: o.insecureSkipTLSverify,
+		MinTLSVersion:         o.minTLSVersion,
+		Proxy:                 o.proxy,
+		Mirrors:               o.mirrors,
+		CredentialHelper:      o.credentialHelper,
+	}
+	if o.minTLSVersion != "" {
+		if _, err := tlsutil.ParseVersion(o.minTLSVersion); err != nil {
+			return err
+		}
+	}
+	if o.proxy != "" {
+		if u, err := url.Parse(o.proxy); err != nil || u.Host == "" {
+			return i18n.Errorf(ErrCodeRepoInvalidProxy, "invalid proxy URL %q", o.proxy)
+		}
+	}
+	switch o.mirrorOrder {
+	case "", repo.MirrorOrderListed:
+	case repo.MirrorOrderLatency:
+		c.MirrorOrder = o.mirrorOrder
+	default:
+		return i18n.Errorf(ErrCodeRepoInvalidMirrorOrder, "invalid mirror order %q, must be %q or %q", o.mirrorOrder, repo.MirrorOrderListed, repo.MirrorOrderLatency)
 	}
 
 	// Check if the repo name is legal
 	if strings.Contains(o.name, "/") {
-		return fmt.Errorf("repository name (%s) contains '/', please specify a different name without '/'", o.name)
+		return i18n.Errorf(ErrCodeRepoInvalidName, "repository name (%s) contains '/', please specify a different name without '/'", o.name)
 	}
 
 	// If the repo exists do one of two things:
@@ -190,18 +228,22 @@ func (o *repoAddOptions) run(out io.Writer) error {
 	// 2. When the config is different require --force-update
 	if !o.forceUpdate && f.Has(o.name) {
 		existing := f.Get(o.name)
-		if c != *existing {
+		if !equalEntries(c, *existing) {
 			// The input coming in for the name is different from what is already
 			// configured. Return an error.
-			return fmt.Errorf("repository name (%s) already exists, please specify a different name", o.name)
+			return i18n.Errorf(ErrCodeRepoExists, "repository name (%s) already exists, please specify a different name", o.name)
 		}
 
 		// The add is idempotent so do nothing
-		fmt.Fprintf(out, "%q already exists with the same configuration, skipping\n", o.name)
+		i18n.Fprintf(out, "%q already exists with the same configuration, skipping\n", o.name)
 		return nil
 	}
 
-	r, err := repo.NewChartRepository(&c, getter.All(settings, getter.WithTimeout(o.timeout)))
+	// The credentials are only stored with the credential helper once the
+	// repository is known to be valid.
+	probe := c
+	probe.CredentialHelper = ""
+	r, err := repo.NewChartRepository(&probe, getter.All(settings, getter.WithTimeout(o.timeout)))
 	if err != nil {
 		return err
 	}
@@ -209,15 +251,37 @@ func (o *repoAddOptions) run(out io.Writer) error {
 	if o.repoCache != "" {
 		r.CachePath = o.repoCache
 	}
-	if _, err := r.DownloadIndexFile(); err != nil {
-		return fmt.Errorf("looks like %q is not a valid chart repository or cannot be reached: %w", o.url, err)
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		return i18n.Errorf(ErrCodeRepoUnreachable, "looks like %q is not a valid chart repository or cannot be reached: %w", o.url, err)
 	}
+	writeSearchCache(o.name, idx)
 
+	if err := c.StoreCredentials(); err != nil {
+		return err
+	}
 	f.Update(&c)
 
 	if err := f.WriteFile(o.repoFile, 0o600); err != nil {
 		return err
 	}
-	fmt.Fprintf(out, "%q has been added to your repositories\n", o.name)
+	i18n.Fprintf(out, "%q has been added to your repositories\n", o.name)
 	return nil
 }
+
+// equalEntries reports whether two repository configurations are the same. The
+// credentials of b kept by a credential helper are compared with the ones of a.
+func equalEntries(a, b repo.Entry) bool {
+	if b.CredentialHelper != "" {
+		username, password, err := b.Credentials()
+		if err != nil {
+			return false
+		}
+		b.Username, b.Password = username, password
+	}
+	if !slices.Equal(a.Mirrors, b.Mirrors) {
+		return false
+	}
+	a.Mirrors, b.Mirrors = nil, nil
+	return reflect.DeepEqual(a, b)
+}
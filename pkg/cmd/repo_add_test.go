@@ -21,8 +21,12 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"testing"
@@ -134,6 +138,180 @@ func TestRepoAdd(t *testing.T) {
 	}
 }
 
+func TestRepoAddMirrors(t *testing.T) {
+	ts := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+	)
+	defer ts.Stop()
+	defer resetEnv()()
+
+	rootDir := t.TempDir()
+	repoFile := filepath.Join(rootDir, "repositories.yaml")
+	t.Setenv(xdg.CacheHomeEnvVar, rootDir)
+
+	// The repository is added from the mirror when it cannot be reached.
+	down := repotest.NewTempServer(t)
+	down.Stop()
+	o := &repoAddOptions{
+		name:        "mirrored",
+		url:         down.URL(),
+		mirrors:     []string{ts.URL()},
+		mirrorOrder: repo.MirrorOrderLatency,
+		repoFile:    repoFile,
+	}
+	if err := o.run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	f, err := repo.LoadFile(repoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := f.Get("mirrored")
+	if entry == nil || len(entry.Mirrors) != 1 || entry.Mirrors[0] != ts.URL() || entry.MirrorOrder != repo.MirrorOrderLatency {
+		t.Errorf("expected the mirrors to be saved, got %+v", entry)
+	}
+
+	// Adding it again with the same mirrors does nothing, but with others
+	// requires --force-update.
+	if err := o.run(io.Discard); err != nil {
+		t.Errorf("expected the add to be idempotent, got %v", err)
+	}
+	o.mirrors = nil
+	if err := o.run(io.Discard); err == nil {
+		t.Error("expected the repository with other mirrors to already exist")
+	}
+
+	o.name = "invalid"
+	o.mirrorOrder = "random"
+	if err := o.run(io.Discard); err == nil || !strings.Contains(err.Error(), `invalid mirror order "random"`) {
+		t.Errorf("expected an invalid mirror order error, got %v", err)
+	}
+}
+
+func TestRepoAddTransportSettings(t *testing.T) {
+	ts := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+	)
+	defer ts.Stop()
+	defer resetEnv()()
+
+	rootDir := t.TempDir()
+	repoFile := filepath.Join(rootDir, "repositories.yaml")
+	t.Setenv(xdg.CacheHomeEnvVar, rootDir)
+
+	// The repository is only reachable through the proxy.
+	proxy := httptest.NewServer(httputil.NewSingleHostReverseProxy(mustParseURL(t, ts.URL())))
+	defer proxy.Close()
+	o := &repoAddOptions{
+		name:          "proxied",
+		url:           "http://charts.example.invalid",
+		proxy:         proxy.URL,
+		minTLSVersion: "1.2",
+		repoFile:      repoFile,
+	}
+	if err := o.run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	f, err := repo.LoadFile(repoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := f.Get("proxied"); entry == nil || entry.Proxy != proxy.URL || entry.MinTLSVersion != "1.2" {
+		t.Errorf("expected the proxy and minimum TLS version to be saved, got %+v", entry)
+	}
+
+	o.name = "invalid"
+	o.minTLSVersion = "2.0"
+	if err := o.run(io.Discard); err == nil || !strings.Contains(err.Error(), `invalid TLS version "2.0"`) {
+		t.Errorf("expected an invalid TLS version error, got %v", err)
+	}
+	o.minTLSVersion = ""
+	o.proxy = "not a proxy"
+	if err := o.run(io.Discard); err == nil || !strings.Contains(err.Error(), `invalid proxy URL "not a proxy"`) {
+		t.Errorf("expected an invalid proxy URL error, got %v", err)
+	}
+}
+
+// credentialHelperScript is a Docker credential helper keeping the
+// credentials it stores in a file.
+const credentialHelperScript = `#!/bin/sh
+store="$(dirname "$0")/credentials.json"
+case "$1" in
+store) cat > "$store" ;;
+get) cat > /dev/null; if [ -f "$store" ]; then cat "$store"; else echo "credentials not found in native keychain"; exit 1; fi ;;
+erase) cat > /dev/null; rm -f "$store" ;;
+esac
+`
+
+func TestRepoAddCredentialHelper(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the credential helper is a shell script")
+	}
+	ts := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+		repotest.WithMiddleware(repotest.BasicAuthMiddleware(t)),
+	)
+	defer ts.Stop()
+	defer resetEnv()()
+
+	rootDir := t.TempDir()
+	repoFile := filepath.Join(rootDir, "repositories.yaml")
+	t.Setenv(xdg.CacheHomeEnvVar, rootDir)
+	helperDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(helperDir, "docker-credential-helmtest"), []byte(credentialHelperScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", helperDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	o := &repoAddOptions{
+		name:             "secured",
+		url:              ts.URL(),
+		username:         "username",
+		password:         "password",
+		credentialHelper: "helmtest",
+		repoFile:         repoFile,
+	}
+	if err := o.run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(repoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "password: password") {
+		t.Errorf("expected the password not to be written to the repositories file, got:\n%s", data)
+	}
+	stored, err := os.ReadFile(filepath.Join(helperDir, "credentials.json"))
+	if err != nil || !strings.Contains(string(stored), `"Secret":"password"`) {
+		t.Errorf("expected the credentials to be stored by the helper, got %s, %v", stored, err)
+	}
+
+	// Adding it again with the same credentials does nothing.
+	if err := o.run(io.Discard); err != nil {
+		t.Errorf("expected the add to be idempotent, got %v", err)
+	}
+
+	rm := &repoRemoveOptions{names: []string{"secured"}, repoFile: repoFile, repoCache: rootDir}
+	if err := rm.run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(helperDir, "credentials.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials to be erased with the repository, got %v", err)
+	}
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
 func TestRepoAddCheckLegalName(t *testing.T) {
 	ts := repotest.NewTempServer(
 		t,
@@ -155,7 +333,7 @@ func TestRepoAddCheckLegalName(t *testing.T) {
 	}
 	t.Setenv(xdg.CacheHomeEnvVar, rootDir)
 
-	wantErrorMsg := fmt.Sprintf("repository name (%s) contains '/', please specify a different name without '/'", testRepoName)
+	wantErrorMsg := fmt.Sprintf("%s: repository name (%s) contains '/', please specify a different name without '/'", ErrCodeRepoInvalidName, testRepoName)
 
 	if err := o.run(io.Discard); err != nil {
 		if wantErrorMsg != err.Error() {
@@ -235,6 +413,45 @@ func repoAddConcurrent(t *testing.T, testName, repoFile string) {
 	}
 }
 
+func TestRepoAddWithBasicAuth(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+		repotest.WithBasicAuth("username", "password"),
+	)
+	defer srv.Stop()
+
+	tmpdir := t.TempDir()
+	repoFile := filepath.Join(tmpdir, "repositories.yaml")
+
+	tests := []cmdTestCase{
+		{
+			name:      "add a repository without credentials",
+			cmd:       fmt.Sprintf("repo add test-name %s --repository-config %s --repository-cache %s", srv.URL(), repoFile, tmpdir),
+			wantError: true,
+		},
+		{
+			name:      "add a repository with a wrong password",
+			cmd:       fmt.Sprintf("repo add test-name %s --repository-config %s --repository-cache %s --username username --password wrong", srv.URL(), repoFile, tmpdir),
+			wantError: true,
+		},
+		{
+			name:   "add a repository with credentials",
+			cmd:    fmt.Sprintf("repo add test-name %s --repository-config %s --repository-cache %s --username username --password password", srv.URL(), repoFile, tmpdir),
+			golden: "output/repo-add.txt",
+		},
+	}
+	runTestCmd(t, tests)
+
+	f, err := repo.LoadFile(repoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := f.Get("test-name"); e == nil || e.Username != "username" || e.Password != "password" {
+		t.Errorf("expected the repository to be added with its credentials, got %+v", e)
+	}
+}
+
 func TestRepoAddFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "repo add", false)
 	checkFileCompletion(t, "repo add reponame", false)
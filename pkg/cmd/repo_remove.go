@@ -21,11 +21,13 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cmd/require"
 	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/repo/v1"
@@ -61,12 +63,16 @@ func newRepoRemoveCmd(out io.Writer) *cobra.Command {
 func (o *repoRemoveOptions) run(out io.Writer) error {
 	r, err := repo.LoadFile(o.repoFile)
 	if isNotExist(err) || len(r.Repositories) == 0 {
-		return errors.New("no repositories configured")
+		return i18n.Errorf(ErrCodeNoRepositories, "no repositories configured")
 	}
 
 	for _, name := range o.names {
+		entry := r.Get(name)
 		if !r.Remove(name) {
-			return fmt.Errorf("no repo named %q found", name)
+			return i18n.Errorf(ErrCodeRepoNotFound, "no repo named %q found", name)
+		}
+		if err := entry.DeleteCredentials(); err != nil {
+			slog.Warn("the credentials of the repository were not deleted", slog.Any("error", err))
 		}
 		if err := r.WriteFile(o.repoFile, 0600); err != nil {
 			return err
@@ -75,19 +81,20 @@ func (o *repoRemoveOptions) run(out io.Writer) error {
 		if err := removeRepoCache(o.repoCache, name); err != nil {
 			return err
 		}
-		fmt.Fprintf(out, "%q has been removed from your repositories\n", name)
+		i18n.Fprintf(out, "%q has been removed from your repositories\n", name)
 	}
 
 	return nil
 }
 
 func removeRepoCache(root, name string) error {
-	idx := filepath.Join(root, helmpath.CacheChartsFile(name))
-	if _, err := os.Stat(idx); err == nil {
-		os.Remove(idx)
+	for _, f := range []string{helmpath.CacheChartsFile(name), helmpath.CacheSearchFile(name), helmpath.CacheIndexStateFile(name)} {
+		if _, err := os.Stat(filepath.Join(root, f)); err == nil {
+			os.Remove(filepath.Join(root, f))
+		}
 	}
 
-	idx = filepath.Join(root, helmpath.CacheIndexFile(name))
+	idx := filepath.Join(root, helmpath.CacheIndexFile(name))
 	if _, err := os.Stat(idx); errors.Is(err, fs.ErrNotExist) {
 		return nil
 	} else if err != nil {
@@ -20,14 +20,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"path/filepath"
 	"slices"
 	"sync"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/cmd/search"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/helmpath"
 	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
@@ -38,9 +43,13 @@ Information is cached locally, where it is used by commands like 'helm search'.
 You can optionally specify a list of repositories you want to update.
 	$ helm repo update <repo_name> ...
 To update all the repositories, use 'helm repo update'.
+
+The repositories are updated concurrently. When some of them fail to update,
+the command fails unless '--continue-on-error' is set, in which case the
+failures are summarized once all the repositories are updated.
 `
 
-var errNoRepositories = errors.New("no repositories found. You must add one before updating")
+var errNoRepositories = i18n.Errorf(ErrCodeNoRepositories, "no repositories found. You must add one before updating")
 
 type repoUpdateOptions struct {
 	update    func([]*repo.ChartRepository, io.Writer) error
@@ -48,6 +57,9 @@ type repoUpdateOptions struct {
 	repoCache string
 	names     []string
 	timeout   time.Duration
+	// continueOnError reports the repositories that failed to update
+	// without failing.
+	continueOnError bool
 }
 
 func newRepoUpdateCmd(out io.Writer) *cobra.Command {
@@ -66,12 +78,18 @@ func newRepoUpdateCmd(out io.Writer) *cobra.Command {
 			o.repoFile = settings.RepositoryConfig
 			o.repoCache = settings.RepositoryCache
 			o.names = args
+			if o.continueOnError {
+				o.update = func(repos []*repo.ChartRepository, out io.Writer) error {
+					return updateRepos(repos, out, true)
+				}
+			}
 			return o.run(out)
 		},
 	}
 
 	f := cmd.Flags()
 	f.DurationVar(&o.timeout, "timeout", getter.DefaultHTTPTimeout*time.Second, "time to wait for the index file download to complete")
+	f.BoolVar(&o.continueOnError, "continue-on-error", false, "update all the repositories even when some fail, and summarize the failures without failing")
 
 	return cmd
 }
@@ -114,45 +132,88 @@ func (o *repoUpdateOptions) run(out io.Writer) error {
 }
 
 func updateCharts(repos []*repo.ChartRepository, out io.Writer) error {
-	fmt.Fprintln(out, "Hang tight while we grab the latest from your chart repositories...")
-	var wg sync.WaitGroup
-	failRepoURLChan := make(chan string, len(repos))
-
-	writeMutex := sync.Mutex{}
-	for _, re := range repos {
-		wg.Add(1)
-		go func(re *repo.ChartRepository) {
-			defer wg.Done()
-			if _, err := re.DownloadIndexFile(); err != nil {
-				writeMutex.Lock()
-				defer writeMutex.Unlock()
-				fmt.Fprintf(out, "...Unable to get an update from the %q chart repository (%s):\n\t%s\n", re.Config.Name, re.Config.URL, err)
-				failRepoURLChan <- re.Config.URL
-			} else {
-				writeMutex.Lock()
-				defer writeMutex.Unlock()
-				fmt.Fprintf(out, "...Successfully got an update from the %q chart repository\n", re.Config.Name)
-			}
-		}(re)
+	return updateRepos(repos, out, false)
+}
+
+// updateRepos updates the repositories concurrently, reporting their
+// progress to out.
+func updateRepos(repos []*repo.ChartRepository, out io.Writer, continueOnError bool) error {
+	i18n.Fprintln(out, "Hang tight while we grab the latest from your chart repositories...")
+	updater := &repo.Updater{
+		ContinueOnError: continueOnError,
+		Progress:        &updateProgress{out: out},
 	}
+	_, err := updater.Update(repos)
+
+	var updateErr *repo.UpdateError
+	if continueOnError && errors.As(err, &updateErr) {
+		i18n.Fprintf(out, "Update Complete, but %d of %d repositories failed to update:\n", len(updateErr.Failed), len(repos))
+		for _, res := range updateErr.Failed {
+			i18n.Fprintf(out, "\t%s (%s): %s\n", res.Repo.Config.Name, res.Repo.Config.URL, res.Err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	i18n.Fprintln(out, "Update Complete. ⎈Happy Helming!⎈")
+	return nil
+}
 
-	go func() {
-		wg.Wait()
-		close(failRepoURLChan)
-	}()
+// updateProgress writes the outcome of the update of each repository.
+type updateProgress struct {
+	mu  sync.Mutex
+	out io.Writer
+}
 
-	var repoFailList []string
-	for url := range failRepoURLChan {
-		repoFailList = append(repoFailList, url)
+func (p *updateProgress) UpdateStatus(res repo.UpdateResult) {
+	switch res.Status {
+	case repo.UpdateSucceeded:
+		writeSearchCache(res.Repo.Config.Name, res.IndexFile)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if res.Fetched > 0 {
+			i18n.Fprintf(p.out, "...Successfully got an update from the %q chart repository (%s fetched)\n", res.Repo.Config.Name, formatBytes(res.Fetched))
+		} else {
+			i18n.Fprintf(p.out, "...Successfully got an update from the %q chart repository\n", res.Repo.Config.Name)
+		}
+	case repo.UpdateFailed:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		i18n.Fprintf(p.out, "...Unable to get an update from the %q chart repository (%s):\n\t%s\n", res.Repo.Config.Name, res.Repo.Config.URL, res.Err)
+	case repo.UpdateSkipped:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		i18n.Fprintf(p.out, "...Skipped the update of the %q chart repository\n", res.Repo.Config.Name)
 	}
+}
 
-	if len(repoFailList) > 0 {
-		return fmt.Errorf("failed to update the following repositories: %s",
-			repoFailList)
+// UpdateFetched does nothing, the bytes fetched are written once the update
+// succeeded.
+func (p *updateProgress) UpdateFetched(repo.UpdateResult) {}
+
+// formatBytes formats a number of bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
 
-	fmt.Fprintln(out, "Update Complete. ⎈Happy Helming!⎈")
-	return nil
+// writeSearchCache rebuilds the search cache of a repository from its freshly
+// downloaded index, so 'helm search repo' does not have to parse the index.
+func writeSearchCache(name, indexFile string) {
+	cacheFile := filepath.Join(filepath.Dir(indexFile), helmpath.CacheSearchFile(name))
+	if err := search.WriteCache(cacheFile, indexFile); err != nil {
+		slog.Warn("unable to write search cache", "repo", name, slog.Any("error", err))
+	}
 }
 
 func checkRequestedRepos(requestedRepos []string, validRepos []*repo.Entry) error {
@@ -165,7 +226,7 @@ func checkRequestedRepos(requestedRepos []string, validRepos []*repo.Entry) erro
 			}
 		}
 		if !found {
-			return fmt.Errorf("no repositories found matching '%s'.  Nothing will be updated", requestedRepo)
+			return i18n.Errorf(ErrCodeRepoNotFound, "no repositories found matching '%s'.  Nothing will be updated", requestedRepo)
 		}
 	}
 	return nil
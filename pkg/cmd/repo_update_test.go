@@ -19,9 +19,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"helm.sh/helm/v4/internal/test/ensure"
@@ -125,6 +127,9 @@ func TestUpdateCustomCacheCmd(t *testing.T) {
 	if _, err := os.Stat(filepath.Join(cachePath, "test-index.yaml")); err != nil {
 		t.Fatalf("error finding created index file in custom cache: %v", err)
 	}
+	if _, err := os.Stat(filepath.Join(cachePath, "test-search.bin")); err != nil {
+		t.Fatalf("error finding created search cache in custom cache: %v", err)
+	}
 }
 
 func TestUpdateCharts(t *testing.T) {
@@ -210,3 +215,174 @@ func TestUpdateChartsFailWithError(t *testing.T) {
 		t.Error("Update was not successful and should return error message because 'fail-on-repo-update-fail' flag set")
 	}
 }
+
+func TestUpdateChartsFailWithServerErrors(t *testing.T) {
+	defer resetEnv()()
+	ensure.HelmHome(t)
+
+	ts := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+		repotest.WithFailures("/index.yaml", http.StatusServiceUnavailable, 1),
+	)
+	defer ts.Stop()
+
+	r, err := repo.NewChartRepository(&repo.Entry{
+		Name: "charts",
+		URL:  ts.URL(),
+	}, getter.All(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := bytes.NewBuffer(nil)
+	err = updateCharts([]*repo.ChartRepository{r}, b)
+	if err == nil {
+		t.Error("expected the update to fail")
+	}
+	if !strings.Contains(b.String(), "503 Service Unavailable") {
+		t.Errorf("expected the server error to be reported, got %q", b.String())
+	}
+
+	// The server recovers after the first failure.
+	b.Reset()
+	if err := updateCharts([]*repo.ChartRepository{r}, b); err != nil {
+		t.Errorf("expected the second update to succeed, got %v", err)
+	}
+	if n := ts.FaultRequests("/index.yaml"); n != 2 {
+		t.Errorf("expected 2 requests for the index, got %d", n)
+	}
+}
+
+func TestUpdateChartsIncrementally(t *testing.T) {
+	defer resetEnv()()
+	ensure.HelmHome(t)
+
+	var indexRequests atomic.Int32
+	ts := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("testdata/testcharts/compressedchart-0.1.0.tgz"),
+		repotest.WithIndexChangelog(),
+		repotest.WithMiddleware(func(_ http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/index.yaml" {
+				indexRequests.Add(1)
+			}
+		}),
+	)
+	defer ts.Stop()
+
+	r, err := repo.NewChartRepository(&repo.Entry{
+		Name: "charts",
+		URL:  ts.URL(),
+	}, getter.All(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+
+	b := bytes.NewBuffer(nil)
+	if err := updateCharts([]*repo.ChartRepository{r}, b); err != nil {
+		t.Fatalf("expected the update to succeed, got %v: %s", err, b.String())
+	}
+	if _, err := ts.CopyCharts("testdata/testcharts/compressedchart-0.2.0.tgz"); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateCharts([]*repo.ChartRepository{r}, b); err != nil {
+		t.Fatalf("expected the update to succeed, got %v: %s", err, b.String())
+	}
+
+	if n := indexRequests.Load(); n != 1 {
+		t.Errorf("expected the index to be downloaded once, got %d requests", n)
+	}
+	idx, err := repo.LoadIndexFile(filepath.Join(r.CachePath, "charts-index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.Has("compressedchart", "0.1.0") || !idx.Has("compressedchart", "0.2.0") {
+		t.Errorf("expected the added version to be in the cached index, got %v", idx.Entries["compressedchart"])
+	}
+}
+
+func TestUpdateChartsCompressedIndex(t *testing.T) {
+	defer resetEnv()()
+	ensure.HelmHome(t)
+
+	ts := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("testdata/testcharts/compressedchart-0.1.0.tgz"),
+		repotest.WithCompressedIndex(repotest.EncodingZstd, repotest.EncodingGzip),
+	)
+	defer ts.Stop()
+	if err := ts.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := repo.NewChartRepository(&repo.Entry{
+		Name: "charts",
+		URL:  ts.URL(),
+	}, getter.All(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+
+	b := bytes.NewBuffer(nil)
+	if err := updateCharts([]*repo.ChartRepository{r}, b); err != nil {
+		t.Fatalf("expected the update to succeed, got %v: %s", err, b.String())
+	}
+	idx, err := repo.LoadIndexFile(filepath.Join(r.CachePath, "charts-index.yaml"))
+	if err != nil {
+		t.Fatalf("expected the downloaded index to be decoded, got %v", err)
+	}
+	if !idx.Has("compressedchart", "0.1.0") {
+		t.Error("expected the downloaded index to have the chart")
+	}
+	// Helm does not ask for the index to be compressed.
+	if got := ts.ContentEncodings("/index.yaml"); len(got) != 1 || got[0] != repotest.EncodingIdentity {
+		t.Errorf("expected the index to be downloaded uncompressed, got %v", got)
+	}
+}
+
+func TestUpdateChartsContinueOnError(t *testing.T) {
+	defer resetEnv()()
+	ensure.HelmHome(t)
+
+	ts := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("testdata/testserver/*.*"),
+	)
+	defer ts.Stop()
+
+	var repos []*repo.ChartRepository
+	for name, url := range map[string]string{"good": ts.URL(), "bad": ts.URL() + "/missing"} {
+		r, err := repo.NewChartRepository(&repo.Entry{Name: name, URL: url}, getter.All(settings))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.CachePath = t.TempDir()
+		repos = append(repos, r)
+	}
+
+	b := bytes.NewBuffer(nil)
+	if err := updateRepos(repos, b, true); err != nil {
+		t.Fatalf("expected the update to continue on errors, got %v", err)
+	}
+	got := b.String()
+	if !strings.Contains(got, `...Successfully got an update from the "good" chart repository (`) {
+		t.Errorf("expected the good repository to be updated with the bytes fetched, got %q", got)
+	}
+	if !strings.Contains(got, "Update Complete, but 1 of 2 repositories failed to update:\n\tbad ("+ts.URL()+"/missing): ") {
+		t.Errorf("expected the failure to be summarized, got %q", got)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, expected := range map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1024:            "1.0 KiB",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	} {
+		if got := formatBytes(n); got != expected {
+			t.Errorf("expected %q for %d, got %q", expected, n, got)
+		}
+	}
+}
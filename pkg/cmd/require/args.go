@@ -81,6 +81,17 @@ func MinimumNArgs(n int) cobra.PositionalArgs {
 	}
 }
 
+// RangeArgs returns an error if there are not at least minimum args, or more
+// than maximum args.
+func RangeArgs(minimum, maximum int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := MinimumNArgs(minimum)(cmd, args); err != nil {
+			return err
+		}
+		return MaximumNArgs(maximum)(cmd, args)
+	}
+}
+
 func pluralize(word string, n int) string {
 	if n == 1 {
 		return word
@@ -53,6 +53,16 @@ func TestArgs(t *testing.T) {
 	}, {
 		args:         []string{"one", "two"},
 		validateFunc: MinimumNArgs(1),
+	}, {
+		validateFunc: RangeArgs(1, 2),
+		wantError:    `"root" requires at least 1 argument`,
+	}, {
+		args:         []string{"one", "two"},
+		validateFunc: RangeArgs(1, 2),
+	}, {
+		args:         []string{"one", "two", "three"},
+		validateFunc: RangeArgs(1, 2),
+		wantError:    `"root" accepts at most 2 arguments`,
 	}})
 }
 
@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/gosuri/uitable"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+
+	coloroutput "helm.sh/helm/v4/internal/cli/output"
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/output"
+	"helm.sh/helm/v4/pkg/cmd/require"
+)
+
+const resourcesHelp = `
+This command lists the resources of a release as they currently exist in the
+cluster.
+
+The resources are the ones recorded in the manifest of the release. For each
+resource, the namespace, kind and name are shown together with its live status
+and age. Resources that were deleted from the cluster are reported as NotFound.
+
+    $ helm resources my-release
+    NAMESPACE  KIND        NAME        STATUS      AGE
+    default    Service     my-release  Current     3d
+    default    Deployment  my-release  InProgress  3d
+`
+
+func newResourcesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	client := action.NewResources(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "resources RELEASE_NAME",
+		Short: "list the live resources of a release",
+		Long:  resourcesHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			resources, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &resourcesWriter{resources, time.Now(), settings.ShouldDisableColor()})
+		},
+	}
+
+	f := cmd.Flags()
+	f.IntVar(&client.Version, "revision", 0, "list the resources of the named release with revision")
+	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return compListRevisions(toComplete, cfg, args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+type resourcesWriter struct {
+	resources []action.ReleaseResource
+	now       time.Time
+	noColor   bool
+}
+
+func (w *resourcesWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow(
+		coloroutput.ColorizeHeader("NAMESPACE", w.noColor),
+		coloroutput.ColorizeHeader("KIND", w.noColor),
+		coloroutput.ColorizeHeader("NAME", w.noColor),
+		coloroutput.ColorizeHeader("STATUS", w.noColor),
+		coloroutput.ColorizeHeader("AGE", w.noColor),
+	)
+	for _, r := range w.resources {
+		namespace, age := r.Namespace, "-"
+		if namespace == "" {
+			namespace = "-"
+		}
+		if !r.Created.IsZero() {
+			age = duration.HumanDuration(w.now.Sub(r.Created))
+		}
+		table.AddRow(namespace, r.Kind, r.Name, coloroutput.ColorizeResourceStatus(r.Status, w.noColor), age)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *resourcesWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.resources)
+}
+
+func (w *resourcesWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.resources)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v4/internal/test"
+	"helm.sh/helm/v4/pkg/action"
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func TestResourcesCmd(t *testing.T) {
+	rels := []*release.Release{release.Mock(&release.MockReleaseOptions{Name: "thomas-guide"})}
+
+	tests := []cmdTestCase{{
+		name:   "list the resources of a release",
+		cmd:    "resources thomas-guide",
+		golden: "output/resources.txt",
+		rels:   rels,
+	}, {
+		name:   "list the resources of a release as JSON",
+		cmd:    "resources thomas-guide -o json",
+		golden: "output/resources.json",
+		rels:   rels,
+	}, {
+		name:      "list the resources of a missing release",
+		cmd:       "resources thomas-guide --revision 2",
+		golden:    "output/resources-no-revision.txt",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestResourcesWriter(t *testing.T) {
+	now := time.Date(2024, time.June, 4, 9, 30, 0, 0, time.UTC)
+	w := &resourcesWriter{
+		resources: []action.ReleaseResource{
+			{APIVersion: "v1", Kind: "Namespace", Name: "tools", Status: "Current", Created: now.Add(-72 * time.Hour)},
+			{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "tools", Name: "web", Status: "InProgress", Created: now.Add(-90 * time.Second)},
+			{APIVersion: "v1", Kind: "Secret", Namespace: "tools", Name: "web", Status: "NotFound"},
+		},
+		now:     now,
+		noColor: true,
+	}
+	var out bytes.Buffer
+	if err := w.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	test.AssertGoldenString(t, out.String(), "output/resources-table.txt")
+}
+
+func TestResourcesCompletion(t *testing.T) {
+	checkFileCompletion(t, "resources", false)
+	checkFileCompletion(t, "resources myrelease", false)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
@@ -24,7 +25,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	coloroutput "helm.sh/helm/v4/internal/cli/output"
 	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cmd/require"
 )
 
@@ -36,15 +39,22 @@ second is a revision (version) number. If this argument is omitted or set to
 0, it will roll back to the previous release.
 
 To see revision numbers, run 'helm history RELEASE'.
+
+To preview a rollback, run it with '--dry-run --show-diff'. This shows how the
+manifest of the target revision differs from the current revision, and from the
+live state of its resources in the cluster. The --diff-* flags control how the
+changes are shown: the number of unchanged lines around them, the highlighting
+of the changed words, the fields left out, or JSON patches instead of diffs.
 `
 
 func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewRollback(cfg)
+	var showDiff, diffWords bool
 
 	cmd := &cobra.Command{
 		Use:   "rollback <RELEASE> [REVISION]",
 		Short: "roll back a release to a previous revision",
-		Long:  rollbackDesc,
+		Long:  rollbackDesc + exitCodesHelp,
 		Args:  require.MinimumNArgs(1),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
@@ -57,7 +67,7 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 			return noMoreArgsComp()
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: withExitCodes(func(_ *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				ver, err := strconv.Atoi(args[1])
 				if err != nil {
@@ -66,17 +76,34 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				client.Version = ver
 			}
 
+			if showDiff {
+				if !client.DryRun {
+					return errors.New("--show-diff requires --dry-run")
+				}
+				diff, err := client.Diff(args[0])
+				if err != nil {
+					return err
+				}
+				writeDiffSections(out, []diffSection{
+					{i18n.Sprintf("Changes from revision %d (current) to revision %d:", diff.CurrentRevision, diff.TargetRevision), diff.Current},
+					{i18n.Sprintf("Changes from the live state to revision %d:", diff.TargetRevision), diff.Live},
+				}, client.DiffOptions.Format, diffWords, settings.ShouldDisableColor())
+				return nil
+			}
+
 			if err := client.Run(args[0]); err != nil {
 				return err
 			}
 
-			fmt.Fprintf(out, "Rollback was a success! Happy Helming!\n")
+			i18n.Fprintf(out, "Rollback was a success! Happy Helming!\n")
 			return nil
-		},
+		}),
 	}
 
 	f := cmd.Flags()
 	f.BoolVar(&client.DryRun, "dry-run", false, "simulate a rollback")
+	f.BoolVar(&showDiff, "show-diff", false, "with --dry-run, show how the rollback changes the manifest of the current revision and the live state of the resources")
+	addDiffFlags(f, &client.DiffOptions, &diffWords)
 	f.BoolVar(&client.ForceReplace, "force-replace", false, "force resource updates by replacement")
 	f.BoolVar(&client.ForceReplace, "force", false, "deprecated")
 	f.MarkDeprecated("force", "use --force-replace instead")
@@ -93,3 +120,33 @@ func newRollbackCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	return cmd
 }
+
+// diffSection is a diff shown by --show-diff, under a title.
+type diffSection struct {
+	title string
+	diff  string
+}
+
+// writeDiffSections writes the diffs shown by --show-diff. Unified diffs are
+// colorized, with the changed words highlighted if requested.
+func writeDiffSections(out io.Writer, sections []diffSection, format action.DiffFormat, words, noColor bool) {
+	colorize := coloroutput.ColorizeDiff
+	if words {
+		colorize = coloroutput.ColorizeWordDiff
+	}
+	for i, section := range sections {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, section.title)
+		if section.diff == "" {
+			i18n.Fprintln(out, "no changes")
+			continue
+		}
+		if format == action.DiffFormatJSONPatch {
+			fmt.Fprint(out, section.diff)
+			continue
+		}
+		fmt.Fprint(out, colorize(section.diff, noColor))
+	}
+}
@@ -72,6 +72,12 @@ func TestRollbackCmd(t *testing.T) {
 		golden:    "output/rollback-non-existent-version.txt",
 		rels:      rels,
 		wantError: true,
+	}, {
+		name:      "rollback diff requires dry-run",
+		cmd:       "rollback funny-honey 1 --show-diff",
+		golden:    "output/rollback-show-diff-no-dry-run.txt",
+		rels:      rels,
+		wantError: true,
 	}, {
 		name:      "rollback a release without release name",
 		cmd:       "rollback",
@@ -82,6 +88,50 @@ func TestRollbackCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestRollbackShowDiff(t *testing.T) {
+	rels := []*release.Release{
+		{
+			Name:      "funny-honey",
+			Namespace: "default",
+			Info:      &release.Info{Status: release.StatusSuperseded},
+			Chart:     &chart.Chart{},
+			Version:   1,
+			Manifest:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: honey\ndata:\n  flavor: clover\n",
+		},
+		{
+			Name:      "funny-honey",
+			Namespace: "default",
+			Info:      &release.Info{Status: release.StatusDeployed},
+			Chart:     &chart.Chart{},
+			Version:   2,
+			Manifest:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: honey\ndata:\n  flavor: manuka\n",
+		},
+	}
+
+	tests := []cmdTestCase{{
+		name:   "rollback dry-run with diff",
+		cmd:    "rollback funny-honey 1 --dry-run --show-diff",
+		golden: "output/rollback-show-diff.txt",
+		rels:   rels,
+	}, {
+		name:   "rollback dry-run with diff to the same manifest",
+		cmd:    "rollback funny-honey 2 --dry-run --show-diff",
+		golden: "output/rollback-show-diff-no-changes.txt",
+		rels:   rels,
+	}, {
+		name:   "rollback dry-run with diff as JSON patches",
+		cmd:    "rollback funny-honey 1 --dry-run --show-diff --diff-output json-patch",
+		golden: "output/rollback-show-diff-json-patch.txt",
+		rels:   rels,
+	}, {
+		name:   "rollback dry-run with diff ignoring fields",
+		cmd:    "rollback funny-honey 1 --dry-run --show-diff --diff-ignore-path /data --diff-context 0",
+		golden: "output/rollback-show-diff-ignore-path.txt",
+		rels:   rels,
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestRollbackRevisionCompletion(t *testing.T) {
 	mk := func(name string, vers int, status release.Status) *release.Release {
 		return release.Mock(&release.MockReleaseOptions{
@@ -18,12 +18,15 @@ package cmd // import "helm.sh/helm/v4/pkg/cmd"
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/user"
+	"slices"
 	"strings"
 
 	"github.com/fatih/color"
@@ -31,12 +34,16 @@ import (
 	"sigs.k8s.io/yaml"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 
+	coloroutput "helm.sh/helm/v4/internal/cli/output"
 	"helm.sh/helm/v4/internal/logging"
 	"helm.sh/helm/v4/internal/tlsutil"
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/cli/i18n"
+	"helm.sh/helm/v4/pkg/helmpath"
 	kubefake "helm.sh/helm/v4/pkg/kube/fake"
 	"helm.sh/helm/v4/pkg/registry"
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -68,6 +75,7 @@ Environment variables:
 | $HELM_NO_PLUGINS                   | disable plugins. Set HELM_NO_PLUGINS=1 to disable plugins.                                                 |
 | $HELM_PLUGINS                      | set the path to the plugins directory                                                                      |
 | $HELM_REGISTRY_CONFIG              | set the path to the registry config file.                                                                  |
+| $HELM_REGISTRY_MIRRORS_CONFIG      | set the path to the file configuring the mirrors of registries.                                            |
 | $HELM_REPOSITORY_CACHE             | set the path to the repository cache directory                                                             |
 | $HELM_REPOSITORY_CONFIG            | set the path to the repositories file.                                                                     |
 | $KUBECONFIG                        | set an alternative Kubernetes configuration file (default "~/.kube/config")                                |
@@ -79,10 +87,16 @@ Environment variables:
 | $HELM_KUBETOKEN                    | set the Bearer KubeToken used for authentication.                                                          |
 | $HELM_KUBEINSECURE_SKIP_TLS_VERIFY | indicate if the Kubernetes API server's certificate validation should be skipped (insecure)                |
 | $HELM_KUBETLS_SERVER_NAME          | set the server name used to validate the Kubernetes API server certificate                                 |
+| $HELM_KUBEEXEC_TIMEOUT             | set the time to wait for each invocation of the kubeconfig exec credential plugin (e.g. 30s)               |
+| $HELM_KUBEEXEC_RETRIES             | set the number of times a failed invocation of the kubeconfig exec credential plugin is retried            |
+| $HELM_KUBEEXEC_CACHE               | indicate whether the credentials of the kubeconfig exec credential plugin are cached on disk               |
 | $HELM_BURST_LIMIT                  | set the default burst limit in the case the server contains many CRDs (default 100, -1 to disable)         |
 | $HELM_QPS                          | set the Queries Per Second in cases where a high number of calls exceed the option for higher burst values |
+| $HELM_RECORD_ACTOR                 | indicate whether the name of the user running Helm is recorded in the releases it creates                  |
 | $HELM_COLOR                        | set color output mode. Allowed values: never, always, auto (default: never)                                |
 | $NO_COLOR                          | set to any non-empty value to disable all colored output (overrides $HELM_COLOR)                           |
+| $HELM_LOCALE                       | set the language of messages, such as "de" or "pt_BR" (defaults to $LC_ALL, $LC_MESSAGES or $LANG)         |
+| $HELM_LOCALE_DIR                   | set the path to the directory holding message catalogs (default: $HELM_DATA_HOME/locales)                  |
 
 Helm stores cache, configuration, and data based on the following configuration order:
 
@@ -109,6 +123,12 @@ func NewRootCmd(out io.Writer, args []string, logSetup func(bool)) (*cobra.Comma
 	}
 	cobra.OnInitialize(func() {
 		helmDriver := os.Getenv("HELM_DRIVER")
+		if settings.KubeAsUser != "" || len(settings.KubeAsGroups) > 0 {
+			actionConfig.Impersonation = &rest.ImpersonationConfig{
+				UserName: settings.KubeAsUser,
+				Groups:   settings.KubeAsGroups,
+			}
+		}
 		if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), helmDriver); err != nil {
 			log.Fatal(err)
 		}
@@ -116,10 +136,25 @@ func NewRootCmd(out io.Writer, args []string, logSetup func(bool)) (*cobra.Comma
 			loadReleasesInMemory(actionConfig)
 		}
 		actionConfig.SetHookOutputFunc(hookOutputWriter)
+		if settings.RecordActor {
+			actionConfig.Actor = currentActor()
+		}
+		actionConfig.EventHandler = newPluginEventHandler(settings.PluginsDirectory)
+		actionConfig.WarningFunc = printWarning
 	})
 	return cmd, nil
 }
 
+// currentActor returns who runs Helm, recorded in the releases it creates
+// with --record-actor. The Kubernetes user Helm impersonates is recorded
+// separately.
+func currentActor() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
 // SetupLogging sets up Helm logging used by the Helm client.
 // This function is passed to the NewRootCmd function to enable logging. Any other
 // application that uses the NewRootCmd function to setup all the Helm commands may
@@ -133,29 +168,74 @@ func SetupLogging(debug bool) {
 }
 
 // configureColorOutput configures the color output based on the ColorMode setting
-func configureColorOutput(settings *cli.EnvSettings) {
+func configureColorOutput(out io.Writer, settings *cli.EnvSettings) {
 	switch settings.ColorMode {
 	case "never":
 		color.NoColor = true
 	case "always":
 		color.NoColor = false
 	case "auto":
-		// Let fatih/color handle automatic detection
-		// It will check if output is a terminal and NO_COLOR env var
-		// We don't need to do anything here
+		// Only colorize output written to a terminal, so scripts reading
+		// the output of Helm get plain text
+		color.NoColor = !coloroutput.IsTerminal(out)
 	}
 }
 
+// configureLocale selects the language of the messages printed by Helm and
+// loads the message catalogs installed alongside it.
+func configureLocale() {
+	i18n.SetLocale(i18n.LocaleFromEnv())
+	if i18n.Locale() == "" {
+		return
+	}
+	dir := os.Getenv("HELM_LOCALE_DIR")
+	if dir == "" {
+		dir = helmpath.DataPath("locales")
+	}
+	if err := i18n.LoadDir(dir); err != nil {
+		slog.Warn("failed to load message catalogs", slog.String("dir", dir), slog.Any("error", err))
+	}
+}
+
+// checkStdinReaders fails when the kubeconfig and values of a command are
+// both read from standard input, which can only be read once.
+func checkStdinReaders(cmd *cobra.Command) error {
+	if settings.KubeConfig != cli.KubeConfigStdin {
+		return nil
+	}
+	// The flags are missing from the commands taking no values.
+	if files, err := cmd.Flags().GetStringSlice("values"); err == nil && slices.ContainsFunc(files, isStdin) {
+		return errors.New("--kubeconfig and --values cannot both be read from standard input")
+	}
+	values, _ := cmd.Flags().GetStringArray("set-file")
+	for _, value := range values {
+		for _, kv := range strings.Split(value, ",") {
+			if _, file, ok := strings.Cut(kv, "="); ok && isStdin(file) {
+				return errors.New("--kubeconfig and --set-file cannot both be read from standard input")
+			}
+		}
+	}
+	return nil
+}
+
+func isStdin(file string) bool {
+	return strings.TrimSpace(file) == "-"
+}
+
 func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, args []string, logSetup func(bool)) (*cobra.Command, error) {
 	cmd := &cobra.Command{
 		Use:          "helm",
 		Short:        "The Helm package manager for Kubernetes.",
 		Long:         globalUsage,
 		SilenceUsage: true,
-		PersistentPreRun: func(_ *cobra.Command, _ []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := checkStdinReaders(cmd); err != nil {
+				return err
+			}
 			if err := startProfiling(); err != nil {
 				log.Printf("Warning: Failed to start profiling: %v", err)
 			}
+			return nil
 		},
 		PersistentPostRun: func(_ *cobra.Command, _ []string) {
 			if err := stopProfiling(); err != nil {
@@ -187,7 +267,9 @@ func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, arg
 	}
 
 	// Configure color output based on ColorMode setting
-	configureColorOutput(settings)
+	configureColorOutput(out, settings)
+
+	configureLocale()
 
 	// Setup shell completion for the color flag
 	_ = cmd.RegisterFlagCompletionFunc("color", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
@@ -226,6 +308,11 @@ func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, arg
 	err = cmd.RegisterFlagCompletionFunc("kube-context", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		cobra.CompDebugln("About to get the different kube-contexts", settings.Debug)
 
+		// Completing must not consume a kubeconfig given on standard input.
+		if settings.KubeConfig == cli.KubeConfigStdin {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
 		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 		if len(settings.KubeConfig) > 0 {
 			loadingRules = &clientcmd.ClientConfigLoadingRules{ExplicitPath: settings.KubeConfig}
@@ -256,11 +343,13 @@ func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, arg
 	cmd.AddCommand(
 		// chart commands
 		newCreateCmd(out),
+		newConvertCmd(out),
 		newDependencyCmd(actionConfig, out),
 		newPullCmd(actionConfig, out),
 		newShowCmd(actionConfig, out),
 		newLintCmd(out),
 		newPackageCmd(out),
+		newCacheCmd(out),
 		newRepoCmd(out),
 		newSearchCmd(out),
 		newVerifyCmd(out),
@@ -271,6 +360,7 @@ func newRootCmdWithConfig(actionConfig *action.Configuration, out io.Writer, arg
 		newInstallCmd(actionConfig, out),
 		newListCmd(actionConfig, out),
 		newReleaseTestCmd(actionConfig, out),
+		newResourcesCmd(actionConfig, out),
 		newRollbackCmd(actionConfig, out),
 		newStatusCmd(actionConfig, out),
 		newTemplateCmd(actionConfig, out),
@@ -343,6 +433,11 @@ func hookOutputWriter(_, _, _ string) io.Writer {
 	return log.Writer()
 }
 
+// printWarning prints the warnings of the actions to stderr as they occur.
+func printWarning(w action.Warning) {
+	fmt.Fprintf(os.Stderr, "WARNING: %s\n", w.Message)
+}
+
 func checkForExpiredRepos(repofile string) {
 
 	expiredRepos := []struct {
@@ -414,6 +509,7 @@ func newDefaultRegistryClient(plainHTTP bool, username, password string) (*regis
 		registry.ClientOptEnableCache(true),
 		registry.ClientOptWriter(os.Stderr),
 		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptRegistriesFile(settings.RegistryMirrorsConfig),
 		registry.ClientOptBasicAuth(username, password),
 	}
 	if plainHTTP {
@@ -447,6 +543,7 @@ func newRegistryClientWithTLS(
 		registry.ClientOptEnableCache(true),
 		registry.ClientOptWriter(os.Stderr),
 		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptRegistriesFile(settings.RegistryMirrorsConfig),
 		registry.ClientOptHTTPClient(&http.Client{
 			Transport: &http.Transport{
 				TLSClientConfig: tlsConf,
@@ -465,3 +562,7 @@ type CommandError struct {
 	error
 	ExitCode int
 }
+
+func (e CommandError) Unwrap() error {
+	return e.error
+}
@@ -24,7 +24,8 @@ import (
 
 const searchDesc = `
 Search provides the ability to search for Helm charts in the various places
-they can be stored including the Artifact Hub and repositories you have added.
+they can be stored including the Artifact Hub, repositories you have added and
+OCI registries.
 Use search subcommands to search different locations for charts.
 `
 
@@ -38,6 +39,7 @@ func newSearchCmd(out io.Writer) *cobra.Command {
 
 	cmd.AddCommand(newSearchHubCmd(out))
 	cmd.AddCommand(newSearchRepoCmd(out))
+	cmd.AddCommand(newSearchRegistryCmd(out))
 
 	return cmd
 }
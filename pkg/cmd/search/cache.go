@@ -0,0 +1,238 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"helm.sh/helm/v4/internal/fileutil"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// cacheFormat is the version of the search cache format. Caches of another
+// version are rebuilt.
+const cacheFormat = 3
+
+// cacheHeader is the beginning of the search cache of a repository. It holds
+// the size and modification time of the index the cache was built from to
+// detect when it is stale.
+//
+// It is followed by a cacheEntry per chart of the index. The entries are read
+// one at a time by each search, so that repositories with indexes much larger
+// than the available memory can be searched.
+type cacheHeader struct {
+	Format       int
+	IndexSize    int64
+	IndexModTime int64
+}
+
+// cacheEntry is a chart in the search cache, with its versions newest first.
+type cacheEntry struct {
+	Name     string
+	Versions repo.ChartVersions
+}
+
+// WriteCache builds the search cache of a repository from its index file.
+// The index is read one chart version at a time.
+func WriteCache(cacheFile, indexFile string) error {
+	f, err := os.Open(indexFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(encodeCache(pw, f, fi, indexFile))
+	}()
+	err = fileutil.AtomicWriteFile(cacheFile, pr, 0644)
+	// Stop the encoding when the cache could not be written.
+	pr.Close()
+	return err
+}
+
+// encodeCache encodes the search cache of the index read from r.
+func encodeCache(w io.Writer, r io.Reader, fi os.FileInfo, source string) error {
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(&cacheHeader{
+		Format:       cacheFormat,
+		IndexSize:    fi.Size(),
+		IndexModTime: fi.ModTime().UnixNano(),
+	}); err != nil {
+		return fmt.Errorf("cannot encode search cache: %w", err)
+	}
+	return streamCharts(r, source, func(name string, versions repo.ChartVersions) error {
+		for i, cv := range versions {
+			// Dependencies are not searched, and their import values cannot
+			// be encoded.
+			md := *cv.Metadata
+			md.Dependencies = nil
+			scv := *cv
+			scv.Metadata = &md
+			versions[i] = &scv
+		}
+		if err := enc.Encode(&cacheEntry{Name: name, Versions: versions}); err != nil {
+			return fmt.Errorf("cannot encode search cache: %w", err)
+		}
+		return nil
+	})
+}
+
+// streamCharts reads an index one chart at a time, and calls fn with the
+// versions of each chart, newest first. The versions of a chart are expected
+// to be next to each other in the index, as they are in the map of its
+// entries.
+func streamCharts(r io.Reader, source string, fn func(name string, versions repo.ChartVersions) error) error {
+	var name string
+	var versions repo.ChartVersions
+	flush := func() error {
+		if len(versions) == 0 {
+			return nil
+		}
+		sort.Sort(sort.Reverse(versions))
+		return fn(name, versions)
+	}
+	_, err := repo.StreamIndex(r, source, func(n string, cv *repo.ChartVersion) error {
+		if n != name {
+			if err := flush(); err != nil {
+				return err
+			}
+			name, versions = n, nil
+		}
+		versions = append(versions, cv)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// cacheReader reads the charts of the search cache of a repository one at a
+// time.
+type cacheReader struct {
+	f   *os.File
+	dec *gob.Decoder
+}
+
+// openCache opens the search cache of a repository. It returns false when the
+// cache is missing, corrupt or stale.
+func openCache(cacheFile string, fi os.FileInfo) (*cacheReader, bool) {
+	f, err := os.Open(cacheFile)
+	if err != nil {
+		return nil, false
+	}
+	dec := gob.NewDecoder(f)
+	var h cacheHeader
+	if err := dec.Decode(&h); err != nil || h.Format != cacheFormat || h.IndexSize != fi.Size() || h.IndexModTime != fi.ModTime().UnixNano() {
+		f.Close()
+		return nil, false
+	}
+	return &cacheReader{f: f, dec: dec}, true
+}
+
+// next returns the next chart of the cache, or io.EOF when there is none.
+func (c *cacheReader) next() (*cacheEntry, error) {
+	var e cacheEntry
+	if err := c.dec.Decode(&e); err != nil {
+		return nil, err
+	}
+	for _, cv := range e.Versions {
+		if cv.Metadata == nil {
+			cv.Metadata = &chart.Metadata{}
+		}
+	}
+	return &e, nil
+}
+
+func (c *cacheReader) Close() error {
+	return c.f.Close()
+}
+
+// cachedRepo is a repository searched from its search cache.
+type cachedRepo struct {
+	name, cacheFile, indexFile string
+	all                        bool
+}
+
+// each calls fn with the versions of each chart of the repository. The index
+// file is read instead of the cache when it changed since the cache was built.
+func (r cachedRepo) each(fn func(name string, versions repo.ChartVersions)) error {
+	fi, err := os.Stat(r.indexFile)
+	if err != nil {
+		return err
+	}
+	c, ok := openCache(r.cacheFile, fi)
+	if !ok {
+		f, err := os.Open(r.indexFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return streamCharts(f, r.indexFile, func(name string, versions repo.ChartVersions) error {
+			fn(name, versions)
+			return nil
+		})
+	}
+	defer c.Close()
+	for {
+		e, err := c.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(e.Name, e.Versions)
+	}
+}
+
+// LoadRepo adds a repository to the search index from its search cache, which
+// is read by each search instead of being loaded. When the cache is missing
+// or older than the index file, the charts of the index file are added
+// instead. The cache is rebuilt by WriteCache when the index is downloaded.
+func (i *Index) LoadRepo(rname, indexFile, cacheFile string, all bool) error {
+	fi, err := os.Stat(indexFile)
+	if err != nil {
+		return err
+	}
+	if c, ok := openCache(cacheFile, fi); ok {
+		c.Close()
+		i.caches = append(i.caches, cachedRepo{name: rname, cacheFile: cacheFile, indexFile: indexFile, all: all})
+		return nil
+	}
+
+	f, err := os.Open(indexFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return streamCharts(f, indexFile, func(name string, versions repo.ChartVersions) error {
+		i.addChart(rname, name, versions, all)
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	indexFile := filepath.Join(dir, "testing-index.yaml")
+	cacheFile := filepath.Join(dir, "testing-search.bin")
+
+	ind := repo.NewIndexFile()
+	for _, versions := range indexfileEntries {
+		for _, cv := range versions {
+			md := *cv.Metadata
+			ind.MustAdd(&md, "chart.tgz", "http://example.com/charts", "sha256:1234")
+		}
+	}
+	// Import values cannot be encoded, and dependencies are not searched.
+	ind.Entries["niña"][0].Dependencies = []*chart.Dependency{{Name: "dep", ImportValues: []interface{}{map[string]interface{}{"child": "a", "parent": "b"}}}}
+	if err := ind.WriteFile(indexFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteCache(cacheFile, indexFile); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := openCache(cacheFile, fi)
+	if !ok {
+		t.Fatal("expected the search cache to be used")
+	}
+	cached := map[string]repo.ChartVersions{}
+	for {
+		e, err := c.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		cached[e.Name] = e.Versions
+	}
+	c.Close()
+	if len(cached["santa-maria"]) != 2 || cached["santa-maria"][0].Version != "1.2.3" {
+		t.Errorf("expected the versions of santa-maria newest first, got %v", cached["santa-maria"])
+	}
+
+	// The cache is searched without being loaded.
+	fromCache := NewIndex()
+	if err := fromCache.LoadRepo("testing", indexFile, cacheFile, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromCache.lines) != 0 || len(fromCache.caches) != 1 {
+		t.Errorf("expected the search cache not to be loaded, got %d charts", len(fromCache.lines))
+	}
+	fromIndex := NewIndex()
+	fromIndex.AddRepo("testing", ind, true)
+	for _, term := range []string{"boat", "santa", "1.2.2"} {
+		expected := fromIndex.SearchLiteral(term, 100)
+		got := fromCache.SearchLiteral(term, 100)
+		SortScore(expected)
+		SortScore(got)
+		if len(got) != len(expected) {
+			t.Fatalf("expected %d results for %q, got %d", len(expected), term, len(got))
+		}
+		for j := range expected {
+			if got[j].Name != expected[j].Name || got[j].Score != expected[j].Score || got[j].Chart.Version != expected[j].Chart.Version {
+				t.Errorf("expected %+v for %q, got %+v", expected[j], term, got[j])
+			}
+		}
+	}
+	if len(fromCache.All()) != 4 {
+		t.Errorf("expected 4 chart versions, got %d", len(fromCache.All()))
+	}
+
+	// A cache older than the index is stale.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(indexFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err = os.Stat(indexFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := openCache(cacheFile, fi); ok {
+		t.Error("expected a stale search cache not to be used")
+	}
+	if len(fromCache.All()) != 4 {
+		t.Errorf("expected the index to be searched instead of the stale cache, got %d chart versions", len(fromCache.All()))
+	}
+	i := NewIndex()
+	if err := i.LoadRepo("testing", indexFile, cacheFile, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(i.lines) != 3 || len(i.All()) != 3 {
+		t.Errorf("expected the 3 charts of the index to be added, got %d", len(i.All()))
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// LicenseAnnotation is the chart annotation holding the SPDX license
+// expression of a chart.
+const LicenseAnnotation = chartutil.LicenseAnnotation
+
+// Filter selects search results by the metadata of their charts. A chart
+// matches when it matches every value of every field; empty fields match all
+// charts. Names, emails, keywords and licenses are compared case-insensitively.
+type Filter struct {
+	// Maintainers are names or emails of maintainers of the chart.
+	Maintainers []string
+	// Keywords are keywords of the chart.
+	Keywords []string
+	// Annotations are annotations of the chart. An empty value matches any
+	// value of the annotation.
+	Annotations map[string]string
+	// Licenses are SPDX license identifiers in the license expression of
+	// the chart, found in its LicenseAnnotation annotation.
+	Licenses []string
+}
+
+// ParseAnnotationFilters parses annotation filters in the form "key=value" or
+// "key".
+func ParseAnnotationFilters(filters []string) (map[string]string, error) {
+	annotations := map[string]string{}
+	for _, f := range filters {
+		key, value, _ := strings.Cut(f, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid annotation filter %q, expected key=value", f)
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// IsZero returns true if the filter matches all charts.
+func (f Filter) IsZero() bool {
+	return len(f.Maintainers) == 0 && len(f.Keywords) == 0 && len(f.Annotations) == 0 && len(f.Licenses) == 0
+}
+
+// Match returns true if the chart matches the filter.
+func (f Filter) Match(cv *repo.ChartVersion) bool {
+	if cv == nil || cv.Metadata == nil {
+		return f.IsZero()
+	}
+	for _, m := range f.Maintainers {
+		if !slices.ContainsFunc(cv.Maintainers, func(cm *chart.Maintainer) bool {
+			return cm != nil && (strings.EqualFold(cm.Name, m) || strings.EqualFold(cm.Email, m))
+		}) {
+			return false
+		}
+	}
+	for _, k := range f.Keywords {
+		if !slices.ContainsFunc(cv.Keywords, func(ck string) bool { return strings.EqualFold(ck, k) }) {
+			return false
+		}
+	}
+	for k, v := range f.Annotations {
+		cvv, ok := cv.Annotations[k]
+		if !ok || (v != "" && cvv != v) {
+			return false
+		}
+	}
+	licenses := chartutil.LicenseIDs(cv.Annotations[LicenseAnnotation])
+	for _, l := range f.Licenses {
+		if !slices.ContainsFunc(licenses, func(cl string) bool { return strings.EqualFold(cl, l) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterResults returns the results whose charts match the filter.
+func FilterResults(res []*Result, f Filter) []*Result {
+	if f.IsZero() {
+		return res
+	}
+	out := make([]*Result, 0, len(res))
+	for _, r := range res {
+		if f.Match(r.Chart) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Sort orders of search results.
+const (
+	// SortByScore sorts results by score, then by name and newest version.
+	SortByScore = "score"
+	// SortByName sorts results by name, then by newest version.
+	SortByName = "name"
+	// SortByCreated sorts results by the time their chart was created,
+	// newest first.
+	SortByCreated = "created"
+)
+
+// SortOrders are the orders results can be sorted in.
+var SortOrders = []string{SortByScore, SortByName, SortByCreated}
+
+// SortBy does an in-place sort of the results in the given order. The order
+// is reversed if reverse is true.
+func SortBy(r []*Result, order string, reverse bool) error {
+	var less func(a, b int) bool
+	switch order {
+	case SortByScore, "":
+		less = scoreSorter(r).Less
+	case SortByName:
+		less = func(a, b int) bool {
+			if r[a].Name == r[b].Name {
+				return scoreSorter(r).Less(a, b)
+			}
+			return r[a].Name < r[b].Name
+		}
+	case SortByCreated:
+		less = func(a, b int) bool {
+			if r[a].Chart.Created.Equal(r[b].Chart.Created) {
+				return r[a].Name < r[b].Name
+			}
+			return r[a].Chart.Created.After(r[b].Chart.Created)
+		}
+	default:
+		return fmt.Errorf("invalid sort order %q, valid orders are %s", order, strings.Join(SortOrders, ", "))
+	}
+	sort.SliceStable(r, less)
+	if reverse {
+		slices.Reverse(r)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package search
+
+import (
+	"testing"
+	"time"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+func TestFilterMatch(t *testing.T) {
+	cv := &repo.ChartVersion{Metadata: &chart.Metadata{
+		Name:        "db",
+		Keywords:    []string{"database", "sql"},
+		Maintainers: []*chart.Maintainer{{Name: "Platform", Email: "platform@example.com"}},
+		Annotations: map[string]string{
+			LicenseAnnotation:  "(MIT OR Apache-2.0)",
+			"example.com/tier": "gold",
+		},
+	}}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		expect bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"maintainer name", Filter{Maintainers: []string{"platform"}}, true},
+		{"maintainer email", Filter{Maintainers: []string{"platform@example.com"}}, true},
+		{"other maintainer", Filter{Maintainers: []string{"someone"}}, false},
+		{"keywords", Filter{Keywords: []string{"SQL", "database"}}, true},
+		{"missing keyword", Filter{Keywords: []string{"sql", "cache"}}, false},
+		{"annotation value", Filter{Annotations: map[string]string{"example.com/tier": "gold"}}, true},
+		{"other annotation value", Filter{Annotations: map[string]string{"example.com/tier": "silver"}}, false},
+		{"annotation key", Filter{Annotations: map[string]string{"example.com/tier": ""}}, true},
+		{"missing annotation", Filter{Annotations: map[string]string{"example.com/owner": ""}}, false},
+		{"license in expression", Filter{Licenses: []string{"apache-2.0"}}, true},
+		{"other license", Filter{Licenses: []string{"GPL-3.0"}}, false},
+		{"all fields", Filter{
+			Maintainers: []string{"Platform"},
+			Keywords:    []string{"sql"},
+			Annotations: map[string]string{"example.com/tier": "gold"},
+			Licenses:    []string{"MIT"},
+		}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(cv); got != tt.expect {
+			t.Errorf("%s: expected %t, got %t", tt.name, tt.expect, got)
+		}
+	}
+}
+
+func TestParseAnnotationFilters(t *testing.T) {
+	annotations, err := ParseAnnotationFilters([]string{"a=b", "c", "d=e=f"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{"a": "b", "c": "", "d": "e=f"}
+	for k, v := range expect {
+		if annotations[k] != v {
+			t.Errorf("expected %q for %s, got %q", v, k, annotations[k])
+		}
+	}
+	if _, err := ParseAnnotationFilters([]string{"=b"}); err == nil {
+		t.Error("expected an error for an annotation filter without a key")
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	res := func() []*Result {
+		return []*Result{
+			{Name: "b", Score: 0, Chart: &repo.ChartVersion{Metadata: &chart.Metadata{Version: "1.0.0"}, Created: day(1)}},
+			{Name: "c", Score: 1, Chart: &repo.ChartVersion{Metadata: &chart.Metadata{Version: "1.0.0"}, Created: day(3)}},
+			{Name: "a", Score: 2, Chart: &repo.ChartVersion{Metadata: &chart.Metadata{Version: "1.0.0"}, Created: day(2)}},
+		}
+	}
+
+	tests := []struct {
+		order   string
+		reverse bool
+		expect  string
+	}{
+		{SortByScore, false, "bca"},
+		{SortByScore, true, "acb"},
+		{SortByName, false, "abc"},
+		{SortByCreated, false, "cab"},
+		{SortByCreated, true, "bac"},
+	}
+	for _, tt := range tests {
+		r := res()
+		if err := SortBy(r, tt.order, tt.reverse); err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, rr := range r {
+			got += rr.Name
+		}
+		if got != tt.expect {
+			t.Errorf("%s (reverse %t): expected %s, got %s", tt.order, tt.reverse, tt.expect, got)
+		}
+	}
+
+	if err := SortBy(res(), "size", false); err == nil {
+		t.Error("expected an error for an invalid sort order")
+	}
+}
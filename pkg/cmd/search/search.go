@@ -17,14 +17,15 @@ limitations under the License.
 /*
 Package search provides client-side repository searching.
 
-This supports building an in-memory search index based on the contents of
-multiple repositories, and then using string matching or regular expressions
-to find matches.
+This supports building a search index based on the contents of multiple
+repositories, and then using string matching or regular expressions to find
+matches. Repositories with a search cache are read from it one chart at a time
+by each search instead of being held in memory.
 */
 package search
 
 import (
-	"path"
+	"log/slog"
 	"regexp"
 	"sort"
 	"strings"
@@ -48,6 +49,10 @@ type Result struct {
 type Index struct {
 	lines  map[string]string
 	charts map[string]*repo.ChartVersion
+	// caches are the repositories loaded from their search caches, which are
+	// read one chart at a time by each search instead of being held in
+	// memory.
+	caches []cachedRepo
 }
 
 const sep = "\v"
@@ -64,28 +69,56 @@ const verSep = "$$"
 func (i *Index) AddRepo(rname string, ind *repo.IndexFile, all bool) {
 	ind.SortEntries()
 	for name, ref := range ind.Entries {
-		if len(ref) == 0 {
-			// Skip chart names that have zero releases.
-			continue
-		}
-		// By convention, an index file is supposed to have the newest at the
-		// 0 slot, so our best bet is to grab the 0 entry and build the index
-		// entry off of that.
-		// Note: Do not use filePath.Join since on Windows it will return \
-		//       which results in a repo name that cannot be understood.
-		fname := path.Join(rname, name)
-		if !all {
-			i.lines[fname] = indstr(rname, ref[0])
-			i.charts[fname] = ref[0]
-			continue
-		}
+		i.addChart(rname, name, ref, all)
+	}
+}
+
+// addChart adds the versions of a chart, newest first, to the index.
+func (i *Index) addChart(rname, name string, ref repo.ChartVersions, all bool) {
+	eachVersion(rname, name, ref, all, func(key, line string, cv *repo.ChartVersion) {
+		i.lines[key] = line
+		i.charts[key] = cv
+	})
+}
+
+// eachVersion calls fn with the key, the searched line and the chart version
+// of the versions of a chart that are searched.
+func eachVersion(rname, name string, ref repo.ChartVersions, all bool, fn func(key, line string, cv *repo.ChartVersion)) {
+	if len(ref) == 0 {
+		// Skip chart names that have zero releases.
+		return
+	}
+	// By convention, an index file is supposed to have the newest at the
+	// 0 slot, so our best bet is to grab the 0 entry and build the index
+	// entry off of that.
+	// Note: Do not use filePath.Join since on Windows it will return \
+	//       which results in a repo name that cannot be understood, nor
+	//       path.Join, which would turn the oci:// of registries into oci:/.
+	fname := rname + "/" + name
+	if !all {
+		fn(fname, indstr(rname, ref[0]), ref[0])
+		return
+	}
+
+	// If 'all' is set, then we go through all of the refs, and add them all
+	// to the index. This will generate a lot of near-duplicate entries.
+	for _, rr := range ref {
+		fn(fname+verSep+rr.Version, indstr(rname, rr), rr)
+	}
+}
 
-		// If 'all' is set, then we go through all of the refs, and add them all
-		// to the index. This will generate a lot of near-duplicate entries.
-		for _, rr := range ref {
-			versionedName := fname + verSep + rr.Version
-			i.lines[versionedName] = indstr(rname, rr)
-			i.charts[versionedName] = rr
+// each calls fn with the key, the searched line and the chart version of all
+// the charts in the index.
+func (i *Index) each(fn func(key, line string, cv *repo.ChartVersion)) {
+	for k, v := range i.lines {
+		fn(k, v, i.charts[k])
+	}
+	for _, c := range i.caches {
+		err := c.each(func(name string, versions repo.ChartVersions) {
+			eachVersion(c.name, name, versions, c.all, fn)
+		})
+		if err != nil {
+			slog.Warn("repo search cache is corrupt", "repo", c.name, slog.Any("error", err))
 		}
 	}
 }
@@ -94,16 +127,14 @@ func (i *Index) AddRepo(rname string, ind *repo.IndexFile, all bool) {
 //
 // Each will be given a score of 0.
 func (i *Index) All() []*Result {
-	res := make([]*Result, len(i.charts))
-	j := 0
-	for name, ch := range i.charts {
+	res := make([]*Result, 0, len(i.charts))
+	i.each(func(name, _ string, ch *repo.ChartVersion) {
 		parts := strings.Split(name, verSep)
-		res[j] = &Result{
+		res = append(res, &Result{
 			Name:  parts[0],
 			Chart: ch,
-		}
-		j++
-	}
+		})
+	})
 	return res
 }
 
@@ -146,14 +177,14 @@ func (i *Index) calcScore(index int, matchline string) int {
 func (i *Index) SearchLiteral(term string, threshold int) []*Result {
 	term = strings.ToLower(term)
 	buf := []*Result{}
-	for k, v := range i.lines {
+	i.each(func(k, v string, cv *repo.ChartVersion) {
 		lv := strings.ToLower(v)
 		res := strings.Index(lv, term)
 		if score := i.calcScore(res, lv); res != -1 && score < threshold {
 			parts := strings.Split(k, verSep) // Remove version, if it is there.
-			buf = append(buf, &Result{Name: parts[0], Score: score, Chart: i.charts[k]})
+			buf = append(buf, &Result{Name: parts[0], Score: score, Chart: cv})
 		}
-	}
+	})
 	return buf
 }
 
@@ -164,16 +195,16 @@ func (i *Index) SearchRegexp(re string, threshold int) ([]*Result, error) {
 		return []*Result{}, err
 	}
 	buf := []*Result{}
-	for k, v := range i.lines {
+	i.each(func(k, v string, cv *repo.ChartVersion) {
 		ind := matcher.FindStringIndex(v)
 		if len(ind) == 0 {
-			continue
+			return
 		}
 		if score := i.calcScore(ind[0], v); ind[0] >= 0 && score < threshold {
 			parts := strings.Split(k, verSep) // Remove version, if it is there.
-			buf = append(buf, &Result{Name: parts[0], Score: score, Chart: i.charts[k]})
+			buf = append(buf, &Result{Name: parts[0], Score: score, Chart: cv})
 		}
-	}
+	})
 	return buf, nil
 }
 
@@ -0,0 +1,139 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v4/pkg/cmd/require"
+	"helm.sh/helm/v4/pkg/cmd/search"
+	"helm.sh/helm/v4/pkg/registry"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+const searchRegistryDesc = `
+Search the charts of an OCI registry, under a namespace such as
+oci://ghcr.io/org/charts, or in the whole registry.
+
+The repositories are listed with the catalog API of the registry, which some
+public registries only offer to their administrators, and the metadata of the
+charts is read from their manifests, without pulling them. The charts are
+named by their references, so they can be installed as they are found.
+
+It will display the latest stable versions of the charts found. If you
+specify the --devel flag, the output will include pre-release versions.
+If you want to search using a version constraint, use --version.
+
+Examples:
+
+    # Search the charts of a namespace for the keyword "nginx"
+    $ helm search registry oci://registry.example.com/charts nginx
+
+    # List all the versions of all the charts of a registry
+    $ helm search registry oci://registry.example.com --versions
+`
+
+type searchRegistryOptions struct {
+	searchRepoOptions
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+	plainHTTP             bool
+	username              string
+	password              string
+}
+
+func newSearchRegistryCmd(out io.Writer) *cobra.Command {
+	o := &searchRegistryOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "registry [oci://registry/namespace] [keyword]",
+		Short: "search an OCI registry for a keyword in charts",
+		Long:  searchRegistryDesc,
+		Args:  require.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return o.run(out, args[0], args[1:])
+		},
+	}
+
+	o.bindFlags(cmd)
+	f := cmd.Flags()
+	f.StringVar(&o.certFile, "cert-file", "", "identify registry client using this SSL certificate file")
+	f.StringVar(&o.keyFile, "key-file", "", "identify registry client using this SSL key file")
+	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the registry")
+	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the registry")
+	f.StringVar(&o.username, "username", "", "registry username")
+	f.StringVar(&o.password, "password", "", "registry password")
+
+	return cmd
+}
+
+func (o *searchRegistryOptions) run(out io.Writer, namespace string, args []string) error {
+	if !registry.IsOCI(namespace) {
+		return fmt.Errorf("invalid registry %q, expected an oci:// reference", namespace)
+	}
+	o.setupSearchedVersion()
+
+	registryClient, err := newRegistryClient(
+		o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify, o.plainHTTP, o.username, o.password,
+	)
+	if err != nil {
+		return fmt.Errorf("missing registry client: %w", err)
+	}
+	index, err := o.buildIndex(registryClient, namespace)
+	if err != nil {
+		return err
+	}
+	return o.searchIndex(out, index, args)
+}
+
+// buildIndex indexes the charts of a namespace of a registry, named by the
+// references of their repositories.
+func (o *searchRegistryOptions) buildIndex(client *registry.Client, namespace string) (*search.Index, error) {
+	constraint, err := semver.NewConstraint(o.version)
+	if err != nil {
+		return nil, fmt.Errorf("an invalid version/constraint format: %w", err)
+	}
+	listings, err := client.ListCharts(namespace,
+		registry.ListChartsOptVersion(constraint),
+		registry.ListChartsOptAllVersions(o.versions))
+	if err != nil {
+		return nil, err
+	}
+
+	// The results are named after the namespace and the path of their
+	// repository in it, which make up the reference of the repository.
+	base := strings.TrimSuffix(strings.TrimPrefix(namespace, fmt.Sprintf("%s://", registry.OCIScheme)), "/")
+	ind := repo.NewIndexFile()
+	for _, l := range listings {
+		name := strings.TrimPrefix(l.Repository, base+"/")
+		ind.Entries[name] = append(ind.Entries[name], &repo.ChartVersion{
+			Metadata: l.Metadata,
+			URLs:     []string{fmt.Sprintf("%s://%s", registry.OCIScheme, l.Ref)},
+		})
+	}
+	i := search.NewIndex()
+	i.AddRepo(fmt.Sprintf("%s://%s", registry.OCIScheme, base), ind, true)
+	return i, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/repo/v1/repotest"
+)
+
+func TestSearchRegistryCmd(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/testcharts/oci-dependent-chart-0.1.0.tgz"),
+	)
+	defer srv.Stop()
+
+	ociSrv, err := repotest.NewOCIServer(t, srv.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ociSrv.Run(t)
+
+	flags := fmt.Sprintf("--registry-config %s --plain-http --max-col-width 200", filepath.Join(srv.Root(), "config.json"))
+	namespace := fmt.Sprintf("oci://%s/u/ocitestuser", ociSrv.RegistryURL)
+
+	tests := []struct {
+		name      string
+		args      string
+		expect    string
+		wantError bool
+	}{
+		{
+			name:   "search a namespace",
+			args:   namespace,
+			expect: namespace + "/oci-dependent-chart\t0.1.0",
+		},
+		{
+			name:   "search a namespace for a keyword",
+			args:   namespace + " dependent",
+			expect: namespace + "/oci-dependent-chart\t0.1.0",
+		},
+		{
+			name:   "search a namespace for another keyword",
+			args:   namespace + " syzygy",
+			expect: "No results found",
+		},
+		{
+			name:   "search a namespace with a version constraint",
+			args:   namespace + " --version '>=1.0.0'",
+			expect: "No results found",
+		},
+		{
+			name:      "search a registry without the oci scheme",
+			args:      ociSrv.RegistryURL,
+			wantError: true,
+			expect:    "expected an oci:// reference",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out, err := executeActionCommand(fmt.Sprintf("search registry %s %s", tt.args, flags))
+			if tt.wantError {
+				if err == nil || !strings.Contains(err.Error(), tt.expect) {
+					t.Errorf("expected error %q, got %v", tt.expect, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The table pads its columns with spaces
+			if got := strings.Join(strings.Fields(out), "\t"); !strings.Contains(got, strings.Join(strings.Fields(tt.expect), "\t")) {
+				t.Errorf("expected %q in the output, got %q", tt.expect, out)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -40,7 +41,8 @@ import (
 const searchRepoDesc = `
 Search reads through all of the repositories configured on the system, and
 looks for matches. Search of these repositories uses the metadata stored on
-the system.
+the system. 'helm repo add' and 'helm repo update' also store a search cache
+for each repository, so searching many large repositories stays fast.
 
 It will display the latest stable versions of the charts found. If you
 specify the --devel flag, the output will include pre-release versions.
@@ -57,6 +59,18 @@ Examples:
     # Search for the latest stable release for nginx-ingress with a major version of 1
     $ helm search repo nginx-ingress --version ^1.0.0
 
+Results can be filtered by the metadata of the charts, and sorted by name or
+by creation time instead of relevance:
+
+    # Search for charts maintained by the platform team with the keyword "database"
+    $ helm search repo --maintainer platform@example.com --keyword database
+
+    # Search for Apache-2.0 licensed charts, newest first
+    $ helm search repo --license Apache-2.0 --sort-by created
+
+The JSON and YAML output also holds the icon and the annotations of the charts,
+and the digests and provenance files recorded in the repository index.
+
 Repositories are managed with 'helm repo' commands.
 `
 
@@ -73,6 +87,12 @@ type searchRepoOptions struct {
 	repoCacheDir   string
 	outputFormat   output.Format
 	failOnNoResult bool
+	maintainers    []string
+	keywords       []string
+	annotations    []string
+	licenses       []string
+	sortBy         string
+	reverse        bool
 }
 
 func newSearchRepoCmd(out io.Writer) *cobra.Command {
@@ -89,17 +109,36 @@ func newSearchRepoCmd(out io.Writer) *cobra.Command {
 		},
 	}
 
+	o.bindFlags(cmd)
+
+	return cmd
+}
+
+// bindFlags binds the flags of the searches of indexes, shared by the
+// searches of repositories and registries.
+func (o *searchRepoOptions) bindFlags(cmd *cobra.Command) {
 	f := cmd.Flags()
-	f.BoolVarP(&o.regexp, "regexp", "r", false, "use regular expressions for searching repositories you have added")
-	f.BoolVarP(&o.versions, "versions", "l", false, "show the long listing, with each version of each chart on its own line, for repositories you have added")
+	f.BoolVarP(&o.regexp, "regexp", "r", false, "use regular expressions for searching")
+	f.BoolVarP(&o.versions, "versions", "l", false, "show the long listing, with each version of each chart on its own line")
 	f.BoolVar(&o.devel, "devel", false, "use development versions (alpha, beta, and release candidate releases), too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
-	f.StringVar(&o.version, "version", "", "search using semantic versioning constraints on repositories you have added")
+	f.StringVar(&o.version, "version", "", "search using semantic versioning constraints")
 	f.UintVar(&o.maxColWidth, "max-col-width", 50, "maximum column width for output table")
 	f.BoolVar(&o.failOnNoResult, "fail-on-no-result", false, "search fails if no results are found")
+	f.StringArrayVar(&o.maintainers, "maintainer", nil, "only show charts maintained by this name or email (can specify multiple)")
+	f.StringArrayVar(&o.keywords, "keyword", nil, "only show charts with this keyword (can specify multiple)")
+	f.StringArrayVar(&o.annotations, "annotation", nil, "only show charts with this annotation, in the form key=value or key (can specify multiple)")
+	f.StringArrayVar(&o.licenses, "license", nil, "only show charts under this SPDX license, read from the "+search.LicenseAnnotation+" annotation (can specify multiple)")
+	f.StringVar(&o.sortBy, "sort-by", search.SortByScore, fmt.Sprintf("sort results by one of %s", strings.Join(search.SortOrders, ", ")))
+	f.BoolVar(&o.reverse, "reverse", false, "reverse the order of the results")
 
 	bindOutputFlag(cmd, &o.outputFormat)
 
-	return cmd
+	err := cmd.RegisterFlagCompletionFunc("sort-by", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return search.SortOrders, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func (o *searchRepoOptions) run(out io.Writer, args []string) error {
@@ -109,8 +148,14 @@ func (o *searchRepoOptions) run(out io.Writer, args []string) error {
 	if err != nil {
 		return err
 	}
+	return o.searchIndex(out, index, args)
+}
 
+// searchIndex searches an index for the keyword of the arguments, and writes
+// the results matching the filters and the version constraint.
+func (o *searchRepoOptions) searchIndex(out io.Writer, index *search.Index, args []string) error {
 	var res []*search.Result
+	var err error
 	if len(args) == 0 {
 		res = index.All()
 	} else {
@@ -121,11 +166,27 @@ func (o *searchRepoOptions) run(out io.Writer, args []string) error {
 		}
 	}
 
+	annotations, err := search.ParseAnnotationFilters(o.annotations)
+	if err != nil {
+		return err
+	}
+	res = search.FilterResults(res, search.Filter{
+		Maintainers: o.maintainers,
+		Keywords:    o.keywords,
+		Annotations: annotations,
+		Licenses:    o.licenses,
+	})
+
 	search.SortScore(res)
 	data, err := o.applyConstraint(res)
 	if err != nil {
 		return err
 	}
+	if o.sortBy != search.SortByScore || o.reverse {
+		if err := search.SortBy(data, o.sortBy, o.reverse); err != nil {
+			return err
+		}
+	}
 
 	return o.outputFormat.Write(out, &repoSearchWriter{data, o.maxColWidth, o.failOnNoResult})
 }
@@ -188,22 +249,25 @@ func (o *searchRepoOptions) buildIndex() (*search.Index, error) {
 	for _, re := range rf.Repositories {
 		n := re.Name
 		f := filepath.Join(o.repoCacheDir, helmpath.CacheIndexFile(n))
-		ind, err := repo.LoadIndexFile(f)
-		if err != nil {
+		cf := filepath.Join(o.repoCacheDir, helmpath.CacheSearchFile(n))
+		if err := i.LoadRepo(n, f, cf, o.versions || len(o.version) > 0); err != nil {
 			slog.Warn("repo is corrupt or missing", "repo", n, slog.Any("error", err))
-			continue
 		}
-
-		i.AddRepo(n, ind, o.versions || len(o.version) > 0)
 	}
 	return i, nil
 }
 
 type repoChartElement struct {
-	Name        string `json:"name"`
-	Version     string `json:"version"`
-	AppVersion  string `json:"app_version"`
-	Description string `json:"description"`
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	AppVersion  string            `json:"app_version"`
+	Description string            `json:"description"`
+	Icon        string            `json:"icon,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Signed      bool              `json:"signed,omitempty"`
+	Provenance  string            `json:"provenance,omitempty"`
+	Digest      string            `json:"digest,omitempty"`
+	Digests     map[string]string `json:"digests,omitempty"`
 }
 
 type repoSearchWriter struct {
@@ -252,7 +316,18 @@ func (r *repoSearchWriter) encodeByFormat(out io.Writer, format output.Format) e
 	chartList := make([]repoChartElement, 0, len(r.results))
 
 	for _, r := range r.results {
-		chartList = append(chartList, repoChartElement{r.Name, r.Chart.Version, r.Chart.AppVersion, r.Chart.Description})
+		chartList = append(chartList, repoChartElement{
+			Name:        r.Name,
+			Version:     r.Chart.Version,
+			AppVersion:  r.Chart.AppVersion,
+			Description: r.Chart.Description,
+			Icon:        r.Chart.Icon,
+			Annotations: r.Chart.Annotations,
+			Signed:      r.Chart.Signed(),
+			Provenance:  r.Chart.Provenance,
+			Digest:      r.Chart.Digest,
+			Digests:     r.Chart.Digests,
+		})
 	}
 
 	switch format {
@@ -17,7 +17,14 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
 	"testing"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/cmd/search"
+	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
 func TestSearchRepositoriesCmd(t *testing.T) {
@@ -86,6 +93,19 @@ func TestSearchRepositoriesCmd(t *testing.T) {
 		name:   "search for 'alpine', expect valid yaml output",
 		cmd:    "search repo alpine --output yaml",
 		golden: "output/search-output-yaml.txt",
+	}, {
+		name:   "search for 'alpine' with a keyword filter, expect no matches",
+		cmd:    "search repo alpine --keyword database",
+		golden: "output/search-not-found.txt",
+	}, {
+		name:   "search all charts sorted by creation time",
+		cmd:    "search repo --sort-by created",
+		golden: "output/search-sort-by-created.txt",
+	}, {
+		name:      "search with an invalid sort order, expect failure",
+		cmd:       "search repo alpine --sort-by size",
+		golden:    "output/search-invalid-sort-by.txt",
+		wantError: true,
 	}}
 
 	settings.Debug = true
@@ -105,3 +125,40 @@ func TestSearchRepoOutputCompletion(t *testing.T) {
 func TestSearchRepoFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "search repo", true) // File completion may be useful when inputting a keyword
 }
+
+func TestSearchRepoOutputIndexEntry(t *testing.T) {
+	cv := &repo.ChartVersion{
+		Metadata: &chart.Metadata{
+			Name:        "signtest",
+			Version:     "0.1.0",
+			Icon:        "https://example.com/icon.png",
+			Annotations: map[string]string{"category": "test"},
+		},
+		Digest:     "abc",
+		Provenance: "https://example.com/charts/signtest-0.1.0.tgz.prov",
+		Digests:    map[string]string{repo.DigestChart: "sha256:abc", repo.DigestProvenance: "sha256:def"},
+	}
+	w := &repoSearchWriter{results: []*search.Result{{Name: "testing/signtest", Chart: cv}}}
+	var out bytes.Buffer
+	if err := w.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	var charts []repoChartElement
+	if err := json.Unmarshal(out.Bytes(), &charts); err != nil {
+		t.Fatal(err)
+	}
+	expected := repoChartElement{
+		Name:        "testing/signtest",
+		Version:     "0.1.0",
+		Icon:        cv.Icon,
+		Annotations: cv.Annotations,
+		Signed:      true,
+		Provenance:  cv.Provenance,
+		Digest:      "abc",
+		Digests:     cv.Digests,
+	}
+	if len(charts) != 1 || !reflect.DeepEqual(charts[0], expected) {
+		t.Errorf("expected %+v, got %+v", expected, charts)
+	}
+}
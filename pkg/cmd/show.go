@@ -30,6 +30,11 @@ import (
 
 const showDesc = `
 This command consists of multiple subcommands to display information about a chart
+
+'helm show chart', 'helm show values' and 'helm show readme' read what they
+display without downloading the whole chart when they can: from the config and
+separate layers of charts in OCI registries, or from the beginning of the
+chart archive of charts in HTTP repositories.
 `
 
 const showAllDesc = `
@@ -44,7 +49,8 @@ of the values.yaml file
 
 const showChartDesc = `
 This command inspects a chart (directory, file, or URL) and displays the contents
-of the Chart.yaml file
+of the Chart.yaml file. For charts of a repository, the digests and provenance
+file recorded in the repository index follow as comments.
 `
 
 const readmeChartDesc = `
@@ -52,6 +58,15 @@ This command inspects a chart (directory, file, or URL) and displays the content
 of the README file
 `
 
+const showLicensesDesc = `
+This command inspects a chart (directory, file, or URL) and displays the licenses
+of the chart and all its dependencies.
+
+The license of a chart is the SPDX license expression of its
+'artifacthub.io/license' annotation. Charts without the annotation are listed
+with the licenses recognized in their LICENSE or COPYING files.
+`
+
 const showCRDsDesc = `
 This command inspects a chart (directory, file, or URL) and displays the contents
 of the CustomResourceDefinition files
@@ -181,7 +196,28 @@ func newShowCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmds := []*cobra.Command{all, readmeSubCmd, valuesSubCmd, chartSubCmd, crdsSubCmd}
+	licensesSubCmd := &cobra.Command{
+		Use:               "licenses [CHART]",
+		Short:             "show the licenses of the chart and its dependencies",
+		Long:              showLicensesDesc,
+		Args:              require.ExactArgs(1),
+		ValidArgsFunction: validArgsFunc,
+		RunE: func(_ *cobra.Command, args []string) error {
+			client.OutputFormat = action.ShowLicenses
+			err := addRegistryClient(client)
+			if err != nil {
+				return err
+			}
+			output, err := runShow(args, client)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(out, output)
+			return nil
+		},
+	}
+
+	cmds := []*cobra.Command{all, readmeSubCmd, valuesSubCmd, chartSubCmd, crdsSubCmd, licensesSubCmd}
 	for _, subCmd := range cmds {
 		addShowFlags(subCmd, client)
 		showCommand.AddCommand(subCmd)
@@ -218,6 +254,11 @@ func runShow(args []string, client *action.Show) (string, error) {
 		client.Version = ">0.0.0-0"
 	}
 
+	// Avoid downloading the whole chart when only a part of it is shown.
+	if output, ok, err := client.RunWithoutChart(args[0], settings); err != nil || ok {
+		return output, err
+	}
+
 	cp, err := client.LocateChart(args[0], settings)
 	if err != nil {
 		return "", err
@@ -156,3 +156,12 @@ func TestShowValuesFileCompletion(t *testing.T) {
 func TestShowCRDsFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "show crds", true)
 }
+
+func TestShowLicenses(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "show the licenses of a chart and its dependencies",
+		cmd:    "show licenses testdata/testcharts/chart-with-licenses",
+		golden: "output/show-licenses.txt",
+	}}
+	runTestCmd(t, tests)
+}
@@ -18,18 +18,22 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"strings"
 	"time"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
 	"k8s.io/kubectl/pkg/cmd/get"
 
 	coloroutput "helm.sh/helm/v4/internal/cli/output"
 	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/chart/common"
 	"helm.sh/helm/v4/pkg/chart/common/util"
 	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cmd/require"
@@ -46,13 +50,19 @@ The status consists of:
 - revision of the release
 - description of the release (can be completion message or error message)
 - list of resources that this release consists of
+- health of each resource as computed by kstatus (JSON and YAML output only)
 - details on last test suite run, if applicable
 - additional notes provided by the chart
+
+With '--revision N --show-delta', the resources added, removed and changed
+from revision N to the current revision are listed as well. They are computed
+from the manifests stored with both revisions.
 `
 
 func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewStatus(cfg)
 	var outfmt output.Format
+	var showDelta bool
 
 	cmd := &cobra.Command{
 		Use:   "status RELEASE_NAME",
@@ -66,22 +76,34 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if showDelta && client.Version <= 0 {
+				return errors.New("--show-delta requires --revision")
+			}
 			// When the output format is a table the resources should be fetched
 			// and displayed as a table. When YAML or JSON the resources will be
 			// returned. This mirrors the handling in kubectl.
 			if outfmt == output.Table {
 				client.ShowResourcesTable = true
+			} else {
+				client.ShowResourceStatus = true
 			}
 			rel, err := client.Run(args[0])
 			if err != nil {
 				return err
 			}
+			var delta *action.ResourceDelta
+			if showDelta {
+				if delta, err = client.Delta(rel); err != nil {
+					return err
+				}
+			}
 
 			// strip chart metadata from the output
 			rel.Chart = nil
 
 			return outfmt.Write(out, &statusPrinter{
 				release:      rel,
+				delta:        delta,
 				debug:        false,
 				showMetadata: false,
 				hideNotes:    false,
@@ -93,6 +115,7 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f := cmd.Flags()
 
 	f.IntVar(&client.Version, "revision", 0, "if set, display the status of the named release with revision")
+	f.BoolVar(&showDelta, "show-delta", false, "with --revision, list the resources added, removed and changed from that revision to the current one")
 
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
@@ -111,6 +134,7 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 type statusPrinter struct {
 	release      *release.Release
+	delta        *action.ResourceDelta
 	debug        bool
 	showMetadata bool
 	hideNotes    bool
@@ -118,11 +142,23 @@ type statusPrinter struct {
 }
 
 func (s statusPrinter) WriteJSON(out io.Writer) error {
-	return output.EncodeJSON(out, s.release)
+	return output.EncodeJSON(out, s.object())
 }
 
 func (s statusPrinter) WriteYAML(out io.Writer) error {
-	return output.EncodeYAML(out, s.release)
+	return output.EncodeYAML(out, s.object())
+}
+
+// object returns the object written as JSON or YAML: the release, with the
+// resource delta alongside its fields when it was computed.
+func (s statusPrinter) object() interface{} {
+	if s.delta == nil {
+		return s.release
+	}
+	return struct {
+		*release.Release
+		Delta *action.ResourceDelta `json:"delta"`
+	}{s.release, s.delta}
 }
 
 func (s statusPrinter) WriteTable(out io.Writer) error {
@@ -170,6 +206,10 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		_, _ = fmt.Fprintf(out, "RESOURCES:\n%s\n", buf.String())
 	}
 
+	if s.delta != nil {
+		writeResourceDelta(out, s.delta)
+	}
+
 	executions := executionsByHookEvent(s.release)
 	if tests, ok := executions[release.HookTest]; !ok || len(tests) == 0 {
 		_, _ = fmt.Fprintln(out, "TEST SUITE: None")
@@ -209,6 +249,13 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 		}
 		// Print an extra newline
 		_, _ = fmt.Fprintln(out)
+
+		if len(s.release.ValuesProvenance) > 0 {
+			_, _ = fmt.Fprintln(out, "VALUES PROVENANCE:")
+			_, _ = fmt.Fprintln(out, formatValuesProvenance(s.release.ValuesProvenance))
+			// Print an extra newline
+			_, _ = fmt.Fprintln(out)
+		}
 	}
 
 	if strings.EqualFold(s.release.Info.Description, "Dry run complete") || s.debug {
@@ -226,6 +273,46 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 	return nil
 }
 
+// writeResourceDelta writes the resources that changed from a revision of a
+// release to the current one.
+func writeResourceDelta(out io.Writer, delta *action.ResourceDelta) {
+	title := fmt.Sprintf("CHANGES FROM REVISION %d TO REVISION %d (CURRENT):", delta.FromRevision, delta.ToRevision)
+	if len(delta.Added)+len(delta.Removed)+len(delta.Changed) == 0 {
+		_, _ = fmt.Fprintf(out, "%s None\n", title)
+		return
+	}
+	table := uitable.New()
+	table.AddRow("CHANGE", "RESOURCE")
+	for _, change := range []struct {
+		name      string
+		resources []string
+	}{
+		{"added", delta.Added},
+		{"removed", delta.Removed},
+		{"changed", delta.Changed},
+	} {
+		for _, r := range change.resources {
+			table.AddRow(change.name, r)
+		}
+	}
+	_, _ = fmt.Fprintf(out, "%s\n%s\n\n", title, table)
+}
+
+// formatValuesProvenance returns a table of the effective values of a release
+// and where they come from.
+func formatValuesProvenance(origins []common.ValueOrigin) string {
+	table := uitable.New()
+	table.AddRow("PATH", "VALUE", "SOURCE")
+	for _, o := range origins {
+		value, err := json.Marshal(o.Value)
+		if err != nil {
+			value = []byte(fmt.Sprint(o.Value))
+		}
+		table.AddRow(o.Path, string(value), o.Source)
+	}
+	return table.String()
+}
+
 func executionsByHookEvent(rel *release.Release) map[release.HookEvent][]*release.Hook {
 	result := make(map[release.HookEvent][]*release.Hook)
 	for _, h := range rel.Hooks {
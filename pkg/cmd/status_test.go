@@ -125,10 +125,51 @@ func TestStatusCmd(t *testing.T) {
 				},
 			},
 		),
+	}, {
+		name:   "get status of a revision with the delta to the current revision",
+		cmd:    "status flummoxed-chickadee --revision 1 --show-delta",
+		golden: "output/status-with-delta.txt",
+		rels:   releasesMockWithRevisions(),
+	}, {
+		name:   "get status of a revision with the delta to the current revision in json",
+		cmd:    "status flummoxed-chickadee --revision 1 --show-delta -o json",
+		golden: "output/status-with-delta.json",
+		rels:   releasesMockWithRevisions(),
+	}, {
+		name:      "show delta without a revision",
+		cmd:       "status flummoxed-chickadee --show-delta",
+		golden:    "output/status-show-delta-no-revision.txt",
+		wantError: true,
+		rels:      releasesMockWithRevisions(),
 	}}
 	runTestCmd(t, tests)
 }
 
+// releasesMockWithRevisions returns two revisions of a release, the second
+// changing, adding and removing a resource of the first.
+func releasesMockWithRevisions() []*release.Release {
+	manifests := []string{
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  color: blue\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: app\n",
+		"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  color: red\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+	}
+	var rels []*release.Release
+	for i, manifest := range manifests {
+		rels = append(rels, &release.Release{
+			Name:      "flummoxed-chickadee",
+			Namespace: "default",
+			Version:   i + 1,
+			Manifest:  manifest,
+			Info: &release.Info{
+				Status:       release.StatusSuperseded,
+				LastDeployed: time.Unix(1452902400, 0).UTC(),
+			},
+			Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "name", Version: "1.2.3", AppVersion: "3.2.1"}},
+		})
+	}
+	rels[1].Info.Status = release.StatusDeployed
+	return rels
+}
+
 func mustParseTime(t string) time.Time {
 	res, _ := time.Parse(time.RFC3339, t)
 	return res
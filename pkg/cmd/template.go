@@ -25,9 +25,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"slices"
-	"sort"
 	"strings"
 
 	release "helm.sh/helm/v4/pkg/release/v1"
@@ -36,9 +34,10 @@ import (
 
 	"helm.sh/helm/v4/pkg/action"
 	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/v2/lint/support"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
-	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+	"helm.sh/helm/v4/pkg/getter"
 )
 
 const templateDesc = `
@@ -57,7 +56,8 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	valueOpts := &values.Options{}
 	var kubeVersion string
 	var extraAPIs []string
-	var showFiles []string
+	filter := &action.ManifestFilter{}
+	var valuesMatrix string
 
 	cmd := &cobra.Command{
 		Use:   "template [NAME] [CHART]",
@@ -67,7 +67,7 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return compInstall(args, toComplete, client)
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			if kubeVersion != "" {
 				parsedKubeVersion, err := common.ParseKubeVersion(kubeVersion)
 				if err != nil {
@@ -95,106 +95,18 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			client.ClientOnly = !validate
 			client.APIVersions = common.VersionSet(extraAPIs)
 			client.IncludeCRDs = includeCrds
-			rel, err := runInstall(args, client, valueOpts, out)
-
-			if err != nil && !settings.Debug {
-				if rel != nil {
-					return fmt.Errorf("%w\n\nUse --debug flag to render out invalid YAML", err)
-				}
-				return err
-			}
-
-			// We ignore a potential error here because, when the --debug flag was specified,
-			// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
-			if rel != nil {
-				var manifests bytes.Buffer
-				fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
-				if !client.DisableHooks {
-					fileWritten := make(map[string]bool)
-					for _, m := range rel.Hooks {
-						if skipTests && isTestHook(m) {
-							continue
-						}
-						if client.OutputDir == "" {
-							fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", m.Path, m.Manifest)
-						} else {
-							newDir := client.OutputDir
-							if client.UseReleaseName {
-								newDir = filepath.Join(client.OutputDir, client.ReleaseName)
-							}
-							_, err := os.Stat(filepath.Join(newDir, m.Path))
-							if err == nil {
-								fileWritten[m.Path] = true
-							}
-
-							err = writeToFile(newDir, m.Path, m.Manifest, fileWritten[m.Path])
-							if err != nil {
-								return err
-							}
-						}
-
-					}
-				}
-
-				// if we have a list of files to render, then check that each of the
-				// provided files exists in the chart.
-				if len(showFiles) > 0 {
-					// This is necessary to ensure consistent manifest ordering when using --show-only
-					// with globs or directory names.
-					splitManifests := releaseutil.SplitManifests(manifests.String())
-					manifestsKeys := make([]string, 0, len(splitManifests))
-					for k := range splitManifests {
-						manifestsKeys = append(manifestsKeys, k)
-					}
-					sort.Sort(releaseutil.BySplitManifestsOrder(manifestsKeys))
-
-					manifestNameRegex := regexp.MustCompile("# Source: [^/]+/(.+)")
-					var manifestsToRender []string
-					for _, f := range showFiles {
-						missing := true
-						// Use linux-style filepath separators to unify user's input path
-						f = filepath.ToSlash(f)
-						for _, manifestKey := range manifestsKeys {
-							manifest := splitManifests[manifestKey]
-							submatch := manifestNameRegex.FindStringSubmatch(manifest)
-							if len(submatch) == 0 {
-								continue
-							}
-							manifestName := submatch[1]
-							// manifest.Name is rendered using linux-style filepath separators on Windows as
-							// well as macOS/linux.
-							manifestPathSplit := strings.Split(manifestName, "/")
-							// manifest.Path is connected using linux-style filepath separators on Windows as
-							// well as macOS/linux
-							manifestPath := strings.Join(manifestPathSplit, "/")
-
-							// if the filepath provided matches a manifest path in the
-							// chart, render that manifest
-							if matched, _ := filepath.Match(f, manifestPath); !matched {
-								continue
-							}
-							manifestsToRender = append(manifestsToRender, manifest)
-							missing = false
-						}
-						if missing {
-							return fmt.Errorf("could not find template %s in chart", f)
-						}
-					}
-					for _, m := range manifestsToRender {
-						fmt.Fprintf(out, "---\n%s\n", m)
-					}
-				} else {
-					fmt.Fprintf(out, "%s", manifests.String())
-				}
+			if valuesMatrix != "" {
+				return runTemplateMatrix(args, client, valueOpts, out, cmd.ErrOrStderr(), valuesMatrix, skipTests, filter)
 			}
-
-			return err
+			return runTemplate(args, client, valueOpts, out, skipTests, filter)
 		},
 	}
 
 	f := cmd.Flags()
 	addInstallFlags(cmd, f, client, valueOpts)
-	f.StringArrayVarP(&showFiles, "show-only", "s", []string{}, "only show manifests rendered from the given templates")
+	f.StringArrayVarP(&filter.Files, "show-only", "s", []string{}, "only show manifests rendered from the given templates. Glob patterns starting with '*/' or '**/' also match the templates of subcharts, and '**' matches any number of directories")
+	f.StringArrayVar(&filter.Kinds, "show-kind", []string{}, "only show objects of the given kind, optionally qualified by API version (e.g. Deployment or apps/v1/Deployment)")
+	f.StringArrayVar(&filter.Names, "show-name", []string{}, "only show objects with a name matching the given glob pattern")
 	f.StringVar(&client.OutputDir, "output-dir", "", "writes the executed templates to files in output-dir instead of stdout")
 	f.BoolVar(&validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. This is the same validation performed on an install")
 	f.BoolVar(&includeCrds, "include-crds", false, "include CRDs in the templated output")
@@ -203,13 +115,157 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion")
 	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions (multiple can be specified)")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
+	f.StringVar(&valuesMatrix, "values-matrix", "", "render the chart once per values file in the given directory, or per permutation defined in the given matrix file")
 	bindPostRenderFlag(cmd, &client.PostRenderer, settings)
 
 	return cmd
 }
 
+// runTemplate renders the chart and writes the resulting manifests to out, or
+// to client.OutputDir when set.
+func runTemplate(args []string, client *action.Install, valueOpts *values.Options, out io.Writer, skipTests bool, filter *action.ManifestFilter) error {
+	rel, err := runInstall(args, client, valueOpts, out)
+
+	if err != nil && !settings.Debug {
+		if rel != nil {
+			return fmt.Errorf("%w\n\nUse --debug flag to render out invalid YAML", err)
+		}
+		return err
+	}
+
+	// We ignore a potential error here because, when the --debug flag was specified,
+	// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
+	if rel != nil {
+		var manifests bytes.Buffer
+		fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
+		if !client.DisableHooks {
+			fileWritten := make(map[string]bool)
+			for _, m := range rel.Hooks {
+				if skipTests && isTestHook(m) {
+					continue
+				}
+				if client.OutputDir == "" {
+					fmt.Fprintf(&manifests, "---\n# Source: %s\n%s\n", m.Path, m.Manifest)
+				} else {
+					newDir := client.OutputDir
+					if client.UseReleaseName {
+						newDir = filepath.Join(client.OutputDir, client.ReleaseName)
+					}
+					_, err := os.Stat(filepath.Join(newDir, m.Path))
+					if err == nil {
+						fileWritten[m.Path] = true
+					}
+
+					err = writeToFile(newDir, m.Path, m.Manifest, fileWritten[m.Path])
+					if err != nil {
+						return err
+					}
+				}
+
+			}
+		}
+
+		if filter.Empty() {
+			fmt.Fprintf(out, "%s", manifests.String())
+			return err
+		}
+		selected, ferr := filter.Apply(manifests.String())
+		if ferr != nil {
+			return ferr
+		}
+		for _, m := range selected {
+			fmt.Fprintf(out, "---\n%s\n", m)
+		}
+	}
+
+	return err
+}
+
+// runTemplateMatrix renders the chart once per permutation of the given values
+// matrix. Each permutation is also linted, and render failures and lint
+// findings of all permutations are reported together once every permutation
+// has been rendered.
+func runTemplateMatrix(args []string, client *action.Install, valueOpts *values.Options, out, errOut io.Writer, matrix string, skipTests bool, filter *action.ManifestFilter) error {
+	entries, err := values.LoadMatrix(matrix)
+	if err != nil {
+		return err
+	}
+
+	outputDir := client.OutputDir
+	defer func() { client.OutputDir = outputDir }()
+
+	var findings strings.Builder
+	failed := 0
+	for _, e := range entries {
+		// Values given on the command line take precedence over the ones of the permutation.
+		opts := *valueOpts
+		opts.ValueFiles = append(slices.Clone(e.ValueFiles), valueOpts.ValueFiles...)
+
+		if outputDir != "" {
+			client.OutputDir = filepath.Join(outputDir, e.Name)
+		} else {
+			fmt.Fprintf(out, "# Values permutation: %s\n", e.Name)
+		}
+
+		if err := runTemplate(args, client, &opts, out, skipTests, filter); err != nil {
+			fmt.Fprintf(&findings, "==> %s\nError %s\n\n", e.Name, err)
+			failed++
+			continue
+		}
+
+		result, err := lintTemplatePermutation(args, client, &opts)
+		if err != nil {
+			fmt.Fprintf(&findings, "==> %s\nError %s\n\n", e.Name, err)
+			failed++
+			continue
+		}
+		if !action.HasWarningsOrErrors(result) {
+			continue
+		}
+		fmt.Fprintf(&findings, "==> %s\n", e.Name)
+		for _, msg := range result.Messages {
+			if msg.Severity > support.InfoSev {
+				fmt.Fprintf(&findings, "%s\n", msg)
+			}
+		}
+		fmt.Fprint(&findings, "\n")
+		if len(result.Errors) > 0 {
+			failed++
+		}
+	}
+
+	fmt.Fprint(errOut, findings.String())
+	summary := fmt.Sprintf("%d values permutation(s) rendered, %d failed", len(entries), failed)
+	if failed > 0 {
+		return errors.New(summary)
+	}
+	fmt.Fprintln(errOut, summary)
+	return nil
+}
+
+// lintTemplatePermutation lints the chart being templated with the given values.
+func lintTemplatePermutation(args []string, client *action.Install, valueOpts *values.Options) (*action.LintResult, error) {
+	_, chartRef, err := client.NameAndChart(args)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := client.LocateChart(chartRef, settings)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := valueOpts.MergeValues(getter.All(settings))
+	if err != nil {
+		return nil, err
+	}
+	lint := action.NewLint()
+	lint.Namespace = settings.Namespace()
+	lint.KubeVersion = client.KubeVersion
+	lint.SkipSchemaValidation = client.SkipSchemaValidation
+	return lint.Run([]string{cp}, vals), nil
+}
+
 func isTestHook(h *release.Hook) bool {
-	return slices.Contains(h.Events, release.HookTest)
+	return slices.Contains(h.Events, release.HookTest) || slices.Contains(h.Events, release.HookTestFixture)
 }
 
 // The following functions (writeToFile, createOrOpenFile, and ensureDirectoryForFile)
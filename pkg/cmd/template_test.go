@@ -48,12 +48,29 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf(`template '%s' --name-template='foobar-{{ b64enc "abc" | lower }}-baz'`, chartPath),
 			golden: "output/template-name-template.txt",
 		},
+		{
+			name:   "check values matrix",
+			cmd:    "template testdata/testcharts/alpine --values-matrix testdata/values-matrix",
+			golden: "output/template-values-matrix.txt",
+		},
 		{
 			name:      "check no args",
 			cmd:       "template",
 			wantError: true,
 			golden:    "output/template-no-args.txt",
 		},
+		{
+			name:      "check kubeconfig and values from standard input",
+			cmd:       fmt.Sprintf("template '%s' --kubeconfig - --values -", chartPath),
+			wantError: true,
+			golden:    "output/template-kubeconfig-stdin-values.txt",
+		},
+		{
+			name:      "check kubeconfig and set-file from standard input",
+			cmd:       fmt.Sprintf("template '%s' --kubeconfig - --set-file a=values.yaml,b=-", chartPath),
+			wantError: true,
+			golden:    "output/template-kubeconfig-stdin-set-file.txt",
+		},
 		{
 			name:      "check library chart",
 			cmd:       fmt.Sprintf("template '%s'", "testdata/testcharts/lib-chart"),
@@ -113,6 +130,32 @@ func TestTemplateCmd(t *testing.T) {
 			// Repeat to ensure manifest ordering regressions are caught
 			repeat: 10,
 		},
+		{
+			name:   "template with show-only glob across subcharts",
+			cmd:    fmt.Sprintf("template '%s' --show-only '*/service.yaml'", chartPath),
+			golden: "output/template-show-only-subcharts.txt",
+			repeat: 10,
+		},
+		{
+			name:   "template with show-only matching any number of directories",
+			cmd:    fmt.Sprintf("template '%s' --show-only 'templates/**/role*.yaml' --show-only templates/subdir/role.yaml", chartPath),
+			golden: "output/template-show-only-doublestar.txt",
+		},
+		{
+			name:   "template with show-kind and show-name",
+			cmd:    fmt.Sprintf("template '%s' --show-kind Service --show-kind rbac.authorization.k8s.io/v1/role --show-name 'subchart*' --show-name '*-role'", chartPath),
+			golden: "output/template-show-kind.txt",
+		},
+		{
+			name:   "template with show-only and show-kind",
+			cmd:    fmt.Sprintf("template '%s' --show-only 'templates/subdir/*' --show-kind ServiceAccount", chartPath),
+			golden: "output/template-show-only-kind.txt",
+		},
+		{
+			name:      "template with show-kind matching no object",
+			cmd:       fmt.Sprintf("template '%s' --show-kind apps/v1/Service", chartPath),
+			wantError: true,
+		},
 		{
 			name:   "sorted output of manifests (order of filenames, then order of objects within each YAML file)",
 			cmd:    fmt.Sprintf("template '%s'", "testdata/testcharts/object-order"),
@@ -0,0 +1,7 @@
+{{- define "chart-with-coverage.name" -}}
+{{ .Chart.Name }}
+{{- end -}}
+
+{{- define "chart-with-coverage.unused" -}}
+unused
+{{- end -}}
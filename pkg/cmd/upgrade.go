@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -32,10 +33,10 @@ import (
 	"helm.sh/helm/v4/pkg/action"
 	ci "helm.sh/helm/v4/pkg/chart"
 	"helm.sh/helm/v4/pkg/chart/loader"
+	"helm.sh/helm/v4/pkg/cli/i18n"
 	"helm.sh/helm/v4/pkg/cli/output"
 	"helm.sh/helm/v4/pkg/cli/values"
 	"helm.sh/helm/v4/pkg/cmd/require"
-	"helm.sh/helm/v4/pkg/downloader"
 	"helm.sh/helm/v4/pkg/getter"
 	release "helm.sh/helm/v4/pkg/release/v1"
 	"helm.sh/helm/v4/pkg/storage/driver"
@@ -78,6 +79,13 @@ or '--set' flags. Priority is given to new values.
 The --dry-run flag will output all generated chart manifests, including Secrets
 which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
+Combined with the --debug flag, the output of --dry-run also lists where each
+value comes from, including the values reused from the current release.
+
+To preview an upgrade, run it with '--dry-run --show-diff'. This shows how the
+manifest of the upgrade differs from the deployed revision, and from the live
+state of its resources in the cluster. The --diff-* flags control how the
+changes are shown.
 `
 
 func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -85,11 +93,14 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	valueOpts := &values.Options{}
 	var outfmt output.Format
 	var createNamespace bool
+	var skipInstallConstraints bool
+	var ignoreChartConflicts bool
+	var showDiff, diffWords bool
 
 	cmd := &cobra.Command{
 		Use:   "upgrade [RELEASE] [CHART]",
 		Short: "upgrade a release",
-		Long:  upgradeDesc,
+		Long:  upgradeDesc + exitCodesHelp,
 		Args:  require.ExactArgs(2),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
@@ -100,8 +111,9 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			return noMoreArgsComp()
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: withExitCodes(func(_ *cobra.Command, args []string) error {
 			client.Namespace = settings.Namespace()
+			client.HealthChecks.GetterOptions = healthCheckGetterOptions(client.ChartPathOptions)
 
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
 				client.InsecureSkipTLSverify, client.PlainHTTP, client.Username, client.Password)
@@ -130,6 +142,8 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					}
 					instClient := action.NewInstall(cfg)
 					instClient.CreateNamespace = createNamespace
+					instClient.SkipInstallConstraints = skipInstallConstraints
+					instClient.IgnoreChartConflicts = ignoreChartConflicts
 					instClient.ChartPathOptions = client.ChartPathOptions
 					instClient.ForceReplace = client.ForceReplace
 					instClient.DryRun = client.DryRun
@@ -147,15 +161,29 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.SubNotes = client.SubNotes
 					instClient.HideNotes = client.HideNotes
 					instClient.SkipSchemaValidation = client.SkipSchemaValidation
+					instClient.SkipSetValidation = client.SkipSetValidation
+					instClient.StrictSetValidation = client.StrictSetValidation
 					instClient.Description = client.Description
 					instClient.DependencyUpdate = client.DependencyUpdate
 					instClient.Labels = client.Labels
+					instClient.ObjectLabels = client.ObjectLabels
+					instClient.IgnoreDifferences = client.IgnoreDifferences
 					instClient.EnableDNS = client.EnableDNS
+					instClient.ConfigChecksums = client.ConfigChecksums
 					instClient.HideSecret = client.HideSecret
 					instClient.TakeOwnership = client.TakeOwnership
+					instClient.CheckPermissions = client.CheckPermissions
+					instClient.LicensePolicy = client.LicensePolicy
+					instClient.AdvisoryPolicy = client.AdvisoryPolicy
+					instClient.HealthChecks = client.HealthChecks
 
 					if isReleaseUninstalled(versions) {
 						instClient.Replace = true
+						// The history is only said to be replaced on an
+						// explicit install --replace.
+						if instClient.Description == "" {
+							instClient.Description = "Install complete"
+						}
 					}
 
 					rel, err := runInstall(args, instClient, valueOpts, out)
@@ -187,15 +215,25 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if err := validateDryRunOptionFlag(client.DryRunOption); err != nil {
 				return err
 			}
+			if showDiff && (client.DryRunOption == "none" || client.DryRunOption == "false") {
+				return errors.New("--show-diff requires --dry-run")
+			}
 
 			p := getter.All(settings)
-			vals, err := valueOpts.MergeValues(p)
+			vals, sources, err := valueOpts.MergeValuesWithSources(p)
 			if err != nil {
 				return err
 			}
-
-			// Check chart dependencies to make sure all are present in /charts
-			ch, err := loader.Load(chartPath)
+			client.ValuesSources = sources
+
+			// Check chart dependencies to make sure all are present in /charts,
+			// building them first when requested.
+			var ch ci.Charter
+			if client.DependencyUpdate {
+				ch, err = action.UpdateDependencies(newDependencyManager(out, chartPath, client.Keyring, p, registryClient))
+			} else {
+				ch, err = loader.Load(chartPath)
+			}
 			if err != nil {
 				return err
 			}
@@ -206,29 +244,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			if req := ac.MetaDependencies(); req != nil {
 				if err := action.CheckDependencies(ch, req); err != nil {
-					err = fmt.Errorf("an error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies: %w", err)
-					if client.DependencyUpdate {
-						man := &downloader.Manager{
-							Out:              out,
-							ChartPath:        chartPath,
-							Keyring:          client.Keyring,
-							SkipUpdate:       false,
-							Getters:          p,
-							RepositoryConfig: settings.RepositoryConfig,
-							RepositoryCache:  settings.RepositoryCache,
-							ContentCache:     settings.ContentCache,
-							Debug:            settings.Debug,
-						}
-						if err := man.Update(); err != nil {
-							return err
-						}
-						// Reload the chart with the updated Chart.lock file.
-						if ch, err = loader.Load(chartPath); err != nil {
-							return fmt.Errorf("failed reloading chart after repo update: %w", err)
-						}
-					} else {
-						return err
-					}
+					return fmt.Errorf("an error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies: %w", err)
 				}
 			}
 
@@ -256,6 +272,18 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				return fmt.Errorf("UPGRADE FAILED: %w", err)
 			}
 
+			if showDiff {
+				diff, err := client.Diff(rel)
+				if err != nil {
+					return err
+				}
+				writeDiffSections(out, []diffSection{
+					{i18n.Sprintf("Changes from revision %d (current) to revision %d (upgrade):", diff.CurrentRevision, diff.TargetRevision), diff.Current},
+					{i18n.Sprintf("Changes from the live state to revision %d (upgrade):", diff.TargetRevision), diff.Live},
+				}, client.DiffOptions.Format, diffWords, settings.ShouldDisableColor())
+				return nil
+			}
+
 			if outfmt == output.Table {
 				fmt.Fprintf(out, "Release %q has been upgraded. Happy Helming!\n", args[0])
 			}
@@ -267,15 +295,19 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				hideNotes:    client.HideNotes,
 				noColor:      settings.ShouldDisableColor(),
 			})
-		},
+		}),
 	}
 
 	f := cmd.Flags()
 	f.BoolVar(&createNamespace, "create-namespace", false, "if --install is set, create the release namespace if not present")
+	f.BoolVar(&ignoreChartConflicts, "ignore-chart-conflicts", false, "if --install is set, install the chart even though it conflicts with charts deployed in the cluster")
+	f.BoolVar(&skipInstallConstraints, "skip-install-constraints", false, "if --install is set, do not check the constraints the chart annotations declare on its releases")
 	f.BoolVarP(&client.Install, "install", "i", false, "if a release by this name doesn't already exist, run an install")
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
 	f.StringVar(&client.DryRunOption, "dry-run", "", "simulate an install. If --dry-run is set with no option being specified or as '--dry-run=client', it will not attempt cluster connections. Setting '--dry-run=server' allows attempting cluster connections.")
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
+	f.BoolVar(&showDiff, "show-diff", false, "with --dry-run, show how the upgrade changes the manifest of the deployed revision and the live state of the resources")
+	addDiffFlags(f, &client.DiffOptions, &diffWords)
 	f.Lookup("dry-run").NoOptDefVal = "client"
 	f.BoolVar(&client.ForceReplace, "force-replace", false, "force resource updates by replacement")
 	f.BoolVar(&client.ForceReplace, "force", false, "deprecated")
@@ -298,11 +330,20 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in upgrade output. Does not affect presence in chart metadata")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
+	f.BoolVar(&client.SkipSetValidation, "skip-set-validation", false, "if set, do not check that the values set with --set and related flags are in the default values or the values schema of the chart")
+	f.BoolVar(&client.StrictSetValidation, "strict-set-validation", false, "if set, fail instead of warning when the values set with --set and related flags are neither in the default values nor in the values schema of the chart")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be separated by comma. Original release labels will be merged with upgrade labels. You can unset label using null.")
+	addObjectLabelsFlags(f, &client.ObjectLabels)
+	addIgnoreDifferencesFlag(f, &client.IgnoreDifferences)
 	f.StringVar(&client.Description, "description", "", "add a custom description")
-	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
+	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing or out of date with Chart.lock before installing the chart")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&client.ConfigChecksums, "config-checksums", false, "if set, annotate the pod templates of workloads with the checksum of the ConfigMaps and Secrets of the release they reference, so that they roll out when their configuration changes")
 	f.BoolVar(&client.TakeOwnership, "take-ownership", false, "if set, upgrade will ignore the check for helm annotations and take ownership of the existing resources")
+	f.BoolVar(&client.CheckPermissions, "check-permissions", false, "if set, check that you are allowed to create, update and delete all the resources changed by the upgrade before upgrading, and list all the missing permissions")
+	bindLicensePolicyFlag(f, &client.LicensePolicy)
+	bindAdvisoryFlags(f, &client.AdvisoryPolicy)
+	addHealthCheckFlags(f, &client.HealthChecks)
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	addValueOptionsFlags(f, valueOpts)
 	bindOutputFlag(cmd, &outfmt)
@@ -574,3 +574,58 @@ func TestUpgradeWithDryRun(t *testing.T) {
 		t.Error("expected error when --hide-secret used without --dry-run")
 	}
 }
+
+func TestUpgradeShowDiff(t *testing.T) {
+	_, _, chartPath := prepareMockRelease(t, "funny-diff")
+
+	defer resetEnv()()
+
+	store := storageFixture()
+	if _, _, err := executeActionCommandC(store, fmt.Sprintf("upgrade funny-diff --install '%s' --set favoriteDrink=beer", chartPath)); err != nil {
+		t.Fatalf("unexpected error, got '%v'", err)
+	}
+
+	cmd := fmt.Sprintf("upgrade funny-diff '%s' --dry-run --show-diff --diff-context 1 --diff-ignore-path /data/myvalue --set favoriteDrink=tea", chartPath)
+	_, out, err := executeActionCommandC(store, cmd)
+	if err != nil {
+		t.Fatalf("unexpected error, got '%v'", err)
+	}
+	expected := `Changes from revision 1 (current) to revision 2 (upgrade):
+--- ConfigMap default/funny-diff-configmap (revision 1)
++++ ConfigMap default/funny-diff-configmap (revision 2)
+@@ -2,3 +2,3 @@
+ data:
+-  drink: beer
++  drink: tea
+ kind: ConfigMap
+
+Changes from the live state to revision 2 (upgrade):
+--- ConfigMap default/funny-diff-configmap (live)
++++ ConfigMap default/funny-diff-configmap (revision 2)
+@@ -0,0 +1,6 @@
++apiVersion: v1
++data:
++  drink: tea
++kind: ConfigMap
++metadata:
++  name: funny-diff-configmap
+`
+	if out != expected {
+		t.Errorf("expected the diff of the upgrade\n%s\ngot\n%s", expected, out)
+	}
+
+	// No second release should be stored because this is a dry run.
+	if _, err := store.Get("funny-diff", 2); err == nil {
+		t.Error("expected no new release")
+	}
+
+	cmd = fmt.Sprintf("upgrade funny-diff '%s' --show-diff", chartPath)
+	if _, _, err := executeActionCommandC(store, cmd); err == nil || err.Error() != "--show-diff requires --dry-run" {
+		t.Errorf("expected --show-diff to require --dry-run, got %v", err)
+	}
+
+	cmd = fmt.Sprintf("upgrade funny-diff '%s' --dry-run --show-diff --diff-output side-by-side", chartPath)
+	if _, _, err := executeActionCommandC(store, cmd); err == nil || !strings.Contains(err.Error(), `invalid diff format "side-by-side"`) {
+		t.Errorf("expected an invalid diff format error, got %v", err)
+	}
+}
@@ -16,13 +16,20 @@ limitations under the License.
 package downloader
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"helm.sh/helm/v4/internal/fileutil"
 )
@@ -67,21 +74,202 @@ func (c *DiskCache) Get(key [sha256.Size]byte, cacheType string) (string, error)
 	if fi.IsDir() {
 		return p, errors.New("is a directory")
 	}
+	// Record the use of the file for pruning the least recently used files.
+	now := time.Now()
+	if err := os.Chtimes(p, now, now); err != nil {
+		slog.Debug("failed to record the use of a cached file", "path", p, slog.Any("error", err))
+	}
 	return p, nil
 }
 
 // Put stores the given reader for the given key.
 // It returns the path to the stored file.
+//
+// Charts are keyed by the digest of their content: a chart whose content does
+// not match the key is not stored.
 func (c *DiskCache) Put(key [sha256.Size]byte, data io.Reader, cacheType string) (string, error) {
-	// TODO: verify the key and digest of the key are the same.
 	p := c.fileName(key, cacheType)
 	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
 		slog.Error("failed to create cache directory")
 		return p, err
 	}
+	if cacheType == CacheChart {
+		data = &digestReader{r: data, h: sha256.New(), key: key}
+	}
 	return p, fileutil.AtomicWriteFile(p, data, 0644)
 }
 
+// digestReader fails at the end of the content of a chart when its digest
+// does not match its key.
+type digestReader struct {
+	r   io.Reader
+	h   hash.Hash
+	key [sha256.Size]byte
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF && !bytes.Equal(r.h.Sum(nil), r.key[:]) {
+		return n, fmt.Errorf("content has the digest sha256:%x, not the digest sha256:%x it is cached under", r.h.Sum(nil), r.key)
+	}
+	return n, err
+}
+
+// CacheEntry is a file of a DiskCache.
+type CacheEntry struct {
+	// Digest is the SHA256 digest of the chart the file is cached for, as
+	// "sha256:<hex>".
+	Digest string `json:"digest"`
+	// Type is CacheChart or CacheProv.
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// LastUsed is the last time the file was stored in or read from the cache.
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Entries lists the files of the cache, the least recently used first.
+// Files not stored by the cache are ignored.
+func (c *DiskCache) Entries() ([]CacheEntry, error) {
+	var entries []CacheEntry
+	err := filepath.WalkDir(c.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == c.Root {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		ext := filepath.Ext(name)
+		key, err := hex.DecodeString(strings.TrimSuffix(name, ext))
+		if (ext != CacheChart && ext != CacheProv) || err != nil || len(key) != sha256.Size || filepath.Base(filepath.Dir(path)) != name[:2] {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, CacheEntry{
+			Digest:   "sha256:" + strings.TrimSuffix(name, ext),
+			Type:     ext,
+			Path:     path,
+			Size:     fi.Size(),
+			LastUsed: fi.ModTime(),
+		})
+		return nil
+	})
+	slices.SortStableFunc(entries, func(a, b CacheEntry) int {
+		return a.LastUsed.Compare(b.LastUsed)
+	})
+	return entries, err
+}
+
+// Prune removes the charts of the cache not used since olderThan, when it is
+// not zero, and then the least recently used charts until the cache holds at
+// most maxSize bytes, when it is positive. The provenance file of a chart is
+// removed with it, and the provenance files of charts not in the cache are
+// removed. It returns the removed files.
+func (c *DiskCache) Prune(olderThan time.Time, maxSize int64) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	charts := make(map[string]bool)
+	for _, e := range entries {
+		size += e.Size
+		if e.Type == CacheChart {
+			charts[e.Digest] = true
+		}
+	}
+	removed := make(map[string]bool)
+	var pruned []CacheEntry
+	remove := func(e CacheEntry) error {
+		if removed[e.Path] {
+			return nil
+		}
+		if err := os.Remove(e.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		removed[e.Path] = true
+		size -= e.Size
+		pruned = append(pruned, e)
+		return nil
+	}
+	for _, e := range entries {
+		if e.Type == CacheProv && !charts[e.Digest] {
+			if err := remove(e); err != nil {
+				return pruned, err
+			}
+		}
+	}
+	for _, e := range entries {
+		expired := !olderThan.IsZero() && e.LastUsed.Before(olderThan)
+		if e.Type != CacheChart || !expired && (maxSize <= 0 || size <= maxSize) {
+			continue
+		}
+		if err := remove(e); err != nil {
+			return pruned, err
+		}
+		for _, prov := range entries {
+			if prov.Type == CacheProv && prov.Digest == e.Digest {
+				if err := remove(prov); err != nil {
+					return pruned, err
+				}
+			}
+		}
+	}
+	return pruned, nil
+}
+
+// Verify checks that the content of each chart of the cache matches the digest
+// it is cached under. It returns the charts that do not, which are removed
+// from the cache when remove is set.
+func (c *DiskCache) Verify(remove bool) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var corrupted []CacheEntry
+	for _, e := range entries {
+		if e.Type != CacheChart {
+			continue
+		}
+		ok, err := matchesDigest(e)
+		if err != nil {
+			return corrupted, err
+		}
+		if ok {
+			continue
+		}
+		corrupted = append(corrupted, e)
+		if remove {
+			if err := os.Remove(e.Path); err != nil {
+				return corrupted, err
+			}
+		}
+	}
+	return corrupted, nil
+}
+
+// matchesDigest reports whether the content of a cached file matches its digest.
+func matchesDigest(e CacheEntry) (bool, error) {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return "sha256:"+hex.EncodeToString(h.Sum(nil)) == e.Digest, nil
+}
+
 // fileName generates the filename in a structured manner where the first part is the
 // directory and the full hash is the filename.
 func (c *DiskCache) fileName(id [sha256.Size]byte, cacheType string) string {
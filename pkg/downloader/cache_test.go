@@ -19,9 +19,11 @@ package downloader
 import (
 	"bytes"
 	"crypto/sha256"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -120,3 +122,80 @@ func TestDiskCache_fileName(t *testing.T) {
 	assert.Equal(t, filepath.Join("/tmp/cache", "13", "1307990e6ba5ca145eb35e99182a9bec46531bc54ddf656a602c780fa0240dee.chart"), cache.fileName(key, CacheChart))
 	assert.Equal(t, filepath.Join("/tmp/cache", "13", "1307990e6ba5ca145eb35e99182a9bec46531bc54ddf656a602c780fa0240dee.prov"), cache.fileName(key, CacheProv))
 }
+
+func TestDiskCache_PutMismatchedDigest(t *testing.T) {
+	cache := &DiskCache{Root: t.TempDir()}
+	key := sha256.Sum256([]byte("hello world"))
+
+	_, err := cache.Put(key, bytes.NewReader([]byte("goodbye world")), CacheChart)
+	require.ErrorContains(t, err, "not the digest sha256:")
+	_, err = cache.Get(key, CacheChart)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	entries, err := os.ReadDir(filepath.Dir(cache.fileName(key, CacheChart)))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "no temporary file should be left behind")
+}
+
+func TestDiskCache_Manage(t *testing.T) {
+	cache := &DiskCache{Root: t.TempDir()}
+	entries, err := cache.Entries()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	put := func(content string, lastUsed time.Time) [sha256.Size]byte {
+		t.Helper()
+		key := sha256.Sum256([]byte(content))
+		for _, cacheType := range []string{CacheChart, CacheProv} {
+			p, err := cache.Put(key, bytes.NewReader([]byte(content)), cacheType)
+			require.NoError(t, err)
+			require.NoError(t, os.Chtimes(p, lastUsed, lastUsed))
+		}
+		return key
+	}
+	now := time.Now()
+	old := put("old chart", now.Add(-48*time.Hour))
+	recent := put("recent chart", now.Add(-time.Hour))
+	current := put("current chart", now)
+	// Files not stored by the cache are ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(cache.Root, "README"), []byte("not cached"), 0644))
+
+	entries, err = cache.Entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 6)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", old), entries[0].Digest)
+	assert.Equal(t, int64(len("old chart")), entries[0].Size)
+
+	// Reading a file records its use.
+	_, err = cache.Get(old, CacheChart)
+	require.NoError(t, err)
+	entries, err = cache.Entries()
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", old), entries[len(entries)-1].Digest)
+
+	// Corrupted charts are found, and removed on demand.
+	require.NoError(t, os.WriteFile(cache.fileName(recent, CacheChart), []byte("tampered"), 0644))
+	corrupted, err := cache.Verify(false)
+	require.NoError(t, err)
+	require.Len(t, corrupted, 1)
+	assert.Equal(t, fmt.Sprintf("sha256:%x", recent), corrupted[0].Digest)
+	_, err = cache.Verify(true)
+	require.NoError(t, err)
+	assert.NoFileExists(t, cache.fileName(recent, CacheChart))
+
+	// Pruning removes the provenance files of the charts not in the cache,
+	// and those of the pruned charts with them.
+	pruned, err := cache.Prune(now.Add(-30*time.Minute), 0)
+	require.NoError(t, err)
+	assert.Len(t, pruned, 1)
+	assert.NoFileExists(t, cache.fileName(recent, CacheProv))
+	assert.FileExists(t, cache.fileName(old, CacheChart))
+	assert.FileExists(t, cache.fileName(old, CacheProv))
+
+	// Pruning to a size removes the least recently used charts first.
+	pruned, err = cache.Prune(time.Time{}, int64(len("old chart")*2))
+	require.NoError(t, err)
+	assert.Len(t, pruned, 2)
+	assert.NoFileExists(t, cache.fileName(current, CacheChart))
+	assert.NoFileExists(t, cache.fileName(current, CacheProv))
+	assert.FileExists(t, cache.fileName(old, CacheChart))
+}
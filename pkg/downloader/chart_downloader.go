@@ -16,7 +16,6 @@ limitations under the License.
 package downloader
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -27,7 +26,9 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"helm.sh/helm/v4/internal/fileutil"
 	ifs "helm.sh/helm/v4/internal/third_party/dep/fs"
@@ -85,6 +86,14 @@ type ChartDownloader struct {
 
 	// Cache specifies the cache implementation to use.
 	Cache Cache
+
+	// Retries is the number of times a download failing with a network or
+	// server error is retried. Interrupted downloads from HTTP repositories
+	// resume where they stopped.
+	Retries int
+	// RetryBackoff is the time waited before the first retry, doubled before
+	// each of the next ones. It defaults to DefaultRetryBackoff.
+	RetryBackoff time.Duration
 }
 
 // DownloadTo retrieves a chart. Depending on the settings, it may also download a provenance file.
@@ -106,7 +115,7 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 		c.Cache = &DiskCache{Root: c.ContentCache}
 		slog.Debug("setup up default downloader cache")
 	}
-	hash, u, err := c.ResolveChartVersion(ref, version)
+	hash, u, locs, err := c.resolveChartVersion(ref, version)
 	if err != nil {
 		return "", nil, err
 	}
@@ -116,38 +125,47 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 		return "", nil, err
 	}
 
-	// Check the cache for the content. Otherwise download it.
-	// Note, this process will pull from the cache but does not automatically populate
-	// the cache with the file it downloads.
-	var data *bytes.Buffer
+	// Check the cache for the content. Otherwise download it to the cache, so
+	// that the next downloads of the chart are read from it.
 	var found bool
-	var digest []byte
 	var digest32 [32]byte
+	var pth string
 	if hash != "" {
 		// if there is a hash, populate the other formats
-		digest, err = hex.DecodeString(hash)
+		digest, err := hex.DecodeString(hash)
 		if err != nil {
 			return "", nil, err
 		}
 		copy(digest32[:], digest)
-		if pth, err := c.Cache.Get(digest32, CacheChart); err == nil {
-			fdata, err := os.ReadFile(pth)
-			if err == nil {
-				found = true
-				data = bytes.NewBuffer(fdata)
-				slog.Debug("found chart in cache", "id", hash)
-			}
+		if pth, err = c.Cache.Get(digest32, CacheChart); err == nil {
+			found = true
+			slog.Debug("found chart in cache", "id", hash)
 		}
 	}
 
 	if !found {
 		c.Options = append(c.Options, getter.WithAcceptHeader("application/gzip,application/octet-stream"))
 
-		data, err = g.Get(u.String(), c.Options...)
+		loc, err := c.download(g, locs, c.Options, func(content io.Reader) (err error) {
+			if hash == "" {
+				pth, digest32, err = c.putChart(content)
+			} else {
+				pth, err = c.Cache.Put(digest32, content, CacheChart)
+			}
+			return err
+		})
 		if err != nil {
 			return "", nil, err
 		}
+		u = loc
+		hash = hex.EncodeToString(digest32[:])
+		slog.Debug("put downloaded chart in cache", "id", hash)
+	}
+	data, err := os.Open(pth)
+	if err != nil {
+		return "", nil, err
 	}
+	defer data.Close()
 
 	name := filepath.Base(u.Path)
 	if u.Scheme == registry.OCIScheme {
@@ -163,20 +181,11 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 	// If provenance is requested, verify it.
 	ver := &provenance.Verification{}
 	if c.Verify > VerifyNever {
-		found = false
-		var body *bytes.Buffer
-		if hash != "" {
-			if pth, err := c.Cache.Get(digest32, CacheProv); err == nil {
-				fdata, err := os.ReadFile(pth)
-				if err == nil {
-					found = true
-					body = bytes.NewBuffer(fdata)
-					slog.Debug("found provenance in cache", "id", hash)
-				}
-			}
-		}
-		if !found {
-			body, err = g.Get(u.String() + ".prov")
+		ppth, err := c.Cache.Get(digest32, CacheProv)
+		if err == nil {
+			slog.Debug("found provenance in cache", "id", hash)
+		} else {
+			content, _, err := c.get(g, locs, ".prov")
 			if err != nil {
 				if c.Verify == VerifyAlways {
 					return destfile, ver, fmt.Errorf("failed to fetch provenance %q", u.String()+".prov")
@@ -184,9 +193,14 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 				fmt.Fprintf(c.Out, "WARNING: Verification not found for %s: %s\n", ref, err)
 				return destfile, ver, nil
 			}
+			defer content.Close()
+			if ppth, err = c.Cache.Put(digest32, content, CacheProv); err != nil {
+				return destfile, nil, err
+			}
+			slog.Debug("put downloaded provenance file in cache", "id", hash)
 		}
 		provfile := destfile + ".prov"
-		if err := fileutil.AtomicWriteFile(provfile, body, 0644); err != nil {
+		if err := ifs.CopyFile(ppth, provfile); err != nil {
 			return destfile, nil, err
 		}
 
@@ -212,7 +226,7 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 		slog.Debug("setup up default downloader cache")
 	}
 
-	digestString, u, err := c.ResolveChartVersion(ref, version)
+	digestString, u, locs, err := c.resolveChartVersion(ref, version)
 	if err != nil {
 		return "", nil, err
 	}
@@ -250,20 +264,18 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 		}
 
 		// Get file not in the cache
-		data, gerr := g.Get(u.String(), c.Options...)
-		if gerr != nil {
-			return "", nil, gerr
-		}
-
-		// Generate the digest
-		if len(digest) == 0 {
-			digest32 = sha256.Sum256(data.Bytes())
-		}
-
-		pth, err = c.Cache.Put(digest32, data, CacheChart)
+		loc, err := c.download(g, locs, c.Options, func(content io.Reader) (err error) {
+			if len(digest) == 0 {
+				pth, digest32, err = c.putChart(content)
+			} else {
+				pth, err = c.Cache.Put(digest32, content, CacheChart)
+			}
+			return err
+		})
 		if err != nil {
 			return "", nil, err
 		}
+		u = loc
 		slog.Debug("put downloaded chart in cache", "id", hex.EncodeToString(digest32[:]))
 	}
 
@@ -279,7 +291,7 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 				return pth, ver, err
 			}
 
-			body, err := g.Get(u.String() + ".prov")
+			body, _, err := c.get(g, locs, ".prov")
 			if err != nil {
 				if c.Verify == VerifyAlways {
 					return pth, ver, fmt.Errorf("failed to fetch provenance %q", u.String()+".prov")
@@ -287,6 +299,7 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 				fmt.Fprintf(c.Out, "WARNING: Verification not found for %s: %s\n", ref, err)
 				return pth, ver, nil
 			}
+			defer body.Close()
 
 			ppth, err = c.Cache.Put(digest32, body, CacheProv)
 			if err != nil {
@@ -333,6 +346,30 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 	return pth, ver, nil
 }
 
+// putChart puts a downloaded chart whose digest is unknown in the cache. The
+// digest is the key of the chart in the cache: the chart is written to a
+// temporary file while computing it, instead of being kept in memory.
+func (c *ChartDownloader) putChart(data io.Reader) (string, [sha256.Size]byte, error) {
+	var digest32 [sha256.Size]byte
+	tmp, err := os.CreateTemp("", "helm-chart-")
+	if err != nil {
+		return "", digest32, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), data); err != nil {
+		return "", digest32, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", digest32, err
+	}
+	copy(digest32[:], h.Sum(nil))
+	pth, err := c.Cache.Put(digest32, tmp, CacheChart)
+	return pth, digest32, err
+}
+
 // ResolveChartVersion resolves a chart reference to a URL.
 //
 // It returns:
@@ -353,23 +390,39 @@ func (c *ChartDownloader) DownloadToCache(ref, version string) (string, *provena
 //
 // TODO: support OCI hash
 func (c *ChartDownloader) ResolveChartVersion(ref, version string) (string, *url.URL, error) {
+	hash, u, _, err := c.resolveChartVersion(ref, version)
+	return hash, u, err
+}
+
+// chartLocation is a URL a chart can be downloaded from, with the URL of the
+// repository, or of the mirror of the repository, serving it.
+type chartLocation struct {
+	url     *url.URL
+	repoURL string
+}
+
+// resolveChartVersion resolves a chart reference like ResolveChartVersion,
+// and also returns the locations the chart can be downloaded from, in the
+// order they are tried: the resolved URL and the same file on the mirrors of
+// the repository.
+func (c *ChartDownloader) resolveChartVersion(ref, version string) (string, *url.URL, []chartLocation, error) {
 	u, err := url.Parse(ref)
 	if err != nil {
-		return "", nil, fmt.Errorf("invalid chart URL format: %s", ref)
+		return "", nil, nil, fmt.Errorf("invalid chart URL format: %s", ref)
 	}
 
 	if registry.IsOCI(u.String()) {
 		if c.RegistryClient == nil {
-			return "", nil, fmt.Errorf("unable to lookup ref %s at version '%s', missing registry client", ref, version)
+			return "", nil, nil, fmt.Errorf("unable to lookup ref %s at version '%s', missing registry client", ref, version)
 		}
 
 		digest, OCIref, err := c.RegistryClient.ValidateReference(ref, version, u)
-		return digest, OCIref, err
+		return digest, OCIref, []chartLocation{{url: OCIref}}, err
 	}
 
 	rf, err := loadRepoConfig(c.RepositoryConfig)
 	if err != nil {
-		return "", u, err
+		return "", u, nil, err
 	}
 
 	if u.IsAbs() && len(u.Host) > 0 && len(u.Path) > 0 {
@@ -386,9 +439,9 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (string, *url
 			if err == ErrNoOwnerRepo {
 				// Make sure to add the ref URL as the URL for the getter
 				c.Options = append(c.Options, getter.WithURL(ref))
-				return "", u, nil
+				return "", u, []chartLocation{{url: u, repoURL: ref}}, nil
 			}
-			return "", u, err
+			return "", u, nil, err
 		}
 
 		// If we get here, we don't need to go through the next phase of looking
@@ -400,27 +453,33 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (string, *url
 		if rc.CertFile != "" || rc.KeyFile != "" || rc.CAFile != "" {
 			c.Options = append(c.Options, getter.WithTLSClientConfig(rc.CertFile, rc.KeyFile, rc.CAFile))
 		}
-		if rc.Username != "" && rc.Password != "" {
+		c.Options = append(c.Options, repositoryTransportOptions(rc)...)
+		username, password, err := rc.Credentials()
+		if err != nil {
+			return "", u, nil, err
+		}
+		if username != "" && password != "" {
 			c.Options = append(
 				c.Options,
-				getter.WithBasicAuth(rc.Username, rc.Password),
+				getter.WithBasicAuth(username, password),
 				getter.WithPassCredentialsAll(rc.PassCredentialsAll),
 			)
 		}
-		return "", u, nil
+		locs, err := c.mirrorLocations(rc, ref)
+		return "", u, locs, err
 	}
 
 	// See if it's of the form: repo/path_to_chart
 	p := strings.SplitN(u.Path, "/", 2)
 	if len(p) < 2 {
-		return "", u, fmt.Errorf("non-absolute URLs should be in form of repo_name/path_to_chart, got: %s", u)
+		return "", u, nil, fmt.Errorf("non-absolute URLs should be in form of repo_name/path_to_chart, got: %s", u)
 	}
 
 	repoName := p[0]
 	chartName := p[1]
 	rc, err := pickChartRepositoryConfigByName(repoName, rf.Repositories)
 	if err != nil {
-		return "", u, err
+		return "", u, nil, err
 	}
 
 	// Now that we have the chart repository information we can use that URL
@@ -429,45 +488,185 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (string, *url
 
 	r, err := repo.NewChartRepository(rc, c.Getters)
 	if err != nil {
-		return "", u, err
+		return "", u, nil, err
 	}
 
 	if r != nil && r.Config != nil {
 		if r.Config.CertFile != "" || r.Config.KeyFile != "" || r.Config.CAFile != "" {
 			c.Options = append(c.Options, getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile))
 		}
-		if r.Config.Username != "" && r.Config.Password != "" {
+		c.Options = append(c.Options, repositoryTransportOptions(r.Config)...)
+		username, password, err := r.Config.Credentials()
+		if err != nil {
+			return "", u, nil, err
+		}
+		if username != "" && password != "" {
 			c.Options = append(c.Options,
-				getter.WithBasicAuth(r.Config.Username, r.Config.Password),
+				getter.WithBasicAuth(username, password),
 				getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
 			)
 		}
 	}
 
 	// Next, we need to load the index, and actually look up the chart.
+	cv, err := c.lookupIndexEntry(r, chartName, version)
+	if err != nil {
+		return "", u, nil, err
+	}
+
+	if len(cv.URLs) == 0 {
+		return "", u, nil, fmt.Errorf("chart %q has no downloadable URLs", ref)
+	}
+
+	// TODO: Seems that picking first URL is not fully correct
+	resolvedURL, err := repo.ResolveReferenceURL(rc.URL, cv.URLs[0])
+	if err != nil {
+		return cv.Digest, u, nil, fmt.Errorf("invalid chart URL format: %s", ref)
+	}
+
+	loc, err := url.Parse(resolvedURL)
+	if err != nil {
+		return cv.Digest, loc, nil, err
+	}
+	locs, err := c.mirrorLocations(rc, resolvedURL)
+	return cv.Digest, loc, locs, err
+}
+
+// repositoryTransportOptions returns the getter options for the proxy and the
+// minimum TLS version set for a repository, if any.
+func repositoryTransportOptions(rc *repo.Entry) []getter.Option {
+	var options []getter.Option
+	if rc.Proxy != "" {
+		options = append(options, getter.WithProxy(rc.Proxy))
+	}
+	if rc.MinTLSVersion != "" {
+		options = append(options, getter.WithMinTLSVersion(rc.MinTLSVersion))
+	}
+	return options
+}
+
+// mirrorLocations returns the locations of a file of a repository on the
+// repository and its mirrors, in the order they are tried. The files that are
+// not under the URL of the repository are only downloaded from their URL.
+func (c *ChartDownloader) mirrorLocations(rc *repo.Entry, fileURL string) ([]chartLocation, error) {
+	r, err := repo.NewChartRepository(rc, c.Getters)
+	if err != nil {
+		return nil, err
+	}
+	var locs []chartLocation
+	for _, base := range r.URLs() {
+		mirrored, ok := repo.MirrorURL(fileURL, rc.URL, base)
+		if !ok {
+			continue
+		}
+		u, err := url.Parse(mirrored)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chart URL format: %s", mirrored)
+		}
+		locs = append(locs, chartLocation{url: u, repoURL: base})
+	}
+	if len(locs) == 0 {
+		u, err := url.Parse(fileURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chart URL format: %s", fileURL)
+		}
+		locs = append(locs, chartLocation{url: u, repoURL: rc.URL})
+	}
+	return locs, nil
+}
+
+// download downloads a chart and passes its content to put. When the chart
+// changed while an interrupted download was resumed, it is downloaded again
+// from its start once.
+func (c *ChartDownloader) download(g getter.Getter, locs []chartLocation, options []getter.Option, put func(io.Reader) error) (*url.URL, error) {
+	for restarted := false; ; restarted = true {
+		content, loc, err := c.get(g, locs, "", options...)
+		if err != nil {
+			return nil, err
+		}
+		err = put(content)
+		content.Close()
+		if restarted || !errors.Is(err, getter.ErrContentChanged) {
+			return loc, err
+		}
+		slog.Warn("chart changed while its download was resumed, downloading it again", "url", loc.String())
+	}
+}
+
+// get downloads a file, or the file with the given suffix next to it, from
+// the first of its locations serving it. It only moves on to the next
+// location when the download fails with a network or server error, and
+// returns the URL the file is downloaded from. The content is streamed as it
+// is read, and must be closed.
+func (c *ChartDownloader) get(g getter.Getter, locs []chartLocation, suffix string, options ...getter.Option) (*getter.Content, *url.URL, error) {
+	var errs []error
+	for i, loc := range locs {
+		opts := options
+		if loc.repoURL != "" && len(locs) > 1 {
+			opts = append(slices.Clip(options), getter.WithURL(loc.repoURL))
+		}
+		content, err := c.getWithRetries(g, loc.url.String()+suffix, opts)
+		if err == nil {
+			return content, loc.url, nil
+		}
+		if len(locs) == 1 || !repo.IsFailoverError(err) {
+			return nil, nil, err
+		}
+		errs = append(errs, err)
+		if i < len(locs)-1 {
+			slog.Warn("chart download failed, trying the next mirror", "url", loc.url.String()+suffix, "next", locs[i+1].url.String()+suffix, slog.Any("error", err))
+		}
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+// lookupIndexEntry looks a version of a chart up in the cached index of a
+// repository.
+func (c *ChartDownloader) lookupIndexEntry(r *repo.ChartRepository, chartName, version string) (*repo.ChartVersion, error) {
 	idxFile := filepath.Join(c.RepositoryCache, helmpath.CacheIndexFile(r.Config.Name))
 	i, err := repo.LoadIndexFile(idxFile)
 	if err != nil {
-		return "", u, fmt.Errorf("no cached repo found. (try 'helm repo update'): %w", err)
+		return nil, fmt.Errorf("no cached repo found. (try 'helm repo update'): %w", err)
+	}
+	r.IndexFile = i
+	r.CachePath = c.RepositoryCache
+	if err := r.LoadShard(chartName); err != nil {
+		return nil, err
 	}
 
 	cv, err := i.Get(chartName, version)
 	if err != nil {
-		return "", u, fmt.Errorf("chart %q matching %s not found in %s index. (try 'helm repo update'): %w", chartName, version, r.Config.Name, err)
+		return nil, fmt.Errorf("chart %q matching %s not found in %s index. (try 'helm repo update'): %w", chartName, version, r.Config.Name, err)
 	}
+	return cv, nil
+}
 
-	if len(cv.URLs) == 0 {
-		return "", u, fmt.Errorf("chart %q has no downloadable URLs", ref)
+// IndexEntry returns the entry of a version of a chart in the cached index of
+// its repository, with its digests and provenance file. It returns nil for
+// references that are not of the form 'reponame/chartname'.
+func (c *ChartDownloader) IndexEntry(ref, version string) (*repo.ChartVersion, error) {
+	u, err := url.Parse(ref)
+	if err != nil || registry.IsOCI(ref) || u.IsAbs() {
+		return nil, nil
+	}
+	p := strings.SplitN(u.Path, "/", 2)
+	if len(p) < 2 {
+		return nil, nil
 	}
 
-	// TODO: Seems that picking first URL is not fully correct
-	resolvedURL, err := repo.ResolveReferenceURL(rc.URL, cv.URLs[0])
+	rf, err := loadRepoConfig(c.RepositoryConfig)
 	if err != nil {
-		return cv.Digest, u, fmt.Errorf("invalid chart URL format: %s", ref)
+		return nil, err
 	}
-
-	loc, err := url.Parse(resolvedURL)
-	return cv.Digest, loc, err
+	rc, err := pickChartRepositoryConfigByName(p[0], rf.Repositories)
+	if err != nil {
+		return nil, err
+	}
+	r, err := repo.NewChartRepository(rc, c.Getters)
+	if err != nil {
+		return nil, err
+	}
+	return c.lookupIndexEntry(r, p[1], version)
 }
 
 // VerifyChart takes a path to a chart archive and a keyring, and verifies the chart.
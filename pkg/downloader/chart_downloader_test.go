@@ -16,11 +16,17 @@ limitations under the License.
 package downloader
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -239,6 +245,58 @@ func TestDownloadTo(t *testing.T) {
 	if _, err := os.Stat(filepath.Join(dest, cname)); err != nil {
 		t.Error(err)
 	}
+
+	// The chart and its provenance file are put in the content cache.
+	entries, err := (&DiskCache{Root: contentCache}).Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Digest != v.FileHash {
+		t.Errorf("expected the chart and its provenance file in the content cache, got %v", entries)
+	}
+}
+
+func TestDownloadTo_Uploaded(t *testing.T) {
+	srv := repotest.NewTempServer(t, repotest.WithUploads())
+	defer srv.Stop()
+
+	for _, name := range []string{"signtest-0.1.0.tgz", "signtest-0.1.0.tgz.prov"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		require.NoError(t, err)
+		path := repotest.UploadPath
+		if strings.HasSuffix(name, ".prov") {
+			path = repotest.ProvenanceUploadPath
+		}
+		res, err := srv.Client().Post(srv.URL()+path, "application/octet-stream", bytes.NewReader(data))
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+	}
+
+	// The uploaded chart is found in the regenerated index of the repository,
+	// and verified with its uploaded provenance file.
+	repoFile := filepath.Join(srv.Root(), "repositories.yaml")
+	cache := t.TempDir()
+	getters := getter.All(&cli.EnvSettings{RepositoryConfig: repoFile, RepositoryCache: cache})
+	r, err := repo.NewChartRepository(&repo.Entry{Name: "test", URL: srv.URL()}, getters)
+	require.NoError(t, err)
+	r.CachePath = cache
+	_, err = r.DownloadIndexFile()
+	require.NoError(t, err)
+
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyAlways,
+		Keyring:          "testdata/helm-test-key.pub",
+		RepositoryConfig: repoFile,
+		RepositoryCache:  cache,
+		ContentCache:     t.TempDir(),
+		Getters:          getters,
+	}
+	where, v, err := c.DownloadTo("test/signtest", "0.1.0", t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "signtest-0.1.0.tgz", filepath.Base(where))
+	require.NotEmpty(t, v.FileHash)
 }
 
 func TestDownloadTo_TLS(t *testing.T) {
@@ -301,6 +359,345 @@ func TestDownloadTo_TLS(t *testing.T) {
 	}
 }
 
+func TestDownloadTo_Unauthorized(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+		repotest.WithBasicAuth("username", "password"),
+	)
+	defer srv.Stop()
+
+	contentCache := t.TempDir()
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyNever,
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  repoCache,
+		ContentCache:     contentCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoConfig,
+			RepositoryCache:  repoCache,
+			ContentCache:     contentCache,
+		}),
+		Options: []getter.Option{
+			getter.WithBasicAuth("username", "wrong"),
+		},
+	}
+	_, _, err := c.DownloadTo(srv.URL()+"/signtest-0.1.0.tgz", "", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("expected a 401 Unauthorized error, got %v", err)
+	}
+}
+
+func TestDownloadTo_Faults(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   repotest.Fault
+		options []getter.Option
+		wantErr string
+	}{
+		{
+			name:    "server error",
+			fault:   repotest.Fault{StatusCode: http.StatusBadGateway},
+			wantErr: "502 Bad Gateway",
+		},
+		{
+			name:    "truncated archive",
+			fault:   repotest.Fault{TruncateAt: 100},
+			wantErr: "unexpected EOF",
+		},
+		{
+			name:    "timeout",
+			fault:   repotest.Fault{Latency: time.Second},
+			options: []getter.Option{getter.WithTimeout(100 * time.Millisecond)},
+			wantErr: "Client.Timeout exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := repotest.NewTempServer(
+				t,
+				repotest.WithChartSourceGlob("testdata/*.tgz*"),
+				repotest.WithFault("/*.tgz", tt.fault),
+			)
+			defer srv.Stop()
+
+			contentCache := t.TempDir()
+			c := ChartDownloader{
+				Out:              os.Stderr,
+				Verify:           VerifyNever,
+				RepositoryConfig: repoConfig,
+				RepositoryCache:  repoCache,
+				ContentCache:     contentCache,
+				Getters: getter.All(&cli.EnvSettings{
+					RepositoryConfig: repoConfig,
+					RepositoryCache:  repoCache,
+					ContentCache:     contentCache,
+				}),
+				Options: tt.options,
+			}
+			_, _, err := c.DownloadTo(srv.URL()+"/signtest-0.1.0.tgz", "", t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+			if n := srv.FaultRequests("/*.tgz"); n != 1 {
+				t.Errorf("expected 1 request for the archive, got %d", n)
+			}
+		})
+	}
+}
+
+func TestDownloadTo_Retries(t *testing.T) {
+	var waits []time.Duration
+	defer func(s func(time.Duration)) { sleep = s }(sleep)
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	want, err := os.ReadFile("testdata/signtest-0.1.0.tgz")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		fault repotest.Fault
+	}{
+		{
+			name:  "server errors",
+			fault: repotest.Fault{StatusCode: http.StatusServiceUnavailable, Times: 2},
+		},
+		{
+			// The download resumes after the first 100 bytes.
+			name:  "interrupted downloads",
+			fault: repotest.Fault{TruncateAt: 100, Times: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits = nil
+			srv := repotest.NewTempServer(
+				t,
+				repotest.WithChartSourceGlob("testdata/*.tgz*"),
+				repotest.WithFault("/*.tgz", tt.fault),
+			)
+			defer srv.Stop()
+
+			contentCache := t.TempDir()
+			c := ChartDownloader{
+				Out:              os.Stderr,
+				Verify:           VerifyNever,
+				RepositoryConfig: repoConfig,
+				RepositoryCache:  repoCache,
+				ContentCache:     contentCache,
+				Getters: getter.All(&cli.EnvSettings{
+					RepositoryConfig: repoConfig,
+					RepositoryCache:  repoCache,
+					ContentCache:     contentCache,
+				}),
+				Retries:      2,
+				RetryBackoff: 10 * time.Millisecond,
+			}
+			where, _, err := c.DownloadTo(srv.URL()+"/signtest-0.1.0.tgz", "", t.TempDir())
+			require.NoError(t, err)
+			got, err := os.ReadFile(where)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+			require.Equal(t, 3, srv.FaultRequests("/*.tgz"))
+			require.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}, waits)
+		})
+	}
+}
+
+func TestDownloadTo_ContentChanged(t *testing.T) {
+	defer func(s func(time.Duration)) { sleep = s }(sleep)
+	sleep = func(time.Duration) {}
+
+	want, err := os.ReadFile("testdata/signtest-0.1.0.tgz")
+	require.NoError(t, err)
+
+	// The first download is interrupted, and the chart changes before it is
+	// resumed.
+	var requests []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Clone())
+		if len(requests) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(want)))
+			w.Write(want[:100])
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "signtest-0.1.0.tgz", time.Time{}, bytes.NewReader(want))
+	}))
+	defer srv.Close()
+
+	contentCache := t.TempDir()
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyNever,
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  repoCache,
+		ContentCache:     contentCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoConfig,
+			RepositoryCache:  repoCache,
+			ContentCache:     contentCache,
+		}),
+		Retries: 2,
+	}
+	where, _, err := c.DownloadTo(srv.URL+"/signtest-0.1.0.tgz", "", t.TempDir())
+	require.NoError(t, err)
+	got, err := os.ReadFile(where)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// The resumed download asks for the rest of the first content, and the
+	// chart is downloaded again from its start when it changed.
+	require.Len(t, requests, 3)
+	require.Equal(t, "bytes=100-", requests[1].Get("Range"))
+	require.Equal(t, `"v1"`, requests[1].Get("If-Range"))
+	require.Empty(t, requests[2].Get("Range"))
+}
+
+func TestDownloadTo_Mirrors(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+		repotest.WithFault("/*.tgz", repotest.Fault{StatusCode: http.StatusServiceUnavailable}),
+	)
+	defer srv.Stop()
+	require.NoError(t, srv.CreateIndex())
+	require.NoError(t, srv.LinkIndices())
+	mirror := repotest.NewTempServer(t, repotest.WithChartSourceGlob("testdata/*.tgz*"))
+	defer mirror.Stop()
+
+	repoFile := filepath.Join(t.TempDir(), "repositories.yaml")
+	rf := repo.NewFile()
+	rf.Add(&repo.Entry{Name: "test", URL: srv.URL(), Mirrors: []string{mirror.URL()}})
+	require.NoError(t, rf.WriteFile(repoFile, 0o600))
+
+	contentCache := t.TempDir()
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyAlways,
+		Keyring:          "testdata/helm-test-key.pub",
+		RepositoryConfig: repoFile,
+		RepositoryCache:  srv.Root(),
+		ContentCache:     contentCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoFile,
+			RepositoryCache:  srv.Root(),
+			ContentCache:     contentCache,
+		}),
+	}
+
+	// The chart and its provenance are downloaded from the mirror, and the
+	// mirrors of the repository are also used for the URLs in its index.
+	for _, ref := range []string{"test/signtest", srv.URL() + "/signtest-0.1.0.tgz"} {
+		dest := t.TempDir()
+		where, v, err := c.DownloadTo(ref, "0.1.0", dest)
+		require.NoError(t, err, ref)
+		require.Equal(t, filepath.Join(dest, "signtest-0.1.0.tgz"), where)
+		require.NotEmpty(t, v.FileHash)
+	}
+	require.Equal(t, 2, srv.FaultRequests("/*.tgz"))
+}
+
+func TestDownloadTo_ShardedIndex(t *testing.T) {
+	srv := repotest.NewTempServer(t, repotest.WithChartSourceGlob("testdata/*.tgz*"))
+	defer srv.Stop()
+	i, err := repo.IndexDirectory(srv.Root(), srv.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := i.WriteShardedFile(filepath.Join(srv.Root(), "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index of the repository is cached without the versions of the
+	// charts, which are downloaded when the chart is resolved.
+	cache := t.TempDir()
+	repos := filepath.Join(t.TempDir(), "repositories.yaml")
+	entry := &repo.Entry{Name: "sharded", URL: srv.URL()}
+	rf := repo.NewFile()
+	rf.Add(entry)
+	if err := rf.WriteFile(repos, 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := repo.NewChartRepository(entry, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = cache
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	contentCache := t.TempDir()
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyNever,
+		RepositoryConfig: repos,
+		RepositoryCache:  cache,
+		ContentCache:     contentCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repos,
+			RepositoryCache:  cache,
+			ContentCache:     contentCache,
+		}),
+	}
+	where, _, err := c.DownloadTo("sharded/signtest", "0.1.0", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(where) != "signtest-0.1.0.tgz" {
+		t.Errorf("unexpected download %s", where)
+	}
+	if _, err := os.Stat(filepath.Join(cache, repo.ShardDir)); err != nil {
+		t.Errorf("expected the shard of the chart to be cached: %s", err)
+	}
+}
+
+func TestDownloadTo_OCI(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithOCIRegistry(),
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+
+	registryClient, err := srv.RegistryClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contentCache := t.TempDir()
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyAlways,
+		Keyring:          "testdata/helm-test-key.pub",
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  repoCache,
+		ContentCache:     contentCache,
+		RegistryClient:   registryClient,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoConfig,
+			RepositoryCache:  repoCache,
+			ContentCache:     contentCache,
+		}),
+		Options: []getter.Option{
+			getter.WithRegistryClient(registryClient),
+		},
+	}
+	dest := t.TempDir()
+	where, v, err := c.DownloadTo(srv.OCIURL()+"/signtest", "0.1.0", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := filepath.Join(dest, "signtest-0.1.0.tgz"); where != expect {
+		t.Errorf("Expected download to %s, got %s", expect, where)
+	}
+	if v.FileHash == "" {
+		t.Error("File hash was empty, but verification is required.")
+	}
+}
+
 func TestDownloadTo_VerifyLater(t *testing.T) {
 	ensure.HelmHome(t)
 
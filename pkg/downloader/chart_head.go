@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/chart/loader/archive"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+// ChartHeadSize is the number of bytes of a chart archive FetchHead downloads.
+var ChartHeadSize int64 = 64 * 1024
+
+// ErrNotInHead indicates that a file is not in the beginning of a chart
+// archive, so the whole chart has to be downloaded to read it.
+var ErrNotInHead = errors.New("file not found in the beginning of the chart archive")
+
+// FetchHead downloads the beginning of a chart archive and returns the files
+// it holds in full.
+//
+// 'helm package' writes Chart.yaml and values.yaml first, so they can be read
+// without downloading the whole chart. Only the first ChartHeadSize bytes are
+// requested from servers supporting range requests. Charts in OCI registries
+// are not read this way; use FetchMetadata.
+func (c *ChartDownloader) FetchHead(ref, version string) ([]*archive.BufferedFile, error) {
+	if registry.IsOCI(ref) {
+		return nil, fmt.Errorf("unable to fetch the beginning of OCI chart %s", ref)
+	}
+	_, u, err := c.ResolveChartVersion(ref, version)
+	if err != nil {
+		return nil, err
+	}
+	g, err := c.Getters.ByScheme(u.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := g.Get(u.String(), slices.Concat(c.Options, []getter.Option{
+		getter.WithAcceptHeader("application/gzip,application/octet-stream"),
+		getter.WithMaxBytes(ChartHeadSize),
+	})...)
+	if err != nil {
+		return nil, err
+	}
+	return archive.LoadArchiveFilesPrefix(data)
+}
+
+// FetchMetadata returns the metadata of a chart without downloading the whole
+// chart. It is read from the config of charts in OCI registries, and from the
+// Chart.yaml at the beginning of the archive of other charts.
+func (c *ChartDownloader) FetchMetadata(ref, version string) (*chart.Metadata, error) {
+	if registry.IsOCI(ref) {
+		_, u, err := c.ResolveChartVersion(ref, version)
+		if err != nil {
+			return nil, err
+		}
+		result, err := c.RegistryClient.PullMetadata(u.Host + u.Path)
+		if err != nil {
+			return nil, err
+		}
+		return result.Chart.Meta, nil
+	}
+
+	files, err := c.FetchHead(ref, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.Name == "Chart.yaml" {
+			md := new(chart.Metadata)
+			if err := yaml.Unmarshal(f.Data, md); err != nil {
+				return nil, fmt.Errorf("cannot load Chart.yaml: %w", err)
+			}
+			return md, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: Chart.yaml", ErrNotInHead)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/repo/v1/repotest"
+)
+
+func TestFetchHead(t *testing.T) {
+	srv := repotest.NewTempServer(t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+	require.NoError(t, srv.CreateIndex())
+	require.NoError(t, srv.LinkIndices())
+
+	repoFile := filepath.Join(srv.Root(), "repositories.yaml")
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		RepositoryConfig: repoFile,
+		RepositoryCache:  srv.Root(),
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoFile,
+			RepositoryCache:  srv.Root(),
+		}),
+	}
+
+	files, err := c.FetchHead("test/signtest", "0.1.0")
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "Chart.yaml")
+	assert.Contains(t, names, "values.yaml")
+
+	md, err := c.FetchMetadata("test/signtest", "0.1.0")
+	require.NoError(t, err)
+	assert.Equal(t, "signtest", md.Name)
+	assert.Equal(t, "0.1.0", md.Version)
+
+	cv, err := c.IndexEntry("test/signtest", "0.1.0")
+	require.NoError(t, err)
+	assert.True(t, cv.Signed())
+	assert.Equal(t, srv.URL()+"/signtest-0.1.0.tgz.prov", cv.Provenance)
+	cv, err = c.IndexEntry(srv.URL()+"/signtest-0.1.0.tgz", "")
+	require.NoError(t, err)
+	assert.Nil(t, cv)
+
+	// A head too short to hold Chart.yaml needs the whole chart.
+	defer func(size int64) { ChartHeadSize = size }(ChartHeadSize)
+	ChartHeadSize = 20
+	files, err = c.FetchHead("test/signtest", "0.1.0")
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	_, err = c.FetchMetadata("test/signtest", "0.1.0")
+	assert.ErrorIs(t, err, ErrNotInHead)
+}
@@ -29,10 +29,13 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/internal/fileutil"
+	"helm.sh/helm/v4/internal/gitrepo"
 	"helm.sh/helm/v4/internal/resolver"
 	"helm.sh/helm/v4/internal/third_party/dep/fs"
 	"helm.sh/helm/v4/internal/urlutil"
@@ -41,6 +44,7 @@ import (
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/helmpath"
+	"helm.sh/helm/v4/pkg/provenance"
 	"helm.sh/helm/v4/pkg/registry"
 	"helm.sh/helm/v4/pkg/repo/v1"
 )
@@ -70,6 +74,34 @@ type Manager struct {
 	Keyring string
 	// SkipUpdate indicates that the repository should not be updated first.
 	SkipUpdate bool
+	// Offline forbids network access. Dependencies are resolved from the
+	// cached repository indexes and fetched from the content cache or the
+	// charts/ directory, and the operation fails listing the dependencies
+	// that would need the network.
+	Offline bool
+	// DigestAlgorithm is the hash algorithm of the digest written to the lock
+	// file, such as "sha256" or "sha512". When empty, the algorithm of the
+	// existing lock file is kept, or SHA256 is used.
+	DigestAlgorithm string
+	// ArchiveDigests records the SHA256 digest of the archive of each
+	// dependency in the lock file. It is kept on when the existing lock file
+	// records them. Build verifies the downloaded archives against the digests
+	// of the lock file whenever it records them.
+	ArchiveDigests bool
+	// Explain prints how the version of each dependency was chosen when
+	// resolving the dependencies.
+	Explain bool
+	// Retries is the number of times a download failing with a network or
+	// server error is retried, and RetryBackoff the time waited before the
+	// first retry, doubled before each of the next ones.
+	Retries      int
+	RetryBackoff time.Duration
+	// Concurrency is the maximum number of chart archives downloaded at once.
+	// When it is more than one, the archives are downloaded once all the
+	// dependencies are resolved, and the errors of all the failed downloads
+	// are reported. Otherwise they are downloaded one by one, stopping at the
+	// first failure.
+	Concurrency int
 	// Getter collection for the operation
 	Getters          []getter.Provider
 	RegistryClient   *registry.Client
@@ -84,7 +116,7 @@ type Manager struct {
 //
 // If the lockfile is not present, this will run a Manager.Update()
 //
-// If SkipUpdate is set, this will not update the repository.
+// If SkipUpdate or Offline is set, this will not update the repository.
 func (m *Manager) Build() error {
 	c, err := m.loadChartDir()
 	if err != nil {
@@ -117,7 +149,11 @@ func (m *Manager) Build() error {
 		return err
 	}
 
-	if sum, err := resolver.HashReq(req, lock.Dependencies); err != nil || sum != lock.Digest {
+	inSync, err := resolver.VerifyReq(req, lock.Dependencies, lock.Digest)
+	if err != nil && c.Metadata.APIVersion != chart.APIVersionV1 {
+		return fmt.Errorf("unable to verify the lock file (Chart.lock), which may have been written by a newer version of Helm: %w", err)
+	}
+	if !inSync {
 		// If lock digest differs and chart is apiVersion v1, it maybe because the lock was built
 		// with Helm 2 and therefore should be checked with Helm v2 hash
 		// Fix for: https://github.com/helm/helm/issues/7233
@@ -136,7 +172,7 @@ func (m *Manager) Build() error {
 		return err
 	}
 
-	if !m.SkipUpdate {
+	if !m.SkipUpdate && !m.Offline {
 		// For each repo in the file, update the cached copy of that repo
 		if err := m.UpdateRepositories(); err != nil {
 			return err
@@ -186,15 +222,20 @@ func (m *Manager) Update() error {
 
 	// For each of the repositories Helm is configured to know about, update
 	// the index information locally.
-	if !m.SkipUpdate {
+	if !m.SkipUpdate && !m.Offline {
 		if err := m.UpdateRepositories(); err != nil {
 			return err
 		}
 	}
 
+	hasher, err := m.lockHasher(c.Lock)
+	if err != nil {
+		return err
+	}
+
 	// Now we need to find out which version of a chart best satisfies the
 	// dependencies in the Chart.yaml
-	lock, err := m.resolve(req, repoNames)
+	lock, err := m.resolve(req, repoNames, hasher)
 	if err != nil {
 		return err
 	}
@@ -204,8 +245,16 @@ func (m *Manager) Update() error {
 		return err
 	}
 
+	// downloadAll records the digests of the archives, which are only kept
+	// when asked for.
+	if !m.ArchiveDigests && !hasArchiveDigests(c.Lock) {
+		for _, dep := range lock.Dependencies {
+			dep.ArchiveDigest = ""
+		}
+	}
+
 	// downloadAll might overwrite dependency version, recalculate lock digest
-	newDigest, err := resolver.HashReq(req, lock.Dependencies)
+	newDigest, err := resolver.HashReqWith(hasher, req, lock.Dependencies)
 	if err != nil {
 		return err
 	}
@@ -230,18 +279,81 @@ func (m *Manager) loadChartDir() (*chart.Chart, error) {
 	return loader.LoadDir(m.ChartPath)
 }
 
+// lockHasher returns the hash algorithm of the digest of the lock file.
+func (m *Manager) lockHasher(oldLock *chart.Lock) (resolver.Hasher, error) {
+	if m.DigestAlgorithm != "" {
+		return resolver.LookupHasher(m.DigestAlgorithm)
+	}
+	if oldLock != nil {
+		if h, err := resolver.DigestHasher(oldLock.Digest); err == nil {
+			return h, nil
+		}
+	}
+	return resolver.SHA256, nil
+}
+
 // resolve takes a list of dependencies and translates them into an exact version to download.
 //
 // This returns a lock file, which has all of the dependencies normalized to a specific version.
-func (m *Manager) resolve(req []*chart.Dependency, repoNames map[string]string) (*chart.Lock, error) {
-	res := resolver.New(m.ChartPath, m.RepositoryCache, m.RegistryClient)
-	return res.Resolve(req, repoNames)
+func (m *Manager) resolve(req []*chart.Dependency, repoNames map[string]string, hasher resolver.Hasher) (*chart.Lock, error) {
+	opts := []resolver.Option{resolver.WithHasher(hasher)}
+	if m.Offline {
+		opts = append(opts, resolver.WithOffline())
+	}
+	res := resolver.New(m.ChartPath, m.RepositoryCache, m.RegistryClient, opts...)
+	if !m.Explain {
+		return res.Resolve(req, repoNames)
+	}
+	lock, explanations, err := res.ResolveExplain(req, repoNames)
+	writeExplanations(m.Out, explanations)
+	return lock, err
+}
+
+// writeExplanations prints how the version of each dependency was chosen.
+func writeExplanations(out io.Writer, explanations []*resolver.Explanation) {
+	for _, e := range explanations {
+		fmt.Fprintf(out, "Dependency %s (repository %q, version %q)\n", e.Name, e.Repository, e.Constraint)
+		if e.Source == "" {
+			fmt.Fprintln(out, "  not resolved")
+			continue
+		}
+		fmt.Fprintf(out, "  source: %s\n", e.Source)
+		if len(e.Candidates) > 0 {
+			fmt.Fprintln(out, "  candidates:")
+			width := 0
+			for _, c := range e.Candidates {
+				width = max(width, len(c.Version))
+			}
+			for _, c := range e.Candidates {
+				var verdict string
+				switch {
+				case c.Skipped != "":
+					verdict = "skipped: " + c.Skipped
+				case c.Chosen:
+					verdict = "satisfies " + e.Constraint + ", newest match, chosen"
+				case c.Satisfies:
+					verdict = "satisfies " + e.Constraint
+				default:
+					verdict = "does not satisfy " + e.Constraint
+				}
+				fmt.Fprintf(out, "    %-*s  %s\n", width, c.Version, verdict)
+			}
+		}
+		if e.Version == "" {
+			fmt.Fprintln(out, "  locked: none, no candidate satisfies the version")
+		} else {
+			fmt.Fprintf(out, "  locked: %s\n", e.Version)
+		}
+	}
 }
 
 // downloadAll takes a list of dependencies and downloads them into charts/
 //
 // It will delete versions of the chart that exist on disk and might cause
 // a conflict.
+//
+// The archives of the dependencies recording a digest are verified against
+// it, and the digests of the others are recorded.
 func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 	repos, err := m.loadChartRepositories()
 	if err != nil {
@@ -272,7 +384,16 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 
 	fmt.Fprintf(m.Out, "Saving %d charts\n", len(deps))
 	var saveError error
-	churls := make(map[string]struct{})
+	var needNetwork []string
+	churls := make(map[string]string)
+	// The downloads started once all the dependencies are resolved when
+	// downloading several charts at once.
+	var downloads []*chartDownload
+	queued := make(map[string]*chartDownload)
+	out := m.Out
+	if m.Concurrency > 1 {
+		out = &syncWriter{w: m.Out}
+	}
 	for _, dep := range deps {
 		// No repository means the chart is in charts directory
 		if dep.Repository == "" {
@@ -284,7 +405,7 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 				return fmt.Errorf("unable to load chart '%s': %v", chartPath, err)
 			}
 
-			constraint, err := semver.NewConstraint(dep.Version)
+			constraint, err := resolver.DependencyConstraint(dep, nil)
 			if err != nil {
 				return fmt.Errorf("dependency %s has an invalid version/constraint format: %s", dep.Name, err)
 			}
@@ -313,6 +434,41 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 			continue
 		}
 
+		if gitrepo.IsGit(dep.Repository) {
+			fmt.Fprintf(m.Out, "Archiving %s from git repository %s\n", dep.Name, dep.Repository)
+			if err := m.tarFromGit(dep, tmpPath); errors.Is(err, gitrepo.ErrNotCached) {
+				needNetwork = append(needNetwork, fmt.Sprintf("%q (repository %q, version %q)", dep.Name, dep.Repository, dep.Version))
+			} else if err != nil {
+				saveError = err
+				break
+			}
+			continue
+		}
+
+		if m.Offline {
+			archive, err := m.copyLocalChart(dep, repos, destPath, tmpPath)
+			if err != nil {
+				saveError = err
+				break
+			}
+			if archive == "" {
+				needNetwork = append(needNetwork, fmt.Sprintf("%q (repository %q, version %q)", dep.Name, dep.Repository, dep.Version))
+				continue
+			}
+			if err := checkArchiveDigest(dep, archive); err != nil {
+				saveError = err
+				break
+			}
+			continue
+		}
+
+		if registry.IsOCI(dep.Repository) && dep.Digest != "" && m.RegistryClient != nil {
+			if err := m.checkDigest(dep); err != nil {
+				saveError = err
+				break
+			}
+		}
+
 		// Any failure to resolve/download a chart should fail:
 		// https://github.com/helm/helm/issues/1439
 		churl, username, password, insecureskiptlsverify, passcredentialsall, caFile, certFile, keyFile, err := m.findChartURL(dep.Name, dep.Version, dep.Repository, repos)
@@ -321,15 +477,26 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 			break
 		}
 
-		if _, ok := churls[churl]; ok {
+		// The URL of an OCI chart loses its tag below.
+		key := churl
+		if archive, ok := churls[key]; ok {
 			fmt.Fprintf(m.Out, "Already downloaded %s from repo %s\n", dep.Name, dep.Repository)
+			if err := checkArchiveDigest(dep, archive); err != nil {
+				saveError = err
+				break
+			}
+			continue
+		}
+		if d, ok := queued[key]; ok {
+			fmt.Fprintf(m.Out, "Already downloading %s from repo %s\n", dep.Name, dep.Repository)
+			d.deps = append(d.deps, dep)
 			continue
 		}
 
 		fmt.Fprintf(m.Out, "Downloading %s from repo %s\n", dep.Name, dep.Repository)
 
-		dl := ChartDownloader{
-			Out:              m.Out,
+		dl := &ChartDownloader{
+			Out:              out,
 			Verify:           m.Verify,
 			Keyring:          m.Keyring,
 			RepositoryConfig: m.RepositoryConfig,
@@ -337,6 +504,8 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 			ContentCache:     m.ContentCache,
 			RegistryClient:   m.RegistryClient,
 			Getters:          m.Getters,
+			Retries:          m.Retries,
+			RetryBackoff:     m.RetryBackoff,
 			Options: []getter.Option{
 				getter.WithBasicAuth(username, password),
 				getter.WithPassCredentialsAll(passcredentialsall),
@@ -356,12 +525,26 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 				getter.WithTagName(version))
 		}
 
-		if _, _, err = dl.DownloadTo(churl, version, tmpPath); err != nil {
-			saveError = fmt.Errorf("could not download %s: %w", churl, err)
+		d := &chartDownload{deps: []*chart.Dependency{dep}, dl: dl, url: churl, version: version}
+		if m.Concurrency > 1 {
+			queued[key] = d
+			downloads = append(downloads, d)
+			continue
+		}
+		archive, err := d.run(tmpPath)
+		if err != nil {
+			saveError = err
 			break
 		}
+		churls[key] = archive
+	}
 
-		churls[churl] = struct{}{}
+	if saveError == nil && len(downloads) > 0 {
+		saveError = m.downloadParallel(downloads, tmpPath)
+	}
+
+	if saveError == nil && len(needNetwork) > 0 {
+		saveError = fmt.Errorf("offline mode: %d subchart(s) are neither in the charts directory nor in the content cache and would need network access: %s", len(needNetwork), strings.Join(needNetwork, ", "))
 	}
 
 	// TODO: this should probably be refactored to be a []error, so we can capture and provide more information rather than "last error wins".
@@ -377,6 +560,230 @@ func (m *Manager) downloadAll(deps []*chart.Dependency) error {
 	return nil
 }
 
+// chartDownload is the download of a chart archive shared by the dependencies
+// resolved to it.
+type chartDownload struct {
+	deps    []*chart.Dependency
+	dl      *ChartDownloader
+	url     string
+	version string
+}
+
+// run downloads the chart archive to dest, and checks it against the archive
+// digests of the dependencies. It returns the path of the archive.
+func (d *chartDownload) run(dest string) (string, error) {
+	archive, _, err := d.dl.DownloadTo(d.url, d.version, dest)
+	if err != nil {
+		return "", fmt.Errorf("could not download %s: %w", d.url, err)
+	}
+	for _, dep := range d.deps {
+		if err := checkArchiveDigest(dep, archive); err != nil {
+			return "", err
+		}
+	}
+	return archive, nil
+}
+
+// downloadParallel runs downloads to dest, m.Concurrency of them at once. All
+// of them run even when some fail, and the returned error joins the errors of
+// all the failed ones.
+func (m *Manager) downloadParallel(downloads []*chartDownload, dest string) error {
+	errs := make([]error, len(downloads))
+	slots := make(chan struct{}, m.Concurrency)
+
+	var wg sync.WaitGroup
+	for i, d := range downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			_, errs[i] = d.run(dest)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// syncWriter serializes the writes to a writer shared by concurrent downloads.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// copyLocalChart copies the archive of a dependency into dest without
+// accessing the network. The archive is taken from the charts directory, or
+// from the content cache using the digest recorded in the cached repository
+// index. It returns the path of the copy, or an empty string when the archive
+// was not found.
+func (m *Manager) copyLocalChart(dep *chart.Dependency, repos map[string]*repo.ChartRepository, chartsPath, dest string) (string, error) {
+	archive, err := findArchive(chartsPath, dep.Name, dep.Version)
+	if err != nil {
+		return "", err
+	}
+	var prov string
+	if archive == "" {
+		archive, prov = m.findCachedChart(dep, repos)
+	} else if _, err := os.Stat(archive + ".prov"); err == nil {
+		prov = archive + ".prov"
+	}
+	if archive == "" {
+		return "", nil
+	}
+
+	fmt.Fprintf(m.Out, "Copying %s from the local cache\n", dep.Name)
+	ch, err := loader.LoadFile(archive)
+	if err != nil {
+		return "", fmt.Errorf("unable to load chart '%s': %w", archive, err)
+	}
+	destfile := filepath.Join(dest, fmt.Sprintf("%s-%s.tgz", ch.Name(), ch.Metadata.Version))
+	if err := copyFile(archive, destfile); err != nil {
+		return "", err
+	}
+
+	if m.Verify == VerifyNever {
+		return destfile, nil
+	}
+	if prov == "" {
+		if m.Verify == VerifyAlways {
+			return "", fmt.Errorf("failed to verify %s: provenance file not available offline", dep.Name)
+		}
+		fmt.Fprintf(m.Out, "WARNING: Verification not found for %s: provenance file not available offline\n", dep.Name)
+		return destfile, nil
+	}
+	if err := copyFile(prov, destfile+".prov"); err != nil {
+		return "", err
+	}
+	if m.Verify != VerifyLater {
+		if _, err := VerifyChart(destfile, destfile+".prov", m.Keyring); err != nil {
+			return "", err
+		}
+	}
+	return destfile, nil
+}
+
+// findCachedChart returns the paths of the archive and provenance file of a
+// dependency in the content cache, if the cached index of its repository
+// records the digest of the archive.
+func (m *Manager) findCachedChart(dep *chart.Dependency, repos map[string]*repo.ChartRepository) (archive, prov string) {
+	if registry.IsOCI(dep.Repository) || m.ContentCache == "" {
+		return "", ""
+	}
+	for _, cr := range repos {
+		if !urlutil.Equal(dep.Repository, cr.Config.URL) {
+			continue
+		}
+		entry, err := findEntryByName(dep.Name, cr)
+		if err != nil {
+			return "", ""
+		}
+		ve, err := findVersionedEntry(dep.Version, entry)
+		if err != nil || ve.Digest == "" {
+			return "", ""
+		}
+		digest, err := hex.DecodeString(ve.Digest)
+		if err != nil || len(digest) != 32 {
+			return "", ""
+		}
+		var digest32 [32]byte
+		copy(digest32[:], digest)
+		cache := &DiskCache{Root: m.ContentCache}
+		if archive, err = cache.Get(digest32, CacheChart); err != nil {
+			return "", ""
+		}
+		prov, _ = cache.Get(digest32, CacheProv)
+		return archive, prov
+	}
+	return "", ""
+}
+
+// checkDigest checks that the tag of an OCI dependency still points to the
+// manifest digest recorded in the lock file.
+func (m *Manager) checkDigest(dep *chart.Dependency) error {
+	digest, err := resolver.ManifestDigest(m.RegistryClient, dep.Repository, dep.Name, dep.Version)
+	if err != nil {
+		return err
+	}
+	if digest != dep.Digest {
+		return fmt.Errorf("dependency %s %s has the digest %s, but the lock file (Chart.lock) pins it to %s. The chart was pushed again since the lock file was written: run 'helm dependency update' to accept its new content", dep.Name, dep.Version, digest, dep.Digest)
+	}
+	return nil
+}
+
+// checkArchiveDigest checks the archive of a dependency against the digest
+// recorded in the lock file, or records its digest when there is none.
+func checkArchiveDigest(dep *chart.Dependency, archive string) error {
+	sum, err := provenance.DigestFile(archive)
+	if err != nil {
+		return err
+	}
+	digest := "sha256:" + sum
+	if dep.ArchiveDigest == "" {
+		dep.ArchiveDigest = digest
+		return nil
+	}
+	if digest != dep.ArchiveDigest {
+		return fmt.Errorf("the archive of dependency %s %s has the digest %s, but the lock file (Chart.lock) pins it to %s. The chart was published again since the lock file was written: run 'helm dependency update' to accept its new content", dep.Name, dep.Version, digest, dep.ArchiveDigest)
+	}
+	return nil
+}
+
+// hasArchiveDigests reports whether a lock file records the digests of the
+// archives of the dependencies.
+func hasArchiveDigests(lock *chart.Lock) bool {
+	if lock == nil {
+		return false
+	}
+	for _, dep := range lock.Dependencies {
+		if dep.ArchiveDigest != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// findArchive returns the path of a chart archive in dir with the given name
+// and a version satisfying the given version or constraint, if there is one.
+func findArchive(dir, name, version string) (string, error) {
+	constraint, err := semver.NewConstraint(version)
+	if err != nil {
+		return "", fmt.Errorf("dependency %s has an invalid version/constraint format: %w", name, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".tgz") {
+			continue
+		}
+		archive := filepath.Join(dir, e.Name())
+		ch, err := loader.LoadFile(archive)
+		if err != nil || ch.Name() != name {
+			continue
+		}
+		if v, err := semver.NewVersion(ch.Metadata.Version); err == nil && constraint.Check(v) {
+			return archive, nil
+		}
+	}
+	return "", nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return fileutil.AtomicWriteFile(dest, in, 0644)
+}
+
 func parseOCIRef(chartRef string) (string, string, error) {
 	refTagRegexp := regexp.MustCompile(`^(oci://[^:]+(:[0-9]{1,5})?[^:]+):(.*)$`)
 	caps := refTagRegexp.FindStringSubmatch(chartRef)
@@ -475,8 +882,8 @@ func (m *Manager) hasAllRepos(deps []*chart.Dependency) error {
 	missing := []string{}
 Loop:
 	for _, dd := range deps {
-		// If repo is from local path or OCI, continue
-		if strings.HasPrefix(dd.Repository, "file://") || registry.IsOCI(dd.Repository) {
+		// If repo is from local path, OCI or git, continue
+		if strings.HasPrefix(dd.Repository, "file://") || registry.IsOCI(dd.Repository) || gitrepo.IsGit(dd.Repository) {
 			continue
 		}
 
@@ -548,7 +955,7 @@ func (m *Manager) ensureMissingRepos(repoNames map[string]string, deps []*chart.
 	// repositories configured by the user. Here we update repos found in
 	// the dependencies that are not known to the user if update skipping
 	// is not configured.
-	if !m.SkipUpdate && len(ru) > 0 {
+	if !m.SkipUpdate && !m.Offline && len(ru) > 0 {
 		fmt.Fprintln(m.Out, "Getting updates for unmanaged Helm repositories...")
 		if err := m.parallelRepoUpdate(ru); err != nil {
 			return repoNames, err
@@ -593,7 +1000,7 @@ func (m *Manager) resolveRepoNames(deps []*chart.Dependency) (map[string]string,
 			continue
 		}
 
-		if registry.IsOCI(dd.Repository) {
+		if registry.IsOCI(dd.Repository) || gitrepo.IsGit(dd.Repository) {
 			reposMap[dd.Name] = dd.Repository
 			continue
 		}
@@ -752,8 +1159,11 @@ func (m *Manager) findChartURL(name, version, repoURL string, repos map[string]*
 				//nolint:nakedret
 				return
 			}
-			username = cr.Config.Username
-			password = cr.Config.Password
+			username, password, err = cr.Config.Credentials()
+			if err != nil {
+				//nolint:nakedret
+				return
+			}
 			passcredentialsall = cr.Config.PassCredentialsAll
 			insecureskiptlsverify = cr.Config.InsecureSkipTLSverify
 			caFile = cr.Config.CAFile
@@ -906,6 +1316,26 @@ func tarFromLocalDir(chartpath, name, repo, version, destPath string) (string, e
 	return "", fmt.Errorf("can't get a valid version for dependency %s", name)
 }
 
+// tarFromGit archives the locked version of a dependency kept in a git
+// repository into destPath. The clone of the repository is kept in the
+// repository cache.
+func (m *Manager) tarFromGit(dep *chart.Dependency, destPath string) error {
+	source, err := gitrepo.Parse(dep.Repository)
+	if err != nil {
+		return err
+	}
+	gr, err := gitrepo.Open(source, m.RepositoryCache, m.Offline)
+	if err != nil {
+		return err
+	}
+	ch, err := gr.LoadVersion(dep.Name, dep.Version)
+	if err != nil {
+		return err
+	}
+	_, err = chartutil.Save(ch, destPath)
+	return err
+}
+
 // The prefix to use for cache keys created by the manager for repo names
 const managerKeyPrefix = "helm-manager-"
 
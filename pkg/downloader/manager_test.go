@@ -17,21 +17,33 @@ package downloader
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
+	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/internal/resolver"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/cli"
 	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/provenance"
+	"helm.sh/helm/v4/pkg/registry"
 	"helm.sh/helm/v4/pkg/repo/v1"
 	"helm.sh/helm/v4/pkg/repo/v1/repotest"
 )
@@ -368,6 +380,128 @@ func TestUpdateBeforeBuild(t *testing.T) {
 	}
 }
 
+func TestUpdate_DigestAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	d := &chart.Chart{Metadata: &chart.Metadata{Name: "dep-chart", Version: "0.1.0", APIVersion: "v2"}}
+	if err := chartutil.SaveDir(d, dir); err != nil {
+		t.Fatal(err)
+	}
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-dependency",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       "dep-chart",
+				Version:    ">=0.1.0",
+				Repository: "file://../dep-chart",
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		t.Fatal(err)
+	}
+	repoConfig := filepath.Join(dir, "repositories.yaml")
+	if err := repo.NewFile().WriteFile(repoConfig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	lockDigest := func() string {
+		t.Helper()
+		ch, err := loader.LoadDir(filepath.Join(dir, "with-dependency"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ch.Lock.Digest
+	}
+
+	m := &Manager{
+		ChartPath:        filepath.Join(dir, "with-dependency"),
+		Out:              bytes.NewBuffer(nil),
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  dir,
+		DigestAlgorithm:  "sha512",
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Regexp(t, "^sha512:[0-9a-f]{128}$", lockDigest())
+
+	// Building verifies the digest with the algorithm recorded in the lock
+	// file, and updating keeps it.
+	m.DigestAlgorithm = ""
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Regexp(t, "^sha512:", lockDigest())
+
+	m.DigestAlgorithm = "md4"
+	assert.EqualError(t, m.Update(), `unsupported digest algorithm "md4" (supported: sha256, sha512)`)
+
+	// Lock files written with an unknown algorithm cannot be verified.
+	lockfile := filepath.Join(dir, "with-dependency", "Chart.lock")
+	data, err := os.ReadFile(lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockfile, bytes.Replace(data, []byte("digest: sha512:"), []byte("digest: blake3:"), 1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.DigestAlgorithm = ""
+	assert.EqualError(t, m.Build(), `unable to verify the lock file (Chart.lock), which may have been written by a newer version of Helm: unsupported digest algorithm "blake3" (supported: sha256, sha512)`)
+}
+
+func TestWriteExplanations(t *testing.T) {
+	var b bytes.Buffer
+	writeExplanations(&b, []*resolver.Explanation{
+		{
+			Name:       "alpine",
+			Repository: "https://charts.example.com",
+			Constraint: "^1.4.0",
+			Source:     "cached index of repository example",
+			Candidates: []resolver.Candidate{
+				{Version: "2.0.0"},
+				{Version: "1.4.3-rc.1", Skipped: "no download URL"},
+				{Version: "1.4.2", Satisfies: true, Chosen: true},
+				{Version: "1.4.0", Satisfies: true},
+			},
+			Version: "1.4.2",
+		},
+		{
+			Name:       "local",
+			Constraint: "0.1.0",
+			Source:     "unpacked chart in the charts directory, checked against the constraint when building",
+			Version:    "0.1.0",
+		},
+		{
+			Name:       "redis",
+			Repository: "oci://registry.example.com/charts",
+			Constraint: ">=3.0.0",
+			Source:     "tags of registry.example.com/charts/redis",
+			Candidates: []resolver.Candidate{{Version: "2.1.0"}},
+		},
+	})
+	assert.Equal(t, `Dependency alpine (repository "https://charts.example.com", version "^1.4.0")
+  source: cached index of repository example
+  candidates:
+    2.0.0       does not satisfy ^1.4.0
+    1.4.3-rc.1  skipped: no download URL
+    1.4.2       satisfies ^1.4.0, newest match, chosen
+    1.4.0       satisfies ^1.4.0
+  locked: 1.4.2
+Dependency local (repository "", version "0.1.0")
+  source: unpacked chart in the charts directory, checked against the constraint when building
+  locked: 0.1.0
+Dependency redis (repository "oci://registry.example.com/charts", version ">=3.0.0")
+  source: tags of registry.example.com/charts/redis
+  candidates:
+    2.1.0  does not satisfy >=3.0.0
+  locked: none, no candidate satisfies the version
+`, b.String())
+}
+
 // TestUpdateWithNoRepo is for the case of a dependency that has no repo listed.
 // This happens when the dependency is in the charts directory and does not need
 // to be fetched.
@@ -550,6 +684,98 @@ func TestBuild_WithRepositoryAlias(t *testing.T) {
 	})
 }
 
+func TestBuild_Offline(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "offline",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{
+				{Name: "local-subchart", Version: "0.1.0", Repository: srv.URL()},
+			},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bytes.NewBuffer(nil)
+	m := &Manager{
+		ChartPath: dir("offline"),
+		Out:       b,
+		Getters: getter.Providers{getter.Provider{
+			Schemes: []string{"http", "https"},
+			New:     getter.NewHTTPGetter,
+		}},
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+		ContentCache:     t.TempDir(),
+	}
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	archive := dir("offline", "charts", "local-subchart-0.1.0.tgz")
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// From here on, any network access fails.
+	srv.Stop()
+	m.Offline = true
+
+	// The archive in the charts directory satisfies the lock file.
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	assert.FileExists(t, archive)
+
+	// Without it, nor the copy of the download in the content cache, the
+	// build fails listing what would need the network.
+	if err := os.Remove(archive); err != nil {
+		t.Fatal(err)
+	}
+	m.ContentCache = t.TempDir()
+	err = m.Build()
+	assert.ErrorContains(t, err, `offline mode: 1 subchart(s) are neither in the charts directory nor in the content cache and would need network access: "local-subchart" (repository "`+srv.URL()+`", version "0.1.0")`)
+
+	// The content cache is used with the digest of the cached index.
+	index, err := repo.LoadIndexFile(dir("index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv, err := index.Get("local-subchart", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var key [32]byte
+	digest, err := hex.DecodeString(cv.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(key[:], digest)
+	cache := &DiskCache{Root: m.ContentCache}
+	if _, err := cache.Put(key, bytes.NewReader(data), CacheChart); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	assert.FileExists(t, archive)
+}
+
 func TestErrRepoNotFound_Error(t *testing.T) {
 	type fields struct {
 		Repos []string
@@ -767,3 +993,238 @@ func TestWriteLock(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestArchiveDigests(t *testing.T) {
+	srv := repotest.NewTempServer(
+		t,
+		repotest.WithChartSourceGlob("testdata/*.tgz*"),
+	)
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	reqs := []*chart.Dependency{{Name: "local-subchart", Version: "0.1.0", Repository: srv.URL()}}
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "integrity", Version: "0.1.0", APIVersion: chart.APIVersionV2, Dependencies: reqs}}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		ChartPath:        dir("integrity"),
+		Out:              io.Discard,
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+		ContentCache:     t.TempDir(),
+		ArchiveDigests:   true,
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := provenance.DigestFile(dir("integrity", "charts", "local-subchart-0.1.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock := loadLock(t, dir("integrity"))
+	assert.Equal(t, "sha256:"+sum, lock.Dependencies[0].ArchiveDigest)
+
+	// The digests are kept once the lock file records them, and verified.
+	m.ArchiveDigests = false
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "sha256:"+sum, loadLock(t, dir("integrity")).Dependencies[0].ArchiveDigest)
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock.Dependencies[0].ArchiveDigest = "sha256:" + strings.Repeat("0", 64)
+	if lock.Digest, err = resolver.HashReq(reqs, lock.Dependencies); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLock(dir("integrity"), lock, false); err != nil {
+		t.Fatal(err)
+	}
+	err = m.Build()
+	assert.ErrorContains(t, err, "the archive of dependency local-subchart 0.1.0 has the digest sha256:"+sum+", but the lock file (Chart.lock) pins it to sha256:0000000000000000000000000000000000000000000000000000000000000000")
+}
+
+func TestUpdate_GitRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Helm", "-c", "user.email=helm@example.com"}, args...)...)
+		cmd.Dir = remote
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for _, v := range []string{"0.1.0", "0.2.0", "1.0.0"} {
+		c := &chart.Chart{Metadata: &chart.Metadata{Name: "gitchart", Version: v, APIVersion: chart.APIVersionV2}}
+		if err := chartutil.SaveDir(c, filepath.Join(remote, "charts")); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", v)
+		git("tag", "gitchart-"+v)
+	}
+
+	dir := t.TempDir()
+	reqs := []*chart.Dependency{{Name: "gitchart", Version: "^0.1.0 || ^0.2.0", Repository: "git+file://" + filepath.ToSlash(remote)}}
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "umbrella", Version: "0.1.0", APIVersion: chart.APIVersionV2, Dependencies: reqs}}
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		ChartPath:        filepath.Join(dir, "umbrella"),
+		Out:              io.Discard,
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: filepath.Join(dir, "repositories.yaml"),
+		RepositoryCache:  dir,
+		ContentCache:     t.TempDir(),
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "0.2.0", loadLock(t, m.ChartPath).Dependencies[0].Version)
+	assert.FileExists(t, filepath.Join(m.ChartPath, "charts", "gitchart-0.2.0.tgz"))
+
+	// Building offline uses the clone of the repository.
+	if err := os.RemoveAll(filepath.Join(m.ChartPath, "charts")); err != nil {
+		t.Fatal(err)
+	}
+	m.Offline = true
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	assert.FileExists(t, filepath.Join(m.ChartPath, "charts", "gitchart-0.2.0.tgz"))
+}
+
+func loadLock(t *testing.T, chartPath string) *chart.Lock {
+	t.Helper()
+	c, err := loader.LoadDir(chartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Lock == nil {
+		t.Fatal("the chart has no lock file")
+	}
+	return c.Lock
+}
+
+func TestBuild_OCIDigestMismatch(t *testing.T) {
+	// The registry serves a manifest pushed again since the lock file was written.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/charts/alpine/manifests/0.1.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", "sha256:"+strings.Repeat("1", 64))
+		w.Header().Set("Content-Length", "2")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	reqs := []*chart.Dependency{{Name: "alpine", Version: "0.1.0", Repository: "oci://" + strings.TrimPrefix(srv.URL, "http://") + "/charts"}}
+	c := &chart.Chart{Metadata: &chart.Metadata{Name: "pinned", Version: "0.1.0", APIVersion: chart.APIVersionV2, Dependencies: reqs}}
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		t.Fatal(err)
+	}
+	locked := []*chart.Dependency{{Name: "alpine", Version: "0.1.0", Repository: reqs[0].Repository, Digest: "sha256:" + strings.Repeat("0", 64)}}
+	digest, err := resolver.HashReq(reqs, locked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeLock(filepath.Join(dir, "pinned"), &chart.Lock{Generated: time.Now(), Digest: digest, Dependencies: locked}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	registryClient, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")),
+		registry.ClientOptPlainHTTP(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		ChartPath:        filepath.Join(dir, "pinned"),
+		Out:              io.Discard,
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RegistryClient:   registryClient,
+		RepositoryConfig: filepath.Join(dir, "repositories.yaml"),
+		RepositoryCache:  dir,
+		ContentCache:     t.TempDir(),
+	}
+	err = m.Build()
+	assert.ErrorContains(t, err, "dependency alpine 0.1.0 has the digest sha256:1111111111111111111111111111111111111111111111111111111111111111, but the lock file (Chart.lock) pins it to sha256:0000000000000000000000000000000000000000000000000000000000000000")
+}
+
+func TestUpdate_Concurrency(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		fault repotest.Fault
+		err   string
+	}{{
+		name: "downloads",
+	}, {
+		name:  "failed downloads",
+		fault: repotest.Fault{StatusCode: http.StatusNotFound},
+		err:   "could not download",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []repotest.ServerOption{repotest.WithChartSourceGlob("testdata/*.tgz*")}
+			if tt.fault.StatusCode != 0 {
+				opts = append(opts, repotest.WithFault("/*.tgz", tt.fault))
+			}
+			srv := repotest.NewTempServer(t, opts...)
+			defer srv.Stop()
+			if err := srv.LinkIndices(); err != nil {
+				t.Fatal(err)
+			}
+			dir := func(p ...string) string {
+				return filepath.Join(append([]string{srv.Root()}, p...)...)
+			}
+
+			reqs := []*chart.Dependency{
+				{Name: "local-subchart", Version: "0.1.0", Repository: srv.URL()},
+				{Name: "signtest", Version: "0.1.0", Repository: srv.URL()},
+				{Name: "signtest", Alias: "signtest2", Version: "0.1.0", Repository: srv.URL()},
+			}
+			c := &chart.Chart{Metadata: &chart.Metadata{Name: "umbrella", Version: "0.1.0", APIVersion: chart.APIVersionV2, Dependencies: reqs}}
+			if err := chartutil.SaveDir(c, dir()); err != nil {
+				t.Fatal(err)
+			}
+			out := new(bytes.Buffer)
+			m := &Manager{
+				ChartPath:        dir("umbrella"),
+				Out:              out,
+				Getters:          getter.All(&cli.EnvSettings{}),
+				RepositoryConfig: dir("repositories.yaml"),
+				RepositoryCache:  dir(),
+				ContentCache:     t.TempDir(),
+				Concurrency:      2,
+			}
+			err := m.Update()
+			if tt.err != "" {
+				// The errors of all the failed downloads are reported.
+				require.ErrorContains(t, err, tt.err)
+				assert.Contains(t, err.Error(), srv.URL()+"/local-subchart-0.1.0.tgz")
+				assert.Contains(t, err.Error(), srv.URL()+"/signtest-0.1.0.tgz")
+				assert.NoFileExists(t, dir("umbrella", "charts", "local-subchart-0.1.0.tgz"))
+				return
+			}
+			require.NoError(t, err)
+			assert.FileExists(t, dir("umbrella", "charts", "local-subchart-0.1.0.tgz"))
+			assert.FileExists(t, dir("umbrella", "charts", "signtest-0.1.0.tgz"))
+			assert.Contains(t, out.String(), "Already downloading signtest from repo "+srv.URL())
+		})
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// DefaultRetryBackoff is the time waited before retrying a failed download
+// the first time, when ChartDownloader.RetryBackoff is not set.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryBackoff bounds the time waited between two retries.
+const maxRetryBackoff = time.Minute
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+// backoff returns the time to wait before the given retry, doubled for each
+// retry.
+func (c *ChartDownloader) backoff(retry int) time.Duration {
+	d := c.RetryBackoff
+	if d <= 0 {
+		d = DefaultRetryBackoff
+	}
+	for i := 1; i < retry && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// getWithRetries starts downloading a URL, retrying up to c.Retries times
+// when it fails with a network or server error. When reading the content of
+// an HTTP URL fails the same way, the download resumes where it stopped with
+// a range request, within the same number of retries. The range is only
+// downloaded while the content keeps the entity tag it had when the download
+// started; reading fails with getter.ErrContentChanged otherwise.
+func (c *ChartDownloader) getWithRetries(g getter.Getter, href string, options []getter.Option) (*getter.Content, error) {
+	r := &resumingReader{c: c, g: g, href: href, options: options}
+	content, err := r.get(0)
+	if err != nil {
+		return nil, err
+	}
+	if c.Retries == 0 || !resumable(href) {
+		return content, nil
+	}
+	r.rc, r.etag = content.ReadCloser, content.ETag
+	content.ReadCloser = r
+	return content, nil
+}
+
+// resumable reports whether the getter of a URL can resume a download.
+func resumable(href string) bool {
+	u, err := url.Parse(href)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// resumingReader reads the content of a URL, downloading it again from where
+// it stopped when reading it fails with a network or server error.
+type resumingReader struct {
+	c       *ChartDownloader
+	g       getter.Getter
+	href    string
+	options []getter.Option
+
+	// etag is the entity tag of the content when the download started.
+	etag    string
+	rc      io.ReadCloser
+	read    int64
+	retries int
+	// err is the error to resume the download after, at the next read.
+	err error
+	// failed is the error the download could not be resumed after.
+	failed error
+}
+
+// get starts downloading the content from an offset, retrying it while
+// retries are left.
+func (r *resumingReader) get(offset int64) (*getter.Content, error) {
+	options := r.options
+	if offset > 0 {
+		options = append(slices.Clip(options), getter.WithOffset(offset))
+		if r.etag != "" {
+			options = append(options, getter.WithIfRange(r.etag))
+		}
+	}
+	for {
+		content, err := getter.GetStream(r.g, r.href, options...)
+		if err == nil || !repo.IsFailoverError(err) || r.retries >= r.c.Retries {
+			return content, err
+		}
+		r.retries++
+		wait := r.c.backoff(r.retries)
+		slog.Warn("chart download failed, retrying", "url", r.href, "retry", r.retries, "wait", wait, slog.Any("error", err))
+		sleep(wait)
+	}
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	if r.failed != nil {
+		return 0, r.failed
+	}
+	if r.err != nil {
+		r.rc.Close()
+		r.retries++
+		wait := r.c.backoff(r.retries)
+		slog.Warn("chart download interrupted, resuming", "url", r.href, "offset", r.read, "retry", r.retries, "wait", wait, slog.Any("error", r.err))
+		sleep(wait)
+		content, err := r.get(r.read)
+		if err != nil {
+			r.failed = err
+			return 0, err
+		}
+		r.rc, r.err = content.ReadCloser, nil
+	}
+
+	n, err := r.rc.Read(p)
+	r.read += int64(n)
+	if err == nil || errors.Is(err, io.EOF) || !repo.IsFailoverError(err) || r.retries >= r.c.Retries {
+		return n, err
+	}
+	r.err = err
+	if n == 0 {
+		return r.Read(p)
+	}
+	return n, nil
+}
+
+func (r *resumingReader) Close() error {
+	return r.rc.Close()
+}
@@ -0,0 +1,276 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// CoverageKind is the kind of a block of a template.
+type CoverageKind string
+
+// The kinds of blocks recorded by Coverage.
+const (
+	// CoverageFile is a whole template file.
+	CoverageFile CoverageKind = "file"
+	// CoverageDefine is a named template, declared with define or block.
+	CoverageDefine CoverageKind = "define"
+	// CoverageIf is the body of an if action.
+	CoverageIf CoverageKind = "if"
+	// CoverageWith is the body of a with action.
+	CoverageWith CoverageKind = "with"
+	// CoverageRange is the body of a range action.
+	CoverageRange CoverageKind = "range"
+	// CoverageElse is the else branch of an if, with or range action. The
+	// else branch of an if or with action without one is the path taken when
+	// the condition is false.
+	CoverageElse CoverageKind = "else"
+)
+
+// coverageFunc is the template function the instrumented templates call when
+// entering a block.
+const coverageFunc = "_helmCoverage"
+
+// CoverageBlock is a block of a template, along with how often it was
+// executed.
+type CoverageBlock struct {
+	// File is the template file of the block, such as mychart/templates/service.yaml.
+	File string
+	// Line is the line of the block in File.
+	Line int
+	// Kind is the kind of the block.
+	Kind CoverageKind
+	// Name is the name of a named template.
+	Name string
+	// Hits is the number of times the block was executed.
+	Hits int
+}
+
+// String describes the block and its location.
+func (b CoverageBlock) String() string {
+	switch b.Kind {
+	case CoverageFile:
+		return b.File
+	case CoverageDefine:
+		return fmt.Sprintf("%s:%d: define %q", b.File, b.Line, b.Name)
+	case CoverageElse:
+		return fmt.Sprintf("%s:%d: else branch", b.File, b.Line)
+	}
+	return fmt.Sprintf("%s:%d: %s body", b.File, b.Line, b.Kind)
+}
+
+// Coverage records which template files, named templates and branches are
+// executed while rendering. Set it on an Engine to instrument its renders; a
+// Coverage shared by several renders, such as with different values,
+// accumulates their coverage.
+//
+// Templates parsed at render time by the tpl function are not recorded. The
+// templates reading the rendered manifests of other templates are rendered,
+// and recorded, twice.
+type Coverage struct {
+	mu     sync.Mutex
+	blocks map[coverageKey]*CoverageBlock
+}
+
+type coverageKey struct {
+	file string
+	pos  parse.Pos
+	kind CoverageKind
+}
+
+// NewCoverage creates an empty Coverage.
+func NewCoverage() *Coverage {
+	return &Coverage{blocks: make(map[coverageKey]*CoverageBlock)}
+}
+
+// Blocks returns the blocks of the rendered templates, ordered by file and
+// line.
+func (c *Coverage) Blocks() []CoverageBlock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	blocks := make([]CoverageBlock, 0, len(c.blocks))
+	for _, b := range c.blocks {
+		blocks = append(blocks, *b)
+	}
+	slices.SortFunc(blocks, func(a, b CoverageBlock) int {
+		return cmp.Or(
+			cmp.Compare(a.File, b.File),
+			cmp.Compare(a.Line, b.Line),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return blocks
+}
+
+// instrument adds a call to the coverage function at the start of every block
+// of the templates associated with t, and adds the coverage function itself.
+func (c *Coverage) instrument(t *template.Template) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The blocks of this render, indexed by the argument of their call.
+	var blocks []*CoverageBlock
+	block := func(tree *parse.Tree, n parse.Node, kind CoverageKind, name string) (parse.Node, error) {
+		key := coverageKey{file: tree.ParseName, pos: n.Position(), kind: kind}
+		b, ok := c.blocks[key]
+		if !ok {
+			b = &CoverageBlock{File: tree.ParseName, Line: nodeLine(tree, n), Kind: kind, Name: name}
+			c.blocks[key] = b
+		}
+		blocks = append(blocks, b)
+		return coverageCall(len(blocks) - 1)
+	}
+
+	templates := t.Templates()
+	slices.SortFunc(templates, func(a, b *template.Template) int {
+		return cmp.Compare(a.Name(), b.Name())
+	})
+	for _, tmpl := range templates {
+		tree := tmpl.Tree
+		if tree == nil || tree.Root == nil {
+			continue
+		}
+		// The tree of a template file is named after the file, while the
+		// tree of a named template is named after the template.
+		var call parse.Node
+		var err error
+		switch {
+		case tree.Name != tree.ParseName:
+			call, err = block(tree, tree.Root, CoverageDefine, tree.Name)
+		case !strings.HasPrefix(path.Base(tree.Name), "_"):
+			call, err = block(tree, tree.Root, CoverageFile, "")
+		}
+		if err != nil {
+			return err
+		}
+		if err := instrumentList(tree, tree.Root, block); err != nil {
+			return err
+		}
+		if call != nil {
+			tree.Root.Nodes = slices.Insert(tree.Root.Nodes, 0, call)
+		}
+	}
+
+	t.Funcs(template.FuncMap{
+		coverageFunc: func(i int) string {
+			c.mu.Lock()
+			blocks[i].Hits++
+			c.mu.Unlock()
+			return ""
+		},
+	})
+	return nil
+}
+
+type blockFunc func(tree *parse.Tree, n parse.Node, kind CoverageKind, name string) (parse.Node, error)
+
+// instrumentList instruments the actions of list.
+func instrumentList(tree *parse.Tree, list *parse.ListNode, block blockFunc) error {
+	if list == nil {
+		return nil
+	}
+	for _, n := range list.Nodes {
+		var branch *parse.BranchNode
+		var kind CoverageKind
+		switch n := n.(type) {
+		case *parse.IfNode:
+			branch, kind = &n.BranchNode, CoverageIf
+		case *parse.WithNode:
+			branch, kind = &n.BranchNode, CoverageWith
+		case *parse.RangeNode:
+			branch, kind = &n.BranchNode, CoverageRange
+		default:
+			continue
+		}
+		if err := instrumentBranch(tree, branch, kind, block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instrumentBranch instruments the body and the else branch of an if, with or
+// range action. An if or with action without an else branch is given an
+// empty one, to record whether its condition was ever false.
+func instrumentBranch(tree *parse.Tree, n *parse.BranchNode, kind CoverageKind, block blockFunc) error {
+	if err := instrumentList(tree, n.List, block); err != nil {
+		return err
+	}
+	if err := instrumentList(tree, n.ElseList, block); err != nil {
+		return err
+	}
+	call, err := block(tree, n, kind, "")
+	if err != nil {
+		return err
+	}
+	n.List.Nodes = slices.Insert(n.List.Nodes, 0, call)
+
+	if n.ElseList == nil {
+		if kind == CoverageRange {
+			return nil
+		}
+		n.ElseList = &parse.ListNode{NodeType: parse.NodeList, Pos: n.Pos}
+	} else if len(n.ElseList.Nodes) == 1 && isBranch(n.ElseList.Nodes[0]) {
+		// The branches of an else if or else with chain are those of the
+		// chained action.
+		return nil
+	}
+	call, err = block(tree, n.ElseList, CoverageElse, "")
+	if err != nil {
+		return err
+	}
+	n.ElseList.Nodes = slices.Insert(n.ElseList.Nodes, 0, call)
+	return nil
+}
+
+func isBranch(n parse.Node) bool {
+	switch n.(type) {
+	case *parse.IfNode, *parse.WithNode:
+		return true
+	}
+	return false
+}
+
+// coverageCall returns an action calling the coverage function for the block i.
+func coverageCall(i int) (parse.Node, error) {
+	trees, err := parse.Parse(coverageFunc, "{{"+coverageFunc+" "+strconv.Itoa(i)+"}}", "{{", "}}",
+		map[string]any{coverageFunc: true})
+	if err != nil {
+		return nil, err
+	}
+	return trees[coverageFunc].Root.Nodes[0], nil
+}
+
+// nodeLine returns the line of n in the template file of tree.
+func nodeLine(tree *parse.Tree, n parse.Node) int {
+	location, _ := tree.ErrorContext(n)
+	// The location is file:line:column.
+	parts := strings.Split(location, ":")
+	if len(parts) < 3 {
+		return 0
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-2])
+	return line
+}
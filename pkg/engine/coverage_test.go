@@ -0,0 +1,107 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/chart/common"
+)
+
+const coverageHelpers = `{{- define "mychart.name" -}}
+{{ .Values.name | default "mychart" }}
+{{- end -}}
+
+{{- define "mychart.unused" -}}
+unused
+{{- end -}}
+`
+
+const coverageService = `name: {{ include "mychart.name" . }}
+{{- if .Values.tls }}
+port: 443
+{{- else if .Values.plain }}
+port: 80
+{{- end }}
+{{- range .Values.ports }}
+- {{ . }}
+{{- end }}
+{{- with .Values.labels }}
+labels: {{ . }}
+{{- else }}
+labels: none
+{{- end }}
+`
+
+func TestCoverage(t *testing.T) {
+	render := func(e Engine, vals map[string]interface{}) map[string]string {
+		t.Helper()
+		values := common.Values{"Values": vals}
+		out, err := e.render(map[string]renderable{
+			"mychart/templates/_helpers.tpl": {tpl: coverageHelpers, vals: values},
+			"mychart/templates/service.yaml": {tpl: coverageService, vals: values},
+			"mychart/templates/unused.yaml":  {tpl: `{{ define "mychart.other" }}{{ end }}`, vals: common.Values{}},
+		})
+		require.NoError(t, err)
+		return out
+	}
+
+	coverage := NewCoverage()
+	vals := map[string]interface{}{"tls": true, "labels": "app"}
+	out := render(Engine{Coverage: coverage}, vals)
+	assert.Equal(t, render(Engine{}, vals), out, "coverage must not change the rendered output")
+
+	hits := func() map[string]int {
+		hits := make(map[string]int)
+		for _, b := range coverage.Blocks() {
+			hits[b.String()] = b.Hits
+		}
+		return hits
+	}
+	assert.Equal(t, map[string]int{
+		"mychart/templates/_helpers.tpl:2: define \"mychart.name\"":   1,
+		"mychart/templates/_helpers.tpl:6: define \"mychart.unused\"": 0,
+		"mychart/templates/service.yaml":                              1,
+		"mychart/templates/service.yaml:2: if body":                   1,
+		"mychart/templates/service.yaml:4: else branch":               0,
+		"mychart/templates/service.yaml:4: if body":                   0,
+		"mychart/templates/service.yaml:7: range body":                0,
+		"mychart/templates/service.yaml:10: with body":                1,
+		"mychart/templates/service.yaml:12: else branch":              0,
+		"mychart/templates/unused.yaml":                               1,
+		"mychart/templates/unused.yaml:1: define \"mychart.other\"":   0,
+	}, hits())
+
+	// Renders with other values add to the coverage.
+	render(Engine{Coverage: coverage}, map[string]interface{}{"plain": true, "ports": []int{80, 8080}})
+	assert.Equal(t, map[string]int{
+		"mychart/templates/_helpers.tpl:2: define \"mychart.name\"":   2,
+		"mychart/templates/_helpers.tpl:6: define \"mychart.unused\"": 0,
+		"mychart/templates/service.yaml":                              2,
+		"mychart/templates/service.yaml:2: if body":                   1,
+		"mychart/templates/service.yaml:4: else branch":               0,
+		"mychart/templates/service.yaml:4: if body":                   1,
+		"mychart/templates/service.yaml:7: range body":                2,
+		"mychart/templates/service.yaml:10: with body":                1,
+		"mychart/templates/service.yaml:12: else branch":              1,
+		"mychart/templates/unused.yaml":                               2,
+		"mychart/templates/unused.yaml:1: define \"mychart.other\"":   0,
+	}, hits())
+}
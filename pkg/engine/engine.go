@@ -17,6 +17,7 @@ limitations under the License.
 package engine
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -24,8 +25,10 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"text/template"
 
 	"k8s.io/client-go/rest"
@@ -47,6 +50,9 @@ type Engine struct {
 	EnableDNS bool
 	// CustomTemplateFuncs is defined by users to provide custom template funcs
 	CustomTemplateFuncs template.FuncMap
+	// Coverage, when set, records the template files, named templates and
+	// branches executed by the renders of the engine
+	Coverage *Coverage
 }
 
 // New creates a new instance of Engine using the passed in rest config.
@@ -131,7 +137,8 @@ func warnWrap(warn string) string {
 // well as regular file-loaded templates.
 func includeFun(t *template.Template, includedNames map[string]int) func(string, interface{}) (string, error) {
 	return func(name string, data interface{}) (string, error) {
-		var buf strings.Builder
+		buf := getBuffer()
+		defer putBuffer(buf)
 		if v, ok := includedNames[name]; ok {
 			if v > recursionMaxNums {
 				return "", fmt.Errorf(
@@ -142,7 +149,7 @@ func includeFun(t *template.Template, includedNames map[string]int) func(string,
 		} else {
 			includedNames[name] = 1
 		}
-		err := t.ExecuteTemplate(&buf, name, data)
+		err := t.ExecuteTemplate(buf, name, data)
 		includedNames[name]--
 		return buf.String(), err
 	}
@@ -184,24 +191,26 @@ func tplFun(parent *template.Template, includedNames map[string]int, strict bool
 			return "", fmt.Errorf("cannot parse template %q: %w", tpl, err)
 		}
 
-		var buf strings.Builder
-		if err := t.Execute(&buf, vals); err != nil {
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := t.Execute(buf, vals); err != nil {
 			return "", fmt.Errorf("error during tpl function execution for %q: %w", tpl, err)
 		}
 
 		// See comment in renderWithReferences explaining the <no value> hack.
-		return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+		return removeNoValue(buf), nil
 	}
 }
 
 // initFunMap creates the Engine's FuncMap and adds context-specific functions.
-func (e Engine) initFunMap(t *template.Template) {
+func (e Engine) initFunMap(t *template.Template, manifests *renderedManifests) {
 	funcMap := funcMap()
 	includedNames := make(map[string]int)
 
 	// Add the template-rendering functions here so we can close over t.
 	funcMap["include"] = includeFun(t, includedNames)
 	funcMap["tpl"] = tplFun(t, includedNames, e.Strict)
+	funcMap["renderedManifest"] = manifests.get
 
 	// Add the `required` function here so we can use lintMode
 	funcMap["required"] = func(warn string, val interface{}) (interface{}, error) {
@@ -278,7 +287,8 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 		t.Option("missingkey=zero")
 	}
 
-	e.initFunMap(t)
+	manifests := newRenderedManifests(tpls)
+	e.initFunMap(t, manifests)
 
 	// We want to parse the templates in a predictable order. The order favors
 	// higher-level (in file system) templates over deeply nested templates.
@@ -291,28 +301,86 @@ func (e Engine) render(tpls map[string]renderable) (rendered map[string]string,
 		}
 	}
 
-	rendered = make(map[string]string, len(keys))
-	for _, filename := range keys {
-		// Don't render partials. We don't care out the direct output of partials.
-		// They are only included from other templates.
-		if strings.HasPrefix(path.Base(filename), "_") {
-			continue
+	if e.Coverage != nil {
+		if err := e.Coverage.instrument(t); err != nil {
+			return map[string]string{}, fmt.Errorf("unable to instrument templates for coverage: %w", err)
 		}
+	}
+
+	// Don't render partials. We don't care out the direct output of partials.
+	// They are only included from other templates.
+	keys = slices.DeleteFunc(keys, func(filename string) bool {
+		return strings.HasPrefix(path.Base(filename), "_")
+	})
+
+	if err := executeTemplates(t, tpls, keys, manifests); err != nil {
+		return map[string]string{}, err
+	}
+	return manifests.rendered, nil
+}
+
+// executeTemplates renders the templates of keys, in order, into the rendered
+// manifests. The templates reading the manifests of others render them first.
+func executeTemplates(t *template.Template, tpls map[string]renderable, keys []string, manifests *renderedManifests) error {
+	manifests.render = func(filename string) (string, error) {
+		buf := getBuffer()
+		defer putBuffer(buf)
 		// At render time, add information about the template that is being rendered.
 		vals := tpls[filename].vals
+		if len(manifests.rendering) > 1 {
+			// The templates of a chart share their values, so the template
+			// reading this manifest gets its own information back.
+			defer func(reader interface{}) { vals["Template"] = reader }(vals["Template"])
+		}
 		vals["Template"] = common.Values{"Name": filename, "BasePath": tpls[filename].basePath}
-		var buf strings.Builder
-		if err := t.ExecuteTemplate(&buf, filename, vals); err != nil {
-			return map[string]string{}, reformatExecErrorMsg(filename, err)
+		if err := t.ExecuteTemplate(buf, filename, vals); err != nil {
+			return "", reformatExecErrorMsg(filename, err)
 		}
 
 		// Work around the issue where Go will emit "<no value>" even if Options(missing=zero)
 		// is set. Since missing=error will never get here, we do not need to handle
 		// the Strict case.
-		rendered[filename] = strings.ReplaceAll(buf.String(), "<no value>", "")
+		return removeNoValue(buf), nil
+	}
+	for _, filename := range keys {
+		if _, err := manifests.manifest(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bufferPool holds the buffers templates are executed into. Reusing them
+// avoids growing a new buffer for every template and every include.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// maxPooledBufferSize keeps exceptionally large buffers out of the pool.
+const maxPooledBufferSize = 1 << 20
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
 	}
+}
 
-	return rendered, nil
+var noValue = []byte("<no value>")
+
+// removeNoValue returns the content of buf without the "<no value>" markers
+// emitted by text/template for missing values.
+func removeNoValue(buf *bytes.Buffer) string {
+	b := buf.Bytes()
+	if !bytes.Contains(b, noValue) {
+		return string(b)
+	}
+	return string(bytes.ReplaceAll(b, noValue, nil))
 }
 
 func cleanupParseError(filename string, err error) error {
@@ -567,6 +635,7 @@ func recAllTpls(c ci.Charter, templates map[string]renderable, values common.Val
 		sub, _ := ci.NewAccessor(child)
 		subCharts[sub.Name()] = recAllTpls(child, templates, next)
 	}
+	next["Dependencies"] = dependencyMetadata(accessor, subCharts)
 
 	newParentID := accessor.ChartFullPath()
 	for _, t := range accessor.Templates() {
@@ -586,6 +655,62 @@ func recAllTpls(c ci.Charter, templates map[string]renderable, values common.Val
 	return next
 }
 
+// dependencyMetadata describes the dependencies of a chart for its templates,
+// keyed by their name or alias. Each one has the Name, Alias, Repository,
+// Condition and Tags declared in Chart.yaml, the Constraint on its version,
+// whether it is Enabled, and the Version and AppVersion of the subchart.
+//
+// Dependencies disabled by their condition or tags are listed too, without a
+// Version or AppVersion as their subcharts are not loaded.
+func dependencyMetadata(accessor ci.Accessor, subCharts map[string]interface{}) map[string]interface{} {
+	deps := make(map[string]interface{})
+	add := func(d ci.Dependency) {
+		dep, err := ci.NewDependencyAccessor(d)
+		if err != nil {
+			slog.Error("error accessing chart dependency", "error", err)
+			return
+		}
+		deps[dep.Name()] = map[string]interface{}{
+			"Name":       dep.Name(),
+			"Alias":      dep.Alias(),
+			"Repository": dep.Repository(),
+			"Condition":  dep.Condition(),
+			"Tags":       dep.Tags(),
+			"Constraint": dep.Version(),
+			"Enabled":    dep.Enabled(),
+			"Version":    "",
+			"AppVersion": "",
+		}
+	}
+	for _, d := range accessor.MetaDependencies() {
+		add(d)
+	}
+	for _, d := range accessor.DisabledDependencies() {
+		add(d)
+	}
+
+	// Subcharts found in the charts directory need not be declared in Chart.yaml.
+	for name, sub := range subCharts {
+		chartMetaData, _ := sub.(map[string]interface{})["Chart"].(map[string]interface{})
+		dep, ok := deps[name].(map[string]interface{})
+		if !ok {
+			dep = map[string]interface{}{
+				"Name":       name,
+				"Alias":      "",
+				"Repository": "",
+				"Condition":  "",
+				"Tags":       []string(nil),
+				"Constraint": "",
+			}
+			deps[name] = dep
+		}
+		dep["Enabled"] = true
+		dep["Version"] = chartMetaData["Version"]
+		dep["AppVersion"] = chartMetaData["AppVersion"]
+	}
+	return deps
+}
+
 // isTemplateValid returns true if the template is valid for the chart type
 func isTemplateValid(accessor ci.Accessor, templateName string) bool {
 	if accessor.IsLibraryChart() {
@@ -35,6 +35,7 @@ import (
 	"helm.sh/helm/v4/pkg/chart/common"
 	"helm.sh/helm/v4/pkg/chart/common/util"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 )
 
 func TestSortTemplates(t *testing.T) {
@@ -804,6 +805,52 @@ func TestRenderBuiltinValues(t *testing.T) {
 
 }
 
+func TestRenderDependencyMetadata(t *testing.T) {
+	tpl := `{{range $name, $dep := .Dependencies}}{{$name}}:{{$dep.Name}},{{$dep.Version}},{{$dep.AppVersion}},{{$dep.Constraint}},{{$dep.Enabled}};{{end}}`
+	ch := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "umbrella",
+			APIVersion: chart.APIVersionV2,
+			Dependencies: []*chart.Dependency{
+				{Name: "postgresql", Version: "~12.1", Repository: "https://charts.example.com", Condition: "postgresql.enabled"},
+				{Name: "redis", Alias: "cache", Version: "^17.0.0", Repository: "https://charts.example.com"},
+				{Name: "mysql", Version: "9.x", Condition: "mysql.enabled"},
+			},
+		},
+		Templates: []*common.File{
+			{Name: "templates/deps", Data: []byte(tpl)},
+			{Name: "templates/cache", Data: []byte(`{{.Dependencies.cache.Repository}} {{.Dependencies.cache.Alias}}`)},
+		},
+		Values: map[string]interface{}{"mysql": map[string]interface{}{"enabled": false}},
+	}
+	ch.AddDependency(
+		&chart.Chart{Metadata: &chart.Metadata{Name: "postgresql", Version: "12.1.6", AppVersion: "15.2.0"}},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "redis", Version: "17.3.1", AppVersion: "7.0.5"}},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "mysql", Version: "9.4.5", AppVersion: "8.0.32"}},
+		&chart.Chart{Metadata: &chart.Metadata{Name: "vendored", Version: "0.1.0", AppVersion: "1.0"}},
+	)
+	if err := chartutil.ProcessDependencies(ch, common.Values{}); err != nil {
+		t.Fatalf("failed to process dependencies: %s", err)
+	}
+
+	out, err := Render(ch, common.Values{"Values": ch.Values})
+	if err != nil {
+		t.Fatalf("failed to render templates: %s", err)
+	}
+
+	expect := "cache:cache,17.3.1,7.0.5,^17.0.0,true;" +
+		"mysql:mysql,,,9.x,false;" +
+		"postgresql:postgresql,12.1.6,15.2.0,~12.1,true;" +
+		"vendored:vendored,0.1.0,1.0,,true;"
+	if got := out["umbrella/templates/deps"]; got != expect {
+		t.Errorf("Expected %q, got %q", expect, got)
+	}
+	expect = "https://charts.example.com cache"
+	if got := out["umbrella/templates/cache"]; got != expect {
+		t.Errorf("Expected %q, got %q", expect, got)
+	}
+}
+
 func TestAlterFuncMap_include(t *testing.T) {
 	c := &chart.Chart{
 		Metadata: &chart.Metadata{Name: "conrad"},
@@ -1476,3 +1523,237 @@ func TestTraceableError_NoTemplateForm(t *testing.T) {
 		}
 	}
 }
+
+// benchmarkUmbrellaChart builds an umbrella chart with the given number of
+// subcharts, each rendering a few templates that lean on include and toYaml.
+func benchmarkUmbrellaChart(subcharts int) *chart.Chart {
+	helpers := `{{- define "bench.labels" -}}
+app.kubernetes.io/name: {{ .Chart.Name }}
+app.kubernetes.io/instance: {{ .Release.Name }}
+app.kubernetes.io/version: {{ .Chart.Version | quote }}
+{{- end -}}
+{{- define "bench.fullname" -}}
+{{ printf "%s-%s" .Release.Name .Chart.Name | trunc 63 | trimSuffix "-" }}
+{{- end -}}`
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "bench.fullname" . }}
+  labels:
+    {{- include "bench.labels" . | nindent 4 }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    metadata:
+      labels:
+        {{- include "bench.labels" . | nindent 8 }}
+    spec:
+      containers:
+      {{- range .Values.containers }}
+      - name: {{ .name }}
+        image: {{ .image }}
+        env:
+        {{- toYaml .env | nindent 8 }}
+      {{- end }}
+`
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ include "bench.fullname" . }}
+  labels:
+    {{- include "bench.labels" . | nindent 4 }}
+data:
+  {{- range $k, $v := .Values.config }}
+  {{ $k }}: {{ $v | quote }}
+  {{- end }}
+`
+	values := func() map[string]interface{} {
+		config := make(map[string]interface{})
+		for i := range 50 {
+			config[fmt.Sprintf("key%d", i)] = fmt.Sprintf("value%d", i)
+		}
+		var containers []interface{}
+		for i := range 5 {
+			var env []interface{}
+			for j := range 10 {
+				env = append(env, map[string]interface{}{"name": fmt.Sprintf("ENV_%d", j), "value": fmt.Sprintf("%d", j)})
+			}
+			containers = append(containers, map[string]interface{}{"name": fmt.Sprintf("c%d", i), "image": "nginx:1.27", "env": env})
+		}
+		return map[string]interface{}{
+			"replicaCount": 2,
+			"config":       config,
+			"containers":   containers,
+			"global":       map[string]interface{}{"registry": "example.com"},
+		}
+	}
+	templates := func() []*common.File {
+		return []*common.File{
+			{Name: "templates/_helpers.tpl", Data: []byte(helpers)},
+			{Name: "templates/deployment.yaml", Data: []byte(deployment)},
+			{Name: "templates/configmap.yaml", Data: []byte(configMap)},
+		}
+	}
+
+	umbrella := &chart.Chart{
+		Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "umbrella", Version: "1.0.0"},
+		Templates: templates(),
+		Values:    values(),
+	}
+	for i := range subcharts {
+		umbrella.AddDependency(&chart.Chart{
+			Metadata:  &chart.Metadata{APIVersion: chart.APIVersionV2, Name: fmt.Sprintf("sub%d", i), Version: "1.0.0"},
+			Templates: templates(),
+			Values:    values(),
+		})
+	}
+	return umbrella
+}
+
+func TestRenderCapabilitiesHelpers(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "caps", Version: "1.0.0"},
+		Templates: []*common.File{
+			{Name: "templates/route", Data: []byte(`{{ if .Capabilities.HasAPI "gateway.networking.k8s.io/v1" }}gateway{{ else }}ingress{{ end }}`)},
+			{Name: "templates/version", Data: []byte(`{{ .Capabilities.KubeVersion.AtLeast "1.29" }} {{ .Capabilities.KubeVersion.LessThan "1.29" }}`)},
+			{Name: "templates/invalid", Data: []byte(`{{ .Capabilities.KubeVersion.AtLeast .Values.version }}`)},
+		},
+	}
+	kubeVersion, err := common.ParseKubeVersion("v1.29.3-eks-ad3f6b8")
+	assert.NoError(t, err)
+	caps := &common.Capabilities{
+		KubeVersion: *kubeVersion,
+		APIVersions: common.VersionSet{"v1", "gateway.networking.k8s.io/v1"},
+	}
+	options := common.ReleaseOptions{Name: "caps", Namespace: "default"}
+
+	vals, err := util.ToRenderValues(c, map[string]interface{}{"version": "1.29"}, options, caps)
+	assert.NoError(t, err)
+	out, err := Render(c, vals)
+	assert.NoError(t, err)
+	assert.Equal(t, "gateway", out["caps/templates/route"])
+	assert.Equal(t, "true false", out["caps/templates/version"])
+
+	vals, err = util.ToRenderValues(c, map[string]interface{}{"version": "latest"}, options, caps)
+	assert.NoError(t, err)
+	_, err = Render(c, vals)
+	assert.ErrorContains(t, err, `invalid version "latest"`)
+}
+
+func TestRenderedManifest(t *testing.T) {
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{Name: "parent"},
+		Templates: []*common.File{
+			{Name: "templates/configmap.yaml", Data: []byte(`greeting: {{ .Values.greeting }}`)},
+			{Name: "templates/deployment.yaml", Data: []byte(`checksum: {{ renderedManifest (print .Template.BasePath "/configmap.yaml") | sha256sum }}`)},
+			{Name: "templates/service.yaml", Data: []byte(`{{ renderedManifest "parent/templates/deployment.yaml" }}`)},
+			{Name: "templates/child.yaml", Data: []byte(`{{ renderedManifest "parent/charts/child/templates/secret.yaml" }}`)},
+		},
+	}
+	c.AddDependency(&chart.Chart{
+		Metadata: &chart.Metadata{Name: "child"},
+		Templates: []*common.File{
+			{Name: "templates/secret.yaml", Data: []byte(`password: {{ .Values.password }}`)},
+		},
+	})
+	vals := common.Values{"Values": map[string]interface{}{
+		"greeting": "hello",
+		"child":    map[string]interface{}{"password": "secret"},
+	}}
+
+	out, err := Render(c, vals)
+	assert.NoError(t, err)
+	checksum := "checksum: 3c6ac18a67305bf96690d803fc1843912c72a2f2f2edabd2159133a62933f7f6"
+	assert.Equal(t, checksum, out["parent/templates/deployment.yaml"], "the checksum of the rendered configmap")
+	assert.Equal(t, checksum, out["parent/templates/service.yaml"], "the manifests read manifests reading others")
+	assert.Equal(t, "password: secret", out["parent/templates/child.yaml"], "the manifests are rendered with their own values")
+}
+
+func TestRenderedManifestOnDemand(t *testing.T) {
+	vals := common.Values{"Values": map[string]interface{}{}}
+	tpls := map[string]renderable{
+		"a": {tpl: `{{ (fromYaml (renderedManifest "b")).name | required "b has no name" }}`, vals: vals},
+		"b": {tpl: `name: {{ .Template.Name }}`, vals: vals},
+		"c": {tpl: `{{ if renderedManifest "b" }}{{ renderedManifest "d" }}{{ end }} {{ .Template.Name }}`, vals: vals},
+		"d": {tpl: `{{ if not (renderedManifest "b") }}{{ fail "b is empty" }}{{ end }}d`, vals: vals},
+	}
+
+	out, err := new(Engine).render(tpls)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", out["a"], "the manifest read is rendered before it is read")
+	assert.Equal(t, "d c", out["c"], "the manifests read in branches are rendered, and the reader keeps its own information")
+	assert.Equal(t, "d", out["d"])
+}
+
+func TestRenderedManifestErrors(t *testing.T) {
+	vals := common.Values{"Values": map[string]interface{}{}}
+	cases := []struct {
+		name     string
+		tpls     map[string]renderable
+		expected string
+	}{
+		{
+			name: "cycle",
+			tpls: map[string]renderable{
+				"a": {tpl: `{{ renderedManifest "b" }}`, vals: vals},
+				"b": {tpl: `{{ renderedManifest "c" }}`, vals: vals},
+				"c": {tpl: `{{ renderedManifest "b" }}`, vals: vals},
+			},
+			expected: "templates read the rendered manifests of each other: c -> b -> c",
+		},
+		{
+			name: "own manifest",
+			tpls: map[string]renderable{
+				"a": {tpl: `{{ renderedManifest "a" }}`, vals: vals},
+			},
+			expected: `template "a" reads its own rendered manifest`,
+		},
+		{
+			name: "missing template",
+			tpls: map[string]renderable{
+				"a": {tpl: `{{ renderedManifest "missing" }}`, vals: vals},
+			},
+			expected: `no rendered manifest of template "missing"`,
+		},
+		{
+			name: "partial",
+			tpls: map[string]renderable{
+				"a":            {tpl: `{{ renderedManifest "_helpers.tpl" }}`, vals: vals},
+				"_helpers.tpl": {tpl: `{{ define "helper" }}{{ end }}`, vals: vals},
+			},
+			expected: `no rendered manifest of template "_helpers.tpl"`,
+		},
+		{
+			name: "cycle through a branch",
+			tpls: map[string]renderable{
+				"a": {tpl: `{{ if renderedManifest "b" }}{{ renderedManifest "c" }}{{ end }}`, vals: vals},
+				"b": {tpl: `b`, vals: vals},
+				"c": {tpl: `{{ renderedManifest "a" }}`, vals: vals},
+			},
+			expected: "templates read the rendered manifests of each other",
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := new(Engine).render(tt.tpls)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func BenchmarkRenderUmbrellaChart(b *testing.B) {
+	c := benchmarkUmbrellaChart(20)
+	options := common.ReleaseOptions{Name: "bench", Namespace: "default", Revision: 1, IsInstall: true}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		vals, err := util.ToRenderValues(c, map[string]interface{}{"replicaCount": 3}, options, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := Render(c, vals); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -39,6 +39,7 @@ import (
 //
 //   - "include"
 //   - "tpl"
+//   - "renderedManifest"
 //
 // These are late-bound in Engine.Render().  The
 // version included in the FuncMap is a placeholder.
@@ -67,6 +68,9 @@ func funcMap() template.FuncMap {
 		"include":  func(string, interface{}) string { return "not implemented" },
 		"tpl":      func(string, interface{}) interface{} { return "not implemented" },
 		"required": func(string, interface{}) (interface{}, error) { return "not implemented", nil },
+		// The rendered manifests of the other templates are late-bound to a
+		// render as well.
+		"renderedManifest": func(string) (string, error) { return "", nil },
 		// Provide a placeholder for the "lookup" function, which requires a kubernetes
 		// connection.
 		"lookup": func(string, string, string, string) (map[string]interface{}, error) {
@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package engine
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// renderedManifests implements the renderedManifest function, which returns
+// the rendered manifest of another template of the release, such as:
+//
+//	checksum/config: {{ renderedManifest (print $.Template.BasePath "/configmap.yaml") | sha256sum }}
+//
+// Unlike include, the manifest is the one rendered with the values of the
+// template, not of the caller. Templates are rendered on demand: a template
+// whose manifest is read is rendered then, if it was not already, and each
+// template is rendered once.
+type renderedManifests struct {
+	tpls map[string]renderable
+	// render renders a template into its manifest.
+	render func(filename string) (string, error)
+	// rendering are the templates being rendered, each reading the manifest
+	// of the next one.
+	rendering []string
+	// rendered are the manifests rendered so far.
+	rendered map[string]string
+}
+
+func newRenderedManifests(tpls map[string]renderable) *renderedManifests {
+	return &renderedManifests{tpls: tpls, rendered: map[string]string{}}
+}
+
+// get returns the rendered manifest of a template, rendering it if needed, and
+// fails when templates read the manifests of each other.
+func (r *renderedManifests) get(name string) (string, error) {
+	if _, ok := r.tpls[name]; !ok || strings.HasPrefix(path.Base(name), "_") {
+		return "", fmt.Errorf("no rendered manifest of template %q: it does not exist or it is a partial", name)
+	}
+	if i := slices.Index(r.rendering, name); i >= 0 {
+		if i == len(r.rendering)-1 {
+			return "", fmt.Errorf("template %q reads its own rendered manifest", name)
+		}
+		cycle := append(slices.Clone(r.rendering[i:]), name)
+		return "", fmt.Errorf("templates read the rendered manifests of each other: %s", strings.Join(cycle, " -> "))
+	}
+	return r.manifest(name)
+}
+
+// manifest returns the rendered manifest of a template, rendering it unless
+// it already was.
+func (r *renderedManifests) manifest(filename string) (string, error) {
+	if m, ok := r.rendered[filename]; ok {
+		return m, nil
+	}
+	r.rendering = append(r.rendering, filename)
+	m, err := r.render(filename)
+	r.rendering = r.rendering[:len(r.rendering)-1]
+	if err != nil {
+		return "", err
+	}
+	r.rendered[filename] = m
+	return m, nil
+}
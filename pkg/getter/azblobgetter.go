@@ -0,0 +1,293 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// azureStorageVersion is the version of the Blob service API requested,
+	// which authenticating with Microsoft Entra ID requires.
+	azureStorageVersion = "2023-11-03"
+	azureStorageScope   = "https://storage.azure.com/.default"
+)
+
+// NewAzureBlobGetter constructs a Getter for azblob://<container>/<blob>
+// URLs, reading the blobs of Azure Blob Storage with the credentials found
+// like the Azure SDKs find them:
+//
+//   - the AZURE_STORAGE_CONNECTION_STRING environment variable
+//   - the account key of AZURE_STORAGE_KEY, or the SAS token of
+//     AZURE_STORAGE_SAS_TOKEN
+//   - the service principal of AZURE_TENANT_ID, AZURE_CLIENT_ID and
+//     AZURE_CLIENT_SECRET, or the workload identity of
+//     AZURE_FEDERATED_TOKEN_FILE, as on AKS
+//   - the managed identity of the machine
+//
+// The blobs are read anonymously when there are none. The storage account is
+// the one of the connection string, or AZURE_STORAGE_ACCOUNT.
+func NewAzureBlobGetter(options ...Option) (Getter, error) {
+	s := &azureBlobStore{}
+	g := newObjectStoreGetter(s, options...)
+	s.client = g.httpClient
+	return g, nil
+}
+
+// azureBlobStore is Azure Blob Storage.
+type azureBlobStore struct {
+	// client returns the client of the requests for access tokens, with the
+	// TLS and proxy settings of the getter.
+	client func() (*http.Client, error)
+
+	once    sync.Once
+	initErr error
+	account string
+	// endpoint is the URL of the Blob service of the account.
+	endpoint string
+
+	sharedKey []byte
+	sas       url.Values
+	tokens    oauth2.TokenSource
+}
+
+func (s *azureBlobStore) objectURL(u *url.URL) (string, error) {
+	container, blob := u.Host, strings.TrimPrefix(u.Path, "/")
+	if container == "" {
+		return "", errors.New("missing container")
+	}
+	s.once.Do(func() { s.initErr = s.init() })
+	if s.initErr != nil {
+		return "", s.initErr
+	}
+	return s.endpoint + escapePath("/"+container+"/"+blob), nil
+}
+
+// init finds the account and its credentials in the environment.
+func (s *azureBlobStore) init() error {
+	s.account = os.Getenv("AZURE_STORAGE_ACCOUNT")
+	key, sas := os.Getenv("AZURE_STORAGE_KEY"), os.Getenv("AZURE_STORAGE_SAS_TOKEN")
+	if conn := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); conn != "" {
+		fields := map[string]string{}
+		for field := range strings.SplitSeq(conn, ";") {
+			if name, value, ok := strings.Cut(field, "="); ok {
+				fields[strings.ToLower(strings.TrimSpace(name))] = value
+			}
+		}
+		s.account, key, sas = fields["accountname"], fields["accountkey"], fields["sharedaccesssignature"]
+		if endpoint := fields["blobendpoint"]; endpoint != "" {
+			s.endpoint = strings.TrimSuffix(endpoint, "/")
+		} else if s.account != "" {
+			protocol, suffix := fields["defaultendpointsprotocol"], fields["endpointsuffix"]
+			if protocol == "" {
+				protocol = "https"
+			}
+			if suffix == "" {
+				suffix = "core.windows.net"
+			}
+			s.endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, s.account, suffix)
+		}
+	}
+	if s.endpoint == "" {
+		if s.account == "" {
+			return errors.New("no storage account: set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING")
+		}
+		s.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", s.account)
+	}
+
+	switch {
+	case key != "":
+		sharedKey, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return fmt.Errorf("invalid storage account key: %w", err)
+		}
+		s.sharedKey = sharedKey
+	case sas != "":
+		sasValues, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return fmt.Errorf("invalid SAS token: %w", err)
+		}
+		s.sas = sasValues
+	default:
+		tokens, err := findAzureCredentials(s.client)
+		if err != nil {
+			return fmt.Errorf("unable to get Azure credentials: %w", err)
+		}
+		if tokens == nil {
+			slog.Debug("no Azure credentials found, reading Blob Storage anonymously")
+		}
+		s.tokens = tokens
+	}
+	return nil
+}
+
+func (s *azureBlobStore) anonymous() bool {
+	return s.initErr == nil && s.sharedKey == nil && s.sas == nil && s.tokens == nil
+}
+
+func (s *azureBlobStore) authorize(req *http.Request) error {
+	req.Header.Set("X-Ms-Version", azureStorageVersion)
+	switch {
+	case s.sharedKey != nil:
+		s.signSharedKey(req, time.Now())
+	case s.sas != nil:
+		query := req.URL.Query()
+		for name, values := range s.sas {
+			query[name] = values
+		}
+		req.URL.RawQuery = query.Encode()
+	case s.tokens != nil:
+		if err := bearerToken(req, s.tokens); err != nil {
+			return fmt.Errorf("unable to get Azure credentials: %w", err)
+		}
+	}
+	return nil
+}
+
+// signSharedKey signs a request without a body with the key of the account.
+func (s *azureBlobStore) signSharedKey(req *http.Request, now time.Time) {
+	req.Header.Set("X-Ms-Date", now.UTC().Format(http.TimeFormat))
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		if name = strings.ToLower(name); strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	slices.Sort(msHeaders)
+
+	resource := "/" + s.account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		values := slices.Sorted(slices.Values(query[name]))
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		"", // Content-Length, empty without a body
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, replaced by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		resource,
+	}, "\n")
+	signature := base64.StdEncoding.EncodeToString(hmacSHA256(s.sharedKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", s.account, signature))
+}
+
+// findAzureCredentials looks for the credentials of Microsoft Entra ID in
+// the order of the default credential chain of the Azure SDKs.
+func findAzureCredentials(httpClient func() (*http.Client, error)) (oauth2.TokenSource, error) {
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = "https://login.microsoftonline.com"
+	}
+	tenant, client := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), tenant)
+	ctx, err := clientContext(httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if secret := os.Getenv("AZURE_CLIENT_SECRET"); tenant != "" && client != "" && secret != "" {
+		cfg := &clientcredentials.Config{
+			ClientID:     client,
+			ClientSecret: secret,
+			TokenURL:     tokenURL,
+			Scopes:       []string{azureStorageScope},
+			AuthStyle:    oauth2.AuthStyleInParams,
+		}
+		return cfg.TokenSource(ctx), nil
+	}
+	if file := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tenant != "" && client != "" && file != "" {
+		return oauth2.ReuseTokenSource(nil, &federatedTokenSource{ctx: ctx, client: client, tokenURL: tokenURL, file: file}), nil
+	}
+
+	endpoint := os.Getenv("AZURE_POD_IDENTITY_AUTHORITY_HOST")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {"https://storage.azure.com/"}}
+	if client != "" {
+		query.Set("client_id", client)
+	}
+	tokens := &metadataTokenSource{
+		url:    strings.TrimSuffix(endpoint, "/") + "/metadata/identity/oauth2/token?" + query.Encode(),
+		header: http.Header{"Metadata": {"true"}},
+	}
+	token, err := tokens.Token()
+	if err != nil {
+		// Most likely not running on Azure
+		slog.Debug("unable to get the managed identity of the Azure machine", slog.Any("error", err))
+		return nil, nil
+	}
+	return oauth2.ReuseTokenSource(token, tokens), nil
+}
+
+// federatedTokenSource exchanges the token of a workload identity, which is
+// renewed in its file, for access tokens of its application.
+type federatedTokenSource struct {
+	// ctx holds the HTTP client of the token requests.
+	ctx      context.Context
+	client   string
+	tokenURL string
+	file     string
+}
+
+func (s *federatedTokenSource) Token() (*oauth2.Token, error) {
+	assertion, err := os.ReadFile(s.file)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &clientcredentials.Config{
+		ClientID: s.client,
+		TokenURL: s.tokenURL,
+		Scopes:   []string{azureStorageScope},
+		EndpointParams: url.Values{
+			"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+			"client_assertion":      {strings.TrimSpace(string(assertion))},
+		},
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	return cfg.Token(s.ctx)
+}
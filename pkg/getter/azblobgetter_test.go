@@ -0,0 +1,236 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearAzureEnv clears the Azure credentials and settings of the
+// environment.
+func clearAzureEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_KEY", "AZURE_STORAGE_SAS_TOKEN", "AZURE_STORAGE_CONNECTION_STRING",
+		"AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET", "AZURE_FEDERATED_TOKEN_FILE", "AZURE_AUTHORITY_HOST",
+	} {
+		t.Setenv(name, "")
+	}
+	// Nothing listens on port 1.
+	t.Setenv("AZURE_POD_IDENTITY_AUTHORITY_HOST", "http://127.0.0.1:1")
+}
+
+func TestAzureBlobGetter(t *testing.T) {
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		if r.URL.Path != "/devstoreaccount1/charts/stable/index.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "index")
+	}))
+	defer srv.Close()
+	key := base64.StdEncoding.EncodeToString([]byte("account key"))
+
+	for _, tt := range []struct {
+		name  string
+		setup func(t *testing.T)
+		check func(t *testing.T, req *http.Request)
+	}{
+		{
+			name: "connection string with an account key",
+			setup: func(t *testing.T) {
+				t.Setenv("AZURE_STORAGE_CONNECTION_STRING", fmt.Sprintf("DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey=%s;BlobEndpoint=%s/devstoreaccount1;", key, srv.URL))
+			},
+			check: func(t *testing.T, req *http.Request) {
+				t.Helper()
+				if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "SharedKey devstoreaccount1:") {
+					t.Errorf("expected a request signed with the account key, got Authorization %q", auth)
+				}
+				if req.Header.Get("X-Ms-Date") == "" {
+					t.Error("expected the signed request to be dated")
+				}
+			},
+		},
+		{
+			name: "connection string with a SAS token",
+			setup: func(t *testing.T) {
+				t.Setenv("AZURE_STORAGE_CONNECTION_STRING", fmt.Sprintf("BlobEndpoint=%s/devstoreaccount1;SharedAccessSignature=sv=2023-11-03&sp=r&sig=signature", srv.URL))
+			},
+			check: func(t *testing.T, req *http.Request) {
+				t.Helper()
+				if sig := req.URL.Query().Get("sig"); sig != "signature" {
+					t.Errorf("expected the SAS token in the query, got %q", req.URL.RawQuery)
+				}
+			},
+		},
+		{
+			name: "anonymous",
+			setup: func(t *testing.T) {
+				t.Setenv("AZURE_STORAGE_CONNECTION_STRING", fmt.Sprintf("BlobEndpoint=%s/devstoreaccount1", srv.URL))
+			},
+			check: func(t *testing.T, req *http.Request) {
+				t.Helper()
+				if auth := req.Header.Get("Authorization"); auth != "" || req.URL.RawQuery != "" {
+					t.Errorf("expected an anonymous request, got Authorization %q and query %q", auth, req.URL.RawQuery)
+				}
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAzureEnv(t)
+			tt.setup(t)
+			g, err := NewAzureBlobGetter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := g.Get("azblob://charts/stable/index.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != "index" {
+				t.Errorf("expected the content of the blob, got %q", buf.String())
+			}
+			if v := req.Header.Get("X-Ms-Version"); v != azureStorageVersion {
+				t.Errorf("expected version %s of the API, got %q", azureStorageVersion, v)
+			}
+			tt.check(t, req)
+		})
+	}
+}
+
+func TestAzureBlobObjectURL(t *testing.T) {
+	clearAzureEnv(t)
+	g, err := NewAzureBlobGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("azblob://charts/index.yaml"); err == nil || !strings.Contains(err.Error(), "no storage account") {
+		t.Errorf("expected an error for the missing storage account, got %v", err)
+	}
+
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "helmcharts")
+	s := &azureBlobStore{}
+	u, err := url.Parse("azblob://charts/stable/app+1.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.objectURL(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://helmcharts.blob.core.windows.net/charts/stable/app%2B1.0.tgz"; got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestAzureCredentials(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.URL.Path != "/tenant/oauth2/v2.0/token" || r.Form.Get("scope") != azureStorageScope {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		token := "secret-token"
+		if r.Form.Get("client_assertion") == "federated" {
+			token = "federated-token"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600}`, token)
+	}))
+	defer tokenServer.Close()
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != "https://storage.azure.com/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "managed-identity-token", "expires_in": "86399", "token_type": "Bearer"}`)
+	}))
+	defer imds.Close()
+
+	for _, tt := range []struct {
+		name     string
+		setup    func(t *testing.T)
+		expected string
+	}{
+		{
+			name: "service principal",
+			setup: func(t *testing.T) {
+				t.Setenv("AZURE_CLIENT_SECRET", "secret")
+			},
+			expected: "secret-token",
+		},
+		{
+			name: "workload identity",
+			setup: func(t *testing.T) {
+				filename := filepath.Join(t.TempDir(), "token")
+				if err := os.WriteFile(filename, []byte("federated\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				t.Setenv("AZURE_FEDERATED_TOKEN_FILE", filename)
+			},
+			expected: "federated-token",
+		},
+		{
+			name: "managed identity",
+			setup: func(t *testing.T) {
+				t.Setenv("AZURE_POD_IDENTITY_AUTHORITY_HOST", imds.URL)
+			},
+			expected: "managed-identity-token",
+		},
+		{
+			name:  "none",
+			setup: func(_ *testing.T) {},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAzureEnv(t)
+			t.Setenv("AZURE_AUTHORITY_HOST", tokenServer.URL)
+			t.Setenv("AZURE_TENANT_ID", "tenant")
+			t.Setenv("AZURE_CLIENT_ID", "client")
+			tt.setup(t)
+			tokens, err := findAzureCredentials(func() (*http.Client, error) { return tokenServer.Client(), nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expected == "" {
+				if tokens != nil {
+					t.Error("expected no credentials")
+				}
+				return
+			}
+			if tokens == nil {
+				t.Fatal("expected credentials")
+			}
+			token, err := tokens.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != tt.expected {
+				t.Errorf("expected access token %q, got %q", tt.expected, token.AccessToken)
+			}
+		})
+	}
+}
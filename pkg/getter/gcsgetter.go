@@ -0,0 +1,233 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	gcsReadOnlyScope = "https://www.googleapis.com/auth/devstorage.read_only"
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+)
+
+// NewGCSGetter constructs a Getter for gs://<bucket>/<object> URLs, reading
+// the objects of Google Cloud Storage with the credentials found like the
+// Google Cloud SDKs find them:
+//
+//   - the access token of the GOOGLE_OAUTH_ACCESS_TOKEN environment variable
+//   - the service account key or user credentials of the file
+//     GOOGLE_APPLICATION_CREDENTIALS, or of the file written by
+//     "gcloud auth application-default login"
+//   - the service account of the machine, from the metadata server of
+//     Compute Engine or GKE
+//
+// The objects are read anonymously when there are none. The endpoint of an
+// emulator is taken from STORAGE_EMULATOR_HOST.
+func NewGCSGetter(options ...Option) (Getter, error) {
+	s := &gcsStore{}
+	g := newObjectStoreGetter(s, options...)
+	s.client = g.httpClient
+	return g, nil
+}
+
+// gcsStore is Google Cloud Storage, read through its XML API.
+type gcsStore struct {
+	// client returns the client of the requests for access tokens, with the
+	// TLS and proxy settings of the getter.
+	client func() (*http.Client, error)
+
+	mu     sync.Mutex
+	found  bool
+	tokens oauth2.TokenSource
+}
+
+func (s *gcsStore) objectURL(u *url.URL) (string, error) {
+	bucket, object := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" {
+		return "", errors.New("missing bucket")
+	}
+	endpoint := "https://storage.googleapis.com"
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		base, err := httpsEndpoint(host, "http")
+		if err != nil {
+			return "", fmt.Errorf("invalid STORAGE_EMULATOR_HOST %q: %w", host, err)
+		}
+		endpoint = base.String()
+	}
+	return endpoint + escapePath("/"+bucket+"/"+object), nil
+}
+
+func (s *gcsStore) authorize(req *http.Request) error {
+	tokens, err := s.tokenSource()
+	if err != nil || tokens == nil {
+		return err
+	}
+	if err := bearerToken(req, tokens); err != nil {
+		return fmt.Errorf("unable to get Google Cloud credentials: %w", err)
+	}
+	return nil
+}
+
+// tokenSource returns the source of the access tokens of the credentials
+// found in the environment, or nil when there are none.
+func (s *gcsStore) tokenSource() (oauth2.TokenSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.found {
+		return s.tokens, nil
+	}
+	tokens, err := findGoogleCredentials(s.client)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get Google Cloud credentials: %w", err)
+	}
+	if tokens == nil {
+		slog.Debug("no Google Cloud credentials found, reading Cloud Storage anonymously")
+	} else {
+		tokens = oauth2.ReuseTokenSource(nil, tokens)
+	}
+	s.found, s.tokens = true, tokens
+	return tokens, nil
+}
+
+func (s *gcsStore) anonymous() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.found && s.tokens == nil
+}
+
+// findGoogleCredentials looks for the credentials in the order of the
+// Application Default Credentials.
+func findGoogleCredentials(client func() (*http.Client, error)) (oauth2.TokenSource, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token, TokenType: "Bearer"}), nil
+	}
+	if os.Getenv("STORAGE_EMULATOR_HOST") != "" {
+		// Emulators do not authenticate requests.
+		return nil, nil
+	}
+
+	filename := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	explicit := filename != ""
+	if !explicit {
+		filename = gcloudCredentialsPath()
+	}
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		ctx, err := clientContext(client)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := googleCredentialsFromJSON(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		return tokens, nil
+	}
+	if explicit {
+		return nil, err
+	}
+
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "169.254.169.254"
+	}
+	tokens := &metadataTokenSource{
+		url:    "http://" + host + "/computeMetadata/v1/instance/service-accounts/default/token",
+		header: http.Header{"Metadata-Flavor": {"Google"}},
+	}
+	token, err := tokens.Token()
+	if err != nil {
+		// Most likely not running on Google Cloud
+		slog.Debug("unable to get the credentials of the Google Cloud machine", slog.Any("error", err))
+		return nil, nil
+	}
+	return oauth2.ReuseTokenSource(token, tokens), nil
+}
+
+// googleCredentialsFromJSON returns the source of the access tokens of a
+// service account key, or of the user credentials written by gcloud.
+func googleCredentialsFromJSON(ctx context.Context, data []byte) (oauth2.TokenSource, error) {
+	var f struct {
+		Type         string `json:"type"`
+		ClientEmail  string `json:"client_email"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		TokenURI     string `json:"token_uri"`
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	tokenURL := f.TokenURI
+	if tokenURL == "" {
+		tokenURL = googleTokenURL
+	}
+	switch f.Type {
+	case "service_account":
+		cfg := &jwt.Config{
+			Email:        f.ClientEmail,
+			PrivateKey:   []byte(f.PrivateKey),
+			PrivateKeyID: f.PrivateKeyID,
+			Scopes:       []string{gcsReadOnlyScope},
+			TokenURL:     tokenURL,
+		}
+		return cfg.TokenSource(ctx), nil
+	case "authorized_user":
+		cfg := &oauth2.Config{
+			ClientID:     f.ClientID,
+			ClientSecret: f.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+			Scopes:       []string{gcsReadOnlyScope},
+		}
+		return cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: f.RefreshToken}), nil
+	}
+	return nil, fmt.Errorf("unsupported credentials type %q", f.Type)
+}
+
+// gcloudCredentialsPath returns the path of the credentials written by
+// "gcloud auth application-default login".
+func gcloudCredentialsPath() string {
+	const name = "application_default_credentials.json"
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gcloud", name)
+}
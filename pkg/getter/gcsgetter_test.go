@@ -0,0 +1,205 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearGoogleEnv clears the Google Cloud credentials and settings of the
+// environment.
+func clearGoogleEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	// Nothing listens on port 1.
+	t.Setenv("GCE_METADATA_HOST", "127.0.0.1:1")
+}
+
+// googleTokenServer serves the given access token for the grants of the
+// OAuth2 token endpoint of Google.
+func googleTokenServer(t *testing.T, grantType, accessToken string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != grantType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600}`, accessToken)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGCSGetter(t *testing.T) {
+	clearGoogleEnv(t)
+	var req *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		if r.URL.Path != "/charts/stable/index.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "index")
+	}))
+	defer srv.Close()
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	get := func() {
+		t.Helper()
+		g, err := NewGCSGetter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := g.Get("gs://charts/stable/index.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != "index" {
+			t.Errorf("expected the content of the object, got %q", buf.String())
+		}
+	}
+
+	// Emulators are read anonymously.
+	get()
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		t.Errorf("expected an anonymous request, got Authorization %q", auth)
+	}
+
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "access-token")
+	get()
+	if auth := req.Header.Get("Authorization"); auth != "Bearer access-token" {
+		t.Errorf("expected the access token of the environment, got Authorization %q", auth)
+	}
+}
+
+func TestGoogleCredentials(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	serviceAccountServer := googleTokenServer(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", "service-account-token")
+	userServer := googleTokenServer(t, "refresh_token", "user-token")
+	metadataServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" || r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"access_token": "metadata-token", "token_type": "Bearer", "expires_in": 3599}`)
+	}))
+	defer metadataServer.Close()
+
+	writeJSON := func(t *testing.T, filename string, v interface{}) {
+		t.Helper()
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		setup    func(t *testing.T)
+		expected string
+	}{
+		{
+			name: "service account key",
+			setup: func(t *testing.T) {
+				filename := filepath.Join(t.TempDir(), "key.json")
+				writeJSON(t, filename, map[string]string{
+					"type":         "service_account",
+					"client_email": "helm@project.iam.gserviceaccount.com",
+					"private_key":  string(privateKey),
+					"token_uri":    serviceAccountServer.URL,
+				})
+				t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filename)
+			},
+			expected: "service-account-token",
+		},
+		{
+			name: "gcloud user credentials",
+			setup: func(t *testing.T) {
+				writeJSON(t, filepath.Join(os.Getenv("CLOUDSDK_CONFIG"), "application_default_credentials.json"), map[string]string{
+					"type":          "authorized_user",
+					"client_id":     "client",
+					"client_secret": "secret",
+					"refresh_token": "refresh",
+					"token_uri":     userServer.URL,
+				})
+			},
+			expected: "user-token",
+		},
+		{
+			name: "metadata server",
+			setup: func(t *testing.T) {
+				t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(metadataServer.URL, "http://"))
+			},
+			expected: "metadata-token",
+		},
+		{
+			name:  "none",
+			setup: func(_ *testing.T) {},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clearGoogleEnv(t)
+			tt.setup(t)
+			tokens, err := (&gcsStore{}).tokenSource()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.expected == "" {
+				if tokens != nil {
+					t.Error("expected no credentials")
+				}
+				return
+			}
+			if tokens == nil {
+				t.Fatal("expected credentials")
+			}
+			token, err := tokens.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != tt.expected {
+				t.Errorf("expected access token %q, got %q", tt.expected, token.AccessToken)
+			}
+		})
+	}
+}
@@ -18,7 +18,9 @@ package getter
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"slices"
 	"time"
@@ -36,6 +38,8 @@ type getterOptions struct {
 	certFile              string
 	keyFile               string
 	caFile                string
+	minTLSVersion         string
+	proxy                 string
 	unTar                 bool
 	insecureSkipVerifyTLS bool
 	plainHTTP             bool
@@ -49,6 +53,48 @@ type getterOptions struct {
 	timeout               time.Duration
 	transport             *http.Transport
 	artifactType          string
+	maxBytes              int64
+	offset                int64
+	ifRange               string
+	validators            *Validators
+	// progress is a pointer for the options to remain comparable.
+	progress *func(fetched int64)
+}
+
+// ErrContentChanged is returned by getters resuming a download with
+// WithIfRange when the content changed since the download started, so that
+// it must be downloaded again from its start.
+var ErrContentChanged = errors.New("content changed since the download started")
+
+// ErrNotModified is returned by getters asked for content with validators
+// when the content did not change since the validators were recorded.
+var ErrNotModified = errors.New("not modified")
+
+// HTTPStatusError is returned by the HTTP getter when the server responds
+// with an unexpected status.
+type HTTPStatusError struct {
+	// URL is the requested URL.
+	URL string
+	// StatusCode is the status code of the response, and Status its status
+	// line, such as "404 Not Found".
+	StatusCode int
+	Status     string
+	// Header is the header of the response.
+	Header http.Header
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s : %s", e.URL, e.Status)
+}
+
+// Validators identify the version of the content of a URL, so that it is only
+// downloaded again when it changed.
+type Validators struct {
+	// ETag is the entity tag of the content.
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the time the content was last modified, in the HTTP
+	// date format.
+	LastModified string `json:"lastModified,omitempty"`
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -70,6 +116,56 @@ func WithAcceptHeader(header string) Option {
 	}
 }
 
+// WithMaxBytes limits the download to the first n bytes of the content. Only
+// these bytes are requested from servers supporting range requests.
+func WithMaxBytes(n int64) Option {
+	return func(opts *getterOptions) {
+		opts.maxBytes = n
+	}
+}
+
+// WithOffset starts the download at the given byte of the content, to resume
+// an interrupted download. The HTTP getter requests the rest of the content
+// from servers supporting range requests, and skips the start of the content
+// sent by the others. Like the limit, it only applies to the request it is
+// given for. Other getters ignore it.
+func WithOffset(offset int64) Option {
+	return func(opts *getterOptions) {
+		opts.offset = offset
+	}
+}
+
+// WithIfRange makes a download resumed with WithOffset conditional on the
+// content still having the given entity tag. The HTTP getter returns
+// ErrContentChanged when it changed, instead of skipping the start of a
+// different content. Weak entity tags are ignored.
+func WithIfRange(etag string) Option {
+	return func(opts *getterOptions) {
+		opts.ifRange = etag
+	}
+}
+
+// WithValidators makes the request conditional on the content having changed
+// since the given validators were recorded. Getters supporting conditional
+// requests return ErrNotModified when it did not change, and set the
+// validators to the ones of the content they return otherwise. The
+// validators only apply to the request they are given for.
+func WithValidators(v *Validators) Option {
+	return func(opts *getterOptions) {
+		opts.validators = v
+	}
+}
+
+// WithProgress reports the progress of the download: fn is called with the
+// number of bytes fetched so far as the content is read. Getters that cannot
+// report it ignore it. Like the validators, it only applies to the request it
+// is given for.
+func WithProgress(fn func(fetched int64)) Option {
+	return func(opts *getterOptions) {
+		opts.progress = &fn
+	}
+}
+
 // WithBasicAuth sets the request's Authorization header to use the provided credentials
 func WithBasicAuth(username, password string) Option {
 	return func(opts *getterOptions) {
@@ -107,6 +203,22 @@ func WithTLSClientConfig(certFile, keyFile, caFile string) Option {
 	}
 }
 
+// WithMinTLSVersion sets the minimum TLS version, such as "1.2", the HTTP
+// getter accepts from servers.
+func WithMinTLSVersion(version string) Option {
+	return func(opts *getterOptions) {
+		opts.minTLSVersion = version
+	}
+}
+
+// WithProxy makes the HTTP getter send its requests through the proxy at the
+// given URL, instead of the one set in the environment.
+func WithProxy(proxyURL string) Option {
+	return func(opts *getterOptions) {
+		opts.proxy = proxyURL
+	}
+}
+
 func WithPlainHTTP(plainHTTP bool) Option {
 	return func(opts *getterOptions) {
 		opts.plainHTTP = plainHTTP
@@ -158,6 +270,41 @@ type Getter interface {
 	Get(url string, options ...Option) (*bytes.Buffer, error)
 }
 
+// Content is the content of a URL, streamed by a StreamGetter as it is read.
+// It must be closed once read.
+type Content struct {
+	io.ReadCloser
+	// Size is the length of the content in bytes, or -1 when it is unknown.
+	Size int64
+	// Digest is the digest of the content, such as "sha256:<hex>", when the
+	// source provides one.
+	Digest string
+	// ETag is the entity tag of the content, when the source provides one.
+	ETag string
+}
+
+// StreamGetter is a Getter able to stream content as it is downloaded, instead
+// of reading it all in memory, so that large charts can be written to disk as
+// they are received.
+type StreamGetter interface {
+	Getter
+	// GetStream returns the content of the URL as it is downloaded.
+	GetStream(url string, options ...Option) (*Content, error)
+}
+
+// GetStream streams the content of a URL with g if it is a StreamGetter, and
+// reads it all in memory with Get otherwise.
+func GetStream(g Getter, url string, options ...Option) (*Content, error) {
+	if sg, ok := g.(StreamGetter); ok {
+		return sg.GetStream(url, options...)
+	}
+	buf, err := g.Get(url, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &Content{ReadCloser: io.NopCloser(buf), Size: int64(buf.Len())}, nil
+}
+
 // Constructor is the function for every getter which creates a specific instance
 // according to the configuration
 type Constructor func(options ...Option) (Getter, error)
@@ -200,7 +347,14 @@ const (
 
 var defaultOptions = []Option{WithTimeout(time.Second * DefaultHTTPTimeout)}
 
+// Getters returns the getters of the http, https and oci schemes. The getters
+// of the cloud object storages are only added by All, after the plugins, so
+// that the plugins handling these schemes keep handling them.
 func Getters(extraOpts ...Option) Providers {
+	return builtinGetters(extraOpts...)
+}
+
+func builtinGetters(extraOpts ...Option) Providers {
 	return Providers{
 		Provider{
 			Schemes: []string{"http", "https"},
@@ -221,12 +375,35 @@ func Getters(extraOpts ...Option) Providers {
 	}
 }
 
+// overridableGetters are the built-in getters of the schemes plugins may
+// handle as well: the cloud object storages.
+func overridableGetters(extraOpts ...Option) Providers {
+	provider := func(constructor Constructor, schemes ...string) Provider {
+		return Provider{
+			Schemes: schemes,
+			New: func(options ...Option) (Getter, error) {
+				options = append(options, defaultOptions...)
+				options = append(options, extraOpts...)
+				return constructor(options...)
+			},
+		}
+	}
+	return Providers{
+		provider(NewS3Getter, "s3"),
+		provider(NewGCSGetter, "gs"),
+		provider(NewAzureBlobGetter, "azblob"),
+	}
+}
+
 // All finds all of the registered getters as a list of Provider instances.
 // Currently, the built-in getters and the discovered plugins with downloader
-// notations are collected.
+// notations are collected. Plugins handling the schemes of the cloud object
+// storages, such as s3, take precedence over the built-in getters of these
+// schemes.
 func All(settings *cli.EnvSettings, opts ...Option) Providers {
-	result := Getters(opts...)
+	result := builtinGetters(opts...)
 	pluginDownloaders, _ := collectGetterPlugins(settings)
 	result = append(result, pluginDownloaders...)
+	result = append(result, overridableGetters(opts...)...)
 	return result
 }
@@ -70,18 +70,38 @@ func TestProvidersWithTimeout(t *testing.T) {
 	}
 }
 
+func TestGetters(t *testing.T) {
+	getters := Getters()
+	for _, scheme := range []string{"http", "https", "oci"} {
+		if _, err := getters.ByScheme(scheme); err != nil {
+			t.Error(err)
+		}
+	}
+	// Plugins may handle the other schemes.
+	for _, scheme := range []string{"s3", "gs", "azblob"} {
+		if _, err := getters.ByScheme(scheme); err == nil {
+			t.Errorf("expected no getter of %s without the plugins", scheme)
+		}
+	}
+}
+
 func TestAll(t *testing.T) {
 	env := cli.New()
 	env.PluginsDirectory = pluginDir
 
 	all := All(env)
-	if len(all) != 4 {
-		t.Errorf("expected 4 providers (default plus three plugins), got %d", len(all))
+	if len(all) != 7 {
+		t.Errorf("expected 7 providers (default plus three plugins plus three object storages), got %d", len(all))
 	}
 
 	if _, err := all.ByScheme("test2"); err != nil {
 		t.Error(err)
 	}
+	for _, scheme := range []string{"s3", "gs", "azblob"} {
+		if _, err := all.ByScheme(scheme); err != nil {
+			t.Error(err)
+		}
+	}
 }
 
 func TestByScheme(t *testing.T) {
@@ -17,11 +17,15 @@ package getter
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 
 	"helm.sh/helm/v4/internal/tlsutil"
@@ -33,17 +37,44 @@ type HTTPGetter struct {
 	opts      getterOptions
 	transport *http.Transport
 	once      sync.Once
+	// authorizer, when set, authenticates the requests once their headers
+	// are set, such as by signing them for a cloud object storage.
+	authorizer authorizer
+}
+
+// authorizer authenticates requests.
+type authorizer interface {
+	authorize(req *http.Request) error
 }
 
 // Get performs a Get from repo.Getter and returns the body.
 func (g *HTTPGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	content, err := g.GetStream(href, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	buf := bytes.NewBuffer(nil)
+	_, err = io.Copy(buf, content)
+	return buf, err
+}
+
+// GetStream performs a Get from repo.Getter and returns the body as it is
+// downloaded.
+func (g *HTTPGetter) GetStream(href string, options ...Option) (*Content, error) {
+	g.opts.validators = nil
+	g.opts.progress = nil
+	// A limit or offset passed to a single request does not apply to the next ones.
+	defer func(maxBytes, offset int64, ifRange string) {
+		g.opts.maxBytes, g.opts.offset, g.opts.ifRange = maxBytes, offset, ifRange
+	}(g.opts.maxBytes, g.opts.offset, g.opts.ifRange)
 	for _, opt := range options {
 		opt(&g.opts)
 	}
 	return g.get(href)
 }
 
-func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
+func (g *HTTPGetter) get(href string) (*Content, error) {
 	// Set a helm specific user agent so that a repo server and metrics can
 	// separate helm calls from other tools interacting with repos.
 	req, err := http.NewRequest(http.MethodGet, href, nil)
@@ -55,6 +86,29 @@ func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
 		req.Header.Set("Accept", g.opts.acceptHeader)
 	}
 
+	if g.opts.maxBytes > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", g.opts.offset, g.opts.maxBytes-1))
+	} else if g.opts.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", g.opts.offset))
+	}
+	ifRange := g.opts.ifRange
+	if g.opts.offset <= 0 || strings.HasPrefix(ifRange, "W/") {
+		ifRange = ""
+	}
+	if ifRange != "" {
+		// Get the whole content instead of a range of a different one
+		req.Header.Set("If-Range", ifRange)
+	}
+
+	if v := g.opts.validators; v != nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
+
 	req.Header.Set("User-Agent", version.GetUserAgent())
 	if g.opts.userAgent != "" {
 		req.Header.Set("User-Agent", g.opts.userAgent)
@@ -80,6 +134,12 @@ func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
 		}
 	}
 
+	if g.authorizer != nil {
+		if err := g.authorizer.authorize(req); err != nil {
+			return nil, err
+		}
+	}
+
 	client, err := g.httpClient()
 	if err != nil {
 		return nil, err
@@ -89,14 +149,106 @@ func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch %s : %s", href, resp.Status)
+	if resp.StatusCode == http.StatusNotModified && g.opts.validators != nil {
+		resp.Body.Close()
+		return nil, ErrNotModified
+	}
+	ranged := g.opts.maxBytes > 0 || g.opts.offset > 0
+	if resp.StatusCode != http.StatusOK && (!ranged || resp.StatusCode != http.StatusPartialContent) {
+		resp.Body.Close()
+		return nil, &HTTPStatusError{URL: href, StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header}
 	}
 
-	buf := bytes.NewBuffer(nil)
-	_, err = io.Copy(buf, resp.Body)
-	return buf, err
+	content := &Content{
+		Size: resp.ContentLength,
+		ETag: resp.Header.Get("ETag"),
+	}
+	if ifRange != "" && content.ETag != ifRange {
+		resp.Body.Close()
+		return nil, ErrContentChanged
+	}
+	if resp.StatusCode == http.StatusPartialContent && g.opts.offset > 0 {
+		if start := fmt.Sprintf("bytes %d-", g.opts.offset); !strings.HasPrefix(resp.Header.Get("Content-Range"), start) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to fetch %s : unexpected content range %q", href, resp.Header.Get("Content-Range"))
+		}
+	} else if g.opts.offset > 0 {
+		// Servers ignoring the range send the whole content
+		if _, err := io.CopyN(io.Discard, resp.Body, g.opts.offset); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if content.Size >= 0 {
+			content.Size -= g.opts.offset
+		}
+	}
+	body := io.Reader(resp.Body)
+	if g.opts.maxBytes > 0 {
+		// Servers ignoring the range send the whole content
+		n := g.opts.maxBytes - g.opts.offset
+		body = io.LimitReader(resp.Body, n)
+		if content.Size < 0 || content.Size > n {
+			content.Size = n
+		}
+	}
+	if !ranged {
+		content.Digest = contentDigest(resp.Header)
+	}
+
+	if v := g.opts.validators; v != nil {
+		v.ETag = content.ETag
+		v.LastModified = resp.Header.Get("Last-Modified")
+	}
+
+	if g.opts.progress != nil {
+		body = &progressReader{r: body, progress: *g.opts.progress}
+	}
+
+	content.ReadCloser = readCloser{Reader: body, Closer: resp.Body}
+	return content, nil
+}
+
+// contentDigest returns the SHA-256 digest of the content of a response, as
+// "sha256:<hex>", when the server sends it in a Repr-Digest (RFC 9530) or
+// Digest (RFC 3230) header.
+func contentDigest(h http.Header) string {
+	for _, header := range []string{"Repr-Digest", "Digest"} {
+		for field := range strings.SplitSeq(h.Get(header), ",") {
+			alg, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+			if !ok || !strings.EqualFold(alg, "sha-256") {
+				continue
+			}
+			// Repr-Digest encloses the value in colons
+			sum, err := base64.StdEncoding.DecodeString(strings.Trim(value, ":"))
+			if err == nil && len(sum) == sha256.Size {
+				return "sha256:" + hex.EncodeToString(sum)
+			}
+		}
+	}
+	return ""
+}
+
+// readCloser reads from a reader wrapping the body of a response, and closes
+// the body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// progressReader reports the number of bytes read so far after each read.
+type progressReader struct {
+	r        io.Reader
+	fetched  int64
+	progress func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.fetched += int64(n)
+		p.progress(p.fetched)
+	}
+	return n, err
 }
 
 // NewHTTPGetter constructs a valid http/https client as a Getter
@@ -110,6 +262,16 @@ func NewHTTPGetter(options ...Option) (Getter, error) {
 	return &client, nil
 }
 
+// HTTPClient returns a client sending its requests with the TLS, proxy and
+// timeout settings of the given options, like the HTTP getter does.
+func HTTPClient(options ...Option) (*http.Client, error) {
+	var g HTTPGetter
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.httpClient()
+}
+
 func (g *HTTPGetter) httpClient() (*http.Client, error) {
 	if g.opts.transport != nil {
 		return &http.Client{
@@ -121,18 +283,27 @@ func (g *HTTPGetter) httpClient() (*http.Client, error) {
 	g.once.Do(func() {
 		g.transport = &http.Transport{
 			DisableCompression: true,
-			Proxy:              http.ProxyFromEnvironment,
 			// Being nil would cause the tls.Config default to be used
 			// "NewTLSConfig" modifies an empty TLS config, not the default one
 			TLSClientConfig: &tls.Config{},
 		}
 	})
 
-	if (g.opts.certFile != "" && g.opts.keyFile != "") || g.opts.caFile != "" || g.opts.insecureSkipVerifyTLS {
+	g.transport.Proxy = http.ProxyFromEnvironment
+	if g.opts.proxy != "" {
+		proxyURL, err := url.Parse(g.opts.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", g.opts.proxy, err)
+		}
+		g.transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if (g.opts.certFile != "" && g.opts.keyFile != "") || g.opts.caFile != "" || g.opts.insecureSkipVerifyTLS || g.opts.minTLSVersion != "" {
 		tlsConf, err := tlsutil.NewTLSConfig(
 			tlsutil.WithInsecureSkipVerify(g.opts.insecureSkipVerifyTLS),
 			tlsutil.WithCertKeyPairFiles(g.opts.certFile, g.opts.keyFile),
 			tlsutil.WithCAFile(g.opts.caFile),
+			tlsutil.WithMinVersion(g.opts.minTLSVersion),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
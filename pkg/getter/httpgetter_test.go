@@ -16,6 +16,10 @@ limitations under the License.
 package getter
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -678,3 +682,287 @@ func TestHTTPTransportOption(t *testing.T) {
 		t.Fatal("transport.TLSClientConfig should not be set")
 	}
 }
+
+func TestHTTPGetterMaxBytes(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	for _, tt := range []struct {
+		name   string
+		ranges bool
+	}{
+		{"server supporting ranges", true},
+		{"server ignoring ranges", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range")
+				if tt.ranges {
+					http.ServeContent(w, r, "content", time.Time{}, strings.NewReader(content))
+					return
+				}
+				io.WriteString(w, content)
+			}))
+			defer srv.Close()
+
+			g, err := NewHTTPGetter(WithURL(srv.URL), WithMaxBytes(25))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := g.Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotRange != "bytes=0-24" {
+				t.Errorf("expected range bytes=0-24, got %q", gotRange)
+			}
+			if data.String() != content[:25] {
+				t.Errorf("expected %q, got %q", content[:25], data.String())
+			}
+		})
+	}
+}
+
+func TestHTTPGetterStream(t *testing.T) {
+	content := strings.Repeat("0123456789", 10000)
+	sum := sha256.Sum256([]byte(content))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		http.ServeContent(w, r, "chart.tgz", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := GetStream(g, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	data, err := io.ReadAll(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("expected the content of %d bytes, got %d bytes", len(content), len(data))
+	}
+	if c.Size != int64(len(content)) {
+		t.Errorf("expected size %d, got %d", len(content), c.Size)
+	}
+	if expected := "sha256:" + hex.EncodeToString(sum[:]); c.Digest != expected {
+		t.Errorf("expected digest %q, got %q", expected, c.Digest)
+	}
+	if c.ETag != `"v1"` {
+		t.Errorf("expected ETag %q, got %q", `"v1"`, c.ETag)
+	}
+
+	// A part of the content does not have the digest of the whole.
+	c, err = GetStream(g, srv.URL, WithMaxBytes(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.Size != 10 || c.Digest != "" {
+		t.Errorf("expected size 10 without digest, got size %d and digest %q", c.Size, c.Digest)
+	}
+
+	srv404 := httptest.NewServer(http.NotFoundHandler())
+	defer srv404.Close()
+	var statusErr *HTTPStatusError
+	if _, err := GetStream(g, srv404.URL); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404 status error, got %v", err)
+	}
+}
+
+func TestHTTPGetterOffset(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	for _, tt := range []struct {
+		name   string
+		ranges bool
+	}{
+		{"server supporting ranges", true},
+		{"server ignoring ranges", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRange string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotRange = r.Header.Get("Range")
+				if tt.ranges {
+					http.ServeContent(w, r, "content", time.Time{}, strings.NewReader(content))
+					return
+				}
+				io.WriteString(w, content)
+			}))
+			defer srv.Close()
+
+			g, err := NewHTTPGetter(WithURL(srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := g.Get(srv.URL, WithOffset(30))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotRange != "bytes=30-" {
+				t.Errorf("expected range bytes=30-, got %q", gotRange)
+			}
+			if data.String() != content[30:] {
+				t.Errorf("expected %q, got %q", content[30:], data.String())
+			}
+
+			// The offset only applies to the request it is given for.
+			if _, err := g.Get(srv.URL); err != nil {
+				t.Fatal(err)
+			}
+			if gotRange != "" {
+				t.Errorf("expected no range for the next request, got %q", gotRange)
+			}
+		})
+	}
+}
+
+func TestHTTPGetterIfRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	var gotIfRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIfRange = r.Header.Get("If-Range")
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "content", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := g.Get(srv.URL, WithOffset(30), WithIfRange(`"v2"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotIfRange != `"v2"` {
+		t.Errorf("expected If-Range \"v2\", got %q", gotIfRange)
+	}
+	if data.String() != content[30:] {
+		t.Errorf("expected %q, got %q", content[30:], data.String())
+	}
+
+	// The start of a different content is not skipped.
+	if _, err := g.Get(srv.URL, WithOffset(30), WithIfRange(`"v1"`)); !errors.Is(err, ErrContentChanged) {
+		t.Errorf("expected ErrContentChanged, got %v", err)
+	}
+
+	// Weak entity tags cannot be used with If-Range.
+	if _, err := g.Get(srv.URL, WithOffset(30), WithIfRange(`W/"v1"`)); err != nil {
+		t.Fatal(err)
+	}
+	if gotIfRange != "" {
+		t.Errorf("expected no If-Range for a weak entity tag, got %q", gotIfRange)
+	}
+}
+
+func TestHTTPGetterValidators(t *testing.T) {
+	content := "apiVersion: v1\n"
+	modified := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "index.yaml", modified, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v Validators
+	data, err := g.Get(srv.URL, WithValidators(&v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.String() != content {
+		t.Errorf("expected %q, got %q", content, data.String())
+	}
+	if v.ETag != `"v1"` || v.LastModified != modified.Format(http.TimeFormat) {
+		t.Errorf("unexpected validators %+v", v)
+	}
+
+	if _, err := g.Get(srv.URL, WithValidators(&v)); err != ErrNotModified {
+		t.Errorf("expected ErrNotModified, got %v", err)
+	}
+
+	// The validators do not apply to the next requests.
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Errorf("expected the content without validators, got %v", err)
+	}
+
+	stale := Validators{ETag: `"v0"`}
+	if _, err := g.Get(srv.URL, WithValidators(&stale)); err != nil {
+		t.Fatal(err)
+	}
+	if stale.ETag != `"v1"` {
+		t.Errorf("expected the validators to be updated, got %+v", stale)
+	}
+}
+
+func TestHTTPGetterProgress(t *testing.T) {
+	content := strings.Repeat("0123456789", 10000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		io.WriteString(w, content)
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports []int64
+	if _, err := g.Get(srv.URL, WithProgress(func(fetched int64) {
+		reports = append(reports, fetched)
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) == 0 || reports[len(reports)-1] != int64(len(content)) {
+		t.Fatalf("expected the progress to end with %d bytes, got %v", len(content), reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i] <= reports[i-1] {
+			t.Errorf("expected the fetched bytes to increase, got %v", reports)
+		}
+	}
+
+	// The progress only applies to the request it is given for.
+	reports = nil
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("expected no progress for the next request, got %v", reports)
+	}
+}
+
+func TestHTTPGetterProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	g, err := NewHTTPGetter(WithURL("http://charts.example.com"), WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := g.Get("http://charts.example.com/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://charts.example.com/index.yaml" {
+		t.Errorf("expected the request to go through the proxy, got %q", proxied)
+	}
+	if data.String() != "proxied" {
+		t.Errorf("expected the response of the proxy, got %q", data.String())
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// objectStore is a cloud object storage, such as Amazon S3, read through its
+// HTTPS API.
+type objectStore interface {
+	// objectURL returns the HTTPS URL of the object at a URL of the scheme of
+	// the storage, such as s3://bucket/key.
+	objectURL(u *url.URL) (string, error)
+	// authorizer authenticates the requests to the storage with the
+	// credentials found in the environment. Requests are sent anonymously
+	// when there are none, which lets public buckets be read.
+	authorizer
+	// anonymous reports whether the requests are sent anonymously, as no
+	// credentials were found.
+	anonymous() bool
+}
+
+// redirectingStore is an objectStore that may serve an object from another
+// URL than the one it is first requested at, such as S3 for the buckets of
+// another region.
+type redirectingStore interface {
+	objectStore
+	// redirectURL returns the URL to request the object at again after the
+	// request failed with err, and false when it must not be requested again.
+	redirectURL(u *url.URL, err error) (string, bool)
+}
+
+// objectStoreGetter fetches the objects of a cloud object storage with the
+// HTTP getter, so that they are downloaded like the charts of HTTP
+// repositories, with the same TLS, proxy, range and conditional requests.
+type objectStoreGetter struct {
+	HTTPGetter
+	store objectStore
+}
+
+func newObjectStoreGetter(store objectStore, options ...Option) *objectStoreGetter {
+	g := &objectStoreGetter{store: store}
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	g.authorizer = store
+	return g
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *objectStoreGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	content, err := g.GetStream(href, options...)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	buf := bytes.NewBuffer(nil)
+	_, err = io.Copy(buf, content)
+	return buf, err
+}
+
+// GetStream performs a Get from repo.Getter and returns the body as it is
+// downloaded.
+func (g *objectStoreGetter) GetStream(href string, options ...Option) (*Content, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse URL getting from: %w", err)
+	}
+	objectURL, err := g.store.objectURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", href, err)
+	}
+	content, err := g.HTTPGetter.GetStream(objectURL, options...)
+	if rs, ok := g.store.(redirectingStore); ok && err != nil {
+		if objectURL, ok := rs.redirectURL(u, err); ok {
+			content, err = g.HTTPGetter.GetStream(objectURL, options...)
+		}
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) && g.store.anonymous() {
+		return nil, fmt.Errorf("%w: no credentials were found, so the request was sent anonymously", err)
+	}
+	return content, err
+}
+
+// metadataClient is the client of the metadata services of the clouds, which
+// hand out the credentials of the machines they run. It gives up quickly, and
+// only once per process for each service, so that looking for them outside of
+// a cloud does not hold the downloads up.
+var metadataClient = &http.Client{Timeout: 2 * time.Second, Transport: &metadataTransport{}}
+
+// metadataTransport fails the requests to the metadata services that could
+// not be reached before right away.
+type metadataTransport struct {
+	// unreachable maps the hosts that could not be reached to their error.
+	unreachable sync.Map
+}
+
+func (t *metadataTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err, ok := t.unreachable.Load(req.URL.Host); ok {
+		return nil, err.(error)
+	}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.unreachable.Store(req.URL.Host, err)
+	}
+	return resp, err
+}
+
+// storeClient returns the client of the requests of a store for its
+// credentials, the default client when the store has none.
+func storeClient(client func() (*http.Client, error)) (*http.Client, error) {
+	if client == nil {
+		return http.DefaultClient, nil
+	}
+	return client()
+}
+
+// clientContext returns a context making the token sources of oauth2 send
+// their requests with the client of a store.
+func clientContext(client func() (*http.Client, error)) (context.Context, error) {
+	c, err := storeClient(client)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(context.Background(), oauth2.HTTPClient, c), nil
+}
+
+// fetchJSON decodes the JSON response to a request to a credential endpoint.
+func fetchJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// fetchText returns the trimmed text response to a request to a credential
+// endpoint.
+func fetchText(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// httpsEndpoint returns the endpoint set in an environment variable, taken
+// as a host name when it has no scheme, like the emulators of the clouds
+// expect.
+func httpsEndpoint(endpoint, defaultScheme string) (*url.URL, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = defaultScheme + "://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
+
+// escapePath percent-encodes the bytes of a path other than slashes and the
+// unreserved characters of RFC 3986, as the signatures of the requests to the
+// object storages expect.
+func escapePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '/' || isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+// metadataTokenSource gets the access tokens of the identity of the machine
+// from the metadata service of its cloud.
+type metadataTokenSource struct {
+	url    string
+	header http.Header
+}
+
+func (s *metadataTokenSource) Token() (*oauth2.Token, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = s.header.Clone()
+	var resp struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := fetchJSON(metadataClient, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("%s: no access token", req.URL.Redacted())
+	}
+	token := &oauth2.Token{AccessToken: resp.AccessToken, TokenType: "Bearer"}
+	if seconds, err := resp.ExpiresIn.Int64(); err == nil {
+		token.Expiry = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// bearerToken authenticates a request with a token of a source.
+func bearerToken(req *http.Request, source oauth2.TokenSource) error {
+	token, err := source.Token()
+	if err != nil {
+		return err
+	}
+	token.SetAuthHeader(req)
+	return nil
+}
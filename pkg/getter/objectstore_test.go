@@ -0,0 +1,55 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetadataTransport(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		requests++
+	}))
+	addr := srv.URL
+	client := &http.Client{Transport: &metadataTransport{}}
+
+	// A reachable metadata service is requested every time.
+	for range 2 {
+		resp, err := client.Get(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+
+	// Once it could not be reached, it is not requested again.
+	srv.Close()
+	_, first := client.Get(addr)
+	if first == nil {
+		t.Fatal("expected an error once the metadata service is down")
+	}
+	_, second := client.Get(addr)
+	if second == nil || second.Error() != first.Error() {
+		t.Errorf("expected the error of the first request, got %v", second)
+	}
+}
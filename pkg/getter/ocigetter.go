@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"path"
@@ -46,14 +47,42 @@ func (g *OCIGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
 	return g.get(href)
 }
 
-func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
+// GetStream performs a Get from repo.Getter and returns the chart archive as it
+// is downloaded. Provenance files and plugins, which are small, are read in
+// memory.
+func (g *OCIGetter) GetStream(href string, options ...Option) (*Content, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	client, ref, err := g.reference(href)
+	if err != nil {
+		return nil, err
+	}
+	if g.opts.artifactType == "plugin" || strings.HasSuffix(ref, ".prov") {
+		buf, err := g.pull(client, ref)
+		if err != nil {
+			return nil, err
+		}
+		return &Content{ReadCloser: io.NopCloser(buf), Size: int64(buf.Len())}, nil
+	}
+
+	desc, rc, err := client.PullStream(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &Content{ReadCloser: rc, Size: desc.Size, Digest: desc.Digest.String()}, nil
+}
+
+// reference returns the registry client to pull with, and the reference to
+// pull for a URL.
+func (g *OCIGetter) reference(href string) (*registry.Client, string, error) {
 	client := g.opts.registryClient
 	// if the user has already provided a configured registry client, use it,
 	// this is particularly true when user has his own way of handling the client credentials.
 	if client == nil {
 		c, err := g.newRegistryClient()
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		client = c
 	}
@@ -63,6 +92,18 @@ func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
 	if version := g.opts.version; version != "" && !strings.Contains(path.Base(ref), ":") {
 		ref = fmt.Sprintf("%s:%s", ref, version)
 	}
+	return client, ref, nil
+}
+
+func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
+	client, ref, err := g.reference(href)
+	if err != nil {
+		return nil, err
+	}
+	return g.pull(client, ref)
+}
+
+func (g *OCIGetter) pull(client *registry.Client, ref string) (*bytes.Buffer, error) {
 	// Check if this is a plugin request
 	if g.opts.artifactType == "plugin" {
 		return g.getPlugin(client, ref)
@@ -19,7 +19,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-
+	"io"
 	"net/url"
 
 	"helm.sh/helm/v4/internal/plugin"
@@ -94,6 +94,36 @@ type getterPlugin struct {
 }
 
 func (g *getterPlugin) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	data, err := g.invoke(href, nil, options)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+// GetStream returns the content the plugin writes as it writes it. Only
+// subprocess plugins write their content to stdout: the content of the others
+// is returned at once.
+func (g *getterPlugin) GetStream(href string, options ...Option) (*Content, error) {
+	if g.plg.Metadata().Runtime != "subprocess" {
+		data, err := g.invoke(href, nil, options)
+		if err != nil {
+			return nil, err
+		}
+		return &Content{ReadCloser: io.NopCloser(bytes.NewReader(data)), Size: int64(len(data))}, nil
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := g.invoke(href, pw, options)
+		pw.CloseWithError(err)
+	}()
+	return &Content{ReadCloser: pr, Size: -1}, nil
+}
+
+// invoke runs the plugin to get a URL. The plugin writes the content to
+// stdout when it is set, and returns it otherwise.
+func (g *getterPlugin) invoke(href string, stdout io.Writer, options []Option) ([]byte, error) {
 	opts := convertOptions(g.options, options)
 
 	// TODO optimization: pass this along to Get() instead of re-parsing here
@@ -108,8 +138,8 @@ func (g *getterPlugin) Get(href string, options ...Option) (*bytes.Buffer, error
 			Options:  opts,
 			Protocol: u.Scheme,
 		},
-		// TODO should we pass Stdin, Stdout, and Stderr through Input here to getter plugins?
-		//Stdout: os.Stdout,
+		// TODO should we pass Stdin and Stderr through Input here to getter plugins?
+		Stdout: stdout,
 	}
 	output, err := g.plg.Invoke(context.Background(), input)
 	if err != nil {
@@ -121,5 +151,5 @@ func (g *getterPlugin) Get(href string, options ...Option) (*bytes.Buffer, error
 		return nil, fmt.Errorf("invalid output message type from plugin %q", g.plg.Metadata().Name)
 	}
 
-	return bytes.NewBuffer(outputMessage.Data), nil
+	return outputMessage.Data, nil
 }
@@ -17,7 +17,7 @@ package getter
 
 import (
 	"context"
-
+	"io"
 	"testing"
 	"time"
 
@@ -121,8 +121,13 @@ func (t *testPlugin) Metadata() plugin.Metadata {
 	}
 }
 
-func (t *testPlugin) Invoke(_ context.Context, _ *plugin.Input) (*plugin.Output, error) {
+func (t *testPlugin) Invoke(_ context.Context, input *plugin.Input) (*plugin.Output, error) {
 	// Simulate a plugin invocation
+	if input.Stdout != nil {
+		// Subprocess plugins stream their content
+		_, err := io.WriteString(input.Stdout, "fake-plugin output")
+		return &plugin.Output{Message: schema.OutputMessageGetterV1{}}, err
+	}
 	output := &plugin.Output{
 		Message: schema.OutputMessageGetterV1{
 			Data: []byte("fake-plugin output"),
@@ -144,3 +149,19 @@ func TestGetterPlugin(t *testing.T) {
 
 	assert.Equal(t, "fake-plugin output", buf.String())
 }
+
+func TestGetterPluginStream(t *testing.T) {
+	gp := getterPlugin{
+		options: []Option{},
+		plg:     &testPlugin{t: t, dir: "fake/dir"},
+	}
+
+	c, err := GetStream(&gp, "test://example.com")
+	require.NoError(t, err)
+	defer c.Close()
+
+	data, err := io.ReadAll(c)
+	require.NoError(t, err)
+	assert.Equal(t, "fake-plugin output", string(data))
+	assert.Equal(t, int64(-1), c.Size)
+}
@@ -0,0 +1,551 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// emptySHA256 is the SHA-256 hash of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// NewS3Getter constructs a Getter for s3://<bucket>/<key> URLs, reading the
+// objects of Amazon S3 with the credentials found like the AWS CLI finds
+// them:
+//
+//   - the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//     environment variables
+//   - the web identity token of AWS_WEB_IDENTITY_TOKEN_FILE, exchanged for
+//     the credentials of the AWS_ROLE_ARN role, as on EKS
+//   - the profile AWS_PROFILE, or "default", of the shared credentials file
+//   - the credentials of the ECS container, or of the EC2 instance
+//
+// Profiles assuming roles, or using credential_process or SSO, are not
+// supported and fail the downloads. The objects are read anonymously when
+// there are no credentials. The region is taken
+// from AWS_REGION, AWS_DEFAULT_REGION or the shared config file, and the
+// endpoint of an S3 compatible storage, such as MinIO, from
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL. The objects of the buckets of
+// other regions are read from the region S3 redirects to.
+func NewS3Getter(options ...Option) (Getter, error) {
+	s := &s3Store{}
+	g := newObjectStoreGetter(s, options...)
+	s.client = g.httpClient
+	return g, nil
+}
+
+// s3Store is Amazon S3, or a storage compatible with it.
+type s3Store struct {
+	once   sync.Once
+	region string
+	// client returns the client of the requests to STS, with the TLS and
+	// proxy settings of the getter.
+	client func() (*http.Client, error)
+
+	mu    sync.Mutex
+	creds *awsCredentials
+	// bucketRegions are the regions of the buckets found outside of region.
+	bucketRegions map[string]string
+	// noCredentials is set once no credentials were found.
+	noCredentials bool
+}
+
+type awsCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// expired reports whether the credentials expire in less than a minute.
+func (c *awsCredentials) expired() bool {
+	return !c.Expiration.IsZero() && time.Until(c.Expiration) < time.Minute
+}
+
+func (s *s3Store) objectURL(u *url.URL) (string, error) {
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if bucket == "" {
+		return "", errors.New("missing bucket")
+	}
+	s.once.Do(func() { s.region = awsRegion() })
+
+	if endpoint := awsEndpoint("S3"); endpoint != "" {
+		base, err := httpsEndpoint(endpoint, "https")
+		if err != nil {
+			return "", fmt.Errorf("invalid S3 endpoint %q: %w", endpoint, err)
+		}
+		// S3 compatible storages serve the buckets under their path.
+		return base.String() + escapePath("/"+bucket+"/"+key), nil
+	}
+	region := s.bucketRegion(bucket)
+	if strings.Contains(bucket, ".") {
+		// The certificate of S3 does not cover the buckets with dots in
+		// their name as subdomains.
+		return fmt.Sprintf("https://s3.%s.amazonaws.com%s", region, escapePath("/"+bucket+"/"+key)), nil
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", bucket, region, escapePath("/"+key)), nil
+}
+
+// redirectURL returns the URL of an object in the region of its bucket, when
+// S3 answered that the bucket is in another region than the one requested.
+func (s *s3Store) redirectURL(u *url.URL, err error) (string, bool) {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusMovedPermanently || awsEndpoint("S3") != "" {
+		return "", false
+	}
+	region := statusErr.Header.Get("X-Amz-Bucket-Region")
+	if region == "" || region == s.bucketRegion(u.Host) {
+		return "", false
+	}
+	slog.Debug("S3 bucket is in another region", "bucket", u.Host, "region", region)
+	s.mu.Lock()
+	if s.bucketRegions == nil {
+		s.bucketRegions = map[string]string{}
+	}
+	s.bucketRegions[u.Host] = region
+	s.mu.Unlock()
+	objectURL, err := s.objectURL(u)
+	return objectURL, err == nil
+}
+
+// bucketRegion returns the region of a bucket, the one of the environment
+// unless the bucket was found in another one.
+func (s *s3Store) bucketRegion(bucket string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if region, ok := s.bucketRegions[bucket]; ok {
+		return region
+	}
+	return s.region
+}
+
+func (s *s3Store) authorize(req *http.Request) error {
+	creds, err := s.credentials()
+	if err != nil || creds == nil {
+		return err
+	}
+	signV4(req, creds, s.signingRegion(req.URL.Host), "s3", time.Now())
+	return nil
+}
+
+// signingRegion returns the region of the S3 host a request is sent to, or
+// the region of the environment for the endpoints of S3 compatible storages.
+func (s *s3Store) signingRegion(host string) string {
+	rest, ok := strings.CutSuffix(host, ".amazonaws.com")
+	if !ok {
+		return s.region
+	}
+	rest = "." + rest
+	if i := strings.LastIndex(rest, ".s3."); i >= 0 {
+		return rest[i+len(".s3."):]
+	}
+	return s.region
+}
+
+// credentials returns the credentials found in the environment, or nil when
+// there are none.
+func (s *s3Store) credentials() (*awsCredentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.noCredentials || (s.creds != nil && !s.creds.expired()) {
+		return s.creds, nil
+	}
+	creds, err := findAWSCredentials(s.client, s.region)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get AWS credentials: %w", err)
+	}
+	if creds == nil {
+		slog.Debug("no AWS credentials found, reading S3 anonymously")
+		s.noCredentials = true
+	}
+	s.creds = creds
+	return creds, nil
+}
+
+func (s *s3Store) anonymous() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.noCredentials
+}
+
+// findAWSCredentials looks for the credentials in the order of the default
+// credential chain of the AWS SDKs.
+func findAWSCredentials(client func() (*http.Client, error), region string) (*awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return &awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	if tokenFile, role := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN"); tokenFile != "" && role != "" {
+		return assumeRoleWithWebIdentity(client, region, tokenFile, role)
+	}
+	if creds, err := profileCredentials(awsProfile()); err != nil || creds != nil {
+		return creds, err
+	}
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" {
+		return containerCredentials()
+	}
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, nil
+	}
+	creds, err := instanceCredentials()
+	if err != nil {
+		// Most likely not running on EC2
+		slog.Debug("unable to get the credentials of the EC2 instance", slog.Any("error", err))
+		return nil, nil
+	}
+	return creds, nil
+}
+
+// unsupportedProfileKeys are the keys of the profiles getting their
+// credentials in ways the S3 getter does not support.
+var unsupportedProfileKeys = []string{"role_arn", "source_profile", "credential_process", "sso_session", "sso_start_url", "sso_account_id", "web_identity_token_file"}
+
+// profileCredentials returns the static credentials of a profile of the
+// shared credentials and config files, or nil when it has none. It fails for
+// the profiles getting their credentials in the ways it does not support,
+// rather than reading S3 anonymously.
+func profileCredentials(profile string) (*awsCredentials, error) {
+	configSection := profile
+	if profile != "default" {
+		configSection = "profile " + profile
+	}
+	sections := []map[string]string{
+		readINI(awsConfigPath("AWS_SHARED_CREDENTIALS_FILE", "credentials"))[profile],
+		readINI(awsConfigPath("AWS_CONFIG_FILE", "config"))[configSection],
+	}
+	for _, section := range sections {
+		if section["aws_access_key_id"] != "" {
+			return &awsCredentials{
+				AccessKeyID:     section["aws_access_key_id"],
+				SecretAccessKey: section["aws_secret_access_key"],
+				SessionToken:    section["aws_session_token"],
+			}, nil
+		}
+	}
+	for _, section := range sections {
+		for _, key := range unsupportedProfileKeys {
+			if section[key] != "" {
+				return nil, fmt.Errorf("AWS profile %q sets %s, which the s3 getter does not support: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or use a plugin handling s3:// URLs", profile, key)
+			}
+		}
+	}
+	if os.Getenv("AWS_PROFILE") != "" && sections[0] == nil && sections[1] == nil {
+		return nil, fmt.Errorf("AWS profile %q not found", profile)
+	}
+	return nil, nil
+}
+
+// assumeRoleWithWebIdentity exchanges a web identity token for the
+// credentials of a role with STS.
+func assumeRoleWithWebIdentity(client func() (*http.Client, error), region, tokenFile, role string) (*awsCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := awsEndpoint("STS")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = fmt.Sprintf("helm-%d", time.Now().UnixNano())
+	}
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(query.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpClient, err := storeClient(client)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to assume role %s with web identity: %s", role, resp.Status)
+	}
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	c := result.Credentials
+	return &awsCredentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken, Expiration: c.Expiration}, nil
+}
+
+// containerCredentials returns the credentials of the task of an ECS
+// container, or of the pod of EKS Pod Identity.
+func containerCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if endpoint == "" {
+		endpoint = "http://169.254.170.2" + os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	var creds awsCredentials
+	if err := fetchJSON(metadataClient, req, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// instanceCredentials returns the credentials of the role of the EC2
+// instance, from its metadata service (IMDSv2).
+func instanceCredentials() (*awsCredentials, error) {
+	endpoint := strings.TrimSuffix(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), "/")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := fetchText(metadataClient, req)
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(path string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+		return req, nil
+	}
+	req, err = get("")
+	if err != nil {
+		return nil, err
+	}
+	roles, err := fetchText(metadataClient, req)
+	if err != nil {
+		return nil, err
+	}
+	role, _, _ := strings.Cut(roles, "\n")
+	if role == "" {
+		return nil, errors.New("the instance has no role")
+	}
+	req, err = get(role)
+	if err != nil {
+		return nil, err
+	}
+	var creds awsCredentials
+	if err := fetchJSON(metadataClient, req, &creds); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// awsRegion returns the region of the environment, or us-east-1.
+func awsRegion() string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+	profile := awsProfile()
+	if profile != "default" {
+		profile = "profile " + profile
+	}
+	if region := readINI(awsConfigPath("AWS_CONFIG_FILE", "config"))[profile]["region"]; region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// awsEndpoint returns the endpoint of a service set in the environment.
+func awsEndpoint(service string) string {
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_" + service); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("AWS_ENDPOINT_URL")
+}
+
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+// awsConfigPath returns the path of a shared AWS file, set in an environment
+// variable or in ~/.aws.
+func awsConfigPath(env, name string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readINI reads the keys of the sections of an INI file, such as the shared
+// AWS files. It returns nil when the file cannot be read.
+func readINI(filename string) map[string]map[string]string {
+	if filename == "" {
+		return nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	sections := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			section = sections[name]
+			if section == nil {
+				section = map[string]string{}
+				sections[name] = section
+			}
+		case section != nil:
+			if key, value, ok := strings.Cut(line, "="); ok {
+				section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		}
+	}
+	return sections
+}
+
+// signV4 signs a request without a body with AWS Signature Version 4.
+func signV4(req *http.Request, creds *awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	var query []string
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			query = append(query, escapeQuery(key)+"="+escapeQuery(value))
+		}
+	}
+	slices.Sort(query)
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(query, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptySHA256,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// escapeQuery percent-encodes all the bytes of a query key or value other
+// than the unreserved characters of RFC 3986.
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,408 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"helm.sh/helm/v4/pkg/action"
+	chart "helm.sh/helm/v4/pkg/chart"
+	"helm.sh/helm/v4/pkg/chart/common"
+	"helm.sh/helm/v4/pkg/chart/loader"
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/kube"
+	"helm.sh/helm/v4/pkg/registry"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+// ErrReleaseNotFound is returned when a release does not exist.
+var ErrReleaseNotFound = driver.ErrReleaseNotFound
+
+// Client runs Helm operations against a Kubernetes cluster.
+//
+// A Client is created with New and is configured from the same environment
+// variables as the helm binary, such as KUBECONFIG, HELM_NAMESPACE and
+// HELM_REPOSITORY_CONFIG, unless overridden by options.
+type Client struct {
+	settings *cli.EnvSettings
+	cfg      *action.Configuration
+	driver   string
+}
+
+// Option configures a Client created by New.
+type Option func(*Client) error
+
+// WithNamespace sets the namespace the client operates in.
+func WithNamespace(namespace string) Option {
+	return func(c *Client) error {
+		c.settings.SetNamespace(namespace)
+		return nil
+	}
+}
+
+// WithKubeConfig sets the kubeconfig file and the context within it used to
+// connect to the cluster. An empty context selects the current context.
+func WithKubeConfig(path, context string) Option {
+	return func(c *Client) error {
+		c.settings.KubeConfig = path
+		c.settings.KubeContext = context
+		return nil
+	}
+}
+
+// WithStorageDriver sets the driver used to store releases: "secret" (the
+// default), "configmap", "memory" or "sql".
+func WithStorageDriver(driver string) Option {
+	return func(c *Client) error {
+		switch driver {
+		case "", "secret", "secrets", "configmap", "configmaps", "memory", "sql":
+		default:
+			return fmt.Errorf("unknown storage driver %q", driver)
+		}
+		c.driver = driver
+		return nil
+	}
+}
+
+// WithActionConfiguration makes the client use an already initialized action
+// configuration instead of connecting to a cluster.
+//
+// This gives access to the internals of Helm and is therefore not covered by
+// the compatibility guarantees of this package.
+func WithActionConfiguration(cfg *action.Configuration) Option {
+	return func(c *Client) error {
+		if cfg == nil {
+			return errors.New("action configuration must not be nil")
+		}
+		c.cfg = cfg
+		return nil
+	}
+}
+
+// New creates a client with the given options.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{settings: cli.New()}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.cfg == nil {
+		c.cfg = new(action.Configuration)
+		if err := c.cfg.Init(c.settings.RESTClientGetter(), c.settings.Namespace(), c.driver); err != nil {
+			return nil, err
+		}
+	}
+	if c.cfg.RegistryClient == nil {
+		registryClient, err := registry.NewClient(
+			registry.ClientOptEnableCache(true),
+			registry.ClientOptWriter(io.Discard),
+			registry.ClientOptCredentialsFile(c.settings.RegistryConfig),
+			registry.ClientOptRegistriesFile(c.settings.RegistryMirrorsConfig),
+		)
+		if err != nil {
+			return nil, err
+		}
+		c.cfg.RegistryClient = registryClient
+	}
+	return c, nil
+}
+
+// Namespace returns the namespace the client operates in.
+func (c *Client) Namespace() string {
+	return c.settings.Namespace()
+}
+
+// ChartOptions locate the chart of an operation.
+type ChartOptions struct {
+	// Version is a version constraint for the chart. The latest version is
+	// used when empty.
+	Version string
+	// RepoURL is the URL of the chart repository holding the chart.
+	RepoURL string
+	// Username and Password authenticate against the chart repository.
+	Username string
+	Password string
+}
+
+func (o ChartOptions) chartPathOptions() action.ChartPathOptions {
+	return action.ChartPathOptions{
+		Version:  o.Version,
+		RepoURL:  o.RepoURL,
+		Username: o.Username,
+		Password: o.Password,
+	}
+}
+
+// InstallOptions configure Install.
+type InstallOptions struct {
+	ChartOptions
+	// CreateNamespace creates the namespace of the release if it does not exist.
+	CreateNamespace bool
+	// Wait waits until the resources of the release are ready.
+	Wait bool
+	// Timeout limits how long the installation may take. It defaults to five minutes.
+	Timeout time.Duration
+	// RollbackOnFailure uninstalls the release when the installation fails.
+	// It implies Wait.
+	RollbackOnFailure bool
+	// Description is the description of the release.
+	Description string
+}
+
+// Install installs the chart referenced by chartRef as the release name.
+//
+// The chart reference is resolved like the CHART argument of helm install: a
+// path to a packaged or unpacked chart, an OCI reference, a chart URL, or a
+// chart in a configured repository such as "repo/name".
+func (c *Client) Install(ctx context.Context, name, chartRef string, values map[string]interface{}, opts *InstallOptions) (*Release, error) {
+	if opts == nil {
+		opts = &InstallOptions{}
+	}
+	actionOpts := []action.InstallOption{
+		action.InstallWithReleaseName(name),
+		action.InstallWithNamespace(c.Namespace(), opts.CreateNamespace),
+		action.InstallWithChartPathOptions(opts.chartPathOptions()),
+		action.InstallWithDescription(opts.Description),
+	}
+	if opts.Wait || opts.RollbackOnFailure {
+		actionOpts = append(actionOpts, action.InstallWithWait(kube.StatusWatcherStrategy, timeout(opts.Timeout), false))
+	}
+	if opts.RollbackOnFailure {
+		actionOpts = append(actionOpts, action.InstallWithRollbackOnFailure())
+	}
+	client, err := action.NewInstallWithOptions(c.cfg, actionOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = timeout(opts.Timeout)
+	client.SetRegistryClient(c.cfg.RegistryClient)
+
+	ch, err := c.loadChart(&client.ChartPathOptions, chartRef)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := client.RunWithContext(ctx, ch, values)
+	if err != nil {
+		return nil, err
+	}
+	return newRelease(rel), nil
+}
+
+// UpgradeOptions configure Upgrade.
+type UpgradeOptions struct {
+	ChartOptions
+	// Install installs the release when it does not exist yet.
+	Install bool
+	// ReuseValues merges the given values into the values of the current release.
+	ReuseValues bool
+	// Wait waits until the resources of the release are ready.
+	Wait bool
+	// Timeout limits how long the upgrade may take. It defaults to five minutes.
+	Timeout time.Duration
+	// RollbackOnFailure rolls the release back when the upgrade fails.
+	// It implies Wait.
+	RollbackOnFailure bool
+	// Description is the description of the release.
+	Description string
+}
+
+// Upgrade upgrades the release name to the chart referenced by chartRef.
+func (c *Client) Upgrade(ctx context.Context, name, chartRef string, values map[string]interface{}, opts *UpgradeOptions) (*Release, error) {
+	if opts == nil {
+		opts = &UpgradeOptions{}
+	}
+	if opts.Install {
+		histClient := action.NewHistory(c.cfg)
+		histClient.Max = 1
+		if _, err := histClient.Run(name); errors.Is(err, ErrReleaseNotFound) {
+			return c.Install(ctx, name, chartRef, values, &InstallOptions{
+				ChartOptions:      opts.ChartOptions,
+				Wait:              opts.Wait,
+				Timeout:           opts.Timeout,
+				RollbackOnFailure: opts.RollbackOnFailure,
+				Description:       opts.Description,
+			})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	actionOpts := []action.UpgradeOption{
+		action.UpgradeWithNamespace(c.Namespace()),
+		action.UpgradeWithChartPathOptions(opts.chartPathOptions()),
+		action.UpgradeWithDescription(opts.Description),
+	}
+	if opts.Wait || opts.RollbackOnFailure {
+		actionOpts = append(actionOpts, action.UpgradeWithWait(kube.StatusWatcherStrategy, timeout(opts.Timeout), false))
+	}
+	if opts.RollbackOnFailure {
+		actionOpts = append(actionOpts, action.UpgradeWithRollbackOnFailure(false))
+	}
+	if opts.ReuseValues {
+		actionOpts = append(actionOpts, action.UpgradeWithReuseValues())
+	}
+	client, err := action.NewUpgradeWithOptions(c.cfg, actionOpts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = timeout(opts.Timeout)
+	client.SetRegistryClient(c.cfg.RegistryClient)
+
+	ch, err := c.loadChart(&client.ChartPathOptions, chartRef)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := client.RunWithContext(ctx, name, ch, values)
+	if err != nil {
+		return nil, err
+	}
+	return newRelease(rel), nil
+}
+
+// ListOptions configure List.
+type ListOptions struct {
+	// AllNamespaces lists the releases of all namespaces.
+	AllNamespaces bool
+	// Filter is a regular expression the names of the listed releases must match.
+	Filter string
+}
+
+// List lists the deployed and failed releases.
+func (c *Client) List(opts *ListOptions) ([]*Release, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+	client := action.NewList(c.cfg)
+	client.AllNamespaces = opts.AllNamespaces
+	client.Filter = opts.Filter
+	client.SetStateMask()
+	rels, err := client.Run()
+	if err != nil {
+		return nil, err
+	}
+	return newReleases(rels), nil
+}
+
+// Get returns the given revision of the release name. Revision 0 returns the
+// latest revision.
+func (c *Client) Get(name string, revision int) (*Release, error) {
+	client := action.NewGet(c.cfg)
+	client.Version = revision
+	rel, err := client.Run(name)
+	if err != nil {
+		return nil, err
+	}
+	return newRelease(rel), nil
+}
+
+// Pull downloads the chart referenced by chartRef into destDir.
+func (c *Client) Pull(chartRef, destDir string, opts *ChartOptions) error {
+	if opts == nil {
+		opts = &ChartOptions{}
+	}
+	client := action.NewPull(action.WithConfig(c.cfg))
+	client.Settings = c.settings
+	client.ChartPathOptions = opts.chartPathOptions()
+	client.DestDir = destDir
+	_, err := client.Run(chartRef)
+	return err
+}
+
+// Push uploads the chart archive at chartPath to the OCI registry at remote,
+// for example "oci://registry.example.com/charts".
+func (c *Client) Push(chartPath, remote string) error {
+	client := action.NewPushWithOpts(action.WithPushConfig(c.cfg))
+	client.Settings = c.settings
+	_, err := client.Run(chartPath, remote)
+	return err
+}
+
+// RenderOptions configure Render.
+type RenderOptions struct {
+	ChartOptions
+	// ReleaseName is the name of the release used while rendering. It
+	// defaults to "release-name".
+	ReleaseName string
+	// KubeVersion is the Kubernetes version the chart is rendered for. It
+	// defaults to the version Helm was built against.
+	KubeVersion string
+	// IncludeCRDs includes the custom resource definitions of the chart.
+	IncludeCRDs bool
+}
+
+// Render renders the chart referenced by chartRef without contacting the
+// cluster and returns the manifest.
+func (c *Client) Render(ctx context.Context, chartRef string, values map[string]interface{}, opts *RenderOptions) (string, error) {
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+	name := opts.ReleaseName
+	if name == "" {
+		name = "release-name"
+	}
+	client, err := action.NewInstallWithOptions(c.cfg,
+		action.InstallWithReleaseName(name),
+		action.InstallWithNamespace(c.Namespace(), false),
+		action.InstallWithChartPathOptions(opts.chartPathOptions()),
+		action.InstallWithDryRun("client", false),
+	)
+	if err != nil {
+		return "", err
+	}
+	client.ClientOnly = true
+	client.Replace = true
+	client.IncludeCRDs = opts.IncludeCRDs
+	client.SetRegistryClient(c.cfg.RegistryClient)
+	if opts.KubeVersion != "" {
+		kv, err := common.ParseKubeVersion(opts.KubeVersion)
+		if err != nil {
+			return "", err
+		}
+		client.KubeVersion = kv
+	}
+
+	ch, err := c.loadChart(&client.ChartPathOptions, chartRef)
+	if err != nil {
+		return "", err
+	}
+	rel, err := client.RunWithContext(ctx, ch, values)
+	if err != nil {
+		return "", err
+	}
+	return rel.Manifest, nil
+}
+
+func (c *Client) loadChart(cpo *action.ChartPathOptions, chartRef string) (chart.Charter, error) {
+	path, err := cpo.LocateChart(chartRef, c.settings)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load(path)
+}
+
+func timeout(d time.Duration) time.Duration {
+	if d == 0 {
+		return 300 * time.Second
+	}
+	return d
+}
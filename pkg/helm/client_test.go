@@ -0,0 +1,122 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/chart/common"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	kubefake "helm.sh/helm/v4/pkg/kube/fake"
+	"helm.sh/helm/v4/pkg/storage"
+	"helm.sh/helm/v4/pkg/storage/driver"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	cfg := &action.Configuration{
+		Releases:     storage.Init(driver.NewMemory()),
+		KubeClient:   &kubefake.PrintingKubeClient{Out: io.Discard},
+		Capabilities: common.DefaultCapabilities,
+	}
+	c, err := New(WithNamespace("spaced"), WithActionConfiguration(cfg))
+	require.NoError(t, err)
+	return c
+}
+
+func newTestChart(t *testing.T) string {
+	t.Helper()
+	path, err := chartutil.Create("facade", t.TempDir())
+	require.NoError(t, err)
+	return path
+}
+
+func TestClientLifecycle(t *testing.T) {
+	c := newTestClient(t)
+	chartPath := newTestChart(t)
+
+	_, err := c.Get("myrelease", 0)
+	assert.ErrorIs(t, err, ErrReleaseNotFound)
+
+	rel, err := c.Install(t.Context(), "myrelease", chartPath, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "myrelease", rel.Name)
+	assert.Equal(t, "spaced", rel.Namespace)
+	assert.Equal(t, 1, rel.Revision)
+	assert.Equal(t, "deployed", rel.Status)
+	assert.Equal(t, "facade", rel.Chart)
+	assert.Equal(t, "0.1.0", rel.ChartVersion)
+	assert.Contains(t, rel.Manifest, "kind: Deployment")
+
+	rel, err = c.Upgrade(t.Context(), "myrelease", chartPath, map[string]interface{}{"replicaCount": 3}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, rel.Revision)
+	assert.Contains(t, rel.Manifest, "replicas: 3")
+
+	rel, err = c.Get("myrelease", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rel.Revision)
+	assert.Equal(t, "superseded", rel.Status)
+
+	rels, err := c.List(nil)
+	require.NoError(t, err)
+	require.Len(t, rels, 1)
+	assert.Equal(t, 2, rels[0].Revision)
+}
+
+func TestClientUpgradeInstall(t *testing.T) {
+	c := newTestClient(t)
+	chartPath := newTestChart(t)
+
+	rel, err := c.Upgrade(t.Context(), "fresh", chartPath, nil, &UpgradeOptions{Install: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, rel.Revision)
+
+	rel, err = c.Upgrade(t.Context(), "fresh", chartPath, nil, &UpgradeOptions{Install: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, rel.Revision)
+}
+
+func TestClientRender(t *testing.T) {
+	c := newTestClient(t)
+	chartPath := newTestChart(t)
+
+	manifest, err := c.Render(t.Context(), chartPath, map[string]interface{}{"replicaCount": 5}, &RenderOptions{ReleaseName: "rendered"})
+	require.NoError(t, err)
+	assert.Contains(t, manifest, "name: rendered-facade")
+	assert.Contains(t, manifest, "replicas: 5")
+
+	rels, err := c.List(nil)
+	require.NoError(t, err)
+	assert.Empty(t, rels, "rendering must not record a release")
+
+	_, err = c.Render(t.Context(), chartPath, nil, &RenderOptions{KubeVersion: "not-a-version"})
+	assert.Error(t, err)
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	_, err := New(WithStorageDriver("etcd"))
+	assert.ErrorContains(t, err, `unknown storage driver "etcd"`)
+
+	_, err = New(WithActionConfiguration(nil))
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package helm is a small, stable facade over the most common Helm operations:
+installing, upgrading, listing, getting, pulling, pushing and rendering charts.
+
+It is meant for programs embedding Helm that do not need the full flexibility
+of the action package. The types of this package are deliberately minimal and
+do not expose the chart, release, kube or storage packages, which change more
+often.
+
+# Compatibility
+
+Within a major version of Helm the exported API of this package only grows:
+functions, methods and option fields are never removed or changed in an
+incompatible way, and the zero value of every option struct keeps meaning the
+default behavior of the matching helm command.
+*/
+package helm // import "helm.sh/helm/v4/pkg/helm"
@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"time"
+
+	release "helm.sh/helm/v4/pkg/release/v1"
+)
+
+// Release is a deployment of a chart.
+type Release struct {
+	// Name is the name of the release.
+	Name string
+	// Namespace is the namespace the release is deployed into.
+	Namespace string
+	// Revision is the revision of the release, starting at 1.
+	Revision int
+	// Status is the status of the release, such as "deployed" or "failed".
+	Status string
+	// Description is a human readable description of the last operation.
+	Description string
+	// Chart is the name of the released chart.
+	Chart string
+	// ChartVersion is the version of the released chart.
+	ChartVersion string
+	// AppVersion is the version of the application packaged by the chart.
+	AppVersion string
+	// Notes are the rendered notes of the chart.
+	Notes string
+	// Manifest is the rendered manifest of the release.
+	Manifest string
+	// Updated is when the release was last deployed.
+	Updated time.Time
+}
+
+func newRelease(rel *release.Release) *Release {
+	if rel == nil {
+		return nil
+	}
+	r := &Release{
+		Name:      rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Manifest:  rel.Manifest,
+	}
+	if rel.Info != nil {
+		r.Status = rel.Info.Status.String()
+		r.Description = rel.Info.Description
+		r.Notes = rel.Info.Notes
+		r.Updated = rel.Info.LastDeployed
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		r.Chart = rel.Chart.Metadata.Name
+		r.ChartVersion = rel.Chart.Metadata.Version
+		r.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	return r
+}
+
+func newReleases(rels []*release.Release) []*Release {
+	out := make([]*Release, 0, len(rels))
+	for _, rel := range rels {
+		out = append(out, newRelease(rel))
+	}
+	return out
+}
@@ -34,6 +34,15 @@ func CacheIndexFile(name string) string {
 	return name + "index.yaml"
 }
 
+// CacheIndexStateFile returns the path to the state of the cached index of the
+// given named repository, with which the index is updated incrementally.
+func CacheIndexStateFile(name string) string {
+	if name != "" {
+		name += "-"
+	}
+	return name + "index-state.json"
+}
+
 // CacheChartsFile returns the path to a text file listing all the charts
 // within the given named repository.
 func CacheChartsFile(name string) string {
@@ -42,3 +51,12 @@ func CacheChartsFile(name string) string {
 	}
 	return name + "charts.txt"
 }
+
+// CacheSearchFile returns the path to the search cache built from the index of
+// the given named repository.
+func CacheSearchFile(name string) string {
+	if name != "" {
+		name += "-"
+	}
+	return name + "search.bin"
+}
@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v4/pkg/annotations"
+)
+
+// resourceAnnotation returns the normalized value of a Helm annotation of a
+// resource, or def when it is not set or has an invalid value.
+func resourceAnnotation(info *resource.Info, name, def string) string {
+	if info.Object == nil {
+		return def
+	}
+	a, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		slog.Debug("unable to get annotations", "namespace", info.Namespace, "name", info.Name, slog.Any("error", err))
+		return def
+	}
+	return annotations.Get(a, name, def)
+}
+
+// deletePropagation returns the deletion propagation of a resource, which is
+// policy unless the resource overrides it with the delete propagation
+// annotation.
+func deletePropagation(info *resource.Info, policy metav1.DeletionPropagation) metav1.DeletionPropagation {
+	switch resourceAnnotation(info, annotations.DeletePropagation, "") {
+	case annotations.DeletePropagationBackground:
+		return metav1.DeletePropagationBackground
+	case annotations.DeletePropagationForeground:
+		return metav1.DeletePropagationForeground
+	case annotations.DeletePropagationOrphan:
+		return metav1.DeletePropagationOrphan
+	}
+	return policy
+}
+
+// waitedFor returns the resources to wait for, leaving out the ones whose wait
+// policy annotation says to skip them.
+func waitedFor(resources ResourceList) ResourceList {
+	return resources.Filter(func(info *resource.Info) bool {
+		return resourceAnnotation(info, annotations.WaitPolicy, annotations.WaitPolicyWait) != annotations.WaitPolicySkip
+	})
+}
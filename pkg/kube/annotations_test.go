@@ -0,0 +1,157 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+
+	"helm.sh/helm/v4/pkg/annotations"
+)
+
+func annotatedPod(name string, annos map[string]string) *v1.Pod {
+	pod := newPod(name)
+	pod.Annotations = annos
+	return &pod
+}
+
+func TestDeletePropagation(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected metav1.DeletionPropagation
+	}{
+		{"", metav1.DeletePropagationBackground},
+		{"orphan", metav1.DeletePropagationOrphan},
+		{" Foreground ", metav1.DeletePropagationForeground},
+		{"background", metav1.DeletePropagationBackground},
+		{"cascade", metav1.DeletePropagationBackground},
+	}
+	for _, tt := range tests {
+		annos := map[string]string{}
+		if tt.value != "" {
+			annos[annotations.DeletePropagation] = tt.value
+		}
+		info := &resource.Info{Name: "starfish", Object: annotatedPod("starfish", annos)}
+		assert.Equal(t, tt.expected, deletePropagation(info, metav1.DeletePropagationBackground), tt.value)
+	}
+
+	info := &resource.Info{Name: "starfish", Object: annotatedPod("starfish", nil)}
+	assert.Equal(t, metav1.DeletePropagationForeground, deletePropagation(info, metav1.DeletePropagationForeground))
+}
+
+func TestWaitedFor(t *testing.T) {
+	resources := ResourceList{
+		{Name: "starfish", Object: annotatedPod("starfish", nil)},
+		{Name: "otter", Object: annotatedPod("otter", map[string]string{annotations.WaitPolicy: "skip"})},
+		{Name: "squid", Object: annotatedPod("squid", map[string]string{annotations.WaitPolicy: "wait"})},
+		{Name: "dolphin", Object: annotatedPod("dolphin", map[string]string{annotations.WaitPolicy: "later"})},
+	}
+	var names []string
+	for _, info := range waitedFor(resources) {
+		names = append(names, info.Name)
+	}
+	assert.Equal(t, []string{"starfish", "squid", "dolphin"}, names)
+}
+
+func TestDeleteWithPropagationAnnotation(t *testing.T) {
+	c := newTestClient(t)
+	pod := annotatedPod("starfish", map[string]string{annotations.DeletePropagation: "orphan"})
+
+	var body string
+	client := NewRequestResponseLogClient(t, func(_ []RequestResponseAction, req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/namespaces/default/pods/starfish" && req.Method == http.MethodDelete {
+			data, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			body = string(data)
+			return newResponse(http.StatusOK, pod)
+		}
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+		return newResponse(http.StatusNotFound, notFoundBody())
+	})
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client:               fake.CreateHTTPClient(client.Do),
+	}
+
+	resources, err := c.Build(objBody(pod), false)
+	require.NoError(t, err)
+	_, errs := c.Delete(resources)
+	require.Empty(t, errs)
+	assert.Contains(t, body, `"propagationPolicy":"Orphan"`)
+}
+
+func TestUpdateApplyStrategyAnnotation(t *testing.T) {
+	tests := []struct {
+		strategy string
+		method   string
+	}{
+		{annotations.ApplyStrategyReplace, http.MethodPut},
+		{annotations.ApplyStrategyClientSide, http.MethodPatch},
+		{annotations.ApplyStrategyServerSide, http.MethodPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			c := newTestClient(t)
+			original := annotatedPod("starfish", nil)
+			target := annotatedPod("starfish", map[string]string{annotations.ApplyStrategy: tt.strategy})
+			target.Spec.Containers[0].Ports = []v1.ContainerPort{{Name: "https", ContainerPort: 443}}
+
+			var contentType string
+			client := NewRequestResponseLogClient(t, func(_ []RequestResponseAction, req *http.Request) (*http.Response, error) {
+				switch {
+				case req.URL.Path == "/namespaces/default/pods/starfish" && req.Method == http.MethodGet:
+					return newResponse(http.StatusOK, original)
+				case req.URL.Path == "/namespaces/default/pods/starfish" && req.Method == tt.method:
+					contentType = req.Header.Get("Content-Type")
+					return newResponse(http.StatusOK, target)
+				}
+				t.Errorf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return newResponse(http.StatusNotFound, notFoundBody())
+			})
+			c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+				NegotiatedSerializer: unstructuredSerializer,
+				Client:               fake.CreateHTTPClient(client.Do),
+			}
+
+			originals, err := c.Build(objBody(original), false)
+			require.NoError(t, err)
+			targets, err := c.Build(objBody(target), false)
+			require.NoError(t, err)
+
+			// Server-side apply is the default, so an annotated resource must
+			// be applied differently only when it asks for it.
+			_, err = c.Update(originals, targets, ClientUpdateOptionServerSideApply(tt.strategy != annotations.ApplyStrategyServerSide, false))
+			require.NoError(t, err)
+
+			switch tt.strategy {
+			case annotations.ApplyStrategyClientSide:
+				assert.Equal(t, "application/strategic-merge-patch+json", contentType)
+			case annotations.ApplyStrategyServerSide:
+				assert.Equal(t, "application/apply-patch+yaml", contentType)
+			}
+		})
+	}
+}
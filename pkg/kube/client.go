@@ -30,6 +30,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch/v5"
 	v1 "k8s.io/api/core/v1"
@@ -57,6 +58,8 @@ import (
 	"k8s.io/client-go/util/csaupgrade"
 	"k8s.io/client-go/util/retry"
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"helm.sh/helm/v4/pkg/annotations"
 )
 
 // ErrNoObjectsVisited indicates that during a visit operation, no matching objects were found.
@@ -64,6 +67,10 @@ var ErrNoObjectsVisited = errors.New("no objects visited")
 
 var metadataAccessor = meta.NewAccessor()
 
+// DefaultMaxConcurrentRequests is the number of resources created or deleted
+// in parallel when Client.MaxConcurrentRequests is not set.
+const DefaultMaxConcurrentRequests = 64
+
 // ManagedFieldsManager is the name of the manager of Kubernetes managedFields
 // first introduced in Kubernetes 1.18
 var ManagedFieldsManager string
@@ -81,6 +88,16 @@ type Client struct {
 	Factory Factory
 	// Namespace allows to bypass the kubeconfig file for the choice of the namespace
 	Namespace string
+	// MaxConcurrentRequests limits how many resources are created or deleted
+	// in parallel. Bounding it keeps the memory used for in-flight requests
+	// under control when applying very large manifests. Values of 0 or less
+	// use DefaultMaxConcurrentRequests.
+	MaxConcurrentRequests int
+	// HeartbeatInterval is how often the credentials and connections of the
+	// client are exercised while waiting, so that they are still valid once
+	// long waits end. Zero uses DefaultHeartbeatInterval and negative values
+	// disable the heartbeat.
+	HeartbeatInterval time.Duration
 
 	Waiter
 	kubeClient kubernetes.Interface
@@ -137,6 +154,17 @@ func (c *Client) newStatusWatcher() (*statusWaiter, error) {
 }
 
 func (c *Client) GetWaiter(strategy WaitStrategy) (Waiter, error) {
+	w, err := c.getWaiter(strategy)
+	if err != nil {
+		return nil, err
+	}
+	if hb := c.newHeartbeat(); hb != nil {
+		return &heartbeatWaiter{Waiter: w, heartbeat: hb}, nil
+	}
+	return w, nil
+}
+
+func (c *Client) getWaiter(strategy WaitStrategy) (Waiter, error) {
 	switch strategy {
 	case LegacyStrategy:
 		kc, err := c.Factory.KubernetesClientSet()
@@ -300,7 +328,19 @@ func (c *Client) Create(resources ResourceList, options ...ClientCreateOption) (
 		return createResource
 	}
 
-	if err := perform(resources, makeCreateApplyFunc()); err != nil {
+	createApplyFunc := makeCreateApplyFunc()
+	if err := performLimited(resources, c.maxConcurrentRequests(), func(target *resource.Info) error {
+		// The apply strategy annotation of a resource overrides the options
+		switch resourceAnnotation(target, annotations.ApplyStrategy, "") {
+		case annotations.ApplyStrategyServerSide:
+			return patchResourceServerSide(target, createOptions.dryRun, createOptions.forceConflicts, createOptions.fieldValidationDirective)
+		case annotations.ApplyStrategyClientSide, annotations.ApplyStrategyReplace:
+			if !createOptions.dryRun {
+				return createResource(target)
+			}
+		}
+		return createApplyFunc(target)
+	}); err != nil {
 		return nil, err
 	}
 	return &Result{Created: resources}, nil
@@ -482,6 +522,10 @@ func buildResourceList(f Factory, namespace string, validationDirective FieldVal
 }
 
 // Build validates for Kubernetes objects and returns unstructured infos.
+//
+// The manifest is decoded one document at a time as it is read from reader,
+// so that large manifests are not held twice: pass a reader over the
+// manifest rather than a copy of it.
 func (c *Client) Build(reader io.Reader, validate bool) (ResourceList, error) {
 	return buildResourceList(
 		c.Factory,
@@ -561,15 +605,11 @@ func (c *Client) update(originals, targets ResourceList, updateApplyFunc UpdateA
 			slog.Debug("unable to get object", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
 			continue
 		}
-		annotations, err := metadataAccessor.Annotations(info.Object)
-		if err != nil {
-			slog.Debug("unable to get annotations", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
-		}
-		if annotations != nil && annotations[ResourcePolicyAnno] == KeepPolicy {
+		if resourceAnnotation(info, ResourcePolicyAnno, "") == KeepPolicy {
 			slog.Debug("skipping delete due to annotation", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, "annotation", ResourcePolicyAnno, "value", KeepPolicy)
 			continue
 		}
-		if err := deleteResource(info, metav1.DeletePropagationBackground); err != nil {
+		if err := deleteResource(info, deletePropagation(info, metav1.DeletePropagationBackground)); err != nil {
 			slog.Debug("failed to delete resource", "namespace", info.Namespace, "name", info.Name, "kind", info.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
 			continue
 		}
@@ -766,7 +806,23 @@ func (c *Client) Update(originals, targets ResourceList, options ...ClientUpdate
 		}
 	}
 
-	return c.update(originals, targets, makeUpdateApplyFunc())
+	updateApplyFunc := makeUpdateApplyFunc()
+	return c.update(originals, targets, func(original, target *resource.Info) error {
+		// The apply strategy annotation of a resource overrides the options,
+		// except for dry runs, which only server-side apply supports.
+		if updateOptions.dryRun {
+			return updateApplyFunc(original, target)
+		}
+		switch resourceAnnotation(target, annotations.ApplyStrategy, "") {
+		case annotations.ApplyStrategyServerSide:
+			return patchResourceServerSide(target, false, updateOptions.forceConflicts && updateOptions.serverSideApply, updateOptions.fieldValidationDirective)
+		case annotations.ApplyStrategyClientSide:
+			return patchResourceClientSide(original.Object, target, false)
+		case annotations.ApplyStrategyReplace:
+			return replaceResource(target, updateOptions.fieldValidationDirective)
+		}
+		return updateApplyFunc(original, target)
+	})
 }
 
 // Delete deletes Kubernetes resources specified in the resources list with
@@ -774,7 +830,7 @@ func (c *Client) Update(originals, targets ResourceList, options ...ClientUpdate
 // if one or more fail and collect any errors. All successfully deleted items
 // will be returned in the `Deleted` ResourceList that is part of the result.
 func (c *Client) Delete(resources ResourceList) (*Result, []error) {
-	return deleteResources(resources, metav1.DeletePropagationBackground)
+	return deleteResources(resources, metav1.DeletePropagationBackground, c.maxConcurrentRequests())
 }
 
 // Delete deletes Kubernetes resources specified in the resources list with
@@ -782,16 +838,16 @@ func (c *Client) Delete(resources ResourceList) (*Result, []error) {
 // if one or more fail and collect any errors. All successfully deleted items
 // will be returned in the `Deleted` ResourceList that is part of the result.
 func (c *Client) DeleteWithPropagationPolicy(resources ResourceList, policy metav1.DeletionPropagation) (*Result, []error) {
-	return deleteResources(resources, policy)
+	return deleteResources(resources, policy, c.maxConcurrentRequests())
 }
 
-func deleteResources(resources ResourceList, propagation metav1.DeletionPropagation) (*Result, []error) {
+func deleteResources(resources ResourceList, propagation metav1.DeletionPropagation, limit int) (*Result, []error) {
 	var errs []error
 	res := &Result{}
 	mtx := sync.Mutex{}
-	err := perform(resources, func(target *resource.Info) error {
+	err := performLimited(resources, limit, func(target *resource.Info) error {
 		slog.Debug("starting delete resource", "namespace", target.Namespace, "name", target.Name, "kind", target.Mapping.GroupVersionKind.Kind)
-		err := deleteResource(target, propagation)
+		err := deleteResource(target, deletePropagation(target, propagation))
 		if err == nil || apierrors.IsNotFound(err) {
 			if err != nil {
 				slog.Debug("ignoring delete failure", "namespace", target.Namespace, "name", target.Name, "kind", target.Mapping.GroupVersionKind.Kind, slog.Any("error", err))
@@ -850,7 +906,20 @@ func getManagedFieldsManager() string {
 	return filepath.Base(os.Args[0])
 }
 
+func (c *Client) maxConcurrentRequests() int {
+	if c.MaxConcurrentRequests > 0 {
+		return c.MaxConcurrentRequests
+	}
+	return DefaultMaxConcurrentRequests
+}
+
 func perform(infos ResourceList, fn func(*resource.Info) error) error {
+	return performLimited(infos, 0, fn)
+}
+
+// performLimited runs fn for every resource, running at most limit calls at
+// the same time. A limit of 0 or less runs all calls of a kind at once.
+func performLimited(infos ResourceList, limit int, fn func(*resource.Info) error) error {
 	var result error
 
 	if len(infos) == 0 {
@@ -858,7 +927,7 @@ func perform(infos ResourceList, fn func(*resource.Info) error) error {
 	}
 
 	errs := make(chan error)
-	go batchPerform(infos, fn, errs)
+	go batchPerform(infos, limit, fn, errs)
 
 	for range infos {
 		err := <-errs
@@ -870,11 +939,16 @@ func perform(infos ResourceList, fn func(*resource.Info) error) error {
 	return result
 }
 
-func batchPerform(infos ResourceList, fn func(*resource.Info) error, errs chan<- error) {
+func batchPerform(infos ResourceList, limit int, fn func(*resource.Info) error, errs chan<- error) {
 	var kind string
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+
 	for _, info := range infos {
 		currentKind := info.Object.GetObjectKind().GroupVersionKind().Kind
 		if kind != currentKind {
@@ -882,9 +956,16 @@ func batchPerform(infos ResourceList, fn func(*resource.Info) error, errs chan<-
 			kind = currentKind
 		}
 
+		if sem != nil {
+			sem <- struct{}{}
+		}
 		wg.Add(1)
 		go func(info *resource.Info) {
-			errs <- fn(info)
+			err := fn(info)
+			if sem != nil {
+				<-sem
+			}
+			errs <- err
 			wg.Done()
 		}(info)
 	}
@@ -488,6 +488,46 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateStopsAtFirstFailure(t *testing.T) {
+	listOriginal := newPodList("starfish", "otter")
+	listTarget := newPodList("starfish", "otter", "dolphin")
+
+	client := NewRequestResponseLogClient(t, func(_ []RequestResponseAction, req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/namespaces/default/pods/starfish" && req.Method == http.MethodGet {
+			return newResponse(http.StatusInternalServerError, &metav1.Status{
+				Status: metav1.StatusFailure,
+				Reason: metav1.StatusReasonInternalError,
+				Code:   http.StatusInternalServerError,
+			})
+		}
+		t.Errorf("unexpected request %s %s after the first failure", req.Method, req.URL.Path)
+		return nil, nil
+	})
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client:               fake.CreateHTTPClient(client.Do),
+	}
+
+	first, err := c.Build(objBody(&listOriginal), false)
+	require.NoError(t, err)
+	second, err := c.Build(objBody(&listTarget), false)
+	require.NoError(t, err)
+
+	result, err := c.Update(first, second)
+	require.ErrorContains(t, err, "could not get information about the resource")
+	assert.Empty(t, result.Created)
+	assert.Empty(t, result.Updated)
+	assert.Empty(t, result.Deleted)
+
+	actions := []string{}
+	for _, action := range client.Actions {
+		actions = append(actions, action.Request.URL.Path+":"+action.Request.Method)
+	}
+	assert.Equal(t, []string{"/namespaces/default/pods/starfish:GET"}, actions)
+}
+
 func TestBuild(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -616,6 +656,45 @@ func TestPerform(t *testing.T) {
 	}
 }
 
+func TestPerformLimited(t *testing.T) {
+	c := newTestClient(t)
+	infos, err := c.Build(strings.NewReader(guestbookManifest), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make all resources the same kind so they would otherwise run at once.
+	for _, info := range infos {
+		info.Object.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+	}
+
+	var mu sync.Mutex
+	var running, maxRunning, calls int
+	fn := func(_ *resource.Info) error {
+		mu.Lock()
+		running++
+		calls++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	if err := performLimited(infos, 2, fn); err != nil {
+		t.Fatal(err)
+	}
+	if calls != len(infos) {
+		t.Errorf("expected %d calls, got %d", len(infos), calls)
+	}
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", maxRunning)
+	}
+}
+
 func TestWait(t *testing.T) {
 	podList := newPodList("starfish", "otter", "squid")
 
@@ -0,0 +1,389 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// expirySkew is how long before their expiry credentials are considered
+// expired, so that they do not expire while a request is in flight.
+const expirySkew = 30 * time.Second
+
+// ExecCredentialOptions control how the exec credential plugin of a
+// kubeconfig user, such as those of EKS, GKE and AKS, is invoked.
+//
+// The plugins print short-lived tokens. When one of them fails to refresh a
+// token during a long operation, such as waiting for resources to become
+// ready, client-go only reports that the API server rejected the request as
+// "Unauthorized".
+type ExecCredentialOptions struct {
+	// Timeout bounds each invocation of the plugin. Zero means no timeout.
+	Timeout time.Duration
+	// Retries is how many times a failed invocation of the plugin is retried.
+	Retries int
+	// CacheDir is where the credentials printed by the plugin are cached until
+	// they expire, so that they are shared between Helm invocations. Nothing
+	// is cached on disk if it is empty.
+	CacheDir string
+}
+
+func (o ExecCredentialOptions) enabled() bool {
+	return o.Timeout > 0 || o.Retries > 0 || o.CacheDir != ""
+}
+
+// WrapExecCredential makes a REST config invoke its exec credential plugin
+// under the given options rather than leaving the invocation to client-go.
+// Failures to refresh the credentials are reported with the output of the
+// plugin, and rejected credentials are refreshed and the request retried once.
+//
+// The config is returned unchanged when no option is set, when it has no exec
+// credential plugin, or when the plugin may need to interact with the user.
+// Only plugins printing tokens are supported; plugins printing client
+// certificates fail with an error while options are set.
+func WrapExecCredential(config *rest.Config, opts ExecCredentialOptions) *rest.Config {
+	if !opts.enabled() || config.ExecProvider == nil || config.ExecProvider.InteractiveMode == clientcmdapi.AlwaysExecInteractiveMode {
+		return config
+	}
+	source, err := execCredentialSourceFor(config, opts)
+	if err != nil {
+		slog.Warn("not applying exec credential plugin options", slog.Any("error", err))
+		return config
+	}
+	config.ExecProvider = nil
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &execCredentialRoundTripper{source: source, wrapped: rt}
+	})
+	return config
+}
+
+// execCredentialSources holds a source per plugin configuration, so that all
+// the clients of a process share the cached credentials.
+var execCredentialSources sync.Map
+
+func execCredentialSourceFor(config *rest.Config, opts ExecCredentialOptions) (*execCredentialSource, error) {
+	info, err := execInfo(config)
+	if err != nil {
+		return nil, err
+	}
+	key, err := json.Marshal(struct {
+		Exec *clientcmdapi.ExecConfig
+		Info []byte
+		Opts ExecCredentialOptions
+	}{config.ExecProvider, info, opts})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	id := hex.EncodeToString(sum[:])
+	source, _ := execCredentialSources.LoadOrStore(id, &execCredentialSource{
+		id:    id,
+		exec:  config.ExecProvider,
+		info:  info,
+		opts:  opts,
+		now:   time.Now,
+		sleep: time.Sleep,
+	})
+	return source.(*execCredentialSource), nil
+}
+
+// execCredential is the part of the ExecCredential objects printed by the
+// plugins that Helm uses. It is common to all their API versions.
+type execCredential struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Status     *struct {
+		ExpirationTimestamp   *time.Time `json:"expirationTimestamp,omitempty"`
+		Token                 string     `json:"token,omitempty"`
+		ClientCertificateData string     `json:"clientCertificateData,omitempty"`
+	} `json:"status,omitempty"`
+}
+
+// execInfo returns the ExecCredential passed to the plugin in the
+// KUBERNETES_EXEC_INFO environment variable.
+func execInfo(config *rest.Config) ([]byte, error) {
+	type cluster struct {
+		Server                   string `json:"server"`
+		TLSServerName            string `json:"tls-server-name,omitempty"`
+		InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify,omitempty"`
+		CertificateAuthorityData []byte `json:"certificate-authority-data,omitempty"`
+		ProxyURL                 string `json:"proxy-url,omitempty"`
+	}
+	spec := struct {
+		Cluster     *cluster `json:"cluster,omitempty"`
+		Interactive bool     `json:"interactive"`
+	}{}
+	if config.ExecProvider.ProvideClusterInfo {
+		ca := config.CAData
+		if len(ca) == 0 && config.CAFile != "" {
+			var err error
+			if ca, err = os.ReadFile(config.CAFile); err != nil {
+				return nil, err
+			}
+		}
+		spec.Cluster = &cluster{
+			Server:                   config.Host,
+			TLSServerName:            config.ServerName,
+			InsecureSkipTLSVerify:    config.Insecure,
+			CertificateAuthorityData: ca,
+		}
+		if config.Proxy != nil {
+			if u, err := config.Proxy(&http.Request{}); err == nil && u != nil {
+				spec.Cluster.ProxyURL = u.String()
+			}
+		}
+	}
+	return json.Marshal(map[string]any{
+		"apiVersion": config.ExecProvider.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       spec,
+	})
+}
+
+// execCredentialSource invokes a plugin and caches the token it prints.
+type execCredentialSource struct {
+	id   string
+	exec *clientcmdapi.ExecConfig
+	info []byte
+	opts ExecCredentialOptions
+
+	now   func() time.Time
+	sleep func(time.Duration)
+
+	mu      sync.Mutex
+	token   string
+	expires *time.Time
+}
+
+// Token returns the cached token, invoking the plugin when there is none or
+// when it expired.
+func (s *execCredentialSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.valid(s.expires) {
+		return s.token, nil
+	}
+	if cred := s.loadCached(); cred != nil {
+		s.token, s.expires = cred.Status.Token, cred.Status.ExpirationTimestamp
+		return s.token, nil
+	}
+	return s.refresh()
+}
+
+// Refresh invokes the plugin after the API server rejected token, unless the
+// token was refreshed in the meantime.
+func (s *execCredentialSource) Refresh(token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && s.token != token {
+		return s.token, nil
+	}
+	s.token, s.expires = "", nil
+	if s.opts.CacheDir != "" {
+		if err := os.Remove(s.cacheFile()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Debug("unable to remove cached exec credentials", "file", s.cacheFile(), slog.Any("error", err))
+		}
+	}
+	return s.refresh()
+}
+
+// RefreshExpiring invokes the plugin if the cached token expires within the
+// given duration, so that it does not expire during a long operation.
+func (s *execCredentialSource) RefreshExpiring(within time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == "" || s.expires == nil || s.now().Add(within+expirySkew).Before(*s.expires) {
+		return nil
+	}
+	_, err := s.refresh()
+	return err
+}
+
+func (s *execCredentialSource) valid(expires *time.Time) bool {
+	return expires == nil || s.now().Add(expirySkew).Before(*expires)
+}
+
+func (s *execCredentialSource) refresh() (string, error) {
+	var data []byte
+	var err error
+	delay := 500 * time.Millisecond
+	attempts := s.opts.Retries + 1
+	for attempt := 1; ; attempt++ {
+		data, err = s.run()
+		if err == nil || attempt == attempts {
+			break
+		}
+		slog.Debug("exec credential plugin failed, retrying", "command", s.exec.Command, "attempt", attempt, slog.Any("error", err))
+		s.sleep(delay)
+		delay *= 2
+	}
+	if err != nil {
+		if attempts > 1 {
+			return "", fmt.Errorf("exec credential plugin %q failed after %d attempts: %w", s.exec.Command, attempts, err)
+		}
+		return "", fmt.Errorf("exec credential plugin %q failed: %w", s.exec.Command, err)
+	}
+
+	var cred execCredential
+	if err := json.Unmarshal(data, &cred); err != nil {
+		return "", fmt.Errorf("exec credential plugin %q printed invalid credentials: %w", s.exec.Command, err)
+	}
+	if cred.Kind != "ExecCredential" || cred.Status == nil {
+		return "", fmt.Errorf("exec credential plugin %q printed no ExecCredential status", s.exec.Command)
+	}
+	if cred.Status.Token == "" {
+		if cred.Status.ClientCertificateData != "" {
+			return "", fmt.Errorf("exec credential plugin %q printed a client certificate, which is not supported with the exec credential plugin options", s.exec.Command)
+		}
+		return "", fmt.Errorf("exec credential plugin %q printed no token", s.exec.Command)
+	}
+	s.token, s.expires = cred.Status.Token, cred.Status.ExpirationTimestamp
+	s.storeCached(data)
+	return s.token, nil
+}
+
+// run invokes the plugin once and returns what it printed.
+func (s *execCredentialSource) run() ([]byte, error) {
+	ctx := context.Background()
+	if s.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, s.exec.Command, s.exec.Args...)
+	cmd.Env = append(os.Environ(), "KUBERNETES_EXEC_INFO="+string(s.info))
+	for _, env := range s.exec.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("timed out after %s", s.opts.Timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		if s.exec.InstallHint != "" && errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%w\n\n%s", err, s.exec.InstallHint)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *execCredentialSource) cacheFile() string {
+	return filepath.Join(s.opts.CacheDir, s.id+".json")
+}
+
+// loadCached returns the credentials cached on disk if they did not expire.
+func (s *execCredentialSource) loadCached() *execCredential {
+	if s.opts.CacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.cacheFile())
+	if err != nil {
+		return nil
+	}
+	var cred execCredential
+	if err := json.Unmarshal(data, &cred); err != nil || cred.Status == nil || cred.Status.Token == "" {
+		return nil
+	}
+	if cred.Status.ExpirationTimestamp == nil || !s.valid(cred.Status.ExpirationTimestamp) {
+		return nil
+	}
+	return &cred
+}
+
+// storeCached caches credentials on disk. Credentials without an expiry are
+// only cached in memory, as there is no telling when they become invalid.
+func (s *execCredentialSource) storeCached(data []byte) {
+	if s.opts.CacheDir == "" || s.expires == nil {
+		return
+	}
+	err := os.MkdirAll(s.opts.CacheDir, 0o700)
+	if err == nil {
+		err = os.WriteFile(s.cacheFile(), data, 0o600)
+	}
+	if err != nil {
+		slog.Debug("unable to cache exec credentials", "file", s.cacheFile(), slog.Any("error", err))
+	}
+}
+
+// execCredentialRoundTripper authenticates requests with the token of an exec
+// credential plugin.
+type execCredentialRoundTripper struct {
+	source  *execCredentialSource
+	wrapped http.RoundTripper
+}
+
+func (rt *execCredentialRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return rt.wrapped.RoundTrip(req)
+	}
+	token, err := rt.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rt.wrapped.RoundTrip(withBearerToken(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// The token may have been revoked or have expired early. Refresh it and
+	// retry the request if its body can be sent again.
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	token, err = rt.source.Refresh(token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("refreshing credentials rejected by the API server: %w", err)
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return rt.wrapped.RoundTrip(withBearerToken(retry, token))
+}
+
+func withBearerToken(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
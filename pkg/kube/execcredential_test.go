@@ -0,0 +1,193 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// TestExecCredentialPlugin is not a test: it is the exec credential plugin
+// run by the tests below. It prints the token "token-<n>" on its n-th
+// invocation, valid for $EXPIRES or an hour, fails the first $FAIL
+// invocations and sleeps for $SLEEP.
+func TestExecCredentialPlugin(_ *testing.T) {
+	counter := os.Getenv("HELM_TEST_EXEC_COUNTER")
+	if counter == "" {
+		return
+	}
+	data, _ := os.ReadFile(counter)
+	n, _ := strconv.Atoi(string(data))
+	n++
+	_ = os.WriteFile(counter, []byte(strconv.Itoa(n)), 0o644)
+
+	if d, err := time.ParseDuration(os.Getenv("SLEEP")); err == nil {
+		time.Sleep(d)
+	}
+	if fail, _ := strconv.Atoi(os.Getenv("FAIL")); n <= fail {
+		fmt.Fprintln(os.Stderr, "unable to reach the identity provider")
+		os.Exit(1)
+	}
+	validity := time.Hour
+	if d, err := time.ParseDuration(os.Getenv("EXPIRES")); err == nil {
+		validity = d
+	}
+	expires := time.Now().Add(validity).UTC().Format(time.RFC3339)
+	fmt.Printf(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":"token-%d","expirationTimestamp":%q}}`, n, expires)
+	os.Exit(0)
+}
+
+// execTestConfig returns a REST config for server running the plugin above
+// with the given environment, and the file counting its invocations.
+func execTestConfig(t *testing.T, server string, env ...string) (*rest.Config, string) {
+	t.Helper()
+	counter := filepath.Join(t.TempDir(), "counter")
+	exec := &clientcmdapi.ExecConfig{
+		APIVersion: "client.authentication.k8s.io/v1",
+		Command:    os.Args[0],
+		Args:       []string{"-test.run=^TestExecCredentialPlugin$"},
+		Env:        []clientcmdapi.ExecEnvVar{{Name: "HELM_TEST_EXEC_COUNTER", Value: counter}},
+	}
+	for _, e := range env {
+		name, value, _ := strings.Cut(e, "=")
+		exec.Env = append(exec.Env, clientcmdapi.ExecEnvVar{Name: name, Value: value})
+	}
+	return &rest.Config{Host: server, ExecProvider: exec}, counter
+}
+
+func invocations(t *testing.T, counter string) int {
+	t.Helper()
+	data, err := os.ReadFile(counter)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	n, err := strconv.Atoi(string(data))
+	require.NoError(t, err)
+	return n
+}
+
+// execTestServer accepts the tokens after the first n invocations of the plugin.
+func execTestServer(t *testing.T, rejected int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer token-")
+		if n, err := strconv.Atoi(token); err != nil || n <= rejected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, config *rest.Config) (int, error) {
+	t.Helper()
+	client, err := rest.HTTPClientFor(config)
+	require.NoError(t, err)
+	resp, err := client.Get(config.Host + "/version")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestWrapExecCredential(t *testing.T) {
+	srv := execTestServer(t, 0)
+
+	config, counter := execTestConfig(t, srv.URL)
+	assert.Same(t, config.ExecProvider, WrapExecCredential(config, ExecCredentialOptions{}).ExecProvider, "without options the config is left to client-go")
+
+	config = WrapExecCredential(config, ExecCredentialOptions{Timeout: time.Minute})
+	assert.Nil(t, config.ExecProvider)
+	for range 3 {
+		status, err := get(t, config)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	}
+	assert.Equal(t, 1, invocations(t, counter), "the token is cached in memory")
+}
+
+func TestWrapExecCredentialRetries(t *testing.T) {
+	srv := execTestServer(t, 0)
+
+	config, counter := execTestConfig(t, srv.URL, "FAIL=2")
+	config = WrapExecCredential(config, ExecCredentialOptions{Retries: 1})
+	_, err := get(t, config)
+	assert.ErrorContains(t, err, fmt.Sprintf("exec credential plugin %q failed after 2 attempts: exit status 1: unable to reach the identity provider", os.Args[0]))
+	assert.Equal(t, 2, invocations(t, counter))
+
+	status, err := get(t, config)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 3, invocations(t, counter))
+}
+
+func TestWrapExecCredentialTimeout(t *testing.T) {
+	srv := execTestServer(t, 0)
+
+	config, _ := execTestConfig(t, srv.URL, "SLEEP=10s")
+	config = WrapExecCredential(config, ExecCredentialOptions{Timeout: 100 * time.Millisecond})
+	_, err := get(t, config)
+	assert.ErrorContains(t, err, fmt.Sprintf("exec credential plugin %q failed: timed out after 100ms", os.Args[0]))
+}
+
+func TestWrapExecCredentialRefresh(t *testing.T) {
+	// The server rejects the first token, as if it had been revoked.
+	srv := execTestServer(t, 1)
+
+	config, counter := execTestConfig(t, srv.URL)
+	config = WrapExecCredential(config, ExecCredentialOptions{Retries: 1})
+	status, err := get(t, config)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 2, invocations(t, counter))
+}
+
+func TestWrapExecCredentialCache(t *testing.T) {
+	srv := execTestServer(t, 0)
+	cacheDir := t.TempDir()
+
+	config, counter := execTestConfig(t, srv.URL)
+	wrapped := WrapExecCredential(rest.CopyConfig(config), ExecCredentialOptions{CacheDir: cacheDir})
+	_, err := get(t, wrapped)
+	require.NoError(t, err)
+	files, err := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// Another process finds the credentials on disk.
+	execCredentialSources.Clear()
+	wrapped = WrapExecCredential(rest.CopyConfig(config), ExecCredentialOptions{CacheDir: cacheDir})
+	_, err = get(t, wrapped)
+	require.NoError(t, err)
+	assert.Equal(t, 1, invocations(t, counter))
+}
@@ -35,6 +35,7 @@ type FailingKubeClient struct {
 	PrintingKubeClient
 	CreateError                error
 	GetError                   error
+	StatusError                error
 	DeleteError                error
 	DeleteWithPropagationError error
 	UpdateError                error
@@ -76,6 +77,14 @@ func (f *FailingKubeClient) Get(resources kube.ResourceList, related bool) (map[
 	return f.PrintingKubeClient.Get(resources, related)
 }
 
+// Status returns the configured error if set or reports every resource as Current
+func (f *FailingKubeClient) Status(resources kube.ResourceList) ([]kube.ResourceStatus, error) {
+	if f.StatusError != nil {
+		return nil, f.StatusError
+	}
+	return f.PrintingKubeClient.Status(resources)
+}
+
 // Waits the amount of time defined on f.WaitDuration, then returns the configured error if set or prints.
 func (f *FailingKubeWaiter) Wait(resources kube.ResourceList, d time.Duration) error {
 	time.Sleep(f.waitDuration)
@@ -65,6 +65,27 @@ func (p *PrintingKubeClient) Get(resources kube.ResourceList, _ bool) (map[strin
 	return make(map[string][]runtime.Object), nil
 }
 
+// Status implements KubeClient Status.
+//
+// It reports every resource as Current.
+func (p *PrintingKubeClient) Status(resources kube.ResourceList) ([]kube.ResourceStatus, error) {
+	statuses := make([]kube.ResourceStatus, 0, len(resources))
+	for _, info := range resources {
+		rs := kube.ResourceStatus{
+			Namespace: info.Namespace,
+			Name:      info.Name,
+			Status:    "Current",
+			Message:   "Resource is current",
+		}
+		if info.Mapping != nil {
+			rs.APIVersion = info.Mapping.GroupVersionKind.GroupVersion().String()
+			rs.Kind = info.Mapping.GroupVersionKind.Kind
+		}
+		statuses = append(statuses, rs)
+	}
+	return statuses, nil
+}
+
 func (p *PrintingKubeWaiter) Wait(resources kube.ResourceList, _ time.Duration) error {
 	_, err := io.Copy(p.Out, bufferize(resources))
 	return err
@@ -0,0 +1,169 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultHeartbeatInterval is how often a Client exercises its credentials
+// and connections while waiting, when Client.HeartbeatInterval is not set.
+const DefaultHeartbeatInterval = 5 * time.Minute
+
+// heartbeat keeps the credentials and connections of a client fresh during
+// long waits, so that the requests following the wait, such as the final
+// update of the release status, do not fail with expired tokens or with
+// connections dropped by idle load balancers.
+//
+// On every beat, exec credentials expiring before the next beat are
+// refreshed, and a cheap authenticated request is sent. When that request
+// fails, the idle connections are closed so that later requests open new ones.
+type heartbeat struct {
+	interval  time.Duration
+	ping      func(context.Context) error
+	closeIdle func()
+}
+
+// start starts beating until the returned function is called. It does
+// nothing on a nil heartbeat.
+func (h *heartbeat) start() (stop func()) {
+	if h == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				h.beat()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func (h *heartbeat) beat() {
+	refreshExpiringExecCredentials(h.interval)
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+	defer cancel()
+	if err := h.ping(ctx); err != nil {
+		slog.Warn("heartbeat request to the Kubernetes API failed, closing idle connections", slog.Any("error", err))
+		h.closeIdle()
+		return
+	}
+	slog.Debug("heartbeat request to the Kubernetes API succeeded")
+}
+
+// refreshExpiringExecCredentials refreshes the credentials of the exec
+// credential plugins invoked by Helm that expire within the given duration.
+// Credentials invoked by client-go are refreshed by the heartbeat request.
+func refreshExpiringExecCredentials(within time.Duration) {
+	execCredentialSources.Range(func(_, value any) bool {
+		source := value.(*execCredentialSource)
+		if err := source.RefreshExpiring(within); err != nil {
+			slog.Warn("unable to refresh exec credentials ahead of their expiry", slog.Any("error", err))
+		}
+		return true
+	})
+}
+
+// newHeartbeat returns the heartbeat of the client, or nil if it is disabled.
+func (c *Client) newHeartbeat() *heartbeat {
+	interval := c.HeartbeatInterval
+	if interval == 0 {
+		interval = DefaultHeartbeatInterval
+	}
+	if interval < 0 {
+		return nil
+	}
+	return &heartbeat{
+		interval:  interval,
+		ping:      c.ping,
+		closeIdle: c.closeIdleConnections,
+	}
+}
+
+// ping sends a cheap authenticated request to the API server.
+func (c *Client) ping(ctx context.Context) error {
+	kc, err := c.getKubeClient()
+	if err != nil {
+		return err
+	}
+	restClient := kc.Discovery().RESTClient()
+	if restClient == nil {
+		// Fake clients have no REST client.
+		return nil
+	}
+	return restClient.Get().AbsPath("/version").Do(ctx).Error()
+}
+
+// closeIdleConnections closes the idle connections of the transport shared by
+// the clients of the REST config.
+func (c *Client) closeIdleConnections() {
+	cfg, err := c.Factory.ToRESTConfig()
+	if err != nil {
+		return
+	}
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return
+	}
+	utilnet.CloseIdleConnectionsFor(httpClient.Transport)
+}
+
+// heartbeatWaiter runs a heartbeat while waiting.
+type heartbeatWaiter struct {
+	Waiter
+	heartbeat *heartbeat
+}
+
+func (w *heartbeatWaiter) Wait(resources ResourceList, timeout time.Duration) error {
+	defer w.heartbeat.start()()
+	return w.Waiter.Wait(resources, timeout)
+}
+
+func (w *heartbeatWaiter) WaitWithJobs(resources ResourceList, timeout time.Duration) error {
+	defer w.heartbeat.start()()
+	return w.Waiter.WaitWithJobs(resources, timeout)
+}
+
+func (w *heartbeatWaiter) WaitForDelete(resources ResourceList, timeout time.Duration) error {
+	defer w.heartbeat.start()()
+	return w.Waiter.WaitForDelete(resources, timeout)
+}
+
+func (w *heartbeatWaiter) WatchUntilReady(resources ResourceList, timeout time.Duration) error {
+	defer w.heartbeat.start()()
+	return w.Waiter.WatchUntilReady(resources, timeout)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sleepingWaiter is a Waiter whose waits last for a given duration.
+type sleepingWaiter struct {
+	Waiter
+	duration time.Duration
+}
+
+func (w sleepingWaiter) Wait(_ ResourceList, _ time.Duration) error {
+	time.Sleep(w.duration)
+	return nil
+}
+
+func TestHeartbeatWaiter(t *testing.T) {
+	var pings, closes atomic.Int32
+	hb := &heartbeat{
+		interval: 10 * time.Millisecond,
+		ping: func(_ context.Context) error {
+			// Every other request fails, as if the connection had been dropped.
+			if pings.Add(1)%2 == 0 {
+				return errors.New("connection reset by peer")
+			}
+			return nil
+		},
+		closeIdle: func() { closes.Add(1) },
+	}
+	w := &heartbeatWaiter{Waiter: sleepingWaiter{duration: 100 * time.Millisecond}, heartbeat: hb}
+	require.NoError(t, w.Wait(nil, time.Minute))
+
+	n := pings.Load()
+	assert.GreaterOrEqual(t, n, int32(4))
+	assert.Equal(t, n/2, closes.Load(), "idle connections are closed after failed requests")
+
+	// The heartbeat stops with the wait.
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, n, pings.Load())
+}
+
+func TestClientHeartbeat(t *testing.T) {
+	c := newTestClient(t)
+	w, err := c.GetWaiter(StatusWatcherStrategy)
+	require.NoError(t, err)
+	hw, ok := w.(*heartbeatWaiter)
+	require.True(t, ok)
+	assert.Equal(t, DefaultHeartbeatInterval, hw.heartbeat.interval)
+
+	c.HeartbeatInterval = -1
+	w, err = c.GetWaiter(StatusWatcherStrategy)
+	require.NoError(t, err)
+	assert.IsType(t, &statusWaiter{}, w)
+}
+
+func TestHeartbeatRefreshesExpiringCredentials(t *testing.T) {
+	srv := execTestServer(t, 0)
+
+	// The plugin prints tokens valid for ten minutes.
+	config, counter := execTestConfig(t, srv.URL, "EXPIRES=10m")
+	config = WrapExecCredential(config, ExecCredentialOptions{Timeout: time.Minute})
+	status, err := get(t, config)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, invocations(t, counter))
+
+	var source *execCredentialSource
+	execCredentialSources.Range(func(_, value any) bool {
+		s := value.(*execCredentialSource)
+		if s.exec.Env[0].Value == counter {
+			source = s
+		}
+		return source == nil
+	})
+	require.NotNil(t, source)
+
+	// A beat does not refresh tokens outliving the next beat.
+	refreshExpiringExecCredentials(5 * time.Minute)
+	assert.Equal(t, 1, invocations(t, counter))
+
+	// Eight minutes into a long wait, the token expires before the next beat
+	// and is refreshed ahead of its expiry.
+	source.now = func() time.Time { return time.Now().Add(8 * time.Minute) }
+	refreshExpiringExecCredentials(5 * time.Minute)
+	assert.Equal(t, 2, invocations(t, counter))
+	token, err := source.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// ResourceStatus is the kstatus computed status of a single live resource.
+type ResourceStatus struct {
+	// APIVersion is the API version of the resource.
+	APIVersion string
+	// Kind is the kind of the resource.
+	Kind string
+	// Namespace is the namespace of the resource, empty for cluster scoped resources.
+	Namespace string
+	// Name is the name of the resource.
+	Name string
+	// Status is one of the kstatus statuses (InProgress, Failed, Current,
+	// Terminating, NotFound or Unknown).
+	Status string
+	// Message is a human readable explanation of the status.
+	Message string
+	// Created is when the resource was created. It is zero for resources that
+	// do not exist or cannot be read.
+	Created time.Time
+}
+
+// InterfaceStatus is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceStatus and integrate its method(s) into the Interface.
+type InterfaceStatus interface {
+	// Status computes the kstatus status of each of the given resources as
+	// it currently exists in the cluster.
+	Status(resources ResourceList) ([]ResourceStatus, error)
+}
+
+var _ InterfaceStatus = (*Client)(nil)
+
+// Status computes the kstatus status of each of the given resources.
+//
+// Resources that no longer exist in the cluster are reported with the
+// NotFound status, and the ones that cannot be read with the Unknown status
+// and the error as message, rather than returning an error.
+func (c *Client) Status(resources ResourceList) ([]ResourceStatus, error) {
+	var statuses []ResourceStatus
+	err := resources.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		rs := newResourceStatus(info)
+		obj, err := getResource(info)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				rs.Status = status.NotFoundStatus.String()
+				rs.Message = "Resource not found"
+			} else {
+				rs.Status = status.UnknownStatus.String()
+				rs.Message = err.Error()
+			}
+			statuses = append(statuses, rs)
+			return nil
+		}
+		if accessor, err := meta.Accessor(obj); err == nil {
+			rs.Created = accessor.GetCreationTimestamp().Time
+		}
+		result, err := ComputeStatus(obj)
+		if err != nil {
+			return err
+		}
+		rs.Status = result.Status.String()
+		rs.Message = result.Message
+		statuses = append(statuses, rs)
+		return nil
+	})
+	return statuses, err
+}
+
+// ComputeStatus computes the kstatus status of a single object.
+func ComputeStatus(obj runtime.Object) (*status.Result, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("unable to convert object to unstructured: %w", err)
+		}
+		u = &unstructured.Unstructured{Object: content}
+	}
+	return status.Compute(u)
+}
+
+func newResourceStatus(info *resource.Info) ResourceStatus {
+	rs := ResourceStatus{
+		Namespace: info.Namespace,
+		Name:      info.Name,
+	}
+	if info.Mapping != nil {
+		gvk := info.Mapping.GroupVersionKind
+		rs.APIVersion = gvk.GroupVersion().String()
+		rs.Kind = gvk.Kind
+	}
+	return rs
+}
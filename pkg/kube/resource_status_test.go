@@ -0,0 +1,111 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestComputeStatus(t *testing.T) {
+	t.Run("typed object", func(t *testing.T) {
+		cm := &v1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"},
+		}
+		result, err := ComputeStatus(cm)
+		require.NoError(t, err)
+		assert.Equal(t, status.CurrentStatus, result.Status)
+	})
+
+	t.Run("pod in progress", func(t *testing.T) {
+		pod := &v1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			Status:     v1.PodStatus{Phase: v1.PodPending},
+		}
+		result, err := ComputeStatus(pod)
+		require.NoError(t, err)
+		assert.Equal(t, status.InProgressStatus, result.Status)
+		assert.NotEmpty(t, result.Message)
+	})
+
+	t.Run("terminating unstructured object", func(t *testing.T) {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":              "cm",
+				"namespace":         "default",
+				"deletionTimestamp": "2024-01-01T00:00:00Z",
+			},
+		}}
+		result, err := ComputeStatus(u)
+		require.NoError(t, err)
+		assert.Equal(t, status.TerminatingStatus, result.Status)
+	})
+}
+
+func TestStatus(t *testing.T) {
+	pods := newPodList("starfish", "otter", "squid")
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).Client = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/namespaces/default/pods/starfish":
+				return newResponse(http.StatusOK, &pods.Items[0])
+			case "/namespaces/default/pods/otter":
+				return newResponse(http.StatusNotFound, notFoundBody())
+			default:
+				return newResponse(http.StatusForbidden, &metav1.Status{
+					Status:  metav1.StatusFailure,
+					Reason:  metav1.StatusReasonForbidden,
+					Message: `pods "squid" is forbidden`,
+					Code:    http.StatusForbidden,
+				})
+			}
+		}),
+	}
+	resources, err := c.Build(objBody(&pods), false)
+	require.NoError(t, err)
+
+	statuses, err := c.Status(resources)
+	require.NoError(t, err, "resources that cannot be read do not fail the others")
+	require.Len(t, statuses, 3)
+	assert.Equal(t, status.InProgressStatus.String(), statuses[0].Status)
+	assert.Equal(t, ResourceStatus{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  "default",
+		Name:       "otter",
+		Status:     status.NotFoundStatus.String(),
+		Message:    "Resource not found",
+	}, statuses[1])
+	assert.Equal(t, status.UnknownStatus.String(), statuses[2].Status)
+	assert.Contains(t, statuses[2].Message, `pods "squid" is forbidden`)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// The throttling limits of discovery requests, which come in bursts. They
+// match the defaults of genericclioptions.ConfigFlags.
+const (
+	discoveryBurst = 300
+	discoveryQPS   = 50.0
+)
+
+// clientConfigGetter is a RESTClientGetter for a client configuration that
+// does not come from kubeconfig files.
+type clientConfigGetter struct {
+	clientConfig clientcmd.ClientConfig
+	wrap         func(*rest.Config) *rest.Config
+
+	mu        sync.Mutex
+	discovery discovery.CachedDiscoveryInterface
+	mapper    meta.RESTMapper
+}
+
+// NewRESTClientGetter returns a RESTClientGetter for a client configuration,
+// such as one built from a kubeconfig held in memory with
+// clientcmd.NewDefaultClientConfig. If wrap is not nil, it is applied to the
+// REST config of every client, like the WrapConfigFn of
+// genericclioptions.ConfigFlags.
+//
+// Discovery information is cached in memory rather than on disk.
+func NewRESTClientGetter(clientConfig clientcmd.ClientConfig, wrap func(*rest.Config) *rest.Config) genericclioptions.RESTClientGetter {
+	return &clientConfigGetter{clientConfig: clientConfig, wrap: wrap}
+}
+
+// NewRESTClientGetterForConfig returns a RESTClientGetter for an existing
+// REST config, so that programs embedding Helm can use the config they already
+// have, such as an in-cluster config, without writing a kubeconfig file.
+// Namespaced operations default to the given namespace, or to "default".
+func NewRESTClientGetterForConfig(config *rest.Config, namespace string) genericclioptions.RESTClientGetter {
+	return NewRESTClientGetter(&restClientConfig{config: config, namespace: namespace}, nil)
+}
+
+func (g *clientConfigGetter) ToRESTConfig() (*rest.Config, error) {
+	config, err := g.clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if g.wrap != nil {
+		config = g.wrap(config)
+	}
+	return config, nil
+}
+
+func (g *clientConfigGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.toDiscoveryClient()
+}
+
+func (g *clientConfigGetter) toDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	if g.discovery != nil {
+		return g.discovery, nil
+	}
+	config, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.Burst = max(config.Burst, discoveryBurst)
+	config.QPS = max(config.QPS, discoveryQPS)
+	dc, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	g.discovery = memory.NewMemCacheClient(dc)
+	return g.discovery, nil
+}
+
+func (g *clientConfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.mapper != nil {
+		return g.mapper, nil
+	}
+	dc, err := g.toDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	g.mapper = restmapper.NewShortcutExpander(mapper, dc, nil)
+	return g.mapper, nil
+}
+
+func (g *clientConfigGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
+
+// restClientConfig is a clientcmd.ClientConfig for an existing REST config.
+type restClientConfig struct {
+	config    *rest.Config
+	namespace string
+}
+
+// RawConfig returns an empty kubeconfig, as the REST config does not come from one.
+func (c *restClientConfig) RawConfig() (clientcmdapi.Config, error) {
+	return *clientcmdapi.NewConfig(), nil
+}
+
+// ClientConfig returns a copy of the REST config, so that callers cannot
+// modify the config shared by all clients.
+func (c *restClientConfig) ClientConfig() (*rest.Config, error) {
+	return rest.CopyConfig(c.config), nil
+}
+
+func (c *restClientConfig) Namespace() (string, bool, error) {
+	if c.namespace == "" {
+		return "default", false, nil
+	}
+	return c.namespace, true, nil
+}
+
+func (c *restClientConfig) ConfigAccess() clientcmd.ConfigAccess {
+	return clientcmd.NewDefaultClientConfigLoadingRules()
+}
@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v4/pkg/kube"
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestNewRESTClientGetterForConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/version":
+			_, _ = w.Write([]byte(`{"major":"1","minor":"33","gitVersion":"v1.33.0"}`))
+		case "/api":
+			_, _ = w.Write([]byte(`{"kind":"APIVersions","versions":["v1"]}`))
+		case "/apis":
+			_, _ = w.Write([]byte(`{"kind":"APIGroupList","groups":[]}`))
+		case "/api/v1":
+			_, _ = w.Write([]byte(`{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"configmaps","singularName":"configmap","namespaced":true,"kind":"ConfigMap","verbs":["get"],"shortNames":["cm"]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	config := &rest.Config{Host: srv.URL}
+	getter := NewRESTClientGetterForConfig(config, "")
+
+	// Clients get a copy of the config.
+	restConfig, err := getter.ToRESTConfig()
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, restConfig.Host)
+	restConfig.Host = "https://example.com"
+	assert.Equal(t, srv.URL, config.Host)
+
+	ns, explicit, err := getter.ToRawKubeConfigLoader().Namespace()
+	require.NoError(t, err)
+	assert.Equal(t, "default", ns)
+	assert.False(t, explicit)
+
+	ns, explicit, err = NewRESTClientGetterForConfig(config, "apps").ToRawKubeConfigLoader().Namespace()
+	require.NoError(t, err)
+	assert.Equal(t, "apps", ns)
+	assert.True(t, explicit)
+
+	// Short names are expanded by the REST mapper.
+	mapper, err := getter.ToRESTMapper()
+	require.NoError(t, err)
+	gvr, err := mapper.ResourceFor(schema.GroupVersionResource{Resource: "cm"})
+	require.NoError(t, err)
+	assert.Equal(t, schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, gvr)
+
+	dc, err := getter.ToDiscoveryClient()
+	require.NoError(t, err)
+	dc2, err := getter.ToDiscoveryClient()
+	require.NoError(t, err)
+	assert.Same(t, dc, dc2)
+
+	// The client works with the getter.
+	client := New(getter)
+	assert.NoError(t, client.IsReachable())
+}
@@ -53,12 +53,12 @@ type legacyWaiter struct {
 
 func (hw *legacyWaiter) Wait(resources ResourceList, timeout time.Duration) error {
 	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true))
-	return hw.waitForResources(resources, timeout)
+	return hw.waitForResources(waitedFor(resources), timeout)
 }
 
 func (hw *legacyWaiter) WaitWithJobs(resources ResourceList, timeout time.Duration) error {
 	hw.c = NewReadyChecker(hw.kubeClient, PausedAsReady(true), CheckJobs(true))
-	return hw.waitForResources(resources, timeout)
+	return hw.waitForResources(waitedFor(resources), timeout)
 }
 
 // waitForResources polls to get the current status of all pods, PVCs, Services and
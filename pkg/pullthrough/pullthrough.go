@@ -0,0 +1,489 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package pullthrough provides a pull-through caching proxy for chart
+repositories and OCI registries.
+
+A Proxy fronts one upstream, an HTTP chart repository or an OCI registry, and
+keeps a copy of what it serves in a local directory. Content addressed by
+digest, OCI blobs and manifests, and chart archives and provenance files are
+cached for good. Repository indexes and OCI tags are revalidated once they
+are older than their max age. When the upstream cannot be reached, or fails,
+cached responses are served even when they are stale.
+
+	proxy, err := pullthrough.New("https://charts.example.com", "/var/cache/charts")
+	if err != nil {
+		return err
+	}
+	return http.ListenAndServe(":8080", proxy)
+*/
+package pullthrough
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/internal/fileutil"
+)
+
+// DefaultMaxAge is how long repository indexes and OCI tags are served from
+// the cache before they are revalidated, unless the upstream sets its own max
+// age.
+const DefaultMaxAge = 5 * time.Minute
+
+// CacheHeader is the response header telling whether a response was served
+// from the cache: "HIT" when it was, "MISS" when it was fetched from the
+// upstream, "STALE" when the upstream failed and a stale response was served,
+// and "BYPASS" when the response cannot be cached.
+const CacheHeader = "X-Cache"
+
+// hopHeaders are the headers of a connection, which are not forwarded nor
+// cached.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// digestPath matches the paths of OCI content addressed by digest.
+var digestPath = regexp.MustCompile(`/(blobs|manifests)/(sha256):([a-f0-9]{64})$`)
+
+// Proxy is a pull-through caching proxy for a chart repository or an OCI
+// registry.
+//
+// Proxy is read-only: it only serves GET and HEAD requests. Requests carrying
+// their own credentials are forwarded to the upstream, and their responses
+// are not cached, so that the content of a user is never served to another.
+type Proxy struct {
+	upstream *url.URL
+	dir      string
+	client   *http.Client
+	maxAge   time.Duration
+	username string
+	password string
+	now      func() time.Time
+}
+
+// Option configures a Proxy.
+type Option func(*Proxy)
+
+// WithHTTPClient sets the client the proxy fetches from the upstream with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Proxy) {
+		p.client = client
+	}
+}
+
+// WithMaxAge sets how long repository indexes and OCI tags are served from
+// the cache before they are revalidated, when the upstream does not set a max
+// age itself.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(p *Proxy) {
+		p.maxAge = maxAge
+	}
+}
+
+// WithBasicAuth sets the credentials the proxy authenticates to the upstream
+// with. Everything fetched with them is cached and served to all clients.
+func WithBasicAuth(username, password string) Option {
+	return func(p *Proxy) {
+		p.username = username
+		p.password = password
+	}
+}
+
+// New creates a proxy for the upstream URL, caching in dir.
+func New(upstream, dir string, options ...Option) (*Proxy, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %w", upstream, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid upstream %q: the scheme must be http or https", upstream)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	p := &Proxy{
+		upstream: u,
+		dir:      dir,
+		client:   http.DefaultClient,
+		maxAge:   DefaultMaxAge,
+		now:      time.Now,
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p, nil
+}
+
+// entry is the metadata of a cached response.
+type entry struct {
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header"`
+	StoredAt time.Time   `json:"storedAt"`
+	// Immutable responses never go stale.
+	Immutable bool          `json:"immutable,omitempty"`
+	MaxAge    time.Duration `json:"maxAge,omitempty"`
+}
+
+func (e *entry) fresh(now time.Time) bool {
+	return e.Immutable || now.Sub(e.StoredAt) < e.MaxAge
+}
+
+// ServeHTTP serves a request from the cache, or from the upstream when the
+// response is not cached or is stale.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "the pull-through cache is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+	target := p.upstreamURL(r.URL)
+	if r.Header.Get("Authorization") != "" {
+		p.forward(w, r, target)
+		return
+	}
+
+	key := cacheKey(target)
+	e, cached := p.load(key)
+	if cached && e.fresh(p.now()) && !hasDirective(r.Header, "no-cache") {
+		p.serveCached(w, r, key, e, "HIT")
+		return
+	}
+
+	req, err := p.newRequest(r, http.MethodGet, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if cached {
+		if etag := e.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := e.Header.Get("Last-Modified"); lm != "" {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+	resp, err := p.client.Do(req)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError {
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("upstream responded %s", resp.Status)
+		}
+		if cached {
+			slog.Debug("serving stale response", "url", target, slog.Any("error", err))
+			w.Header().Set("Warning", `110 - "Response is Stale"`)
+			p.serveCached(w, r, key, e, "STALE")
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	maxAge, storable := p.maxAgeOf(resp.Header)
+	switch {
+	case cached && resp.StatusCode == http.StatusNotModified:
+		e.StoredAt = p.now()
+		e.MaxAge = maxAge
+		if err := p.saveEntry(key, e); err != nil {
+			slog.Warn("unable to update the pull-through cache", "url", target, slog.Any("error", err))
+		}
+		p.serveCached(w, r, key, e, "HIT")
+	case resp.StatusCode == http.StatusOK && storable:
+		e = &entry{
+			URL:       target,
+			Header:    cacheableHeader(resp.Header),
+			StoredAt:  p.now(),
+			Immutable: isImmutable(target),
+			MaxAge:    maxAge,
+		}
+		if err := p.store(key, e, resp.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		p.serveCached(w, r, key, e, "MISS")
+	default:
+		p.writeResponse(w, r, resp, "BYPASS")
+	}
+}
+
+// forward proxies a request to the upstream without caching its response.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request, target string) {
+	req, err := p.newRequest(r, r.Method, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for k, v := range r.Header {
+		if !isHopHeader(k) {
+			req.Header[k] = v
+		}
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	p.writeResponse(w, r, resp, "BYPASS")
+}
+
+func (p *Proxy) newRequest(r *http.Request, method, target string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(r.Context(), method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept := r.Header.Values("Accept"); len(accept) > 0 {
+		req.Header["Accept"] = accept
+	}
+	if p.username != "" || p.password != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+	return req, nil
+}
+
+// writeResponse writes a response of the upstream to the client.
+func (p *Proxy) writeResponse(w http.ResponseWriter, r *http.Request, resp *http.Response, cacheStatus string) {
+	header := w.Header()
+	for k, v := range resp.Header {
+		if !isHopHeader(k) {
+			header[k] = v
+		}
+	}
+	header.Set(CacheHeader, cacheStatus)
+	if resp.StatusCode == http.StatusOK && isIndex(r.URL.Path) {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		body = p.rewriteIndex(r, body)
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(resp.StatusCode)
+		_, _ = w.Write(body)
+		return
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, resp.Body)
+	}
+}
+
+// serveCached serves a cached response. Ranges and conditional requests are
+// handled as for static files.
+func (p *Proxy) serveCached(w http.ResponseWriter, r *http.Request, key string, e *entry, cacheStatus string) {
+	f, err := os.Open(p.bodyFile(key))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	header := w.Header()
+	for k, v := range e.Header {
+		header[k] = v
+	}
+	header.Set(CacheHeader, cacheStatus)
+	header.Set("Age", strconv.Itoa(int(p.now().Sub(e.StoredAt).Seconds())))
+
+	var content io.ReadSeeker = f
+	if isIndex(r.URL.Path) {
+		body, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(p.rewriteIndex(r, body))
+	}
+	http.ServeContent(w, r, "", time.Time{}, content)
+}
+
+// rewriteIndex points the absolute URLs of the charts of a repository index
+// to the proxy instead of the upstream.
+func (p *Proxy) rewriteIndex(r *http.Request, body []byte) []byte {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	upstream := strings.TrimSuffix(p.upstream.String(), "/") + "/"
+	proxy := scheme + "://" + r.Host + "/"
+	return bytes.ReplaceAll(body, []byte(upstream), []byte(proxy))
+}
+
+func (p *Proxy) upstreamURL(u *url.URL) string {
+	target := *p.upstream
+	target.Path = strings.TrimSuffix(p.upstream.Path, "/") + u.Path
+	target.RawPath = ""
+	target.RawQuery = u.RawQuery
+	return target.String()
+}
+
+// maxAgeOf returns how long a response is fresh, and whether it can be
+// stored, following the Cache-Control header of the upstream.
+func (p *Proxy) maxAgeOf(header http.Header) (time.Duration, bool) {
+	if hasDirective(header, "no-store") || hasDirective(header, "private") {
+		return 0, false
+	}
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, value, ok := strings.Cut(strings.TrimSpace(d), "=")
+			if !ok || !strings.EqualFold(name, "max-age") {
+				continue
+			}
+			if secs, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second, true
+			}
+		}
+	}
+	return p.maxAge, true
+}
+
+func (p *Proxy) bodyFile(key string) string {
+	return filepath.Join(p.dir, key)
+}
+
+func (p *Proxy) entryFile(key string) string {
+	return filepath.Join(p.dir, key+".json")
+}
+
+func (p *Proxy) load(key string) (*entry, bool) {
+	data, err := os.ReadFile(p.entryFile(key))
+	if err != nil {
+		return nil, false
+	}
+	e := &entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		slog.Debug("ignoring corrupt pull-through cache entry", "file", p.entryFile(key), slog.Any("error", err))
+		return nil, false
+	}
+	if _, err := os.Stat(p.bodyFile(key)); err != nil {
+		return nil, false
+	}
+	return e, true
+}
+
+// store caches the body of a response and then its metadata. The body of
+// content addressed by digest is verified before it is cached.
+func (p *Proxy) store(key string, e *entry, body io.Reader) error {
+	tmp, err := os.CreateTemp(p.dir, key+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var h hash.Hash
+	var digest string
+	if m := digestPath.FindStringSubmatch(e.URL); m != nil {
+		h = sha256.New()
+		digest = m[3]
+		body = io.TeeReader(body, h)
+	}
+	_, err = io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to fetch %s: %w", e.URL, err)
+	}
+	if h != nil && hex.EncodeToString(h.Sum(nil)) != digest {
+		return fmt.Errorf("unable to fetch %s: the content does not match its digest", e.URL)
+	}
+	if err := os.Rename(tmp.Name(), p.bodyFile(key)); err != nil {
+		return err
+	}
+	return p.saveEntry(key, e)
+}
+
+func (p *Proxy) saveEntry(key string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(p.entryFile(key), bytes.NewReader(data), 0644)
+}
+
+func cacheKey(target string) string {
+	sum := sha256.Sum256([]byte(target))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheableHeader returns the headers of a response that are cached with it.
+func cacheableHeader(header http.Header) http.Header {
+	h := header.Clone()
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+	for _, k := range []string{"Content-Length", "Date", "Set-Cookie", "Age", "Warning", CacheHeader} {
+		h.Del(k)
+	}
+	return h
+}
+
+// isImmutable returns true for content that never changes once published:
+// OCI content addressed by digest, chart archives and provenance files.
+func isImmutable(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return digestPath.MatchString(u.Path) || strings.HasSuffix(u.Path, ".tgz") || strings.HasSuffix(u.Path, ".prov")
+}
+
+func isIndex(p string) bool {
+	return strings.HasSuffix(p, "/index.yaml") || p == "index.yaml"
+}
+
+func isHopHeader(name string) bool {
+	for _, h := range hopHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDirective(header http.Header, directive string) bool {
+	for _, v := range header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			if name, _, _ := strings.Cut(strings.TrimSpace(d), "="); strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pullthrough
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstream is a fake upstream counting the requests it serves.
+type upstream struct {
+	*httptest.Server
+	requests atomic.Int32
+	files    map[string]string
+	header   map[string]http.Header
+}
+
+func newUpstream(t *testing.T, files map[string]string) *upstream {
+	t.Helper()
+	u := &upstream{files: files, header: map[string]http.Header{}}
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.requests.Add(1)
+		body, ok := u.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		for k, v := range u.header[r.URL.Path] {
+			w.Header()[k] = v
+		}
+		if etag := w.Header().Get("ETag"); etag != "" && r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = io.WriteString(w, strings.ReplaceAll(body, "UPSTREAM", u.URL))
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func get(t *testing.T, srv *httptest.Server, path string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := srv.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestProxy(t *testing.T) {
+	up := newUpstream(t, map[string]string{
+		"/charts/index.yaml":       "entries:\n  alpine:\n  - urls:\n    - UPSTREAM/charts/alpine-0.1.0.tgz\n",
+		"/charts/alpine-0.1.0.tgz": "chart archive",
+	})
+	now := time.Now()
+	proxy, err := New(up.URL+"/charts/", t.TempDir(), WithMaxAge(time.Minute))
+	require.NoError(t, err)
+	proxy.now = func() time.Time { return now }
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	// The URLs of the index point to the proxy.
+	resp, body := get(t, srv, "/index.yaml")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "MISS", resp.Header.Get(CacheHeader))
+	assert.Contains(t, body, srv.URL+"/alpine-0.1.0.tgz")
+
+	resp, body = get(t, srv, "/alpine-0.1.0.tgz")
+	assert.Equal(t, "MISS", resp.Header.Get(CacheHeader))
+	assert.Equal(t, "chart archive", body)
+
+	resp, _ = get(t, srv, "/index.yaml")
+	assert.Equal(t, "HIT", resp.Header.Get(CacheHeader))
+	assert.Equal(t, int32(2), up.requests.Load())
+
+	// Ranges of cached responses are served.
+	resp, body = get(t, srv, "/alpine-0.1.0.tgz", "Range", "bytes=6-")
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "archive", body)
+
+	// The index is fetched again once it is stale, but charts are not.
+	now = now.Add(2 * time.Minute)
+	resp, _ = get(t, srv, "/index.yaml")
+	assert.Equal(t, "MISS", resp.Header.Get(CacheHeader))
+	resp, _ = get(t, srv, "/alpine-0.1.0.tgz")
+	assert.Equal(t, "HIT", resp.Header.Get(CacheHeader))
+	assert.Equal(t, int32(3), up.requests.Load())
+
+	// Clients can ask for cached responses to be revalidated.
+	resp, _ = get(t, srv, "/index.yaml", "Cache-Control", "no-cache")
+	assert.Equal(t, "MISS", resp.Header.Get(CacheHeader))
+
+	// Stale responses are served while the upstream is down.
+	up.Close()
+	now = now.Add(2 * time.Minute)
+	resp, body = get(t, srv, "/index.yaml")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "STALE", resp.Header.Get(CacheHeader))
+	assert.Equal(t, `110 - "Response is Stale"`, resp.Header.Get("Warning"))
+	assert.Contains(t, body, srv.URL+"/alpine-0.1.0.tgz")
+
+	resp, _ = get(t, srv, "/missing.tgz")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestProxyCacheControl(t *testing.T) {
+	up := newUpstream(t, map[string]string{
+		"/index.yaml":  "entries: {}\n",
+		"/no-store":    "secret",
+		"/revalidated": "content",
+	})
+	up.header["/index.yaml"] = http.Header{"Cache-Control": {"public, max-age=600"}}
+	up.header["/no-store"] = http.Header{"Cache-Control": {"no-store"}}
+	up.header["/revalidated"] = http.Header{"Etag": {`"v1"`}, "Cache-Control": {"max-age=0"}}
+
+	proxy, err := New(up.URL, t.TempDir())
+	require.NoError(t, err)
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	get(t, srv, "/index.yaml")
+	resp, _ := get(t, srv, "/index.yaml")
+	assert.Equal(t, "HIT", resp.Header.Get(CacheHeader))
+
+	get(t, srv, "/no-store")
+	resp, body := get(t, srv, "/no-store")
+	assert.Equal(t, "BYPASS", resp.Header.Get(CacheHeader))
+	assert.Equal(t, "secret", body)
+
+	get(t, srv, "/revalidated")
+	resp, body = get(t, srv, "/revalidated")
+	assert.Equal(t, "HIT", resp.Header.Get(CacheHeader))
+	assert.Equal(t, "content", body)
+	assert.Equal(t, int32(5), up.requests.Load())
+}
+
+func TestProxyOCI(t *testing.T) {
+	blob := "layer content"
+	sum := sha256.Sum256([]byte(blob))
+	digest := hex.EncodeToString(sum[:])
+	up := newUpstream(t, map[string]string{
+		"/v2/charts/alpine/blobs/sha256:" + digest:                  blob,
+		"/v2/charts/alpine/blobs/sha256:" + strings.Repeat("0", 64): "corrupt",
+		"/v2/charts/alpine/manifests/0.1.0":                         "{}",
+	})
+
+	proxy, err := New(up.URL, t.TempDir())
+	require.NoError(t, err)
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	resp, body := get(t, srv, "/v2/charts/alpine/blobs/sha256:"+digest)
+	assert.Equal(t, "MISS", resp.Header.Get(CacheHeader))
+	assert.Equal(t, blob, body)
+
+	// Blobs are verified against their digest before they are cached.
+	resp, _ = get(t, srv, "/v2/charts/alpine/blobs/sha256:"+strings.Repeat("0", 64))
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	// Requests with their own credentials are not cached.
+	get(t, srv, "/v2/charts/alpine/manifests/0.1.0", "Authorization", "Bearer token")
+	resp, _ = get(t, srv, "/v2/charts/alpine/manifests/0.1.0", "Authorization", "Bearer token")
+	assert.Equal(t, "BYPASS", resp.Header.Get(CacheHeader))
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/v2/charts/alpine/manifests/0.1.0", nil)
+	require.NoError(t, err)
+	resp, err = srv.Client().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("oci://example.com", t.TempDir())
+	assert.EqualError(t, err, `invalid upstream "oci://example.com": the scheme must be http or https`)
+}
@@ -92,6 +92,11 @@ func (pusher *OCIPusher) push(chartRef, href string) error {
 	// The time the chart was "created" is semantically the time the chart archive file was last written(modified)
 	chartArchiveFileCreatedTime := stat.ModTime()
 	pushOpts = append(pushOpts, registry.PushOptCreationTime(chartArchiveFileCreatedTime.Format(time.RFC3339)))
+	pushOpts = append(pushOpts, registry.PushOptSeparateLayers(pusher.opts.separateLayers))
+	pushOpts = append(pushOpts, registry.PushOptProvReferrer(pusher.opts.provReferrer))
+	pushOpts = append(pushOpts, registry.PushOptAttachments(pusher.opts.attachments...))
+	pushOpts = append(pushOpts, registry.PushOptChunkSize(pusher.opts.chunkSize))
+	pushOpts = append(pushOpts, registry.PushOptProgress(pusher.opts.progress))
 
 	_, err = client.Push(chartBytes, ref, pushOpts...)
 	return err
@@ -34,6 +34,11 @@ type options struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	separateLayers        bool
+	provReferrer          bool
+	attachments           []registry.Attachment
+	chunkSize             int64
+	progress              func(registry.PushProgress)
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -69,6 +74,46 @@ func WithPlainHTTP(plainHTTP bool) Option {
 	}
 }
 
+// WithSeparateLayers pushes the values, schema and README of a chart to OCI
+// registries as separate layers as well.
+func WithSeparateLayers(separateLayers bool) Option {
+	return func(opts *options) {
+		opts.separateLayers = separateLayers
+	}
+}
+
+// WithProvReferrer attaches the provenance file of a chart to it as an OCI
+// referrer, instead of pushing it as a layer of the chart.
+func WithProvReferrer(provReferrer bool) Option {
+	return func(opts *options) {
+		opts.provReferrer = provReferrer
+	}
+}
+
+// WithAttachments attaches artifacts, such as SBOMs or signatures, to a chart
+// pushed to an OCI registry, as referrers of its manifest.
+func WithAttachments(attachments ...registry.Attachment) Option {
+	return func(opts *options) {
+		opts.attachments = append(opts.attachments, attachments...)
+	}
+}
+
+// WithChunkSize uploads the layers of a chart larger than chunkSize bytes to
+// OCI registries in chunks, resumed when failing.
+func WithChunkSize(chunkSize int64) Option {
+	return func(opts *options) {
+		opts.chunkSize = chunkSize
+	}
+}
+
+// WithProgress reports the progress of the uploads of a chart to OCI
+// registries.
+func WithProgress(progress func(registry.PushProgress)) Option {
+	return func(opts *options) {
+		opts.progress = progress
+	}
+}
+
 // Pusher is an interface to support upload to the specified URL.
 type Pusher interface {
 	// Push file content by url string
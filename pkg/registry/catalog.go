@@ -0,0 +1,192 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// errNotChart is returned for the artifacts other than charts, such as
+// container images, stored in the same registries.
+var errNotChart = errors.New("not a chart")
+
+type (
+	// ListChartsOption allows specifying various settings on listing charts
+	ListChartsOption func(*listChartsOperation)
+
+	// ChartListing is a version of a chart found in a registry.
+	ChartListing struct {
+		// Repository is the repository of the chart, such as
+		// "ghcr.io/org/charts/nginx".
+		Repository string `json:"repository"`
+		// Ref is the reference of the version, such as
+		// "ghcr.io/org/charts/nginx:1.2.3".
+		Ref string `json:"ref"`
+		// Digest is the digest of the manifest of the version.
+		Digest   string          `json:"digest"`
+		Metadata *chart.Metadata `json:"metadata"`
+	}
+
+	listChartsOperation struct {
+		allVersions bool
+		constraint  *semver.Constraints
+	}
+)
+
+// ListChartsOptAllVersions returns a function that lists all the versions of
+// the charts, instead of their latest version only.
+func ListChartsOptAllVersions(allVersions bool) ListChartsOption {
+	return func(operation *listChartsOperation) {
+		operation.allVersions = allVersions
+	}
+}
+
+// ListChartsOptVersion returns a function that only lists the versions of the
+// charts matching a semantic version constraint, such as ">=1.0.0".
+func ListChartsOptVersion(constraint *semver.Constraints) ListChartsOption {
+	return func(operation *listChartsOperation) {
+		operation.constraint = constraint
+	}
+}
+
+// Repositories lists the repositories under a namespace of a registry, such
+// as "ghcr.io/org/charts", or all the repositories of the registry when the
+// namespace is only its host. The registry must support the catalog API,
+// which some public registries only offer to their administrators.
+func (c *Client) Repositories(namespace string) ([]string, error) {
+	host, prefix, _ := strings.Cut(strings.Trim(strings.TrimPrefix(namespace, fmt.Sprintf("%s://", OCIScheme)), "/"), "/")
+	reg, err := remote.NewRegistry(host)
+	if err != nil {
+		return nil, err
+	}
+	reg.PlainHTTP = c.plainHTTP
+	reg.Client = c.registryClient()
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	var repositories []string
+	err = reg.Repositories(context.Background(), "", func(names []string) error {
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) {
+				repositories = append(repositories, host+"/"+name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the repositories of %s, the registry may not support the catalog API: %w", host, err)
+	}
+	return repositories, nil
+}
+
+// ListCharts lists the charts of the repositories under a namespace of a
+// registry, with the metadata of their latest version, or of all their
+// versions. Only the manifests and the configs of the versions are fetched.
+// Repositories holding other artifacts, or no semantic version tags, are
+// skipped.
+func (c *Client) ListCharts(namespace string, options ...ListChartsOption) ([]*ChartListing, error) {
+	operation := &listChartsOperation{}
+	for _, option := range options {
+		option(operation)
+	}
+
+	repositories, err := c.Repositories(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var listings []*ChartListing
+	for _, repository := range repositories {
+		tags, err := c.Tags(repository)
+		if err != nil {
+			slog.Warn("unable to list the tags of repository", "repository", repository, slog.Any("error", err))
+			continue
+		}
+		// The tags are sorted from the latest version.
+		for _, tag := range tags {
+			if operation.constraint != nil {
+				v, err := semver.NewVersion(tag)
+				if err != nil || !operation.constraint.Check(v) {
+					continue
+				}
+			}
+			listing, err := c.chartListing(repository, tag)
+			if errors.Is(err, errNotChart) {
+				break
+			}
+			if err != nil {
+				slog.Warn("unable to read the metadata of chart", "repository", repository, "version", tag, slog.Any("error", err))
+				continue
+			}
+			listings = append(listings, listing)
+			if !operation.allVersions {
+				break
+			}
+		}
+	}
+	return listings, nil
+}
+
+// chartListing reads the metadata of a version of a chart from its config,
+// without printing anything like the pulls do.
+func (c *Client) chartListing(repository, version string) (*ChartListing, error) {
+	// Change plus (+) to underscore (_) for the tags
+	// See https://github.com/helm/helm/issues/10166
+	ref := fmt.Sprintf("%s:%s", repository, strings.ReplaceAll(version, "+", "_"))
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	repo.PlainHTTP = c.plainHTTP
+	repo.Client = c.remoteClient()
+
+	ctx := context.Background()
+	desc, manifestData, err := oras.FetchBytes(ctx, repo, ref, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest: %w", err)
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return nil, errNotChart
+	}
+	config, err := content.FetchAll(ctx, repo, manifest.Config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve blob with digest %s: %w", manifest.Config.Digest, err)
+	}
+	listing := &ChartListing{Repository: repository, Ref: ref, Digest: desc.Digest.String()}
+	if err := json.Unmarshal(config, &listing.Metadata); err != nil {
+		return nil, err
+	}
+	return listing, nil
+}
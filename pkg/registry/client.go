@@ -29,11 +29,14 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/opencontainers/image-spec/specs-go"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
 	"oras.land/oras-go/v2/content/memory"
 	"oras.land/oras-go/v2/registry"
 	"oras.land/oras-go/v2/registry/remote"
@@ -75,7 +78,15 @@ type (
 		credentialsStore   credentials.Store
 		httpClient         *http.Client
 		plainHTTP          bool
+		tagListPageSize    int
+		registriesFile     string
+		mirrors            map[string][]*registryMirror
 		err                error // pass any errors from the ClientOption functions
+
+		// usageMu guards the registry usage file and usedRegistries, the
+		// registries whose usage this client already recorded
+		usageMu        sync.Mutex
+		usedRegistries map[string]bool
 	}
 
 	// ClientOption allows specifying various settings configurable by the user for overriding the defaults
@@ -103,21 +114,23 @@ func NewClient(options ...ClientOption) (*Client, error) {
 		}
 	}
 
-	storeOptions := credentials.StoreOptions{
-		AllowPlaintextPut:        true,
-		DetectDefaultNativeStore: true,
-	}
-	store, err := credentials.NewStore(client.credentialsFile, storeOptions)
-	if err != nil {
-		return nil, err
-	}
-	dockerStore, err := credentials.NewStoreFromDocker(storeOptions)
-	if err != nil {
-		// should only fail if user home directory can't be determined
-		client.credentialsStore = store
-	} else {
-		// use Helm credentials with fallback to Docker
-		client.credentialsStore = credentials.NewStoreWithFallbacks(store, dockerStore)
+	if client.credentialsStore == nil {
+		storeOptions := credentials.StoreOptions{
+			AllowPlaintextPut:        true,
+			DetectDefaultNativeStore: true,
+		}
+		store, err := credentials.NewStore(client.credentialsFile, storeOptions)
+		if err != nil {
+			return nil, err
+		}
+		dockerStore, err := credentials.NewStoreFromDocker(storeOptions)
+		if err != nil {
+			// should only fail if user home directory can't be determined
+			client.credentialsStore = store
+		} else {
+			// use Helm credentials with fallback to Docker
+			client.credentialsStore = credentials.NewStoreWithFallbacks(store, dockerStore)
+		}
 	}
 
 	if client.authorizer == nil {
@@ -131,15 +144,19 @@ func NewClient(options ...ClientOption) (*Client, error) {
 				return auth.Credential{Username: client.username, Password: client.password}, nil
 			}
 		} else {
-			authorizer.Credential = credentials.Credential(client.credentialsStore)
+			authorizer.Credential = client.recordingCredential(credentials.Credential(client.credentialsStore))
 		}
 
 		if client.enableCache {
-			authorizer.Cache = auth.NewCache()
+			authorizer.Cache = newTokenCache()
 		}
 		client.authorizer = &authorizer
 	}
 
+	if err := client.loadMirrors(); err != nil {
+		return nil, err
+	}
+
 	return client, nil
 }
 
@@ -211,6 +228,15 @@ func ClientOptHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// ClientOptTagListPageSize returns a function that sets the number of tags
+// requested per page when listing the tags of a repository. The registry
+// decides when it is not set.
+func ClientOptTagListPageSize(size int) ClientOption {
+	return func(client *Client) {
+		client.tagListPageSize = size
+	}
+}
+
 func ClientOptPlainHTTP() ClientOption {
 	return func(c *Client) {
 		c.plainHTTP = true
@@ -257,6 +283,8 @@ func (c *Client) Login(host string, options ...LoginOption) error {
 	if err := c.credentialsStore.Put(ctx, key, cred); err != nil {
 		return err
 	}
+	now := time.Now()
+	c.updateUsage(key, &now)
 
 	fmt.Fprintln(c.out, "Login Succeeded")
 	return nil
@@ -386,6 +414,7 @@ func (c *Client) Logout(host string, opts ...LogoutOption) error {
 	if err := credentials.Logout(context.Background(), c.credentialsStore, host); err != nil {
 		return err
 	}
+	c.updateUsage(credentials.ServerAddressFromRegistry(host), nil)
 	fmt.Fprintf(c.out, "Removing login credentials for %s\n", host)
 	return nil
 }
@@ -400,7 +429,12 @@ type (
 		Config   *DescriptorPullSummary         `json:"config"`
 		Chart    *DescriptorPullSummaryWithMeta `json:"chart"`
 		Prov     *DescriptorPullSummary         `json:"prov"`
-		Ref      string                         `json:"ref"`
+		// Values, Schema and Readme are the separate layers of the chart. They
+		// are nil when not pulled or when the chart was pushed without them.
+		Values *DescriptorPullSummary `json:"values,omitempty"`
+		Schema *DescriptorPullSummary `json:"schema,omitempty"`
+		Readme *DescriptorPullSummary `json:"readme,omitempty"`
+		Ref    string                 `json:"ref"`
 	}
 
 	DescriptorPullSummary struct {
@@ -418,6 +452,9 @@ type (
 		withChart         bool
 		withProv          bool
 		ignoreMissingProv bool
+		withValues        bool
+		withSchema        bool
+		withReadme        bool
 	}
 )
 
@@ -426,13 +463,11 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 	var err error
 
 	// Chart-specific validation
+	// The provenance file is not counted, it may be attached as a referrer.
 	minNumDescriptors := 1 // 1 for the config
 	if operation.withChart {
 		minNumDescriptors++
 	}
-	if operation.withProv && !operation.ignoreMissingProv {
-		minNumDescriptors++
-	}
 
 	numDescriptors := len(genericResult.Descriptors)
 	if numDescriptors < minNumDescriptors {
@@ -444,6 +479,9 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 	var configDescriptor *ocispec.Descriptor
 	var chartDescriptor *ocispec.Descriptor
 	var provDescriptor *ocispec.Descriptor
+	var valuesDescriptor *ocispec.Descriptor
+	var schemaDescriptor *ocispec.Descriptor
+	var readmeDescriptor *ocispec.Descriptor
 
 	for _, descriptor := range genericResult.Descriptors {
 		d := descriptor
@@ -454,6 +492,12 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 			chartDescriptor = &d
 		case ProvLayerMediaType:
 			provDescriptor = &d
+		case ValuesLayerMediaType:
+			valuesDescriptor = &d
+		case SchemaLayerMediaType:
+			schemaDescriptor = &d
+		case ReadmeLayerMediaType:
+			readmeDescriptor = &d
 		case LegacyChartLayerMediaType:
 			chartDescriptor = &d
 			fmt.Fprintf(c.out, "Warning: chart media type %s is deprecated\n", LegacyChartLayerMediaType)
@@ -469,12 +513,20 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 			ChartLayerMediaType)
 	}
 
-	var provMissing bool
+	var provReferrer *DescriptorPullSummary
 	if operation.withProv && provDescriptor == nil {
+		provReferrer, err = c.pullProvReferrer(genericResult.Ref, genericResult.Manifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var provMissing bool
+	if operation.withProv && provDescriptor == nil && provReferrer == nil {
 		if operation.ignoreMissingProv {
 			provMissing = true
 		} else {
-			return nil, fmt.Errorf("manifest does not contain a layer with mediatype %s",
+			return nil, fmt.Errorf("manifest does not contain a layer with mediatype %s, and no referrer of that artifact type is attached",
 				ProvLayerMediaType)
 		}
 	}
@@ -520,7 +572,9 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 		result.Chart.Size = chartDescriptor.Size
 	}
 
-	if operation.withProv && !provMissing {
+	if provReferrer != nil {
+		result.Prov = provReferrer
+	} else if operation.withProv && !provMissing {
 		result.Prov.Data, err = genericClient.GetDescriptorData(genericResult.MemoryStore, *provDescriptor)
 		if err != nil {
 			return nil, fmt.Errorf("unable to retrieve blob with digest %s: %w", provDescriptor.Digest, err)
@@ -529,6 +583,31 @@ func (c *Client) processChartPull(genericResult *GenericPullResult, operation *p
 		result.Prov.Size = provDescriptor.Size
 	}
 
+	// The separate layers are optional, charts pushed without them only have
+	// the chart layer.
+	for _, layer := range []struct {
+		wanted     bool
+		descriptor *ocispec.Descriptor
+		summary    **DescriptorPullSummary
+	}{
+		{operation.withValues, valuesDescriptor, &result.Values},
+		{operation.withSchema, schemaDescriptor, &result.Schema},
+		{operation.withReadme, readmeDescriptor, &result.Readme},
+	} {
+		if !layer.wanted || layer.descriptor == nil {
+			continue
+		}
+		data, err := genericClient.GetDescriptorData(genericResult.MemoryStore, *layer.descriptor)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve blob with digest %s: %w", layer.descriptor.Digest, err)
+		}
+		*layer.summary = &DescriptorPullSummary{
+			Data:   data,
+			Digest: layer.descriptor.Digest.String(),
+			Size:   layer.descriptor.Size,
+		}
+	}
+
 	fmt.Fprintf(c.out, "Pulled: %s\n", result.Ref)
 	fmt.Fprintf(c.out, "Digest: %s\n", result.Manifest.Digest)
 
@@ -548,9 +627,9 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 	for _, option := range options {
 		option(operation)
 	}
-	if !operation.withChart && !operation.withProv {
+	if !operation.withChart && !operation.withProv && !operation.withValues && !operation.withSchema && !operation.withReadme {
 		return nil, errors.New(
-			"must specify at least one layer to pull (chart/prov)")
+			"must specify at least one layer to pull (chart/prov/values/schema/readme)")
 	}
 
 	// Build allowed media types for chart pull
@@ -564,6 +643,15 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 	if operation.withProv {
 		allowedMediaTypes = append(allowedMediaTypes, ProvLayerMediaType)
 	}
+	if operation.withValues {
+		allowedMediaTypes = append(allowedMediaTypes, ValuesLayerMediaType)
+	}
+	if operation.withSchema {
+		allowedMediaTypes = append(allowedMediaTypes, SchemaLayerMediaType)
+	}
+	if operation.withReadme {
+		allowedMediaTypes = append(allowedMediaTypes, ReadmeLayerMediaType)
+	}
 
 	// Use generic client for the pull operation
 	genericClient := c.Generic()
@@ -578,6 +666,94 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 	return c.processChartPull(genericResult, operation)
 }
 
+// PullMetadata downloads only the manifest and the config of a chart. The
+// config holds the metadata of the chart, so it can be read without pulling
+// any layer.
+func (c *Client) PullMetadata(ref string) (*PullResult, error) {
+	genericResult, err := c.Generic().PullGeneric(ref, GenericPullOptions{
+		AllowedMediaTypes: []string{ocispec.MediaTypeImageManifest, ConfigMediaType},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.processChartPull(genericResult, &pullOperation{})
+}
+
+// PullStream streams the chart layer of a chart from a registry, instead of
+// keeping the whole chart in memory like Pull. It returns the descriptor of
+// the layer and a reader of its content, which fails at its end if the
+// content does not match the digest of the layer. The reader must be closed.
+func (c *Client) PullStream(ref string) (ocispec.Descriptor, io.ReadCloser, error) {
+	parsedRef, err := newReference(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	repository, err := remote.NewRepository(parsedRef.String())
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	repository.PlainHTTP = c.plainHTTP
+	repository.Client = c.remoteClient()
+
+	ctx := context.Background()
+	manifestDescriptor, manifestData, err := oras.FetchBytes(ctx, repository, parsedRef.String(), oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return ocispec.Descriptor{}, nil, fmt.Errorf("unable to parse manifest: %w", err)
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return ocispec.Descriptor{}, nil, fmt.Errorf("could not load config with mediatype %s", ConfigMediaType)
+	}
+
+	var chartDescriptor *ocispec.Descriptor
+	for i, layer := range manifest.Layers {
+		switch layer.MediaType {
+		case ChartLayerMediaType:
+			chartDescriptor = &manifest.Layers[i]
+		case LegacyChartLayerMediaType:
+			chartDescriptor = &manifest.Layers[i]
+			fmt.Fprintf(c.out, "Warning: chart media type %s is deprecated\n", LegacyChartLayerMediaType)
+		}
+	}
+	if chartDescriptor == nil {
+		return ocispec.Descriptor{}, nil, fmt.Errorf("manifest does not contain a layer with mediatype %s",
+			ChartLayerMediaType)
+	}
+
+	rc, err := repository.Fetch(ctx, *chartDescriptor)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, fmt.Errorf("unable to retrieve blob with digest %s: %w", chartDescriptor.Digest, err)
+	}
+
+	fmt.Fprintf(c.out, "Pulled: %s\n", parsedRef.String())
+	fmt.Fprintf(c.out, "Digest: %s\n", manifestDescriptor.Digest)
+	if strings.Contains(parsedRef.String(), "_") {
+		fmt.Fprintf(c.out, "%s contains an underscore.\n", parsedRef.String())
+		fmt.Fprint(c.out, registryUnderscoreMessage+"\n")
+	}
+
+	return *chartDescriptor, &verifyReadCloser{VerifyReader: content.NewVerifyReader(rc, *chartDescriptor), Closer: rc}, nil
+}
+
+// verifyReadCloser verifies the content it reads once all of it is read.
+type verifyReadCloser struct {
+	*content.VerifyReader
+	io.Closer
+}
+
+func (r *verifyReadCloser) Read(p []byte) (int, error) {
+	n, err := r.VerifyReader.Read(p)
+	if err == io.EOF {
+		if verr := r.Verify(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
 // PullOptWithChart returns a function that sets the withChart setting on pull
 func PullOptWithChart(withChart bool) PullOption {
 	return func(operation *pullOperation) {
@@ -599,6 +775,27 @@ func PullOptIgnoreMissingProv(ignoreMissingProv bool) PullOption {
 	}
 }
 
+// PullOptWithValues returns a function that sets the withValues setting on pull
+func PullOptWithValues(withValues bool) PullOption {
+	return func(operation *pullOperation) {
+		operation.withValues = withValues
+	}
+}
+
+// PullOptWithSchema returns a function that sets the withSchema setting on pull
+func PullOptWithSchema(withSchema bool) PullOption {
+	return func(operation *pullOperation) {
+		operation.withSchema = withSchema
+	}
+}
+
+// PullOptWithReadme returns a function that sets the withReadme setting on pull
+func PullOptWithReadme(withReadme bool) PullOption {
+	return func(operation *pullOperation) {
+		operation.withReadme = withReadme
+	}
+}
+
 type (
 	// PushOption allows specifying various settings on push
 	PushOption func(*pushOperation)
@@ -609,7 +806,9 @@ type (
 		Config   *descriptorPushSummary         `json:"config"`
 		Chart    *descriptorPushSummaryWithMeta `json:"chart"`
 		Prov     *descriptorPushSummary         `json:"prov"`
-		Ref      string                         `json:"ref"`
+		// Referrers are the artifacts attached to the chart.
+		Referrers []*Referrer `json:"referrers,omitempty"`
+		Ref       string      `json:"ref"`
 	}
 
 	descriptorPushSummary struct {
@@ -623,9 +822,14 @@ type (
 	}
 
 	pushOperation struct {
-		provData     []byte
-		strictMode   bool
-		creationTime string
+		provData       []byte
+		strictMode     bool
+		creationTime   string
+		separateLayers bool
+		provReferrer   bool
+		attachments    []Attachment
+		chunkSize      int64
+		progress       func(PushProgress)
 	}
 )
 
@@ -673,7 +877,15 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 
 	layers := []ocispec.Descriptor{chartDescriptor}
 	var provDescriptor ocispec.Descriptor
-	if operation.provData != nil {
+	attachments := operation.attachments
+	if operation.provData != nil && operation.provReferrer {
+		provDescriptor = content.NewDescriptorFromBytes(ProvLayerMediaType, operation.provData)
+		attachments = append([]Attachment{{
+			ArtifactType: ProvLayerMediaType,
+			Name:         fmt.Sprintf("%s-%s.tgz.prov", meta.Name, meta.Version),
+			Data:         operation.provData,
+		}}, attachments...)
+	} else if operation.provData != nil {
 		provDescriptor, err = oras.PushBytes(ctx, memoryStore, ProvLayerMediaType, operation.provData)
 		if err != nil {
 			return nil, err
@@ -682,6 +894,14 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 		layers = append(layers, provDescriptor)
 	}
 
+	if operation.separateLayers {
+		separateLayers, err := pushSeparateLayers(ctx, memoryStore, data)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, separateLayers...)
+	}
+
 	// sort layers for determinism, similar to how ORAS v1 does it
 	sort.Slice(layers, func(i, j int) bool {
 		return layers[i].Digest < layers[j].Digest
@@ -700,9 +920,41 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 		return nil, err
 	}
 	repository.PlainHTTP = c.plainHTTP
-	repository.Client = c.authorizer
+	repository.Client = c.registryClient()
 
-	manifestDescriptor, err = oras.ExtendedCopy(ctx, memoryStore, parsedRef.String(), repository, parsedRef.String(), oras.DefaultExtendedCopyOptions)
+	if operation.chunkSize > 0 {
+		for _, layer := range layers {
+			if layer.Size <= operation.chunkSize {
+				continue
+			}
+			layerData, err := content.FetchAll(ctx, memoryStore, layer)
+			if err != nil {
+				return nil, err
+			}
+			if err := c.pushBlobChunked(ctx, repository, layer, layerData, operation.chunkSize, operation.progress); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	copyOptions := oras.DefaultExtendedCopyOptions
+	if operation.progress != nil {
+		copyOptions.PostCopy = func(_ context.Context, desc ocispec.Descriptor) error {
+			operation.progress(PushProgress{
+				Digest:    desc.Digest.String(),
+				MediaType: desc.MediaType,
+				Uploaded:  desc.Size,
+				Size:      desc.Size,
+			})
+			return nil
+		}
+	}
+	manifestDescriptor, err = oras.ExtendedCopy(ctx, memoryStore, parsedRef.String(), repository, parsedRef.String(), copyOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers, err := c.attach(ctx, repository, manifestDescriptor, operation.creationTime, attachments)
 	if err != nil {
 		return nil, err
 	}
@@ -721,9 +973,10 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 			Digest: configDescriptor.Digest.String(),
 			Size:   configDescriptor.Size,
 		},
-		Chart: chartSummary,
-		Prov:  &descriptorPushSummary{}, // prevent nil references
-		Ref:   parsedRef.String(),
+		Chart:     chartSummary,
+		Prov:      &descriptorPushSummary{}, // prevent nil references
+		Referrers: referrers,
+		Ref:       parsedRef.String(),
 	}
 	if operation.provData != nil {
 		result.Prov = &descriptorPushSummary{
@@ -748,6 +1001,51 @@ func PushOptProvData(provData []byte) PushOption {
 	}
 }
 
+// PushOptProvReferrer returns a function that sets the provReferrer setting
+// on push. The provenance file is then attached to the chart as an OCI
+// referrer, instead of being pushed as a layer of the chart.
+func PushOptProvReferrer(provReferrer bool) PushOption {
+	return func(operation *pushOperation) {
+		operation.provReferrer = provReferrer
+	}
+}
+
+// PushOptAttachments returns a function that attaches artifacts, such as
+// SBOMs or signatures, to the chart as OCI referrers on push.
+func PushOptAttachments(attachments ...Attachment) PushOption {
+	return func(operation *pushOperation) {
+		operation.attachments = append(operation.attachments, attachments...)
+	}
+}
+
+// PushOptChunkSize returns a function that sets the chunkSize setting on
+// push. The layers larger than chunkSize bytes are then uploaded in chunks,
+// resumed from the last byte received by the registry when failing, instead
+// of in a single request.
+func PushOptChunkSize(chunkSize int64) PushOption {
+	return func(operation *pushOperation) {
+		operation.chunkSize = chunkSize
+	}
+}
+
+// PushOptProgress returns a function that sets the progress callback on push,
+// called with the number of bytes uploaded after each chunk of a layer, and
+// after the upload of each blob and manifest.
+func PushOptProgress(progress func(PushProgress)) PushOption {
+	return func(operation *pushOperation) {
+		operation.progress = progress
+	}
+}
+
+// PushOptSeparateLayers returns a function that sets the separateLayers setting
+// on push. The values.yaml, values.schema.json and README of the chart are then
+// pushed as separate layers as well, so they can be pulled without the chart.
+func PushOptSeparateLayers(separateLayers bool) PushOption {
+	return func(operation *pushOperation) {
+		operation.separateLayers = separateLayers
+	}
+}
+
 // PushOptStrictMode returns a function that sets the strictMode setting on push
 func PushOptStrictMode(strictMode bool) PushOption {
 	return func(operation *pushOperation) {
@@ -775,8 +1073,11 @@ func (c *Client) Tags(ref string) ([]string, error) {
 		return nil, err
 	}
 	repository.PlainHTTP = c.plainHTTP
-	repository.Client = c.authorizer
+	repository.Client = c.remoteClient()
+	repository.TagListPageSize = c.tagListPageSize
 
+	// The callback is called once per page of the tag list, following the
+	// links to the next pages.
 	var tagVersions []*semver.Version
 	err = repository.Tags(ctx, "", func(tags []string) error {
 		for _, tag := range tags {
@@ -814,7 +1115,7 @@ func (c *Client) Resolve(ref string) (desc ocispec.Descriptor, err error) {
 		return desc, err
 	}
 	remoteRepository.PlainHTTP = c.plainHTTP
-	remoteRepository.Client = c.authorizer
+	remoteRepository.Client = c.remoteClient()
 
 	parsedReference, err := newReference(ref)
 	if err != nil {
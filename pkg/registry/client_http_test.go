@@ -64,7 +64,11 @@ func (suite *HTTPRegistryClientTestSuite) Test_3_Tags() {
 	testTags(&suite.TestSuite)
 }
 
-func (suite *HTTPRegistryClientTestSuite) Test_4_ManInTheMiddle() {
+func (suite *HTTPRegistryClientTestSuite) Test_4_ListCharts() {
+	testListCharts(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_5_ManInTheMiddle() {
 	ref := fmt.Sprintf("%s/testrepo/supposedlysafechart:9.9.9", suite.CompromisedRegistryHost)
 
 	// returns content that does not match the expected digest
@@ -73,6 +77,18 @@ func (suite *HTTPRegistryClientTestSuite) Test_4_ManInTheMiddle() {
 	suite.True(errors.Is(err, content.ErrMismatchedDigest))
 }
 
+func (suite *HTTPRegistryClientTestSuite) Test_6_Referrers() {
+	testReferrers(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_7_ChunkedPush() {
+	testChunkedPush(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_8_Mirrors() {
+	testMirrors(&suite.TestSuite)
+}
+
 func TestHTTPRegistryClientTestSuite(t *testing.T) {
 	suite.Run(t, new(HTTPRegistryClientTestSuite))
 }
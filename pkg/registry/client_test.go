@@ -17,10 +17,14 @@ limitations under the License.
 package registry
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -120,3 +124,41 @@ func TestLogin_ResetsForceAttemptOAuth2_OnFailure(t *testing.T) {
 		t.Errorf("ForceAttemptOAuth2 should be false after failed Login")
 	}
 }
+
+func TestTags_Pagination(t *testing.T) {
+	t.Parallel()
+
+	all := []string{"0.1.0", "0.2.0", "latest", "1.0.0_build.1", "0.3.0-rc.1"}
+	pages := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/charts/alpine/tags/list" {
+			http.NotFound(w, r)
+			return
+		}
+		pages++
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			start = slices.Index(all, last) + 1
+		}
+		end := min(start+n, len(all))
+		if end < len(all) {
+			w.Header().Set("Link", fmt.Sprintf(`</v2/charts/alpine/tags/list?n=%d&last=%s>; rel="next"`, n, all[end-1]))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": "charts/alpine", "tags": all[start:end]})
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(
+		ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")),
+		ClientOptPlainHTTP(),
+		ClientOptTagListPageSize(2),
+	)
+	require.NoError(t, err)
+
+	tags, err := c.Tags(strings.TrimPrefix(srv.URL, "http://") + "/charts/alpine")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.0.0+build.1", "0.3.0-rc.1", "0.2.0", "0.1.0"}, tags)
+	require.Equal(t, 3, pages)
+}
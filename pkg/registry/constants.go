@@ -34,4 +34,28 @@ const (
 
 	// LegacyChartLayerMediaType is the legacy reserved media type for Helm chart package content.
 	LegacyChartLayerMediaType = "application/tar+gzip"
+
+	// ValuesLayerMediaType is the reserved media type for the values.yaml of a
+	// chart, pushed as a separate layer next to the chart package content
+	ValuesLayerMediaType = "application/vnd.cncf.helm.chart.values.v1+yaml"
+
+	// SchemaLayerMediaType is the reserved media type for the values.schema.json
+	// of a chart, pushed as a separate layer next to the chart package content
+	SchemaLayerMediaType = "application/vnd.cncf.helm.chart.schema.v1+json"
+
+	// ReadmeLayerMediaType is the reserved media type for the README of a chart,
+	// pushed as a separate layer next to the chart package content
+	ReadmeLayerMediaType = "application/vnd.cncf.helm.chart.readme.v1+markdown"
+
+	// SPDXArtifactType is the artifact type of the SPDX SBOMs of charts,
+	// attached to them as OCI referrers
+	SPDXArtifactType = "application/spdx+json"
+
+	// CycloneDXArtifactType is the artifact type of the CycloneDX SBOMs of
+	// charts, attached to them as OCI referrers
+	CycloneDXArtifactType = "application/vnd.cyclonedx+json"
+
+	// SigstoreBundleArtifactType is the artifact type of the Sigstore bundles
+	// signing charts, attached to them as OCI referrers
+	SigstoreBundleArtifactType = "application/vnd.dev.sigstore.bundle.v0.3+json"
 )
@@ -0,0 +1,248 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// Authentication methods of stored credentials.
+const (
+	AuthMethodBasic         = "basic"
+	AuthMethodIdentityToken = "identity token"
+	AuthMethodRegistryToken = "registry token"
+	AuthMethodNone          = "none"
+	AuthMethodUnknown       = "unknown"
+)
+
+// Credential describes the credentials stored for a registry.
+type Credential struct {
+	// Registry is the registry the credentials are stored for.
+	Registry string `json:"registry"`
+	// AuthMethod is how the credentials authenticate, such as "basic" or "identity token".
+	AuthMethod string `json:"auth_method"`
+	// Store is where the credentials are kept: the credentials file or a credential helper.
+	Store string `json:"store"`
+	// Username is the user the credentials belong to, if any.
+	Username string `json:"username,omitempty"`
+	// Expires is when the token of the credentials expires, if it is known.
+	Expires *time.Time `json:"expires,omitempty"`
+	// LastUsed is when Helm last authenticated with the credentials, if it ever did.
+	LastUsed *time.Time `json:"last_used,omitempty"`
+}
+
+// credentialsConfig holds the parts of the credentials file listing registries.
+type credentialsConfig struct {
+	Auths       map[string]json.RawMessage `json:"auths"`
+	CredsStore  string                     `json:"credsStore"`
+	CredHelpers map[string]string          `json:"credHelpers"`
+}
+
+// registries returns the registries of the credentials file along with the
+// credential helper storing their credentials, if any.
+func (c *Client) registries() (map[string]string, error) {
+	data, err := os.ReadFile(c.credentialsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var config credentialsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", c.credentialsFile, err)
+	}
+	registries := map[string]string{}
+	for registry := range config.Auths {
+		registries[registry] = config.CredsStore
+	}
+	for registry, helper := range config.CredHelpers {
+		registries[registry] = helper
+	}
+	return registries, nil
+}
+
+// Credentials lists the registries Helm has credentials for. Registries only
+// known to the Docker credentials Helm falls back to are not listed.
+func (c *Client) Credentials() ([]Credential, error) {
+	registries, err := c.registries()
+	if err != nil {
+		return nil, err
+	}
+	usage := c.loadUsage()
+	ctx := context.Background()
+
+	creds := make([]Credential, 0, len(registries))
+	for registry, helper := range registries {
+		cred := Credential{Registry: registry, Store: "file"}
+		if helper != "" {
+			cred.Store = "docker-credential-" + helper
+		}
+		if t, ok := usage[registry]; ok {
+			cred.LastUsed = &t
+		}
+
+		stored, err := c.credentialsStore.Get(ctx, registry)
+		switch {
+		case err != nil:
+			slog.Debug("unable to read stored credentials", "registry", registry, slog.Any("error", err))
+			cred.AuthMethod = AuthMethodUnknown
+		case stored.RefreshToken != "":
+			cred.AuthMethod = AuthMethodIdentityToken
+			cred.Expires = tokenExpiry(stored.RefreshToken)
+		case stored.AccessToken != "":
+			cred.AuthMethod = AuthMethodRegistryToken
+			cred.Expires = tokenExpiry(stored.AccessToken)
+		case stored.Username != "" || stored.Password != "":
+			cred.AuthMethod = AuthMethodBasic
+			cred.Username = stored.Username
+			// Some registries hand out short-lived tokens to use as passwords.
+			cred.Expires = tokenExpiry(stored.Password)
+		default:
+			cred.AuthMethod = AuthMethodNone
+		}
+		creds = append(creds, cred)
+	}
+	sort.Slice(creds, func(i, j int) bool {
+		return creds[i].Registry < creds[j].Registry
+	})
+	return creds, nil
+}
+
+// LogoutAll logs out of all the registries Helm has credentials for.
+func (c *Client) LogoutAll(opts ...LogoutOption) error {
+	registries, err := c.registries()
+	if err != nil {
+		return err
+	}
+	hosts := make([]string, 0, len(registries))
+	for registry := range registries {
+		hosts = append(hosts, registry)
+	}
+	sort.Strings(hosts)
+
+	var errs []error
+	for _, host := range hosts {
+		if err := c.Logout(host, opts...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// tokenExpiry returns the expiry of a token if it is a JWT carrying one.
+func tokenExpiry(token string) *time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return nil
+	}
+	expires := time.Unix(int64(claims.Exp), 0).UTC()
+	return &expires
+}
+
+// usageFile returns the file recording when the credentials of each registry
+// were last used. It sits next to the credentials file, which is shared with
+// other tools and is left untouched.
+func (c *Client) usageFile() string {
+	return strings.TrimSuffix(c.credentialsFile, filepath.Ext(c.credentialsFile)) + "-usage.json"
+}
+
+func (c *Client) loadUsage() map[string]time.Time {
+	usage := map[string]time.Time{}
+	data, err := os.ReadFile(c.usageFile())
+	if err != nil {
+		return usage
+	}
+	if err := json.Unmarshal(data, &usage); err != nil {
+		slog.Debug("ignoring invalid registry usage file", "file", c.usageFile(), slog.Any("error", err))
+	}
+	return usage
+}
+
+// updateUsage records when the credentials of a registry were used, or
+// forgets about the registry if used is nil. Failing to record usage does not
+// fail the operation using the credentials.
+func (c *Client) updateUsage(registry string, used *time.Time) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+
+	usage := c.loadUsage()
+	if used != nil {
+		usage[registry] = used.UTC()
+	} else if _, ok := usage[registry]; ok {
+		delete(usage, registry)
+	} else {
+		return
+	}
+	data, err := json.MarshalIndent(usage, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(c.usageFile()), 0o755); err == nil {
+			err = os.WriteFile(c.usageFile(), data, 0o644)
+		}
+	}
+	if err != nil {
+		slog.Debug("unable to record registry usage", "registry", registry, slog.Any("error", err))
+	}
+}
+
+// recordingCredential wraps a credential function to record when the stored
+// credentials of each registry are first used by the client.
+func (c *Client) recordingCredential(credential auth.CredentialFunc) auth.CredentialFunc {
+	return func(ctx context.Context, hostport string) (auth.Credential, error) {
+		cred, err := credential(ctx, hostport)
+		if err != nil || cred == auth.EmptyCredential {
+			return cred, err
+		}
+		registry := credentials.ServerAddressFromHostname(hostport)
+		c.usageMu.Lock()
+		recorded := c.usedRegistries[registry]
+		if c.usedRegistries == nil {
+			c.usedRegistries = map[string]bool{}
+		}
+		c.usedRegistries[registry] = true
+		c.usageMu.Unlock()
+		if !recorded {
+			now := time.Now()
+			c.updateUsage(registry, &now)
+		}
+		return cred, nil
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// credentialsFixture is a credentials file with basic auth credentials, an
+// identity token expiring on 2026-09-21 and an entry without credentials.
+const credentialsFixture = `{
+  "auths": {
+    "registry.example.com": {"auth": "dXNlcjpwYXNz"},
+    "token.example.com": {"identitytoken": "eyJhbGciOiJub25lIn0.eyJleHAiOjE3OTAwMDAwMDB9.sig"},
+    "empty.example.com": {}
+  }
+}`
+
+func newCredentialsTestClient(t *testing.T) (*Client, string, *bytes.Buffer) {
+	t.Helper()
+	// Keep the Docker credentials of the host out of the way.
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	credentialsFile := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(credentialsFile, []byte(credentialsFixture), 0o600))
+	out := new(bytes.Buffer)
+	client, err := NewClient(ClientOptCredentialsFile(credentialsFile), ClientOptWriter(out))
+	require.NoError(t, err)
+	return client, credentialsFile, out
+}
+
+func TestCredentials(t *testing.T) {
+	client, _, _ := newCredentialsTestClient(t)
+
+	// Using the stored credentials records when they were used.
+	before := time.Now().Add(-time.Second)
+	cred, err := client.authorizer.Credential(context.Background(), "registry.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "user", cred.Username)
+	_, err = client.authorizer.Credential(context.Background(), "unknown.example.com")
+	require.NoError(t, err)
+
+	creds, err := client.Credentials()
+	require.NoError(t, err)
+	require.Len(t, creds, 3)
+
+	expires := time.Date(2026, 9, 21, 14, 13, 20, 0, time.UTC)
+	assert.Equal(t, Credential{Registry: "empty.example.com", AuthMethod: AuthMethodNone, Store: "file"}, creds[0])
+	assert.Equal(t, "registry.example.com", creds[1].Registry)
+	assert.Equal(t, AuthMethodBasic, creds[1].AuthMethod)
+	assert.Equal(t, "user", creds[1].Username)
+	assert.Nil(t, creds[1].Expires)
+	require.NotNil(t, creds[1].LastUsed)
+	assert.True(t, creds[1].LastUsed.After(before))
+	assert.Equal(t, Credential{Registry: "token.example.com", AuthMethod: AuthMethodIdentityToken, Store: "file", Expires: &expires}, creds[2])
+}
+
+func TestCredentialsWithoutFile(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	client, err := NewClient(ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")))
+	require.NoError(t, err)
+	creds, err := client.Credentials()
+	require.NoError(t, err)
+	assert.Empty(t, creds)
+}
+
+func TestLogoutAll(t *testing.T) {
+	client, credentialsFile, out := newCredentialsTestClient(t)
+	_, err := client.authorizer.Credential(context.Background(), "registry.example.com")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(filepath.Dir(credentialsFile), "config-usage.json"))
+
+	require.NoError(t, client.LogoutAll())
+	assert.Equal(t, `Removing login credentials for empty.example.com
+Removing login credentials for registry.example.com
+Removing login credentials for token.example.com
+`, out.String())
+
+	creds, err := client.Credentials()
+	require.NoError(t, err)
+	assert.Empty(t, creds)
+	usage, err := os.ReadFile(filepath.Join(filepath.Dir(credentialsFile), "config-usage.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, "{}", string(usage))
+}
+
+func TestTokenExpiry(t *testing.T) {
+	assert.Nil(t, tokenExpiry("password"))
+	assert.Nil(t, tokenExpiry("not.a.jwt"))
+	assert.Nil(t, tokenExpiry("eyJhbGciOiJub25lIn0.e30.sig"))
+	assert.Equal(t, time.Date(2026, 9, 21, 14, 13, 20, 0, time.UTC), *tokenExpiry("eyJhbGciOiJub25lIn0.eyJleHAiOjE3OTAwMDAwMDB9.sig"))
+}
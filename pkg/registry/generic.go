@@ -45,6 +45,7 @@ type GenericClient struct {
 	credentialsStore   credentials.Store
 	httpClient         *http.Client
 	plainHTTP          bool
+	remoteClient       RemoteClient
 }
 
 // GenericPullOptions configures a generic pull operation
@@ -79,6 +80,7 @@ func NewGenericClient(client *Client) *GenericClient {
 		credentialsStore:   client.credentialsStore,
 		httpClient:         client.httpClient,
 		plainHTTP:          client.plainHTTP,
+		remoteClient:       client.remoteClient(),
 	}
 }
 
@@ -98,7 +100,7 @@ func (c *GenericClient) PullGeneric(ref string, options GenericPullOptions) (*Ge
 		return nil, err
 	}
 	repository.PlainHTTP = c.plainHTTP
-	repository.Client = c.authorizer
+	repository.Client = c.remoteClient
 
 	ctx := context.Background()
 
@@ -0,0 +1,250 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/internal/tlsutil"
+	"helm.sh/helm/v4/internal/version"
+)
+
+// RegistriesFileBasename is the name of the file configuring the mirrors of
+// registries, in the Helm configuration directory.
+const RegistriesFileBasename = "registries.yaml"
+
+type (
+	// RegistriesFile is the file configuring the mirrors of registries, such
+	// as:
+	//
+	//	registries:
+	//	  - host: registry.example.com
+	//	    mirrors:
+	//	      - host: cache.internal.example.com
+	//	        prefix: proxy/registry.example.com
+	//	        caFile: /etc/ssl/internal-ca.pem
+	//
+	// The charts of the registries are then pulled through their mirrors,
+	// such as pull-through caches, from oci:// references to the registries.
+	RegistriesFile struct {
+		Registries []*RegistryEntry `json:"registries"`
+	}
+
+	// RegistryEntry is a registry and its mirrors.
+	RegistryEntry struct {
+		// Host is the host of the registry, such as "registry.example.com" or
+		// "docker.io".
+		Host string `json:"host"`
+		// Mirrors are tried in the order they are listed, before the registry.
+		Mirrors []*Mirror `json:"mirrors"`
+	}
+
+	// Mirror is a registry serving the repositories of another registry,
+	// with its own credentials and TLS settings.
+	Mirror struct {
+		// Host is the host of the mirror, such as "cache.example.com:5000".
+		Host string `json:"host"`
+		// Prefix is the namespace of the mirror under which the repositories
+		// of the registry are served, if any, such as "proxy" for the
+		// repository "proxy/charts/nginx" mirroring "charts/nginx".
+		Prefix string `json:"prefix,omitempty"`
+		// PlainHTTP uses HTTP instead of HTTPS for the mirror.
+		PlainHTTP bool `json:"plainHTTP,omitempty"`
+		// Username and Password authenticate to the mirror. The credentials
+		// of its host stored by 'helm registry login' are used otherwise.
+		Username              string `json:"username,omitempty"`
+		Password              string `json:"password,omitempty"`
+		CertFile              string `json:"certFile,omitempty"`
+		KeyFile               string `json:"keyFile,omitempty"`
+		CAFile                string `json:"caFile,omitempty"`
+		InsecureSkipTLSverify bool   `json:"insecure_skip_tls_verify,omitempty"`
+	}
+)
+
+// LoadRegistriesFile loads the file configuring the mirrors of registries. It
+// returns an empty file when there is none.
+func LoadRegistriesFile(path string) (*RegistriesFile, error) {
+	r := new(RegistriesFile)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, fmt.Errorf("couldn't load registries file (%s): %w", path, err)
+	}
+	if err := yaml.UnmarshalStrict(b, r); err != nil {
+		return r, fmt.Errorf("couldn't parse registries file (%s): %w", path, err)
+	}
+	for _, entry := range r.Registries {
+		if entry.Host == "" {
+			return r, fmt.Errorf("invalid registries file (%s): missing host of registry", path)
+		}
+		for _, m := range entry.Mirrors {
+			if m.Host == "" {
+				return r, fmt.Errorf("invalid registries file (%s): missing host of mirror of %s", path, entry.Host)
+			}
+		}
+	}
+	return r, nil
+}
+
+// ClientOptRegistriesFile returns a function that sets the path of the file
+// configuring the mirrors of registries on a client options set.
+func ClientOptRegistriesFile(registriesFile string) ClientOption {
+	return func(client *Client) {
+		client.registriesFile = registriesFile
+	}
+}
+
+// registryMirror is a mirror and its authorizer.
+type registryMirror struct {
+	*Mirror
+	client *auth.Client
+}
+
+// loadMirrors sets up the authorizers of the mirrors of the registries file
+// of the client, by the host of the requests to their registries.
+func (c *Client) loadMirrors() error {
+	if c.registriesFile == "" {
+		return nil
+	}
+	file, err := LoadRegistriesFile(c.registriesFile)
+	if err != nil {
+		return err
+	}
+	for _, entry := range file.Registries {
+		host := entry.Host
+		// The requests to Docker Hub are sent to its registry host.
+		if host == "docker.io" {
+			host = "registry-1.docker.io"
+		}
+		for _, m := range entry.Mirrors {
+			mirror, err := c.newRegistryMirror(m)
+			if err != nil {
+				return fmt.Errorf("invalid mirror %s of registry %s: %w", m.Host, entry.Host, err)
+			}
+			if c.mirrors == nil {
+				c.mirrors = map[string][]*registryMirror{}
+			}
+			c.mirrors[host] = append(c.mirrors[host], mirror)
+		}
+	}
+	return nil
+}
+
+func (c *Client) newRegistryMirror(m *Mirror) (*registryMirror, error) {
+	httpClient := c.httpClient
+	if (m.CertFile != "" && m.KeyFile != "") || m.CAFile != "" || m.InsecureSkipTLSverify {
+		tlsConf, err := tlsutil.NewTLSConfig(
+			tlsutil.WithInsecureSkipVerify(m.InsecureSkipTLSverify),
+			tlsutil.WithCertKeyPairFiles(m.CertFile, m.KeyFile),
+			tlsutil.WithCAFile(m.CAFile),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
+		}
+		httpClient = &http.Client{
+			Transport: retry.NewTransport(&http.Transport{
+				TLSClientConfig: tlsConf,
+				Proxy:           http.ProxyFromEnvironment,
+			}),
+		}
+	}
+
+	authorizer := &auth.Client{Client: httpClient}
+	authorizer.SetUserAgent(version.GetUserAgent())
+	if m.Username != "" && m.Password != "" {
+		authorizer.Credential = auth.StaticCredential(m.Host, auth.Credential{Username: m.Username, Password: m.Password})
+	} else {
+		authorizer.Credential = credentials.Credential(c.credentialsStore)
+	}
+	if c.enableCache {
+		authorizer.Cache = newTokenCache()
+	}
+	return &registryMirror{Mirror: m, client: authorizer}, nil
+}
+
+// remoteClient returns the client of the requests to registries, which sends
+// the pulls from registries with mirrors through them.
+func (c *Client) remoteClient() RemoteClient {
+	if len(c.mirrors) == 0 {
+		return c.registryClient()
+	}
+	return &mirroringClient{registry: c.registryClient(), mirrors: c.mirrors}
+}
+
+// mirroringClient sends the requests reading the repositories of registries
+// to their mirrors in turn, and then to the registries when all the mirrors
+// fail.
+type mirroringClient struct {
+	registry RemoteClient
+	mirrors  map[string][]*registryMirror
+}
+
+func (m *mirroringClient) Do(req *http.Request) (*http.Response, error) {
+	mirrors := m.mirrors[req.URL.Host]
+	repoPath, isRepo := strings.CutPrefix(req.URL.Path, "/v2/")
+	// Only the reads of repositories are mirrored, not the pushes nor the
+	// catalog.
+	if len(mirrors) == 0 || !isRepo || repoPath == "" || strings.HasPrefix(repoPath, "_catalog") ||
+		(req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return m.registry.Do(req)
+	}
+
+	for _, mirror := range mirrors {
+		mirrorReq := req.Clone(req.Context())
+		// The authorizers find the credentials and the tokens of the host
+		// of the requests.
+		mirrorReq.Host = mirror.Host
+		mirrorReq.URL.Host = mirror.Host
+		mirrorReq.URL.Scheme = "https"
+		if mirror.PlainHTTP {
+			mirrorReq.URL.Scheme = "http"
+		}
+		if prefix := strings.Trim(mirror.Prefix, "/"); prefix != "" {
+			mirrorReq.URL.Path = "/v2/" + prefix + "/" + repoPath
+			mirrorReq.URL.RawPath = ""
+		}
+		resp, err := mirror.client.Do(mirrorReq)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			// Relative links, such as the next page of the tags, are
+			// resolved against the registry, and are then mirrored as well.
+			resp.Request = req
+			return resp, nil
+		}
+		if err == nil {
+			resp.Body.Close()
+			err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+		slog.Debug("registry mirror failed, trying the next one", "registry", req.URL.Host, "mirror", mirror.Host, "path", req.URL.Path, slog.Any("error", err))
+	}
+	return m.registry.Do(req)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRegistriesFile(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := LoadRegistriesFile(filepath.Join(dir, "missing.yaml"))
+	require.NoError(t, err, "a missing registries file has no mirrors")
+	assert.Empty(t, f.Registries)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			content: `registries:
+  - host: docker.io
+    mirrors:
+      - host: cache.example.com
+        prefix: dockerhub
+`,
+		},
+		{
+			name:    "missing registry host",
+			content: "registries:\n  - mirrors:\n      - host: cache.example.com\n",
+			wantErr: "missing host of registry",
+		},
+		{
+			name:    "missing mirror host",
+			content: "registries:\n  - host: docker.io\n    mirrors:\n      - prefix: dockerhub\n",
+			wantErr: "missing host of mirror of docker.io",
+		},
+		{
+			name:    "unknown field",
+			content: "registries:\n  - host: docker.io\n    endpoint: cache.example.com\n",
+			wantErr: "couldn't parse registries file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+			f, err := LoadRegistriesFile(path)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, f.Registries, 1)
+			assert.Equal(t, "dockerhub", f.Registries[0].Mirrors[0].Prefix)
+
+			client, err := NewClient(ClientOptRegistriesFile(path))
+			require.NoError(t, err)
+			assert.Len(t, client.mirrors["registry-1.docker.io"], 1, "the mirrors of Docker Hub are used for its registry host")
+		})
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+type (
+	// Attachment is an artifact attached to a chart as an OCI referrer, a
+	// manifest whose subject is the manifest of the chart, such as its
+	// provenance file, an SBOM or a signature.
+	Attachment struct {
+		// ArtifactType is the type of the artifact, such as SPDXArtifactType.
+		// It is the media type of its content as well.
+		ArtifactType string
+		// Name is the file name of the artifact, kept in its title annotation.
+		Name string
+		Data []byte
+	}
+
+	// Referrer is an artifact attached to a chart.
+	Referrer struct {
+		ArtifactType string `json:"artifactType"`
+		Name         string `json:"name,omitempty"`
+		// Digest and Size are the ones of the manifest of the referrer.
+		Digest string `json:"digest"`
+		Size   int64  `json:"size"`
+	}
+)
+
+// SBOMArtifactType returns the artifact type of an SBOM from its format, SPDX
+// or CycloneDX JSON.
+func SBOMArtifactType(data []byte) (string, error) {
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+	if err := json.Unmarshal(data, &doc); err == nil {
+		switch {
+		case doc.SPDXVersion != "":
+			return SPDXArtifactType, nil
+		case doc.BOMFormat == "CycloneDX":
+			return CycloneDXArtifactType, nil
+		}
+	}
+	return "", errors.New("unsupported SBOM, expected an SPDX or CycloneDX JSON document")
+}
+
+// Attach attaches artifacts to a chart already pushed to a registry, as
+// referrers of its manifest.
+func (c *Client) Attach(ref string, attachments ...Attachment) ([]*Referrer, error) {
+	repository, err := c.remoteRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	// The artifacts are attached in the registry, not in its mirrors.
+	repository.Client = c.registryClient()
+	ctx := context.Background()
+	subject, err := repository.Resolve(ctx, repository.Reference.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return c.attach(ctx, repository, subject, "", attachments)
+}
+
+// Referrers lists the artifacts attached to a chart, all of them or the ones
+// of an artifact type. Registries without the referrers API of OCI 1.1 are
+// read through the referrers tag schema.
+func (c *Client) Referrers(ref, artifactType string) ([]*Referrer, error) {
+	repository, err := c.remoteRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	subject, err := repository.Resolve(ctx, repository.Reference.Reference)
+	if err != nil {
+		return nil, err
+	}
+	return referrers(ctx, repository, subject, artifactType)
+}
+
+// FetchReferrer downloads the content of an artifact attached to a chart,
+// verified against the digests of the manifest of the referrer.
+func (c *Client) FetchReferrer(ref string, referrer *Referrer) ([]byte, error) {
+	repository, err := c.remoteRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := fetchReferrer(context.Background(), repository, referrer)
+	return data, err
+}
+
+// remoteRepository returns the repository of a reference.
+func (c *Client) remoteRepository(ref string) (*remote.Repository, error) {
+	parsedRef, err := newReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	repository, err := remote.NewRepository(parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	repository.PlainHTTP = c.plainHTTP
+	repository.Client = c.remoteClient()
+	return repository, nil
+}
+
+// attach pushes the artifacts as manifests whose subject is the manifest of
+// a chart. Registries without the referrers API get the referrers index of
+// the tag schema updated instead.
+func (c *Client) attach(ctx context.Context, repository *remote.Repository, subject ocispec.Descriptor, creationTime string, attachments []Attachment) ([]*Referrer, error) {
+	// Many registries forbid deleting manifests, so the previous referrers
+	// indexes are left untagged rather than deleted.
+	repository.SkipReferrersGC = true
+	result := make([]*Referrer, 0, len(attachments))
+	for _, a := range attachments {
+		if a.ArtifactType == "" {
+			return nil, fmt.Errorf("missing artifact type of attachment %q", a.Name)
+		}
+		layer, err := oras.PushBytes(ctx, repository, a.ArtifactType, a.Data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to push attachment %q: %w", a.Name, err)
+		}
+		annotations := map[string]string{}
+		if a.Name != "" {
+			layer.Annotations = map[string]string{ocispec.AnnotationTitle: a.Name}
+			annotations[ocispec.AnnotationTitle] = a.Name
+		}
+		if creationTime != "" {
+			annotations[ocispec.AnnotationCreated] = creationTime
+		}
+		desc, err := oras.PackManifest(ctx, repository, oras.PackManifestVersion1_1, a.ArtifactType, oras.PackManifestOptions{
+			Subject:             &subject,
+			Layers:              []ocispec.Descriptor{layer},
+			ManifestAnnotations: annotations,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to attach %q: %w", a.Name, err)
+		}
+		result = append(result, &Referrer{
+			ArtifactType: a.ArtifactType,
+			Name:         a.Name,
+			Digest:       desc.Digest.String(),
+			Size:         desc.Size,
+		})
+		fmt.Fprintf(c.out, "Attached: %s (%s)\n", desc.Digest, a.ArtifactType)
+	}
+	return result, nil
+}
+
+// referrers lists the referrers of a manifest.
+func referrers(ctx context.Context, repository *remote.Repository, subject ocispec.Descriptor, artifactType string) ([]*Referrer, error) {
+	var result []*Referrer
+	err := repository.Referrers(ctx, subject, artifactType, func(descriptors []ocispec.Descriptor) error {
+		for _, d := range descriptors {
+			result = append(result, &Referrer{
+				ArtifactType: d.ArtifactType,
+				Name:         d.Annotations[ocispec.AnnotationTitle],
+				Digest:       d.Digest.String(),
+				Size:         d.Size,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the referrers of %s: %w", subject.Digest, err)
+	}
+	return result, nil
+}
+
+// fetchReferrer downloads the manifest of a referrer, then its content, and
+// returns the content and its descriptor.
+func fetchReferrer(ctx context.Context, repository *remote.Repository, referrer *Referrer) ([]byte, ocispec.Descriptor, error) {
+	dgst, err := digest.Parse(referrer.Digest)
+	if err != nil {
+		return nil, ocispec.Descriptor{}, err
+	}
+	manifestData, err := content.FetchAll(ctx, repository, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    dgst,
+		Size:      referrer.Size,
+	})
+	if err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("unable to retrieve referrer with digest %s: %w", dgst, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("unable to parse manifest: %w", err)
+	}
+	if len(manifest.Layers) == 0 {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("referrer with digest %s has no content", dgst)
+	}
+	layer := manifest.Layers[0]
+	data, err := content.FetchAll(ctx, repository, layer)
+	if err != nil {
+		return nil, ocispec.Descriptor{}, fmt.Errorf("unable to retrieve blob with digest %s: %w", layer.Digest, err)
+	}
+	return data, layer, nil
+}
+
+// pullProvReferrer downloads the provenance file attached to a chart as a
+// referrer. It returns nil when there is none.
+func (c *Client) pullProvReferrer(ref string, subject ocispec.Descriptor) (*DescriptorPullSummary, error) {
+	repository, err := c.remoteRepository(ref)
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	provs, err := referrers(ctx, repository, subject, ProvLayerMediaType)
+	if err != nil || len(provs) == 0 {
+		return nil, err
+	}
+	data, layer, err := fetchReferrer(ctx, repository, provs[0])
+	if err != nil {
+		return nil, err
+	}
+	return &DescriptorPullSummary{Data: data, Digest: layer.Digest.String(), Size: layer.Size}, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// tokenRefreshLeeway is how long before their expiry the bearer tokens are
+// refreshed, so that they do not expire while a request is in flight.
+const tokenRefreshLeeway = 30 * time.Second
+
+// ClientOptCredentialsStore returns a function that sets the store of the
+// credentials of the registries on a client options set, instead of the
+// credentials file of Helm with fallback to the one of Docker. The
+// credentials are read from the store when the registries ask for them, and
+// 'helm registry login' saves them to the store.
+func ClientOptCredentialsStore(store credentials.Store) ClientOption {
+	return func(client *Client) {
+		client.credentialsStore = store
+	}
+}
+
+// tokenCache is a cache of the tokens of the registries, which forgets the
+// bearer tokens about to expire, so that they are fetched again, with the
+// credentials of the registries or anonymously, before being sent.
+type tokenCache struct {
+	auth.Cache
+}
+
+func newTokenCache() auth.Cache {
+	return &tokenCache{Cache: auth.NewCache()}
+}
+
+func (c *tokenCache) GetToken(ctx context.Context, registry string, scheme auth.Scheme, key string) (string, error) {
+	token, err := c.Cache.GetToken(ctx, registry, scheme, key)
+	if err != nil {
+		return "", err
+	}
+	if scheme == auth.SchemeBearer {
+		// The registries issuing opaque tokens reject them once expired, and
+		// they are fetched again then.
+		if expiry := tokenExpiry(token); expiry != nil && time.Until(*expiry) < tokenRefreshLeeway {
+			return "", errdef.ErrNotFound
+		}
+	}
+	return token, nil
+}
+
+// registryClient returns the client of the requests to the registries
+// themselves, not their mirrors, which retries the requests rejected because
+// of an expired token.
+func (c *Client) registryClient() RemoteClient {
+	return &refreshingClient{authorizer: c.authorizer}
+}
+
+// refreshingClient sends the requests through the authorizer, and sends them
+// again with a new token when the registry rejects the token of a previous
+// request, such as the token of the start of an upload reused to complete
+// it, expired during the upload of a large chart.
+type refreshingClient struct {
+	authorizer *auth.Client
+}
+
+func (r *refreshingClient) Do(req *http.Request) (*http.Response, error) {
+	// The authorizer can only send the requests again with a new token when
+	// their body can be read again.
+	if err := rewindableBody(req); err != nil {
+		return nil, err
+	}
+	resp, err := r.authorizer.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Header.Get("Authorization") == "" {
+		return resp, err
+	}
+
+	// The authorizer sends the requests with an authorization as they are,
+	// so the authorization is left to the authorizer, which fetches a new
+	// token when the registry rejects its own.
+	resp.Body.Close()
+	retry := req.Clone(req.Context())
+	retry.Header.Del("Authorization")
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return r.authorizer.Do(retry)
+}
+
+// rewindableBody makes the body of a request readable again, reading it in
+// memory when it is not already, as the charts and their layers are.
+func rewindableBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// testJWT returns a JWT expiring at exp.
+func testJWT(id int, exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return fmt.Sprintf("%s.%s.sig", encode([]byte(`{"alg":"none"}`)),
+		encode(fmt.Appendf(nil, `{"jti":"%d","exp":%d}`, id, exp.Unix())))
+}
+
+// tokenRegistry is a registry accepting the last token it issued, until it
+// is revoked, as if it expired.
+type tokenRegistry struct {
+	mu     sync.Mutex
+	url    string
+	valid  string
+	issued int
+	// users are the users the tokens were issued to, "" when anonymous.
+	users  []string
+	bodies []string
+}
+
+func (r *tokenRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req.URL.Path == "/token" {
+		user, _, _ := req.BasicAuth()
+		r.users = append(r.users, user)
+		r.issued++
+		r.valid = testJWT(r.issued, time.Now().Add(time.Hour))
+		json.NewEncoder(w).Encode(map[string]string{"token": r.valid})
+		return
+	}
+	if r.valid == "" || req.Header.Get("Authorization") != "Bearer "+r.valid {
+		w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:charts:pull,push"`, r.url))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// revoke makes the registry reject the token it issued last.
+func (r *tokenRegistry) revoke() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.valid = ""
+}
+
+func TestTokenCache(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		scheme auth.Scheme
+		token  string
+		cached bool
+	}{
+		{"valid token", auth.SchemeBearer, testJWT(1, time.Now().Add(time.Hour)), true},
+		{"token about to expire", auth.SchemeBearer, testJWT(1, time.Now().Add(10*time.Second)), false},
+		{"expired token", auth.SchemeBearer, testJWT(1, time.Now().Add(-time.Minute)), false},
+		{"opaque token", auth.SchemeBearer, "opaque", true},
+		{"basic credentials", auth.SchemeBasic, testJWT(1, time.Now().Add(-time.Minute)), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newTokenCache()
+			_, err := cache.Set(ctx, "registry.example.com", tt.scheme, "key", func(context.Context) (string, error) {
+				return tt.token, nil
+			})
+			require.NoError(t, err)
+
+			token, err := cache.GetToken(ctx, "registry.example.com", tt.scheme, "key")
+			if tt.cached {
+				require.NoError(t, err)
+				assert.Equal(t, tt.token, token)
+			} else {
+				assert.Error(t, err, "tokens about to expire are fetched again")
+			}
+		})
+	}
+}
+
+func TestRegistryClientTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name  string
+		creds *auth.Credential
+		user  string
+	}{
+		{"anonymous", nil, ""},
+		{"stored credentials", &auth.Credential{Username: "helm", Password: "secret"}, "helm"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &tokenRegistry{}
+			srv := httptest.NewServer(reg)
+			defer srv.Close()
+			reg.url = srv.URL
+			host := strings.TrimPrefix(srv.URL, "http://")
+
+			store := credentials.NewMemoryStore()
+			if tt.creds != nil {
+				require.NoError(t, store.Put(context.Background(), host, *tt.creds))
+			}
+			client, err := NewClient(
+				ClientOptEnableCache(true),
+				ClientOptCredentialsStore(store),
+				// The usage of the credentials is recorded next to this file.
+				ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")),
+			)
+			require.NoError(t, err)
+
+			// The start of an upload, whose token is reused to complete it.
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/v2/charts/blobs/uploads/", nil)
+			require.NoError(t, err)
+			resp, err := client.registryClient().Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			// The token expires during the upload, whose body is a stream.
+			reg.revoke()
+			req, err = http.NewRequest(http.MethodPut, srv.URL+"/v2/charts/blobs/uploads/session", io.NopCloser(strings.NewReader("chart")))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", resp.Request.Header.Get("Authorization"))
+			resp, err = client.registryClient().Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			assert.Equal(t, []string{"", "chart"}, reg.bodies, "the upload is sent again in full")
+			assert.Equal(t, []string{tt.user, tt.user}, reg.users, "the token is fetched again")
+		})
+	}
+}
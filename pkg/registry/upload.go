@@ -0,0 +1,219 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v4/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/errcode"
+)
+
+// maxChunkRetries is the number of times the upload of a chunk is resumed
+// after failing, before the push fails.
+const maxChunkRetries = 3
+
+// PushProgress is the progress of the upload of a blob of a chart.
+type PushProgress struct {
+	Digest    string
+	MediaType string
+	// Uploaded is the number of bytes of the blob received by the registry.
+	Uploaded int64
+	Size     int64
+}
+
+// blobUpload is a chunked upload of a blob, in the session of the registry
+// at location.
+type blobUpload struct {
+	client   RemoteClient
+	desc     ocispec.Descriptor
+	data     []byte
+	location *url.URL
+	progress func(PushProgress)
+}
+
+// pushBlobChunked uploads a blob to a repository in chunks of chunkSize bytes.
+// Chunks failing to upload are resumed from the last byte received by the
+// registry, so that large charts do not have to be uploaded again in full
+// when the connection drops.
+func (c *Client) pushBlobChunked(ctx context.Context, repository *remote.Repository, desc ocispec.Descriptor, data []byte, chunkSize int64, progress func(PushProgress)) error {
+	upload := &blobUpload{client: c.registryClient(), desc: desc, data: data, progress: progress}
+	if exists, err := repository.Exists(ctx, desc); err == nil && exists {
+		upload.report(desc.Size)
+		return nil
+	}
+
+	ctx = auth.AppendRepositoryScope(ctx, repository.Reference, auth.ActionPull, auth.ActionPush)
+	scheme := "https"
+	if repository.PlainHTTP {
+		scheme = "http"
+	}
+	start := &url.URL{
+		Scheme: scheme,
+		Host:   repository.Reference.Host(),
+		Path:   fmt.Sprintf("/v2/%s/blobs/uploads/", repository.Reference.Repository),
+	}
+	resp, err := upload.do(ctx, http.MethodPost, start, nil, http.StatusAccepted)
+	if err != nil {
+		return fmt.Errorf("unable to start the upload of blob %s: %w", desc.Digest, err)
+	}
+	if err := upload.setLocation(start, resp); err != nil {
+		return err
+	}
+
+	var offset int64
+	retries := 0
+	for offset < desc.Size {
+		end := min(offset+chunkSize, desc.Size)
+		err := upload.patch(ctx, offset, end)
+		if err == nil {
+			offset, retries = end, 0
+			upload.report(offset)
+			continue
+		}
+		if retries == maxChunkRetries || !retryableUpload(err) {
+			return fmt.Errorf("unable to upload blob %s: %w", desc.Digest, err)
+		}
+		retries++
+		// The registry may have received part of the chunk.
+		if offset, err = upload.status(ctx); err != nil {
+			return fmt.Errorf("unable to resume the upload of blob %s: %w", desc.Digest, err)
+		}
+	}
+
+	query := upload.location.Query()
+	query.Set("digest", desc.Digest.String())
+	upload.location.RawQuery = query.Encode()
+	if _, err := upload.do(ctx, http.MethodPut, upload.location, nil, http.StatusCreated); err != nil {
+		return fmt.Errorf("unable to complete the upload of blob %s: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+// patch uploads the bytes of the blob from offset to end.
+func (u *blobUpload) patch(ctx context.Context, offset, end int64) error {
+	resp, err := u.do(ctx, http.MethodPatch, u.location, u.data[offset:end], http.StatusAccepted,
+		"Content-Range", fmt.Sprintf("%d-%d", offset, end-1))
+	if err != nil {
+		return err
+	}
+	return u.setLocation(u.location, resp)
+}
+
+// status returns the number of bytes of the blob received by the registry.
+func (u *blobUpload) status(ctx context.Context) (int64, error) {
+	resp, err := u.do(ctx, http.MethodGet, u.location, nil, http.StatusNoContent)
+	if err != nil {
+		return 0, err
+	}
+	if err := u.setLocation(u.location, resp); err != nil {
+		return 0, err
+	}
+	// The range of the bytes received is inclusive, such as "0-1023".
+	_, last, ok := strings.Cut(resp.Header.Get("Range"), "-")
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || n+1 > u.desc.Size {
+		return 0, fmt.Errorf("invalid range %q of the upload", resp.Header.Get("Range"))
+	}
+	u.report(n + 1)
+	return n + 1, nil
+}
+
+// setLocation follows the location of the upload session returned by the
+// registry, which may change after each request.
+func (u *blobUpload) setLocation(base *url.URL, resp *http.Response) error {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return errors.New("missing location of the upload session in the response of the registry")
+	}
+	next, err := base.Parse(location)
+	if err != nil {
+		return fmt.Errorf("invalid location of the upload session %q: %w", location, err)
+	}
+	u.location = next
+	return nil
+}
+
+// do sends a request of the upload, and fails unless the registry responds
+// with the expected status code.
+func (u *blobUpload) do(ctx context.Context, method string, target *url.URL, body []byte, expected int, header ...string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		errResp := &errcode.ErrorResponse{Method: method, URL: req.URL, StatusCode: resp.StatusCode}
+		var errs struct {
+			Errors errcode.Errors `json:"errors"`
+		}
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 8*1024)).Decode(&errs); err == nil {
+			errResp.Errors = errs.Errors
+		}
+		return nil, errResp
+	}
+	return resp, nil
+}
+
+// report reports the number of bytes of the blob uploaded.
+func (u *blobUpload) report(uploaded int64) {
+	if u.progress != nil {
+		u.progress(PushProgress{
+			Digest:    u.desc.Digest.String(),
+			MediaType: u.desc.MediaType,
+			Uploaded:  uploaded,
+			Size:      u.desc.Size,
+		})
+	}
+}
+
+// retryableUpload reports whether the upload of a chunk may be resumed after
+// an error: the connection dropped, the registry failed, or the registry
+// received a different range of the blob than the one sent.
+func retryableUpload(err error) bool {
+	var errResp *errcode.ErrorResponse
+	if !errors.As(err, &errResp) {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return errResp.StatusCode >= http.StatusInternalServerError ||
+		errResp.StatusCode == http.StatusRequestedRangeNotSatisfiable ||
+		errResp.StatusCode == http.StatusTooManyRequests
+}
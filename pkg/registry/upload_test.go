@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// flakyUploadServer is a registry accepting chunked uploads, which drops the
+// connection in the middle of one chunk.
+type flakyUploadServer struct {
+	received  []byte
+	completed digest.Digest
+	failed    bool
+	failAt    int
+	patches   int
+}
+
+func (s *flakyUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const location = "/v2/charts/mychart/blobs/uploads/session"
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPost:
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPatch:
+		s.patches++
+		var start, end int
+		fmt.Sscanf(r.Header.Get("Content-Range"), "%d-%d", &start, &end)
+		if start != len(s.received) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if s.patches == s.failAt && !s.failed {
+			// Keep half of the chunk, then drop the connection.
+			s.failed = true
+			s.received = append(s.received, body[:len(body)/2]...)
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		s.received = append(s.received, body...)
+		w.Header().Set("Location", location)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", len(s.received)-1))
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodGet:
+		w.Header().Set("Location", location)
+		w.Header().Set("Range", fmt.Sprintf("0-%d", len(s.received)-1))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.completed = digest.Digest(r.URL.Query().Get("digest"))
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func TestPushBlobChunkedResume(t *testing.T) {
+	server := &flakyUploadServer{failAt: 2}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client, err := NewClient(ClientOptPlainHTTP())
+	require.NoError(t, err)
+	repository, err := remote.NewRepository(strings.TrimPrefix(ts.URL, "http://") + "/charts/mychart")
+	require.NoError(t, err)
+	repository.PlainHTTP = true
+	repository.Client = client.authorizer
+
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	desc := content.NewDescriptorFromBytes(ChartLayerMediaType, data)
+	var progress []int64
+	err = client.pushBlobChunked(context.Background(), repository, desc, data, 300, func(p PushProgress) {
+		assert.Equal(t, desc.Digest.String(), p.Digest)
+		assert.Equal(t, desc.Size, p.Size)
+		progress = append(progress, p.Uploaded)
+	})
+	require.NoError(t, err)
+
+	assert.True(t, server.failed, "the connection was dropped")
+	assert.Equal(t, data, server.received, "the registry received the blob once")
+	assert.Equal(t, desc.Digest, server.completed)
+	// The second chunk is resumed from its half received by the registry.
+	assert.Equal(t, []int64{300, 450, 750, 1000}, progress)
+}
+
+func TestPushBlobChunkedFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusNotFound)
+		case http.MethodPost:
+			w.Header().Set("Location", "/v2/charts/mychart/blobs/uploads/session")
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ClientOptPlainHTTP())
+	require.NoError(t, err)
+	repository, err := remote.NewRepository(strings.TrimPrefix(ts.URL, "http://") + "/charts/mychart")
+	require.NoError(t, err)
+	repository.PlainHTTP = true
+	repository.Client = client.authorizer
+
+	data := []byte("mychart")
+	desc := content.NewDescriptorFromBytes(ChartLayerMediaType, data)
+	err = client.pushBlobChunked(context.Background(), repository, desc, data, 4, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403")
+}
@@ -18,6 +18,8 @@ package registry // import "helm.sh/helm/v4/pkg/registry"
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,11 +28,14 @@ import (
 	"time"
 
 	"helm.sh/helm/v4/internal/tlsutil"
+	"helm.sh/helm/v4/pkg/chart/loader/archive"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 
 	"github.com/Masterminds/semver/v3"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
 )
 
 var immutableOciAnnotations = []string{
@@ -84,6 +89,44 @@ func GetTagMatchingVersionOrConstraint(tags []string, versionString string) (str
 	return "", fmt.Errorf("could not locate a version matching provided version string %s", versionString)
 }
 
+// pushSeparateLayers pushes the values.yaml, values.schema.json and README of
+// a chart archive to the store, and returns the descriptors of their layers.
+func pushSeparateLayers(ctx context.Context, store content.Pusher, chartData []byte) ([]ocispec.Descriptor, error) {
+	files, err := archive.LoadArchiveFiles(bytes.NewReader(chartData))
+	if err != nil {
+		return nil, err
+	}
+	var layers []ocispec.Descriptor
+	for _, f := range files {
+		mediaType := separateLayerMediaType(f.Name)
+		if mediaType == "" {
+			continue
+		}
+		desc := content.NewDescriptorFromBytes(mediaType, f.Data)
+		desc.Annotations = map[string]string{ocispec.AnnotationTitle: f.Name}
+		if err := store.Push(ctx, desc, bytes.NewReader(f.Data)); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+			return nil, err
+		}
+		layers = append(layers, desc)
+	}
+	return layers, nil
+}
+
+// separateLayerMediaType returns the media type of the separate layer a file
+// of a chart is pushed as, or an empty string if it is only part of the chart
+// layer.
+func separateLayerMediaType(name string) string {
+	switch {
+	case name == "values.yaml":
+		return ValuesLayerMediaType
+	case name == "values.schema.json":
+		return SchemaLayerMediaType
+	case slices.Contains([]string{"readme.md", "readme.txt", "readme"}, strings.ToLower(name)):
+		return ReadmeLayerMediaType
+	}
+	return ""
+}
+
 // extractChartMeta is used to extract a chart metadata from a byte array
 func extractChartMeta(chartData []byte) (*chart.Metadata, error) {
 	ch, err := loader.LoadArchive(bytes.NewReader(chartData))
@@ -272,3 +272,20 @@ func TestGenerateOCICreatedAnnotations(t *testing.T) {
 	}
 
 }
+
+func TestSeparateLayerMediaType(t *testing.T) {
+	tests := map[string]string{
+		"values.yaml":            ValuesLayerMediaType,
+		"values.schema.json":     SchemaLayerMediaType,
+		"README.md":              ReadmeLayerMediaType,
+		"readme.txt":             ReadmeLayerMediaType,
+		"Chart.yaml":             "",
+		"charts/sub/values.yaml": "",
+		"templates/README.md":    "",
+	}
+	for name, want := range tests {
+		if got := separateLayerMediaType(name); got != want {
+			t.Errorf("separateLayerMediaType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/distribution/distribution/v3/configuration"
 	"github.com/distribution/distribution/v3/registry"
 	_ "github.com/distribution/distribution/v3/registry/auth/htpasswd"
@@ -135,6 +136,8 @@ func setup(suite *TestSuite, tlsEnabled, insecure bool) {
 	config.HTTP.Addr = ln.Addr().String()
 	config.HTTP.DrainTimeout = time.Duration(10) * time.Second
 	config.Storage = map[string]configuration.Parameters{"inmemory": map[string]interface{}{}}
+	// The catalog endpoint lists no repository when not configured
+	config.Catalog.MaxEntries = 1000
 
 	config.Auth = configuration.Auth{
 		"htpasswd": configuration.Parameters{
@@ -372,6 +375,42 @@ func testPull(suite *TestSuite) {
 		string(result.Config.Data))
 	suite.Equal(chartData, result.Chart.Data)
 	suite.Equal(provData, result.Prov.Data)
+	suite.Nil(result.Values, "no values layer when pushed without separate layers")
+
+	// push with separate layers, then pull only the values
+	ref = fmt.Sprintf("%s/testrepo/layered/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+	_, err = suite.RegistryClient.Push(chartData, ref, PushOptSeparateLayers(true), PushOptCreationTime("1977-09-02T22:04:05Z"))
+	suite.Require().Nil(err, "no error pushing with separate layers")
+
+	result, err = suite.RegistryClient.Pull(ref, PullOptWithChart(false), PullOptWithValues(true), PullOptWithReadme(true))
+	suite.Require().Nil(err, "no error pulling the values layer")
+	suite.Nil(result.Chart.Data, "the chart layer is not pulled")
+	suite.Require().NotNil(result.Values)
+	suite.Equal("{}\n", string(result.Values.Data))
+	// Only the README of the top-level chart is pushed as a layer.
+	suite.Nil(result.Readme)
+
+	// the combined chart layer is still pushed
+	result, err = suite.RegistryClient.Pull(ref)
+	suite.Require().Nil(err, "no error pulling a chart pushed with separate layers")
+	suite.Equal(chartData, result.Chart.Data)
+
+	// the chart layer is streamed and verified
+	desc, rc, err := suite.RegistryClient.PullStream(ref)
+	suite.Require().Nil(err, "no error streaming the chart layer")
+	streamed, err := io.ReadAll(rc)
+	suite.Nil(err, "no error reading the streamed chart layer")
+	suite.Nil(rc.Close())
+	suite.Equal(chartData, streamed)
+	suite.Equal(ChartLayerMediaType, desc.MediaType)
+	suite.Equal(int64(len(chartData)), desc.Size)
+
+	// the metadata is read from the config alone
+	result, err = suite.RegistryClient.PullMetadata(ref)
+	suite.Require().Nil(err, "no error pulling the metadata")
+	suite.Nil(result.Chart.Data, "the chart layer is not pulled")
+	suite.Equal("signtest", result.Chart.Meta.Name)
+	suite.Equal("0.1.0", result.Chart.Meta.Version)
 }
 
 func testTags(suite *TestSuite) {
@@ -387,3 +426,168 @@ func testTags(suite *TestSuite) {
 	suite.Nil(err, "no error retrieving tags")
 	suite.Equal(1, len(tags))
 }
+
+func testListCharts(suite *TestSuite) {
+	// The charts pushed in the previous tests
+	namespace := fmt.Sprintf("%s/testrepo", suite.DockerRegistryHost)
+	repositories, err := suite.RegistryClient.Repositories("oci://" + namespace)
+	suite.Require().Nil(err, "no error listing the repositories")
+	suite.Contains(repositories, namespace+"/local-subchart")
+	suite.Contains(repositories, namespace+"/signtest")
+
+	listings, err := suite.RegistryClient.ListCharts(namespace)
+	suite.Require().Nil(err, "no error listing the charts")
+	names := map[string]string{}
+	for _, l := range listings {
+		names[l.Metadata.Name] = l.Ref
+	}
+	suite.Equal(namespace+"/signtest:0.1.0", names["signtest"])
+	suite.Equal(namespace+"/local-subchart:0.1.0", names["local-subchart"])
+
+	constraint, err := semver.NewConstraint(">1.0.0")
+	suite.Require().Nil(err)
+	listings, err = suite.RegistryClient.ListCharts(namespace, ListChartsOptVersion(constraint))
+	suite.Nil(err, "no error listing the charts")
+	suite.Empty(listings, "no chart matches the constraint")
+
+	repositories, err = suite.RegistryClient.Repositories(suite.DockerRegistryHost + "/no-existy")
+	suite.Nil(err, "no error listing an empty namespace")
+	suite.Empty(repositories)
+}
+
+func testReferrers(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/signtest-0.1.0.tgz")
+	suite.Require().Nil(err, "no error loading test chart")
+	provData, err := os.ReadFile("../downloader/testdata/signtest-0.1.0.tgz.prov")
+	suite.Require().Nil(err, "no error loading test prov")
+	sbom := []byte(`{"spdxVersion":"SPDX-2.3","name":"signtest"}`)
+	sbomType, err := SBOMArtifactType(sbom)
+	suite.Require().Nil(err, "no error detecting the SBOM format")
+	suite.Equal(SPDXArtifactType, sbomType)
+
+	// push with the prov and an SBOM attached as referrers
+	ref := fmt.Sprintf("%s/testrepo/referrers/signtest:0.1.0", suite.DockerRegistryHost)
+	result, err := suite.RegistryClient.Push(chartData, ref,
+		PushOptProvData(provData),
+		PushOptProvReferrer(true),
+		PushOptAttachments(Attachment{ArtifactType: sbomType, Name: "signtest.spdx.json", Data: sbom}),
+		PushOptCreationTime("1977-09-02T22:04:05Z"))
+	suite.Require().Nil(err, "no error pushing with referrers")
+	suite.Len(result.Referrers, 2)
+	suite.Equal(int64(695), result.Prov.Size)
+
+	// the prov is not a layer of the chart, but is pulled from its referrer
+	pulled, err := suite.RegistryClient.Pull(ref, PullOptWithChart(false), PullOptWithProv(true))
+	suite.Require().Nil(err, "no error pulling the prov referrer")
+	suite.NotContains(string(pulled.Manifest.Data), ProvLayerMediaType)
+	suite.Equal(provData, pulled.Prov.Data)
+	suite.Equal(result.Prov.Digest, pulled.Prov.Digest)
+
+	referrers, err := suite.RegistryClient.Referrers(ref, "")
+	suite.Require().Nil(err, "no error listing the referrers")
+	suite.ElementsMatch(result.Referrers, referrers)
+
+	referrers, err = suite.RegistryClient.Referrers(ref, SPDXArtifactType)
+	suite.Require().Nil(err, "no error listing the SBOM referrers")
+	suite.Require().Len(referrers, 1)
+	suite.Equal("signtest.spdx.json", referrers[0].Name)
+	data, err := suite.RegistryClient.FetchReferrer(ref, referrers[0])
+	suite.Require().Nil(err, "no error fetching the SBOM")
+	suite.Equal(sbom, data)
+
+	// attach a signature to the pushed chart
+	attached, err := suite.RegistryClient.Attach(ref, Attachment{ArtifactType: SigstoreBundleArtifactType, Data: []byte(`{}`)})
+	suite.Require().Nil(err, "no error attaching a signature")
+	referrers, err = suite.RegistryClient.Referrers(ref, SigstoreBundleArtifactType)
+	suite.Require().Nil(err, "no error listing the signature referrers")
+	suite.Equal(attached, referrers)
+
+	// charts without referrers
+	ref = fmt.Sprintf("%s/testrepo/local-subchart:0.1.0", suite.DockerRegistryHost)
+	referrers, err = suite.RegistryClient.Referrers(ref, "")
+	suite.Nil(err, "no error listing the referrers of a chart without any")
+	suite.Empty(referrers)
+
+	_, err = SBOMArtifactType([]byte(`{"name":"unknown"}`))
+	suite.NotNil(err, "error detecting the format of an unknown SBOM")
+}
+
+func testChunkedPush(suite *TestSuite) {
+	chartData, err := os.ReadFile("../repo/v1/repotest/testdata/examplechart-0.1.0.tgz")
+	suite.Require().Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Require().Nil(err, "no error extracting chart meta")
+
+	// push the chart layer in chunks of 256 bytes
+	var progress []PushProgress
+	ref := fmt.Sprintf("%s/testrepo/chunked/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+	result, err := suite.RegistryClient.Push(chartData, ref,
+		PushOptChunkSize(256),
+		PushOptProgress(func(p PushProgress) { progress = append(progress, p) }))
+	suite.Require().Nil(err, "no error pushing in chunks")
+
+	var chunks []int64
+	for _, p := range progress {
+		if p.Digest == result.Chart.Digest {
+			chunks = append(chunks, p.Uploaded)
+		}
+	}
+	suite.Require().Len(chunks, int((result.Chart.Size+255)/256))
+	suite.Equal(int64(256), chunks[0])
+	suite.Equal(result.Chart.Size, chunks[len(chunks)-1])
+	suite.Equal(result.Manifest.Digest, progress[len(progress)-1].Digest, "the manifest is uploaded last")
+
+	pulled, err := suite.RegistryClient.Pull(ref)
+	suite.Require().Nil(err, "no error pulling the chart pushed in chunks")
+	suite.Equal(chartData, pulled.Chart.Data)
+
+	// the chart layer already in the registry is not uploaded again
+	progress = nil
+	_, err = suite.RegistryClient.Push(chartData, ref, PushOptChunkSize(256),
+		PushOptProgress(func(p PushProgress) { progress = append(progress, p) }))
+	suite.Require().Nil(err, "no error pushing the chart again")
+	suite.Equal(PushProgress{
+		Digest:    result.Chart.Digest,
+		MediaType: ChartLayerMediaType,
+		Uploaded:  result.Chart.Size,
+		Size:      result.Chart.Size,
+	}, progress[0])
+}
+
+func testMirrors(suite *TestSuite) {
+	// registry.invalid is mirrored by a mirror which is down, and then by
+	// the test registry under the testrepo namespace.
+	registriesFile := filepath.Join(suite.WorkspaceDir, RegistriesFileBasename)
+	err := os.WriteFile(registriesFile, fmt.Appendf(nil, `registries:
+  - host: registry.invalid
+    mirrors:
+      - host: 127.0.0.1:1
+        plainHTTP: true
+      - host: %s
+        prefix: testrepo
+        plainHTTP: true
+        username: %s
+        password: %s
+`, suite.DockerRegistryHost, testUsername, testPassword), 0644)
+	suite.Require().Nil(err, "no error writing the registries file")
+
+	client, err := NewClient(
+		ClientOptWriter(suite.Out),
+		ClientOptCredentialsFile(filepath.Join(suite.WorkspaceDir, CredentialsFileBasename)),
+		ClientOptRegistriesFile(registriesFile))
+	suite.Require().Nil(err, "no error creating a client with mirrors")
+
+	ref := "registry.invalid/local-subchart:0.1.0"
+	result, err := client.Pull(ref)
+	suite.Require().Nil(err, "no error pulling through the mirror")
+	suite.Equal("local-subchart", result.Chart.Meta.Name)
+	suite.Equal(ref, result.Ref)
+
+	tags, err := client.Tags("registry.invalid/local-subchart")
+	suite.Require().Nil(err, "no error listing the tags through the mirror")
+	suite.Contains(tags, "0.1.0")
+
+	// the pushes are not sent to the mirrors
+	_, err = client.Push(result.Chart.Data, ref)
+	suite.NotNil(err, "error pushing to the registry instead of its mirror")
+}
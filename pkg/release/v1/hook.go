@@ -18,6 +18,8 @@ package v1
 
 import (
 	"time"
+
+	"helm.sh/helm/v4/pkg/annotations"
 )
 
 // HookEvent specifies the hook event
@@ -34,6 +36,11 @@ const (
 	HookPreRollback  HookEvent = "pre-rollback"
 	HookPostRollback HookEvent = "post-rollback"
 	HookTest         HookEvent = "test"
+	// HookTestFixture hooks are the fixtures of the tests, such as the
+	// databases they use. They are created before the tests, and deleted after
+	// them, even when the tests fail or are interrupted. The hook-succeeded
+	// delete policy would delete them before the tests.
+	HookTestFixture HookEvent = "test-fixture"
 )
 
 func (x HookEvent) String() string { return string(x) }
@@ -62,16 +69,16 @@ const (
 func (x HookOutputLogPolicy) String() string { return string(x) }
 
 // HookAnnotation is the label name for a hook
-const HookAnnotation = "helm.sh/hook"
+const HookAnnotation = annotations.Hook
 
 // HookWeightAnnotation is the label name for a hook weight
-const HookWeightAnnotation = "helm.sh/hook-weight"
+const HookWeightAnnotation = annotations.HookWeight
 
 // HookDeleteAnnotation is the label name for the delete policy for a hook
-const HookDeleteAnnotation = "helm.sh/hook-delete-policy"
+const HookDeleteAnnotation = annotations.HookDeletePolicy
 
 // HookOutputLogAnnotation is the label name for the output log policy for a hook
-const HookOutputLogAnnotation = "helm.sh/hook-output-log-policy"
+const HookOutputLogAnnotation = annotations.HookOutputLogPolicy
 
 // Hook defines a hook object.
 type Hook struct {
@@ -102,6 +109,17 @@ type HookExecution struct {
 	CompletedAt time.Time `json:"completed_at,omitzero"`
 	// Phase indicates whether the hook completed successfully
 	Phase HookPhase `json:"phase"`
+	// Event is the event the hook last ran for.
+	Event HookEvent `json:"event,omitempty"`
+	// Message explains why the hook failed.
+	Message string `json:"message,omitempty"`
+	// ExitCode is the exit code of the container of the last pod run by the
+	// hook that terminated, with failures taking precedence. It is not set when
+	// the hook ran no pods or none of them terminated.
+	ExitCode *int32 `json:"exit_code,omitempty"`
+	// Retries is how many times the pods of the hook were retried, either by
+	// restarting their containers or by starting new pods.
+	Retries int `json:"retries,omitempty"`
 }
 
 // A HookPhase indicates the state of a hook execution
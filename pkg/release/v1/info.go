@@ -29,6 +29,11 @@ type Info struct {
 	LastDeployed time.Time `json:"last_deployed,omitzero"`
 	// Deleted tracks when this object was deleted.
 	Deleted time.Time `json:"deleted,omitzero"`
+	// DeployedBy identifies who deployed this revision, when known.
+	DeployedBy string `json:"deployed_by,omitempty"`
+	// DeployedAs is the Kubernetes identity this revision was deployed as,
+	// when Helm impersonated another user.
+	DeployedAs *Impersonation `json:"deployed_as,omitempty"`
 	// Description is human-friendly "log entry" about this release.
 	Description string `json:"description,omitempty"`
 	// Status is the current state of the release
@@ -37,4 +42,14 @@ type Info struct {
 	Notes string `json:"notes,omitempty"`
 	// Contains the deployed resources information
 	Resources map[string][]runtime.Object `json:"resources,omitempty"`
+	// ResourceStatuses contains the kstatus computed status of each deployed resource
+	ResourceStatuses []ResourceStatus `json:"resource_statuses,omitempty"`
+}
+
+// Impersonation is a Kubernetes identity Helm impersonated.
+type Impersonation struct {
+	// User is the impersonated user name.
+	User string `json:"user,omitempty"`
+	// Groups are the impersonated groups.
+	Groups []string `json:"groups,omitempty"`
 }
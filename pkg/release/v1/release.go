@@ -16,6 +16,7 @@ limitations under the License.
 package v1
 
 import (
+	"helm.sh/helm/v4/pkg/chart/common"
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 )
 
@@ -50,6 +51,53 @@ type Release struct {
 	// ApplyMethod stores whether server-side or client-side apply was used for the release
 	// Unset (empty string) should be treated as the default of client-side apply
 	ApplyMethod string `json:"apply_method,omitempty"` // "ssa" | "csa"
+	// ObjectLabels customizes the labels set on the objects of the release.
+	// It is nil when the objects carry the standard labels only.
+	ObjectLabels *ObjectLabels `json:"object_labels,omitempty"`
+	// IgnoreDifferences are the fields of the objects of the release left out
+	// of the diffs with their live state and with other revisions, such as the
+	// fields mutated by controllers.
+	IgnoreDifferences []IgnoreDifference `json:"ignore_differences,omitempty"`
+	// ValuesProvenance records where each effective value of the release
+	// comes from. It is only set on the results of dry runs, and is never
+	// stored.
+	ValuesProvenance []common.ValueOrigin `json:"values_provenance,omitempty"`
+}
+
+// ObjectLabels customizes the labels Helm sets on the objects of a release.
+type ObjectLabels struct {
+	// ManagedBy is the value of the app.kubernetes.io/managed-by label. It
+	// defaults to "Helm".
+	ManagedBy string `json:"managed_by,omitempty"`
+	// ReleaseNameKey is the key of a label set to the name of the release.
+	ReleaseNameKey string `json:"release_name_key,omitempty"`
+	// ReleaseNamespaceKey is the key of a label set to the namespace of the
+	// release.
+	ReleaseNamespaceKey string `json:"release_namespace_key,omitempty"`
+	// Extra are labels set on all the objects of the release.
+	Extra map[string]string `json:"extra,omitempty"`
+	// Annotations are annotations set on all the objects of the release.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Required are the keys of the labels all the objects of the release must
+	// have, whether set by their templates or by the release.
+	Required []string `json:"required,omitempty"`
+}
+
+// IgnoreDifference leaves fields of the objects of a release out of the diffs
+// of their manifests.
+type IgnoreDifference struct {
+	// Group, Kind, Name and Namespace select the objects the fields are left
+	// out of. Empty ones match any object.
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// JSONPointers are the paths of the fields as JSON pointers (RFC 6901),
+	// such as "/spec/replicas", in which "*" matches any field or list item.
+	JSONPointers []string `json:"json_pointers,omitempty"`
+	// JSONPaths are the paths of the fields as JSONPath expressions, such as
+	// ".spec.template.spec.containers[?(@.name=='istio-proxy')]".
+	JSONPaths []string `json:"json_paths,omitempty"`
 }
 
 // SetStatus is a helper for setting the status on a release.
@@ -0,0 +1,33 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// ResourceStatus describes the health of a single resource of a release as
+// computed by kstatus.
+type ResourceStatus struct {
+	// APIVersion is the API version of the resource.
+	APIVersion string `json:"apiVersion,omitempty"`
+	// Kind is the kind of the resource.
+	Kind string `json:"kind,omitempty"`
+	// Namespace is the namespace of the resource.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the resource.
+	Name string `json:"name,omitempty"`
+	// Status is one of Current, InProgress, Failed, Terminating, NotFound or Unknown.
+	Status string `json:"status,omitempty"`
+	// Message explains the status.
+	Message string `json:"message,omitempty"`
+}
@@ -26,6 +26,7 @@ import (
 
 	"sigs.k8s.io/yaml"
 
+	"helm.sh/helm/v4/pkg/annotations"
 	"helm.sh/helm/v4/pkg/chart/common"
 	release "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -47,6 +48,7 @@ type manifestFile struct {
 type result struct {
 	hooks   []*release.Hook
 	generic []Manifest
+	skipped []Manifest
 }
 
 // TODO: Refactor this out. It's here because naming conventions were not followed through.
@@ -61,6 +63,7 @@ var events = map[string]release.HookEvent{
 	release.HookPreRollback.String():  release.HookPreRollback,
 	release.HookPostRollback.String(): release.HookPostRollback,
 	release.HookTest.String():         release.HookTest,
+	release.HookTestFixture.String():  release.HookTestFixture,
 	// Support test-success for backward compatibility with Helm 2 tests
 	"test-success": release.HookTest,
 }
@@ -74,7 +77,17 @@ var events = map[string]release.HookEvent{
 //
 // Files that do not parse into the expected format are simply placed into a map and
 // returned.
-func SortManifests(files map[string]string, _ common.VersionSet, ordering KindSortOrder) ([]*release.Hook, []Manifest, error) {
+func SortManifests(files map[string]string, versions common.VersionSet, ordering KindSortOrder) ([]*release.Hook, []Manifest, error) {
+	hooks, manifests, skipped, err := SortManifestsWithSkipped(files, versions, ordering)
+	for _, m := range skipped {
+		slog.Info("skipping unknown hooks", "hookTypes", m.Head.Metadata.Annotations[release.HookAnnotation])
+	}
+	return hooks, manifests, err
+}
+
+// SortManifestsWithSkipped is SortManifests also returning the manifests it
+// skips instead of logging them, which are the hooks of unknown types.
+func SortManifestsWithSkipped(files map[string]string, _ common.VersionSet, ordering KindSortOrder) ([]*release.Hook, []Manifest, []Manifest, error) {
 	result := &result{}
 
 	var sortedFilePaths []string
@@ -102,11 +115,11 @@ func SortManifests(files map[string]string, _ common.VersionSet, ordering KindSo
 		}
 
 		if err := manifestFile.sort(result); err != nil {
-			return result.hooks, result.generic, err
+			return result.hooks, result.generic, result.skipped, err
 		}
 	}
 
-	return sortHooksByKind(result.hooks, ordering), sortManifestsByKind(result.generic, ordering), nil
+	return sortHooksByKind(result.hooks, ordering), sortManifestsByKind(result.generic, ordering), result.skipped, nil
 }
 
 // sort takes a manifestFile object which may contain multiple resource definition
@@ -185,8 +198,7 @@ func (file *manifestFile) sort(result *result) error {
 		}
 
 		isUnknownHook := false
-		for hookType := range strings.SplitSeq(hookTypes, ",") {
-			hookType = strings.ToLower(strings.TrimSpace(hookType))
+		for _, hookType := range annotations.ParseList(hookTypes) {
 			e, ok := events[hookType]
 			if !ok {
 				isUnknownHook = true
@@ -196,7 +208,11 @@ func (file *manifestFile) sort(result *result) error {
 		}
 
 		if isUnknownHook {
-			slog.Info("skipping unknown hooks", "hookTypes", hookTypes)
+			result.skipped = append(result.skipped, Manifest{
+				Name:    file.path,
+				Content: m,
+				Head:    &entry,
+			})
 			continue
 		}
 
@@ -236,8 +252,7 @@ func calculateHookWeight(entry SimpleHead) int {
 // operateAnnotationValues finds the given annotation and runs the operate function with the value of that annotation
 func operateAnnotationValues(entry SimpleHead, annotation string, operate func(p string)) {
 	if dps, ok := entry.Metadata.Annotations[annotation]; ok {
-		for dp := range strings.SplitSeq(dps, ",") {
-			dp = strings.ToLower(strings.TrimSpace(dp))
+		for _, dp := range annotations.ParseList(dps) {
 			operate(dp)
 		}
 	}
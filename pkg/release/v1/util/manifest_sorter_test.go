@@ -225,3 +225,36 @@ metadata:
 		}
 	}
 }
+
+func TestSortManifestsWithSkipped(t *testing.T) {
+	files := map[string]string{
+		"templates/known": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: known
+  annotations:
+    "helm.sh/hook": pre-install
+`,
+		"templates/unknown": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unknown
+  annotations:
+    "helm.sh/hook": pre-install,pre-everything
+`,
+	}
+
+	hooks, manifests, skipped, err := SortManifestsWithSkipped(files, nil, InstallOrder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].Name != "known" {
+		t.Errorf("expected only the known hook, got %v", hooks)
+	}
+	if len(manifests) != 0 {
+		t.Errorf("expected no manifests, got %v", manifests)
+	}
+	if len(skipped) != 1 || skipped[0].Name != "templates/unknown" || skipped[0].Head.Metadata.Name != "unknown" {
+		t.Errorf("expected the unknown hook to be skipped, got %v", skipped)
+	}
+}
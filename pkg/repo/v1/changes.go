@@ -0,0 +1,131 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"os"
+
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+// ChangesSinceParam is the query parameter of the requests for the changelog
+// of a repository that holds the token to get the changes since.
+const ChangesSinceParam = "since"
+
+// IndexChangelog references the append-only changelog of a repository, with
+// which clients update their copy of the index with the chart versions added
+// since they downloaded it, instead of downloading the whole index again.
+//
+// The changes since a token are requested with the token in the
+// ChangesSinceParam query parameter. They are served as an index of the chart
+// versions added since the token, whose changelog token is the one of the
+// index with these versions. Repositories fail the requests for the tokens
+// they no longer have the changes since, and clients then download the whole
+// index.
+type IndexChangelog struct {
+	// URL is the URL of the changelog. It is relative to the URL of the
+	// repository unless it is absolute.
+	URL string `json:"url"`
+	// Token identifies the state of the index in the changelog.
+	Token string `json:"token"`
+}
+
+// indexState is the state of the cached index of a repository, with which it
+// is updated incrementally.
+type indexState struct {
+	// URL is the URL the index was downloaded from.
+	URL string `json:"url"`
+	// Validators are the validators of the index when it was last
+	// downloaded. They are not set when it was updated from the changelog.
+	getter.Validators
+}
+
+// loadIndexState returns the state of the cached index, and whether the
+// cached index is the one of the given URL. The state is empty when it is not.
+func loadIndexState(stateFile, indexFile, indexURL string) (indexState, bool) {
+	if _, err := os.Stat(indexFile); err != nil {
+		return indexState{}, false
+	}
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		return indexState{}, false
+	}
+	var state indexState
+	if err := json.Unmarshal(data, &state); err != nil || state.URL != indexURL {
+		return indexState{}, false
+	}
+	return state, true
+}
+
+// writeIndexState writes the state of the cached index. The state is removed
+// when it cannot be written, for the index not to be mistaken for another one.
+func writeIndexState(stateFile string, state indexState) {
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = os.WriteFile(stateFile, data, 0644)
+	}
+	if err != nil {
+		slog.Debug("unable to write the state of the cached index", slog.String("file", stateFile), slog.Any("error", err))
+		os.Remove(stateFile)
+	}
+}
+
+// updateFromChangelog adds the chart versions added to the repository since
+// the cached index was downloaded to it, when the repository has a changelog.
+// It returns the updated index, or false when it could not be updated from
+// the changelog and has to be downloaded again.
+func (r *ChartRepository) updateFromChangelog(fname, baseURL string, options ...getter.Option) (*IndexFile, bool) {
+	cached, err := LoadIndexFile(fname)
+	if err != nil || cached.Changes == nil || len(cached.Shards) > 0 {
+		return nil, false
+	}
+
+	changesURL, err := ResolveReferenceURL(baseURL, cached.Changes.URL)
+	if err != nil {
+		return nil, false
+	}
+	u, err := url.Parse(changesURL)
+	if err != nil {
+		return nil, false
+	}
+	q := u.Query()
+	q.Set(ChangesSinceParam, cached.Changes.Token)
+	u.RawQuery = q.Encode()
+
+	resp, err := r.Client.Get(u.String(), r.getterOptions(options...)...)
+	if err != nil {
+		slog.Debug("unable to get the changes of the index, downloading it again", slog.String("url", changesURL), slog.Any("error", err))
+		return nil, false
+	}
+	changes, err := loadIndex(resp.Bytes(), changesURL)
+	if err != nil || changes.Changes == nil {
+		slog.Debug("invalid changes of the index, downloading it again", slog.String("url", changesURL), slog.Any("error", err))
+		return nil, false
+	}
+
+	cached.Merge(changes)
+	cached.SortEntries()
+	cached.Generated = changes.Generated
+	cached.Changes.Token = changes.Changes.Token
+	if err := cached.WriteFile(fname, 0644); err != nil {
+		return nil, false
+	}
+	return cached, true
+}
@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/helmpath"
+)
+
+func TestDownloadIndexFileIncrementally(t *testing.T) {
+	index, err := LoadIndexFile("testdata/local-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Changes = &IndexChangelog{URL: "changes", Token: "1"}
+	indexData, err := yaml.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := NewIndexFile()
+	if err := changes.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "nginx", Version: "0.3.0"}, "nginx-0.3.0.tgz", "https://charts.helm.sh/stable", "sha256:1234567890abcdef"); err != nil {
+		t.Fatal(err)
+	}
+	changes.Changes = &IndexChangelog{URL: "changes", Token: "2"}
+	changesData, err := yaml.Marshal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var indexRequests, notModified int
+	var since []string
+	gone := false
+	srv, err := startLocalServerForTests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			indexRequests++
+			if r.Header.Get("If-None-Match") == `"index"` {
+				notModified++
+			}
+			w.Header().Set("ETag", `"index"`)
+			http.ServeContent(w, r, "index.yaml", time.Time{}, bytes.NewReader(indexData))
+		case "/changes":
+			since = append(since, r.URL.Query().Get(ChangesSinceParam))
+			if gone {
+				http.Error(w, "unknown token", http.StatusGone)
+				return
+			}
+			w.Write(changesData)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	r, err := NewChartRepository(&Entry{Name: "incremental", URL: srv.URL}, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The versions added since the index was downloaded are fetched from the
+	// changelog.
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexRequests != 1 || len(since) != 1 || since[0] != "1" {
+		t.Fatalf("expected the changes since token 1 to be requested instead of the index, got %d index requests and tokens %v", indexRequests, since)
+	}
+	updated, err := LoadIndexFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Has("nginx", "0.3.0") || !updated.Has("nginx", "0.1.0") {
+		t.Errorf("expected the changes to be added to the cached index, got %v", updated.Entries["nginx"])
+	}
+	if updated.Changes.Token != "2" {
+		t.Errorf("expected the token of the changes, got %q", updated.Changes.Token)
+	}
+
+	// The index is downloaded again when the changes are no longer available,
+	// and only when it changed afterwards.
+	gone = true
+	if idx, err = r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if indexRequests != 2 || notModified != 0 {
+		t.Fatalf("expected the index to be downloaded again, got %d requests and %d conditional ones", indexRequests, notModified)
+	}
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if indexRequests != 3 || notModified != 1 {
+		t.Fatalf("expected a conditional request for the index, got %d requests and %d conditional ones", indexRequests, notModified)
+	}
+	cached, err := os.ReadFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cached, indexData) {
+		t.Error("expected the cached index to be kept when it did not change")
+	}
+
+	// The state of the index is not used for other repositories with the
+	// same name.
+	r.Config.URL = srv.URL + "/other"
+	if _, err := r.DownloadIndexFile(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the index of the other repository to be requested, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.CachePath, helmpath.CacheIndexStateFile("incremental"))); err != nil {
+		t.Errorf("expected the state of the index to be kept, got %v", err)
+	}
+}
@@ -17,16 +17,18 @@ limitations under the License.
 package repo // import "helm.sh/helm/v4/pkg/repo/v1"
 
 import (
+	"bytes"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"helm.sh/helm/v4/pkg/getter"
 	"helm.sh/helm/v4/pkg/helmpath"
@@ -43,6 +45,21 @@ type Entry struct {
 	CAFile                string `json:"caFile"`
 	InsecureSkipTLSverify bool   `json:"insecure_skip_tls_verify"`
 	PassCredentialsAll    bool   `json:"pass_credentials_all"`
+	// Proxy is the URL of the proxy the repository is reached through,
+	// instead of the one set in the environment.
+	Proxy string `json:"proxy,omitempty"`
+	// MinTLSVersion is the minimum TLS version, such as "1.2", accepted from
+	// the repository.
+	MinTLSVersion string `json:"minTLSVersion,omitempty"`
+	// Mirrors are URLs serving the same charts as URL, which the index and
+	// the charts are downloaded from when URL fails with a network error.
+	Mirrors []string `json:"mirrors,omitempty"`
+	// MirrorOrder is the order URL and the mirrors are tried in:
+	// MirrorOrderListed, the default, or MirrorOrderLatency.
+	MirrorOrder string `json:"mirrorOrder,omitempty"`
+	// CredentialHelper names the credential helper keeping the username and
+	// password of the repository, instead of this entry. See CredentialStore.
+	CredentialHelper string `json:"credentialHelper,omitempty"`
 }
 
 // ChartRepository represents a chart repository
@@ -51,6 +68,16 @@ type ChartRepository struct {
 	IndexFile *IndexFile
 	Client    getter.Getter
 	CachePath string
+
+	// urls are the URL and the mirrors of the repository sorted by latency,
+	// probed once.
+	urlsOnce sync.Once
+	urls     []string
+
+	// username and password are the credentials of the repository, looked
+	// up once.
+	credentialsOnce    sync.Once
+	username, password string
 }
 
 // NewChartRepository constructs ChartRepository
@@ -74,46 +101,121 @@ func NewChartRepository(cfg *Entry, getters getter.Providers) (*ChartRepository,
 }
 
 // DownloadIndexFile fetches the index from a repository.
+//
+// When the index of the repository is already cached, only the chart versions
+// added since it was downloaded are fetched if the repository has a
+// changelog, and the index is not downloaded again if it did not change.
 func (r *ChartRepository) DownloadIndexFile() (string, error) {
-	indexURL, err := ResolveReferenceURL(r.Config.URL, "index.yaml")
+	return r.downloadIndexFile()
+}
+
+// downloadIndexFile fetches the index from a repository, with additional
+// options for the getter.
+func (r *ChartRepository) downloadIndexFile(options ...getter.Option) (string, error) {
+	var fname string
+	err := r.failover(func(baseURL string) error {
+		var err error
+		fname, err = r.downloadIndexFileFrom(baseURL, options...)
+		return err
+	})
+	return fname, err
+}
+
+// downloadIndexFileFrom fetches the index from the URL or a mirror of a
+// repository.
+func (r *ChartRepository) downloadIndexFileFrom(baseURL string, options ...getter.Option) (string, error) {
+	options = append([]getter.Option{getter.WithURL(baseURL)}, options...)
+	indexURL, err := ResolveReferenceURL(baseURL, "index.yaml")
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := r.Client.Get(indexURL,
-		getter.WithURL(r.Config.URL),
-		getter.WithInsecureSkipVerifyTLS(r.Config.InsecureSkipTLSverify),
-		getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile),
-		getter.WithBasicAuth(r.Config.Username, r.Config.Password),
-		getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
-	)
+	fname := filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name))
+	stateFile := filepath.Join(r.CachePath, helmpath.CacheIndexStateFile(r.Config.Name))
+	state, cached := loadIndexState(stateFile, fname, indexURL)
+	if cached {
+		if indexFile, ok := r.updateFromChangelog(fname, baseURL, options...); ok {
+			r.writeChartsFile(indexFile)
+			writeIndexState(stateFile, indexState{URL: indexURL})
+			return fname, nil
+		}
+	}
+
+	resp, err := r.Client.Get(indexURL, append(r.getterOptions(options...), getter.WithValidators(&state.Validators))...)
+	if cached && errors.Is(err, getter.ErrNotModified) {
+		return fname, nil
+	}
 	if err != nil {
 		return "", err
 	}
 
-	index, err := io.ReadAll(resp)
+	index := resp.Bytes()
+
+	// The index is validated one chart version at a time, only keeping the
+	// names of the charts, for large indexes not to be loaded in memory.
+	entries := map[string]ChartVersions{}
+	indexFile, err := StreamIndex(bytes.NewReader(index), baseURL, func(name string, _ *ChartVersion) error {
+		entries[name] = nil
+		return nil
+	})
 	if err != nil {
 		return "", err
 	}
+	indexFile.Entries = entries
 
-	indexFile, err := loadIndex(index, r.Config.URL)
-	if err != nil {
+	r.writeChartsFile(indexFile)
+
+	// Create the index file in the cache directory
+	os.MkdirAll(filepath.Dir(fname), 0755)
+	if err := os.WriteFile(fname, index, 0644); err != nil {
 		return "", err
 	}
+	state.URL = indexURL
+	writeIndexState(stateFile, state)
+	return fname, nil
+}
 
-	// Create the chart list file in the cache directory
+// writeChartsFile creates the chart list file of the index in the cache
+// directory.
+func (r *ChartRepository) writeChartsFile(indexFile *IndexFile) {
 	var charts strings.Builder
 	for name := range indexFile.Entries {
 		fmt.Fprintln(&charts, name)
 	}
+	for name := range indexFile.Shards {
+		if _, ok := indexFile.Entries[name]; !ok {
+			fmt.Fprintln(&charts, name)
+		}
+	}
 	chartsFile := filepath.Join(r.CachePath, helmpath.CacheChartsFile(r.Config.Name))
 	os.MkdirAll(filepath.Dir(chartsFile), 0755)
 	os.WriteFile(chartsFile, []byte(charts.String()), 0644)
+}
 
-	// Create the index file in the cache directory
-	fname := filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name))
-	os.MkdirAll(filepath.Dir(fname), 0755)
-	return fname, os.WriteFile(fname, index, 0644)
+// getterOptions returns the options of the getter downloading the files of
+// the repository, followed by the given ones.
+func (r *ChartRepository) getterOptions(options ...getter.Option) []getter.Option {
+	return append([]getter.Option{
+		getter.WithURL(r.Config.URL),
+		getter.WithInsecureSkipVerifyTLS(r.Config.InsecureSkipTLSverify),
+		getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile),
+		getter.WithBasicAuth(r.credentials()),
+		getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
+		getter.WithProxy(r.Config.Proxy),
+		getter.WithMinTLSVersion(r.Config.MinTLSVersion),
+	}, options...)
+}
+
+// credentials returns the username and password of the repository. When they
+// cannot be looked up, the repository is accessed without them.
+func (r *ChartRepository) credentials() (string, string) {
+	r.credentialsOnce.Do(func() {
+		var err error
+		if r.username, r.password, err = r.Config.Credentials(); err != nil {
+			slog.Warn("accessing the repository without credentials", "repo", r.Config.Name, slog.Any("error", err))
+		}
+	})
+	return r.username, r.password
 }
 
 type findChartInRepoURLOptions struct {
@@ -203,6 +305,7 @@ func FindChartInRepoURL(repoURL string, chartName string, getters getter.Provide
 	defer func() {
 		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheChartsFile(r.Config.Name)))
 		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name)))
+		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheIndexStateFile(r.Config.Name)))
 	}()
 
 	// Read the index file for the repository to get chart information and return chart URL
@@ -210,6 +313,10 @@ func FindChartInRepoURL(repoURL string, chartName string, getters getter.Provide
 	if err != nil {
 		return "", err
 	}
+	r.IndexFile = repoIndex
+	if err := r.LoadShard(chartName); err != nil {
+		return "", err
+	}
 
 	errMsg := fmt.Sprintf("chart %q", chartName)
 	if opts.ChartVersion != "" {
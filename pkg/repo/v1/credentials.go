@@ -0,0 +1,103 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// CredentialHelperKeychain selects the credential helper of the keychain of
+// the operating system: osxkeychain on macOS, wincred on Windows, and pass or
+// secretservice on Linux.
+const CredentialHelperKeychain = "keychain"
+
+// newCredentialStore returns the store of the credentials of repositories
+// kept by a credential helper. It is replaced in tests.
+var newCredentialStore = func(helper string) (credentials.Store, error) {
+	if helper == CredentialHelperKeychain {
+		store, ok := credentials.NewDefaultNativeStore()
+		if !ok {
+			return nil, errors.New("no keychain credential helper is available on this system")
+		}
+		return store, nil
+	}
+	return credentials.NewNativeStore(helper), nil
+}
+
+// CredentialStore returns the store keeping the username and password of the
+// repository, instead of the repositories file, when the entry names a
+// credential helper: CredentialHelperKeychain, or the suffix of any
+// docker-credential-<suffix> program following the Docker credential helper
+// protocol. It returns nil when the credentials are kept in the entry.
+func (e *Entry) CredentialStore() (credentials.Store, error) {
+	if e.CredentialHelper == "" {
+		return nil, nil
+	}
+	return newCredentialStore(e.CredentialHelper)
+}
+
+// Credentials returns the username and password to authenticate to the
+// repository with, either kept in the entry or looked up by URL with its
+// credential helper.
+func (e *Entry) Credentials() (username, password string, err error) {
+	store, err := e.CredentialStore()
+	if err != nil || store == nil {
+		return e.Username, e.Password, err
+	}
+	cred, err := store.Get(context.Background(), e.URL)
+	if err != nil {
+		return "", "", fmt.Errorf("unable to get the credentials of repository %q from credential helper %q: %w", e.Name, e.CredentialHelper, err)
+	}
+	return cred.Username, cred.Password, nil
+}
+
+// StoreCredentials saves the username and password of the repository with
+// its credential helper, and clears them from the entry, so that they are not
+// written to the repositories file.
+func (e *Entry) StoreCredentials() error {
+	store, err := e.CredentialStore()
+	if err != nil || store == nil {
+		return err
+	}
+	if e.Username == "" && e.Password == "" {
+		return nil
+	}
+	cred := auth.Credential{Username: e.Username, Password: e.Password}
+	if err := store.Put(context.Background(), e.URL, cred); err != nil {
+		return fmt.Errorf("unable to store the credentials of repository %q with credential helper %q: %w", e.Name, e.CredentialHelper, err)
+	}
+	e.Username, e.Password = "", ""
+	return nil
+}
+
+// DeleteCredentials removes the credentials of the repository from its
+// credential helper, if it has one.
+func (e *Entry) DeleteCredentials() error {
+	store, err := e.CredentialStore()
+	if err != nil || store == nil {
+		return err
+	}
+	if err := store.Delete(context.Background(), e.URL); err != nil {
+		return fmt.Errorf("unable to delete the credentials of repository %q from credential helper %q: %w", e.Name, e.CredentialHelper, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"oras.land/oras-go/v2/registry/remote/credentials"
+
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+func useMemoryCredentialStore(t *testing.T) credentials.Store {
+	t.Helper()
+	store := credentials.NewMemoryStore()
+	orig := newCredentialStore
+	newCredentialStore = func(helper string) (credentials.Store, error) {
+		if helper != "test" {
+			t.Errorf("expected the test credential helper, got %q", helper)
+		}
+		return store, nil
+	}
+	t.Cleanup(func() { newCredentialStore = orig })
+	return store
+}
+
+func TestEntryCredentials(t *testing.T) {
+	store := useMemoryCredentialStore(t)
+
+	// Without a credential helper the credentials are kept in the entry.
+	plain := &Entry{Name: "plain", URL: "https://charts.example.com", Username: "user", Password: "pass"}
+	if err := plain.StoreCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if username, password, err := plain.Credentials(); err != nil || username != "user" || password != "pass" {
+		t.Errorf("expected the credentials of the entry, got %q, %q, %v", username, password, err)
+	}
+
+	e := &Entry{Name: "helper", URL: "https://charts.example.com", Username: "user", Password: "pass", CredentialHelper: "test"}
+	if err := e.StoreCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Username != "" || e.Password != "" {
+		t.Errorf("expected the credentials to be cleared from the entry, got %q, %q", e.Username, e.Password)
+	}
+	if cred, err := store.Get(context.Background(), e.URL); err != nil || cred.Username != "user" || cred.Password != "pass" {
+		t.Errorf("expected the credentials to be stored, got %+v, %v", cred, err)
+	}
+	if username, password, err := e.Credentials(); err != nil || username != "user" || password != "pass" {
+		t.Errorf("expected the stored credentials, got %q, %q, %v", username, password, err)
+	}
+
+	if err := e.DeleteCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if username, password, err := e.Credentials(); err != nil || username != "" || password != "" {
+		t.Errorf("expected the credentials to be deleted, got %q, %q, %v", username, password, err)
+	}
+}
+
+func TestDownloadIndexFileWithCredentialHelper(t *testing.T) {
+	store := useMemoryCredentialStore(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, "testdata/local-index.yaml")
+	}))
+	defer srv.Close()
+
+	e := &Entry{Name: "helper", URL: srv.URL, Username: "user", Password: "pass", CredentialHelper: "test"}
+	if err := e.StoreCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewChartRepository(e, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatalf("expected the index to be downloaded with the stored credentials, got %v", err)
+	}
+
+	if err := store.Delete(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	r, err = NewChartRepository(e, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	if _, err := r.DownloadIndexFile(); err == nil {
+		t.Error("expected the download to fail without credentials")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -89,6 +90,21 @@ type IndexFile struct {
 	// Annotations are additional mappings uninterpreted by Helm. They are made available for
 	// other applications to add information to the index file.
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Channels maps chart names to the release channels of the chart, each
+	// mapped to a semantic version range. Dependencies track a channel with
+	// their channel field or with a version alias such as "stable".
+	Channels map[string]map[string]string `json:"channels,omitempty"`
+
+	// Shards maps chart names to the indexes of their versions in a sharded
+	// index, see WriteShardedFile. The versions of the sharded charts are
+	// only in Entries once loaded with ChartRepository.LoadShard.
+	Shards map[string]IndexShard `json:"shards,omitempty"`
+
+	// Changes references the changelog of the repository, with which the
+	// index is updated with the chart versions added since it was
+	// downloaded.
+	Changes *IndexChangelog `json:"changes,omitempty"`
 }
 
 // NewIndexFile initializes an index.
@@ -270,6 +286,14 @@ type ChartVersion struct {
 	Created time.Time `json:"created,omitempty"`
 	Removed bool      `json:"removed,omitempty"`
 	Digest  string    `json:"digest,omitempty"`
+	// Provenance is the URL of the provenance file of the chart. It is only
+	// set when the chart is signed.
+	Provenance string `json:"provenance,omitempty"`
+	// Digests are the digests of the artifacts of the chart, keyed by
+	// artifact (see DigestChart and DigestProvenance), in the
+	// "algorithm:hex" form. Clients ignore the artifacts and algorithms they
+	// do not know of.
+	Digests map[string]string `json:"digests,omitempty"`
 
 	// ChecksumDeprecated is deprecated in Helm 3, and therefore ignored. Helm 3 replaced
 	// this with Digest. However, with a strict YAML parser enabled, a field must be
@@ -289,9 +313,23 @@ type ChartVersion struct {
 	URLDeprecated string `json:"url,omitempty"`
 }
 
+// The artifacts of a chart whose digests are recorded in ChartVersion.Digests.
+const (
+	// DigestChart is the chart archive.
+	DigestChart = "chart"
+	// DigestProvenance is the provenance file of the chart.
+	DigestProvenance = "provenance"
+)
+
+// Signed reports whether the chart has a provenance file.
+func (c *ChartVersion) Signed() bool {
+	return c.Provenance != ""
+}
+
 // IndexDirectory reads a (flat) directory and generates an index.
 //
-// It indexes only charts that have been packaged (*.tgz).
+// It indexes only charts that have been packaged (*.tgz). The provenance
+// files next to the charts (*.tgz.prov) are referenced by their entries.
 //
 // The index returned will be in an unsorted state
 func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
@@ -333,10 +371,31 @@ func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
 		if err := index.MustAdd(c.Metadata, fname, parentURL, hash); err != nil {
 			return index, fmt.Errorf("failed adding to %s to index: %w", fname, err)
 		}
+		cvs := index.Entries[c.Name()]
+		if err := addProvenance(cvs[len(cvs)-1], arch); err != nil {
+			return index, err
+		}
 	}
 	return index, nil
 }
 
+// addProvenance records the digests of the chart archive arch in its entry,
+// and references its provenance file when there is one.
+func addProvenance(cv *ChartVersion, arch string) error {
+	cv.Digests = map[string]string{DigestChart: "sha256:" + cv.Digest}
+	prov := arch + ".prov"
+	if _, err := os.Stat(prov); err != nil {
+		return nil
+	}
+	hash, err := provenance.DigestFile(prov)
+	if err != nil {
+		return err
+	}
+	cv.Digests[DigestProvenance] = "sha256:" + hash
+	cv.Provenance = cv.URLs[0] + ".prov"
+	return nil
+}
+
 // loadIndex loads an index file and does minimal validity checking.
 //
 // The source parameter is only used for logging.
@@ -353,26 +412,10 @@ func loadIndex(data []byte, source string) (*IndexFile, error) {
 	}
 
 	for name, cvs := range i.Entries {
-		for idx := len(cvs) - 1; idx >= 0; idx-- {
-			if cvs[idx] == nil {
-				slog.Warn(fmt.Sprintf("skipping loading invalid entry for chart %q from %s: empty entry", name, source))
-				cvs = append(cvs[:idx], cvs[idx+1:]...)
-				continue
-			}
-			// When metadata section missing, initialize with no data
-			if cvs[idx].Metadata == nil {
-				cvs[idx].Metadata = &chart.Metadata{}
-			}
-			if cvs[idx].APIVersion == "" {
-				cvs[idx].APIVersion = chart.APIVersionV1
-			}
-			if err := cvs[idx].Validate(); ignoreSkippableChartValidationError(err) != nil {
-				slog.Warn(fmt.Sprintf("skipping loading invalid entry for chart %q %q from %s: %s", name, cvs[idx].Version, source, err))
-				cvs = append(cvs[:idx], cvs[idx+1:]...)
-			}
-		}
 		// adjust slice to only contain a set of valid versions
-		i.Entries[name] = cvs
+		i.Entries[name] = slices.DeleteFunc(cvs, func(cv *ChartVersion) bool {
+			return !validEntry(name, cv, source)
+		})
 	}
 	i.SortEntries()
 	if i.APIVersion == "" {
@@ -388,6 +431,27 @@ func loadIndex(data []byte, source string) (*IndexFile, error) {
 // checking its validity as JSON. If the data is valid JSON, it will use the
 // `encoding/json` package to unmarshal it. Otherwise, it will use the
 // `sigs.k8s.io/yaml` package to unmarshal the YAML data.
+// validEntry reports whether a version of a chart in an index is valid, and
+// logs why it is skipped when it is not. Missing metadata is initialized.
+func validEntry(name string, cv *ChartVersion, source string) bool {
+	if cv == nil {
+		slog.Warn(fmt.Sprintf("skipping loading invalid entry for chart %q from %s: empty entry", name, source))
+		return false
+	}
+	// When metadata section missing, initialize with no data
+	if cv.Metadata == nil {
+		cv.Metadata = &chart.Metadata{}
+	}
+	if cv.APIVersion == "" {
+		cv.APIVersion = chart.APIVersionV1
+	}
+	if err := cv.Validate(); ignoreSkippableChartValidationError(err) != nil {
+		slog.Warn(fmt.Sprintf("skipping loading invalid entry for chart %q %q from %s: %s", name, cv.Version, source, err))
+		return false
+	}
+	return true
+}
+
 func jsonOrYamlUnmarshal(b []byte, i interface{}) error {
 	if json.Valid(b) {
 		return json.Unmarshal(b, i)
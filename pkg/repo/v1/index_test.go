@@ -508,6 +508,56 @@ func TestIndexDirectory(t *testing.T) {
 	}
 }
 
+func TestIndexDirectoryProvenance(t *testing.T) {
+	dir := t.TempDir()
+	for _, src := range []string{
+		"../../cmd/testdata/testcharts/signtest-0.1.0.tgz",
+		"../../cmd/testdata/testcharts/signtest-0.1.0.tgz.prov",
+		"testdata/repository/frobnitz-1.2.3.tgz",
+	} {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(src)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := IndexDirectory(dir, "http://localhost:8080/charts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if index, err = LoadIndexFile(filepath.Join(dir, "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := index.Get("signtest", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signed.Signed() || signed.Provenance != "http://localhost:8080/charts/signtest-0.1.0.tgz.prov" {
+		t.Errorf("expected the provenance file to be referenced, got %q", signed.Provenance)
+	}
+	if signed.Digests[DigestChart] != "sha256:"+signed.Digest {
+		t.Errorf("expected the digest of the chart, got %v", signed.Digests)
+	}
+	if !strings.HasPrefix(signed.Digests[DigestProvenance], "sha256:") {
+		t.Errorf("expected the digest of the provenance file, got %v", signed.Digests)
+	}
+
+	unsigned, err := index.Get("frobnitz", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unsigned.Signed() || len(unsigned.Digests) != 1 {
+		t.Errorf("expected only the digest of the chart, got provenance %q and digests %v", unsigned.Provenance, unsigned.Digests)
+	}
+}
+
 func TestIndexAdd(t *testing.T) {
 	i := NewIndexFile()
 
@@ -720,3 +770,46 @@ func TestLoadIndex_DuplicateChartDeps(t *testing.T) {
 		})
 	}
 }
+
+// writeBenchmarkIndex writes an index holding the given number of charts and
+// versions of each chart, shaped like the index of a large public repository.
+func writeBenchmarkIndex(b *testing.B, charts, versions int) string {
+	b.Helper()
+	i := NewIndexFile()
+	for c := range charts {
+		for v := range versions {
+			md := &chart.Metadata{
+				APIVersion:  chart.APIVersionV2,
+				Name:        fmt.Sprintf("chart-%d", c),
+				Version:     fmt.Sprintf("1.%d.0", v),
+				AppVersion:  fmt.Sprintf("2.%d.0", v),
+				Description: "A chart generated for benchmarking index loading",
+				Home:        "https://example.com",
+				Keywords:    []string{"benchmark", "index"},
+				Maintainers: []*chart.Maintainer{{Name: "Helm", Email: "helm@example.com"}},
+				Annotations: map[string]string{"category": "Benchmark"},
+			}
+			filename := fmt.Sprintf("%s-%s.tgz", md.Name, md.Version)
+			if err := i.MustAdd(md, filename, "https://example.com/charts", "sha256:1234567890abcdef"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	i.SortEntries()
+	path := filepath.Join(b.TempDir(), "index.yaml")
+	if err := i.WriteFile(path, 0644); err != nil {
+		b.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkLoadIndexFile(b *testing.B) {
+	path := writeBenchmarkIndex(b, 200, 20)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadIndexFile(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"cmp"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+const (
+	// MirrorOrderListed tries the URL of a repository first, and then its
+	// mirrors in the order they are listed.
+	MirrorOrderListed = "listed"
+	// MirrorOrderLatency tries the URL and the mirrors of a repository from
+	// the fastest to respond to the slowest, as measured by requesting the
+	// first byte of their index. The ones that do not respond are tried last.
+	MirrorOrderLatency = "latency"
+)
+
+// URLs returns the URL and the mirrors of the repository, in the order they
+// are tried. With MirrorOrderLatency, their latency is probed the first time.
+func (r *ChartRepository) URLs() []string {
+	urls := append([]string{r.Config.URL}, r.Config.Mirrors...)
+	if r.Config.MirrorOrder != MirrorOrderLatency || len(urls) == 1 {
+		return urls
+	}
+	r.urlsOnce.Do(func() {
+		r.urls = r.probeLatency(urls)
+	})
+	return r.urls
+}
+
+// probeLatency sorts URLs of the repository by the time they take to respond.
+// They are probed one at a time, as the getter of the repository is not safe
+// for concurrent use.
+func (r *ChartRepository) probeLatency(urls []string) []string {
+	latencies := make(map[string]time.Duration, len(urls))
+	for _, u := range urls {
+		indexURL, err := ResolveReferenceURL(u, "index.yaml")
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		if _, err := r.Client.Get(indexURL, r.getterOptions(getter.WithURL(u), getter.WithMaxBytes(1))...); err != nil {
+			slog.Debug("repository mirror did not respond", "url", u, slog.Any("error", err))
+			continue
+		}
+		latencies[u] = time.Since(start)
+	}
+
+	sorted := slices.Clone(urls)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		la, oka := latencies[a]
+		lb, okb := latencies[b]
+		switch {
+		case oka && okb:
+			return cmp.Compare(la, lb)
+		case oka:
+			return -1
+		case okb:
+			return 1
+		}
+		return 0
+	})
+	slog.Debug("probed the latency of the repository mirrors", "repo", r.Config.Name, "urls", sorted)
+	return sorted
+}
+
+// failover calls fn with the URL and the mirrors of the repository in turn,
+// until it succeeds or fails with an error that is not worth trying a mirror
+// for. When all of them fail, it returns the errors of all the attempts.
+func (r *ChartRepository) failover(fn func(baseURL string) error) error {
+	urls := r.URLs()
+	var errs []error
+	for i, u := range urls {
+		err := fn(u)
+		if err == nil || !IsFailoverError(err) {
+			return err
+		}
+		if len(urls) == 1 {
+			return err
+		}
+		errs = append(errs, err)
+		if i < len(urls)-1 {
+			slog.Warn("repository URL failed, trying the next mirror", "repo", r.Config.Name, "url", u, "next", urls[i+1], slog.Any("error", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsFailoverError reports whether a download failed with an error that is
+// worth trying a mirror for: a network error, or a server error.
+func IsFailoverError(err error) bool {
+	var statusErr *getter.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// MirrorURL returns the URL of a file of a repository on one of its mirrors.
+// Only the files under the URL of the repository are mirrored, it reports
+// false for the others.
+func MirrorURL(fileURL, repoURL, mirrorURL string) (string, bool) {
+	if mirrorURL == repoURL {
+		return fileURL, true
+	}
+	prefix := strings.TrimSuffix(repoURL, "/") + "/"
+	rest, ok := strings.CutPrefix(fileURL, prefix)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSuffix(mirrorURL, "/") + "/" + rest, true
+}
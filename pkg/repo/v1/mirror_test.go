@@ -0,0 +1,165 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+func newMirrorTestRepo(t *testing.T, entry *Entry) *ChartRepository {
+	t.Helper()
+	entry.Name = "mirrored"
+	r, err := NewChartRepository(entry, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	return r
+}
+
+func TestDownloadIndexFileFailover(t *testing.T) {
+	var status atomic.Int32
+	var primaryRequests atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		primaryRequests.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer primary.Close()
+	var mirrorRequests atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorRequests.Add(1)
+		http.ServeFile(w, r, "testdata/local-index.yaml")
+	}))
+	defer mirror.Close()
+
+	r := newMirrorTestRepo(t, &Entry{URL: primary.URL, Mirrors: []string{mirror.URL}})
+
+	// Server errors fail over to the mirror.
+	status.Store(http.StatusServiceUnavailable)
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatalf("expected the index to be downloaded from the mirror, got %v", err)
+	}
+	if _, err := LoadIndexFile(idx); err != nil {
+		t.Errorf("expected the index to be cached: %v", err)
+	}
+	if primaryRequests.Load() != 1 || mirrorRequests.Load() != 1 {
+		t.Errorf("expected a request to the repository and the mirror, got %d and %d", primaryRequests.Load(), mirrorRequests.Load())
+	}
+
+	// Other errors do not.
+	status.Store(http.StatusNotFound)
+	if _, err := r.DownloadIndexFile(); err == nil {
+		t.Error("expected the error of the repository")
+	}
+	if mirrorRequests.Load() != 1 {
+		t.Errorf("expected no other request to the mirror, got %d", mirrorRequests.Load())
+	}
+
+	// The errors of all the URLs are returned when they all fail.
+	mirror.Close()
+	status.Store(http.StatusBadGateway)
+	_, err = r.DownloadIndexFile()
+	var statusErr *getter.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected the error of the repository, got %v", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		t.Errorf("expected the error of the mirror, got %v", err)
+	}
+}
+
+func TestURLsByLatency(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		http.ServeFile(w, r, "testdata/local-index.yaml")
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/local-index.yaml")
+	}))
+	defer fast.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	entry := &Entry{URL: down.URL, Mirrors: []string{slow.URL, fast.URL}}
+	r := newMirrorTestRepo(t, entry)
+	if got, expected := r.URLs(), []string{down.URL, slow.URL, fast.URL}; !slices.Equal(got, expected) {
+		t.Errorf("expected the listed order %v, got %v", expected, got)
+	}
+
+	entry.MirrorOrder = MirrorOrderLatency
+	if got, expected := r.URLs(), []string{fast.URL, slow.URL, down.URL}; !slices.Equal(got, expected) {
+		t.Errorf("expected the order of the latencies %v, got %v", expected, got)
+	}
+}
+
+func TestIsFailoverError(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	_, netErr := http.Get(down.URL)
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&getter.HTTPStatusError{StatusCode: http.StatusInternalServerError}, true},
+		{fmt.Errorf("wrapped: %w", &getter.HTTPStatusError{StatusCode: http.StatusGatewayTimeout}), true},
+		{&getter.HTTPStatusError{StatusCode: http.StatusNotFound}, false},
+		{&getter.HTTPStatusError{StatusCode: http.StatusUnauthorized}, false},
+		{netErr, true},
+		{io.ErrUnexpectedEOF, true},
+		{errors.New("invalid index"), false},
+	}
+	for _, tt := range tests {
+		if got := IsFailoverError(tt.err); got != tt.expected {
+			t.Errorf("IsFailoverError(%v): expected %t, got %t", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestMirrorURL(t *testing.T) {
+	tests := []struct {
+		fileURL, repoURL, mirrorURL string
+		expected                    string
+		ok                          bool
+	}{
+		{"https://example.com/charts/foo-1.0.0.tgz", "https://example.com/charts", "https://mirror.example.com/helm/", "https://mirror.example.com/helm/foo-1.0.0.tgz", true},
+		{"https://example.com/charts/a/foo-1.0.0.tgz", "https://example.com/charts/", "https://mirror.example.com", "https://mirror.example.com/a/foo-1.0.0.tgz", true},
+		{"https://cdn.example.com/foo-1.0.0.tgz", "https://example.com/charts", "https://mirror.example.com", "", false},
+		{"https://cdn.example.com/foo-1.0.0.tgz", "https://example.com/charts", "https://example.com/charts", "https://cdn.example.com/foo-1.0.0.tgz", true},
+	}
+	for _, tt := range tests {
+		got, ok := MirrorURL(tt.fileURL, tt.repoURL, tt.mirrorURL)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("MirrorURL(%q, %q, %q): expected %q, %t, got %q, %t", tt.fileURL, tt.repoURL, tt.mirrorURL, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// authRealm is the realm of the authentication challenges of the server.
+const authRealm = "repotest"
+
+// TokenValidator reports whether a bearer token grants access to the server.
+type TokenValidator func(token string) bool
+
+// WithBasicAuth makes the server require HTTP basic authentication with the
+// given credentials. Requests without them are answered with 401
+// Unauthorized and a Basic challenge. The testing repository of the server
+// is set up with the credentials.
+func WithBasicAuth(username, password string) ServerOption {
+	return func(_ *testing.T, server *Server) {
+		server.username = username
+		server.password = password
+		server.challenge = `Basic realm="` + authRealm + `"`
+		server.authenticate = func(r *http.Request) bool {
+			u, p, ok := r.BasicAuth()
+			return ok && u == username && p == password
+		}
+	}
+}
+
+// WithBearerAuth makes the server require bearer tokens that validate
+// accepts. Requests without such a token are answered with 401 Unauthorized
+// and a Bearer challenge.
+func WithBearerAuth(validate TokenValidator) ServerOption {
+	return func(_ *testing.T, server *Server) {
+		server.challenge = `Bearer realm="` + authRealm + `"`
+		server.authenticate = func(r *http.Request) bool {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			return ok && validate(token)
+		}
+	}
+}
+
+// authorize answers requests that do not authenticate with 401 Unauthorized,
+// returning false for them.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if s.authenticate == nil || s.authenticate(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", s.challenge)
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	return false
+}
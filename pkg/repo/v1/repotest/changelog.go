@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/pkg/repo/v1"
+)
+
+// ChangelogPath is the URL path the changelog of the index is served at, see
+// WithIndexChangelog.
+const ChangelogPath = "/changes"
+
+// changelog is the append-only changelog of the index. Its tokens are the
+// number of chart versions appended to it.
+type changelog struct {
+	mu sync.Mutex
+	// base is the token of the first version of the log, which is not zero
+	// once compacted.
+	base     int
+	versions []*repo.ChartVersion
+}
+
+// WithIndexChangelog makes the server keep a changelog of the chart versions
+// added by CreateIndex, and reference it in the index, so that clients can
+// update their copy of the index with the versions added since they
+// downloaded it.
+func WithIndexChangelog() ServerOption {
+	return func(_ *testing.T, server *Server) {
+		server.changelog = &changelog{}
+	}
+}
+
+// CompactChangelog drops the versions logged so far from the changelog, so
+// that the changes since the tokens of the indexes served so far can no
+// longer be requested.
+func (s *Server) CompactChangelog() {
+	if s.changelog == nil {
+		return
+	}
+	s.changelog.mu.Lock()
+	defer s.changelog.mu.Unlock()
+	s.changelog.base += len(s.changelog.versions)
+	s.changelog.versions = nil
+}
+
+// logChanges appends the versions of the index that are not logged yet to the
+// changelog, and references the changelog in the index.
+func (s *Server) logChanges(index *repo.IndexFile) {
+	if s.changelog == nil {
+		return
+	}
+	c := s.changelog
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logged := repo.NewIndexFile()
+	for _, cv := range c.versions {
+		logged.Entries[cv.Name] = append(logged.Entries[cv.Name], cv)
+	}
+	for _, cvs := range index.Entries {
+		for _, cv := range cvs {
+			if !logged.Has(cv.Name, cv.Version) {
+				c.versions = append(c.versions, cv)
+			}
+		}
+	}
+	index.Changes = &repo.IndexChangelog{
+		URL:   ChangelogPath[1:],
+		Token: strconv.Itoa(c.base + len(c.versions)),
+	}
+}
+
+// serveChangelog serves the changes since the token of the request when the
+// server keeps a changelog. It reports whether it served the request.
+func (s *Server) serveChangelog(w http.ResponseWriter, r *http.Request) bool {
+	if s.changelog == nil || r.URL.Path != ChangelogPath {
+		return false
+	}
+	c := s.changelog
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	since, err := strconv.Atoi(r.URL.Query().Get(repo.ChangesSinceParam))
+	if err != nil || since < c.base || since > c.base+len(c.versions) {
+		http.Error(w, "unknown changelog token", http.StatusGone)
+		return true
+	}
+
+	changes := repo.NewIndexFile()
+	changes.Generated = time.Now()
+	for _, cv := range c.versions[since-c.base:] {
+		changes.Entries[cv.Name] = append(changes.Entries[cv.Name], cv)
+	}
+	changes.Changes = &repo.IndexChangelog{
+		URL:   ChangelogPath[1:],
+		Token: strconv.Itoa(c.base + len(c.versions)),
+	}
+	d, err := yaml.Marshal(changes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(d)
+	return true
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings the server can serve the index with.
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+	// EncodingIdentity is the encoding of the responses that are not
+	// compressed.
+	EncodingIdentity = "identity"
+)
+
+// WithCompressedIndex serves index.yaml compressed with the encoding the
+// Accept-Encoding header of the request prefers out of the given ones, which
+// are listed in the order of preference of the server. The index is served
+// uncompressed to the requests accepting none of them.
+func WithCompressedIndex(encodings ...string) ServerOption {
+	return func(t *testing.T, server *Server) {
+		t.Helper()
+		for _, e := range encodings {
+			if e != EncodingGzip && e != EncodingZstd {
+				t.Fatalf("unsupported index encoding %q", e)
+			}
+		}
+		server.indexEncodings = encodings
+	}
+}
+
+// ContentEncodings returns the content encodings of the responses to the
+// requests for the URL path served so far, in the order they were served.
+// Responses that are not compressed have the identity encoding.
+func (s *Server) ContentEncodings(urlPath string) []string {
+	s.encodingsMu.Lock()
+	defer s.encodingsMu.Unlock()
+	return append([]string(nil), s.encodings[urlPath]...)
+}
+
+// recordEncoding records the content encoding of a response.
+func (s *Server) recordEncoding(urlPath, encoding string) {
+	s.encodingsMu.Lock()
+	defer s.encodingsMu.Unlock()
+	if s.encodings == nil {
+		s.encodings = make(map[string][]string)
+	}
+	s.encodings[urlPath] = append(s.encodings[urlPath], encoding)
+}
+
+// serveCompressedIndex serves the index compressed when the request accepts
+// one of the encodings of the server. It reports whether it served the
+// request.
+func (s *Server) serveCompressedIndex(w http.ResponseWriter, r *http.Request, name, etag string) bool {
+	if len(s.indexEncodings) == 0 || r.URL.Path != "/index.yaml" {
+		return false
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+	encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"), s.indexEncodings)
+	s.recordEncoding(r.URL.Path, encoding)
+	if encoding == EncodingIdentity {
+		return false
+	}
+
+	fi, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return false
+	}
+	compressed, err := compress(encoding, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return true
+	}
+
+	// The compressed representations have their own ETags, for caches not
+	// to mix them up with the uncompressed one.
+	if etag != "" {
+		w.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+encoding+`"`)
+	}
+	w.Header().Set("Content-Encoding", encoding)
+	w.Header().Set("Content-Type", "application/yaml")
+	http.ServeContent(w, r, "", fi.ModTime(), bytes.NewReader(compressed))
+	return true
+}
+
+// negotiateEncoding returns the encoding, out of the ones of the server, with
+// the highest quality in the Accept-Encoding headers of a request. The order
+// of the encodings of the server breaks the ties.
+func negotiateEncoding(headers []string, encodings []string) string {
+	best, bestQuality := EncodingIdentity, 0.0
+	for _, e := range encodings {
+		if q := acceptQuality(headers, e); q > bestQuality {
+			best, bestQuality = e, q
+		}
+	}
+	return best
+}
+
+// acceptQuality returns the quality of an encoding in Accept-Encoding
+// headers, falling back to the quality of the "*" wildcard. Encodings that
+// are not accepted have a quality of zero.
+func acceptQuality(headers []string, encoding string) float64 {
+	quality, wildcard := -1.0, 0.0
+	for _, header := range headers {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			q := 1.0
+			for _, param := range strings.Split(params, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if ok && strings.EqualFold(k, "q") {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						q = f
+					}
+				}
+			}
+			switch {
+			case strings.EqualFold(coding, encoding):
+				quality = q
+			case coding == "*":
+				wildcard = q
+			}
+		}
+	}
+	if quality < 0 {
+		return wildcard
+	}
+	return quality
+}
+
+// compress compresses data with an encoding.
+func compress(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch encoding {
+	case EncodingGzip:
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case EncodingZstd:
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return buf.Bytes(), nil
+}
@@ -17,5 +17,7 @@ limitations under the License.
 Package repotest provides utilities for testing.
 
 The server provides a testing server that can be set up and torn down quickly.
+With WithUploads, it also accepts uploads of charts with the API of
+ChartMuseum, and regenerates its index after each of them.
 */
 package repotest
@@ -0,0 +1,144 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"net/http"
+	"path"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fault is a fault the server injects in its responses to some requests.
+type Fault struct {
+	// Latency delays the responses.
+	Latency time.Duration
+	// StatusCode, when set, answers the requests with this status code, e.g.
+	// http.StatusServiceUnavailable, instead of serving them.
+	StatusCode int
+	// TruncateAt, when positive, cuts the response bodies after that many
+	// bytes while still announcing their full length, so that clients get
+	// an unexpected EOF.
+	TruncateAt int
+	// Times limits the fault to the first Times matching requests, after
+	// which they are served normally. Zero injects the fault in all of them.
+	Times int
+}
+
+type fault struct {
+	pattern string
+	Fault
+
+	mu       sync.Mutex
+	requests int
+}
+
+// WithFault injects a fault in the responses to the requests whose URL path
+// matches pattern, as path.Match matches it, e.g. "/index.yaml" or "/*.tgz".
+// Faults are matched in the order they are given, and only the first one
+// matching a request is injected.
+func WithFault(pattern string, f Fault) ServerOption {
+	return func(t *testing.T, server *Server) {
+		t.Helper()
+		if _, err := path.Match(pattern, "/"); err != nil {
+			t.Fatalf("invalid fault pattern %q: %s", pattern, err)
+		}
+		server.faults = append(server.faults, &fault{pattern: pattern, Fault: f})
+	}
+}
+
+// WithLatency delays the responses to the requests whose URL path matches
+// pattern.
+func WithLatency(pattern string, latency time.Duration) ServerOption {
+	return WithFault(pattern, Fault{Latency: latency})
+}
+
+// WithFailures answers the first n requests whose URL path matches pattern
+// with the status code, before serving the following ones normally.
+func WithFailures(pattern string, statusCode, n int) ServerOption {
+	return WithFault(pattern, Fault{StatusCode: statusCode, Times: n})
+}
+
+// FaultRequests returns the number of requests that matched the fault with the
+// given pattern so far, whether or not the fault was injected in them.
+func (s *Server) FaultRequests(pattern string) int {
+	for _, f := range s.faults {
+		if f.pattern == pattern {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			return f.requests
+		}
+	}
+	return 0
+}
+
+// injectFault injects the fault matching the request, if any. It returns the
+// writer to serve the request with, or nil when the request was answered.
+func (s *Server) injectFault(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	for _, f := range s.faults {
+		if ok, _ := path.Match(f.pattern, r.URL.Path); !ok {
+			continue
+		}
+		f.mu.Lock()
+		f.requests++
+		active := f.Times == 0 || f.requests <= f.Times
+		f.mu.Unlock()
+		if !active {
+			return w
+		}
+
+		if f.Latency > 0 {
+			timer := time.NewTimer(f.Latency)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return nil
+			}
+		}
+		if f.StatusCode != 0 {
+			http.Error(w, http.StatusText(f.StatusCode), f.StatusCode)
+			return nil
+		}
+		if f.TruncateAt > 0 {
+			return &truncatingWriter{ResponseWriter: w, remaining: f.TruncateAt}
+		}
+		return w
+	}
+	return w
+}
+
+// truncatingWriter discards what is written past its remaining bytes.
+type truncatingWriter struct {
+	http.ResponseWriter
+	remaining int
+}
+
+func (w *truncatingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > w.remaining {
+		p = p[:w.remaining]
+	}
+	w.remaining -= len(p)
+	if _, err := w.ResponseWriter.Write(p); err != nil {
+		return 0, err
+	}
+	// The discarded bytes are reported as written for the handler to carry
+	// on, the server then closing the connection short of the announced
+	// length.
+	return n, nil
+}
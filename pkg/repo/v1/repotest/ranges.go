@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// WithoutRanges makes the server ignore the Range headers of the requests and
+// serve whole files, as servers that do not support range requests do.
+func WithoutRanges() ServerOption {
+	return func(_ *testing.T, server *Server) {
+		server.noRanges = true
+	}
+}
+
+// RangeRequests returns the Range headers of the requests for the URL path
+// received so far, in the order they were received.
+func (s *Server) RangeRequests(urlPath string) []string {
+	s.rangesMu.Lock()
+	defer s.rangesMu.Unlock()
+	return append([]string(nil), s.ranges[urlPath]...)
+}
+
+// recordRange records the Range header of a request, if it has one.
+func (s *Server) recordRange(r *http.Request) {
+	header := r.Header.Get("Range")
+	if header == "" {
+		return
+	}
+	s.rangesMu.Lock()
+	defer s.rangesMu.Unlock()
+	if s.ranges == nil {
+		s.ranges = make(map[string][]string)
+	}
+	s.ranges[r.URL.Path] = append(s.ranges[r.URL.Path], header)
+}
+
+// serveFile serves the files of the docroot. Files are served with the
+// digest of their content as their ETag, so that clients resuming a download
+// with an If-Range header only get the rest of the file if it did not
+// change, and with its whole content otherwise.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	if s.noRanges {
+		r = r.Clone(r.Context())
+		r.Header.Del("Range")
+		r.Header.Del("If-Range")
+		w = &noRangesWriter{ResponseWriter: w}
+	}
+	if s.serveChangelog(w, r) {
+		return
+	}
+	name := filepath.Join(s.Root(), filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+	etag, ok := fileETag(name)
+	if ok {
+		w.Header().Set("ETag", etag)
+	}
+	if s.serveCompressedIndex(w, r, name, etag) {
+		return
+	}
+	http.FileServer(http.Dir(s.Root())).ServeHTTP(w, r)
+}
+
+// fileETag returns the ETag of a regular file.
+func fileETag(name string) (string, bool) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err != nil || !fi.Mode().IsRegular() {
+		return "", false
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum(nil)), true
+}
+
+// noRangesWriter advertises that ranges are not supported. The file server
+// always writes the header of its responses before their body.
+type noRangesWriter struct {
+	http.ResponseWriter
+}
+
+func (w *noRangesWriter) WriteHeader(statusCode int) {
+	w.Header().Set("Accept-Ranges", "none")
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/distribution/distribution/v3/configuration"
+	"github.com/distribution/distribution/v3/registry/handlers"
+
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	ociRegistry "helm.sh/helm/v4/pkg/registry"
+)
+
+// WithOCIRegistry makes the server an OCI registry as well, keeping its
+// content in memory. The registry serves the distribution API under /v2/,
+// next to the chart repository, so that oci:// references to the host of the
+// server pull from it. Charts are pushed to it with PushCharts, and the charts
+// of WithChartSourceGlob are pushed to it as well.
+func WithOCIRegistry() ServerOption {
+	return func(t *testing.T, server *Server) {
+		t.Helper()
+		config := &configuration.Configuration{}
+		config.Storage = configuration.Storage{"inmemory": configuration.Parameters{}}
+		server.registry = handlers.NewApp(t.Context(), config)
+	}
+}
+
+// isRegistryRequest reports whether the request is one of the distribution API.
+func isRegistryRequest(r *http.Request) bool {
+	return r.URL.Path == "/v2" || strings.HasPrefix(r.URL.Path, "/v2/")
+}
+
+// OCIURL returns the oci:// URL of the registry of the server.
+//
+// Example:
+//
+//	oci://127.0.0.1:1776
+func (s *Server) OCIURL() string {
+	return "oci://" + s.registryHost()
+}
+
+func (s *Server) registryHost() string {
+	u, err := url.Parse(s.URL())
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// RegistryClient returns a registry client for the registry of the server,
+// storing its credentials in the docroot of the server. When the server
+// requires basic authentication, the client is logged in to the registry.
+func (s *Server) RegistryClient() (*ociRegistry.Client, error) {
+	if s.registry == nil {
+		return nil, errors.New("the server is not an OCI registry: use WithOCIRegistry")
+	}
+	options := []ociRegistry.ClientOption{
+		ociRegistry.ClientOptCredentialsFile(filepath.Join(s.docroot, "config.json")),
+	}
+	loginOptions := []ociRegistry.LoginOption{
+		ociRegistry.LoginOptBasicAuth(s.username, s.password),
+	}
+	if s.tlsConfig != nil {
+		client := s.Client()
+		options = append(options, ociRegistry.ClientOptHTTPClient(client))
+		loginOptions = append(loginOptions, ociRegistry.LoginOptTLSClientConfigFromConfig(client.Transport.(*http.Transport).TLSClientConfig))
+	} else {
+		options = append(options, ociRegistry.ClientOptPlainHTTP())
+		loginOptions = append(loginOptions, ociRegistry.LoginOptPlainText(true))
+	}
+	client, err := ociRegistry.NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+	if s.username != "" {
+		if err := client.Login(s.registryHost(), loginOptions...); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// PushCharts takes a glob expression and pushes the chart archives it matches
+// to the registry of the server, each as <name>:<version> at the root of the
+// registry, along with its provenance file when there is one. It returns the
+// references of the pushed charts.
+//
+// The charts are pushed to the registry directly, regardless of the
+// middleware and the TLS configuration of the server.
+func (s *Server) PushCharts(origin string) ([]string, error) {
+	if s.registry == nil {
+		return nil, errors.New("the server is not an OCI registry: use WithOCIRegistry")
+	}
+	files, err := filepath.Glob(origin)
+	if err != nil {
+		return nil, err
+	}
+	client, err := ociRegistry.NewClient(
+		ociRegistry.ClientOptCredentialsFile(filepath.Join(s.docroot, "config.json")),
+		ociRegistry.ClientOptHTTPClient(&http.Client{Transport: handlerTransport{s.registry}}),
+		ociRegistry.ClientOptPlainHTTP(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]string, 0, len(files))
+	for _, f := range files {
+		if strings.HasSuffix(f, ".prov") {
+			continue
+		}
+		ch, err := loader.LoadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		// Tags cannot hold the + of semantic versions.
+		ref := fmt.Sprintf("%s/%s:%s", s.registryHost(), ch.Name(), strings.ReplaceAll(ch.Metadata.Version, "+", "_"))
+		var pushOptions []ociRegistry.PushOption
+		if prov, err := os.ReadFile(f + ".prov"); err == nil {
+			pushOptions = append(pushOptions, ociRegistry.PushOptProvData(prov))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if _, err := client.Push(data, ref, pushOptions...); err != nil {
+			return nil, fmt.Errorf("could not push %s: %w", f, err)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// handlerTransport sends requests to a handler rather than over the network.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (h handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, r)
+	res := rec.Result()
+	res.Request = r
+	return res, nil
+}
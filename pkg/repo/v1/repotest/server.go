@@ -23,6 +23,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -36,6 +37,7 @@ import (
 	chart "helm.sh/helm/v4/pkg/chart/v2"
 	"helm.sh/helm/v4/pkg/chart/v2/loader"
 	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
+	"helm.sh/helm/v4/pkg/pullthrough"
 	ociRegistry "helm.sh/helm/v4/pkg/registry"
 	"helm.sh/helm/v4/pkg/repo/v1"
 )
@@ -70,6 +72,20 @@ func WithChartSourceGlob(glob string) ServerOption {
 	}
 }
 
+// WithPullThroughCache makes the server a pull-through cache of an upstream
+// chart repository or OCI registry instead of serving its docroot. What it
+// serves is cached in the "cache" directory of the docroot.
+func WithPullThroughCache(upstream string, options ...pullthrough.Option) ServerOption {
+	return func(t *testing.T, server *Server) {
+		t.Helper()
+		proxy, err := pullthrough.New(upstream, filepath.Join(server.docroot, "cache"), options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.proxy = proxy
+	}
+}
+
 // Server is an implementation of a repository server for testing.
 type Server struct {
 	docroot         string
@@ -77,6 +93,36 @@ type Server struct {
 	middleware      http.HandlerFunc
 	tlsConfig       *tls.Config
 	chartSourceGlob string
+	proxy           *pullthrough.Proxy
+	registry        http.Handler
+	// authenticate, when set, reports whether requests are authenticated,
+	// and challenge is the WWW-Authenticate header of the ones that are not.
+	authenticate func(*http.Request) bool
+	challenge    string
+	// username and password are the basic authentication credentials of
+	// the testing repository.
+	username, password string
+	// faults are the faults injected in the responses, see WithFault.
+	faults []*fault
+	// noRanges ignores the Range headers of the requests, see WithoutRanges.
+	noRanges bool
+	// ranges are the Range headers of the requests, keyed by their path.
+	rangesMu sync.Mutex
+	ranges   map[string][]string
+	// indexEncodings are the encodings the index is compressed with, see
+	// WithCompressedIndex.
+	indexEncodings []string
+	// encodings are the content encodings of the responses, keyed by their
+	// path.
+	encodingsMu sync.Mutex
+	encodings   map[string][]string
+	// changelog is the changelog of the index, see WithIndexChangelog.
+	changelog *changelog
+	// uploads is whether the server accepts uploads of charts, see
+	// WithUploads. uploadMu serializes them with the regeneration of the
+	// index.
+	uploads  bool
+	uploadMu sync.Mutex
 }
 
 // NewTempServer creates a server inside of a temp dir.
@@ -100,6 +146,11 @@ func NewTempServer(t *testing.T, options ...ServerOption) *Server {
 		if _, err := srv.CopyCharts(srv.chartSourceGlob); err != nil {
 			t.Fatal(err)
 		}
+		if srv.registry != nil {
+			if _, err := srv.PushCharts(srv.chartSourceGlob); err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 
 	return srv
@@ -125,13 +176,31 @@ func newServer(t *testing.T, docroot string, options ...ServerOption) *Server {
 		if s.middleware != nil {
 			s.middleware.ServeHTTP(w, r)
 		}
-		http.FileServer(http.Dir(s.Root())).ServeHTTP(w, r)
+		s.recordRange(r)
+		if w = s.injectFault(w, r); w == nil {
+			return
+		}
+		if !s.authorize(w, r) {
+			return
+		}
+		if s.registry != nil && isRegistryRequest(r) {
+			s.registry.ServeHTTP(w, r)
+			return
+		}
+		if s.serveUpload(w, r) {
+			return
+		}
+		if s.proxy != nil {
+			s.proxy.ServeHTTP(w, r)
+			return
+		}
+		s.serveFile(w, r)
 	}))
 
 	s.start()
 
 	// Add the testing repository as the only repo. Server must be started for the server's URL to be valid
-	if err := setTestingRepository(s.URL(), filepath.Join(s.docroot, "repositories.yaml")); err != nil {
+	if err := s.setTestingRepository(filepath.Join(s.docroot, "repositories.yaml")); err != nil {
 		t.Fatal(err)
 	}
 
@@ -186,6 +255,8 @@ func NewOCIServer(t *testing.T, dir string) (*OCIServer, error) {
 	config.HTTP.Addr = ln.Addr().String()
 	config.HTTP.DrainTimeout = time.Duration(10) * time.Second
 	config.Storage = map[string]configuration.Parameters{"inmemory": map[string]interface{}{}}
+	// The catalog endpoint lists no repository when not configured
+	config.Catalog.MaxEntries = 1000
 	config.Auth = configuration.Auth{
 		"htpasswd": configuration.Parameters{
 			"realm": "localhost",
@@ -340,12 +411,15 @@ func (s *Server) CopyCharts(origin string) ([]string, error) {
 }
 
 // CreateIndex will read docroot and generate an index.yaml file.
+// The chart versions it adds to the index are logged to the changelog of the
+// server, see WithIndexChangelog.
 func (s *Server) CreateIndex() error {
 	// generate the index
 	index, err := repo.IndexDirectory(s.docroot, s.URL())
 	if err != nil {
 		return err
 	}
+	s.logChanges(index)
 
 	d, err := yaml.Marshal(index)
 	if err != nil {
@@ -394,16 +468,19 @@ func (s *Server) LinkIndices() error {
 	return os.Symlink(lstart, ldest)
 }
 
-// setTestingRepository sets up a testing repository.yaml with only the given URL.
-func setTestingRepository(url, fname string) error {
-	if url == "" {
+// setTestingRepository sets up a testing repository.yaml with only the URL of
+// the server, and its basic authentication credentials if it has some.
+func (s *Server) setTestingRepository(fname string) error {
+	if s.URL() == "" {
 		panic("no url")
 	}
 
 	r := repo.NewFile()
 	r.Add(&repo.Entry{
-		Name: "test",
-		URL:  url,
+		Name:     "test",
+		URL:      s.URL(),
+		Username: s.username,
+		Password: s.password,
 	})
 	return r.WriteFile(fname, 0o640)
 }
@@ -16,15 +16,26 @@ limitations under the License.
 package repotest
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v4/internal/test/ensure"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	chartutil "helm.sh/helm/v4/pkg/chart/v2/util"
 	"helm.sh/helm/v4/pkg/repo/v1"
 )
 
@@ -220,3 +231,602 @@ func TestNewTempServer_TLS(t *testing.T) {
 		t.Fatal("non-TLS server")
 	}
 }
+
+func TestPullThroughCache(t *testing.T) {
+	ensure.HelmHome(t)
+
+	upstream := NewTempServer(t, WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"))
+	if err := upstream.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewTempServer(t, WithPullThroughCache(upstream.URL()))
+	defer srv.Stop()
+
+	res, err := srv.Client().Get(srv.URL() + "/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := repo.NewIndexFile()
+	if err := yaml.Unmarshal(data, m); err != nil {
+		t.Fatal(err)
+	}
+	urls := m.Entries["examplechart"][0].URLs
+	if len(urls) != 1 || !strings.HasPrefix(urls[0], srv.URL()) {
+		t.Fatalf("expected the chart URL to point to the pull-through cache, got %v", urls)
+	}
+
+	get := func() {
+		t.Helper()
+		res, err := srv.Client().Get(urls[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", urls[0], res.StatusCode)
+		}
+	}
+	get()
+
+	// The cached chart is served while the upstream is down.
+	upstream.Stop()
+	get()
+}
+
+func TestAuth(t *testing.T) {
+	ensure.HelmHome(t)
+
+	get := func(t *testing.T, srv *Server, setAuth func(*http.Request)) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, srv.URL()+"/index.yaml", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if setAuth != nil {
+			setAuth(req)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("basic", func(t *testing.T) {
+		srv := NewTempServer(t, WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"), WithBasicAuth("username", "password"))
+		defer srv.Stop()
+
+		res := get(t, srv, nil)
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 without credentials, got %d", res.StatusCode)
+		}
+		if challenge := res.Header.Get("WWW-Authenticate"); challenge != `Basic realm="repotest"` {
+			t.Errorf("unexpected challenge %q", challenge)
+		}
+		res = get(t, srv, func(r *http.Request) { r.SetBasicAuth("username", "wrong") })
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 with a wrong password, got %d", res.StatusCode)
+		}
+		res = get(t, srv, func(r *http.Request) { r.SetBasicAuth("username", "password") })
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("expected 200 with the credentials, got %d", res.StatusCode)
+		}
+
+		rf, err := repo.LoadFile(filepath.Join(srv.Root(), "repositories.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := rf.Get("test"); e == nil || e.Username != "username" || e.Password != "password" {
+			t.Errorf("expected the testing repository to have the credentials, got %+v", e)
+		}
+	})
+
+	t.Run("bearer", func(t *testing.T) {
+		srv := NewTempServer(t, WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"), WithBearerAuth(func(token string) bool {
+			return token == "s3cr3t"
+		}))
+		defer srv.Stop()
+
+		res := get(t, srv, nil)
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 without a token, got %d", res.StatusCode)
+		}
+		if challenge := res.Header.Get("WWW-Authenticate"); challenge != `Bearer realm="repotest"` {
+			t.Errorf("unexpected challenge %q", challenge)
+		}
+		res = get(t, srv, func(r *http.Request) { r.SetBasicAuth("username", "s3cr3t") })
+		if res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected 401 with basic authentication, got %d", res.StatusCode)
+		}
+		res = get(t, srv, func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cr3t") })
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("expected 200 with a valid token, got %d", res.StatusCode)
+		}
+	})
+}
+
+func TestFaults(t *testing.T) {
+	ensure.HelmHome(t)
+
+	srv := NewTempServer(t,
+		WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+		WithFailures("/index.yaml", http.StatusServiceUnavailable, 2),
+		WithLatency("/slow/*", 500*time.Millisecond),
+		WithFault("/*.tgz", Fault{TruncateAt: 10}),
+	)
+	defer srv.Stop()
+
+	get := func(p string) (*http.Response, []byte, error) {
+		res, err := srv.Client().Get(srv.URL() + p)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		return res, data, err
+	}
+
+	for i, want := range []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK} {
+		res, _, err := get("/index.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != want {
+			t.Errorf("request %d: expected %d, got %d", i+1, want, res.StatusCode)
+		}
+	}
+	if n := srv.FaultRequests("/index.yaml"); n != 3 {
+		t.Errorf("expected 3 requests for the index, got %d", n)
+	}
+
+	res, data, err := get("/examplechart-0.1.0.tgz")
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected an unexpected EOF reading a truncated body, got %v", err)
+	}
+	if res != nil && (len(data) != 10 || res.ContentLength <= 10) {
+		t.Errorf("expected 10 bytes out of the %d announced, got %d", res.ContentLength, len(data))
+	}
+
+	client := srv.Client()
+	client.Timeout = 100 * time.Millisecond
+	if _, err := client.Get(srv.URL() + "/slow/index.yaml"); err == nil {
+		t.Error("expected a delayed response to time out")
+	}
+	if n := srv.FaultRequests("/slow/*"); n != 1 {
+		t.Errorf("expected 1 delayed request, got %d", n)
+	}
+}
+
+func TestRanges(t *testing.T) {
+	ensure.HelmHome(t)
+
+	srv := NewTempServer(t,
+		WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+		WithFault("/*.tgz", Fault{TruncateAt: 100, Times: 1}),
+	)
+	defer srv.Stop()
+
+	get := func(srv *Server, header http.Header) (*http.Response, []byte, error) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL()+"/examplechart-0.1.0.tgz", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer res.Body.Close()
+		data, err := io.ReadAll(res.Body)
+		return res, data, err
+	}
+
+	want, err := os.ReadFile("testdata/examplechart-0.1.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The download is cut short, and resumed where it stopped.
+	res, data, err := get(srv, http.Header{})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected an unexpected EOF reading a truncated body, got %v", err)
+	}
+	if res.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("expected ranges to be advertised, got %q", res.Header.Get("Accept-Ranges"))
+	}
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("expected the chart to have an ETag")
+	}
+	res, rest, err := get(srv, http.Header{"Range": {fmt.Sprintf("bytes=%d-", len(data))}, "If-Range": {etag}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusPartialContent {
+		t.Errorf("expected %d, got %d", http.StatusPartialContent, res.StatusCode)
+	}
+	if want := fmt.Sprintf("bytes 100-%d/%d", len(want)-1, len(want)); res.Header.Get("Content-Range") != want {
+		t.Errorf("expected the content range %q, got %q", want, res.Header.Get("Content-Range"))
+	}
+	if !bytes.Equal(append(data, rest...), want) {
+		t.Error("expected the resumed download to match the chart")
+	}
+
+	// A download resumed with a stale ETag gets the whole chart.
+	res, data, err = get(srv, http.Header{"Range": {"bytes=100-"}, "If-Range": {`"stale"`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !bytes.Equal(data, want) {
+		t.Errorf("expected the whole chart with a stale ETag, got %d and %d bytes", res.StatusCode, len(data))
+	}
+	if got := srv.RangeRequests("/examplechart-0.1.0.tgz"); !slices.Equal(got, []string{"bytes=100-", "bytes=100-"}) {
+		t.Errorf("unexpected range requests: %v", got)
+	}
+
+	noRanges := NewTempServer(t, WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"), WithoutRanges())
+	defer noRanges.Stop()
+	res, data, err = get(noRanges, http.Header{"Range": {"bytes=0-9"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK || !bytes.Equal(data, want) {
+		t.Errorf("expected the whole chart from a server without ranges, got %d and %d bytes", res.StatusCode, len(data))
+	}
+	if res.Header.Get("Accept-Ranges") != "none" {
+		t.Errorf("expected ranges not to be advertised, got %q", res.Header.Get("Accept-Ranges"))
+	}
+}
+
+func TestCompressedIndex(t *testing.T) {
+	ensure.HelmHome(t)
+
+	srv := NewTempServer(t,
+		WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+		WithCompressedIndex(EncodingZstd, EncodingGzip),
+	)
+	defer srv.Stop()
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(srv.Root(), "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"identity", EncodingIdentity},
+		{"gzip", EncodingGzip},
+		{"gzip, zstd", EncodingZstd},
+		{"zstd;q=0.5, gzip", EncodingGzip},
+		{"zstd;q=0, *", EncodingGzip},
+		{"br, identity", EncodingIdentity},
+		{"*;q=0", EncodingIdentity},
+	} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL()+"/index.yaml", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Setting the header stops the client from decoding the response.
+		req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := res.Header.Get("Content-Encoding")
+		if got == "" {
+			got = EncodingIdentity
+		}
+		if got != tt.encoding {
+			t.Errorf("%q: expected the %s encoding, got %s", tt.acceptEncoding, tt.encoding, got)
+			continue
+		}
+		if res.Header.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: expected the response to vary with the accepted encodings, got %q", tt.acceptEncoding, res.Header.Get("Vary"))
+		}
+		var r io.Reader = bytes.NewReader(body)
+		switch tt.encoding {
+		case EncodingGzip:
+			if r, err = gzip.NewReader(r); err != nil {
+				t.Fatal(err)
+			}
+		case EncodingZstd:
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			r = zr
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.acceptEncoding, err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("%q: expected the decoded index to match the index", tt.acceptEncoding)
+		}
+	}
+
+	if got := srv.ContentEncodings("/index.yaml"); !slices.Equal(got, []string{
+		EncodingIdentity, EncodingGzip, EncodingZstd, EncodingGzip, EncodingGzip, EncodingIdentity, EncodingIdentity,
+	}) {
+		t.Errorf("unexpected content encodings: %v", got)
+	}
+
+	// The charts are never compressed.
+	res, err := srv.Client().Get(srv.URL() + "/examplechart-0.1.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.Uncompressed || res.Header.Get("Content-Encoding") != "" {
+		t.Error("expected the chart not to be compressed")
+	}
+}
+
+func TestOCIRegistry(t *testing.T) {
+	ensure.HelmHome(t)
+
+	testCases := map[string][]ServerOption{
+		"plainhttp": {
+			WithOCIRegistry(),
+			WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+		},
+		"tls": {
+			WithOCIRegistry(),
+			WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+			WithTLSConfig(MakeTestTLSConfig(t, "../../../../testdata")),
+		},
+		"basicauth": {
+			WithOCIRegistry(),
+			WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+			WithBasicAuth("username", "password"),
+		},
+		"tls-basicauth": {
+			WithOCIRegistry(),
+			WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+			WithTLSConfig(MakeTestTLSConfig(t, "../../../../testdata")),
+			WithBasicAuth("username", "password"),
+		},
+	}
+
+	for name, options := range testCases {
+		t.Run(name, func(t *testing.T) {
+			srv := NewTempServer(t, options...)
+			defer srv.Stop()
+
+			if !strings.HasPrefix(srv.OCIURL(), "oci://127.0.0.1:") {
+				t.Errorf("unexpected OCI URL %s", srv.OCIURL())
+			}
+
+			client, err := srv.RegistryClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ref := strings.TrimPrefix(srv.OCIURL(), "oci://") + "/examplechart"
+			tags, err := client.Tags(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tags) != 1 || tags[0] != "0.1.0" {
+				t.Errorf("expected the tags [0.1.0], got %v", tags)
+			}
+			result, err := client.Pull(ref + ":0.1.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Chart.Meta.Name != "examplechart" {
+				t.Errorf("expected to pull examplechart, got %s", result.Chart.Meta.Name)
+			}
+
+			// The chart repository is still served.
+			req, err := http.NewRequest(http.MethodGet, srv.URL()+"/index.yaml", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetBasicAuth("username", "password")
+			res, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("expected the index to be served, got %s", res.Status)
+			}
+		})
+	}
+
+	srv := NewTempServer(t)
+	defer srv.Stop()
+	if _, err := srv.PushCharts("testdata/*.tgz"); err == nil {
+		t.Error("expected an error pushing to a server that is not a registry")
+	}
+}
+
+func TestIndexChangelog(t *testing.T) {
+	ensure.HelmHome(t)
+
+	srv := NewTempServer(t,
+		WithChartSourceGlob("testdata/examplechart-0.1.0.tgz"),
+		WithIndexChangelog(),
+	)
+	defer srv.Stop()
+
+	getChanges := func(since string) (*repo.IndexFile, int) {
+		t.Helper()
+		res, err := srv.Client().Get(srv.URL() + ChangelogPath + "?" + repo.ChangesSinceParam + "=" + since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK {
+			return nil, res.StatusCode
+		}
+		changes := &repo.IndexFile{}
+		if err := yaml.Unmarshal(body, changes); err != nil {
+			t.Fatal(err)
+		}
+		return changes, res.StatusCode
+	}
+
+	index, err := repo.LoadIndexFile(filepath.Join(srv.Root(), "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Changes == nil || index.Changes.URL != "changes" || index.Changes.Token != "1" {
+		t.Fatalf("expected the index to reference the changelog, got %+v", index.Changes)
+	}
+
+	ch, err := loader.Load("testdata/examplechart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.Metadata.Version = "0.2.0"
+	if _, err := chartutil.Save(ch, srv.Root()); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, status := getChanges("1")
+	if status != http.StatusOK {
+		t.Fatalf("expected the changes since token 1, got status %d", status)
+	}
+	if len(changes.Entries["examplechart"]) != 1 || !changes.Has("examplechart", "0.2.0") {
+		t.Errorf("expected only the added version, got %v", changes.Entries)
+	}
+	if changes.Changes.Token != "2" {
+		t.Errorf("expected token 2, got %q", changes.Changes.Token)
+	}
+	if changes, _ := getChanges("0"); len(changes.Entries["examplechart"]) != 2 {
+		t.Errorf("expected all the versions since token 0, got %v", changes.Entries)
+	}
+	if _, status := getChanges("3"); status != http.StatusGone {
+		t.Errorf("expected an unknown token to be gone, got status %d", status)
+	}
+
+	srv.CompactChangelog()
+	if _, status := getChanges("1"); status != http.StatusGone {
+		t.Errorf("expected the compacted changes to be gone, got status %d", status)
+	}
+	if changes, status := getChanges("2"); status != http.StatusOK || len(changes.Entries) != 0 {
+		t.Errorf("expected no changes since the last token, got status %d and %v", status, changes)
+	}
+}
+
+func TestUploads(t *testing.T) {
+	ensure.HelmHome(t)
+
+	srv := NewTempServer(t, WithUploads())
+	defer srv.Stop()
+
+	upload := func(path, contentType string, body io.Reader) int {
+		t.Helper()
+		res, err := srv.Client().Post(srv.URL()+path, contentType, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+	readFile := func(name string) []byte {
+		t.Helper()
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	loadIndex := func() *repo.IndexFile {
+		t.Helper()
+		index, err := repo.LoadIndexFile(filepath.Join(srv.Root(), "index.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return index
+	}
+
+	chart := readFile("testdata/examplechart-0.1.0.tgz")
+	if status := upload(UploadPath, "application/gzip", bytes.NewReader(chart)); status != http.StatusCreated {
+		t.Fatalf("expected the chart to be saved, got status %d", status)
+	}
+	if !loadIndex().Has("examplechart", "0.1.0") {
+		t.Error("expected the uploaded chart to be added to the index")
+	}
+	if status := upload(UploadPath, "application/gzip", bytes.NewReader(chart)); status != http.StatusConflict {
+		t.Errorf("expected an existing chart not to be replaced, got status %d", status)
+	}
+	if status := upload(UploadPath+"?force", "application/gzip", bytes.NewReader(chart)); status != http.StatusCreated {
+		t.Errorf("expected an existing chart to be replaced when forced, got status %d", status)
+	}
+	if status := upload(UploadPath, "application/gzip", strings.NewReader("not a chart")); status != http.StatusBadRequest {
+		t.Errorf("expected an invalid chart to be rejected, got status %d", status)
+	}
+
+	// Charts are uploaded along with their provenance files in multipart
+	// forms, and provenance files on their own.
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	for field, name := range map[string]string{"chart": "signtest-0.1.0.tgz", "prov": "signtest-0.1.0.tgz.prov"} {
+		fw, err := mw.CreateFormFile(field, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(readFile(filepath.Join("../../../downloader/testdata", name)))
+	}
+	mw.Close()
+	if status := upload(UploadPath, mw.FormDataContentType(), &form); status != http.StatusCreated {
+		t.Fatalf("expected the signed chart to be saved, got status %d", status)
+	}
+	cv, err := loadIndex().Get("signtest", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cv.Signed() {
+		t.Error("expected the provenance file of the uploaded chart to be referenced")
+	}
+	prov := readFile("../../../downloader/testdata/signtest-0.1.0.tgz.prov")
+	if status := upload(ProvenanceUploadPath+"?force", "application/pgp-signature", bytes.NewReader(prov)); status != http.StatusCreated {
+		t.Errorf("expected the provenance file to be saved, got status %d", status)
+	}
+
+	// Deleted charts are removed from the index.
+	req, err := http.NewRequest(http.MethodDelete, srv.UploadURL()+"/signtest/0.1.0", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the chart to be deleted, got status %d", res.StatusCode)
+	}
+	if loadIndex().Has("signtest", "0.1.0") {
+		t.Error("expected the deleted chart to be removed from the index")
+	}
+	if _, err := os.Stat(filepath.Join(srv.Root(), "signtest-0.1.0.tgz.prov")); !os.IsNotExist(err) {
+		t.Errorf("expected the provenance file to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp/clearsign" //nolint
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	"helm.sh/helm/v4/pkg/provenance"
+)
+
+const (
+	// UploadPath is the URL path charts are uploaded to, see WithUploads.
+	UploadPath = "/api/charts"
+	// ProvenanceUploadPath is the URL path provenance files are uploaded to,
+	// see WithUploads.
+	ProvenanceUploadPath = "/api/prov"
+)
+
+// maxUploadSize is the maximum size of the uploaded files.
+const maxUploadSize = 32 << 20
+
+// errFileExists is the error of the uploads of files that already exist.
+var errFileExists = errors.New("file already exists")
+
+// WithUploads makes the server accept uploads of charts and provenance files
+// with the API of ChartMuseum, and regenerate its index after each of them:
+//
+//   - POST /api/charts uploads a chart archive sent as the body of the
+//     request, or in the "chart" field of a multipart form along with its
+//     provenance file in the "prov" field.
+//   - POST /api/prov uploads a provenance file sent as the body of the
+//     request, or in the "prov" field of a multipart form.
+//   - DELETE /api/charts/<name>/<version> deletes a version of a chart and its
+//     provenance file.
+//
+// The files are stored in the docroot, named after the name and version of
+// their chart. Files that already exist are only replaced when the request
+// has the "force" query parameter, and the upload fails with 409 Conflict
+// otherwise.
+func WithUploads() ServerOption {
+	return func(_ *testing.T, server *Server) {
+		server.uploads = true
+	}
+}
+
+// UploadURL returns the URL charts are uploaded to, see WithUploads.
+func (s *Server) UploadURL() string {
+	return s.URL() + UploadPath
+}
+
+// serveUpload serves the requests of the upload API when the server accepts
+// uploads. It reports whether it served the request.
+func (s *Server) serveUpload(w http.ResponseWriter, r *http.Request) bool {
+	if !s.uploads {
+		return false
+	}
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == UploadPath:
+		s.uploadChart(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == ProvenanceUploadPath:
+		s.uploadProvenance(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, UploadPath+"/"):
+		s.deleteChart(w, r)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) uploadChart(w http.ResponseWriter, r *http.Request) {
+	archive, prov, err := uploadedFiles(r, "chart")
+	if err != nil {
+		uploadError(w, http.StatusBadRequest, err)
+		return
+	}
+	if archive == nil {
+		uploadError(w, http.StatusBadRequest, errors.New("no chart uploaded"))
+		return
+	}
+	c, err := loader.LoadArchive(bytes.NewReader(archive))
+	if err != nil {
+		uploadError(w, http.StatusBadRequest, fmt.Errorf("invalid chart: %w", err))
+		return
+	}
+	files := map[string][]byte{chartFileName(c.Metadata): archive}
+	if prov != nil {
+		files[chartFileName(c.Metadata)+".prov"] = prov
+	}
+	s.saveUploads(w, r, files)
+}
+
+func (s *Server) uploadProvenance(w http.ResponseWriter, r *http.Request) {
+	_, prov, err := uploadedFiles(r, "prov")
+	if err != nil {
+		uploadError(w, http.StatusBadRequest, err)
+		return
+	}
+	if prov == nil {
+		uploadError(w, http.StatusBadRequest, errors.New("no provenance file uploaded"))
+		return
+	}
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		uploadError(w, http.StatusBadRequest, errors.New("invalid provenance file: no signed message"))
+		return
+	}
+	md := new(chart.Metadata)
+	if err := provenance.ParseMessageBlock(block.Plaintext, md, &provenance.SumCollection{}); err != nil || md.Name == "" || md.Version == "" {
+		uploadError(w, http.StatusBadRequest, fmt.Errorf("invalid provenance file: %v", err))
+		return
+	}
+	s.saveUploads(w, r, map[string][]byte{chartFileName(md) + ".prov": prov})
+}
+
+func (s *Server) deleteChart(w http.ResponseWriter, r *http.Request) {
+	name, version, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, UploadPath+"/"), "/")
+	if !ok || name == "" || version == "" || strings.Contains(version, "/") {
+		uploadError(w, http.StatusBadRequest, errors.New("expected /api/charts/<name>/<version>"))
+		return
+	}
+	fname := filepath.Join(s.docroot, chartFileName(&chart.Metadata{Name: name, Version: version}))
+
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+	if err := os.Remove(fname); err != nil {
+		uploadError(w, http.StatusNotFound, fmt.Errorf("no chart %s %s", name, version))
+		return
+	}
+	os.Remove(fname + ".prov")
+	if err := s.CreateIndex(); err != nil {
+		uploadError(w, http.StatusInternalServerError, err)
+		return
+	}
+	uploadResponse(w, http.StatusOK, map[string]bool{"deleted": true})
+}
+
+// saveUploads writes the uploaded files to the docroot and regenerates the
+// index.
+func (s *Server) saveUploads(w http.ResponseWriter, r *http.Request, files map[string][]byte) {
+	s.uploadMu.Lock()
+	defer s.uploadMu.Unlock()
+	if _, force := r.URL.Query()["force"]; !force {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(s.docroot, name)); err == nil {
+				uploadError(w, http.StatusConflict, errFileExists)
+				return
+			}
+		}
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(s.docroot, name), data, 0o644); err != nil {
+			uploadError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := s.CreateIndex(); err != nil {
+		uploadError(w, http.StatusInternalServerError, err)
+		return
+	}
+	uploadResponse(w, http.StatusCreated, map[string]bool{"saved": true})
+}
+
+// uploadedFiles returns the uploaded chart archive and provenance file. A
+// file sent as the body of the request is the one of the given field.
+func uploadedFiles(r *http.Request, field string) (archive, prov []byte, err error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxUploadSize))
+		if err != nil {
+			return nil, nil, err
+		}
+		if field == "prov" {
+			return nil, data, nil
+		}
+		return data, nil, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return nil, nil, err
+	}
+	if archive, err = formFile(r, "chart"); err != nil {
+		return nil, nil, err
+	}
+	if prov, err = formFile(r, "prov"); err != nil {
+		return nil, nil, err
+	}
+	return archive, prov, nil
+}
+
+// formFile returns the content of a file of a multipart form, or nil when the
+// form does not have it.
+func formFile(r *http.Request, field string) ([]byte, error) {
+	f, _, err := r.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// chartFileName returns the name of the archive of a chart in the docroot.
+func chartFileName(md *chart.Metadata) string {
+	return filepath.Base(fmt.Sprintf("%s-%s.tgz", md.Name, md.Version))
+}
+
+func uploadError(w http.ResponseWriter, status int, err error) {
+	uploadResponse(w, status, map[string]string{"error": err.Error()})
+}
+
+func uploadResponse(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,166 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v4/internal/fileutil"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/provenance"
+)
+
+// ShardDir is the directory, next to the index, the shards of a sharded index
+// are written to.
+const ShardDir = "index"
+
+// shardDigest matches the digests of the shards, which name the cached shards.
+var shardDigest = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// IndexShard references the index of the versions of a chart in a sharded
+// index.
+type IndexShard struct {
+	// URL is the URL of the index of the chart. It is relative to the URL of
+	// the repository unless it is absolute.
+	URL string `json:"url"`
+	// Digest is the SHA256 digest of the index of the chart.
+	Digest string `json:"digest"`
+}
+
+// WriteShardedFile writes the index as a sharded index to the given
+// destination path, which is the index of a repository.
+//
+// The versions of each chart are written to their own index in ShardDir, and
+// the index written to dest only references them. Clients then download the
+// index of a chart when they need it, and only when it changed.
+//
+// The mode on the files is set to 'mode'.
+func (i IndexFile) WriteShardedFile(dest string, mode os.FileMode) error {
+	dir := filepath.Join(filepath.Dir(dest), ShardDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	shards := maps.Clone(i.Shards)
+	if shards == nil {
+		shards = make(map[string]IndexShard, len(i.Entries))
+	}
+	for name, cvs := range i.Entries {
+		shard := IndexFile{
+			APIVersion: APIVersionV1,
+			Generated:  i.Generated,
+			Entries:    map[string]ChartVersions{name: cvs},
+		}
+		b, err := yaml.Marshal(shard)
+		if err != nil {
+			return err
+		}
+		digest, err := provenance.Digest(bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		fname := url.PathEscape(name) + ".yaml"
+		if err := fileutil.AtomicWriteFile(filepath.Join(dir, fname), bytes.NewReader(b), mode); err != nil {
+			return err
+		}
+		shards[name] = IndexShard{URL: ShardDir + "/" + fname, Digest: digest}
+	}
+
+	i.Entries = map[string]ChartVersions{}
+	i.Shards = shards
+	return i.WriteFile(dest, mode)
+}
+
+// LoadShard loads the versions of a chart into the index of the repository
+// when the index is sharded, downloading the index of the chart unless it is
+// already cached. It does nothing when the chart is not sharded, or its
+// versions are already loaded.
+func (r *ChartRepository) LoadShard(name string) error {
+	shard, ok := r.IndexFile.Shards[name]
+	if !ok {
+		return nil
+	}
+	if _, ok := r.IndexFile.Entries[name]; ok {
+		return nil
+	}
+	if !shardDigest.MatchString(shard.Digest) {
+		return fmt.Errorf("invalid digest %q of the index of chart %s", shard.Digest, name)
+	}
+
+	data, err := r.shard(shard)
+	if err != nil {
+		return fmt.Errorf("unable to load the index of chart %s: %w", name, err)
+	}
+	index, err := loadIndex(data, shard.URL)
+	if err != nil {
+		return fmt.Errorf("unable to load the index of chart %s: %w", name, err)
+	}
+	if r.IndexFile.Entries == nil {
+		r.IndexFile.Entries = map[string]ChartVersions{}
+	}
+	r.IndexFile.Entries[name] = index.Entries[name]
+	return nil
+}
+
+// shard returns the content of the index of a chart. Shards are cached by
+// digest, so that only the ones that changed since they were last loaded are
+// downloaded.
+func (r *ChartRepository) shard(shard IndexShard) ([]byte, error) {
+	cached := filepath.Join(r.CachePath, ShardDir, shard.Digest+".yaml")
+	if data, err := os.ReadFile(cached); err == nil {
+		if digest, err := provenance.Digest(bytes.NewReader(data)); err == nil && digest == shard.Digest {
+			return data, nil
+		}
+	}
+
+	var shardURL string
+	var data []byte
+	if err := r.failover(func(baseURL string) error {
+		var err error
+		if shardURL, err = ResolveReferenceURL(baseURL, shard.URL); err != nil {
+			return err
+		}
+		resp, err := r.Client.Get(shardURL, r.getterOptions(getter.WithURL(baseURL))...)
+		if err != nil {
+			return err
+		}
+		data = resp.Bytes()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	digest, err := provenance.Digest(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if digest != shard.Digest {
+		return nil, fmt.Errorf("digest mismatch for %s: expected %s, got %s", shardURL, shard.Digest, digest)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cached), 0755); err != nil {
+		return nil, err
+	}
+	return data, os.WriteFile(cached, data, 0644)
+}
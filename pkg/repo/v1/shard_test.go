@@ -0,0 +1,174 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+	"helm.sh/helm/v4/pkg/helmpath"
+)
+
+// startShardedServerForTests serves testdata/local-index.yaml as a sharded
+// index, and counts the requests for the shards.
+func startShardedServerForTests(t *testing.T) (string, string, *atomic.Int32) {
+	t.Helper()
+	i, err := LoadIndexFile("testdata/local-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := i.WriteShardedFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var shardRequests atomic.Int32
+	fs := http.FileServer(http.Dir(dir))
+	srv, err := startLocalServerForTests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/"+ShardDir+"/") {
+			shardRequests.Add(1)
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	return srv.URL, dir, &shardRequests
+}
+
+func TestWriteShardedFile(t *testing.T) {
+	_, dir, _ := startShardedServerForTests(t)
+
+	root, err := LoadIndexFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Entries) != 0 {
+		t.Errorf("expected the sharded index not to list versions, got %d charts", len(root.Entries))
+	}
+	if len(root.Shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(root.Shards))
+	}
+	shard := root.Shards["nginx"]
+	if shard.URL != "index/nginx.yaml" {
+		t.Errorf("unexpected shard URL %q", shard.URL)
+	}
+	nginx, err := LoadIndexFile(filepath.Join(dir, "index", "nginx.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nginx.Entries) != 1 || len(nginx.Entries["nginx"]) != 2 {
+		t.Errorf("expected the shard to have the 2 versions of nginx, got %v", nginx.Entries)
+	}
+}
+
+func TestLoadShard(t *testing.T) {
+	srvURL, dir, shardRequests := startShardedServerForTests(t)
+
+	newRepo := func() *ChartRepository {
+		t.Helper()
+		r, err := NewChartRepository(&Entry{Name: "sharded", URL: srvURL}, getter.All(&cli.EnvSettings{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.CachePath = filepath.Join(dir, "cache")
+		return r
+	}
+
+	r := newRepo()
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	charts, err := os.ReadFile(filepath.Join(r.CachePath, helmpath.CacheChartsFile("sharded")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(charts), "nginx\n") {
+		t.Errorf("expected the sharded charts to be listed, got %q", charts)
+	}
+	if shardRequests.Load() != 0 {
+		t.Error("expected the shards not to be downloaded with the index")
+	}
+
+	if r.IndexFile, err = LoadIndexFile(idx); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadShard("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	cv, err := r.IndexFile.Get("nginx", "0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv.URLs[0] != "https://charts.helm.sh/stable/nginx-0.1.0.tgz" {
+		t.Errorf("unexpected chart URL %q", cv.URLs[0])
+	}
+	if _, err := r.IndexFile.Get("alpine", ""); err == nil {
+		t.Error("expected only the loaded shard to be in the index")
+	}
+	if err := r.LoadShard("unknown"); err != nil {
+		t.Errorf("expected charts that are not sharded to be ignored, got %v", err)
+	}
+
+	// The shard is only downloaded once.
+	r = newRepo()
+	if r.IndexFile, err = LoadIndexFile(idx); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadShard("nginx"); err != nil {
+		t.Fatal(err)
+	}
+	if !r.IndexFile.Has("nginx", "0.2.0") {
+		t.Error("expected the cached shard to be loaded")
+	}
+	if n := shardRequests.Load(); n != 1 {
+		t.Errorf("expected the shard to be downloaded once, got %d requests", n)
+	}
+
+	// Shards that do not match their digest are rejected.
+	if err := os.WriteFile(filepath.Join(dir, "index", "alpine.yaml"), []byte("apiVersion: v1\nentries: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = r.LoadShard("alpine")
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+	r.IndexFile.Shards["alpine"] = IndexShard{URL: "index/alpine.yaml", Digest: "../../alpine"}
+	if err := r.LoadShard("alpine"); err == nil || !strings.Contains(err.Error(), "invalid digest") {
+		t.Errorf("expected an invalid digest, got %v", err)
+	}
+}
+
+func TestFindChartInShardedRepoURL(t *testing.T) {
+	srvURL, _, _ := startShardedServerForTests(t)
+
+	chartURL, err := FindChartInRepoURL(srvURL, "nginx", getter.All(&cli.EnvSettings{}), WithChartVersion("0.1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chartURL != "https://charts.helm.sh/stable/nginx-0.1.0.tgz" {
+		t.Errorf("%s is not the valid URL", chartURL)
+	}
+}
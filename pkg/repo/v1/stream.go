@@ -0,0 +1,330 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// StreamIndex decodes an index one chart version at a time, so that indexes
+// much larger than the available memory can be read. It calls fn with the
+// name of the chart and each valid version of the entries of the index, in
+// the order of the index, and returns the index without its entries.
+//
+// The source parameter is only used for logging. Like LoadIndexFile, it fails
+// with ErrEmptyIndexYaml when the index is empty and with ErrNoAPIVersion when
+// its API version is not set.
+func StreamIndex(r io.Reader, source string, fn func(name string, cv *ChartVersion) error) (*IndexFile, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err == io.EOF {
+		return &IndexFile{}, ErrEmptyIndexYaml
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	emit := func(name string, cv *ChartVersion) error {
+		if !validEntry(name, cv, source) {
+			return nil
+		}
+		return fn(name, cv)
+	}
+	var i *IndexFile
+	if first == '{' {
+		i, err = streamJSONIndex(br, emit)
+	} else {
+		i, err = streamYAMLIndex(br, emit)
+	}
+	if err != nil {
+		return i, err
+	}
+	i.Entries = map[string]ChartVersions{}
+	if i.APIVersion == "" {
+		return i, ErrNoAPIVersion
+	}
+	return i, nil
+}
+
+// peekNonSpace returns the first byte of the reader that is not a space,
+// without consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b, br.UnreadByte()
+	}
+}
+
+// streamJSONIndex decodes the entries of an index in JSON one chart version
+// at a time.
+func streamJSONIndex(r io.Reader, emit func(string, *ChartVersion) error) (*IndexFile, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+	header := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		if key != "entries" {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			header[key] = raw
+			continue
+		}
+		if err := streamJSONEntries(dec, emit); err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	i := &IndexFile{}
+	return i, json.Unmarshal(data, i)
+}
+
+// streamJSONEntries decodes the entries of an index in JSON, which may be
+// null.
+func streamJSONEntries(dec *json.Decoder, emit func(string, *ChartVersion) error) error {
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("invalid index entries: unexpected %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, _ := tok.(string)
+		if tok, err = dec.Token(); err != nil {
+			return err
+		}
+		if tok == nil {
+			continue
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("invalid versions of chart %q: unexpected %v", name, tok)
+		}
+		for dec.More() {
+			var cv *ChartVersion
+			if err := dec.Decode(&cv); err != nil {
+				return err
+			}
+			if err := emit(name, cv); err != nil {
+				return err
+			}
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("invalid index: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// yamlIndexStream decodes the entries of an index in YAML one chart version
+// at a time. The index is split into the blocks of the chart versions by
+// their indentation, and each block is decoded on its own, so only the lines
+// of a single version are held in memory besides the ones of the header.
+type yamlIndexStream struct {
+	emit func(string, *ChartVersion) error
+	// header holds the lines of the index that are not part of its entries.
+	header bytes.Buffer
+	// inEntries is whether the lines read are the ones of the entries.
+	inEntries bool
+	// keyIndent is the indentation of the names of the charts, and itemIndent
+	// the one of the versions of the current chart. They are -1 until known.
+	keyIndent, itemIndent int
+	name                  string
+	// entry holds the lines of the current version.
+	entry bytes.Buffer
+}
+
+func streamYAMLIndex(br *bufio.Reader, emit func(string, *ChartVersion) error) (*IndexFile, error) {
+	s := &yamlIndexStream{emit: emit}
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if err := s.line(line); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+
+	i := &IndexFile{}
+	if err := yaml.UnmarshalStrict(s.header.Bytes(), i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (s *yamlIndexStream) line(l string) error {
+	trimmed := strings.TrimLeft(l, " ")
+	indent := len(l) - len(trimmed)
+	content := strings.TrimSpace(trimmed)
+	blank := content == "" || strings.HasPrefix(content, "#")
+
+	if !s.inEntries {
+		if indent == 0 && isEntriesKey(content) {
+			s.inEntries = true
+			s.keyIndent = -1
+			return nil
+		}
+		s.header.WriteString(l)
+		return nil
+	}
+
+	switch {
+	case blank:
+		if s.entry.Len() > 0 {
+			s.entry.WriteString(l)
+		}
+		return nil
+	case indent == 0:
+		// The entries end with the next key of the index.
+		if err := s.flush(); err != nil {
+			return err
+		}
+		s.inEntries = false
+		return s.line(l)
+	case s.keyIndent < 0:
+		s.keyIndent = indent
+	}
+
+	isItem := content == "-" || strings.HasPrefix(content, "- ")
+	switch {
+	case indent == s.keyIndent && !isItem:
+		if err := s.flush(); err != nil {
+			return err
+		}
+		return s.chart(content)
+	case indent < s.keyIndent:
+		return fmt.Errorf("invalid index entries: unexpected indentation of %q", content)
+	case isItem && s.name != "" && (s.itemIndent < 0 || indent == s.itemIndent):
+		if err := s.flush(); err != nil {
+			return err
+		}
+		s.itemIndent = indent
+	case s.entry.Len() == 0:
+		return fmt.Errorf("invalid index entries: unexpected %q", content)
+	}
+	s.entry.WriteString(l)
+	return nil
+}
+
+// chart starts the versions of the chart whose name is on the given line.
+// Their lines follow unless they are on the same line.
+func (s *yamlIndexStream) chart(line string) error {
+	var key map[string]ChartVersions
+	if err := yaml.UnmarshalStrict([]byte(line), &key); err != nil {
+		return fmt.Errorf("invalid index entries: %w", err)
+	}
+	for name, cvs := range key {
+		s.name = name
+		s.itemIndent = -1
+		for _, cv := range cvs {
+			if err := s.emit(name, cv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flush decodes the current version and passes it on.
+func (s *yamlIndexStream) flush() error {
+	if s.entry.Len() == 0 {
+		return nil
+	}
+	// Decode the version as a sequence of one item, with its indentation
+	// removed.
+	var block bytes.Buffer
+	for l := range strings.Lines(s.entry.String()) {
+		n := min(s.itemIndent, len(l)-len(strings.TrimLeft(l, " ")))
+		block.WriteString(l[n:])
+	}
+	s.entry.Reset()
+
+	var cvs []*ChartVersion
+	if err := yaml.UnmarshalStrict(block.Bytes(), &cvs); err != nil {
+		return fmt.Errorf("invalid version of chart %q: %w", s.name, err)
+	}
+	for _, cv := range cvs {
+		if err := s.emit(s.name, cv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isEntriesKey reports whether a line of the index starts its entries on the
+// next lines.
+func isEntriesKey(content string) bool {
+	rest, ok := strings.CutPrefix(content, "entries:")
+	if !ok {
+		return false
+	}
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
@@ -0,0 +1,129 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+)
+
+// streamIndexBytes streams an index into an index file with its entries.
+func streamIndexBytes(t *testing.T, data []byte) (*IndexFile, error) {
+	t.Helper()
+	entries := map[string]ChartVersions{}
+	i, err := StreamIndex(bytes.NewReader(data), "test", func(name string, cv *ChartVersion) error {
+		entries[name] = append(entries[name], cv)
+		return nil
+	})
+	if i != nil {
+		i.Entries = entries
+		i.SortEntries()
+	}
+	return i, err
+}
+
+func TestStreamIndex(t *testing.T) {
+	for _, file := range []string{testfile, annotationstestfile, chartmuseumtestfile, unorderedTestfile, jsonTestfile} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected, err := loadIndex(data, file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			streamed, err := streamIndexBytes(t, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected, streamed) {
+				t.Errorf("expected the streamed index to be the loaded one\nexpected: %+v\ngot:      %+v", expected, streamed)
+			}
+		})
+	}
+
+	// The indexes written by Helm are streamed, with their versions in the
+	// order of the index.
+	index := NewIndexFile()
+	for _, v := range []string{"0.1.0", "0.3.0", "0.2.0"} {
+		md := &chart.Metadata{APIVersion: "v2", Name: "multiline", Version: v, Description: "first\n\n  second\n- third", Keywords: []string{"a", "b"}}
+		if err := index.MustAdd(md, "multiline-"+v+".tgz", "https://example.com", "sha256:1234"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index.Entries["empty"] = nil
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := loadIndex(data, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	if _, err := StreamIndex(bytes.NewReader(data), "test", func(name string, cv *ChartVersion) error {
+		if expected, err := expected.Get(name, cv.Version); err != nil || !reflect.DeepEqual(expected, cv) {
+			t.Errorf("expected %+v for %s %s, got %+v", expected, name, cv.Version, cv)
+		}
+		versions = append(versions, cv.Version)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(versions, " ") != "0.1.0 0.3.0 0.2.0" {
+		t.Errorf("expected the versions in the order of the index, got %v", versions)
+	}
+}
+
+func TestStreamIndexErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		index string
+		err   error
+	}{
+		"empty":          {index: "\n", err: ErrEmptyIndexYaml},
+		"no API version": {index: "entries:\n  foo:\n  - name: foo\n    version: 1.0.0\n", err: ErrNoAPIVersion},
+		"unknown field":  {index: "apiVersion: v1\nentries:\n  foo:\n  - name: foo\n    version: 1.0.0\n    bogus: true\n"},
+		"bad indent":     {index: "apiVersion: v1\nentries:\n    foo:\n    - name: foo\n  bar:\n"},
+		"version only":   {index: "apiVersion: v1\nentries:\n  - name: foo\n"},
+		"bad JSON":       {index: `{"apiVersion": "v1", "entries": {"foo": {}}}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := streamIndexBytes(t, []byte(tt.index))
+			if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// Invalid versions are skipped.
+	i, err := streamIndexBytes(t, []byte("apiVersion: v1\nentries:\n  foo:\n  - name: foo\n    version: 1.0.0\n  - name: foo\n  -\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(i.Entries["foo"]) != 1 {
+		t.Errorf("expected only the valid version, got %v", i.Entries["foo"])
+	}
+}
@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+// ErrUpdateSkipped is the error of the updates skipped because another one
+// failed.
+var ErrUpdateSkipped = errors.New("skipped after another repository failed to update")
+
+// UpdateStatus is the status of the update of the index of a repository.
+type UpdateStatus string
+
+const (
+	// UpdatePending is the status of the updates waiting to start.
+	UpdatePending UpdateStatus = "pending"
+	// UpdateFetching is the status of the updates downloading the index.
+	UpdateFetching UpdateStatus = "fetching"
+	// UpdateSucceeded is the status of the updates that succeeded.
+	UpdateSucceeded UpdateStatus = "succeeded"
+	// UpdateFailed is the status of the updates that failed.
+	UpdateFailed UpdateStatus = "failed"
+	// UpdateSkipped is the status of the updates that did not start because
+	// another one failed, see Updater.ContinueOnError.
+	UpdateSkipped UpdateStatus = "skipped"
+)
+
+// UpdateResult is the progress, and eventually the result, of the update of
+// the index of a repository.
+type UpdateResult struct {
+	// Repo is the updated repository.
+	Repo *ChartRepository
+	// Status is the status of the update.
+	Status UpdateStatus
+	// Fetched is the number of bytes of the index fetched so far. It is
+	// zero when the cached index did not change.
+	Fetched int64
+	// IndexFile is the path of the updated index in the cache, once the
+	// update succeeded.
+	IndexFile string
+	// Err is the error of the update once it failed or was skipped.
+	Err error
+}
+
+// UpdateProgress receives the progress of the updates of an Updater. Its
+// methods are called concurrently for different repositories, but in order
+// for each of them.
+type UpdateProgress interface {
+	// UpdateStatus is called when the status of the update of a repository
+	// changes.
+	UpdateStatus(UpdateResult)
+	// UpdateFetched is called as the index of a repository is downloaded,
+	// with the number of bytes fetched so far.
+	UpdateFetched(UpdateResult)
+}
+
+// UpdateError is the error of the updates of the indexes of repositories that
+// failed or were skipped.
+type UpdateError struct {
+	// Failed holds the updates that failed or were skipped, in the order of
+	// the repositories.
+	Failed []UpdateResult
+}
+
+func (e *UpdateError) Error() string {
+	urls := make([]string, 0, len(e.Failed))
+	for _, r := range e.Failed {
+		urls = append(urls, r.Repo.Config.URL)
+	}
+	return fmt.Sprintf("failed to update the following repositories: [%s]", strings.Join(urls, " "))
+}
+
+// Unwrap returns the errors of the failed updates.
+func (e *UpdateError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, r := range e.Failed {
+		errs = append(errs, r.Err)
+	}
+	return errs
+}
+
+// Updater updates the indexes of repositories concurrently.
+type Updater struct {
+	// Concurrency is the maximum number of indexes downloaded at once. All of
+	// them are when it is zero.
+	Concurrency int
+	// ContinueOnError updates all the repositories even when some of them
+	// fail. Otherwise, the updates that did not start when one fails are
+	// skipped.
+	ContinueOnError bool
+	// Progress receives the progress of the updates when set.
+	Progress UpdateProgress
+}
+
+// Update downloads the indexes of repositories to their cache. It returns the
+// results of the updates in the order of the repositories, and an
+// *UpdateError when some of them failed.
+func (u *Updater) Update(repos []*ChartRepository) ([]UpdateResult, error) {
+	results := make([]UpdateResult, len(repos))
+	for i, r := range repos {
+		results[i] = UpdateResult{Repo: r, Status: UpdatePending}
+	}
+	limit := u.Concurrency
+	if limit <= 0 {
+		limit = len(repos)
+	}
+	slots := make(chan struct{}, limit)
+
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(res *UpdateResult) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if failed.Load() && !u.ContinueOnError {
+				res.Err = ErrUpdateSkipped
+				u.setStatus(res, UpdateSkipped)
+				return
+			}
+			u.setStatus(res, UpdateFetching)
+			res.IndexFile, res.Err = res.Repo.downloadIndexFile(getter.WithProgress(func(fetched int64) {
+				res.Fetched = fetched
+				if u.Progress != nil {
+					u.Progress.UpdateFetched(*res)
+				}
+			}))
+			if res.Err != nil {
+				failed.Store(true)
+				u.setStatus(res, UpdateFailed)
+				return
+			}
+			u.setStatus(res, UpdateSucceeded)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var errs []UpdateResult
+	for _, res := range results {
+		if res.Status != UpdateSucceeded {
+			errs = append(errs, res)
+		}
+	}
+	if len(errs) > 0 {
+		return results, &UpdateError{Failed: errs}
+	}
+	return results, nil
+}
+
+func (u *Updater) setStatus(res *UpdateResult, status UpdateStatus) {
+	res.Status = status
+	if u.Progress != nil {
+		u.Progress.UpdateStatus(*res)
+	}
+}
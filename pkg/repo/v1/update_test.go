@@ -0,0 +1,165 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v4/pkg/cli"
+	"helm.sh/helm/v4/pkg/getter"
+)
+
+// recordedProgress records the progress of updates.
+type recordedProgress struct {
+	mu       sync.Mutex
+	statuses map[string][]UpdateStatus
+	fetched  map[string]int64
+}
+
+func (p *recordedProgress) UpdateStatus(res UpdateResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.statuses[res.Repo.Config.Name] = append(p.statuses[res.Repo.Config.Name], res.Status)
+}
+
+func (p *recordedProgress) UpdateFetched(res UpdateResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fetched[res.Repo.Config.Name] = res.Fetched
+}
+
+func newUpdateTestRepos(t *testing.T, urls ...string) []*ChartRepository {
+	t.Helper()
+	var repos []*ChartRepository
+	for i, u := range urls {
+		r, err := NewChartRepository(&Entry{Name: string(rune('a' + i)), URL: u}, getter.All(&cli.EnvSettings{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.CachePath = t.TempDir()
+		repos = append(repos, r)
+	}
+	return repos
+}
+
+func TestUpdater(t *testing.T) {
+	index, err := os.ReadFile("testdata/local-index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+	srv, err := startLocalServerForTests(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		w.Write(index)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	repos := newUpdateTestRepos(t, srv.URL, srv.URL, srv.URL)
+	progress := &recordedProgress{statuses: map[string][]UpdateStatus{}, fetched: map[string]int64{}}
+	u := &Updater{Concurrency: 2, Progress: progress}
+	results, err := u.Update(repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if maxInFlight > 2 {
+		t.Errorf("expected at most 2 concurrent downloads, got %d", maxInFlight)
+	}
+	for i, res := range results {
+		name := repos[i].Config.Name
+		if res.Repo != repos[i] || res.Status != UpdateSucceeded || res.Err != nil {
+			t.Errorf("%s: unexpected result %+v", name, res)
+		}
+		if res.Fetched != int64(len(index)) || progress.fetched[name] != res.Fetched {
+			t.Errorf("%s: expected %d bytes fetched, got %d and %d reported", name, len(index), res.Fetched, progress.fetched[name])
+		}
+		if _, err := LoadIndexFile(res.IndexFile); err != nil {
+			t.Errorf("%s: expected the index to be cached: %v", name, err)
+		}
+		if got := progress.statuses[name]; len(got) != 2 || got[0] != UpdateFetching || got[1] != UpdateSucceeded {
+			t.Errorf("%s: unexpected statuses %v", name, got)
+		}
+	}
+}
+
+func TestUpdaterErrors(t *testing.T) {
+	srv, err := startLocalServerForTests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, "testdata/local-index.yaml")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	failing := srv.URL + "/missing"
+
+	// The updates are done one at a time, so the other one is skipped when it
+	// starts after the failure.
+	repos := newUpdateTestRepos(t, failing, srv.URL)
+	results, err := (&Updater{Concurrency: 1}).Update(repos)
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected an UpdateError, got %v", err)
+	}
+	statuses := map[UpdateStatus]int{}
+	for _, res := range results {
+		statuses[res.Status]++
+	}
+	if statuses[UpdateFailed] != 1 || statuses[UpdateSucceeded]+statuses[UpdateSkipped] != 1 {
+		t.Errorf("expected one failed update and the other succeeded or skipped, got %v", statuses)
+	}
+	if statuses[UpdateSkipped] == 1 && !errors.Is(err, ErrUpdateSkipped) {
+		t.Errorf("expected the skipped update in the error, got %v", err)
+	}
+
+	// All the repositories are updated when continuing on errors.
+	repos = newUpdateTestRepos(t, failing, srv.URL, failing)
+	results, err = (&Updater{Concurrency: 1, ContinueOnError: true}).Update(repos)
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected an UpdateError, got %v", err)
+	}
+	if len(updateErr.Failed) != 2 || updateErr.Failed[0].Repo != repos[0] || updateErr.Failed[1].Repo != repos[2] {
+		t.Errorf("expected the failed updates in the order of the repositories, got %+v", updateErr.Failed)
+	}
+	if results[1].Status != UpdateSucceeded {
+		t.Errorf("expected the other repository to be updated, got %+v", results[1])
+	}
+	expected := "failed to update the following repositories: [" + failing + " " + failing + "]"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
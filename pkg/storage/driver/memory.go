@@ -108,6 +108,25 @@ func (mem *Memory) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error
 	return ls, nil
 }
 
+// ListAllNamespaces returns the list of the releases of all the namespaces
+// such that filter(release) == true, whatever the namespace of the driver.
+func (mem *Memory) ListAllNamespaces(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	defer unlock(mem.rlock())
+
+	var ls []*rspb.Release
+	for _, releases := range mem.cache {
+		for _, recs := range releases {
+			recs.Iter(func(_ int, rec *record) bool {
+				if filter(rec.rls) {
+					ls = append(ls, rec.rls)
+				}
+				return true
+			})
+		}
+	}
+	return ls, nil
+}
+
 // Query returns the set of releases that match the provided set of labels
 func (mem *Memory) Query(keyvals map[string]string) ([]*rspb.Release, error) {
 	defer unlock(mem.rlock())
@@ -140,6 +140,21 @@ func TestMemoryList(t *testing.T) {
 	}
 }
 
+func TestMemoryListAllNamespaces(t *testing.T) {
+	ts := tsFixtureMemory(t)
+	ts.SetNamespace("default")
+
+	dpl, err := ts.ListAllNamespaces(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Errorf("Failed to list deployed releases: %s", err)
+	}
+	if len(dpl) != 3 {
+		t.Errorf("Expected 3 deployed, got %d", len(dpl))
+	}
+}
+
 func TestMemoryQuery(t *testing.T) {
 	var tests = []struct {
 		desc      string
@@ -22,7 +22,6 @@ import (
 	"log/slog"
 	"strconv"
 	"strings"
-	"time"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -30,6 +29,7 @@ import (
 	kblabels "k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/clock"
 
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -43,6 +43,10 @@ const SecretsDriverName = "Secret"
 // SecretsInterface.
 type Secrets struct {
 	impl corev1.SecretInterface
+
+	// Clock is the clock of the createdAt and modifiedAt timestamps of the
+	// releases. The real clock is used when it is not set.
+	Clock clock.PassiveClock
 }
 
 // NewSecrets initializes a new Secrets wrapping an implementation of
@@ -153,7 +157,7 @@ func (secrets *Secrets) Create(key string, rls *rspb.Release) error {
 
 	lbs.init()
 	lbs.fromMap(rls.Labels)
-	lbs.set("createdAt", fmt.Sprintf("%v", time.Now().Unix()))
+	lbs.set("createdAt", fmt.Sprintf("%v", now(secrets.Clock).Unix()))
 
 	// create a new secret to hold the release
 	obj, err := newSecretsObject(key, rls, lbs)
@@ -179,7 +183,7 @@ func (secrets *Secrets) Update(key string, rls *rspb.Release) error {
 
 	lbs.init()
 	lbs.fromMap(rls.Labels)
-	lbs.set("modifiedAt", fmt.Sprintf("%v", time.Now().Unix()))
+	lbs.set("modifiedAt", fmt.Sprintf("%v", now(secrets.Clock).Unix()))
 
 	// create a new secret object to hold the release
 	obj, err := newSecretsObject(key, rls, lbs)
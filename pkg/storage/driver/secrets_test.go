@@ -19,8 +19,10 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -191,6 +193,31 @@ func TestSecretCreate(t *testing.T) {
 	}
 }
 
+func TestSecretTimestamps(t *testing.T) {
+	var mock MockSecretsInterface
+	mock.Init(t)
+	secrets := NewSecrets(&mock)
+	clock := clocktesting.NewFakePassiveClock(time.Unix(1700000000, 0))
+	secrets.Clock = clock
+
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	key := testKey(rel.Name, rel.Version)
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	if got := mock.objects[key].Labels["createdAt"]; got != "1700000000" {
+		t.Errorf("Expected the creation time of the clock, got %q", got)
+	}
+
+	clock.SetTime(time.Unix(1700000060, 0))
+	if err := secrets.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if got := mock.objects[key].Labels["modifiedAt"]; got != "1700000060" {
+		t.Errorf("Expected the modification time of the clock, got %q", got)
+	}
+}
+
 func TestSecretUpdate(t *testing.T) {
 	vers := 1
 	name := "smug-pigeon"
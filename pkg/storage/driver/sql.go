@@ -22,10 +22,10 @@ import (
 	"maps"
 	"sort"
 	"strconv"
-	"time"
 
 	"github.com/jmoiron/sqlx"
 	migrate "github.com/rubenv/sql-migrate"
+	"k8s.io/utils/clock"
 
 	sq "github.com/Masterminds/squirrel"
 
@@ -88,6 +88,10 @@ type SQL struct {
 	db               *sqlx.DB
 	namespace        string
 	statementBuilder sq.StatementBuilderType
+
+	// Clock is the clock of the createdAt and modifiedAt timestamps of the
+	// releases. The real clock is used when it is not set.
+	Clock clock.PassiveClock
 }
 
 // Name returns the name of the driver.
@@ -485,7 +489,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 			int(rls.Version),
 			rls.Info.Status.String(),
 			sqlReleaseDefaultOwner,
-			int(time.Now().Unix()),
+			int(now(s.Clock).Unix()),
 		).ToSql()
 	if err != nil {
 		slog.Debug("failed to build insert query", slog.Any("error", err))
@@ -571,7 +575,7 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 		Set(sqlReleaseTableVersionColumn, int(rls.Version)).
 		Set(sqlReleaseTableStatusColumn, rls.Info.Status.String()).
 		Set(sqlReleaseTableOwnerColumn, sqlReleaseDefaultOwner).
-		Set(sqlReleaseTableModifiedAtColumn, int(time.Now().Unix())).
+		Set(sqlReleaseTableModifiedAtColumn, int(now(s.Clock).Unix())).
 		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: namespace}).
 		ToSql()
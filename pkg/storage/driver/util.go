@@ -23,6 +23,9 @@ import (
 	"encoding/json"
 	"io"
 	"slices"
+	"time"
+
+	"k8s.io/utils/clock"
 
 	rspb "helm.sh/helm/v4/pkg/release/v1"
 )
@@ -33,6 +36,14 @@ var magicGzip = []byte{0x1f, 0x8b, 0x08}
 
 var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt"}
 
+// now returns the time of a clock, or the current time when it is not set.
+func now(c clock.PassiveClock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
+
 // encodeRelease encodes a release returning a base64 encoded
 // gzipped string representation, or error.
 func encodeRelease(rls *rspb.Release) (string, error) {
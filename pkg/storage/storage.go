@@ -56,10 +56,17 @@ func (s *Storage) Get(name string, version int) (*rspb.Release, error) {
 // error is returned if the storage driver fails to store the
 // release, or a release with an identical key already exists.
 func (s *Storage) Create(rls *rspb.Release) error {
+	return s.CreateWithMaxHistory(rls, s.MaxHistory)
+}
+
+// CreateWithMaxHistory is like Create, but retains at most maxHistory
+// releases instead of MaxHistory. It lets callers sharing a Storage apply
+// their own limit without modifying it.
+func (s *Storage) CreateWithMaxHistory(rls *rspb.Release, maxHistory int) error {
 	slog.Debug("creating release", "key", makeKey(rls.Name, rls.Version))
-	if s.MaxHistory > 0 {
+	if maxHistory > 0 {
 		// Want to make space for one more release.
-		if err := s.removeLeastRecent(rls.Name, s.MaxHistory-1); err != nil &&
+		if err := s.removeLeastRecent(rls.Name, maxHistory-1); err != nil &&
 			!errors.Is(err, driver.ErrReleaseNotFound) {
 			return err
 		}
@@ -390,6 +390,28 @@ func TestStorageRemoveLeastRecent(t *testing.T) {
 	}
 }
 
+func TestStorageCreateWithMaxHistory(t *testing.T) {
+	storage := Init(driver.NewMemory())
+	storage.MaxHistory = 10
+
+	const name = "angry-bird"
+	for v := 1; v <= 4; v++ {
+		rls := ReleaseTestData{Name: name, Version: v, Status: rspb.StatusSuperseded}.ToRelease()
+		assertErrNil(t.Fatal, storage.CreateWithMaxHistory(rls, 2), fmt.Sprintf("Storing release 'angry-bird' (v%d)", v))
+	}
+
+	hist, err := storage.History(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist) != 2 {
+		t.Fatalf("expected 2 items in history, got %d", len(hist))
+	}
+	if storage.MaxHistory != 10 {
+		t.Errorf("expected MaxHistory to be left untouched, got %d", storage.MaxHistory)
+	}
+}
+
 func TestStorageDoNotDeleteDeployed(t *testing.T) {
 	storage := Init(driver.NewMemory())
 	storage.MaxHistory = 3
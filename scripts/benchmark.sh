@@ -0,0 +1,72 @@
+#!/usr/bin/env bash
+
+# Copyright The Helm Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Runs the benchmarks of the hot paths of Helm (rendering, coalescing values,
+# loading repository indexes, resolving dependencies and listing releases).
+#
+# Usage:
+#   scripts/benchmark.sh            run the benchmarks of the working tree
+#   scripts/benchmark.sh <git-ref>  compare the working tree against <git-ref>
+#
+# Comparisons are reported with benchstat. BENCH_COUNT sets the number of runs
+# of every benchmark and BENCH_FILTER the benchmarks to run.
+
+set -euo pipefail
+
+packages=(
+  ./pkg/engine/
+  ./pkg/chart/common/util/
+  ./pkg/repo/v1/
+  ./internal/resolver/
+  ./pkg/action/
+)
+count="${BENCH_COUNT:-6}"
+filter="${BENCH_FILTER:-.}"
+benchstat="${BENCHSTAT:-go run golang.org/x/perf/cmd/benchstat@latest}"
+
+run_benchmarks() {
+  local dir="$1" output="$2"
+  (
+    cd "$dir"
+    # Packages missing in older revisions are skipped.
+    local existing=()
+    for p in "${packages[@]}"; do
+      [[ -d "$p" ]] && existing+=("$p")
+    done
+    go test -run '^$' -bench "$filter" -benchmem -count "$count" "${existing[@]}"
+  ) | tee "$output"
+}
+
+outdir=$(mktemp -d /tmp/helm-bench.XXXXXXXXXX)
+
+if [[ $# -eq 0 ]]; then
+  run_benchmarks . "${outdir}/current.txt"
+  echo "Results written to ${outdir}/current.txt"
+  exit 0
+fi
+
+base="$1"
+worktree="${outdir}/base"
+git worktree add --detach "$worktree" "$base" >/dev/null
+trap 'git worktree remove --force "$worktree"' EXIT
+
+echo "==> Running benchmarks of ${base} <=="
+run_benchmarks "$worktree" "${outdir}/base.txt"
+echo "==> Running benchmarks of the working tree <=="
+run_benchmarks . "${outdir}/current.txt"
+
+echo "==> Comparison <=="
+$benchstat "${outdir}/base.txt" "${outdir}/current.txt"